@@ -0,0 +1,69 @@
+// Command auditverify verifies the hash chain of the audit trail and reports the first broken link.
+//
+// It reads the same DSN_AUTH and AUDIT_HMAC_KEY environment variables as the server.
+// It exits with status 1 if the chain is broken, and with status 2 if the verification could not run.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+
+	"backend/internal/infrastructure/persistence"
+	"backend/internal/usecase"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	exitBroken = 1
+	exitFailed = 2
+)
+
+func main() {
+	log.SetFlags(0)
+
+	dsnAuth := os.Getenv("DSN_AUTH")
+	if dsnAuth == "" {
+		log.Print("environment variable DSN_AUTH is not set")
+		os.Exit(exitFailed)
+	}
+
+	db, err := gorm.Open(postgres.Open(dsnAuth), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		log.Printf("failed to connect database: %v", err)
+		os.Exit(exitFailed)
+	}
+
+	signingKey := []byte(os.Getenv("AUDIT_HMAC_KEY"))
+	if len(signingKey) == 0 {
+		log.Print("warning: AUDIT_HMAC_KEY is not set, signatures are not verified")
+	}
+
+	uc := usecase.NewAuditLogUsecase(persistence.NewAuditLogGormRepository(db, signingKey), signingKey)
+
+	output, err := uc.VerifyAuditLogs(context.Background())
+	if err != nil {
+		log.Printf("failed to verify audit logs: %v", err)
+		os.Exit(exitFailed)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	err = encoder.Encode(output)
+	if err != nil {
+		log.Printf("failed to write result: %v", err)
+		os.Exit(exitFailed)
+	}
+
+	if !output.Valid {
+		log.Printf("audit log chain is broken at entry %d: %s", *output.FirstBrokenID, output.Reason)
+		os.Exit(exitBroken)
+	}
+}
@@ -2,16 +2,27 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"database/sql"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"backend/internal/domain/service"
 	"backend/internal/infrastructure/persistence"
+	"backend/internal/infrastructure/pki"
+	"backend/internal/infrastructure/storage"
 	"backend/internal/presentation/handler"
+	"backend/internal/presentation/mqtt"
 	"backend/internal/usecase"
 
 	"github.com/gin-gonic/gin"
@@ -26,45 +37,205 @@ const (
 	dbMaxOpenConns          = 100
 	serverShutdownTimeout   = 5 * time.Second
 	serverReadHeaderTimeout = 10 * time.Second
+
+	defaultFirmwareStorageDir = "/app/firmware"
 )
 
+// errMQTTBrokerURLNotSet is returned when the MQTT worker is not configured.
+var errMQTTBrokerURLNotSet = errors.New("MQTT_BROKER_URL is not set")
+
 func main() {
-	// --- Initialize database connection ---
-	dsnAuth := os.Getenv("DSN_AUTH")
-	if dsnAuth == "" {
-		log.Fatal("environment variable DSN_AUTH is not set")
+	// --- Initialize database connections ---
+	// The auth database holds devices and credentials (control plane),
+	// and the telemetry database holds sensor data (data plane).
+	db, sqlDB := openDatabase("DSN_AUTH")
+	telemetryDB, telemetrySQLDB := openDatabase("DSN_TELEM")
+
+	// --- Load the private CA ---
+	certValidity := getEnvDuration("CERT_VALIDITY", pki.DefaultValidity)
+
+	ca, err := pki.LoadCA(
+		getEnv("CA_CERT_PATH", pki.DefaultCertPath),
+		getEnv("CA_KEY_PATH", pki.DefaultKeyPath),
+		certValidity,
+	)
+	if err != nil {
+		log.Fatalf("failed to load CA: %v", err)
 	}
 
-	db, err := gorm.Open(postgres.Open(dsnAuth), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
+	// Issued certificates point to the OCSP responder if OCSP_URL is set, e.g. "https://api.example.com/ocsp".
+	ocspURL := os.Getenv("OCSP_URL")
+	if ocspURL != "" {
+		ca.SetOCSPServers([]string{ocspURL})
+	}
+
+	ocspResponder, err := pki.NewOCSPResponder(ca, pki.DefaultOCSPSignerValidity)
+	if err != nil {
+		log.Fatalf("failed to create OCSP responder: %v", err)
+	}
+
+	// --- Dependency Injection ---
+	transactor := persistence.NewGormTransactor(db)
+	deviceRepo := persistence.NewDeviceGormRepository(db)
+	enrollmentTokenRepo := persistence.NewEnrollmentTokenGormRepository(db)
+	certificateRepo := persistence.NewCertificateGormRepository(db)
+	deviceGroupRepo := persistence.NewDeviceGroupGormRepository(db)
+	deviceTwinRepo := persistence.NewDeviceTwinGormRepository(db)
+	deviceCommandRepo := persistence.NewDeviceCommandGormRepository(db)
+	firmwareArtifactRepo := persistence.NewFirmwareArtifactGormRepository(db)
+	firmwareCampaignRepo := persistence.NewFirmwareCampaignGormRepository(db)
+	firmwareSigningKeyRepo := persistence.NewFirmwareSigningKeyGormRepository(db)
+	// Audit log entries are signed if AUDIT_HMAC_KEY is set, so that the hash chain cannot be rebuilt
+	// by someone who can only access the database.
+	auditSigningKey := []byte(os.Getenv("AUDIT_HMAC_KEY"))
+	auditLogRepo := persistence.NewAuditLogGormRepository(db, auditSigningKey)
+	telemetryRepo := persistence.NewTelemetryGormRepository(telemetryDB)
+
+	deviceUsecase := usecase.NewDeviceUsecase(deviceRepo, auditLogRepo, transactor)
+	provisioningUsecase := usecase.NewProvisioningUsecase(
+		deviceRepo, enrollmentTokenRepo, certificateRepo, ca, transactor, auditLogRepo,
+	)
+	enrollmentTokenConfig := usecase.EnrollmentTokenConfig{
+		DefaultTTL: getEnvDuration("ENROLLMENT_TOKEN_TTL", usecase.DefaultEnrollmentTokenTTL),
+		MaxTTL:     getEnvDuration("ENROLLMENT_TOKEN_MAX_TTL", usecase.DefaultEnrollmentTokenMaxTTL),
+	}
+	enrollmentTokenUsecase := usecase.NewEnrollmentTokenUsecase(
+		deviceRepo, enrollmentTokenRepo, auditLogRepo, transactor, enrollmentTokenConfig,
+	)
+	deviceImportUsecase := usecase.NewDeviceImportUsecase(
+		deviceRepo, enrollmentTokenRepo, auditLogRepo, transactor, enrollmentTokenConfig,
+	)
+	deviceExportUsecase := usecase.NewDeviceExportUsecase(deviceRepo, transactor)
+	// The CRL is also written to a file if CRL_PATH is set, e.g. for the `crlfile` option of Mosquitto.
+	var crlPublisher service.RevocationListPublisher
+
+	crlPath := os.Getenv("CRL_PATH")
+	if crlPath != "" {
+		crlPublisher = pki.NewCRLFile(crlPath)
+	}
+
+	crlUsecase := usecase.NewCRLUsecase(certificateRepo, ca, crlPublisher, usecase.CRLConfig{
+		Validity:      getEnvDuration("CRL_VALIDITY", usecase.DefaultCRLValidity),
+		RefreshBefore: getEnvDuration("CRL_REFRESH_BEFORE", usecase.DefaultCRLRefreshBefore),
+	})
+	ocspUsecase := usecase.NewOCSPUsecase(certificateRepo, ocspResponder, usecase.OCSPConfig{
+		Validity: getEnvDuration("OCSP_VALIDITY", usecase.DefaultOCSPValidity),
 	})
+	certificateUsecase := usecase.NewCertificateUsecase(
+		deviceRepo, certificateRepo, crlUsecase, ocspUsecase, auditLogRepo, transactor,
+	)
+	deviceGroupUsecase := usecase.NewDeviceGroupUsecase(
+		deviceGroupRepo, deviceRepo, deviceUsecase, certificateUsecase, auditLogRepo, transactor,
+	)
+	deviceTopologyUsecase := usecase.NewDeviceTopologyUsecase(deviceRepo, auditLogRepo, transactor)
+	// The twin deltas and the commands are published through the MQTT brokers,
+	// which are attached to the publisher below.
+	devicePublisher := mqtt.NewDevicePublisher()
+	deviceTwinUsecase := usecase.NewDeviceTwinUsecase(
+		deviceRepo, deviceTwinRepo, auditLogRepo, transactor, devicePublisher,
+	)
+	deviceCommandMaxTTL := getEnvDuration("DEVICE_COMMAND_MAX_TTL", usecase.DefaultDeviceCommandMaxTTL)
+	deviceCommandUsecase := usecase.NewDeviceCommandUsecase(
+		deviceRepo, deviceCommandRepo, auditLogRepo, transactor, devicePublisher, usecase.DeviceCommandConfig{
+			DefaultTTL:       getEnvDuration("DEVICE_COMMAND_TTL", usecase.DefaultDeviceCommandTTL),
+			MaxTTL:           deviceCommandMaxTTL,
+			DispatchInterval: usecase.DefaultDeviceCommandDispatchInterval,
+		},
+	)
+	// Firmware images are kept in FIRMWARE_STORAGE_DIR, and signed with the CA key.
+	firmwareStorage, err := storage.NewFirmwareFileStorage(getEnv("FIRMWARE_STORAGE_DIR", defaultFirmwareStorageDir))
 	if err != nil {
-		log.Fatalf("failed to connect database: %v", err)
+		log.Fatalf("failed to open firmware storage: %v", err)
 	}
 
-	// --- Configure database connection pool ---
-	sqlDB, err := db.DB()
+	// Firmware manifests are signed with keys kept in FIRMWARE_SIGNING_KEY_DIR. The manifests signed by a retired key
+	// remain verifiable for FIRMWARE_SIGNING_KEY_GRACE_PERIOD.
+	firmwareKeyStore, err := pki.NewFirmwareKeyStore(getEnv("FIRMWARE_SIGNING_KEY_DIR", pki.DefaultFirmwareKeyDir))
 	if err != nil {
-		log.Fatalf("failed to get underlying sql.DB: %v", err)
+		log.Fatalf("failed to open firmware signing key store: %v", err)
 	}
 
-	sqlDB.SetMaxIdleConns(dbMaxIdleConns)
-	sqlDB.SetMaxOpenConns(dbMaxOpenConns)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	firmwareManifestUsecase := usecase.NewFirmwareManifestUsecase(
+		firmwareArtifactRepo, firmwareSigningKeyRepo, firmwareKeyStore, auditLogRepo, transactor,
+		usecase.FirmwareManifestConfig{
+			KeyAlgorithm: getEnv("FIRMWARE_SIGNING_KEY_ALGORITHM", usecase.DefaultFirmwareKeyAlgorithm),
+			GracePeriod:  getEnvDuration("FIRMWARE_SIGNING_KEY_GRACE_PERIOD", usecase.DefaultFirmwareKeyGracePeriod),
+		},
+	)
 
-	// --- Dependency Injection ---
-	deviceRepo := persistence.NewDeviceGormRepository(db)
-	deviceUsecase := usecase.NewDeviceUsecase(deviceRepo)
-	deviceHandler := handler.NewDeviceHandler(deviceUsecase)
+	err = firmwareManifestUsecase.PrepareFirmwareSigningKey(context.Background())
+	if err != nil {
+		log.Fatalf("failed to prepare firmware signing key: %v", err)
+	}
+
+	firmwareUsecase := usecase.NewFirmwareUsecase(
+		firmwareArtifactRepo, firmwareStorage, ca, firmwareManifestUsecase, auditLogRepo, transactor,
+	)
+	// Devices download the images from FIRMWARE_BASE_URL, e.g. "https://api.example.com".
+	firmwareCampaignConfig := usecase.FirmwareCampaignConfig{
+		DefaultUpdateTimeout: getEnvDuration("FIRMWARE_UPDATE_TIMEOUT", usecase.DefaultFirmwareUpdateTimeout),
+		MaxUpdateTimeout:     getEnvDuration("FIRMWARE_UPDATE_MAX_TIMEOUT", usecase.DefaultFirmwareUpdateMaxTimeout),
+		MaxCommandTTL:        deviceCommandMaxTTL,
+		DispatchInterval:     usecase.DefaultFirmwareCampaignDispatchInterval,
+		DownloadBaseURL:      os.Getenv("FIRMWARE_BASE_URL"),
+	}
+	firmwareCampaignUsecase := usecase.NewFirmwareCampaignUsecase(
+		firmwareCampaignRepo, firmwareArtifactRepo, deviceRepo, deviceUsecase, deviceCommandUsecase,
+		firmwareManifestUsecase, auditLogRepo, transactor, firmwareCampaignConfig,
+	)
+	auditLogUsecase := usecase.NewAuditLogUsecase(auditLogRepo, auditSigningKey)
+	telemetryUsecase := usecase.NewTelemetryUsecase(deviceRepo, telemetryRepo)
+	deviceConnectionUsecase := usecase.NewDeviceConnectionUsecase(deviceRepo, certificateRepo)
+	// A connected device not heard from within DEVICE_HEARTBEAT_INTERVAL is flagged as STALE.
+	devicePresenceUsecase := usecase.NewDevicePresenceUsecase(deviceRepo, usecase.DevicePresenceConfig{
+		HeartbeatInterval:     getEnvDuration("DEVICE_HEARTBEAT_INTERVAL", usecase.DefaultDeviceHeartbeatInterval),
+		SweepInterval:         usecase.DefaultDevicePresenceSweepInterval,
+		ActivityWriteInterval: usecase.DefaultDeviceActivityWriteInterval,
+	})
+	ingestionUsecase := usecase.NewIngestionUsecase(
+		deviceRepo, telemetryUsecase, devicePresenceUsecase, usecase.IngestionConfig{
+			BatchSize:     usecase.DefaultIngestionBatchSize,
+			FlushInterval: getEnvDuration("INGESTION_FLUSH_INTERVAL", usecase.DefaultIngestionFlushInterval),
+		},
+	)
+
+	deviceHandler := handler.NewDeviceHandler(deviceUsecase, handler.DeviceHandlerConfig{
+		RequireIfMatch: getEnvBool("REQUIRE_IF_MATCH", false),
+	})
+	deviceImportHandler := handler.NewDeviceImportHandler(deviceImportUsecase)
+	deviceExportHandler := handler.NewDeviceExportHandler(deviceExportUsecase)
+	deviceGroupHandler := handler.NewDeviceGroupHandler(deviceGroupUsecase)
+	deviceTopologyHandler := handler.NewDeviceTopologyHandler(deviceTopologyUsecase)
+	deviceTwinHandler := handler.NewDeviceTwinHandler(deviceTwinUsecase)
+	deviceCommandHandler := handler.NewDeviceCommandHandler(deviceCommandUsecase)
+	firmwareHandler := handler.NewFirmwareHandler(firmwareUsecase)
+	firmwareManifestHandler := handler.NewFirmwareManifestHandler(firmwareManifestUsecase)
+	firmwareCampaignHandler := handler.NewFirmwareCampaignHandler(firmwareCampaignUsecase)
+	provisioningHandler := handler.NewProvisioningHandler(provisioningUsecase)
+	enrollmentTokenHandler := handler.NewEnrollmentTokenHandler(enrollmentTokenUsecase)
+	certificateHandler := handler.NewCertificateHandler(certificateUsecase)
+	crlHandler := handler.NewCRLHandler(crlUsecase)
+	ocspHandler := handler.NewOCSPHandler(ocspUsecase)
+	auditLogHandler := handler.NewAuditLogHandler(auditLogUsecase)
+	telemetryHandler := handler.NewTelemetryHandler(telemetryUsecase)
 
 	// --- Gin router setup ---
 	router := gin.Default()
+	// Attribute the operations of each request to the caller named in the X-Actor header, for the audit trail.
+	router.Use(handler.Actor())
 
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
 		err := sqlDB.PingContext(c.Request.Context())
 		if err != nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unhealthy", "error": err.Error()})
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unhealthy", "error": "auth db: " + err.Error()})
+
+			return
+		}
+
+		err = telemetrySQLDB.PingContext(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unhealthy", "error": "telemetry db: " + err.Error()})
 
 			return
 		}
@@ -79,9 +250,157 @@ func main() {
 		deviceRoutes.GET("", deviceHandler.ListDevices)
 		deviceRoutes.GET("/:id", deviceHandler.GetDevice)
 		deviceRoutes.PUT("/:id", deviceHandler.UpdateDevice)
+		deviceRoutes.PATCH("/:id", deviceHandler.PatchDevice)
 		deviceRoutes.DELETE("/:id", deviceHandler.DeleteDevice)
+		deviceRoutes.POST("/:id/status/activate", deviceHandler.ActivateDevice)
+		deviceRoutes.POST("/:id/status/suspend", deviceHandler.SuspendDevice)
+		deviceRoutes.POST("/:id/status/revoke", deviceHandler.RevokeDevice)
+		deviceRoutes.POST("/:id/enrollment-tokens", enrollmentTokenHandler.CreateEnrollmentToken)
+		deviceRoutes.GET("/:id/enrollment-tokens", enrollmentTokenHandler.ListEnrollmentTokens)
+		deviceRoutes.POST("/:id/enrollment-tokens/:tokenId/revoke", enrollmentTokenHandler.RevokeEnrollmentToken)
+		deviceRoutes.GET("/:id/certificates", certificateHandler.ListDeviceCertificates)
+		deviceRoutes.GET("/:id/sensor-data", telemetryHandler.ListSensorData)
+		deviceRoutes.GET("/:id/children", deviceTopologyHandler.ListChildDevices)
+		deviceRoutes.POST("/:id/children", deviceTopologyHandler.AttachChildDevice)
+		deviceRoutes.DELETE("/:id/children/:childId", deviceTopologyHandler.DetachChildDevice)
+		deviceRoutes.GET("/:id/ancestors", deviceTopologyHandler.ListAncestorDevices)
+		deviceRoutes.GET("/:id/twin", deviceTwinHandler.GetDeviceTwin)
+		deviceRoutes.PUT("/:id/twin/desired", deviceTwinHandler.SetDesiredState)
+		deviceRoutes.PATCH("/:id/twin/desired", deviceTwinHandler.PatchDesiredState)
+		deviceRoutes.POST("/:id/commands", deviceCommandHandler.SendDeviceCommand)
+		deviceRoutes.GET("/:id/commands/:commandId", deviceCommandHandler.GetDeviceCommand)
+	}
+
+	// Custom methods of the device collection, such as POST /devices:import and GET /devices:export.
+	// Gin cannot route a colon inside a path segment, so the handlers check the method parameter.
+	router.POST("/devices:method", deviceImportHandler.ImportDevices)
+	router.GET("/devices:method", deviceExportHandler.ExportDevices)
+
+	// Device groups, whose members are added to them or match their rule
+	deviceGroupRoutes := router.Group("/device-groups")
+	{
+		deviceGroupRoutes.POST("", deviceGroupHandler.CreateDeviceGroup)
+		deviceGroupRoutes.GET("", deviceGroupHandler.ListDeviceGroups)
+		deviceGroupRoutes.GET("/:id", deviceGroupHandler.GetDeviceGroup)
+		deviceGroupRoutes.PUT("/:id", deviceGroupHandler.UpdateDeviceGroup)
+		deviceGroupRoutes.DELETE("/:id", deviceGroupHandler.DeleteDeviceGroup)
+		deviceGroupRoutes.POST("/:id/members", deviceGroupHandler.AddDeviceGroupMembers)
+		deviceGroupRoutes.DELETE("/:id/members/:deviceId", deviceGroupHandler.RemoveDeviceGroupMember)
+		deviceGroupRoutes.GET("/:id/devices", deviceGroupHandler.ListDeviceGroupDevices)
+		deviceGroupRoutes.POST("/:id/actions/:action", deviceGroupHandler.ApplyDeviceGroupAction)
+	}
+
+	// Firmware registry, whose images devices download during the updates
+	firmwareRoutes := router.Group("/firmware")
+	{
+		firmwareRoutes.POST("", firmwareHandler.UploadFirmware)
+		firmwareRoutes.GET("", firmwareHandler.ListFirmware)
+		firmwareRoutes.GET("/:id", firmwareHandler.GetFirmware)
+		firmwareRoutes.GET("/:id/image", firmwareHandler.DownloadFirmwareImage)
+		firmwareRoutes.GET("/:id/manifest", firmwareManifestHandler.GetFirmwareManifest)
+		firmwareRoutes.DELETE("/:id", firmwareHandler.DeleteFirmware)
+	}
+
+	// Keys that sign the firmware manifests, which devices verify them with
+	firmwareSigningKeyRoutes := router.Group("/firmware-signing-keys")
+	{
+		firmwareSigningKeyRoutes.GET("", firmwareManifestHandler.ListFirmwareSigningKeys)
+		firmwareSigningKeyRoutes.POST("/rotate", firmwareManifestHandler.RotateFirmwareSigningKey)
+	}
+
+	// Firmware campaigns, which roll out firmware to devices in waves
+	firmwareCampaignRoutes := router.Group("/firmware-campaigns")
+	{
+		firmwareCampaignRoutes.POST("", firmwareCampaignHandler.CreateFirmwareCampaign)
+		firmwareCampaignRoutes.GET("", firmwareCampaignHandler.ListFirmwareCampaigns)
+		firmwareCampaignRoutes.GET("/:id", firmwareCampaignHandler.GetFirmwareCampaign)
+		firmwareCampaignRoutes.GET("/:id/devices", firmwareCampaignHandler.ListFirmwareCampaignDevices)
+		firmwareCampaignRoutes.POST("/:id/pause", firmwareCampaignHandler.PauseFirmwareCampaign)
+		firmwareCampaignRoutes.POST("/:id/resume", firmwareCampaignHandler.ResumeFirmwareCampaign)
+		firmwareCampaignRoutes.POST("/:id/cancel", firmwareCampaignHandler.CancelFirmwareCampaign)
 	}
 
+	// Certificate inspection endpoints
+	router.GET("/certificates/:serial", certificateHandler.GetCertificate)
+	router.POST("/certificates/:serial/revoke", certificateHandler.RevokeCertificate)
+
+	// Audit trail
+	router.GET("/audit-logs", auditLogHandler.ListAuditLogs)
+	router.GET("/audit-logs/verify", auditLogHandler.VerifyAuditLogs)
+
+	// Certificate revocation list
+	router.GET("/crl", crlHandler.GetCRL)
+
+	// OCSP responder
+	router.GET("/ocsp/*request", ocspHandler.HandleGet)
+	router.POST("/ocsp", ocspHandler.HandlePost)
+
+	// --- Background jobs ---
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	var background sync.WaitGroup
+
+	// Regenerate the CRL before it expires.
+	background.Go(func() { crlUsecase.Run(backgroundCtx) })
+
+	// Expire the overdue device commands, and publish the queued ones again.
+	background.Go(func() { deviceCommandUsecase.Run(backgroundCtx) })
+
+	// Send the next waves of the running firmware campaigns, and fail the overdue updates.
+	background.Go(func() { firmwareCampaignUsecase.Run(backgroundCtx) })
+
+	// Flag the connected devices that have gone unheard as STALE.
+	background.Go(func() { devicePresenceUsecase.Run(backgroundCtx) })
+
+	// Write the sensor data received over MQTT in batches.
+	// It is stopped after the subscriber, so that the messages received until then are written.
+	ingestionCtx, stopIngestion := context.WithCancel(context.Background())
+	defer stopIngestion()
+
+	var ingestion sync.WaitGroup
+
+	ingestion.Go(func() { ingestionUsecase.Run(ingestionCtx) })
+
+	// Run the embedded MQTT broker if MQTT_BROKER_ADDR is set, e.g. ":8883".
+	// Devices connect to it directly, and their sensor data is ingested without an external broker.
+	brokerAddr := os.Getenv("MQTT_BROKER_ADDR")
+	if brokerAddr != "" {
+		broker := newMQTTBroker(
+			brokerAddr, ca, deviceConnectionUsecase, devicePresenceUsecase, ingestionUsecase, deviceTwinUsecase,
+			deviceCommandUsecase, firmwareCampaignUsecase,
+		)
+		devicePublisher.Attach(broker)
+
+		background.Go(func() {
+			err := broker.Run(backgroundCtx)
+			if err != nil {
+				log.Printf("MQTT broker stopped: %v", err)
+			}
+		})
+	}
+
+	// Subscribe to the sensor data of the devices on an external broker if MQTT_BROKER_URL is set.
+	// The API keeps running without the worker, e.g. if the client certificate of the backend is not issued yet.
+	subscriber, err := newMQTTSubscriber(
+		os.Getenv("MQTT_BROKER_URL"), ingestionUsecase, deviceTwinUsecase, deviceCommandUsecase,
+		firmwareCampaignUsecase,
+	)
+	if err != nil {
+		log.Printf("MQTT worker is disabled: %v", err)
+	} else {
+		devicePublisher.Attach(subscriber)
+		background.Go(func() {
+			err := subscriber.Run(backgroundCtx)
+			if err != nil {
+				log.Printf("MQTT subscriber stopped: %v", err)
+			}
+		})
+	}
+
+	// Device onboarding endpoint
+	router.POST("/api/provision", provisioningHandler.Provision)
+
 	// --- Graceful shutdown of the server ---
 	srv := &http.Server{ //nolint:exhaustruct
 		Addr:              defaultServerPort,
@@ -104,6 +423,10 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopBackground()
+	background.Wait()
+	stopIngestion()
+	ingestion.Wait()
 
 	// Shutdown process with a timeout context.
 	ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
@@ -118,3 +441,159 @@ func main() {
 
 	log.Println("Server exiting")
 }
+
+// openDatabase connects to the PostgreSQL database whose DSN is in the environment variable dsnKey
+// and configures its connection pool. It exits the process if the database cannot be opened.
+func openDatabase(dsnKey string) (*gorm.DB, *sql.DB) {
+	dsn := os.Getenv(dsnKey)
+	if dsn == "" {
+		log.Fatalf("environment variable %s is not set", dsnKey)
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		log.Fatalf("failed to connect database (%s): %v", dsnKey, err)
+	}
+
+	// --- Configure database connection pool ---
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("failed to get underlying sql.DB (%s): %v", dsnKey, err)
+	}
+
+	sqlDB.SetMaxIdleConns(dbMaxIdleConns)
+	sqlDB.SetMaxOpenConns(dbMaxOpenConns)
+	sqlDB.SetConnMaxLifetime(time.Hour)
+
+	return db, sqlDB
+}
+
+// newMQTTBroker creates the embedded MQTT broker. Its server certificate is issued from the CA
+// for the host names in MQTT_BROKER_HOSTS, so devices verify it with the CA chain they received at provisioning.
+// It exits the process on failure.
+func newMQTTBroker(
+	addr string,
+	ca *pki.CA,
+	deviceConnectionUsecase usecase.DeviceConnectionUsecase,
+	devicePresenceUsecase usecase.DevicePresenceUsecase,
+	ingestionUsecase usecase.IngestionUsecase,
+	deviceTwinUsecase usecase.DeviceTwinUsecase,
+	deviceCommandUsecase usecase.DeviceCommandUsecase,
+	firmwareCampaignUsecase usecase.FirmwareCampaignUsecase,
+) *mqtt.Broker {
+	serverCert, err := pki.NewServerCertificate(
+		ca,
+		strings.Split(getEnv("MQTT_BROKER_HOSTS", "localhost,backend,mqtt-broker"), ","),
+		getEnvDuration("MQTT_BROKER_CERT_VALIDITY", pki.DefaultServerCertValidity),
+	)
+	if err != nil {
+		log.Fatalf("failed to issue MQTT broker certificate: %v", err)
+	}
+
+	broker, err := mqtt.NewBroker(mqtt.BrokerConfig{
+		Addr: addr,
+		TLSConfig: &tls.Config{ //nolint:exhaustruct
+			GetCertificate: serverCert.GetCertificate,
+			ClientCAs:      ca.ClientCAs(),
+			MinVersion:     tls.VersionTLS12,
+		},
+		TelemetryTopic: getEnv("MQTT_TOPIC", mqtt.DefaultTopic),
+		MaxPacketSize:  mqtt.DefaultMaxPacketSize,
+	}, deviceConnectionUsecase, devicePresenceUsecase, ingestionUsecase, deviceTwinUsecase, deviceCommandUsecase,
+		firmwareCampaignUsecase)
+	if err != nil {
+		log.Fatalf("failed to create MQTT broker: %v", err)
+	}
+
+	return broker
+}
+
+// newMQTTSubscriber creates the subscriber of the sensor data, twin, command response and firmware progress topics.
+// For TLS broker URLs such as "tls://", it connects with the client certificate in MQTT_CERT_PATH and MQTT_KEY_PATH,
+// and verifies the broker with MQTT_CA_PATH (default: the platform CA).
+func newMQTTSubscriber(
+	brokerURL string,
+	ingestionUsecase usecase.IngestionUsecase,
+	deviceTwinUsecase usecase.DeviceTwinUsecase,
+	deviceCommandUsecase usecase.DeviceCommandUsecase,
+	firmwareCampaignUsecase usecase.FirmwareCampaignUsecase,
+) (*mqtt.Subscriber, error) {
+	if brokerURL == "" {
+		return nil, errMQTTBrokerURLNotSet
+	}
+
+	config := mqtt.SubscriberConfig{
+		BrokerURL: brokerURL,
+		ClientID:  getEnv("MQTT_CLIENT_ID", mqtt.DefaultClientID),
+		Topic:     getEnv("MQTT_TOPIC", mqtt.DefaultTopic),
+		QoS:       mqtt.DefaultQoS,
+		TLSConfig: nil,
+	}
+
+	scheme, _, _ := strings.Cut(brokerURL, "://")
+	if slices.Contains([]string{"tls", "ssl", "mqtts", "tcps"}, scheme) {
+		tlsConfig, err := mqtt.LoadTLSConfig(
+			getEnv("MQTT_CA_PATH", getEnv("CA_CERT_PATH", pki.DefaultCertPath)),
+			getEnv("MQTT_CERT_PATH", mqtt.DefaultCertPath),
+			getEnv("MQTT_KEY_PATH", mqtt.DefaultKeyPath),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load MQTT TLS config: %w", err)
+		}
+
+		config.TLSConfig = tlsConfig
+	}
+
+	subscriber, err := mqtt.NewSubscriber(
+		config, ingestionUsecase, deviceTwinUsecase, deviceCommandUsecase, firmwareCampaignUsecase,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MQTT subscriber: %w", err)
+	}
+
+	return subscriber, nil
+}
+
+// getEnv returns the value of the environment variable, or fallback if it is not set.
+func getEnv(key, fallback string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
+
+// getEnvDuration returns the duration in the environment variable, or fallback if it is not set.
+// It exits the process if the value is not a valid duration (e.g. "24h").
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+
+	return duration
+}
+
+// getEnvBool returns the boolean in the environment variable, or fallback if it is not set.
+// It exits the process if the value is not a valid boolean (e.g. "true").
+func getEnvBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+
+	return enabled
+}
@@ -0,0 +1,76 @@
+// Package campaignstatus provides a value object for the status of a firmware rollout campaign.
+package campaignstatus
+
+import (
+	"errors"
+
+	"backend/internal/domain/VO/statemachine"
+)
+
+// Status is a value object representing the status of a firmware rollout campaign.
+// It is stored as a string in the `firmware_campaigns.status` column.
+type Status string
+
+const (
+	// Running is the initial status of a campaign, whose waves are rolled out one after the other.
+	Running Status = "RUNNING"
+	// Paused is the status of a campaign that an operator or the failure threshold has stopped.
+	// The devices already updating carry on, but no further wave is started until it is resumed.
+	Paused Status = "PAUSED"
+	// Completed is the terminal status of a campaign whose devices have all been updated or have failed.
+	Completed Status = "COMPLETED"
+	// Cancelled is the terminal status of a campaign an operator has stopped for good.
+	Cancelled Status = "CANCELLED"
+)
+
+var (
+	// ErrInvalidStatus is returned when a string is not a known campaign status.
+	ErrInvalidStatus = errors.New("invalid campaign status")
+	// ErrInvalidTransition is returned when a status transition is not allowed.
+	ErrInvalidTransition = errors.New("invalid campaign status transition")
+)
+
+// transitions defines the allowed status transitions.
+// COMPLETED and CANCELLED are terminal, so they have no outgoing transitions.
+//
+//nolint:gochecknoglobals
+var transitions = statemachine.New(ErrInvalidStatus, ErrInvalidTransition, map[Status][]Status{
+	Running:   {Paused, Completed, Cancelled},
+	Paused:    {Running, Cancelled},
+	Completed: {}, // terminal
+	Cancelled: {}, // terminal
+})
+
+// TransitionError is returned when a transition between two statuses is not allowed.
+// It wraps ErrInvalidTransition, so it can be checked with errors.Is.
+type TransitionError = statemachine.TransitionError[Status]
+
+// Parse parses a status from a string.
+func Parse(s string) (Status, error) {
+	return transitions.Parse(s)
+}
+
+// IsValid reports whether the status is a known campaign status.
+func (s Status) IsValid() bool {
+	return transitions.IsValid(s)
+}
+
+// CanTransitionTo reports whether the status can transition to the next status.
+func (s Status) CanTransitionTo(next Status) bool {
+	return transitions.CanTransition(s, next)
+}
+
+// TransitionTo returns the next status if the transition is allowed.
+// Otherwise, it returns a *TransitionError.
+func (s Status) TransitionTo(next Status) (Status, error) {
+	return transitions.Transition(s, next)
+}
+
+// IsTerminal reports whether no further transitions are possible from the status.
+func (s Status) IsTerminal() bool {
+	return transitions.IsTerminal(s)
+}
+
+func (s Status) String() string {
+	return string(s)
+}
@@ -0,0 +1,98 @@
+package campaignstatus_test
+
+import (
+	"errors"
+	"testing"
+
+	"backend/internal/domain/VO/campaignstatus"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid status", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := campaignstatus.Parse("PAUSED")
+		if err != nil {
+			t.Fatalf("Parse() returned an error for a valid status: %v", err)
+		}
+
+		if got != campaignstatus.Paused {
+			t.Errorf("Parse() = %v, want %v", got, campaignstatus.Paused)
+		}
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := campaignstatus.Parse("paused")
+		if !errors.Is(err, campaignstatus.ErrInvalidStatus) {
+			t.Errorf("Parse() error = %v, want %v", err, campaignstatus.ErrInvalidStatus)
+		}
+	})
+}
+
+func TestTransitionTo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		from    campaignstatus.Status
+		to      campaignstatus.Status
+		wantErr bool
+	}{
+		{name: "pause", from: campaignstatus.Running, to: campaignstatus.Paused, wantErr: false},
+		{name: "resume", from: campaignstatus.Paused, to: campaignstatus.Running, wantErr: false},
+		{name: "complete", from: campaignstatus.Running, to: campaignstatus.Completed, wantErr: false},
+		{name: "cancel running", from: campaignstatus.Running, to: campaignstatus.Cancelled, wantErr: false},
+		{name: "cancel paused", from: campaignstatus.Paused, to: campaignstatus.Cancelled, wantErr: false},
+		{name: "complete paused", from: campaignstatus.Paused, to: campaignstatus.Completed, wantErr: true},
+		{name: "pause twice", from: campaignstatus.Paused, to: campaignstatus.Paused, wantErr: true},
+		{name: "completed is terminal", from: campaignstatus.Completed, to: campaignstatus.Running, wantErr: true},
+		{name: "cancelled is terminal", from: campaignstatus.Cancelled, to: campaignstatus.Running, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.from.TransitionTo(tt.to)
+
+			if tt.wantErr {
+				var transitionErr *campaignstatus.TransitionError
+				if !errors.As(err, &transitionErr) || !errors.Is(err, campaignstatus.ErrInvalidTransition) {
+					t.Fatalf("TransitionTo() error = %v, want a *TransitionError", err)
+				}
+
+				if got != tt.from {
+					t.Errorf("TransitionTo() = %v, want unchanged %v", got, tt.from)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("TransitionTo() unexpected error: %v", err)
+			}
+
+			if got != tt.to {
+				t.Errorf("TransitionTo() = %v, want %v", got, tt.to)
+			}
+		})
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	t.Parallel()
+
+	for _, status := range []campaignstatus.Status{campaignstatus.Completed, campaignstatus.Cancelled} {
+		if !status.IsTerminal() {
+			t.Errorf("%s should be terminal", status)
+		}
+	}
+
+	if campaignstatus.Paused.IsTerminal() {
+		t.Error("PAUSED should not be terminal")
+	}
+}
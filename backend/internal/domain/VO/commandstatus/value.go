@@ -0,0 +1,79 @@
+// Package commandstatus provides a value object for the delivery status of a device command.
+package commandstatus
+
+import (
+	"errors"
+
+	"backend/internal/domain/VO/statemachine"
+)
+
+// Status is a value object representing the delivery status of a command sent to a device.
+// It is stored as a string in the `device_commands.status` column.
+type Status string
+
+const (
+	// Queued is the initial status of a command that has not been handed to a broker yet.
+	Queued Status = "QUEUED"
+	// Sent is the status of a command that has been published to the device, but not acknowledged yet.
+	Sent Status = "SENT"
+	// Acked is the terminal status of a command the device has executed.
+	Acked Status = "ACKED"
+	// Failed is the terminal status of a command the device could not execute.
+	Failed Status = "FAILED"
+	// Expired is the terminal status of a command that was not acknowledged within its TTL.
+	Expired Status = "EXPIRED"
+)
+
+var (
+	// ErrInvalidStatus is returned when a string is not a known command status.
+	ErrInvalidStatus = errors.New("invalid command status")
+	// ErrInvalidTransition is returned when a status transition is not allowed.
+	ErrInvalidTransition = errors.New("invalid command status transition")
+)
+
+// transitions defines the allowed status transitions.
+// ACKED, FAILED and EXPIRED are terminal, so they have no outgoing transitions.
+//
+//nolint:gochecknoglobals
+var transitions = statemachine.New(ErrInvalidStatus, ErrInvalidTransition, map[Status][]Status{
+	// A queued command may be acknowledged before it is marked as sent, if the device answers quickly.
+	Queued:  {Sent, Acked, Failed, Expired},
+	Sent:    {Acked, Failed, Expired},
+	Acked:   {}, // terminal
+	Failed:  {}, // terminal
+	Expired: {}, // terminal
+})
+
+// TransitionError is returned when a transition between two statuses is not allowed.
+// It wraps ErrInvalidTransition, so it can be checked with errors.Is.
+type TransitionError = statemachine.TransitionError[Status]
+
+// Parse parses a status from a string.
+func Parse(s string) (Status, error) {
+	return transitions.Parse(s)
+}
+
+// IsValid reports whether the status is a known command status.
+func (s Status) IsValid() bool {
+	return transitions.IsValid(s)
+}
+
+// CanTransitionTo reports whether the status can transition to the next status.
+func (s Status) CanTransitionTo(next Status) bool {
+	return transitions.CanTransition(s, next)
+}
+
+// TransitionTo returns the next status if the transition is allowed.
+// Otherwise, it returns a *TransitionError.
+func (s Status) TransitionTo(next Status) (Status, error) {
+	return transitions.Transition(s, next)
+}
+
+// IsTerminal reports whether no further transitions are possible from the status.
+func (s Status) IsTerminal() bool {
+	return transitions.IsTerminal(s)
+}
+
+func (s Status) String() string {
+	return string(s)
+}
@@ -0,0 +1,99 @@
+package commandstatus_test
+
+import (
+	"errors"
+	"testing"
+
+	"backend/internal/domain/VO/commandstatus"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid status", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := commandstatus.Parse("ACKED")
+		if err != nil {
+			t.Fatalf("Parse() returned an error for a valid status: %v", err)
+		}
+
+		if got != commandstatus.Acked {
+			t.Errorf("Parse() = %v, want %v", got, commandstatus.Acked)
+		}
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := commandstatus.Parse("acked")
+		if !errors.Is(err, commandstatus.ErrInvalidStatus) {
+			t.Errorf("Parse() error = %v, want %v", err, commandstatus.ErrInvalidStatus)
+		}
+	})
+}
+
+func TestTransitionTo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		from    commandstatus.Status
+		to      commandstatus.Status
+		wantErr bool
+	}{
+		{name: "send", from: commandstatus.Queued, to: commandstatus.Sent, wantErr: false},
+		{name: "ack", from: commandstatus.Sent, to: commandstatus.Acked, wantErr: false},
+		{name: "fail", from: commandstatus.Sent, to: commandstatus.Failed, wantErr: false},
+		{name: "ack before sent", from: commandstatus.Queued, to: commandstatus.Acked, wantErr: false},
+		{name: "expire queued", from: commandstatus.Queued, to: commandstatus.Expired, wantErr: false},
+		{name: "expire sent", from: commandstatus.Sent, to: commandstatus.Expired, wantErr: false},
+		{name: "back to queued", from: commandstatus.Sent, to: commandstatus.Queued, wantErr: true},
+		{name: "send twice", from: commandstatus.Sent, to: commandstatus.Sent, wantErr: true},
+		{name: "acked is terminal", from: commandstatus.Acked, to: commandstatus.Failed, wantErr: true},
+		{name: "expired is terminal", from: commandstatus.Expired, to: commandstatus.Acked, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.from.TransitionTo(tt.to)
+
+			if tt.wantErr {
+				var transitionErr *commandstatus.TransitionError
+				if !errors.As(err, &transitionErr) || !errors.Is(err, commandstatus.ErrInvalidTransition) {
+					t.Fatalf("TransitionTo() error = %v, want a *TransitionError", err)
+				}
+
+				if got != tt.from {
+					t.Errorf("TransitionTo() = %v, want unchanged %v", got, tt.from)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("TransitionTo() unexpected error: %v", err)
+			}
+
+			if got != tt.to {
+				t.Errorf("TransitionTo() = %v, want %v", got, tt.to)
+			}
+		})
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	t.Parallel()
+
+	for _, status := range []commandstatus.Status{commandstatus.Acked, commandstatus.Failed, commandstatus.Expired} {
+		if !status.IsTerminal() {
+			t.Errorf("%s should be terminal", status)
+		}
+	}
+
+	if commandstatus.Sent.IsTerminal() {
+		t.Error("SENT should not be terminal")
+	}
+}
@@ -0,0 +1,54 @@
+// Package connectionstate provides a value object for the connection state of a device.
+package connectionstate
+
+import (
+	"errors"
+	"fmt"
+)
+
+// State is a value object representing whether a device is connected to the platform.
+// It is stored as a string in the `devices.connection_state` column.
+//
+// Unlike the lifecycle status, the state follows what the broker observes, so any state can follow any other.
+type State string
+
+const (
+	// Connected is the state of a device that has connected and been heard from recently.
+	Connected State = "CONNECTED"
+	// Disconnected is the state of a device that has never connected or has closed its connection.
+	Disconnected State = "DISCONNECTED"
+	// Stale is the state of a connected device that has not been heard from within the heartbeat interval.
+	Stale State = "STALE"
+)
+
+// ErrInvalidState is returned when a string is not a known connection state.
+var ErrInvalidState = errors.New("invalid connection state")
+
+// Parse parses a state from a string.
+func Parse(s string) (State, error) {
+	state := State(s)
+	if !state.IsValid() {
+		return "", fmt.Errorf("%w: %q", ErrInvalidState, s)
+	}
+
+	return state, nil
+}
+
+// IsValid reports whether the state is a known connection state.
+func (s State) IsValid() bool {
+	switch s {
+	case Connected, Disconnected, Stale:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsOnline reports whether the device is considered online, i.e. connected and not stale.
+func (s State) IsOnline() bool {
+	return s == Connected
+}
+
+func (s State) String() string {
+	return string(s)
+}
@@ -0,0 +1,57 @@
+package connectionstate_test
+
+import (
+	"errors"
+	"testing"
+
+	"backend/internal/domain/VO/connectionstate"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid state", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := connectionstate.Parse("STALE")
+		if err != nil {
+			t.Fatalf("Parse() returned an error for a valid state: %v", err)
+		}
+
+		if got != connectionstate.Stale {
+			t.Errorf("Parse() = %v, want %v", got, connectionstate.Stale)
+		}
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := connectionstate.Parse("connected")
+		if !errors.Is(err, connectionstate.ErrInvalidState) {
+			t.Errorf("Parse() error = %v, want %v", err, connectionstate.ErrInvalidState)
+		}
+	})
+}
+
+func TestIsOnline(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		state connectionstate.State
+		want  bool
+	}{
+		{state: connectionstate.Connected, want: true},
+		{state: connectionstate.Disconnected, want: false},
+		{state: connectionstate.Stale, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state.String(), func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.state.IsOnline(); got != tt.want {
+				t.Errorf("IsOnline() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
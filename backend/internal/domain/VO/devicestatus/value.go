@@ -0,0 +1,75 @@
+// Package devicestatus provides a value object for the lifecycle status of a device.
+package devicestatus
+
+import (
+	"errors"
+
+	"backend/internal/domain/VO/statemachine"
+)
+
+// Status is a value object representing the lifecycle status of a device.
+// It is stored as a string in the `devices.status` column.
+type Status string
+
+const (
+	// Unregistered is the initial status of a device that has not been provisioned yet.
+	Unregistered Status = "UNREGISTERED"
+	// Active is the status of a provisioned device that is allowed to connect.
+	Active Status = "ACTIVE"
+	// Suspended is the status of a device that is temporarily not allowed to connect.
+	Suspended Status = "SUSPENDED"
+	// Revoked is the terminal status of a device that is permanently decommissioned.
+	Revoked Status = "REVOKED"
+)
+
+var (
+	// ErrInvalidStatus is returned when a string is not a known device status.
+	ErrInvalidStatus = errors.New("invalid device status")
+	// ErrInvalidTransition is returned when a status transition is not allowed.
+	ErrInvalidTransition = errors.New("invalid device status transition")
+)
+
+// transitions defines the allowed status transitions.
+// REVOKED is terminal, so it has no outgoing transitions.
+//
+//nolint:gochecknoglobals
+var transitions = statemachine.New(ErrInvalidStatus, ErrInvalidTransition, map[Status][]Status{
+	Unregistered: {Active, Revoked},    // provisioning, or decommissioning before use
+	Active:       {Suspended, Revoked}, // temporary suspension or decommissioning
+	Suspended:    {Active, Revoked},    // resuming or decommissioning
+	Revoked:      {},                   // terminal
+})
+
+// TransitionError is returned when a transition between two statuses is not allowed.
+// It wraps ErrInvalidTransition, so it can be checked with errors.Is.
+type TransitionError = statemachine.TransitionError[Status]
+
+// Parse parses a status from a string.
+func Parse(s string) (Status, error) {
+	return transitions.Parse(s)
+}
+
+// IsValid reports whether the status is a known device status.
+func (s Status) IsValid() bool {
+	return transitions.IsValid(s)
+}
+
+// CanTransitionTo reports whether the status can transition to the next status.
+func (s Status) CanTransitionTo(next Status) bool {
+	return transitions.CanTransition(s, next)
+}
+
+// TransitionTo returns the next status if the transition is allowed.
+// Otherwise, it returns a *TransitionError.
+func (s Status) TransitionTo(next Status) (Status, error) {
+	return transitions.Transition(s, next)
+}
+
+// IsTerminal reports whether no further transitions are possible from the status.
+func (s Status) IsTerminal() bool {
+	return transitions.IsTerminal(s)
+}
+
+func (s Status) String() string {
+	return string(s)
+}
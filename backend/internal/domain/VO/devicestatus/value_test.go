@@ -0,0 +1,106 @@
+package devicestatus_test
+
+import (
+	"errors"
+	"testing"
+
+	"backend/internal/domain/VO/devicestatus"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid status", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := devicestatus.Parse("ACTIVE")
+		if err != nil {
+			t.Fatalf("Parse() returned an error for a valid status: %v", err)
+		}
+
+		if got != devicestatus.Active {
+			t.Errorf("Parse() = %v, want %v", got, devicestatus.Active)
+		}
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := devicestatus.Parse("active")
+		if !errors.Is(err, devicestatus.ErrInvalidStatus) {
+			t.Errorf("Parse() error = %v, want %v", err, devicestatus.ErrInvalidStatus)
+		}
+	})
+}
+
+func TestTransitionTo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		from    devicestatus.Status
+		to      devicestatus.Status
+		wantErr bool
+	}{
+		{name: "provisioning", from: devicestatus.Unregistered, to: devicestatus.Active, wantErr: false},
+		{name: "suspend", from: devicestatus.Active, to: devicestatus.Suspended, wantErr: false},
+		{name: "resume", from: devicestatus.Suspended, to: devicestatus.Active, wantErr: false},
+		{name: "revoke unregistered", from: devicestatus.Unregistered, to: devicestatus.Revoked, wantErr: false},
+		{name: "revoke active", from: devicestatus.Active, to: devicestatus.Revoked, wantErr: false},
+		{name: "revoke suspended", from: devicestatus.Suspended, to: devicestatus.Revoked, wantErr: false},
+		{name: "suspend unregistered", from: devicestatus.Unregistered, to: devicestatus.Suspended, wantErr: true},
+		{name: "activate active", from: devicestatus.Active, to: devicestatus.Active, wantErr: true},
+		{name: "back to unregistered", from: devicestatus.Active, to: devicestatus.Unregistered, wantErr: true},
+		{name: "revoked is terminal", from: devicestatus.Revoked, to: devicestatus.Active, wantErr: true},
+		{name: "revoke revoked", from: devicestatus.Revoked, to: devicestatus.Revoked, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.from.TransitionTo(tt.to)
+
+			if tt.wantErr {
+				if !errors.Is(err, devicestatus.ErrInvalidTransition) {
+					t.Fatalf("TransitionTo() error = %v, want %v", err, devicestatus.ErrInvalidTransition)
+				}
+
+				var transitionErr *devicestatus.TransitionError
+				if !errors.As(err, &transitionErr) {
+					t.Fatalf("TransitionTo() error is not a *TransitionError: %v", err)
+				}
+
+				if transitionErr.From != tt.from || transitionErr.To != tt.to {
+					t.Errorf("TransitionError = %v -> %v, want %v -> %v", transitionErr.From, transitionErr.To, tt.from, tt.to)
+				}
+
+				if got != tt.from {
+					t.Errorf("TransitionTo() = %v, want unchanged %v", got, tt.from)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("TransitionTo() unexpected error: %v", err)
+			}
+
+			if got != tt.to {
+				t.Errorf("TransitionTo() = %v, want %v", got, tt.to)
+			}
+		})
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	t.Parallel()
+
+	if !devicestatus.Revoked.IsTerminal() {
+		t.Error("REVOKED should be terminal")
+	}
+
+	if devicestatus.Active.IsTerminal() {
+		t.Error("ACTIVE should not be terminal")
+	}
+}
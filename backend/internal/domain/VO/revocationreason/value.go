@@ -0,0 +1,96 @@
+// Package revocationreason provides a value object for the reason a certificate is revoked.
+package revocationreason
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Reason is a value object representing a CRLReason code defined in RFC 5280, section 5.3.1.
+// It is stored as a number in the `certificates.revocation_reason` column.
+type Reason int
+
+const (
+	// Unspecified is used when no other reason applies.
+	Unspecified Reason = 0
+	// KeyCompromise means the private key of the device is known or suspected to be compromised.
+	KeyCompromise Reason = 1
+	// CACompromise means the private key of the CA is known or suspected to be compromised.
+	CACompromise Reason = 2
+	// AffiliationChanged means the subject information of the certificate has changed.
+	AffiliationChanged Reason = 3
+	// Superseded means the certificate has been replaced by a new one.
+	Superseded Reason = 4
+	// CessationOfOperation means the certificate is no longer needed, e.g. the device is decommissioned.
+	CessationOfOperation Reason = 5
+	// PrivilegeWithdrawn means the device is no longer allowed to use the certificate.
+	PrivilegeWithdrawn Reason = 9
+	// AACompromise means the attribute authority is known or suspected to be compromised.
+	AACompromise Reason = 10
+)
+
+// ErrInvalidReason is returned when a value is not an accepted revocation reason.
+var ErrInvalidReason = errors.New("invalid revocation reason")
+
+// names maps the accepted reasons to their names in RFC 5280.
+// certificateHold (6) and removeFromCRL (8) are not accepted, as revocation is permanent.
+//
+//nolint:gochecknoglobals
+var names = map[Reason]string{
+	Unspecified:          "unspecified",
+	KeyCompromise:        "keyCompromise",
+	CACompromise:         "cACompromise",
+	AffiliationChanged:   "affiliationChanged",
+	Superseded:           "superseded",
+	CessationOfOperation: "cessationOfOperation",
+	PrivilegeWithdrawn:   "privilegeWithdrawn",
+	AACompromise:         "aACompromise",
+}
+
+// Parse parses a reason from its RFC 5280 name (e.g. "keyCompromise") or its numeric code (e.g. "1").
+// An empty string is parsed as Unspecified.
+func Parse(s string) (Reason, error) {
+	if s == "" {
+		return Unspecified, nil
+	}
+
+	code, err := strconv.Atoi(s)
+	if err == nil {
+		reason := Reason(code)
+		if !reason.IsValid() {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidReason, s)
+		}
+
+		return reason, nil
+	}
+
+	for reason, name := range names {
+		if name == s {
+			return reason, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %q", ErrInvalidReason, s)
+}
+
+// IsValid reports whether the reason is an accepted revocation reason.
+func (r Reason) IsValid() bool {
+	_, ok := names[r]
+
+	return ok
+}
+
+// Code returns the numeric CRLReason code.
+func (r Reason) Code() int {
+	return int(r)
+}
+
+func (r Reason) String() string {
+	name, ok := names[r]
+	if !ok {
+		return strconv.Itoa(int(r))
+	}
+
+	return name
+}
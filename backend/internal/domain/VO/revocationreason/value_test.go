@@ -0,0 +1,65 @@
+package revocationreason_test
+
+import (
+	"errors"
+	"testing"
+
+	"backend/internal/domain/VO/revocationreason"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		want    revocationreason.Reason
+		wantErr bool
+	}{
+		{name: "empty is unspecified", input: "", want: revocationreason.Unspecified, wantErr: false},
+		{name: "by name", input: "keyCompromise", want: revocationreason.KeyCompromise, wantErr: false},
+		{name: "by code", input: "5", want: revocationreason.CessationOfOperation, wantErr: false},
+		{name: "certificateHold is rejected", input: "6", want: 0, wantErr: true},
+		{name: "removeFromCRL is rejected", input: "8", want: 0, wantErr: true},
+		{name: "unknown name", input: "KeyCompromise", want: 0, wantErr: true},
+		{name: "out of range code", input: "11", want: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := revocationreason.Parse(tt.input)
+
+			if tt.wantErr {
+				if !errors.Is(err, revocationreason.ErrInvalidReason) {
+					t.Fatalf("Parse() error = %v, want %v", err, revocationreason.ErrInvalidReason)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	t.Parallel()
+
+	name := revocationreason.Superseded.String()
+	if name != "superseded" {
+		t.Errorf("String() = %q, want %q", name, "superseded")
+	}
+
+	code := revocationreason.Superseded.Code()
+	if code != 4 {
+		t.Errorf("Code() = %d, want %d", code, 4)
+	}
+}
@@ -0,0 +1,80 @@
+// Package statemachine provides the transition table shared by the status value objects.
+package statemachine
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Table defines the allowed transitions between the statuses of a value object.
+// A status with no outgoing transitions is terminal.
+type Table[S ~string] struct {
+	transitions          map[S][]S
+	errInvalidStatus     error
+	errInvalidTransition error
+}
+
+// New creates a Table from the allowed transitions of every status.
+// Parse wraps errInvalidStatus, and a transition that is not allowed wraps errInvalidTransition.
+func New[S ~string](errInvalidStatus, errInvalidTransition error, transitions map[S][]S) Table[S] {
+	return Table[S]{
+		transitions:          transitions,
+		errInvalidStatus:     errInvalidStatus,
+		errInvalidTransition: errInvalidTransition,
+	}
+}
+
+// TransitionError is returned when a transition between two statuses is not allowed.
+// It wraps Err, the invalid transition error of the status, so it can be checked with errors.Is.
+type TransitionError[S ~string] struct {
+	From S
+	To   S
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *TransitionError[S]) Error() string {
+	return fmt.Sprintf("%s: %s -> %s", e.Err.Error(), e.From, e.To)
+}
+
+// Unwrap returns Err.
+func (e *TransitionError[S]) Unwrap() error {
+	return e.Err
+}
+
+// Parse parses a status from a string.
+func (t Table[S]) Parse(s string) (S, error) {
+	status := S(s)
+	if !t.IsValid(status) {
+		return "", fmt.Errorf("%w: %q", t.errInvalidStatus, s)
+	}
+
+	return status, nil
+}
+
+// IsValid reports whether the status is in the table.
+func (t Table[S]) IsValid(status S) bool {
+	_, ok := t.transitions[status]
+
+	return ok
+}
+
+// CanTransition reports whether the status from can transition to the status to.
+func (t Table[S]) CanTransition(from, to S) bool {
+	return slices.Contains(t.transitions[from], to)
+}
+
+// Transition returns the status to if the transition is allowed.
+// Otherwise, it returns the status from and a *TransitionError.
+func (t Table[S]) Transition(from, to S) (S, error) {
+	if !t.CanTransition(from, to) {
+		return from, &TransitionError[S]{From: from, To: to, Err: t.errInvalidTransition}
+	}
+
+	return to, nil
+}
+
+// IsTerminal reports whether no further transitions are possible from the status.
+func (t Table[S]) IsTerminal(status S) bool {
+	return t.IsValid(status) && len(t.transitions[status]) == 0
+}
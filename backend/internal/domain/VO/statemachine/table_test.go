@@ -0,0 +1,73 @@
+package statemachine_test
+
+import (
+	"errors"
+	"testing"
+
+	"backend/internal/domain/VO/statemachine"
+)
+
+type status string
+
+var (
+	errInvalidStatus     = errors.New("invalid status")
+	errInvalidTransition = errors.New("invalid status transition")
+)
+
+func newTable() statemachine.Table[status] {
+	return statemachine.New(errInvalidStatus, errInvalidTransition, map[status][]status{
+		"OPEN":   {"CLOSED"},
+		"CLOSED": {},
+	})
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	table := newTable()
+
+	got, err := table.Parse("OPEN")
+	if err != nil || got != "OPEN" {
+		t.Errorf("Parse(OPEN) = %v, %v, want OPEN", got, err)
+	}
+
+	_, err = table.Parse("open")
+	if !errors.Is(err, errInvalidStatus) {
+		t.Errorf("Parse(open) error = %v, want %v", err, errInvalidStatus)
+	}
+}
+
+func TestTransition(t *testing.T) {
+	t.Parallel()
+
+	table := newTable()
+
+	got, err := table.Transition("OPEN", "CLOSED")
+	if err != nil || got != "CLOSED" {
+		t.Errorf("Transition(OPEN, CLOSED) = %v, %v, want CLOSED", got, err)
+	}
+
+	got, err = table.Transition("CLOSED", "OPEN")
+
+	var transitionErr *statemachine.TransitionError[status]
+	if !errors.As(err, &transitionErr) || !errors.Is(err, errInvalidTransition) {
+		t.Fatalf("Transition(CLOSED, OPEN) error = %v, want a *TransitionError", err)
+	}
+
+	if got != "CLOSED" || transitionErr.From != "CLOSED" || transitionErr.To != "OPEN" {
+		t.Errorf("Transition(CLOSED, OPEN) = %v, %v, want CLOSED unchanged", got, err)
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	t.Parallel()
+
+	table := newTable()
+
+	tests := map[status]bool{"OPEN": false, "CLOSED": true, "UNKNOWN": false}
+	for s, want := range tests {
+		if got := table.IsTerminal(s); got != want {
+			t.Errorf("IsTerminal(%v) = %v, want %v", s, got, want)
+		}
+	}
+}
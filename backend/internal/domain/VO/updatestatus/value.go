@@ -0,0 +1,89 @@
+// Package updatestatus provides a value object for the status of the firmware update of a device in a campaign.
+package updatestatus
+
+import (
+	"errors"
+
+	"backend/internal/domain/VO/statemachine"
+)
+
+// Status is a value object representing the progress of the firmware update of a device.
+// It is stored as a string in the `firmware_updates.status` column.
+type Status string
+
+const (
+	// Pending is the initial status of an update whose wave has not started yet.
+	Pending Status = "PENDING"
+	// Sent is the status of an update whose command has been sent to the device, which has not reported yet.
+	Sent Status = "SENT"
+	// Downloading is the status of an update whose image the device reports to be downloading.
+	Downloading Status = "DOWNLOADING"
+	// Installing is the status of an update whose image the device reports to be installing.
+	Installing Status = "INSTALLING"
+	// Succeeded is the terminal status of an update the device has installed.
+	Succeeded Status = "SUCCEEDED"
+	// Failed is the terminal status of an update that could not be sent, that the device reported as failed,
+	// or that the device did not complete in time.
+	Failed Status = "FAILED"
+)
+
+var (
+	// ErrInvalidStatus is returned when a string is not a known update status.
+	ErrInvalidStatus = errors.New("invalid firmware update status")
+	// ErrInvalidTransition is returned when a status transition is not allowed.
+	ErrInvalidTransition = errors.New("invalid firmware update status transition")
+)
+
+// transitions defines the allowed status transitions.
+// DOWNLOADING and INSTALLING can transition to themselves, as the device reports its progress.
+// SUCCEEDED and FAILED are terminal, so they have no outgoing transitions.
+//
+//nolint:gochecknoglobals
+var transitions = statemachine.New(ErrInvalidStatus, ErrInvalidTransition, map[Status][]Status{
+	Pending: {Sent, Failed},
+	// The device may skip reporting the steps it goes through quickly.
+	Sent:        {Downloading, Installing, Succeeded, Failed},
+	Downloading: {Downloading, Installing, Succeeded, Failed},
+	Installing:  {Installing, Succeeded, Failed},
+	Succeeded:   {}, // terminal
+	Failed:      {}, // terminal
+})
+
+// TransitionError is returned when a transition between two statuses is not allowed.
+// It wraps ErrInvalidTransition, so it can be checked with errors.Is.
+type TransitionError = statemachine.TransitionError[Status]
+
+// Parse parses a status from a string.
+func Parse(s string) (Status, error) {
+	return transitions.Parse(s)
+}
+
+// IsValid reports whether the status is a known update status.
+func (s Status) IsValid() bool {
+	return transitions.IsValid(s)
+}
+
+// CanTransitionTo reports whether the status can transition to the next status.
+func (s Status) CanTransitionTo(next Status) bool {
+	return transitions.CanTransition(s, next)
+}
+
+// TransitionTo returns the next status if the transition is allowed.
+// Otherwise, it returns a *TransitionError.
+func (s Status) TransitionTo(next Status) (Status, error) {
+	return transitions.Transition(s, next)
+}
+
+// IsTerminal reports whether no further transitions are possible from the status.
+func (s Status) IsTerminal() bool {
+	return transitions.IsTerminal(s)
+}
+
+// IsInProgress reports whether the update has been sent to the device and is not finished yet.
+func (s Status) IsInProgress() bool {
+	return s.IsValid() && s != Pending && !s.IsTerminal()
+}
+
+func (s Status) String() string {
+	return string(s)
+}
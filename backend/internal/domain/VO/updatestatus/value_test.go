@@ -0,0 +1,107 @@
+package updatestatus_test
+
+import (
+	"errors"
+	"testing"
+
+	"backend/internal/domain/VO/updatestatus"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	t.Run("valid status", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := updatestatus.Parse("INSTALLING")
+		if err != nil {
+			t.Fatalf("Parse() returned an error for a valid status: %v", err)
+		}
+
+		if got != updatestatus.Installing {
+			t.Errorf("Parse() = %v, want %v", got, updatestatus.Installing)
+		}
+	})
+
+	t.Run("invalid status", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := updatestatus.Parse("installing")
+		if !errors.Is(err, updatestatus.ErrInvalidStatus) {
+			t.Errorf("Parse() error = %v, want %v", err, updatestatus.ErrInvalidStatus)
+		}
+	})
+}
+
+func TestTransitionTo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		from    updatestatus.Status
+		to      updatestatus.Status
+		wantErr bool
+	}{
+		{name: "send", from: updatestatus.Pending, to: updatestatus.Sent, wantErr: false},
+		{name: "fail to send", from: updatestatus.Pending, to: updatestatus.Failed, wantErr: false},
+		{name: "download", from: updatestatus.Sent, to: updatestatus.Downloading, wantErr: false},
+		{name: "download progress", from: updatestatus.Downloading, to: updatestatus.Downloading, wantErr: false},
+		{name: "install", from: updatestatus.Downloading, to: updatestatus.Installing, wantErr: false},
+		{name: "succeed", from: updatestatus.Installing, to: updatestatus.Succeeded, wantErr: false},
+		{name: "succeed without steps", from: updatestatus.Sent, to: updatestatus.Succeeded, wantErr: false},
+		{name: "fail", from: updatestatus.Installing, to: updatestatus.Failed, wantErr: false},
+		{name: "report before sent", from: updatestatus.Pending, to: updatestatus.Downloading, wantErr: true},
+		{name: "download after install", from: updatestatus.Installing, to: updatestatus.Downloading, wantErr: true},
+		{name: "succeeded is terminal", from: updatestatus.Succeeded, to: updatestatus.Failed, wantErr: true},
+		{name: "failed is terminal", from: updatestatus.Failed, to: updatestatus.Succeeded, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.from.TransitionTo(tt.to)
+
+			if tt.wantErr {
+				var transitionErr *updatestatus.TransitionError
+				if !errors.As(err, &transitionErr) || !errors.Is(err, updatestatus.ErrInvalidTransition) {
+					t.Fatalf("TransitionTo() error = %v, want a *TransitionError", err)
+				}
+
+				if got != tt.from {
+					t.Errorf("TransitionTo() = %v, want unchanged %v", got, tt.from)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("TransitionTo() unexpected error: %v", err)
+			}
+
+			if got != tt.to {
+				t.Errorf("TransitionTo() = %v, want %v", got, tt.to)
+			}
+		})
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	t.Parallel()
+
+	for _, status := range []updatestatus.Status{updatestatus.Succeeded, updatestatus.Failed} {
+		if !status.IsTerminal() || status.IsInProgress() {
+			t.Errorf("%s should be terminal", status)
+		}
+	}
+
+	for _, status := range []updatestatus.Status{updatestatus.Sent, updatestatus.Downloading, updatestatus.Installing} {
+		if status.IsTerminal() || !status.IsInProgress() {
+			t.Errorf("%s should be in progress", status)
+		}
+	}
+
+	if updatestatus.Pending.IsTerminal() || updatestatus.Pending.IsInProgress() {
+		t.Error("PENDING should be neither terminal nor in progress")
+	}
+}
@@ -0,0 +1,323 @@
+package entity
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditAction is the kind of operation recorded in an audit log entry.
+type AuditAction string
+
+const (
+	// AuditDeviceCreate records the registration of a device.
+	AuditDeviceCreate AuditAction = "DEVICE_CREATE"
+	// AuditDeviceUpdate records a change of the name or metadata of a device.
+	AuditDeviceUpdate AuditAction = "DEVICE_UPDATE"
+	// AuditDeviceDelete records the deletion of a device.
+	AuditDeviceDelete AuditAction = "DEVICE_DELETE"
+	// AuditDeviceStatusChange records a lifecycle status transition of a device.
+	AuditDeviceStatusChange AuditAction = "DEVICE_STATUS_CHANGE"
+	// AuditCertificateIssue records the issuance of a client certificate.
+	AuditCertificateIssue AuditAction = "CERT_ISSUE"
+	// AuditCertificateRevoke records the revocation of a client certificate.
+	AuditCertificateRevoke AuditAction = "CERT_REVOKE"
+	// AuditTokenCreate records the issuance of an enrollment token.
+	AuditTokenCreate AuditAction = "TOKEN_CREATE"
+	// AuditTokenUse records the consumption of an enrollment token by provisioning.
+	AuditTokenUse AuditAction = "TOKEN_USE"
+	// AuditTokenRevoke records the revocation of an enrollment token.
+	AuditTokenRevoke AuditAction = "TOKEN_REVOKE"
+	// AuditDeviceGroupCreate records the creation of a device group.
+	AuditDeviceGroupCreate AuditAction = "DEVICE_GROUP_CREATE"
+	// AuditDeviceGroupUpdate records a change of the name, description or rule of a device group.
+	AuditDeviceGroupUpdate AuditAction = "DEVICE_GROUP_UPDATE"
+	// AuditDeviceGroupDelete records the deletion of a device group.
+	AuditDeviceGroupDelete AuditAction = "DEVICE_GROUP_DELETE"
+	// AuditDeviceGroupMemberAdd records the addition of a device to a device group.
+	AuditDeviceGroupMemberAdd AuditAction = "DEVICE_GROUP_MEMBER_ADD"
+	// AuditDeviceGroupMemberRemove records the removal of a device from a device group.
+	AuditDeviceGroupMemberRemove AuditAction = "DEVICE_GROUP_MEMBER_REMOVE"
+	// AuditDeviceAttach records the attachment of a device to a parent, such as a gateway.
+	AuditDeviceAttach AuditAction = "DEVICE_ATTACH"
+	// AuditDeviceDetach records the detachment of a device from its parent.
+	AuditDeviceDetach AuditAction = "DEVICE_DETACH"
+	// AuditDeviceTwinUpdate records a change of the desired state of a device twin.
+	AuditDeviceTwinUpdate AuditAction = "DEVICE_TWIN_UPDATE"
+	// AuditDeviceCommandSend records a command sent to a device.
+	AuditDeviceCommandSend AuditAction = "DEVICE_COMMAND_SEND"
+	// AuditFirmwareUpload records the upload of a firmware artifact.
+	AuditFirmwareUpload AuditAction = "FIRMWARE_UPLOAD"
+	// AuditFirmwareDelete records the deletion of a firmware artifact.
+	AuditFirmwareDelete AuditAction = "FIRMWARE_DELETE"
+	// AuditFirmwareCampaignCreate records the creation of a firmware campaign.
+	AuditFirmwareCampaignCreate AuditAction = "FIRMWARE_CAMPAIGN_CREATE"
+	// AuditFirmwareCampaignPause records that a firmware campaign was paused, by an operator
+	// or because of its failure threshold.
+	AuditFirmwareCampaignPause AuditAction = "FIRMWARE_CAMPAIGN_PAUSE"
+	// AuditFirmwareCampaignResume records that a paused firmware campaign was resumed.
+	AuditFirmwareCampaignResume AuditAction = "FIRMWARE_CAMPAIGN_RESUME"
+	// AuditFirmwareCampaignCancel records the cancellation of a firmware campaign.
+	AuditFirmwareCampaignCancel AuditAction = "FIRMWARE_CAMPAIGN_CANCEL"
+	// AuditFirmwareSigningKeyRotate records that a new firmware signing key took over, retiring the previous one.
+	AuditFirmwareSigningKeyRotate AuditAction = "FIRMWARE_SIGNING_KEY_ROTATE"
+)
+
+const (
+	// SystemActor is the actor of operations that are not attributed to anyone, e.g. device provisioning.
+	SystemActor = "system"
+	// maxActorLength is the length of the `audit_logs.actor` column.
+	maxActorLength = 100
+)
+
+// AuditLogGenesisHash is the previous hash of the first entry of the hash chain.
+//
+//nolint:gochecknoglobals
+var AuditLogGenesisHash = strings.Repeat("0", sha256.Size*2) //nolint:mnd
+
+// auditActions lists the known audit actions.
+//
+//nolint:gochecknoglobals
+var auditActions = []AuditAction{
+	AuditDeviceCreate,
+	AuditDeviceUpdate,
+	AuditDeviceDelete,
+	AuditDeviceStatusChange,
+	AuditCertificateIssue,
+	AuditCertificateRevoke,
+	AuditTokenCreate,
+	AuditTokenUse,
+	AuditTokenRevoke,
+	AuditDeviceGroupCreate,
+	AuditDeviceGroupUpdate,
+	AuditDeviceGroupDelete,
+	AuditDeviceGroupMemberAdd,
+	AuditDeviceGroupMemberRemove,
+	AuditDeviceAttach,
+	AuditDeviceDetach,
+	AuditDeviceTwinUpdate,
+	AuditDeviceCommandSend,
+	AuditFirmwareUpload,
+	AuditFirmwareDelete,
+	AuditFirmwareCampaignCreate,
+	AuditFirmwareCampaignPause,
+	AuditFirmwareCampaignResume,
+	AuditFirmwareCampaignCancel,
+	AuditFirmwareSigningKeyRotate,
+}
+
+// IsValid reports whether the action is a known audit action.
+func (a AuditAction) IsValid() bool {
+	return slices.Contains(auditActions, a)
+}
+
+// AuditLog is an entry of the audit trail. Entries are only ever appended.
+//
+// Entries form a hash chain: each entry stores the hash of its content and of the previous entry's hash,
+// so editing, deleting or reordering entries breaks the chain. The hash can optionally be signed with
+// an HMAC key, so that the chain cannot be rebuilt by someone who can only access the database.
+type AuditLog struct {
+	ID int64 `gorm:"primaryKey"`
+	// TargetDeviceID is the device the operation was applied to.
+	TargetDeviceID *uuid.UUID  `gorm:"type:uuid"`
+	Action         AuditAction `gorm:"type:varchar(100);not null"`
+	// Details is a JSON-encoded AuditDetails.
+	Details string
+	Actor   string `gorm:"type:varchar(100)"`
+
+	// PrevHash is the Hash of the previous entry, or AuditLogGenesisHash for the first entry.
+	// PrevHash, Hash and Signature are empty for entries written before the chain was introduced.
+	PrevHash string `gorm:"type:char(64);default:null"`
+	// Hash is the hex-encoded SHA-256 hash of the content of the entry, including PrevHash.
+	Hash string `gorm:"type:char(64);default:null"`
+	// Signature is the hex-encoded HMAC-SHA256 of Hash, or empty if no key is configured.
+	Signature string `gorm:"type:char(64);default:null"`
+
+	// CreatedAt is part of the hashed content, so it is set by Chain rather than by GORM.
+	CreatedAt time.Time
+}
+
+// AuditDetails describes what an operation changed.
+type AuditDetails struct {
+	// Target identifies the object of the operation, e.g. "device/<id>" or "certificate/<serial>".
+	Target string `json:"target"`
+	// Before and After hold the fields of the target that the operation changed.
+	// Before is omitted for creations and After for deletions.
+	Before map[string]any `json:"before,omitempty"`
+	After  map[string]any `json:"after,omitempty"`
+}
+
+// NewAuditLog creates an audit log entry.
+// before and after are snapshots of the target that are encoded as JSON objects; either may be nil.
+// If both are given, only the fields that differ are kept, so the entry is a diff of the change.
+func NewAuditLog(
+	action AuditAction,
+	targetDeviceID uuid.UUID,
+	target string,
+	actor string,
+	before, after any,
+) (*AuditLog, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	if beforeFields != nil && afterFields != nil {
+		for key, value := range beforeFields {
+			if reflect.DeepEqual(value, afterFields[key]) {
+				delete(beforeFields, key)
+				delete(afterFields, key)
+			}
+		}
+	}
+
+	details, err := json.Marshal(AuditDetails{Target: target, Before: beforeFields, After: afterFields})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit details: %w", err)
+	}
+
+	var deviceID *uuid.UUID
+	if targetDeviceID != uuid.Nil {
+		deviceID = &targetDeviceID
+	}
+
+	return &AuditLog{
+		ID:             0,
+		TargetDeviceID: deviceID,
+		Action:         action,
+		Details:        string(details),
+		Actor:          normalizeActor(actor),
+		PrevHash:       "",
+		Hash:           "",
+		Signature:      "",
+		CreatedAt:      time.Time{},
+	}, nil
+}
+
+// Chain links the entry to the previous entry and computes its hash, stamping it with now.
+// If key is not empty, the hash is also signed with it.
+func (l *AuditLog) Chain(prevHash string, now time.Time, key []byte) {
+	// The timestamp is hashed, so it is truncated to the precision the database stores.
+	l.CreatedAt = now.UTC().Truncate(time.Microsecond)
+	l.PrevHash = prevHash
+	l.Hash = l.ComputeHash()
+	l.Signature = ""
+
+	if len(key) > 0 {
+		l.Signature = signAuditHash(l.Hash, key)
+	}
+}
+
+// IsChained reports whether the entry is part of the hash chain.
+func (l *AuditLog) IsChained() bool {
+	return l.Hash != ""
+}
+
+// ComputeHash returns the hash of the content of the entry, including PrevHash.
+func (l *AuditLog) ComputeHash() string {
+	// The fields are encoded in a fixed order, so the encoding is canonical.
+	content, _ := json.Marshal(struct { //nolint:errchkjson // Strings and UUIDs cannot fail to marshal.
+		PrevHash       string      `json:"prevHash"`
+		TargetDeviceID *uuid.UUID  `json:"targetDeviceId"`
+		Action         AuditAction `json:"action"`
+		Details        string      `json:"details"`
+		Actor          string      `json:"actor"`
+		CreatedAt      string      `json:"createdAt"`
+	}{
+		PrevHash:       l.PrevHash,
+		TargetDeviceID: l.TargetDeviceID,
+		Action:         l.Action,
+		Details:        l.Details,
+		Actor:          l.Actor,
+		CreatedAt:      l.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyChain checks that the entry follows the entry with prevHash and that its content is unchanged.
+// If key is not empty, a present signature is checked as well; requireSignature rejects unsigned entries.
+func (l *AuditLog) VerifyChain(prevHash string, key []byte, requireSignature bool) error {
+	if l.PrevHash != prevHash {
+		return fmt.Errorf("%w: previous hash is %s, want %s", ErrAuditLogChainBroken, l.PrevHash, prevHash)
+	}
+
+	if l.ComputeHash() != l.Hash {
+		return ErrAuditLogHashMismatch
+	}
+
+	if len(key) == 0 {
+		return nil
+	}
+
+	if l.Signature == "" {
+		if requireSignature {
+			return fmt.Errorf("%w: entry is not signed", ErrAuditLogSignatureInvalid)
+		}
+
+		return nil
+	}
+
+	if !hmac.Equal([]byte(l.Signature), []byte(signAuditHash(l.Hash, key))) {
+		return ErrAuditLogSignatureInvalid
+	}
+
+	return nil
+}
+
+// signAuditHash returns the hex-encoded HMAC-SHA256 of an entry hash.
+func signAuditHash(hash string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(hash))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// auditFields encodes a snapshot as a JSON object and decodes it into its fields.
+func auditFields(snapshot any) (map[string]any, error) {
+	if snapshot == nil || reflect.ValueOf(snapshot).Kind() == reflect.Pointer && reflect.ValueOf(snapshot).IsNil() {
+		return nil, nil //nolint:nilnil
+	}
+
+	encoded, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit snapshot: %w", err)
+	}
+
+	var fields map[string]any
+
+	err = json.Unmarshal(encoded, &fields)
+	if err != nil {
+		return nil, fmt.Errorf("audit snapshot is not a JSON object: %w", err)
+	}
+
+	return fields, nil
+}
+
+// normalizeActor returns the actor, or SystemActor if it is empty, truncated to the column length.
+func normalizeActor(actor string) string {
+	if actor == "" {
+		return SystemActor
+	}
+
+	runes := []rune(actor)
+	if len(runes) > maxActorLength {
+		return string(runes[:maxActorLength])
+	}
+
+	return actor
+}
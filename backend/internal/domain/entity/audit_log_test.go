@@ -0,0 +1,224 @@
+package entity_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+)
+
+func TestNewAuditLog(t *testing.T) {
+	t.Parallel()
+
+	deviceID := uuid.New()
+
+	tests := []struct {
+		name       string
+		before     any
+		after      any
+		wantBefore map[string]any
+		wantAfter  map[string]any
+	}{
+		{
+			name:       "creation",
+			before:     nil,
+			after:      map[string]any{"name": "a", "status": "UNREGISTERED"},
+			wantBefore: nil,
+			wantAfter:  map[string]any{"name": "a", "status": "UNREGISTERED"},
+		},
+		{
+			name:       "deletion",
+			before:     map[string]any{"name": "a"},
+			after:      nil,
+			wantBefore: map[string]any{"name": "a"},
+			wantAfter:  nil,
+		},
+		{
+			name:       "update keeps only changed fields",
+			before:     map[string]any{"name": "a", "status": "ACTIVE", "metadata": map[string]any{"fw": "1.0"}},
+			after:      map[string]any{"name": "b", "status": "ACTIVE", "metadata": map[string]any{"fw": "1.0"}},
+			wantBefore: map[string]any{"name": "a"},
+			wantAfter:  map[string]any{"name": "b"},
+		},
+		{
+			name:       "added and removed fields",
+			before:     map[string]any{"revoked": false},
+			after:      map[string]any{"revoked": true, "revocationReason": "keyCompromise"},
+			wantBefore: map[string]any{"revoked": false},
+			wantAfter:  map[string]any{"revoked": true, "revocationReason": "keyCompromise"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			log, err := entity.NewAuditLog(entity.AuditDeviceUpdate, deviceID, "device/x", "alice", tt.before, tt.after)
+			if err != nil {
+				t.Fatalf("NewAuditLog() unexpected error: %v", err)
+			}
+
+			if log.TargetDeviceID == nil || *log.TargetDeviceID != deviceID {
+				t.Errorf("TargetDeviceID = %v, want %v", log.TargetDeviceID, deviceID)
+			}
+
+			var details entity.AuditDetails
+
+			err = json.Unmarshal([]byte(log.Details), &details)
+			if err != nil {
+				t.Fatalf("Details is not valid JSON: %v", err)
+			}
+
+			if details.Target != "device/x" {
+				t.Errorf("Target = %q, want %q", details.Target, "device/x")
+			}
+
+			if !reflect.DeepEqual(details.Before, tt.wantBefore) {
+				t.Errorf("Before = %v, want %v", details.Before, tt.wantBefore)
+			}
+
+			if !reflect.DeepEqual(details.After, tt.wantAfter) {
+				t.Errorf("After = %v, want %v", details.After, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func TestNewAuditLogActor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		actor string
+		want  string
+	}{
+		{name: "named actor", actor: "alice", want: "alice"},
+		{name: "empty actor is the system", actor: "", want: entity.SystemActor},
+		{name: "long actor is truncated", actor: strings.Repeat("a", 150), want: strings.Repeat("a", 100)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			log, err := entity.NewAuditLog(entity.AuditDeviceDelete, uuid.Nil, "device/x", tt.actor, nil, nil)
+			if err != nil {
+				t.Fatalf("NewAuditLog() unexpected error: %v", err)
+			}
+
+			if log.Actor != tt.want {
+				t.Errorf("Actor = %q, want %q", log.Actor, tt.want)
+			}
+
+			if log.TargetDeviceID != nil {
+				t.Errorf("TargetDeviceID = %v, want nil for uuid.Nil", log.TargetDeviceID)
+			}
+		})
+	}
+}
+
+func TestAuditActionIsValid(t *testing.T) {
+	t.Parallel()
+
+	if !entity.AuditCertificateRevoke.IsValid() {
+		t.Error("CERT_REVOKE should be valid")
+	}
+
+	if entity.AuditAction("cert_revoke").IsValid() {
+		t.Error("cert_revoke should not be valid")
+	}
+}
+
+func TestAuditLogChain(t *testing.T) {
+	t.Parallel()
+
+	key := []byte("audit-key")
+	now := time.Date(2025, 1, 2, 3, 4, 5, 123456789, time.FixedZone("JST", 9*60*60))
+
+	newChained := func(t *testing.T, signingKey []byte) *entity.AuditLog {
+		t.Helper()
+
+		log, err := entity.NewAuditLog(entity.AuditDeviceCreate, uuid.New(), "device/x", "alice", nil, map[string]any{})
+		if err != nil {
+			t.Fatalf("NewAuditLog() unexpected error: %v", err)
+		}
+
+		log.Chain(entity.AuditLogGenesisHash, now, signingKey)
+
+		return log
+	}
+
+	t.Run("chain stamps the entry", func(t *testing.T) {
+		t.Parallel()
+
+		log := newChained(t, key)
+
+		if !log.CreatedAt.Equal(now.Truncate(time.Microsecond)) || log.CreatedAt.Location() != time.UTC {
+			t.Errorf("CreatedAt = %v, want %v in UTC", log.CreatedAt, now.Truncate(time.Microsecond))
+		}
+
+		if !log.IsChained() || len(log.Hash) != 64 || len(log.Signature) != 64 {
+			t.Errorf("Hash = %q, Signature = %q, want 64 hex digits each", log.Hash, log.Signature)
+		}
+
+		// The hash does not depend on the time zone the timestamp is read in.
+		log.CreatedAt = log.CreatedAt.In(time.FixedZone("EST", -5*60*60))
+		if log.ComputeHash() != log.Hash {
+			t.Error("ComputeHash() changed with the time zone of CreatedAt")
+		}
+	})
+
+	tests := []struct {
+		name             string
+		signingKey       []byte
+		verifyKey        []byte
+		requireSignature bool
+		tamper           func(log *entity.AuditLog)
+		wantErr          error
+	}{
+		{name: "intact", signingKey: key, verifyKey: key, requireSignature: true, tamper: nil, wantErr: nil},
+		{name: "not verified without a key", signingKey: key, verifyKey: nil, requireSignature: true,
+			tamper: func(log *entity.AuditLog) { log.Signature = "" }, wantErr: nil},
+		{name: "unsigned entry allowed", signingKey: nil, verifyKey: key, requireSignature: false,
+			tamper: nil, wantErr: nil},
+		{name: "unsigned entry required to be signed", signingKey: nil, verifyKey: key, requireSignature: true,
+			tamper: nil, wantErr: entity.ErrAuditLogSignatureInvalid},
+		{name: "wrong key", signingKey: []byte("other"), verifyKey: key, requireSignature: true,
+			tamper: nil, wantErr: entity.ErrAuditLogSignatureInvalid},
+		{name: "edited actor", signingKey: key, verifyKey: key, requireSignature: true,
+			tamper: func(log *entity.AuditLog) { log.Actor = "mallory" }, wantErr: entity.ErrAuditLogHashMismatch},
+		{name: "edited timestamp", signingKey: key, verifyKey: key, requireSignature: true,
+			tamper:  func(log *entity.AuditLog) { log.CreatedAt = log.CreatedAt.Add(time.Microsecond) },
+			wantErr: entity.ErrAuditLogHashMismatch},
+		{name: "rehashed without the key", signingKey: key, verifyKey: key, requireSignature: true,
+			tamper: func(log *entity.AuditLog) {
+				log.Details = "{}"
+				log.Hash = log.ComputeHash()
+			}, wantErr: entity.ErrAuditLogSignatureInvalid},
+		{name: "relinked", signingKey: key, verifyKey: key, requireSignature: true,
+			tamper:  func(log *entity.AuditLog) { log.PrevHash = strings.Repeat("1", 64) },
+			wantErr: entity.ErrAuditLogChainBroken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			log := newChained(t, tt.signingKey)
+			if tt.tamper != nil {
+				tt.tamper(log)
+			}
+
+			err := log.VerifyChain(entity.AuditLogGenesisHash, tt.verifyKey, tt.requireSignature)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyChain() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
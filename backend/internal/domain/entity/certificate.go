@@ -0,0 +1,97 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/VO/revocationreason"
+)
+
+// CertificateState is the derived state of a certificate.
+type CertificateState string
+
+const (
+	// CertificateValid means the certificate is within its validity period and not revoked.
+	CertificateValid CertificateState = "VALID"
+	// CertificateNotYetValid means the validity period of the certificate has not started yet.
+	CertificateNotYetValid CertificateState = "NOT_YET_VALID"
+	// CertificateExpired means the validity period of the certificate has ended.
+	CertificateExpired CertificateState = "EXPIRED"
+	// CertificateRevoked means the certificate has been revoked.
+	CertificateRevoked CertificateState = "REVOKED"
+)
+
+// Certificate is a client certificate issued to a device by the platform CA.
+// Certificates are kept as a history, so a device can have several of them.
+type Certificate struct {
+	// SerialNumber is the serial number assigned by the CA.
+	SerialNumber int64     `gorm:"primaryKey;autoIncrement:false"`
+	DeviceID     uuid.UUID `gorm:"type:uuid;not null"`
+
+	// Fingerprint is the hex-encoded SHA-256 hash of the DER-encoded certificate.
+	Fingerprint string `gorm:"not null"`
+	// PEMRaw is the PEM-encoded certificate.
+	PEMRaw string `gorm:"column:pem_raw;not null"`
+
+	ValidFrom time.Time `gorm:"not null"`
+	ValidTo   time.Time `gorm:"not null"`
+	IsRevoked bool      `gorm:"default:false"`
+
+	// RevokedAt and RevocationReason are set when the certificate is revoked.
+	RevokedAt        *time.Time
+	RevocationReason *revocationreason.Reason `gorm:"type:smallint"`
+
+	// CreatedAt is automatically managed by GORM.
+	CreatedAt time.Time
+}
+
+// NewCertificate creates a new Certificate issued to a device.
+func NewCertificate(
+	deviceID uuid.UUID,
+	serialNumber int64,
+	fingerprint string,
+	pemRaw string,
+	validFrom time.Time,
+	validTo time.Time,
+) *Certificate {
+	return &Certificate{
+		SerialNumber:     serialNumber,
+		DeviceID:         deviceID,
+		Fingerprint:      fingerprint,
+		PEMRaw:           pemRaw,
+		ValidFrom:        validFrom,
+		ValidTo:          validTo,
+		IsRevoked:        false,
+		RevokedAt:        nil,
+		RevocationReason: nil,
+		CreatedAt:        time.Time{},
+	}
+}
+
+// State returns the state of the certificate at now. Revocation takes precedence over the validity period.
+func (c *Certificate) State(now time.Time) CertificateState {
+	switch {
+	case c.IsRevoked:
+		return CertificateRevoked
+	case now.Before(c.ValidFrom):
+		return CertificateNotYetValid
+	case now.After(c.ValidTo):
+		return CertificateExpired
+	default:
+		return CertificateValid
+	}
+}
+
+// Revoke marks the certificate as revoked. Revocation is permanent.
+func (c *Certificate) Revoke(reason revocationreason.Reason, now time.Time) error {
+	if c.IsRevoked {
+		return ErrCertificateAlreadyRevoked
+	}
+
+	c.IsRevoked = true
+	c.RevokedAt = &now
+	c.RevocationReason = &reason
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package entity_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/revocationreason"
+	"backend/internal/domain/entity"
+
+	"github.com/google/uuid"
+)
+
+// TestCertificateState tests the State method of Certificate.
+func TestCertificateState(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		desc      string
+		validFrom time.Time
+		validTo   time.Time
+		revoked   bool
+		want      entity.CertificateState
+	}{
+		{
+			name:      "valid",
+			desc:      "Verify that a certificate within its validity period is VALID.",
+			validFrom: now.Add(-time.Hour),
+			validTo:   now.Add(time.Hour),
+			revoked:   false,
+			want:      entity.CertificateValid,
+		},
+		{
+			name:      "not yet valid",
+			desc:      "Verify that a certificate before its validity period is NOT_YET_VALID.",
+			validFrom: now.Add(time.Hour),
+			validTo:   now.Add(2 * time.Hour),
+			revoked:   false,
+			want:      entity.CertificateNotYetValid,
+		},
+		{
+			name:      "expired",
+			desc:      "Verify that a certificate after its validity period is EXPIRED.",
+			validFrom: now.Add(-2 * time.Hour),
+			validTo:   now.Add(-time.Hour),
+			revoked:   false,
+			want:      entity.CertificateExpired,
+		},
+		{
+			name:      "revoked",
+			desc:      "Verify that revocation takes precedence over the validity period.",
+			validFrom: now.Add(-2 * time.Hour),
+			validTo:   now.Add(-time.Hour),
+			revoked:   true,
+			want:      entity.CertificateRevoked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			certificate := entity.NewCertificate(uuid.New(), 1, "fp", "pem", tt.validFrom, tt.validTo)
+			certificate.IsRevoked = tt.revoked
+
+			got := certificate.State(now)
+			if got != tt.want {
+				t.Errorf("State() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCertificateRevoke tests the Revoke method of Certificate.
+func TestCertificateRevoke(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	certificate := entity.NewCertificate(uuid.New(), 1, "fp", "pem", now.Add(-time.Hour), now.Add(time.Hour))
+
+	err := certificate.Revoke(revocationreason.KeyCompromise, now)
+	if err != nil {
+		t.Fatalf("Revoke() unexpected error: %v", err)
+	}
+
+	if !certificate.IsRevoked || certificate.RevokedAt == nil || !certificate.RevokedAt.Equal(now) {
+		t.Errorf("Revoke() did not record the revocation: %+v", certificate)
+	}
+
+	if certificate.RevocationReason == nil || *certificate.RevocationReason != revocationreason.KeyCompromise {
+		t.Errorf("RevocationReason = %v, want %v", certificate.RevocationReason, revocationreason.KeyCompromise)
+	}
+
+	// Revocation is permanent, so the first reason is kept.
+	err = certificate.Revoke(revocationreason.Superseded, now.Add(time.Minute))
+	if !errors.Is(err, entity.ErrCertificateAlreadyRevoked) {
+		t.Errorf("Revoke() error = %v, want %v", err, entity.ErrCertificateAlreadyRevoked)
+	}
+
+	if *certificate.RevocationReason != revocationreason.KeyCompromise {
+		t.Errorf("RevocationReason = %v, want %v", *certificate.RevocationReason, revocationreason.KeyCompromise)
+	}
+}
@@ -2,10 +2,14 @@
 package entity
 
 import (
+	"fmt"
 	"maps"
 	"time"
 
 	"github.com/google/uuid"
+
+	"backend/internal/domain/VO/connectionstate"
+	"backend/internal/domain/VO/devicestatus"
 )
 
 // Device represents a device entity, with HardwareID being mandatory and Name and Metadata being optional.
@@ -19,12 +23,34 @@ type Device struct {
 	// Name is an optional, user-friendly name for the device.
 	Name string `gorm:"default:null"`
 
+	// Status is the lifecycle status of the device.
+	// Changes must go through the transition methods (Activate, Suspend, Revoke).
+	Status devicestatus.Status `gorm:"type:varchar(50);not null;default:'UNREGISTERED'"`
+
 	// Metadata contains device-specific information, such as specifications,
 	// installation location, and firmware version.
 	// Its content is searchable, so it is stored in JSONB format.
 	// Defaults to an empty JSON object '{}'.
 	Metadata JSONBMap `gorm:"type:jsonb;default:'{}'"`
 
+	// Version is incremented by the repository on every update, for optimistic concurrency control.
+	// An update or a deletion only succeeds if the stored version is still the one the device was read with.
+	Version int64 `gorm:"not null;default:1"`
+
+	// ParentID is the gateway the device sits behind, if any.
+	// A gateway holds the mTLS identity and publishes on behalf of its children.
+	// Changes must go through AttachTo and Detach, which keep the topology free of cycles.
+	ParentID *uuid.UUID `gorm:"type:uuid;index"`
+
+	// ConnectionState, LastSeenAt and LastIP are the presence of the device, as the broker observes it.
+	// They are only written by DeviceRepository.RecordPresence and MarkStale, never by Save,
+	// so that they neither conflict with nor bump the version of changes made through the API.
+	ConnectionState connectionstate.State `gorm:"type:varchar(20);not null;default:'DISCONNECTED'"`
+	// LastSeenAt is when the device last connected, published or pinged, or nil if it never has.
+	LastSeenAt *time.Time `gorm:"index"`
+	// LastIP is the IP address the device last connected from, or empty if it never has.
+	LastIP string `gorm:"type:varchar(45);not null;default:''"`
+
 	// CreatedAt and UpdatedAt are automatically managed by GORM.
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -43,9 +69,17 @@ func NewDevice(hardwareID string, name *string, metadata map[string]any) (*Devic
 		ID:         uuid.Nil,
 		HardwareID: hardwareID,
 		Name:       "", // Default to an empty string, to be overwritten if a name is provided.
+		Status:     devicestatus.Unregistered,
 		Metadata:   newMetadata,
-		CreatedAt:  time.Time{},
-		UpdatedAt:  time.Time{},
+		Version:    0, // Assigned by the repository when the device is created.
+		ParentID:   nil,
+
+		ConnectionState: connectionstate.Disconnected,
+		LastSeenAt:      nil,
+		LastIP:          "",
+
+		CreatedAt: time.Time{},
+		UpdatedAt: time.Time{},
 	}
 
 	if name != nil {
@@ -54,3 +88,122 @@ func NewDevice(hardwareID string, name *string, metadata map[string]any) (*Devic
 
 	return newDevice, nil
 }
+
+// Activate transitions the device to ACTIVE.
+// It is used both for provisioning (UNREGISTERED -> ACTIVE) and for resuming (SUSPENDED -> ACTIVE).
+func (d *Device) Activate() error {
+	return d.transitionTo(devicestatus.Active)
+}
+
+// Suspend transitions the device to SUSPENDED.
+func (d *Device) Suspend() error {
+	return d.transitionTo(devicestatus.Suspended)
+}
+
+// Revoke transitions the device to REVOKED. This is terminal.
+func (d *Device) Revoke() error {
+	return d.transitionTo(devicestatus.Revoked)
+}
+
+// AttachTo makes the device a child of parent. ancestors are the ancestors of parent, from its own parent
+// up to the root of its tree. It returns ErrDeviceTopologyCycle if the device is parent or one of its ancestors,
+// and ErrDeviceAlreadyAttached if the device is the child of another device; it must be detached first.
+// Attaching a device to its current parent does nothing.
+func (d *Device) AttachTo(parent *Device, ancestors []*Device) error {
+	if parent.ID == d.ID {
+		return fmt.Errorf("%w: device %s cannot be its own parent", ErrDeviceTopologyCycle, d.ID)
+	}
+
+	for _, ancestor := range ancestors {
+		if ancestor.ID == d.ID {
+			return fmt.Errorf("%w: device %s is an ancestor of device %s", ErrDeviceTopologyCycle, d.ID, parent.ID)
+		}
+	}
+
+	if d.ParentID != nil && *d.ParentID != parent.ID {
+		return fmt.Errorf("%w: device %s is a child of device %s", ErrDeviceAlreadyAttached, d.ID, *d.ParentID)
+	}
+
+	parentID := parent.ID
+	d.ParentID = &parentID
+
+	return nil
+}
+
+// Detach removes the device from its parent. It returns ErrDeviceNotChild if parentID is not its parent.
+func (d *Device) Detach(parentID uuid.UUID) error {
+	if d.ParentID == nil || *d.ParentID != parentID {
+		return fmt.Errorf("%w: device %s is not a child of device %s", ErrDeviceNotChild, d.ID, parentID)
+	}
+
+	d.ParentID = nil
+
+	return nil
+}
+
+// MergePatch applies a JSON Merge Patch (RFC 7396) to the patchable fields of the device.
+// See patch for the document the patch applies to.
+func (d *Device) MergePatch(patch []byte) error {
+	return d.patch(func(document JSONBMap) (JSONBMap, error) {
+		return document.MergePatch(patch)
+	})
+}
+
+// JSONPatch applies a JSON Patch (RFC 6902) to the patchable fields of the device.
+// See patch for the document the patch applies to.
+func (d *Device) JSONPatch(patch []byte) error {
+	return d.patch(func(document JSONBMap) (JSONBMap, error) {
+		return document.JSONPatch(patch)
+	})
+}
+
+// patch applies a patch to the document {"name": ..., "metadata": {...}} of the patchable fields of the device.
+// HardwareID and Status cannot be patched. Removing the name or the metadata clears it.
+// The device is only changed if the patched document is valid.
+func (d *Device) patch(apply func(document JSONBMap) (JSONBMap, error)) error {
+	metadata := map[string]any(d.Metadata)
+	if metadata == nil {
+		metadata = make(map[string]any)
+	}
+
+	patched, err := apply(JSONBMap{"name": d.Name, "metadata": metadata})
+	if err != nil {
+		return err
+	}
+
+	for key := range patched {
+		if key != "name" && key != "metadata" {
+			return fmt.Errorf("%w: %q cannot be patched", ErrInvalidPatch, key)
+		}
+	}
+
+	name, ok := patched["name"].(string)
+	if !ok && patched["name"] != nil {
+		return fmt.Errorf("%w: name must be a string", ErrInvalidPatch)
+	}
+
+	newMetadata, ok := patched["metadata"].(map[string]any)
+	if !ok && patched["metadata"] != nil {
+		return fmt.Errorf("%w: metadata must be an object", ErrInvalidPatch)
+	}
+
+	if newMetadata == nil {
+		newMetadata = make(map[string]any)
+	}
+
+	d.Name = name
+	d.Metadata = newMetadata
+
+	return nil
+}
+
+func (d *Device) transitionTo(next devicestatus.Status) error {
+	status, err := d.Status.TransitionTo(next)
+	if err != nil {
+		return fmt.Errorf("device %s: %w", d.ID, err)
+	}
+
+	d.Status = status
+
+	return nil
+}
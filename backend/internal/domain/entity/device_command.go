@@ -0,0 +1,146 @@
+package entity
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/VO/commandstatus"
+)
+
+// maxCommandNameLength is the maximum number of characters of a command name.
+const maxCommandNameLength = 64
+
+// DeviceCommand is an instruction sent from the platform to a device, such as "reboot" or "rotate-certificate".
+// It is delivered over MQTT and must be acknowledged by the device before it expires.
+type DeviceCommand struct {
+	ID       uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	DeviceID uuid.UUID `gorm:"type:uuid;not null"`
+
+	// Name is what the device is asked to do. Its meaning is up to the device.
+	Name string `gorm:"not null"`
+	// Payload holds the parameters of the command, as a JSON object.
+	Payload JSONBMap `gorm:"type:jsonb;not null"`
+
+	// Status is the delivery status of the command.
+	// Changes must go through the transition methods (MarkSent, Complete, Expire).
+	Status commandstatus.Status `gorm:"type:varchar(20);not null"`
+	// Result is the response of the device when it acknowledged the command, as a JSON object.
+	Result JSONBMap `gorm:"type:jsonb"`
+	// FailureReason is the reason the device gave for a FAILED command.
+	FailureReason string `gorm:"not null"`
+
+	// ExpiresAt is the time after which the command is no longer delivered nor accepted as answered.
+	ExpiresAt time.Time `gorm:"not null"`
+	// SentAt is set when the command is published to the device.
+	SentAt *time.Time
+	// CompletedAt is set when the command reaches a terminal status.
+	CompletedAt *time.Time
+
+	// CreatedAt is automatically managed by GORM.
+	CreatedAt time.Time
+}
+
+// NewDeviceCommand creates a new QUEUED DeviceCommand for a device that expires after ttl.
+// A nil payload is stored as an empty object.
+func NewDeviceCommand(
+	deviceID uuid.UUID,
+	name string,
+	payload map[string]any,
+	ttl time.Duration,
+	now time.Time,
+) (*DeviceCommand, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxCommandNameLength {
+		return nil, ErrInvalidCommandName
+	}
+
+	if ttl <= 0 {
+		return nil, ErrDeviceCommandTTLInvalid
+	}
+
+	if payload == nil {
+		payload = make(map[string]any)
+	}
+
+	return &DeviceCommand{
+		ID:            uuid.Nil,
+		DeviceID:      deviceID,
+		Name:          name,
+		Payload:       payload,
+		Status:        commandstatus.Queued,
+		Result:        nil,
+		FailureReason: "",
+		ExpiresAt:     now.Add(ttl),
+		SentAt:        nil,
+		CompletedAt:   nil,
+		CreatedAt:     time.Time{},
+	}, nil
+}
+
+// StatusAt returns the status of the command at now. A command that is still pending after its TTL
+// is EXPIRED, even if it has not been marked as such yet.
+func (c *DeviceCommand) StatusAt(now time.Time) commandstatus.Status {
+	if !c.Status.IsTerminal() && !now.Before(c.ExpiresAt) {
+		return commandstatus.Expired
+	}
+
+	return c.Status
+}
+
+// MarkSent records that the command has been published to the device.
+func (c *DeviceCommand) MarkSent(now time.Time) error {
+	next, err := c.Status.TransitionTo(commandstatus.Sent)
+	if err != nil {
+		return err
+	}
+
+	c.Status = next
+	c.SentAt = &now
+
+	return nil
+}
+
+// Complete records the answer of the device: ACKED with an optional result, or FAILED with a reason.
+// It returns ErrDeviceCommandExpired if the command has expired.
+func (c *DeviceCommand) Complete(
+	status commandstatus.Status,
+	result map[string]any,
+	failureReason string,
+	now time.Time,
+) error {
+	if status != commandstatus.Acked && status != commandstatus.Failed {
+		return &commandstatus.TransitionError{From: c.Status, To: status, Err: commandstatus.ErrInvalidTransition}
+	}
+
+	if c.StatusAt(now) == commandstatus.Expired {
+		return ErrDeviceCommandExpired
+	}
+
+	next, err := c.Status.TransitionTo(status)
+	if err != nil {
+		return err
+	}
+
+	c.Status = next
+	c.Result = result
+	c.FailureReason = failureReason
+	c.CompletedAt = &now
+
+	return nil
+}
+
+// Expire records that the command was not answered within its TTL.
+func (c *DeviceCommand) Expire(now time.Time) error {
+	next, err := c.Status.TransitionTo(commandstatus.Expired)
+	if err != nil {
+		return err
+	}
+
+	c.Status = next
+	c.CompletedAt = &now
+
+	return nil
+}
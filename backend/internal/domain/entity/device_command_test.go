@@ -0,0 +1,129 @@
+package entity_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/commandstatus"
+	"backend/internal/domain/entity"
+
+	"github.com/google/uuid"
+)
+
+// TestNewDeviceCommand tests the NewDeviceCommand function.
+func TestNewDeviceCommand(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	command, err := entity.NewDeviceCommand(uuid.New(), " reboot ", nil, time.Minute, now)
+	if err != nil {
+		t.Fatalf("NewDeviceCommand() unexpected error: %v", err)
+	}
+
+	if command.Name != "reboot" || command.Status != commandstatus.Queued {
+		t.Errorf("NewDeviceCommand() = %+v, want a QUEUED command named reboot", command)
+	}
+
+	if command.Payload == nil || len(command.Payload) != 0 {
+		t.Errorf("NewDeviceCommand() payload = %v, want an empty object", command.Payload)
+	}
+
+	if !command.ExpiresAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("NewDeviceCommand() expires at = %v, want %v", command.ExpiresAt, now.Add(time.Minute))
+	}
+
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		wantErr error
+	}{
+		{name: "", ttl: time.Minute, wantErr: entity.ErrInvalidCommandName},
+		{name: strings.Repeat("あ", 65), ttl: time.Minute, wantErr: entity.ErrInvalidCommandName},
+		{name: "reboot", ttl: 0, wantErr: entity.ErrDeviceCommandTTLInvalid},
+	}
+
+	for _, tt := range tests {
+		_, err = entity.NewDeviceCommand(uuid.New(), tt.name, nil, tt.ttl, now)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("NewDeviceCommand(%q, %v) error = %v, want %v", tt.name, tt.ttl, err, tt.wantErr)
+		}
+	}
+}
+
+// TestDeviceCommandLifecycle tests the MarkSent, Complete and StatusAt methods of DeviceCommand.
+func TestDeviceCommandLifecycle(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	command, err := entity.NewDeviceCommand(uuid.New(), "reboot", nil, time.Minute, now)
+	if err != nil {
+		t.Fatalf("NewDeviceCommand() unexpected error: %v", err)
+	}
+
+	err = command.MarkSent(now)
+	if err != nil || command.Status != commandstatus.Sent || command.SentAt == nil {
+		t.Fatalf("MarkSent() error = %v, command = %+v", err, command)
+	}
+
+	err = command.Complete(commandstatus.Expired, nil, "", now)
+	if !errors.Is(err, commandstatus.ErrInvalidTransition) {
+		t.Errorf("Complete(EXPIRED) error = %v, want %v", err, commandstatus.ErrInvalidTransition)
+	}
+
+	err = command.Complete(commandstatus.Failed, nil, "busy", now.Add(time.Second))
+	if err != nil {
+		t.Fatalf("Complete() unexpected error: %v", err)
+	}
+
+	if command.Status != commandstatus.Failed || command.FailureReason != "busy" || command.CompletedAt == nil {
+		t.Errorf("Complete() command = %+v, want FAILED with reason busy", command)
+	}
+
+	err = command.Complete(commandstatus.Acked, nil, "", now.Add(2*time.Second))
+	if !errors.Is(err, commandstatus.ErrInvalidTransition) {
+		t.Errorf("Complete() of a completed command error = %v, want %v", err, commandstatus.ErrInvalidTransition)
+	}
+
+	if got := command.StatusAt(now.Add(time.Hour)); got != commandstatus.Failed {
+		t.Errorf("StatusAt() of a completed command = %v, want %v", got, commandstatus.Failed)
+	}
+}
+
+// TestDeviceCommandExpiry tests that a command past its TTL is reported as EXPIRED and cannot be answered.
+func TestDeviceCommandExpiry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	command, err := entity.NewDeviceCommand(uuid.New(), "reboot", nil, time.Minute, now)
+	if err != nil {
+		t.Fatalf("NewDeviceCommand() unexpected error: %v", err)
+	}
+
+	if got := command.StatusAt(now.Add(59 * time.Second)); got != commandstatus.Queued {
+		t.Errorf("StatusAt() before the TTL = %v, want %v", got, commandstatus.Queued)
+	}
+
+	if got := command.StatusAt(now.Add(time.Minute)); got != commandstatus.Expired {
+		t.Errorf("StatusAt() after the TTL = %v, want %v", got, commandstatus.Expired)
+	}
+
+	err = command.Complete(commandstatus.Acked, nil, "", now.Add(time.Minute))
+	if !errors.Is(err, entity.ErrDeviceCommandExpired) {
+		t.Errorf("Complete() after the TTL error = %v, want %v", err, entity.ErrDeviceCommandExpired)
+	}
+
+	err = command.Expire(now.Add(time.Minute))
+	if err != nil || command.Status != commandstatus.Expired || command.CompletedAt == nil {
+		t.Errorf("Expire() error = %v, command = %+v", err, command)
+	}
+
+	err = command.Expire(now.Add(time.Minute))
+	if !errors.Is(err, commandstatus.ErrInvalidTransition) {
+		t.Errorf("Expire() of an expired command error = %v, want %v", err, commandstatus.ErrInvalidTransition)
+	}
+}
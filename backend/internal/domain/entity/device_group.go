@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeviceGroup is a named set of devices, such as the devices of a site or of a product line.
+// Its members are the devices added to it, and the devices whose metadata satisfies its rule.
+type DeviceGroup struct {
+	ID uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+
+	// Name is the unique, user-friendly name of the group.
+	Name string `gorm:"uniqueIndex;not null"`
+
+	// Description is an optional free-form description of the group.
+	Description string `gorm:"not null;default:''"`
+
+	// Rule is the dynamic membership rule: conditions on the metadata, such as "location.building=Factory-A",
+	// in the syntax of the metadata filters of the device list. A device satisfying all of them is a member.
+	// A group without conditions only has the devices added to it.
+	Rule JSONBStrings `gorm:"type:jsonb;not null;default:'[]'"`
+
+	// CreatedAt and UpdatedAt are automatically managed by GORM.
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// DeviceGroupMember records that a device has been added to a group.
+type DeviceGroupMember struct {
+	GroupID   uuid.UUID `gorm:"primaryKey;type:uuid"`
+	DeviceID  uuid.UUID `gorm:"primaryKey;type:uuid"`
+	CreatedAt time.Time
+}
+
+// NewDeviceGroup creates a new DeviceGroup. The rule is not validated here, as its syntax belongs to the queries.
+func NewDeviceGroup(name, description string, rule []string) (*DeviceGroup, error) {
+	group := &DeviceGroup{
+		ID:          uuid.Nil,
+		Name:        "",
+		Description: description,
+		Rule:        slices.Clone(JSONBStrings(rule)),
+		CreatedAt:   time.Time{},
+		UpdatedAt:   time.Time{},
+	}
+
+	err := group.Rename(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// Rename changes the name of the group. Surrounding whitespace is removed, and the name cannot be empty.
+func (g *DeviceGroup) Rename(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ErrDeviceGroupNameEmpty
+	}
+
+	g.Name = name
+
+	return nil
+}
+
+// IsDynamic reports whether the group has a membership rule.
+func (g *DeviceGroup) IsDynamic() bool {
+	return len(g.Rule) > 0
+}
@@ -0,0 +1,99 @@
+package entity_test
+
+import (
+	"errors"
+	"testing"
+
+	"backend/internal/domain/entity"
+)
+
+// TestNewDeviceGroup tests the NewDeviceGroup constructor function.
+func TestNewDeviceGroup(t *testing.T) {
+	t.Parallel()
+
+	rule := []string{"location.building=Factory-A"}
+
+	group, err := entity.NewDeviceGroup("  Factory A  ", "Devices of the factory", rule)
+	if err != nil {
+		t.Fatalf("NewDeviceGroup() unexpected error: %v", err)
+	}
+
+	if group.Name != "Factory A" {
+		t.Errorf("NewDeviceGroup() name = %q, want the trimmed name", group.Name)
+	}
+
+	if !group.IsDynamic() || len(group.Rule) != 1 || group.Rule[0] != rule[0] {
+		t.Errorf("NewDeviceGroup() rule = %v, want %v", group.Rule, rule)
+	}
+
+	// The rule is copied, so that the caller cannot change it afterwards.
+	rule[0] = "changed"
+	if group.Rule[0] == "changed" {
+		t.Errorf("NewDeviceGroup() shares the rule with the caller")
+	}
+
+	static, err := entity.NewDeviceGroup("Spares", "", nil)
+	if err != nil {
+		t.Fatalf("NewDeviceGroup() unexpected error: %v", err)
+	}
+
+	if static.IsDynamic() {
+		t.Errorf("IsDynamic() = true for a group without a rule")
+	}
+
+	_, err = entity.NewDeviceGroup(" \t", "", nil)
+	if !errors.Is(err, entity.ErrDeviceGroupNameEmpty) {
+		t.Errorf("NewDeviceGroup() error = %v, want %v", err, entity.ErrDeviceGroupNameEmpty)
+	}
+}
+
+// TestDeviceGroupRename tests the Rename method of DeviceGroup.
+func TestDeviceGroupRename(t *testing.T) {
+	t.Parallel()
+
+	group, err := entity.NewDeviceGroup("Factory A", "", nil)
+	if err != nil {
+		t.Fatalf("NewDeviceGroup() unexpected error: %v", err)
+	}
+
+	err = group.Rename("")
+	if !errors.Is(err, entity.ErrDeviceGroupNameEmpty) {
+		t.Errorf("Rename() error = %v, want %v", err, entity.ErrDeviceGroupNameEmpty)
+	}
+
+	if group.Name != "Factory A" {
+		t.Errorf("Rename() changed the name to %q on error", group.Name)
+	}
+
+	err = group.Rename("Factory B ")
+	if err != nil || group.Name != "Factory B" {
+		t.Errorf("Rename() = %v, name %q, want Factory B", err, group.Name)
+	}
+}
+
+// TestJSONBStrings tests the database conversions of JSONBStrings.
+func TestJSONBStrings(t *testing.T) {
+	t.Parallel()
+
+	value, err := entity.JSONBStrings(nil).Value()
+	if err != nil || value != "[]" {
+		t.Errorf("Value() of nil = %v, %v, want an empty array", value, err)
+	}
+
+	var strings entity.JSONBStrings
+
+	err = strings.Scan([]byte(`["a","b"]`))
+	if err != nil || len(strings) != 2 || strings[1] != "b" {
+		t.Errorf("Scan() = %v, %v, want [a b]", strings, err)
+	}
+
+	err = strings.Scan(nil)
+	if err != nil || strings == nil || len(strings) != 0 {
+		t.Errorf("Scan(nil) = %v, %v, want an empty slice", strings, err)
+	}
+
+	err = strings.Scan(42)
+	if !errors.Is(err, entity.ErrUnsupportedTypeForJSONBStringsScan) {
+		t.Errorf("Scan(42) error = %v, want %v", err, entity.ErrUnsupportedTypeForJSONBStringsScan)
+	}
+}
@@ -1,9 +1,11 @@
 package entity_test
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 
+	"backend/internal/domain/VO/devicestatus"
 	"backend/internal/domain/entity"
 
 	"github.com/google/uuid"
@@ -14,6 +16,7 @@ type want struct {
 	hardwareID string
 	name       string
 	metadata   entity.JSONBMap
+	status     devicestatus.Status
 	id         uuid.UUID
 }
 
@@ -116,6 +119,7 @@ func getNewDeviceTestCases() []struct {
 				hardwareID: "test-hw-id-1",
 				name:       "test-device-1",
 				metadata:   entity.JSONBMap{"key": "value"},
+				status:     devicestatus.Unregistered,
 				id:         uuid.Nil,
 			},
 			wantErr:    false,
@@ -133,6 +137,7 @@ func getNewDeviceTestCases() []struct {
 				hardwareID: "test-hw-id-2",
 				name:       "",
 				metadata:   entity.JSONBMap{},
+				status:     devicestatus.Unregistered,
 				id:         uuid.Nil,
 			},
 			wantErr:    false,
@@ -150,6 +155,7 @@ func getNewDeviceTestCases() []struct {
 				hardwareID: "test-hw-id-3",
 				name:       "env-sensor-1",
 				metadata:   entity.JSONBMap(complexMetadata),
+				status:     devicestatus.Unregistered,
 				id:         uuid.Nil,
 			},
 			wantErr:    false,
@@ -167,6 +173,7 @@ func getNewDeviceTestCases() []struct {
 				hardwareID: "",
 				name:       "",
 				metadata:   nil,
+				status:     "",
 				id:         uuid.Nil,
 			},
 			wantErr:    true,
@@ -194,7 +201,263 @@ func assertDevice(t *testing.T, want want, got *entity.Device) {
 		t.Errorf("NewDevice() Metadata = %v, want %v", got.Metadata, want.metadata)
 	}
 
+	if got.Status != want.status {
+		t.Errorf("NewDevice() Status = %v, want %v", got.Status, want.status)
+	}
+
 	if got.ID != want.id {
 		t.Errorf("NewDevice() ID = %v, want %v", got.ID, want.id)
 	}
 }
+
+// TestDeviceStatusTransitions tests the lifecycle transition methods of Device.
+func TestDeviceStatusTransitions(t *testing.T) {
+	t.Parallel()
+
+	device, err := entity.NewDevice("test-hw-id-status", nil, nil)
+	if err != nil {
+		t.Fatalf("NewDevice() unexpected error: %v", err)
+	}
+
+	// UNREGISTERED -> SUSPENDED is not allowed, and the status must be left unchanged.
+	err = device.Suspend()
+	if !errors.Is(err, devicestatus.ErrInvalidTransition) {
+		t.Fatalf("Suspend() error = %v, want %v", err, devicestatus.ErrInvalidTransition)
+	}
+
+	if device.Status != devicestatus.Unregistered {
+		t.Fatalf("Status = %v, want %v", device.Status, devicestatus.Unregistered)
+	}
+
+	// UNREGISTERED -> ACTIVE -> SUSPENDED -> ACTIVE -> REVOKED
+	steps := []struct {
+		transition func() error
+		want       devicestatus.Status
+	}{
+		{transition: device.Activate, want: devicestatus.Active},
+		{transition: device.Suspend, want: devicestatus.Suspended},
+		{transition: device.Activate, want: devicestatus.Active},
+		{transition: device.Revoke, want: devicestatus.Revoked},
+	}
+
+	for _, step := range steps {
+		err = step.transition()
+		if err != nil {
+			t.Fatalf("transition to %v: unexpected error: %v", step.want, err)
+		}
+
+		if device.Status != step.want {
+			t.Fatalf("Status = %v, want %v", device.Status, step.want)
+		}
+	}
+
+	// REVOKED is terminal.
+	err = device.Activate()
+	if !errors.Is(err, devicestatus.ErrInvalidTransition) {
+		t.Errorf("Activate() after revocation error = %v, want %v", err, devicestatus.ErrInvalidTransition)
+	}
+}
+
+func TestDevicePatch(t *testing.T) {
+	t.Parallel()
+
+	newPatchDevice := func() *entity.Device {
+		name := "sensor"
+
+		device, err := entity.NewDevice("hw-patch-001", &name, map[string]any{
+			"config": map[string]any{"sync_interval_sec": 60.0, "alert_threshold_temp": 40.0},
+		})
+		if err != nil {
+			t.Fatalf("NewDevice() unexpected error: %v", err)
+		}
+
+		return device
+	}
+
+	tests := []struct {
+		name         string
+		desc         string
+		apply        func(*entity.Device) error
+		wantName     string
+		wantMetadata entity.JSONBMap
+		wantErr      error
+	}{
+		{
+			name: "success: merge patch of a nested metadata key",
+			desc: "Verify that a merge patch updates one nested key and keeps the others.",
+			apply: func(d *entity.Device) error {
+				return d.MergePatch([]byte(`{"metadata": {"config": {"sync_interval_sec": 30}}}`))
+			},
+			wantName: "sensor",
+			wantMetadata: entity.JSONBMap{
+				"config": map[string]any{"sync_interval_sec": 30.0, "alert_threshold_temp": 40.0},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "success: merge patch removes the name",
+			desc: "Verify that a null name clears the name.",
+			apply: func(d *entity.Device) error {
+				return d.MergePatch([]byte(`{"name": null}`))
+			},
+			wantName: "",
+			wantMetadata: entity.JSONBMap{
+				"config": map[string]any{"sync_interval_sec": 60.0, "alert_threshold_temp": 40.0},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "success: json patch",
+			desc: "Verify that a JSON Patch updates the name and the metadata.",
+			apply: func(d *entity.Device) error {
+				return d.JSONPatch([]byte(`[
+					{"op": "replace", "path": "/name", "value": "renamed"},
+					{"op": "remove", "path": "/metadata/config/alert_threshold_temp"}
+				]`))
+			},
+			wantName:     "renamed",
+			wantMetadata: entity.JSONBMap{"config": map[string]any{"sync_interval_sec": 60.0}},
+			wantErr:      nil,
+		},
+		{
+			name: "failure: read-only field",
+			desc: "Verify that fields other than the name and the metadata cannot be patched.",
+			apply: func(d *entity.Device) error {
+				return d.MergePatch([]byte(`{"status": "ACTIVE"}`))
+			},
+			wantName: "sensor",
+			wantMetadata: entity.JSONBMap{
+				"config": map[string]any{"sync_interval_sec": 60.0, "alert_threshold_temp": 40.0},
+			},
+			wantErr: entity.ErrInvalidPatch,
+		},
+		{
+			name: "failure: metadata is not an object",
+			desc: "Verify that the metadata cannot be replaced with a scalar, and the device is left unchanged.",
+			apply: func(d *entity.Device) error {
+				return d.JSONPatch([]byte(`[
+					{"op": "replace", "path": "/name", "value": "renamed"},
+					{"op": "replace", "path": "/metadata", "value": 1}
+				]`))
+			},
+			wantName: "sensor",
+			wantMetadata: entity.JSONBMap{
+				"config": map[string]any{"sync_interval_sec": 60.0, "alert_threshold_temp": 40.0},
+			},
+			wantErr: entity.ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			device := newPatchDevice()
+
+			err := tt.apply(device)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("patch error = %v, want %v", err, tt.wantErr)
+			}
+
+			if device.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", device.Name, tt.wantName)
+			}
+
+			if !reflect.DeepEqual(device.Metadata, tt.wantMetadata) {
+				t.Errorf("Metadata = %v, want %v", device.Metadata, tt.wantMetadata)
+			}
+		})
+	}
+}
+
+// TestDeviceTopology tests the AttachTo and Detach methods of Device.
+func TestDeviceTopology(t *testing.T) {
+	t.Parallel()
+
+	newTopologyDevice := func(id string) *entity.Device {
+		device, err := entity.NewDevice("hw-"+id, nil, nil)
+		if err != nil {
+			t.Fatalf("NewDevice() unexpected error: %v", err)
+		}
+
+		device.ID = uuid.MustParse(id)
+
+		return device
+	}
+
+	// gateway <- hub <- sensor
+	gateway := newTopologyDevice("00000000-0000-0000-0000-000000000001")
+	hub := newTopologyDevice("00000000-0000-0000-0000-000000000002")
+	sensor := newTopologyDevice("00000000-0000-0000-0000-000000000003")
+
+	err := hub.AttachTo(gateway, nil)
+	if err != nil {
+		t.Fatalf("AttachTo() unexpected error: %v", err)
+	}
+
+	err = sensor.AttachTo(hub, []*entity.Device{gateway})
+	if err != nil {
+		t.Fatalf("AttachTo() unexpected error: %v", err)
+	}
+
+	if sensor.ParentID == nil || *sensor.ParentID != hub.ID {
+		t.Fatalf("ParentID = %v, want %v", sensor.ParentID, hub.ID)
+	}
+
+	// Attaching a device to its current parent does nothing.
+	err = sensor.AttachTo(hub, []*entity.Device{gateway})
+	if err != nil {
+		t.Errorf("AttachTo() the same parent unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		child     *entity.Device
+		parent    *entity.Device
+		ancestors []*entity.Device
+		wantErr   error
+	}{
+		{name: "itself", child: gateway, parent: gateway, ancestors: nil, wantErr: entity.ErrDeviceTopologyCycle},
+		{
+			name:      "a descendant",
+			child:     gateway,
+			parent:    sensor,
+			ancestors: []*entity.Device{hub, gateway},
+			wantErr:   entity.ErrDeviceTopologyCycle,
+		},
+		{
+			name:      "another parent",
+			child:     sensor,
+			parent:    gateway,
+			ancestors: nil,
+			wantErr:   entity.ErrDeviceAlreadyAttached,
+		},
+	}
+
+	for _, tt := range tests {
+		parentID := tt.child.ParentID
+
+		err = tt.child.AttachTo(tt.parent, tt.ancestors)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("AttachTo() %s error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+
+		if tt.child.ParentID != parentID {
+			t.Errorf("AttachTo() %s changed the parent to %v", tt.name, tt.child.ParentID)
+		}
+	}
+
+	err = sensor.Detach(gateway.ID)
+	if !errors.Is(err, entity.ErrDeviceNotChild) {
+		t.Errorf("Detach() from another device error = %v, want %v", err, entity.ErrDeviceNotChild)
+	}
+
+	err = sensor.Detach(hub.ID)
+	if err != nil {
+		t.Fatalf("Detach() unexpected error: %v", err)
+	}
+
+	if sensor.ParentID != nil {
+		t.Errorf("ParentID after Detach() = %v, want nil", *sensor.ParentID)
+	}
+}
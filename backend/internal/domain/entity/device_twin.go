@@ -0,0 +1,116 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeviceTwin holds the configuration of a device on both sides: the desired state the platform wants it to apply,
+// such as {"config": {"sync_interval_sec": 60}}, and the reported state the device says it has applied.
+// Each state has its own version, which is incremented with every change of the state.
+type DeviceTwin struct {
+	DeviceID uuid.UUID `gorm:"primaryKey;type:uuid"`
+
+	// Desired is the state set through the API, as a JSON object.
+	Desired          JSONBMap `gorm:"type:jsonb;not null"`
+	DesiredVersion   int64    `gorm:"not null"`
+	DesiredUpdatedAt *time.Time
+
+	// Reported is the state published by the device, as a JSON object.
+	Reported          JSONBMap `gorm:"type:jsonb;not null"`
+	ReportedVersion   int64    `gorm:"not null"`
+	ReportedUpdatedAt *time.Time
+}
+
+// NewDeviceTwin creates the twin of a device whose states have never been set: both are empty at version 0.
+func NewDeviceTwin(deviceID uuid.UUID) *DeviceTwin {
+	return &DeviceTwin{
+		DeviceID:          deviceID,
+		Desired:           make(JSONBMap),
+		DesiredVersion:    0,
+		DesiredUpdatedAt:  nil,
+		Reported:          make(JSONBMap),
+		ReportedVersion:   0,
+		ReportedUpdatedAt: nil,
+	}
+}
+
+// ParseTwinState parses a state document, which must be a JSON object.
+func ParseTwinState(data []byte) (JSONBMap, error) {
+	var state JSONBMap
+
+	err := json.Unmarshal(data, &state)
+	if err != nil || state == nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTwinState, string(data))
+	}
+
+	return state, nil
+}
+
+// SetDesired replaces the desired state. It reports whether the state changed, in which case its version
+// is incremented and its update time set to now.
+func (t *DeviceTwin) SetDesired(state JSONBMap, now time.Time) bool {
+	return setTwinState(&t.Desired, &t.DesiredVersion, &t.DesiredUpdatedAt, state, now)
+}
+
+// SetReported replaces the reported state. It reports whether the state changed, in which case its version
+// is incremented and its update time set to now.
+func (t *DeviceTwin) SetReported(state JSONBMap, now time.Time) bool {
+	return setTwinState(&t.Reported, &t.ReportedVersion, &t.ReportedUpdatedAt, state, now)
+}
+
+// Delta returns the part of the desired state that the reported state does not match yet:
+// the desired values that are missing from the reported state or differ from it, compared recursively
+// in nested objects. Values that are only in the reported state are not part of it.
+func (t *DeviceTwin) Delta() map[string]any {
+	return stateDelta(t.Desired, t.Reported)
+}
+
+// setTwinState replaces one of the states of a twin and increments its version if it changed.
+func setTwinState(current *JSONBMap, version *int64, updatedAt **time.Time, state JSONBMap, now time.Time) bool {
+	if state == nil {
+		state = make(JSONBMap)
+	}
+
+	if reflect.DeepEqual(map[string]any(*current), map[string]any(state)) {
+		return false
+	}
+
+	*current = state
+	*version++
+	*updatedAt = &now
+
+	return true
+}
+
+// stateDelta returns the values of desired that are missing from reported or differ from it.
+// Both are JSON documents, so numbers are float64 on both sides.
+func stateDelta(desired, reported map[string]any) map[string]any {
+	delta := make(map[string]any)
+
+	for key, want := range desired {
+		got, ok := reported[key]
+
+		wantObject, wantIsObject := want.(map[string]any)
+		gotObject, gotIsObject := got.(map[string]any)
+
+		if wantIsObject && gotIsObject {
+			nested := stateDelta(wantObject, gotObject)
+			if len(nested) > 0 {
+				delta[key] = nested
+			}
+
+			continue
+		}
+
+		if !ok || !reflect.DeepEqual(want, got) {
+			delta[key] = want
+		}
+	}
+
+	return delta
+}
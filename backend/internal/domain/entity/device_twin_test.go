@@ -0,0 +1,121 @@
+package entity_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"backend/internal/domain/entity"
+
+	"github.com/google/uuid"
+)
+
+// TestParseTwinState tests the ParseTwinState function.
+func TestParseTwinState(t *testing.T) {
+	t.Parallel()
+
+	state, err := entity.ParseTwinState([]byte(`{"config": {"mode": "eco"}}`))
+	if err != nil {
+		t.Fatalf("ParseTwinState() unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(map[string]any(state), map[string]any{"config": map[string]any{"mode": "eco"}}) {
+		t.Errorf("ParseTwinState() = %v", state)
+	}
+
+	for _, data := range []string{`null`, `[]`, `"eco"`, `{`} {
+		_, err = entity.ParseTwinState([]byte(data))
+		if !errors.Is(err, entity.ErrInvalidTwinState) {
+			t.Errorf("ParseTwinState(%s) error = %v, want %v", data, err, entity.ErrInvalidTwinState)
+		}
+	}
+}
+
+// TestDeviceTwinSetState tests the SetDesired and SetReported methods of DeviceTwin.
+func TestDeviceTwinSetState(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	twin := entity.NewDeviceTwin(uuid.New())
+
+	if twin.SetDesired(nil, now) || twin.DesiredVersion != 0 || twin.DesiredUpdatedAt != nil {
+		t.Errorf("SetDesired() of an empty state changed the new twin: %+v", twin)
+	}
+
+	if !twin.SetDesired(entity.JSONBMap{"mode": "eco"}, now) || twin.DesiredVersion != 1 {
+		t.Errorf("SetDesired() version = %d, want 1", twin.DesiredVersion)
+	}
+
+	if twin.DesiredUpdatedAt == nil || !twin.DesiredUpdatedAt.Equal(now) {
+		t.Errorf("SetDesired() updated at = %v, want %v", twin.DesiredUpdatedAt, now)
+	}
+
+	if twin.SetDesired(entity.JSONBMap{"mode": "eco"}, now.Add(time.Minute)) || twin.DesiredVersion != 1 {
+		t.Errorf("SetDesired() of the same state changed the version to %d", twin.DesiredVersion)
+	}
+
+	if !twin.SetReported(entity.JSONBMap{"mode": "eco"}, now) || twin.ReportedVersion != 1 {
+		t.Errorf("SetReported() version = %d, want 1", twin.ReportedVersion)
+	}
+
+	if twin.DesiredVersion != 1 {
+		t.Errorf("SetReported() changed the desired version to %d", twin.DesiredVersion)
+	}
+}
+
+// TestDeviceTwinDelta tests the Delta method of DeviceTwin.
+func TestDeviceTwinDelta(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		desired  entity.JSONBMap
+		reported entity.JSONBMap
+		want     map[string]any
+	}{
+		{
+			name:     "in sync",
+			desired:  entity.JSONBMap{"mode": "eco", "interval": 60.0},
+			reported: entity.JSONBMap{"mode": "eco", "interval": 60.0, "firmware": "1.2.0"},
+			want:     map[string]any{},
+		},
+		{
+			name:     "missing and different values",
+			desired:  entity.JSONBMap{"mode": "eco", "interval": 60.0, "tags": []any{"a"}},
+			reported: entity.JSONBMap{"mode": "normal", "tags": []any{"a", "b"}},
+			want:     map[string]any{"mode": "eco", "interval": 60.0, "tags": []any{"a"}},
+		},
+		{
+			name: "nested objects",
+			desired: entity.JSONBMap{
+				"config": map[string]any{"mode": "eco", "led": map[string]any{"on": true}},
+			},
+			reported: entity.JSONBMap{
+				"config": map[string]any{"mode": "eco", "led": map[string]any{"on": false}},
+			},
+			want: map[string]any{"config": map[string]any{"led": map[string]any{"on": true}}},
+		},
+		{
+			name:     "object replaces a value",
+			desired:  entity.JSONBMap{"config": map[string]any{"mode": "eco"}},
+			reported: entity.JSONBMap{"config": "default"},
+			want:     map[string]any{"config": map[string]any{"mode": "eco"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			twin := entity.NewDeviceTwin(uuid.New())
+			twin.Desired = tt.desired
+			twin.Reported = tt.reported
+
+			got := twin.Delta()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Delta() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
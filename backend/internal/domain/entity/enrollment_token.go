@@ -0,0 +1,135 @@
+package entity
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// enrollmentTokenBytes is the number of random bytes in an enrollment token secret (256 bits).
+const enrollmentTokenBytes = 32
+
+// EnrollmentTokenState is the derived state of an enrollment token.
+type EnrollmentTokenState string
+
+const (
+	// EnrollmentTokenPending means the token can still be used for provisioning.
+	EnrollmentTokenPending EnrollmentTokenState = "PENDING"
+	// EnrollmentTokenUsed means the token has been consumed by provisioning.
+	EnrollmentTokenUsed EnrollmentTokenState = "USED"
+	// EnrollmentTokenRevoked means the token has been revoked by an operator.
+	EnrollmentTokenRevoked EnrollmentTokenState = "REVOKED"
+	// EnrollmentTokenExpired means the token has expired without being used.
+	EnrollmentTokenExpired EnrollmentTokenState = "EXPIRED"
+)
+
+// EnrollmentToken is a one-time secret that authorizes a device to be provisioned.
+// Only the hash of the secret is stored.
+type EnrollmentToken struct {
+	ID       uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	DeviceID uuid.UUID `gorm:"type:uuid;not null"`
+
+	// TokenHash is the hex-encoded SHA-256 hash of the secret.
+	TokenHash string `gorm:"not null"`
+
+	ExpiresAt time.Time `gorm:"not null"`
+	// UsedAt is set when the token is consumed by provisioning. Nil means unused.
+	UsedAt *time.Time
+	// RevokedAt is set when the token is revoked by an operator. Nil means not revoked.
+	RevokedAt *time.Time
+
+	// CreatedAt is automatically managed by GORM.
+	CreatedAt time.Time
+}
+
+// NewEnrollmentToken creates a new EnrollmentToken for a device that expires after ttl.
+// It returns the token together with its plaintext secret, which is not stored anywhere
+// and must be handed to the device exactly once.
+func NewEnrollmentToken(deviceID uuid.UUID, ttl time.Duration, now time.Time) (*EnrollmentToken, string, error) {
+	if ttl <= 0 {
+		return nil, "", ErrEnrollmentTokenTTLInvalid
+	}
+
+	buf := make([]byte, enrollmentTokenBytes)
+
+	_, err := rand.Read(buf)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate enrollment token: %w", err)
+	}
+
+	secret := base64.RawURLEncoding.EncodeToString(buf)
+
+	return &EnrollmentToken{
+		ID:        uuid.Nil,
+		DeviceID:  deviceID,
+		TokenHash: HashEnrollmentToken(secret),
+		ExpiresAt: now.Add(ttl),
+		UsedAt:    nil,
+		RevokedAt: nil,
+		CreatedAt: time.Time{},
+	}, secret, nil
+}
+
+// HashEnrollmentToken returns the hex-encoded SHA-256 hash of an enrollment token secret.
+// The secret is high-entropy random data, so a fast hash is sufficient.
+func HashEnrollmentToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks that the token matches the secret, belongs to the device, and is still usable at now.
+func (t *EnrollmentToken) Verify(token string, deviceID uuid.UUID, now time.Time) error {
+	hash := HashEnrollmentToken(token)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(t.TokenHash)) != 1 || t.DeviceID != deviceID {
+		return ErrEnrollmentTokenInvalid
+	}
+
+	switch t.State(now) {
+	case EnrollmentTokenUsed:
+		return ErrEnrollmentTokenUsed
+	case EnrollmentTokenRevoked:
+		return ErrEnrollmentTokenRevoked
+	case EnrollmentTokenExpired:
+		return ErrEnrollmentTokenExpired
+	case EnrollmentTokenPending:
+	}
+
+	return nil
+}
+
+// State returns the state of the token at now.
+func (t *EnrollmentToken) State(now time.Time) EnrollmentTokenState {
+	switch {
+	case t.UsedAt != nil:
+		return EnrollmentTokenUsed
+	case t.RevokedAt != nil:
+		return EnrollmentTokenRevoked
+	case !now.Before(t.ExpiresAt):
+		return EnrollmentTokenExpired
+	default:
+		return EnrollmentTokenPending
+	}
+}
+
+// Revoke revokes the token at now so that it can no longer be used.
+// A used or already revoked token cannot be revoked.
+func (t *EnrollmentToken) Revoke(now time.Time) error {
+	switch t.State(now) {
+	case EnrollmentTokenUsed:
+		return ErrEnrollmentTokenUsed
+	case EnrollmentTokenRevoked:
+		return ErrEnrollmentTokenRevoked
+	case EnrollmentTokenPending, EnrollmentTokenExpired:
+	}
+
+	t.RevokedAt = &now
+
+	return nil
+}
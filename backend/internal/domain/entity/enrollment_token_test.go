@@ -0,0 +1,113 @@
+package entity_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"backend/internal/domain/entity"
+
+	"github.com/google/uuid"
+)
+
+// TestEnrollmentTokenVerify tests the Verify method of EnrollmentToken.
+func TestEnrollmentTokenVerify(t *testing.T) {
+	t.Parallel()
+
+	deviceID := uuid.New()
+	now := time.Now()
+	usedAt := now.Add(-time.Minute)
+
+	tests := []struct {
+		name     string
+		desc     string
+		secret   string
+		deviceID uuid.UUID
+		expires  time.Time
+		usedAt   *time.Time
+		revoked  *time.Time
+		wantErr  error
+	}{
+		{
+			name:     "success: valid token",
+			desc:     "Verify that a matching, unused and unexpired token is accepted.",
+			secret:   "secret",
+			deviceID: deviceID,
+			expires:  now.Add(time.Hour),
+			usedAt:   nil,
+			revoked:  nil,
+			wantErr:  nil,
+		},
+		{
+			name:     "failure: wrong secret",
+			desc:     "Verify that a token whose hash does not match is rejected.",
+			secret:   "other-secret",
+			deviceID: deviceID,
+			expires:  now.Add(time.Hour),
+			usedAt:   nil,
+			revoked:  nil,
+			wantErr:  entity.ErrEnrollmentTokenInvalid,
+		},
+		{
+			name:     "failure: another device",
+			desc:     "Verify that a token issued for another device is rejected.",
+			secret:   "secret",
+			deviceID: uuid.New(),
+			expires:  now.Add(time.Hour),
+			usedAt:   nil,
+			revoked:  nil,
+			wantErr:  entity.ErrEnrollmentTokenInvalid,
+		},
+		{
+			name:     "failure: already used",
+			desc:     "Verify that a used token is rejected.",
+			secret:   "secret",
+			deviceID: deviceID,
+			expires:  now.Add(time.Hour),
+			usedAt:   &usedAt,
+			revoked:  nil,
+			wantErr:  entity.ErrEnrollmentTokenUsed,
+		},
+		{
+			name:     "failure: revoked",
+			desc:     "Verify that a revoked token is rejected.",
+			secret:   "secret",
+			deviceID: deviceID,
+			expires:  now.Add(time.Hour),
+			usedAt:   nil,
+			revoked:  &usedAt,
+			wantErr:  entity.ErrEnrollmentTokenRevoked,
+		},
+		{
+			name:     "failure: expired exactly now",
+			desc:     "Verify that a token is no longer usable at its expiry time.",
+			secret:   "secret",
+			deviceID: deviceID,
+			expires:  now,
+			usedAt:   nil,
+			revoked:  nil,
+			wantErr:  entity.ErrEnrollmentTokenExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			token := &entity.EnrollmentToken{
+				ID:        uuid.New(),
+				DeviceID:  deviceID,
+				TokenHash: entity.HashEnrollmentToken("secret"),
+				ExpiresAt: tt.expires,
+				UsedAt:    tt.usedAt,
+				RevokedAt: tt.revoked,
+				CreatedAt: now,
+			}
+
+			err := token.Verify(tt.secret, tt.deviceID, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package entity
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
 
 var (
 	// ErrHardwareIDEmpty is returned when a hardware ID is empty.
@@ -8,4 +13,130 @@ var (
 	// ErrUnsupportedTypeForJSONBMapScan is returned when an unsupported type is used for scanning a JSONBMap.
 	ErrUnsupportedTypeForJSONBMapScan = errors.New("unsupported type for JSONBMap Scan")
 	ErrDeviceNotFound                 = errors.New("device not found")
+	// ErrEnrollmentTokenInvalid is returned when an enrollment token does not match or belongs to another device.
+	ErrEnrollmentTokenInvalid = errors.New("invalid enrollment token")
+	// ErrEnrollmentTokenExpired is returned when an enrollment token has expired.
+	ErrEnrollmentTokenExpired = errors.New("enrollment token has expired")
+	// ErrEnrollmentTokenUsed is returned when an enrollment token has already been used.
+	ErrEnrollmentTokenUsed = errors.New("enrollment token has already been used")
+	// ErrEnrollmentTokenRevoked is returned when an enrollment token has been revoked.
+	ErrEnrollmentTokenRevoked = errors.New("enrollment token has been revoked")
+	// ErrEnrollmentTokenTTLInvalid is returned when an enrollment token is created with a non-positive TTL.
+	ErrEnrollmentTokenTTLInvalid = errors.New("enrollment token ttl must be positive")
+	// ErrEnrollmentTokenNotFound is returned when an enrollment token does not exist.
+	ErrEnrollmentTokenNotFound = errors.New("enrollment token not found")
+	// ErrCertificateNotFound is returned when a certificate does not exist.
+	ErrCertificateNotFound = errors.New("certificate not found")
+	// ErrCertificateAlreadyRevoked is returned when revoking a certificate that has already been revoked.
+	ErrCertificateAlreadyRevoked = errors.New("certificate is already revoked")
+	// ErrAuditLogChainBroken is returned when an audit log entry does not link to the previous entry.
+	ErrAuditLogChainBroken = errors.New("audit log chain is broken")
+	// ErrAuditLogHashMismatch is returned when the content of an audit log entry does not match its hash.
+	ErrAuditLogHashMismatch = errors.New("audit log entry does not match its hash")
+	// ErrAuditLogSignatureInvalid is returned when the signature of an audit log entry is missing or invalid.
+	ErrAuditLogSignatureInvalid = errors.New("audit log entry signature is invalid")
+	// ErrSensorDataDeviceIDEmpty is returned when sensor data is created without a device ID.
+	ErrSensorDataDeviceIDEmpty = errors.New("sensor data device id cannot be empty")
+	// ErrMalformedPatch is returned when a JSON Patch or JSON Merge Patch document cannot be parsed.
+	ErrMalformedPatch = errors.New("malformed patch")
+	// ErrInvalidPatch is returned when a patch cannot be applied, e.g. because a path does not exist,
+	// a test operation fails, or the patched document is not valid.
+	ErrInvalidPatch = errors.New("patch cannot be applied")
+	// ErrVersionConflict is returned when a device has been updated since the version a change is based on.
+	ErrVersionConflict = errors.New("device has been modified")
+	// ErrUnsupportedTypeForJSONBStringsScan is returned when an unsupported type is used for scanning a JSONBStrings.
+	ErrUnsupportedTypeForJSONBStringsScan = errors.New("unsupported type for JSONBStrings Scan")
+	// ErrDeviceGroupNotFound is returned when a device group does not exist.
+	ErrDeviceGroupNotFound = errors.New("device group not found")
+	// ErrDeviceGroupNameEmpty is returned when a device group is given an empty name.
+	ErrDeviceGroupNameEmpty = errors.New("device group name cannot be empty")
+	// ErrDeviceGroupNameTaken is returned when a device group is given the name of another group.
+	ErrDeviceGroupNameTaken = errors.New("device group name is already taken")
+	// ErrDeviceGroupMemberNotFound is returned when removing a device that has not been added to a group.
+	ErrDeviceGroupMemberNotFound = errors.New("device is not a member of the group")
+	// ErrDeviceTopologyCycle is returned when attaching a device would make it its own ancestor.
+	ErrDeviceTopologyCycle = errors.New("device topology cannot have cycles")
+	// ErrDeviceAlreadyAttached is returned when attaching a device that is already the child of another device.
+	ErrDeviceAlreadyAttached = errors.New("device is already attached to another parent")
+	// ErrDeviceNotChild is returned when detaching a device from a device that is not its parent.
+	ErrDeviceNotChild = errors.New("device is not a child of the parent")
+	// ErrInvalidTwinState is returned when a desired or reported state of a device twin is not a JSON object.
+	ErrInvalidTwinState = errors.New("twin state must be a JSON object")
+	// ErrDeviceTwinVersionConflict is returned when a state of a device twin has been changed
+	// since the version a change is based on.
+	ErrDeviceTwinVersionConflict = errors.New("device twin has been modified")
+	// ErrInvalidCommandName is returned when the name of a device command is empty or too long.
+	ErrInvalidCommandName = errors.New("command name must be between 1 and 64 characters")
+	// ErrDeviceCommandTTLInvalid is returned when a device command is created with a non-positive TTL.
+	ErrDeviceCommandTTLInvalid = errors.New("device command TTL must be positive")
+	// ErrDeviceCommandNotFound is returned when a command is not found among the commands of a device.
+	ErrDeviceCommandNotFound = errors.New("device command not found")
+	// ErrDeviceCommandExpired is returned when a device answers a command after its TTL.
+	ErrDeviceCommandExpired = errors.New("device command has expired")
+	// ErrDeviceCommandStatusConflict is returned when the status of a device command has been changed
+	// since it was read, e.g. because the device answered while it was being marked as sent.
+	ErrDeviceCommandStatusConflict = errors.New("device command status has been modified")
+	// ErrInvalidFirmwareVersion is returned when the version of a firmware artifact is empty or too long.
+	ErrInvalidFirmwareVersion = errors.New("firmware version must be between 1 and 64 characters")
+	// ErrFirmwareVersionTaken is returned when a firmware artifact is uploaded for a version that already exists.
+	ErrFirmwareVersionTaken = errors.New("firmware version already exists")
+	// ErrFirmwareArtifactNotFound is returned when a firmware artifact does not exist.
+	ErrFirmwareArtifactNotFound = errors.New("firmware artifact not found")
+	// ErrFirmwareArtifactInUse is returned when deleting a firmware artifact that a campaign rolls out.
+	ErrFirmwareArtifactInUse = errors.New("firmware artifact is used by a campaign")
+	// ErrFirmwareCampaignNotFound is returned when a firmware campaign does not exist.
+	ErrFirmwareCampaignNotFound = errors.New("firmware campaign not found")
+	// ErrFirmwareCampaignNameEmpty is returned when a firmware campaign is given an empty name.
+	ErrFirmwareCampaignNameEmpty = errors.New("firmware campaign name cannot be empty")
+	// ErrInvalidWaveSize is returned when a firmware campaign is given a wave size that is not positive.
+	ErrInvalidWaveSize = errors.New("wave size must be positive")
+	// ErrInvalidFailureThreshold is returned when a failure threshold is not a percentage between 0 and 100.
+	ErrInvalidFailureThreshold = errors.New("failure threshold must be between 0 and 100")
+	// ErrFirmwareUpdateTimeoutInvalid is returned when a firmware campaign is given an update timeout
+	// shorter than a second.
+	ErrFirmwareUpdateTimeoutInvalid = errors.New("firmware update timeout must be at least a second")
+	// ErrFirmwareCampaignConflict is returned when a firmware campaign has been changed
+	// since the version a change is based on.
+	ErrFirmwareCampaignConflict = errors.New("firmware campaign has been modified")
+	// ErrFirmwareUpdateNotFound is returned when a device is not part of a firmware campaign.
+	ErrFirmwareUpdateNotFound = errors.New("firmware update not found")
+	// ErrFirmwareUpdateStatusConflict is returned when the status of a firmware update has been changed
+	// since it was read, e.g. because the device reported while it was being marked as sent.
+	ErrFirmwareUpdateStatusConflict = errors.New("firmware update status has been modified")
+	// ErrInvalidFirmwareProgress is returned when a device reports a progress that is not between 0 and 100.
+	ErrInvalidFirmwareProgress = errors.New("firmware update progress must be between 0 and 100")
+	// ErrInvalidHardwareRevision is returned when a firmware artifact is given a negative minimum hardware revision.
+	ErrInvalidHardwareRevision = errors.New("minimum hardware revision cannot be negative")
+	// ErrFirmwareManifestNotFound is returned when a firmware artifact has no signed manifest yet.
+	ErrFirmwareManifestNotFound = errors.New("firmware manifest not found")
+	// ErrFirmwareSigningKeyNotFound is returned when a firmware signing key does not exist.
+	ErrFirmwareSigningKeyNotFound = errors.New("firmware signing key not found")
+	// ErrFirmwareSigningKeyRetired is returned when retiring a firmware signing key that is already retired.
+	ErrFirmwareSigningKeyRetired = errors.New("firmware signing key is already retired")
+	// ErrFirmwareSigningKeyConflict is returned when the active firmware signing key has been changed
+	// since it was read, e.g. because the keys were rotated concurrently.
+	ErrFirmwareSigningKeyConflict = errors.New("firmware signing key has been rotated concurrently")
+	// ErrInvalidGracePeriod is returned when a firmware signing key is retired with a negative or too long
+	// grace period.
+	ErrInvalidGracePeriod = errors.New("invalid grace period")
 )
+
+// VersionConflictError is returned when a device cannot be updated or deleted,
+// because its current version is not the one the change is based on.
+// It wraps ErrVersionConflict, so it can be checked with errors.Is.
+type VersionConflictError struct {
+	ID uuid.UUID
+	// Expected is the version the change is based on, and Actual is the stored version.
+	Expected int64
+	Actual   int64
+}
+
+// Error implements the error interface.
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%s: device %s is at version %d, not %d", ErrVersionConflict.Error(), e.ID, e.Actual, e.Expected)
+}
+
+// Unwrap returns ErrVersionConflict.
+func (e *VersionConflictError) Unwrap() error {
+	return ErrVersionConflict
+}
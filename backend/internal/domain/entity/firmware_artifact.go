@@ -0,0 +1,75 @@
+package entity
+
+import (
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// maxFirmwareVersionLength is the maximum number of characters of a firmware version.
+const maxFirmwareVersionLength = 64
+
+// FirmwareArtifact is a firmware image in the registry, which campaigns roll out to devices.
+// The image itself is kept in the firmware storage; the artifact records where it is and how to verify it.
+type FirmwareArtifact struct {
+	ID uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+
+	// Version is the unique version of the firmware, as devices report it in their `firmware.version` metadata.
+	Version string `gorm:"uniqueIndex;not null"`
+	// Description is an optional free-form description, such as release notes.
+	Description string `gorm:"not null;default:''"`
+	// MinHardwareRevision is the lowest hardware revision the firmware runs on, or 0 for any revision.
+	MinHardwareRevision int `gorm:"not null;default:0"`
+	// Target holds conditions on the metadata, in the syntax of the metadata filters of the device list,
+	// that the devices the firmware is meant for satisfy. Campaigns only update those devices.
+	Target JSONBStrings `gorm:"type:jsonb;not null;default:'[]'"`
+
+	// Size is the size of the image in bytes.
+	Size int64 `gorm:"not null"`
+	// SHA256 is the hex-encoded SHA-256 hash of the image.
+	SHA256 string `gorm:"column:sha256;type:char(64);not null"`
+	// Signature is the signature of the SHA-256 hash of the image with the platform signing key.
+	Signature []byte `gorm:"not null"`
+	// SignatureAlgorithm is how the signature was made, e.g. "ECDSA-SHA256".
+	SignatureAlgorithm string `gorm:"type:varchar(32);not null"`
+	// StorageKey is the key of the image in the firmware storage.
+	StorageKey string `gorm:"not null"`
+
+	// CreatedAt is automatically managed by GORM.
+	CreatedAt time.Time
+}
+
+// NewFirmwareArtifact creates a new FirmwareArtifact for a version, whose image is set once it is stored.
+// Surrounding whitespace is removed from the version, which must be between 1 and 64 characters.
+// The target is not validated here, as its syntax belongs to the queries.
+func NewFirmwareArtifact(
+	version, description string,
+	minHardwareRevision int,
+	target []string,
+) (*FirmwareArtifact, error) {
+	version = strings.TrimSpace(version)
+	if version == "" || utf8.RuneCountInString(version) > maxFirmwareVersionLength {
+		return nil, ErrInvalidFirmwareVersion
+	}
+
+	if minHardwareRevision < 0 {
+		return nil, ErrInvalidHardwareRevision
+	}
+
+	return &FirmwareArtifact{
+		ID:                  uuid.Nil,
+		Version:             version,
+		Description:         description,
+		MinHardwareRevision: minHardwareRevision,
+		Target:              slices.Clone(JSONBStrings(target)),
+		Size:                0,
+		SHA256:              "",
+		Signature:           nil,
+		SignatureAlgorithm:  "",
+		StorageKey:          "",
+		CreatedAt:           time.Time{},
+	}, nil
+}
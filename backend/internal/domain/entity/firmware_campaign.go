@@ -0,0 +1,276 @@
+package entity
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/VO/campaignstatus"
+	"backend/internal/domain/VO/updatestatus"
+)
+
+const (
+	// maxFailureThreshold is the highest failure threshold, in percent, which never pauses a campaign.
+	maxFailureThreshold = 100
+	// maxFirmwareProgress is the progress of a completed download or installation, in percent.
+	maxFirmwareProgress = 100
+)
+
+// FirmwareCampaign rolls out a firmware artifact to the devices matching a filter, in waves of devices.
+// A wave starts once the updates of the previous wave have all finished, and the campaign is paused
+// when the share of failed updates exceeds its failure threshold.
+type FirmwareCampaign struct {
+	ID uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+
+	// Name is a user-friendly name of the campaign.
+	Name string `gorm:"not null"`
+	// FirmwareID is the firmware artifact being rolled out.
+	FirmwareID uuid.UUID `gorm:"type:uuid;not null"`
+
+	// Filter holds conditions on the metadata, such as "location.building=Factory-A", in the syntax of the metadata
+	// filters of the device list. The ACTIVE devices satisfying all of them when the campaign is created are updated.
+	Filter JSONBStrings `gorm:"type:jsonb;not null;default:'[]'"`
+	// WaveSize is the number of devices updated in each wave.
+	WaveSize int `gorm:"not null"`
+	// FailureThreshold is the percentage of failed updates, among those sent so far,
+	// above which the campaign is paused.
+	FailureThreshold int `gorm:"not null"`
+	// UpdateTimeoutSeconds is how long a device has to complete its update once it was sent.
+	UpdateTimeoutSeconds int64 `gorm:"not null"`
+
+	// Status is the status of the campaign.
+	// Changes must go through the transition methods (Pause, Resume, Cancel, Complete).
+	Status campaignstatus.Status `gorm:"type:varchar(20);not null"`
+	// StatusReason explains the status, e.g. why the campaign was paused.
+	StatusReason string `gorm:"not null;default:''"`
+	// CurrentWave is the number of the last wave started, from 1 to TotalWaves, or 0 before the first wave.
+	CurrentWave int `gorm:"not null;default:0"`
+	// TotalWaves is the number of waves of the campaign.
+	TotalWaves int `gorm:"not null"`
+
+	// Version is incremented with every change, so that concurrent changes are detected.
+	Version int64 `gorm:"not null;default:1"`
+
+	// CreatedAt and UpdatedAt are automatically managed by GORM.
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// FirmwareUpdate is the update of one device in a firmware campaign.
+type FirmwareUpdate struct {
+	CampaignID uuid.UUID `gorm:"primaryKey;type:uuid"`
+	DeviceID   uuid.UUID `gorm:"primaryKey;type:uuid"`
+
+	// Wave is the number of the wave the device is updated in, starting at 1.
+	Wave int `gorm:"not null"`
+	// Status is the progress of the update.
+	// Changes must go through the transition methods (MarkSent, Report, Fail).
+	Status updatestatus.Status `gorm:"type:varchar(20);not null"`
+	// Progress is the percentage of the current step the device last reported.
+	Progress int `gorm:"not null;default:0"`
+	// FailureReason is the reason the update failed.
+	FailureReason string `gorm:"not null;default:''"`
+
+	// SentAt is set when the update is sent to the device.
+	SentAt *time.Time
+	// CompletedAt is set when the update reaches a terminal status.
+	CompletedAt *time.Time
+	// UpdatedAt is automatically managed by GORM.
+	UpdatedAt time.Time
+}
+
+// NewFirmwareCampaign creates a new RUNNING FirmwareCampaign, whose waves are assigned by AssignWaves.
+// The filter is not validated here, as its syntax belongs to the queries.
+func NewFirmwareCampaign(
+	name string,
+	firmwareID uuid.UUID,
+	filter []string,
+	waveSize, failureThreshold int,
+	updateTimeout time.Duration,
+) (*FirmwareCampaign, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrFirmwareCampaignNameEmpty
+	}
+
+	if waveSize <= 0 {
+		return nil, ErrInvalidWaveSize
+	}
+
+	if failureThreshold < 0 || failureThreshold > maxFailureThreshold {
+		return nil, ErrInvalidFailureThreshold
+	}
+
+	if updateTimeout < time.Second {
+		return nil, ErrFirmwareUpdateTimeoutInvalid
+	}
+
+	return &FirmwareCampaign{
+		ID:                   uuid.Nil,
+		Name:                 name,
+		FirmwareID:           firmwareID,
+		Filter:               slices.Clone(JSONBStrings(filter)),
+		WaveSize:             waveSize,
+		FailureThreshold:     failureThreshold,
+		UpdateTimeoutSeconds: int64(updateTimeout / time.Second),
+		Status:               campaignstatus.Running,
+		StatusReason:         "",
+		CurrentWave:          0,
+		TotalWaves:           0,
+		Version:              1,
+		CreatedAt:            time.Time{},
+		UpdatedAt:            time.Time{},
+	}, nil
+}
+
+// AssignWaves splits the devices into waves of WaveSize devices, in their order,
+// and returns a PENDING update for each of them. The updates belong to the campaign ID as it is then,
+// so a new campaign sets it on them once it has been saved.
+func (c *FirmwareCampaign) AssignWaves(deviceIDs []uuid.UUID) []*FirmwareUpdate {
+	updates := make([]*FirmwareUpdate, 0, len(deviceIDs))
+
+	for i, deviceID := range deviceIDs {
+		updates = append(updates, &FirmwareUpdate{
+			CampaignID:    c.ID,
+			DeviceID:      deviceID,
+			Wave:          i/c.WaveSize + 1,
+			Status:        updatestatus.Pending,
+			Progress:      0,
+			FailureReason: "",
+			SentAt:        nil,
+			CompletedAt:   nil,
+			UpdatedAt:     time.Time{},
+		})
+	}
+
+	c.TotalWaves = (len(deviceIDs) + c.WaveSize - 1) / c.WaveSize
+
+	return updates
+}
+
+// UpdateTimeout returns how long a device has to complete its update once it was sent.
+func (c *FirmwareCampaign) UpdateTimeout() time.Duration {
+	return time.Duration(c.UpdateTimeoutSeconds) * time.Second
+}
+
+// ExceedsFailureThreshold reports whether failed of the sent updates are more than the failure threshold allows.
+func (c *FirmwareCampaign) ExceedsFailureThreshold(failed, sent int64) bool {
+	return sent > 0 && failed*maxFailureThreshold > int64(c.FailureThreshold)*sent
+}
+
+// SetFailureThreshold changes the percentage of failed updates above which the campaign is paused.
+func (c *FirmwareCampaign) SetFailureThreshold(failureThreshold int) error {
+	if failureThreshold < 0 || failureThreshold > maxFailureThreshold {
+		return ErrInvalidFailureThreshold
+	}
+
+	c.FailureThreshold = failureThreshold
+
+	return nil
+}
+
+// StartNextWave moves the campaign to its next wave. It returns false if the last wave has already started.
+func (c *FirmwareCampaign) StartNextWave() bool {
+	if c.Status != campaignstatus.Running || c.CurrentWave >= c.TotalWaves {
+		return false
+	}
+
+	c.CurrentWave++
+
+	return true
+}
+
+// Pause stops the campaign from starting further waves, for the reason given.
+func (c *FirmwareCampaign) Pause(reason string) error {
+	return c.transition(campaignstatus.Paused, reason)
+}
+
+// Resume lets a paused campaign start its further waves again.
+func (c *FirmwareCampaign) Resume() error {
+	return c.transition(campaignstatus.Running, "")
+}
+
+// Cancel stops the campaign for good. The devices already updating carry on.
+func (c *FirmwareCampaign) Cancel() error {
+	return c.transition(campaignstatus.Cancelled, "")
+}
+
+// Complete records that the updates of all the waves have finished.
+func (c *FirmwareCampaign) Complete() error {
+	return c.transition(campaignstatus.Completed, "")
+}
+
+func (c *FirmwareCampaign) transition(next campaignstatus.Status, reason string) error {
+	status, err := c.Status.TransitionTo(next)
+	if err != nil {
+		return err
+	}
+
+	c.Status = status
+	c.StatusReason = reason
+
+	return nil
+}
+
+// MarkSent records that the update has been sent to the device.
+func (u *FirmwareUpdate) MarkSent(now time.Time) error {
+	next, err := u.Status.TransitionTo(updatestatus.Sent)
+	if err != nil {
+		return err
+	}
+
+	u.Status = next
+	u.SentAt = &now
+
+	return nil
+}
+
+// Report records the progress reported by the device: DOWNLOADING or INSTALLING with the percentage done,
+// or the outcome, SUCCEEDED or FAILED with a reason.
+func (u *FirmwareUpdate) Report(status updatestatus.Status, progress int, reason string, now time.Time) error {
+	if status == updatestatus.Pending || status == updatestatus.Sent {
+		return &updatestatus.TransitionError{From: u.Status, To: status, Err: updatestatus.ErrInvalidTransition}
+	}
+
+	if progress < 0 || progress > maxFirmwareProgress {
+		return ErrInvalidFirmwareProgress
+	}
+
+	next, err := u.Status.TransitionTo(status)
+	if err != nil {
+		return err
+	}
+
+	u.Status = next
+	u.Progress = progress
+
+	if next == updatestatus.Succeeded {
+		u.Progress = maxFirmwareProgress
+	}
+
+	if next == updatestatus.Failed {
+		u.FailureReason = reason
+	}
+
+	if next.IsTerminal() {
+		u.CompletedAt = &now
+	}
+
+	return nil
+}
+
+// Fail records that the update could not be sent or completed, for the reason given.
+func (u *FirmwareUpdate) Fail(reason string, now time.Time) error {
+	next, err := u.Status.TransitionTo(updatestatus.Failed)
+	if err != nil {
+		return err
+	}
+
+	u.Status = next
+	u.FailureReason = reason
+	u.CompletedAt = &now
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package entity_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/campaignstatus"
+	"backend/internal/domain/VO/updatestatus"
+	"backend/internal/domain/entity"
+
+	"github.com/google/uuid"
+)
+
+// TestNewFirmwareArtifact tests the NewFirmwareArtifact function.
+func TestNewFirmwareArtifact(t *testing.T) {
+	t.Parallel()
+
+	artifact, err := entity.NewFirmwareArtifact(" 1.2.0 ", "Fixes the sensor drift", 3, []string{"model=X100"})
+	if err != nil {
+		t.Fatalf("NewFirmwareArtifact() unexpected error: %v", err)
+	}
+
+	if artifact.Version != "1.2.0" {
+		t.Errorf("NewFirmwareArtifact() version = %q, want %q", artifact.Version, "1.2.0")
+	}
+
+	if artifact.MinHardwareRevision != 3 || len(artifact.Target) != 1 {
+		t.Errorf("NewFirmwareArtifact() = %+v, want hardware revision 3 and a target", artifact)
+	}
+
+	for _, version := range []string{"", " ", strings.Repeat("あ", 65)} {
+		_, err = entity.NewFirmwareArtifact(version, "", 0, nil)
+		if !errors.Is(err, entity.ErrInvalidFirmwareVersion) {
+			t.Errorf("NewFirmwareArtifact(%q) error = %v, want %v", version, err, entity.ErrInvalidFirmwareVersion)
+		}
+	}
+
+	_, err = entity.NewFirmwareArtifact("1.2.0", "", -1, nil)
+	if !errors.Is(err, entity.ErrInvalidHardwareRevision) {
+		t.Errorf("NewFirmwareArtifact(-1) error = %v, want %v", err, entity.ErrInvalidHardwareRevision)
+	}
+}
+
+// TestNewFirmwareCampaign tests the NewFirmwareCampaign function.
+func TestNewFirmwareCampaign(t *testing.T) {
+	t.Parallel()
+
+	campaign, err := entity.NewFirmwareCampaign(" Roll out ", uuid.New(), nil, 10, 20, time.Hour)
+	if err != nil {
+		t.Fatalf("NewFirmwareCampaign() unexpected error: %v", err)
+	}
+
+	if campaign.Name != "Roll out" || campaign.Status != campaignstatus.Running {
+		t.Errorf("NewFirmwareCampaign() = %+v, want a RUNNING campaign named Roll out", campaign)
+	}
+
+	if campaign.UpdateTimeout() != time.Hour {
+		t.Errorf("NewFirmwareCampaign() update timeout = %v, want %v", campaign.UpdateTimeout(), time.Hour)
+	}
+
+	tests := []struct {
+		name             string
+		waveSize         int
+		failureThreshold int
+		timeout          time.Duration
+		wantErr          error
+	}{
+		{name: " ", waveSize: 1, failureThreshold: 0, timeout: time.Hour, wantErr: entity.ErrFirmwareCampaignNameEmpty},
+		{name: "a", waveSize: 0, failureThreshold: 0, timeout: time.Hour, wantErr: entity.ErrInvalidWaveSize},
+		{name: "a", waveSize: 1, failureThreshold: -1, timeout: time.Hour, wantErr: entity.ErrInvalidFailureThreshold},
+		{name: "a", waveSize: 1, failureThreshold: 101, timeout: time.Hour, wantErr: entity.ErrInvalidFailureThreshold},
+		{name: "a", waveSize: 1, failureThreshold: 0, timeout: 0, wantErr: entity.ErrFirmwareUpdateTimeoutInvalid},
+	}
+
+	for _, tt := range tests {
+		_, err = entity.NewFirmwareCampaign(tt.name, uuid.New(), nil, tt.waveSize, tt.failureThreshold, tt.timeout)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("NewFirmwareCampaign(%q, %d, %d, %v) error = %v, want %v",
+				tt.name, tt.waveSize, tt.failureThreshold, tt.timeout, err, tt.wantErr)
+		}
+	}
+}
+
+// TestFirmwareCampaignWaves tests the AssignWaves, StartNextWave and ExceedsFailureThreshold methods.
+func TestFirmwareCampaignWaves(t *testing.T) {
+	t.Parallel()
+
+	campaign, err := entity.NewFirmwareCampaign("Roll out", uuid.New(), nil, 2, 25, time.Hour)
+	if err != nil {
+		t.Fatalf("NewFirmwareCampaign() unexpected error: %v", err)
+	}
+
+	updates := campaign.AssignWaves([]uuid.UUID{uuid.New(), uuid.New(), uuid.New()})
+	if campaign.TotalWaves != 2 || len(updates) != 3 {
+		t.Fatalf("AssignWaves() total waves = %d, updates = %d, want 2 and 3", campaign.TotalWaves, len(updates))
+	}
+
+	for i, want := range []int{1, 1, 2} {
+		if updates[i].Wave != want || updates[i].Status != updatestatus.Pending {
+			t.Errorf("AssignWaves()[%d] = %+v, want a PENDING update in wave %d", i, updates[i], want)
+		}
+	}
+
+	if !campaign.StartNextWave() || !campaign.StartNextWave() || campaign.StartNextWave() {
+		t.Errorf("StartNextWave() should start the 2 waves only, current wave = %d", campaign.CurrentWave)
+	}
+
+	tests := []struct {
+		failed, sent int64
+		want         bool
+	}{
+		{failed: 0, sent: 0, want: false},
+		{failed: 1, sent: 4, want: false},
+		{failed: 2, sent: 4, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := campaign.ExceedsFailureThreshold(tt.failed, tt.sent); got != tt.want {
+			t.Errorf("ExceedsFailureThreshold(%d, %d) = %v, want %v", tt.failed, tt.sent, got, tt.want)
+		}
+	}
+
+	err = campaign.Pause("too many failures")
+	if err != nil || campaign.StatusReason != "too many failures" || campaign.StartNextWave() {
+		t.Errorf("Pause() error = %v, campaign = %+v", err, campaign)
+	}
+
+	err = campaign.Complete()
+	if !errors.Is(err, campaignstatus.ErrInvalidTransition) {
+		t.Errorf("Complete() of a paused campaign error = %v, want %v", err, campaignstatus.ErrInvalidTransition)
+	}
+}
+
+// TestFirmwareUpdateReport tests the MarkSent, Report and Fail methods of FirmwareUpdate.
+func TestFirmwareUpdateReport(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	update := &entity.FirmwareUpdate{Status: updatestatus.Pending} //nolint:exhaustruct
+
+	err := update.Report(updatestatus.Downloading, 10, "", now)
+	if !errors.Is(err, updatestatus.ErrInvalidTransition) {
+		t.Errorf("Report() before MarkSent() error = %v, want %v", err, updatestatus.ErrInvalidTransition)
+	}
+
+	err = update.MarkSent(now)
+	if err != nil || update.Status != updatestatus.Sent || update.SentAt == nil {
+		t.Fatalf("MarkSent() error = %v, update = %+v", err, update)
+	}
+
+	err = update.Report(updatestatus.Downloading, 101, "", now)
+	if !errors.Is(err, entity.ErrInvalidFirmwareProgress) {
+		t.Errorf("Report() with progress 101 error = %v, want %v", err, entity.ErrInvalidFirmwareProgress)
+	}
+
+	err = update.Report(updatestatus.Installing, 30, "", now)
+	if err != nil || update.Status != updatestatus.Installing || update.Progress != 30 {
+		t.Errorf("Report(INSTALLING) error = %v, update = %+v", err, update)
+	}
+
+	err = update.Report(updatestatus.Downloading, 0, "", now)
+	if !errors.Is(err, updatestatus.ErrInvalidTransition) {
+		t.Errorf("Report(DOWNLOADING) after INSTALLING error = %v, want %v", err, updatestatus.ErrInvalidTransition)
+	}
+
+	err = update.Report(updatestatus.Failed, 30, "flash error", now.Add(time.Minute))
+	if err != nil || update.FailureReason != "flash error" || update.CompletedAt == nil {
+		t.Errorf("Report(FAILED) error = %v, update = %+v", err, update)
+	}
+
+	err = update.Fail("timed out", now)
+	if !errors.Is(err, updatestatus.ErrInvalidTransition) {
+		t.Errorf("Fail() of a failed update error = %v, want %v", err, updatestatus.ErrInvalidTransition)
+	}
+}
@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FirmwareManifest is the signed description of a firmware artifact, which devices verify before they install
+// the image. The signature covers Payload exactly as stored, so that devices do not need to re-encode it.
+type FirmwareManifest struct {
+	FirmwareID uuid.UUID `gorm:"primaryKey;type:uuid"`
+
+	// KeyID is the ID of the FirmwareSigningKey that signed the manifest.
+	KeyID string `gorm:"type:varchar(64);not null"`
+	// Algorithm is how the key signed, e.g. "Ed25519".
+	Algorithm string `gorm:"type:varchar(32);not null"`
+	// Payload is the JSON encoding of the FirmwareManifestContent.
+	Payload []byte `gorm:"not null"`
+	// Signature is the signature of Payload with the key.
+	Signature []byte `gorm:"not null"`
+
+	// IssuedAt is when the manifest was signed.
+	IssuedAt time.Time `gorm:"not null"`
+}
+
+// FirmwareManifestContent is what a manifest states about a firmware artifact.
+type FirmwareManifestContent struct {
+	FirmwareID uuid.UUID `json:"firmwareId"`
+	Version    string    `json:"version"`
+	// SHA256 is the hex-encoded SHA-256 hash of the image, which devices compare with the image they downloaded.
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+	// MinHardwareRevision is the lowest hardware revision the firmware runs on, or 0 for any revision.
+	MinHardwareRevision int `json:"minHardwareRevision"`
+	// Target holds the conditions on the metadata of the devices the firmware is meant for.
+	Target []string `json:"target"`
+	// KeyID is the ID of the signing key, so that a manifest cannot be passed off as signed by another key.
+	KeyID    string    `json:"keyId"`
+	IssuedAt time.Time `json:"issuedAt"`
+}
+
+// NewFirmwareManifest creates the manifest of a stored firmware artifact, to be signed with an active key at now.
+// Its signature is set once it is signed.
+func NewFirmwareManifest(
+	artifact *FirmwareArtifact,
+	key *FirmwareSigningKey,
+	now time.Time,
+) (*FirmwareManifest, error) {
+	if !key.IsActive() {
+		return nil, ErrFirmwareSigningKeyRetired
+	}
+
+	target := slices.Clone([]string(artifact.Target))
+	if target == nil {
+		target = []string{}
+	}
+
+	payload, err := json.Marshal(FirmwareManifestContent{
+		FirmwareID:          artifact.ID,
+		Version:             artifact.Version,
+		SHA256:              artifact.SHA256,
+		Size:                artifact.Size,
+		MinHardwareRevision: artifact.MinHardwareRevision,
+		Target:              target,
+		KeyID:               key.ID,
+		IssuedAt:            now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal firmware manifest: %w", err)
+	}
+
+	return &FirmwareManifest{
+		FirmwareID: artifact.ID,
+		KeyID:      key.ID,
+		Algorithm:  key.Algorithm,
+		Payload:    payload,
+		Signature:  nil,
+		IssuedAt:   now,
+	}, nil
+}
@@ -0,0 +1,95 @@
+package entity_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"backend/internal/domain/entity"
+
+	"github.com/google/uuid"
+)
+
+// TestFirmwareSigningKeyRetire tests the Retire, IsActive and IsVerifiableAt methods.
+func TestFirmwareSigningKeyRetire(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	key := entity.NewFirmwareSigningKey("0123456789abcdef", "Ed25519", []byte{0x30})
+
+	if !key.IsActive() || !key.IsVerifiableAt(now.Add(365*24*time.Hour)) {
+		t.Fatalf("NewFirmwareSigningKey() = %+v, want an active key verifiable forever", key)
+	}
+
+	err := key.Retire(now, -time.Second)
+	if !errors.Is(err, entity.ErrInvalidGracePeriod) {
+		t.Errorf("Retire(-1s) error = %v, want %v", err, entity.ErrInvalidGracePeriod)
+	}
+
+	err = key.Retire(now, time.Hour)
+	if err != nil {
+		t.Fatalf("Retire() unexpected error: %v", err)
+	}
+
+	if key.IsActive() {
+		t.Error("IsActive() = true after Retire(), want false")
+	}
+
+	if !key.IsVerifiableAt(now.Add(59*time.Minute)) || key.IsVerifiableAt(now.Add(time.Hour)) {
+		t.Errorf("IsVerifiableAt() does not follow the grace period ending at %v", key.ExpiresAt)
+	}
+
+	err = key.Retire(now, time.Hour)
+	if !errors.Is(err, entity.ErrFirmwareSigningKeyRetired) {
+		t.Errorf("Retire() twice error = %v, want %v", err, entity.ErrFirmwareSigningKeyRetired)
+	}
+}
+
+// TestNewFirmwareManifest tests the NewFirmwareManifest function.
+func TestNewFirmwareManifest(t *testing.T) {
+	t.Parallel()
+
+	artifact, err := entity.NewFirmwareArtifact("1.2.0", "", 2, nil)
+	if err != nil {
+		t.Fatalf("NewFirmwareArtifact() unexpected error: %v", err)
+	}
+
+	artifact.ID = uuid.New()
+	artifact.Size = 1024
+	artifact.SHA256 = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	key := entity.NewFirmwareSigningKey("0123456789abcdef", "Ed25519", []byte{0x30})
+	now := time.Now().UTC()
+
+	manifest, err := entity.NewFirmwareManifest(artifact, key, now)
+	if err != nil {
+		t.Fatalf("NewFirmwareManifest() unexpected error: %v", err)
+	}
+
+	if manifest.FirmwareID != artifact.ID || manifest.KeyID != key.ID || manifest.Algorithm != key.Algorithm {
+		t.Errorf("NewFirmwareManifest() = %+v, want the manifest of the artifact by the key", manifest)
+	}
+
+	var content entity.FirmwareManifestContent
+
+	err = json.Unmarshal(manifest.Payload, &content)
+	if err != nil {
+		t.Fatalf("NewFirmwareManifest() payload is not JSON: %v", err)
+	}
+
+	if content.Version != "1.2.0" || content.SHA256 != artifact.SHA256 || content.Size != 1024 ||
+		content.MinHardwareRevision != 2 || content.Target == nil || content.KeyID != key.ID {
+		t.Errorf("NewFirmwareManifest() content = %+v, want the fields of the artifact", content)
+	}
+
+	err = key.Retire(now, time.Hour)
+	if err != nil {
+		t.Fatalf("Retire() unexpected error: %v", err)
+	}
+
+	_, err = entity.NewFirmwareManifest(artifact, key, now)
+	if !errors.Is(err, entity.ErrFirmwareSigningKeyRetired) {
+		t.Errorf("NewFirmwareManifest() with a retired key error = %v, want %v",
+			err, entity.ErrFirmwareSigningKeyRetired)
+	}
+}
@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"slices"
+	"time"
+)
+
+// FirmwareSigningKey is a key that signs firmware manifests. Its private half is kept in the key store.
+// A single key is active at a time. Once a newer key takes over, it is retired, and devices can still verify
+// the manifests it signed until the end of its grace period.
+type FirmwareSigningKey struct {
+	// ID is the ID of the key in the key store, which devices find in the manifests it signed.
+	ID string `gorm:"primaryKey;type:varchar(64)"`
+	// Algorithm is how the key signs, e.g. "Ed25519" or "ECDSA-P256-SHA256".
+	Algorithm string `gorm:"type:varchar(32);not null"`
+	// PublicKey is the DER-encoded SubjectPublicKeyInfo of the key.
+	PublicKey []byte `gorm:"not null"`
+
+	// RetiredAt is set when the key stops signing manifests.
+	RetiredAt *time.Time
+	// ExpiresAt is the end of the grace period of a retired key, after which it no longer verifies manifests.
+	ExpiresAt *time.Time
+
+	// CreatedAt is automatically managed by GORM.
+	CreatedAt time.Time
+}
+
+// NewFirmwareSigningKey creates a new active FirmwareSigningKey for a key generated by the key store.
+func NewFirmwareSigningKey(id, algorithm string, publicKey []byte) *FirmwareSigningKey {
+	return &FirmwareSigningKey{
+		ID:        id,
+		Algorithm: algorithm,
+		PublicKey: slices.Clone(publicKey),
+		RetiredAt: nil,
+		ExpiresAt: nil,
+		CreatedAt: time.Time{},
+	}
+}
+
+// IsActive reports whether the key signs the manifests.
+func (k *FirmwareSigningKey) IsActive() bool {
+	return k.RetiredAt == nil
+}
+
+// IsVerifiableAt reports whether the manifests signed by the key are still to be trusted at now.
+func (k *FirmwareSigningKey) IsVerifiableAt(now time.Time) bool {
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// Retire stops the key from signing manifests at now. The manifests it signed remain verifiable
+// for the grace period, which lets devices fetch the manifests signed by its successor.
+func (k *FirmwareSigningKey) Retire(now time.Time, gracePeriod time.Duration) error {
+	if !k.IsActive() {
+		return ErrFirmwareSigningKeyRetired
+	}
+
+	if gracePeriod < 0 {
+		return ErrInvalidGracePeriod
+	}
+
+	expiresAt := now.Add(gracePeriod)
+	k.RetiredAt = &now
+	k.ExpiresAt = &expiresAt
+
+	return nil
+}
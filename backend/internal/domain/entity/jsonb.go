@@ -59,3 +59,54 @@ func (j *JSONBMap) Value() (driver.Value, error) {
 
 	return bytes, nil
 }
+
+// JSONBStrings is a custom type for `[]string` to handle JSONB array columns.
+type JSONBStrings []string
+
+// Scan implements the sql.Scanner interface, allowing the type to read from a database.
+func (j *JSONBStrings) Scan(value any) error {
+	if value == nil {
+		*j = JSONBStrings{}
+
+		return nil
+	}
+
+	var source []byte
+
+	switch v := value.(type) {
+	case []byte:
+		source = v
+	case string:
+		source = []byte(v)
+	default:
+		return ErrUnsupportedTypeForJSONBStringsScan
+	}
+
+	if len(source) == 0 {
+		*j = JSONBStrings{}
+
+		return nil
+	}
+
+	err := json.Unmarshal(source, j)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal JSONBStrings: %w", err)
+	}
+
+	return nil
+}
+
+// Value implements the driver.Valuer interface, allowing the type to be written to a database.
+// A nil slice is written as an empty JSON array, as the columns are not nullable.
+func (j JSONBStrings) Value() (driver.Value, error) {
+	if j == nil {
+		return "[]", nil
+	}
+
+	bytes, err := json.Marshal([]string(j))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSONBStrings: %w", err)
+	}
+
+	return bytes, nil
+}
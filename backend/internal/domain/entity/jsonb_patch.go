@@ -0,0 +1,419 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// JSON Patch operations (RFC 6902).
+const (
+	jsonPatchAdd     = "add"
+	jsonPatchRemove  = "remove"
+	jsonPatchReplace = "replace"
+	jsonPatchMove    = "move"
+	jsonPatchCopy    = "copy"
+	jsonPatchTest    = "test"
+)
+
+// jsonPatchOperation is an operation of a JSON Patch document.
+type jsonPatchOperation struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	From string `json:"from"`
+	// Value is kept raw to tell a missing value from null.
+	Value json.RawMessage `json:"value"`
+}
+
+// MergePatch returns the document with a JSON Merge Patch (RFC 7396) applied.
+// The document itself is not modified. The patch and the result must be JSON objects.
+func (j JSONBMap) MergePatch(patch []byte) (JSONBMap, error) {
+	var decoded any
+
+	err := json.Unmarshal(patch, &decoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedPatch, err)
+	}
+
+	if _, ok := decoded.(map[string]any); !ok {
+		return nil, fmt.Errorf("%w: merge patch must be a JSON object", ErrMalformedPatch)
+	}
+
+	document, err := j.document()
+	if err != nil {
+		return nil, err
+	}
+
+	result, _ := mergePatch(document, decoded).(map[string]any)
+
+	return result, nil
+}
+
+// JSONPatch returns the document with a JSON Patch (RFC 6902) applied.
+// The operations are applied in order, and if any of them fails, none of them is.
+// The document itself is not modified. The result must be a JSON object.
+func (j JSONBMap) JSONPatch(patch []byte) (JSONBMap, error) {
+	var operations []jsonPatchOperation
+
+	err := json.Unmarshal(patch, &operations)
+	if err != nil {
+		return nil, fmt.Errorf("%w: json patch must be an array of operations: %w", ErrMalformedPatch, err)
+	}
+
+	document, err := j.document()
+	if err != nil {
+		return nil, err
+	}
+
+	var result any = document
+
+	for i, operation := range operations {
+		result, err = applyJSONPatchOperation(result, operation)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, operation.Op, operation.Path, err)
+		}
+	}
+
+	object, ok := result.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: patched document must be a JSON object", ErrInvalidPatch)
+	}
+
+	return object, nil
+}
+
+// document returns a deep copy of the map as a plain JSON document, as json.Unmarshal would return it.
+func (j JSONBMap) document() (map[string]any, error) {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSONBMap: %w", err)
+	}
+
+	document := make(map[string]any)
+
+	err = json.Unmarshal(data, &document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSONBMap: %w", err)
+	}
+
+	return document, nil
+}
+
+// mergePatch applies a merge patch to a target value as described in RFC 7396.
+func mergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any)
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+
+			continue
+		}
+
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+
+	return targetObject
+}
+
+// applyJSONPatchOperation applies one JSON Patch operation to a document and returns the new document.
+func applyJSONPatchOperation(document any, operation jsonPatchOperation) (any, error) {
+	path, err := parseJSONPointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation.Op {
+	case jsonPatchAdd, jsonPatchReplace, jsonPatchTest:
+		value, err := decodePatchValue(operation.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		switch operation.Op {
+		case jsonPatchAdd:
+			return addValue(document, path, value)
+		case jsonPatchReplace:
+			return replaceValue(document, path, value)
+		default:
+			return document, testValue(document, path, value)
+		}
+	case jsonPatchRemove:
+		document, _, err = removeValue(document, path)
+
+		return document, err
+	case jsonPatchMove, jsonPatchCopy:
+		from, err := parseJSONPointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+
+		if operation.Op == jsonPatchCopy {
+			value, err := getValue(document, from)
+			if err != nil {
+				return nil, err
+			}
+
+			// The copy must not share maps or slices with the source.
+			return addValue(document, path, copyJSONValue(value))
+		}
+
+		if len(from) < len(path) && isPointerPrefix(from, path) {
+			return nil, fmt.Errorf("%w: cannot move a value into itself", ErrInvalidPatch)
+		}
+
+		document, value, err := removeValue(document, from)
+		if err != nil {
+			return nil, err
+		}
+
+		return addValue(document, path, value)
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", ErrMalformedPatch, operation.Op)
+	}
+}
+
+// decodePatchValue decodes the value of an operation, which must be present.
+func decodePatchValue(raw json.RawMessage) (any, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("%w: operation requires a value", ErrMalformedPatch)
+	}
+
+	var value any
+
+	err := json.Unmarshal(raw, &value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedPatch, err)
+	}
+
+	return value, nil
+}
+
+// parseJSONPointer splits a JSON Pointer (RFC 6901) into its unescaped reference tokens.
+// The empty pointer refers to the whole document.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: invalid JSON pointer %q", ErrMalformedPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// isPointerPrefix reports whether the tokens of prefix are the first tokens of path.
+func isPointerPrefix(prefix, path []string) bool {
+	return len(prefix) <= len(path) && slices.Equal(prefix, path[:len(prefix)])
+}
+
+// getValue returns the value the path refers to.
+func getValue(document any, path []string) (any, error) {
+	value := document
+
+	for _, token := range path {
+		var err error
+
+		value, err = childValue(value, token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return value, nil
+}
+
+// childValue returns the member of an object or the element of an array the token refers to.
+func childValue(container any, token string) (any, error) {
+	switch container := container.(type) {
+	case map[string]any:
+		value, ok := container[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: member %q does not exist", ErrInvalidPatch, token)
+		}
+
+		return value, nil
+	case []any:
+		index, err := arrayIndex(token, len(container)-1)
+		if err != nil {
+			return nil, err
+		}
+
+		return container[index], nil
+	default:
+		return nil, fmt.Errorf("%w: cannot refer to %q in a scalar value", ErrInvalidPatch, token)
+	}
+}
+
+// updateParent applies update to the object or array that holds the last token of the path,
+// and returns the document with the updated container in place.
+func updateParent(document any, path []string, update func(container any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return update(document, path[0])
+	}
+
+	child, err := childValue(document, path[0])
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := updateParent(child, path[1:], update)
+	if err != nil {
+		return nil, err
+	}
+
+	switch container := document.(type) {
+	case map[string]any:
+		container[path[0]] = updated
+	case []any:
+		index, _ := arrayIndex(path[0], len(container)-1)
+		container[index] = updated
+	}
+
+	return document, nil
+}
+
+// addValue adds a member to an object, inserts an element into an array, or replaces the whole document.
+func addValue(document any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return updateParent(document, path, func(container any, token string) (any, error) {
+		switch container := container.(type) {
+		case map[string]any:
+			container[token] = value
+
+			return container, nil
+		case []any:
+			if token == "-" {
+				return append(container, value), nil
+			}
+
+			index, err := arrayIndex(token, len(container))
+			if err != nil {
+				return nil, err
+			}
+
+			return append(container[:index], append([]any{value}, container[index:]...)...), nil
+		default:
+			return nil, fmt.Errorf("%w: cannot add %q to a scalar value", ErrInvalidPatch, token)
+		}
+	})
+}
+
+// replaceValue replaces the existing value the path refers to.
+func replaceValue(document any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return updateParent(document, path, func(container any, token string) (any, error) {
+		_, err := childValue(container, token)
+		if err != nil {
+			return nil, err
+		}
+
+		switch container := container.(type) {
+		case map[string]any:
+			container[token] = value
+		case []any:
+			index, _ := arrayIndex(token, len(container)-1)
+			container[index] = value
+		}
+
+		return container, nil
+	})
+}
+
+// removeValue removes the value the path refers to, and returns the new document and the removed value.
+func removeValue(document any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidPatch)
+	}
+
+	var removed any
+
+	document, err := updateParent(document, path, func(container any, token string) (any, error) {
+		value, err := childValue(container, token)
+		if err != nil {
+			return nil, err
+		}
+
+		removed = value
+
+		switch container := container.(type) {
+		case map[string]any:
+			delete(container, token)
+
+			return container, nil
+		default:
+			elements, _ := container.([]any)
+			index, _ := arrayIndex(token, len(elements)-1)
+
+			return append(elements[:index:index], elements[index+1:]...), nil
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return document, removed, nil
+}
+
+// testValue checks that the value the path refers to is equal to the given value.
+func testValue(document any, path []string, value any) error {
+	actual, err := getValue(document, path)
+	if err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(actual, value) {
+		return fmt.Errorf("%w: test failed", ErrInvalidPatch)
+	}
+
+	return nil
+}
+
+// arrayIndex parses an array index of a JSON Pointer, which must not be greater than maxIndex.
+func arrayIndex(token string, maxIndex int) (int, error) {
+	// Leading zeros are not allowed by RFC 6901.
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPatch, token)
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > maxIndex {
+		return 0, fmt.Errorf("%w: array index %q is out of range", ErrInvalidPatch, token)
+	}
+
+	return index, nil
+}
+
+// copyJSONValue returns a deep copy of a JSON value.
+func copyJSONValue(value any) any {
+	data, _ := json.Marshal(value) //nolint:errchkjson // The value was decoded from JSON.
+
+	var copied any
+
+	_ = json.Unmarshal(data, &copied)
+
+	return copied
+}
@@ -0,0 +1,248 @@
+package entity_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"backend/internal/domain/entity"
+)
+
+func TestJSONBMapMergePatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		desc     string
+		document entity.JSONBMap
+		patch    string
+		want     entity.JSONBMap
+		wantErr  error
+	}{
+		{
+			name:     "success: nested key",
+			desc:     "Verify that a nested key is updated without resending its siblings.",
+			document: entity.JSONBMap{"config": map[string]any{"sync_interval_sec": 60.0, "alert": 40.0}},
+			patch:    `{"config": {"sync_interval_sec": 30}}`,
+			want:     entity.JSONBMap{"config": map[string]any{"sync_interval_sec": 30.0, "alert": 40.0}},
+			wantErr:  nil,
+		},
+		{
+			name:     "success: null removes a member",
+			desc:     "Verify that a null value removes the member.",
+			document: entity.JSONBMap{"a": "b", "c": map[string]any{"d": "e", "f": "g"}},
+			patch:    `{"a": "z", "c": {"f": null}}`,
+			want:     entity.JSONBMap{"a": "z", "c": map[string]any{"d": "e"}},
+			wantErr:  nil,
+		},
+		{
+			name:     "success: arrays are replaced",
+			desc:     "Verify that an array is replaced as a whole, as RFC 7396 specifies.",
+			document: entity.JSONBMap{"tags": []any{"a", "b"}},
+			patch:    `{"tags": ["c"]}`,
+			want:     entity.JSONBMap{"tags": []any{"c"}},
+			wantErr:  nil,
+		},
+		{
+			name:     "success: object replaces a scalar",
+			desc:     "Verify that an object patch applied to a scalar member creates an object.",
+			document: entity.JSONBMap{"a": "b"},
+			patch:    `{"a": {"c": "d", "e": null}}`,
+			want:     entity.JSONBMap{"a": map[string]any{"c": "d"}},
+			wantErr:  nil,
+		},
+		{
+			name:     "failure: not an object",
+			desc:     "Verify that a patch that is not a JSON object is rejected.",
+			document: entity.JSONBMap{},
+			patch:    `["a"]`,
+			want:     nil,
+			wantErr:  entity.ErrMalformedPatch,
+		},
+		{
+			name:     "failure: not JSON",
+			desc:     "Verify that a patch that is not JSON is rejected.",
+			document: entity.JSONBMap{},
+			patch:    `{`,
+			want:     nil,
+			wantErr:  entity.ErrMalformedPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			original := copyJSONBMap(t, tt.document)
+
+			got, err := tt.document.MergePatch([]byte(tt.patch))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MergePatch() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergePatch() = %v, want %v", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(tt.document, original) {
+				t.Errorf("MergePatch() modified the document: %v, want %v", tt.document, original)
+			}
+		})
+	}
+}
+
+func TestJSONBMapJSONPatch(t *testing.T) {
+	t.Parallel()
+
+	document := func() entity.JSONBMap {
+		return entity.JSONBMap{
+			"location": map[string]any{"building": "Factory-A", "floor": 2.0},
+			"tags":     []any{"a", "b"},
+			"a/b":      "slash",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		desc    string
+		patch   string
+		want    entity.JSONBMap
+		wantErr error
+	}{
+		{
+			name: "success: add, replace and remove",
+			desc: "Verify that the operations are applied in order.",
+			patch: `[
+				{"op": "add", "path": "/location/zone", "value": "shipping"},
+				{"op": "replace", "path": "/location/floor", "value": 3},
+				{"op": "remove", "path": "/a~1b"}
+			]`,
+			want: entity.JSONBMap{
+				"location": map[string]any{"building": "Factory-A", "floor": 3.0, "zone": "shipping"},
+				"tags":     []any{"a", "b"},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "success: array operations",
+			desc: "Verify that elements are inserted, appended and removed by index.",
+			patch: `[
+				{"op": "add", "path": "/tags/0", "value": "first"},
+				{"op": "add", "path": "/tags/-", "value": "last"},
+				{"op": "remove", "path": "/tags/1"}
+			]`,
+			want: entity.JSONBMap{
+				"location": map[string]any{"building": "Factory-A", "floor": 2.0},
+				"tags":     []any{"first", "b", "last"},
+				"a/b":      "slash",
+			},
+			wantErr: nil,
+		},
+		{
+			name: "success: move, copy and test",
+			desc: "Verify that values are moved and copied, and that a passing test changes nothing.",
+			patch: `[
+				{"op": "test", "path": "/location/building", "value": "Factory-A"},
+				{"op": "copy", "from": "/location", "path": "/previous"},
+				{"op": "move", "from": "/location/floor", "path": "/floor"}
+			]`,
+			want: entity.JSONBMap{
+				"location": map[string]any{"building": "Factory-A"},
+				"previous": map[string]any{"building": "Factory-A", "floor": 2.0},
+				"floor":    2.0,
+				"tags":     []any{"a", "b"},
+				"a/b":      "slash",
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "failure: test fails",
+			desc:    "Verify that a failing test rejects the whole patch.",
+			patch:   `[{"op": "remove", "path": "/tags"}, {"op": "test", "path": "/location/floor", "value": 5}]`,
+			want:    nil,
+			wantErr: entity.ErrInvalidPatch,
+		},
+		{
+			name:    "failure: replace a missing member",
+			desc:    "Verify that replace requires the member to exist.",
+			patch:   `[{"op": "replace", "path": "/location/zone", "value": "x"}]`,
+			want:    nil,
+			wantErr: entity.ErrInvalidPatch,
+		},
+		{
+			name:    "failure: index out of range",
+			desc:    "Verify that an array index past the end is rejected.",
+			patch:   `[{"op": "add", "path": "/tags/3", "value": "x"}]`,
+			want:    nil,
+			wantErr: entity.ErrInvalidPatch,
+		},
+		{
+			name:    "failure: move into itself",
+			desc:    "Verify that a value cannot be moved into one of its children.",
+			patch:   `[{"op": "move", "from": "/location", "path": "/location/inner"}]`,
+			want:    nil,
+			wantErr: entity.ErrInvalidPatch,
+		},
+		{
+			name:    "failure: result is not an object",
+			desc:    "Verify that the whole document cannot be replaced with a scalar.",
+			patch:   `[{"op": "replace", "path": "", "value": 1}]`,
+			want:    nil,
+			wantErr: entity.ErrInvalidPatch,
+		},
+		{
+			name:    "failure: missing value",
+			desc:    "Verify that an add operation without a value is rejected.",
+			patch:   `[{"op": "add", "path": "/x"}]`,
+			want:    nil,
+			wantErr: entity.ErrMalformedPatch,
+		},
+		{
+			name:    "failure: unknown operation",
+			desc:    "Verify that an unknown operation is rejected.",
+			patch:   `[{"op": "increment", "path": "/location/floor"}]`,
+			want:    nil,
+			wantErr: entity.ErrMalformedPatch,
+		},
+		{
+			name:    "failure: not an array",
+			desc:    "Verify that a patch that is not an array of operations is rejected.",
+			patch:   `{"op": "remove", "path": "/tags"}`,
+			want:    nil,
+			wantErr: entity.ErrMalformedPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			original := document()
+
+			got, err := original.JSONPatch([]byte(tt.patch))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("JSONPatch() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("JSONPatch() = %v, want %v", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(original, document()) {
+				t.Errorf("JSONPatch() modified the document: %v", original)
+			}
+		})
+	}
+}
+
+// copyJSONBMap returns a deep copy of a document, to check that patching does not modify it.
+func copyJSONBMap(t *testing.T, document entity.JSONBMap) entity.JSONBMap {
+	t.Helper()
+
+	copied, err := document.MergePatch([]byte(`{}`))
+	if err != nil {
+		t.Fatalf("failed to copy the document: %v", err)
+	}
+
+	return copied
+}
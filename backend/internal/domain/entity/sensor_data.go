@@ -0,0 +1,124 @@
+package entity
+
+import (
+	"encoding/json"
+	"maps"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxMetricNameLength is the length of the `sensor_metrics.name` column.
+const maxMetricNameLength = 100
+
+// SensorData is a reading reported by a device. It is stored in the telemetry database.
+// A device reports at most one reading per timestamp, so DeviceID and RecordedAt identify it.
+type SensorData struct {
+	DeviceID uuid.UUID `gorm:"primaryKey;type:uuid"`
+	// RecordedAt is when the device took the reading.
+	RecordedAt time.Time `gorm:"primaryKey"`
+	// ReceivedAt is when the server received the reading.
+	ReceivedAt time.Time `gorm:"not null"`
+
+	// Payload is the reading as reported by the device.
+	Payload JSONBMap `gorm:"type:jsonb;not null;default:'{}'"`
+
+	// Metrics are the numeric top-level fields of Payload.
+	// They are stored as typed rows in `sensor_metrics`, so they can be aggregated efficiently.
+	Metrics map[string]float64 `gorm:"-"`
+}
+
+// TableName returns the table name of SensorData, which is not pluralized.
+func (SensorData) TableName() string {
+	return "sensor_data"
+}
+
+// SensorMetric is a numeric field of a SensorData, stored in `sensor_metrics`.
+type SensorMetric struct {
+	DeviceID   uuid.UUID `gorm:"primaryKey;type:uuid"`
+	Name       string    `gorm:"primaryKey;type:varchar(100)"`
+	RecordedAt time.Time `gorm:"primaryKey"`
+	Value      float64   `gorm:"not null"`
+}
+
+// NewSensorData creates a new SensorData and extracts its metrics from the payload.
+// If recordedAt is zero, e.g. because the device has no clock, receivedAt is used instead.
+func NewSensorData(deviceID uuid.UUID, recordedAt, receivedAt time.Time, payload map[string]any) (*SensorData, error) {
+	if deviceID == uuid.Nil {
+		return nil, ErrSensorDataDeviceIDEmpty
+	}
+
+	if recordedAt.IsZero() {
+		recordedAt = receivedAt
+	}
+
+	newPayload := make(JSONBMap)
+	maps.Copy(newPayload, payload)
+
+	return &SensorData{
+		DeviceID: deviceID,
+		// Timestamps are truncated to the precision the database stores, so that duplicates are detected.
+		RecordedAt: recordedAt.UTC().Truncate(time.Microsecond),
+		ReceivedAt: receivedAt.UTC().Truncate(time.Microsecond),
+		Payload:    newPayload,
+		Metrics:    extractMetrics(newPayload),
+	}, nil
+}
+
+// MetricRows returns the metrics of the reading as rows of `sensor_metrics`.
+func (d *SensorData) MetricRows() []*SensorMetric {
+	rows := make([]*SensorMetric, 0, len(d.Metrics))
+
+	for name, value := range d.Metrics {
+		rows = append(rows, &SensorMetric{
+			DeviceID:   d.DeviceID,
+			Name:       name,
+			RecordedAt: d.RecordedAt,
+			Value:      value,
+		})
+	}
+
+	return rows
+}
+
+// extractMetrics returns the numeric top-level fields of a payload.
+// Nested objects, strings and booleans are kept in the payload only.
+func extractMetrics(payload map[string]any) map[string]float64 {
+	metrics := make(map[string]float64)
+
+	for name, raw := range payload {
+		if name == "" || len(name) > maxMetricNameLength {
+			continue
+		}
+
+		value, ok := toFloat64(raw)
+		if !ok || math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+
+		metrics[name] = value
+	}
+
+	return metrics
+}
+
+// toFloat64 converts a decoded JSON number to float64.
+func toFloat64(raw any) (float64, bool) {
+	switch v := raw.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		value, err := v.Float64()
+
+		return value, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,114 @@
+package entity_test
+
+import (
+	"errors"
+	"maps"
+	"math"
+	"testing"
+	"time"
+
+	"backend/internal/domain/entity"
+
+	"github.com/google/uuid"
+)
+
+// TestNewSensorData tests the NewSensorData function.
+func TestNewSensorData(t *testing.T) {
+	t.Parallel()
+
+	deviceID := uuid.New()
+	receivedAt := time.Date(2025, 1, 2, 3, 4, 5, 123456789, time.FixedZone("JST", 9*60*60))
+	recordedAt := receivedAt.Add(-time.Minute)
+
+	tests := []struct {
+		name           string
+		desc           string
+		deviceID       uuid.UUID
+		recordedAt     time.Time
+		payload        map[string]any
+		wantRecordedAt time.Time
+		wantMetrics    map[string]float64
+		wantErr        error
+	}{
+		{
+			name:       "numeric fields become metrics",
+			desc:       "Verify that only the numeric top-level fields of the payload are extracted as metrics.",
+			deviceID:   deviceID,
+			recordedAt: recordedAt,
+			payload: map[string]any{
+				"temperature": 21.5,
+				"humidity":    40,
+				"status":      "ok",
+				"door":        true,
+				"location":    map[string]any{"lat": 35.6},
+				"broken":      math.NaN(),
+			},
+			wantRecordedAt: recordedAt.UTC().Truncate(time.Microsecond),
+			wantMetrics:    map[string]float64{"temperature": 21.5, "humidity": 40},
+			wantErr:        nil,
+		},
+		{
+			name:           "missing timestamp",
+			desc:           "Verify that the time of receipt is used if the device did not report a timestamp.",
+			deviceID:       deviceID,
+			recordedAt:     time.Time{},
+			payload:        nil,
+			wantRecordedAt: receivedAt.UTC().Truncate(time.Microsecond),
+			wantMetrics:    map[string]float64{},
+			wantErr:        nil,
+		},
+		{
+			name:           "empty device ID",
+			desc:           "Verify that a reading without a device ID is rejected.",
+			deviceID:       uuid.Nil,
+			recordedAt:     recordedAt,
+			payload:        map[string]any{"temperature": 21.5},
+			wantRecordedAt: time.Time{},
+			wantMetrics:    nil,
+			wantErr:        entity.ErrSensorDataDeviceIDEmpty,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := entity.NewSensorData(tt.deviceID, tt.recordedAt, receivedAt, tt.payload)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("NewSensorData() error = %v, want %v", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("NewSensorData() unexpected error: %v", err)
+			}
+
+			if !got.RecordedAt.Equal(tt.wantRecordedAt) || got.RecordedAt.Location() != time.UTC {
+				t.Errorf("RecordedAt = %v, want %v", got.RecordedAt, tt.wantRecordedAt)
+			}
+
+			if got.ReceivedAt.Nanosecond()%int(time.Microsecond) != 0 {
+				t.Errorf("ReceivedAt = %v, want microsecond precision", got.ReceivedAt)
+			}
+
+			if !maps.Equal(got.Metrics, tt.wantMetrics) {
+				t.Errorf("Metrics = %v, want %v", got.Metrics, tt.wantMetrics)
+			}
+
+			rows := got.MetricRows()
+			if len(rows) != len(tt.wantMetrics) {
+				t.Fatalf("MetricRows() returned %d rows, want %d", len(rows), len(tt.wantMetrics))
+			}
+
+			for _, row := range rows {
+				if row.DeviceID != tt.deviceID || !row.RecordedAt.Equal(got.RecordedAt) {
+					t.Errorf("MetricRows() row %+v does not belong to the reading", row)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+)
+
+// AuditLogger records entries of the audit trail.
+type AuditLogger interface {
+	// Record appends an AuditLog entry to the hash chain.
+	// It sets the creation time, the previous hash, the hash and the signature of the entry.
+	// When called within a transaction, the entry is only kept if the transaction commits.
+	Record(ctx context.Context, log *entity.AuditLog) error
+}
+
+// AuditLogFilter narrows down the AuditLog entries returned by AuditLogRepository.FindAll.
+// Zero-valued fields do not filter.
+type AuditLogFilter struct {
+	DeviceID *uuid.UUID
+	Action   entity.AuditAction
+	Actor    string
+	// From and To bound the creation time of the entries; From is inclusive and To is exclusive.
+	From *time.Time
+	To   *time.Time
+	// BeforeID only returns entries older than the entry with this ID, for paging.
+	BeforeID int64
+	// Limit is the maximum number of entries to return.
+	Limit int
+}
+
+// AuditLogRepository defines the interface for persisting and querying AuditLog entries.
+type AuditLogRepository interface {
+	AuditLogger
+	// FindAll retrieves the AuditLog entries matching the filter, newest first.
+	FindAll(ctx context.Context, filter AuditLogFilter) ([]*entity.AuditLog, error)
+	// FindAfter retrieves up to limit AuditLog entries with an ID greater than afterID, oldest first.
+	// It is used to walk the hash chain.
+	FindAfter(ctx context.Context, afterID int64, limit int) ([]*entity.AuditLog, error)
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+)
+
+// CertificateRepository defines the interface for persisting Certificate entities.
+type CertificateRepository interface {
+	// Save stores a newly issued Certificate.
+	Save(ctx context.Context, certificate *entity.Certificate) error
+	// FindBySerialNumber retrieves a Certificate by its serial number.
+	FindBySerialNumber(ctx context.Context, serialNumber int64) (*entity.Certificate, error)
+	// FindByDeviceID retrieves all Certificates issued to a device, newest first.
+	FindByDeviceID(ctx context.Context, deviceID uuid.UUID) ([]*entity.Certificate, error)
+	// FindRevoked retrieves all revoked Certificates that have not expired at the given time.
+	FindRevoked(ctx context.Context, at time.Time) ([]*entity.Certificate, error)
+	// Revoke persists the revocation of a Certificate.
+	// It returns entity.ErrCertificateAlreadyRevoked if the certificate has already been revoked.
+	Revoke(ctx context.Context, certificate *entity.Certificate) error
+}
@@ -2,22 +2,171 @@
 package repository
 
 import (
+	"backend/internal/domain/VO/connectionstate"
+	"backend/internal/domain/VO/devicestatus"
 	"backend/internal/domain/entity"
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// DeviceSortField is a field devices can be sorted by.
+type DeviceSortField string
+
+const (
+	// DeviceSortCreatedAt sorts devices by their creation time.
+	DeviceSortCreatedAt DeviceSortField = "created_at"
+	// DeviceSortUpdatedAt sorts devices by their last update time.
+	DeviceSortUpdatedAt DeviceSortField = "updated_at"
+	// DeviceSortName sorts devices by their name.
+	DeviceSortName DeviceSortField = "name"
+)
+
+// IsValid reports whether the field is one of the known sort fields.
+func (f DeviceSortField) IsValid() bool {
+	switch f {
+	case DeviceSortCreatedAt, DeviceSortUpdatedAt, DeviceSortName:
+		return true
+	default:
+		return false
+	}
+}
+
+// MetadataOperator is the comparison of a MetadataCondition.
+type MetadataOperator string
+
+const (
+	// MetadataEquals matches devices whose metadata has the value at the path.
+	MetadataEquals MetadataOperator = "="
+	// MetadataNotEquals matches devices whose metadata does not have the value at the path,
+	// including devices without the path.
+	MetadataNotEquals MetadataOperator = "!="
+	// MetadataGreater, MetadataGreaterOrEqual, MetadataLess and MetadataLessOrEqual compare the value at the path
+	// with a number or a string. A value that looks like a version, such as 2.4 or "2.4.1", is also compared
+	// with the strings that look like versions, component by component. Values of another JSON type do not match.
+	MetadataGreater        MetadataOperator = ">"
+	MetadataGreaterOrEqual MetadataOperator = ">="
+	MetadataLess           MetadataOperator = "<"
+	MetadataLessOrEqual    MetadataOperator = "<="
+	// MetadataExists matches devices whose metadata has the path, whatever its value.
+	MetadataExists MetadataOperator = "exists"
+	// MetadataNotExists matches devices whose metadata does not have the path.
+	MetadataNotExists MetadataOperator = "not exists"
+)
+
+// IsOrdering reports whether the operator compares the order of values.
+func (o MetadataOperator) IsOrdering() bool {
+	switch o {
+	case MetadataGreater, MetadataGreaterOrEqual, MetadataLess, MetadataLessOrEqual:
+		return true
+	default:
+		return false
+	}
+}
+
+// MetadataCondition is a condition on the value at a path of the JSON metadata of a device.
+type MetadataCondition struct {
+	// Path is the sequence of object keys leading to the value, e.g. ["location", "building"].
+	Path     []string
+	Operator MetadataOperator
+	// Value is the JSON scalar compared with: a string, a json.Number, a bool or nil.
+	// It is not used by the existence checks.
+	Value any
+}
+
+// DeviceFilter narrows down the devices returned by DeviceRepository.FindByQuery and FindEach,
+// and counted by Count.
+// Zero-valued fields do not filter.
+type DeviceFilter struct {
+	Status devicestatus.Status
+	// HardwareIDPrefix only matches devices whose hardware ID starts with it.
+	HardwareIDPrefix string
+	// NameContains only matches devices whose name contains it, ignoring case.
+	NameContains string
+	// Metadata only matches devices whose metadata satisfies all the conditions.
+	Metadata []MetadataCondition
+	// Group only matches the members of a device group.
+	Group *GroupMembership
+	// ParentID only matches the children of the device.
+	ParentID *uuid.UUID
+	// Online, if true, only matches the CONNECTED devices, and if false, the DISCONNECTED and STALE ones.
+	Online *bool
+	// LastSeenBefore only matches the devices last seen before it, including those never seen.
+	LastSeenBefore *time.Time
+}
+
+// DevicePresence is what the broker observed of a device, as recorded by DeviceRepository.RecordPresence.
+type DevicePresence struct {
+	State connectionstate.State
+	// SeenAt, if not nil, becomes the time the device was last seen.
+	SeenAt *time.Time
+	// IP, if not empty, becomes the IP address the device last connected from.
+	IP string
+}
+
+// GroupMembership matches the members of a device group: the devices added to it,
+// and, if the group has a rule, the devices whose metadata satisfies all of its conditions.
+type GroupMembership struct {
+	GroupID uuid.UUID
+	Rule    []MetadataCondition
+}
+
+// DeviceCursor is the position of a device in a sorted list; a page starts after it.
+// Only the sort key of the field being sorted by has to be set, together with the ID that breaks ties.
+type DeviceCursor struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string
+}
+
+// DeviceQuery specifies a page of devices, or all of them if it has no cursor and no limit.
+type DeviceQuery struct {
+	Filter DeviceFilter
+	// SortBy is the field the devices are sorted by; the ID breaks ties.
+	SortBy     DeviceSortField
+	Descending bool
+	// After only returns devices that come after the cursor in the sort order, for paging.
+	After *DeviceCursor
+	// Limit is the maximum number of devices to return. Zero does not limit.
+	Limit int
+}
+
 // DeviceRepository defines the interface for persisting Device entities.
 type DeviceRepository interface {
-	// Save creates a new Device or updates an existing one.
+	// CreateAll creates new Device entities in one statement, and sets their IDs and versions.
+	CreateAll(ctx context.Context, devices []*entity.Device) error
+	// Save creates a new Device or updates an existing one, and sets its new version.
+	// An update only succeeds if the stored version is still device.Version;
+	// otherwise it returns a *entity.VersionConflictError.
 	Save(ctx context.Context, device *entity.Device) error
 	// FindByID retrieves a Device by its UUID.
 	FindByID(ctx context.Context, id uuid.UUID) (*entity.Device, error)
 	// FindByHardwareID retrieves a Device by its hardware ID.
 	FindByHardwareID(ctx context.Context, hardwareID string) (*entity.Device, error)
-	// FindAll retrieves all Device entities.
-	FindAll(ctx context.Context) ([]*entity.Device, error)
-	// Delete removes a Device by its UUID.
-	Delete(ctx context.Context, id uuid.UUID) error
+	// FindByHardwareIDs retrieves the Device entities with any of the hardware IDs, in no particular order.
+	FindByHardwareIDs(ctx context.Context, hardwareIDs []string) ([]*entity.Device, error)
+	// FindByQuery retrieves a page of the Device entities matching the query, in its sort order.
+	FindByQuery(ctx context.Context, query DeviceQuery) ([]*entity.Device, error)
+	// FindEach calls fn with each of the Device entities matching the query, in its sort order,
+	// reading them one at a time instead of loading them all. It stops at the first error fn returns.
+	// fn must not use the repository, because the connection is busy reading the devices meanwhile.
+	FindEach(ctx context.Context, query DeviceQuery, fn func(device *entity.Device) error) error
+	// FindAncestors retrieves the ancestors of a Device, from its parent up to the root of its tree.
+	FindAncestors(ctx context.Context, id uuid.UUID) ([]*entity.Device, error)
+	// LockTopology serializes the changes to the parents of devices until the end of the current transaction,
+	// so that concurrent attachments cannot form a cycle together.
+	LockTopology(ctx context.Context) error
+	// Count returns the number of Device entities matching the filter.
+	Count(ctx context.Context, filter DeviceFilter) (int64, error)
+	// RecordPresence records the presence of a Device, without changing its version or its update time.
+	// It returns entity.ErrDeviceNotFound if the device does not exist.
+	RecordPresence(ctx context.Context, id uuid.UUID, presence DevicePresence) error
+	// MarkStale marks the CONNECTED devices last seen before seenBefore as STALE,
+	// and returns how many were marked.
+	MarkStale(ctx context.Context, seenBefore time.Time) (int64, error)
+	// Delete removes a Device by its UUID, if its stored version is still version;
+	// otherwise it returns a *entity.VersionConflictError.
+	Delete(ctx context.Context, id uuid.UUID, version int64) error
 }
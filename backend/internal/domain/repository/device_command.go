@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/VO/commandstatus"
+	"backend/internal/domain/entity"
+)
+
+// DeviceCommandRepository defines the interface for persisting DeviceCommand entities.
+type DeviceCommandRepository interface {
+	// Save stores a newly created DeviceCommand.
+	// It returns entity.ErrDeviceNotFound if the device does not exist.
+	Save(ctx context.Context, command *entity.DeviceCommand) error
+	// FindByID retrieves a DeviceCommand of a device by its UUID.
+	FindByID(ctx context.Context, deviceID, id uuid.UUID) (*entity.DeviceCommand, error)
+	// FindQueued retrieves up to limit QUEUED DeviceCommands that have not expired at now, oldest first.
+	FindQueued(ctx context.Context, now time.Time, limit int) ([]*entity.DeviceCommand, error)
+	// UpdateStatus stores the status, the answer and the timestamps of a DeviceCommand,
+	// if its stored status is still from. Otherwise, it returns entity.ErrDeviceCommandStatusConflict.
+	UpdateStatus(ctx context.Context, command *entity.DeviceCommand, from commandstatus.Status) error
+	// ExpireOverdue marks the QUEUED and SENT DeviceCommands whose TTL has passed at now as EXPIRED,
+	// and returns how many were marked.
+	ExpireOverdue(ctx context.Context, now time.Time) (int64, error)
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+)
+
+// DeviceGroupRepository defines the interface for persisting DeviceGroup entities and their static members.
+type DeviceGroupRepository interface {
+	// Save creates a new DeviceGroup or updates an existing one.
+	// It returns entity.ErrDeviceGroupNameTaken if another group has the same name.
+	Save(ctx context.Context, group *entity.DeviceGroup) error
+	// FindByID retrieves a DeviceGroup by its UUID.
+	FindByID(ctx context.Context, id uuid.UUID) (*entity.DeviceGroup, error)
+	// FindAll retrieves all the DeviceGroup entities, sorted by name.
+	FindAll(ctx context.Context) ([]*entity.DeviceGroup, error)
+	// Delete removes a DeviceGroup by its UUID, together with its members.
+	// It returns entity.ErrDeviceGroupNotFound if the group does not exist.
+	Delete(ctx context.Context, id uuid.UUID) error
+	// AddMembers adds devices to a DeviceGroup and returns the IDs of those that were not members yet.
+	// It returns entity.ErrDeviceNotFound if any of the devices does not exist, in which case none is added.
+	AddMembers(ctx context.Context, groupID uuid.UUID, deviceIDs []uuid.UUID) ([]uuid.UUID, error)
+	// RemoveMember removes a device from a DeviceGroup.
+	// It returns entity.ErrDeviceGroupMemberNotFound if the device has not been added to the group.
+	RemoveMember(ctx context.Context, groupID, deviceID uuid.UUID) error
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+)
+
+// DeviceTwinRepository defines the interface for persisting DeviceTwin entities.
+// The desired and reported states are saved separately, so that the API and the device
+// do not overwrite each other's changes.
+type DeviceTwinRepository interface {
+	// FindByDeviceID retrieves the DeviceTwin of a device.
+	FindByDeviceID(ctx context.Context, deviceID uuid.UUID) (*entity.DeviceTwin, error)
+	// SaveDesired saves the desired state of a DeviceTwin whose desired version has just been incremented,
+	// creating the twin if needed. It returns entity.ErrDeviceTwinVersionConflict if the stored desired version
+	// is not the previous one, and entity.ErrDeviceNotFound if the device does not exist.
+	SaveDesired(ctx context.Context, twin *entity.DeviceTwin) error
+	// SaveReported saves the reported state of a DeviceTwin like SaveDesired saves the desired state.
+	SaveReported(ctx context.Context, twin *entity.DeviceTwin) error
+}
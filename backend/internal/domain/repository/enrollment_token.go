@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+)
+
+// EnrollmentTokenRepository defines the interface for persisting EnrollmentToken entities.
+type EnrollmentTokenRepository interface {
+	// Save stores a newly created EnrollmentToken.
+	Save(ctx context.Context, token *entity.EnrollmentToken) error
+	// FindByID retrieves an EnrollmentToken by its UUID.
+	FindByID(ctx context.Context, id uuid.UUID) (*entity.EnrollmentToken, error)
+	// FindByDeviceID retrieves all EnrollmentTokens of a device, newest first.
+	FindByDeviceID(ctx context.Context, deviceID uuid.UUID) ([]*entity.EnrollmentToken, error)
+	// FindByTokenHash retrieves an EnrollmentToken by the hash of its secret.
+	FindByTokenHash(ctx context.Context, tokenHash string) (*entity.EnrollmentToken, error)
+	// MarkUsed atomically marks an unused and unrevoked EnrollmentToken as used.
+	// It returns entity.ErrEnrollmentTokenUsed if the token has already been used or revoked.
+	MarkUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+	// Revoke atomically marks an unused and unrevoked EnrollmentToken as revoked.
+	// It returns entity.ErrEnrollmentTokenUsed if the token has already been used or revoked.
+	Revoke(ctx context.Context, id uuid.UUID, revokedAt time.Time) error
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+)
+
+// FirmwareArtifactRepository defines the interface for persisting FirmwareArtifact entities.
+type FirmwareArtifactRepository interface {
+	// Save stores a newly uploaded FirmwareArtifact.
+	// It returns entity.ErrFirmwareVersionTaken if an artifact has the same version.
+	Save(ctx context.Context, artifact *entity.FirmwareArtifact) error
+	// FindByID retrieves a FirmwareArtifact by its UUID.
+	FindByID(ctx context.Context, id uuid.UUID) (*entity.FirmwareArtifact, error)
+	// FindAll retrieves all the FirmwareArtifact entities, newest first.
+	FindAll(ctx context.Context) ([]*entity.FirmwareArtifact, error)
+	// Delete removes a FirmwareArtifact by its UUID.
+	// It returns entity.ErrFirmwareArtifactNotFound if the artifact does not exist,
+	// and entity.ErrFirmwareArtifactInUse if a campaign rolls it out.
+	Delete(ctx context.Context, id uuid.UUID) error
+	// SaveManifest stores the signed manifest of a FirmwareArtifact, replacing the previous one.
+	// It returns entity.ErrFirmwareArtifactNotFound if the artifact does not exist.
+	SaveManifest(ctx context.Context, manifest *entity.FirmwareManifest) error
+	// FindManifest retrieves the signed manifest of a FirmwareArtifact.
+	FindManifest(ctx context.Context, firmwareID uuid.UUID) (*entity.FirmwareManifest, error)
+}
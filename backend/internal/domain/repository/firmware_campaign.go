@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/VO/campaignstatus"
+	"backend/internal/domain/VO/updatestatus"
+	"backend/internal/domain/entity"
+)
+
+// FirmwareUpdateFilter narrows down the updates returned by FirmwareCampaignRepository.FindUpdates.
+// Zero-valued fields do not filter.
+type FirmwareUpdateFilter struct {
+	Status updatestatus.Status
+	// MaxWave only matches the updates of the waves up to it.
+	MaxWave int
+}
+
+// FirmwareCampaignRepository defines the interface for persisting FirmwareCampaign entities
+// and the FirmwareUpdate of each of their devices.
+type FirmwareCampaignRepository interface {
+	// Save creates a new FirmwareCampaign or updates an existing one, and increments its version.
+	// An update only succeeds if the stored version is still campaign.Version;
+	// otherwise it returns entity.ErrFirmwareCampaignConflict.
+	// It returns entity.ErrFirmwareArtifactNotFound if the firmware artifact does not exist.
+	Save(ctx context.Context, campaign *entity.FirmwareCampaign) error
+	// FindByID retrieves a FirmwareCampaign by its UUID.
+	FindByID(ctx context.Context, id uuid.UUID) (*entity.FirmwareCampaign, error)
+	// FindAll retrieves all the FirmwareCampaign entities, newest first.
+	FindAll(ctx context.Context) ([]*entity.FirmwareCampaign, error)
+	// FindByStatus retrieves the FirmwareCampaign entities with the status, oldest first.
+	FindByStatus(ctx context.Context, status campaignstatus.Status) ([]*entity.FirmwareCampaign, error)
+	// CreateUpdates stores the FirmwareUpdate entities of a new campaign.
+	CreateUpdates(ctx context.Context, updates []*entity.FirmwareUpdate) error
+	// FindUpdate retrieves the FirmwareUpdate of a device in a campaign.
+	FindUpdate(ctx context.Context, campaignID, deviceID uuid.UUID) (*entity.FirmwareUpdate, error)
+	// FindUpdates retrieves the FirmwareUpdate entities of a campaign matching the filter,
+	// by wave and then by device ID.
+	FindUpdates(
+		ctx context.Context,
+		campaignID uuid.UUID,
+		filter FirmwareUpdateFilter,
+	) ([]*entity.FirmwareUpdate, error)
+	// CountUpdates returns the number of FirmwareUpdate entities of a campaign in each status.
+	CountUpdates(ctx context.Context, campaignID uuid.UUID) (map[updatestatus.Status]int64, error)
+	// SaveUpdate stores the status, the progress and the timestamps of a FirmwareUpdate,
+	// if its stored status is still from. Otherwise, it returns entity.ErrFirmwareUpdateStatusConflict.
+	SaveUpdate(ctx context.Context, update *entity.FirmwareUpdate, from updatestatus.Status) error
+	// FailOverdue marks the updates of a campaign that are still in progress and were sent before sentBefore
+	// as FAILED at now for the reason given, and returns how many were marked.
+	FailOverdue(ctx context.Context, campaignID uuid.UUID, sentBefore, now time.Time, reason string) (int64, error)
+}
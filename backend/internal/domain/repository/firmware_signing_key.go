@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/domain/entity"
+)
+
+// FirmwareSigningKeyRepository defines the interface for persisting FirmwareSigningKey entities.
+type FirmwareSigningKeyRepository interface {
+	// Save stores a new active FirmwareSigningKey. It returns entity.ErrFirmwareSigningKeyConflict
+	// if another key is still active, e.g. because it was created concurrently.
+	Save(ctx context.Context, key *entity.FirmwareSigningKey) error
+	// Retire stores the retirement of a FirmwareSigningKey, if it is still active.
+	// Otherwise, it returns entity.ErrFirmwareSigningKeyConflict.
+	Retire(ctx context.Context, key *entity.FirmwareSigningKey) error
+	// FindActive retrieves the active FirmwareSigningKey.
+	FindActive(ctx context.Context) (*entity.FirmwareSigningKey, error)
+	// FindVerifiable retrieves the FirmwareSigningKey entities whose manifests are still to be trusted at now,
+	// newest first.
+	FindVerifiable(ctx context.Context, now time.Time) ([]*entity.FirmwareSigningKey, error)
+	// Lock serializes the signing of manifests and the rotations of the keys until the end of the current
+	// transaction, so that no manifest is signed with a key that a concurrent rotation retires.
+	Lock(ctx context.Context) error
+}
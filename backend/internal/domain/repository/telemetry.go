@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+)
+
+// TelemetryRepository defines the interface for persisting SensorData in the telemetry database.
+type TelemetryRepository interface {
+	// SaveBatch stores readings together with their metrics.
+	// Readings that have already been stored, e.g. because a message was redelivered, are skipped.
+	SaveBatch(ctx context.Context, data []*entity.SensorData) error
+	// FindByDeviceID retrieves up to limit readings of a device recorded in [from, to), newest first.
+	FindByDeviceID(ctx context.Context, deviceID uuid.UUID, from, to time.Time, limit int) ([]*entity.SensorData, error)
+}
@@ -0,0 +1,15 @@
+package repository
+
+import "context"
+
+// Transactor runs a unit of work within a database transaction.
+type Transactor interface {
+	// WithinTransaction runs fn within a transaction.
+	// Repository calls made with the context passed to fn participate in the transaction.
+	// The transaction is committed if fn returns nil, and rolled back otherwise.
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	// WithinSnapshot runs fn within a read-only transaction, in which all repository calls made with
+	// the context passed to fn see the data as of the same point in time.
+	// If ctx already carries a transaction, fn joins it instead.
+	WithinSnapshot(ctx context.Context, fn func(ctx context.Context) error) error
+}
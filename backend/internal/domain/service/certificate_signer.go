@@ -0,0 +1,41 @@
+// Package service defines the interfaces of domain services implemented by the infrastructure layer.
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CertificateSignRequest is a request to issue a client certificate for a device.
+type CertificateSignRequest struct {
+	// CSR is a PEM-encoded PKCS#10 certificate signing request generated by the device.
+	CSR []byte
+	// DeviceID is the ID of the device the certificate is issued for.
+	DeviceID uuid.UUID
+	// HardwareID is the hardware ID of the device. The CSR subject must match it.
+	HardwareID string
+}
+
+// IssuedCertificate is a client certificate issued by the CA.
+type IssuedCertificate struct {
+	// SerialNumber is the serial number of the certificate.
+	// It always fits in a signed 64-bit integer, so it can be stored as BIGINT.
+	SerialNumber int64
+	// Fingerprint is the hex-encoded SHA-256 hash of the DER-encoded certificate.
+	Fingerprint string
+	// CertificatePEM is the PEM-encoded certificate.
+	CertificatePEM string
+	NotBefore      time.Time
+	NotAfter       time.Time
+}
+
+// CertificateSigner issues client certificates for devices.
+type CertificateSigner interface {
+	// SignCSR validates the CSR and issues a client certificate bound to the device.
+	// It returns an error wrapping ErrInvalidCSR if the CSR is not acceptable.
+	SignCSR(ctx context.Context, req CertificateSignRequest) (*IssuedCertificate, error)
+	// CAChainPEM returns the PEM-encoded CA certificate chain, starting with the issuing CA.
+	CAChainPEM() string
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeviceCommandMessage is a command as it is sent to a device.
+type DeviceCommandMessage struct {
+	ID       uuid.UUID
+	DeviceID uuid.UUID
+	Name     string
+	Payload  map[string]any
+	// ExpiresAt is the time after which the answer of the device is no longer accepted.
+	ExpiresAt time.Time
+}
+
+// DeviceCommandPublisher sends commands to the devices, e.g. over MQTT.
+type DeviceCommandPublisher interface {
+	// PublishCommand sends a command to its device. It returns an error if the command could not be
+	// handed to any broker, in which case it is sent again later.
+	PublishCommand(ctx context.Context, command DeviceCommandMessage) error
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// DeviceTwinDelta is the part of the desired state of a device that its reported state does not match yet.
+type DeviceTwinDelta struct {
+	DeviceID uuid.UUID
+	// Version is the version of the desired state the delta is computed from.
+	Version int64
+	State   map[string]any
+}
+
+// DeviceTwinPublisher sends the deltas of device twins to the devices, e.g. over MQTT.
+type DeviceTwinPublisher interface {
+	// PublishDelta sends a delta to its device. Delivery is not guaranteed, so a device that misses it
+	// receives the delta again when it reports its state next.
+	PublishDelta(ctx context.Context, delta DeviceTwinDelta) error
+}
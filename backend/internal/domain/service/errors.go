@@ -0,0 +1,18 @@
+package service
+
+import "errors"
+
+var (
+	// ErrInvalidCSR is returned when a certificate signing request is malformed or not acceptable.
+	ErrInvalidCSR = errors.New("invalid certificate signing request")
+	// ErrMalformedOCSPRequest is returned when an OCSP request cannot be parsed or is not supported.
+	ErrMalformedOCSPRequest = errors.New("malformed ocsp request")
+	// ErrOCSPUnauthorized is returned when an OCSP request asks about a certificate of another CA.
+	ErrOCSPUnauthorized = errors.New("ocsp request for a certificate of another issuer")
+	// ErrFirmwareImageNotFound is returned when a firmware image is not in the storage.
+	ErrFirmwareImageNotFound = errors.New("firmware image not found")
+	// ErrUnsupportedKeyAlgorithm is returned when a firmware signing key is requested for an unknown algorithm.
+	ErrUnsupportedKeyAlgorithm = errors.New("unsupported signing key algorithm")
+	// ErrFirmwareKeyNotFound is returned when the private key of a firmware signing key is not in the key store.
+	ErrFirmwareKeyNotFound = errors.New("firmware signing key not found")
+)
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"io"
+)
+
+// StoredFirmwareImage describes a firmware image written to the FirmwareStorage.
+type StoredFirmwareImage struct {
+	// Key identifies the image in the storage.
+	Key  string
+	Size int64
+	// SHA256 is the SHA-256 hash of the image, computed while it was written.
+	SHA256 []byte
+}
+
+// FirmwareStorage keeps the firmware images, e.g. on the local filesystem.
+type FirmwareStorage interface {
+	// Put writes an image read from r under a new key. Nothing is kept if it fails.
+	Put(ctx context.Context, r io.Reader) (*StoredFirmwareImage, error)
+	// Open opens the image stored under key. It returns an error wrapping ErrFirmwareImageNotFound
+	// if there is none.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete removes the image stored under key. Deleting an image that does not exist is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// FirmwareSignature is the signature of a firmware image.
+type FirmwareSignature struct {
+	// Algorithm is how the signature was made, e.g. "ECDSA-SHA256", "RSA-PKCS1v15-SHA256" or "Ed25519".
+	Algorithm string
+	Signature []byte
+}
+
+// FirmwareSigner signs firmware images with the platform signing key, so that devices can verify them.
+type FirmwareSigner interface {
+	// SignFirmware signs the SHA-256 hash of a firmware image.
+	SignFirmware(ctx context.Context, digest []byte) (*FirmwareSignature, error)
+}
+
+// Algorithms of the keys that sign firmware manifests.
+const (
+	// FirmwareKeyEd25519 signs the manifest itself with Ed25519.
+	FirmwareKeyEd25519 = "Ed25519"
+	// FirmwareKeyECDSAP256 signs the SHA-256 hash of the manifest with ECDSA on P-256, ASN.1-encoded.
+	FirmwareKeyECDSAP256 = "ECDSA-P256-SHA256"
+)
+
+// FirmwarePublicKey is the public half of a key generated by the FirmwareKeyStore.
+type FirmwarePublicKey struct {
+	// ID identifies the key in the store. It is derived from the public key.
+	ID        string
+	Algorithm string
+	// PublicKey is the DER-encoded SubjectPublicKeyInfo of the key.
+	PublicKey []byte
+}
+
+// FirmwareKeyStore keeps the private keys that sign firmware manifests. Only their public halves leave it.
+type FirmwareKeyStore interface {
+	// GenerateKey generates and stores a new key for the algorithm, e.g. FirmwareKeyEd25519.
+	// It returns an error wrapping ErrUnsupportedKeyAlgorithm for an unknown algorithm.
+	GenerateKey(ctx context.Context, algorithm string) (*FirmwarePublicKey, error)
+	// Sign signs a manifest with the key. It returns an error wrapping ErrFirmwareKeyNotFound if there is none.
+	Sign(ctx context.Context, keyID string, manifest []byte) ([]byte, error)
+	// DeleteKey removes the private key, once it no longer signs. Deleting a key that does not exist is not an error.
+	DeleteKey(ctx context.Context, keyID string) error
+}
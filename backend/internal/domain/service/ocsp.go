@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"time"
+)
+
+// OCSPCertStatus is the status of a certificate in an OCSP response.
+type OCSPCertStatus int
+
+const (
+	// OCSPGood means the certificate is not revoked.
+	OCSPGood OCSPCertStatus = iota
+	// OCSPRevoked means the certificate has been revoked.
+	OCSPRevoked
+	// OCSPUnknown means the responder does not know the certificate.
+	OCSPUnknown
+)
+
+// OCSPRequest is a parsed OCSP request about a certificate issued by the platform CA.
+type OCSPRequest struct {
+	// SerialNumber is the serial number of the requested certificate.
+	// It may not fit in an int64 if the certificate was not issued by the platform.
+	SerialNumber *big.Int
+	// CertID is the DER-encoded certificate identifier of the request. It is echoed in the response.
+	CertID []byte
+	// Nonce is the value of the nonce extension, or nil if the request has none.
+	Nonce []byte
+}
+
+// OCSPResponse is the content of an OCSP response to be signed.
+type OCSPResponse struct {
+	Request *OCSPRequest
+	Status  OCSPCertStatus
+	// RevokedAt and ReasonCode are only used if Status is OCSPRevoked.
+	RevokedAt  time.Time
+	ReasonCode int
+	ThisUpdate time.Time
+	NextUpdate time.Time
+}
+
+// OCSPResponder parses OCSP requests and signs OCSP responses for certificates issued by the platform CA.
+type OCSPResponder interface {
+	// ParseOCSPRequest parses a DER-encoded OCSP request.
+	// It returns an error wrapping ErrMalformedOCSPRequest if the request cannot be parsed,
+	// or ErrOCSPUnauthorized if it is about a certificate of another CA.
+	ParseOCSPRequest(der []byte) (*OCSPRequest, error)
+	// SignOCSPResponse signs the response and returns it DER-encoded.
+	SignOCSPResponse(ctx context.Context, response OCSPResponse) ([]byte, error)
+}
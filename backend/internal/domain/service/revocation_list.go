@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// RevokedCertificate is an entry of a certificate revocation list.
+type RevokedCertificate struct {
+	SerialNumber int64
+	RevokedAt    time.Time
+	// ReasonCode is the CRLReason code defined in RFC 5280.
+	ReasonCode int
+}
+
+// RevocationList is the content of a certificate revocation list to be signed.
+type RevocationList struct {
+	// Number is the CRL number. It must increase with every issued CRL.
+	Number     int64
+	ThisUpdate time.Time
+	NextUpdate time.Time
+	Entries    []RevokedCertificate
+}
+
+// RevocationListSigner signs certificate revocation lists with the issuing CA key.
+type RevocationListSigner interface {
+	// SignRevocationList signs the revocation list and returns it DER-encoded.
+	SignRevocationList(ctx context.Context, list RevocationList) ([]byte, error)
+}
+
+// RevocationListPublisher makes a signed certificate revocation list available outside of the API,
+// e.g. to the MQTT broker.
+type RevocationListPublisher interface {
+	// PublishRevocationList publishes a DER-encoded revocation list.
+	PublishRevocationList(ctx context.Context, der []byte) error
+}
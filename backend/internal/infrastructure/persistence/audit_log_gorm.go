@@ -0,0 +1,116 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+// auditLogChainLockKey is the key of the advisory lock that serializes appends to the hash chain.
+const auditLogChainLockKey = 0x61756469745f6c67 // "audit_lg"
+
+// AuditLogGormRepository is the GORM implementation of the AuditLogRepository.
+type AuditLogGormRepository struct {
+	db *gorm.DB
+	// signingKey signs the hash of each entry. It may be empty.
+	signingKey []byte
+	now        func() time.Time
+}
+
+// NewAuditLogGormRepository creates a new instance of AuditLogGormRepository.
+// If signingKey is not empty, the hash of each recorded entry is signed with HMAC-SHA256.
+//
+//nolint:ireturn
+func NewAuditLogGormRepository(db *gorm.DB, signingKey []byte) repository.AuditLogRepository {
+	return &AuditLogGormRepository{db: db, signingKey: signingKey, now: time.Now}
+}
+
+// Record appends an audit log entry to the hash chain.
+func (r *AuditLogGormRepository) Record(ctx context.Context, log *entity.AuditLog) error {
+	// The lock is held until the outermost transaction ends, so that concurrent entries are chained
+	// in the order they are committed. Without it, two entries could link to the same predecessor.
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditLogChainLockKey).Error
+		if err != nil {
+			return err
+		}
+
+		var last entity.AuditLog
+
+		err = tx.Select("hash").Order("id DESC").Limit(1).Find(&last).Error
+		if err != nil {
+			return err
+		}
+
+		// The chain starts over after entries written before it was introduced.
+		prevHash := last.Hash
+		if prevHash == "" {
+			prevHash = entity.AuditLogGenesisHash
+		}
+
+		log.Chain(prevHash, r.now(), r.signingKey)
+
+		return tx.Create(log).Error
+	})
+}
+
+// FindAll retrieves the audit log entries matching the filter, newest first.
+func (r *AuditLogGormRepository) FindAll(
+	ctx context.Context,
+	filter repository.AuditLogFilter,
+) ([]*entity.AuditLog, error) {
+	query := conn(ctx, r.db)
+
+	if filter.DeviceID != nil {
+		query = query.Where("target_device_id = ?", *filter.DeviceID)
+	}
+
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	if filter.BeforeID > 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var logs []*entity.AuditLog
+	// It returns an empty slice if no entries are found.
+	err := query.Order("id DESC").Find(&logs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
+// FindAfter retrieves up to limit audit log entries with an ID greater than afterID, oldest first.
+func (r *AuditLogGormRepository) FindAfter(ctx context.Context, afterID int64, limit int) ([]*entity.AuditLog, error) {
+	var logs []*entity.AuditLog
+
+	err := conn(ctx, r.db).Where("id > ?", afterID).Order("id").Limit(limit).Find(&logs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
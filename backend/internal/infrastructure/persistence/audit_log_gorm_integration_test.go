@@ -0,0 +1,127 @@
+package persistence_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"backend/internal/infrastructure/persistence"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAuditLogGormRepository_Integration performs integration tests for
+// the GORM audit log repository against a real database.
+func TestAuditLogGormRepository_Integration(t *testing.T) {
+	if testDB == nil {
+		t.Fatal("testDB is not initialized")
+	}
+
+	signingKey := []byte("test-key")
+	repo := persistence.NewAuditLogGormRepository(testDB, signingKey)
+	deviceRepo := persistence.NewDeviceGormRepository(testDB)
+	ctx := context.Background()
+
+	record := func(t *testing.T, action entity.AuditAction, deviceID uuid.UUID, actor string) *entity.AuditLog {
+		t.Helper()
+
+		log, err := entity.NewAuditLog(action, deviceID, "device/"+deviceID.String(), actor, nil, map[string]any{})
+		require.NoError(t, err)
+		require.NoError(t, repo.Record(ctx, log))
+
+		return log
+	}
+
+	t.Run("FindAll - Filters entries and returns them newest first", func(t *testing.T) {
+		cleanupTable(t)
+
+		deviceA, deviceB := uuid.New(), uuid.New()
+		first := record(t, entity.AuditDeviceCreate, deviceA, "alice")
+		record(t, entity.AuditDeviceCreate, deviceB, "bob")
+		third := record(t, entity.AuditTokenCreate, deviceA, "alice")
+
+		all, err := repo.FindAll(ctx, repository.AuditLogFilter{}) //nolint:exhaustruct
+		require.NoError(t, err)
+		require.Len(t, all, 3)
+		assert.Equal(t, third.ID, all[0].ID)
+		assert.JSONEq(t, third.Details, all[0].Details)
+
+		byDevice, err := repo.FindAll(ctx, repository.AuditLogFilter{DeviceID: &deviceA, Limit: 1}) //nolint:exhaustruct
+		require.NoError(t, err)
+		require.Len(t, byDevice, 1)
+		assert.Equal(t, third.ID, byDevice[0].ID)
+
+		page, err := repo.FindAll(ctx, repository.AuditLogFilter{ //nolint:exhaustruct
+			Action:   entity.AuditDeviceCreate,
+			Actor:    "alice",
+			BeforeID: third.ID,
+		})
+		require.NoError(t, err)
+		require.Len(t, page, 1)
+		assert.Equal(t, first.ID, page[0].ID)
+
+		future := time.Now().Add(time.Hour)
+		none, err := repo.FindAll(ctx, repository.AuditLogFilter{From: &future}) //nolint:exhaustruct
+		require.NoError(t, err)
+		assert.Empty(t, none)
+	})
+
+	t.Run("Record - Keeps the device ID after the device is deleted", func(t *testing.T) {
+		cleanupTable(t)
+
+		device, err := entity.NewDevice("hw-audit-01", nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, deviceRepo.Save(ctx, device))
+		record(t, entity.AuditDeviceDelete, device.ID, "")
+		require.NoError(t, deviceRepo.Delete(ctx, device.ID, device.Version))
+
+		logs, err := repo.FindAll(ctx, repository.AuditLogFilter{DeviceID: &device.ID}) //nolint:exhaustruct
+		require.NoError(t, err)
+		require.Len(t, logs, 1)
+		assert.Equal(t, entity.SystemActor, logs[0].Actor)
+	})
+
+	t.Run("Record - Chains concurrent entries in ID order", func(t *testing.T) {
+		cleanupTable(t)
+
+		// An entry written before the chain was introduced.
+		err := testDB.Exec("INSERT INTO audit_logs (action, actor) VALUES ('DEVICE_CREATE', 'legacy')").Error
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+
+		for range 20 {
+			wg.Go(func() {
+				log, err := entity.NewAuditLog(entity.AuditDeviceUpdate, uuid.New(), "device/x", "", nil, nil)
+				assert.NoError(t, err)
+				assert.NoError(t, repo.Record(ctx, log))
+			})
+		}
+
+		wg.Wait()
+
+		logs, err := repo.FindAfter(ctx, 0, 100)
+		require.NoError(t, err)
+		require.Len(t, logs, 21)
+		assert.False(t, logs[0].IsChained())
+
+		// Each entry links to the one before it, and the stored timestamps still match the hashes.
+		prevHash := entity.AuditLogGenesisHash
+		for _, log := range logs[1:] {
+			require.NoError(t, log.VerifyChain(prevHash, signingKey, true), "entry %d", log.ID)
+			prevHash = log.Hash
+		}
+
+		// Editing a row is detected.
+		require.NoError(t, testDB.Exec("UPDATE audit_logs SET actor = 'mallory' WHERE id = ?", logs[5].ID).Error)
+
+		edited, err := repo.FindAfter(ctx, logs[4].ID, 1)
+		require.NoError(t, err)
+		require.ErrorIs(t, edited[0].VerifyChain(logs[4].Hash, signingKey, true), entity.ErrAuditLogHashMismatch)
+	})
+}
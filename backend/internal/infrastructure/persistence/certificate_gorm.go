@@ -0,0 +1,101 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+// CertificateGormRepository is the GORM implementation of the CertificateRepository.
+type CertificateGormRepository struct {
+	db *gorm.DB
+}
+
+// NewCertificateGormRepository creates a new instance of CertificateGormRepository.
+//
+//nolint:ireturn
+func NewCertificateGormRepository(db *gorm.DB) repository.CertificateRepository {
+	return &CertificateGormRepository{db: db}
+}
+
+// Save stores a newly issued certificate.
+func (r *CertificateGormRepository) Save(ctx context.Context, certificate *entity.Certificate) error {
+	// The serial number is assigned by the CA, so this is always an insert.
+	return conn(ctx, r.db).Create(certificate).Error
+}
+
+// FindBySerialNumber finds a certificate by its serial number.
+func (r *CertificateGormRepository) FindBySerialNumber(
+	ctx context.Context,
+	serialNumber int64,
+) (*entity.Certificate, error) {
+	var certificate entity.Certificate
+	// It returns `gorm.ErrRecordNotFound` if no record is found.
+	err := conn(ctx, r.db).First(&certificate, "serial_number = ?", serialNumber).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &certificate, nil
+}
+
+// FindByDeviceID retrieves all certificates issued to a device, newest first.
+func (r *CertificateGormRepository) FindByDeviceID(
+	ctx context.Context,
+	deviceID uuid.UUID,
+) ([]*entity.Certificate, error) {
+	var certificates []*entity.Certificate
+	// It returns an empty slice if no certificates are found.
+	err := conn(ctx, r.db).
+		Where("device_id = ?", deviceID).
+		Order("valid_from DESC, serial_number DESC").
+		Find(&certificates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return certificates, nil
+}
+
+// FindRevoked retrieves all revoked certificates that have not expired at the given time.
+// Expired certificates are left out, so the CRL does not grow forever.
+func (r *CertificateGormRepository) FindRevoked(ctx context.Context, at time.Time) ([]*entity.Certificate, error) {
+	var certificates []*entity.Certificate
+
+	err := conn(ctx, r.db).
+		Where("is_revoked = ? AND valid_to > ?", true, at).
+		Order("serial_number").
+		Find(&certificates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return certificates, nil
+}
+
+// Revoke persists the revocation of a certificate.
+// The update is conditional, so concurrent revocations of the same certificate are detected.
+func (r *CertificateGormRepository) Revoke(ctx context.Context, certificate *entity.Certificate) error {
+	result := conn(ctx, r.db).
+		Model(&entity.Certificate{}). //nolint:exhaustruct
+		Where("serial_number = ? AND is_revoked = ?", certificate.SerialNumber, false).
+		Updates(map[string]any{
+			"is_revoked":        true,
+			"revoked_at":        certificate.RevokedAt,
+			"revocation_reason": certificate.RevocationReason,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return entity.ErrCertificateAlreadyRevoked
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"backend/internal/domain/VO/commandstatus"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+// DeviceCommandGormRepository is the GORM implementation of the DeviceCommandRepository.
+type DeviceCommandGormRepository struct {
+	db *gorm.DB
+}
+
+// NewDeviceCommandGormRepository creates a new instance of DeviceCommandGormRepository.
+//
+//nolint:ireturn
+func NewDeviceCommandGormRepository(db *gorm.DB) repository.DeviceCommandRepository {
+	return &DeviceCommandGormRepository{db: db}
+}
+
+// Save stores a newly created device command.
+func (r *DeviceCommandGormRepository) Save(ctx context.Context, command *entity.DeviceCommand) error {
+	err := conn(ctx, r.db).Create(command).Error
+	if hasSQLState(err, pgForeignKeyViolation) {
+		return entity.ErrDeviceNotFound
+	}
+
+	return err
+}
+
+// FindByID finds a command of a device by its UUID.
+func (r *DeviceCommandGormRepository) FindByID(
+	ctx context.Context,
+	deviceID, id uuid.UUID,
+) (*entity.DeviceCommand, error) {
+	var command entity.DeviceCommand
+	// It returns `gorm.ErrRecordNotFound` if no record is found.
+	err := conn(ctx, r.db).First(&command, "id = ? AND device_id = ?", id, deviceID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &command, nil
+}
+
+// FindQueued retrieves the oldest QUEUED commands that have not expired.
+func (r *DeviceCommandGormRepository) FindQueued(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) ([]*entity.DeviceCommand, error) {
+	var commands []*entity.DeviceCommand
+	// It returns an empty slice if no commands are found.
+	err := conn(ctx, r.db).
+		Where("status = ? AND expires_at > ?", commandstatus.Queued, now).
+		Order("created_at, id").
+		Limit(limit).
+		Find(&commands).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return commands, nil
+}
+
+// UpdateStatus stores the status of a command. The condition on the stored status makes it atomic,
+// so a command is answered only once, and never after it has expired.
+func (r *DeviceCommandGormRepository) UpdateStatus(
+	ctx context.Context,
+	command *entity.DeviceCommand,
+	from commandstatus.Status,
+) error {
+	result := conn(ctx, r.db).
+		Model(&entity.DeviceCommand{}). //nolint:exhaustruct
+		Where("id = ? AND status = ?", command.ID, from).
+		Updates(map[string]any{
+			"status":         command.Status,
+			"result":         &command.Result,
+			"failure_reason": command.FailureReason,
+			"sent_at":        command.SentAt,
+			"completed_at":   command.CompletedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return entity.ErrDeviceCommandStatusConflict
+	}
+
+	return nil
+}
+
+// ExpireOverdue marks the pending commands whose TTL has passed as EXPIRED.
+func (r *DeviceCommandGormRepository) ExpireOverdue(ctx context.Context, now time.Time) (int64, error) {
+	result := conn(ctx, r.db).
+		Model(&entity.DeviceCommand{}). //nolint:exhaustruct
+		Where("status IN ? AND expires_at <= ?", []commandstatus.Status{commandstatus.Queued, commandstatus.Sent}, now).
+		Updates(map[string]any{"status": commandstatus.Expired, "completed_at": now})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,139 @@
+package persistence_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/commandstatus"
+	"backend/internal/domain/entity"
+	"backend/internal/infrastructure/persistence"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// TestDeviceCommandGormRepository_Integration performs integration tests for
+// the GORM device command repository against a real database.
+func TestDeviceCommandGormRepository_Integration(t *testing.T) {
+	// testDB is initialized in main_test.go.
+	if testDB == nil {
+		t.Fatal("testDB is not initialized")
+	}
+
+	repo := persistence.NewDeviceCommandGormRepository(testDB)
+	deviceRepo := persistence.NewDeviceGormRepository(testDB)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	newCommand := func(t *testing.T, deviceID uuid.UUID, ttl time.Duration) *entity.DeviceCommand {
+		t.Helper()
+
+		command, err := entity.NewDeviceCommand(deviceID, "reboot", map[string]any{"delay": 5.0}, ttl, now)
+		require.NoError(t, err)
+		require.NoError(t, repo.Save(ctx, command))
+
+		return command
+	}
+
+	t.Run("Save and FindByID - Save a command and find it by its device", func(t *testing.T) {
+		cleanupTable(t)
+
+		device, err := entity.NewDevice("hw-command-01", nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, deviceRepo.Save(ctx, device))
+
+		command := newCommand(t, device.ID, time.Minute)
+		assert.NotEqual(t, uuid.Nil, command.ID)
+
+		found, err := repo.FindByID(ctx, device.ID, command.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "reboot", found.Name)
+		assert.Equal(t, entity.JSONBMap{"delay": 5.0}, found.Payload)
+		assert.Equal(t, commandstatus.Queued, found.Status)
+		assert.True(t, found.ExpiresAt.Equal(now.Add(time.Minute)))
+
+		_, err = repo.FindByID(ctx, uuid.New(), command.ID)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		unknown, err := entity.NewDeviceCommand(uuid.New(), "reboot", nil, time.Minute, now)
+		require.NoError(t, err)
+		require.ErrorIs(t, repo.Save(ctx, unknown), entity.ErrDeviceNotFound)
+	})
+
+	t.Run("UpdateStatus - Update only from the expected status", func(t *testing.T) {
+		cleanupTable(t)
+
+		device, err := entity.NewDevice("hw-command-01", nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, deviceRepo.Save(ctx, device))
+
+		command := newCommand(t, device.ID, time.Minute)
+		require.NoError(t, command.Complete(commandstatus.Acked, map[string]any{"ok": true}, "", now))
+		require.NoError(t, repo.UpdateStatus(ctx, command, commandstatus.Queued))
+
+		found, err := repo.FindByID(ctx, device.ID, command.ID)
+		require.NoError(t, err)
+		assert.Equal(t, commandstatus.Acked, found.Status)
+		assert.Equal(t, entity.JSONBMap{"ok": true}, found.Result)
+		require.NotNil(t, found.CompletedAt)
+		assert.True(t, found.CompletedAt.Equal(now))
+
+		// The command was answered in the meantime, so it cannot be marked as sent any more.
+		stale := newCommand(t, device.ID, time.Minute)
+		stale.ID = command.ID
+		require.NoError(t, stale.MarkSent(now))
+		require.ErrorIs(t, repo.UpdateStatus(ctx, stale, commandstatus.Queued), entity.ErrDeviceCommandStatusConflict)
+	})
+
+	t.Run("FindQueued and ExpireOverdue - Deliver pending commands and expire overdue ones", func(t *testing.T) {
+		cleanupTable(t)
+
+		device, err := entity.NewDevice("hw-command-01", nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, deviceRepo.Save(ctx, device))
+
+		queued := newCommand(t, device.ID, time.Minute)
+		overdue := newCommand(t, device.ID, time.Second)
+
+		sent := newCommand(t, device.ID, time.Second)
+		require.NoError(t, sent.MarkSent(now))
+		require.NoError(t, repo.UpdateStatus(ctx, sent, commandstatus.Queued))
+
+		commands, err := repo.FindQueued(ctx, now.Add(30*time.Second), 10)
+		require.NoError(t, err)
+		require.Len(t, commands, 1)
+		assert.Equal(t, queued.ID, commands[0].ID)
+
+		expired, err := repo.ExpireOverdue(ctx, now.Add(30*time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), expired)
+
+		for _, command := range []*entity.DeviceCommand{overdue, sent} {
+			found, err := repo.FindByID(ctx, device.ID, command.ID)
+			require.NoError(t, err)
+			assert.Equal(t, commandstatus.Expired, found.Status)
+			assert.NotNil(t, found.CompletedAt)
+		}
+
+		found, err := repo.FindByID(ctx, device.ID, queued.ID)
+		require.NoError(t, err)
+		assert.Equal(t, commandstatus.Queued, found.Status)
+	})
+
+	t.Run("Delete - Deleting a device deletes its commands", func(t *testing.T) {
+		cleanupTable(t)
+
+		device, err := entity.NewDevice("hw-command-01", nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, deviceRepo.Save(ctx, device))
+
+		command := newCommand(t, device.ID, time.Minute)
+		require.NoError(t, deviceRepo.Delete(ctx, device.ID, device.Version))
+
+		_, err = repo.FindByID(ctx, device.ID, command.ID)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
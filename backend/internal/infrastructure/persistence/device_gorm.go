@@ -3,14 +3,24 @@ package persistence
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"backend/internal/domain/VO/connectionstate"
 	"backend/internal/domain/entity"
 	"backend/internal/domain/repository"
 )
 
+// deviceTopologyLockKey is the key of the advisory lock that serializes the changes to the parents of devices.
+const deviceTopologyLockKey = 0x646576746f706f // "devtopo"
+
 // DeviceGormRepository is the GORM implementation of the DeviceRepository.
 type DeviceGormRepository struct {
 	db *gorm.DB
@@ -24,16 +34,60 @@ func NewDeviceGormRepository(db *gorm.DB) repository.DeviceRepository {
 }
 
 // Save creates a new device or updates an existing one.
+// The update is conditional on the version the device was read with, and increments it.
+// If the device has been updated since, it returns a *entity.VersionConflictError,
+// and if it has been deleted, entity.ErrDeviceNotFound.
 func (r *DeviceGormRepository) Save(ctx context.Context, device *entity.Device) error {
-	// GORM's Save method handles both creation (if primary key is zero) and update.
-	return r.db.WithContext(ctx).Save(device).Error
+	if device.ID == uuid.Nil {
+		device.Version = 1
+
+		return conn(ctx, r.db).Create(device).Error
+	}
+
+	version := device.Version
+	device.Version++
+
+	// Like gorm.DB.Save, every column but the creation time is written, except the presence,
+	// which RecordPresence writes concurrently. GORM adds the primary key of the model to the conditions,
+	// so only the row with both the ID and the expected version is updated.
+	result := conn(ctx, r.db).Model(device).
+		Select("*").Omit("created_at", "connection_state", "last_seen_at", "last_ip").
+		Where("version = ?", version).
+		Updates(device)
+	if result.Error != nil {
+		device.Version = version
+
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		device.Version = version
+
+		return r.versionConflict(ctx, device.ID, version)
+	}
+
+	return nil
+}
+
+// CreateAll creates new devices in one INSERT statement.
+func (r *DeviceGormRepository) CreateAll(ctx context.Context, devices []*entity.Device) error {
+	if len(devices) == 0 {
+		return nil
+	}
+
+	for _, device := range devices {
+		device.Version = 1
+	}
+
+	// The IDs generated by the database are returned into the entities.
+	return conn(ctx, r.db).Create(&devices).Error
 }
 
 // FindByID finds a device by its UUID.
 func (r *DeviceGormRepository) FindByID(ctx context.Context, id uuid.UUID) (*entity.Device, error) {
 	var device entity.Device
 	// It returns `gorm.ErrRecordNotFound` if no record is found.
-	err := r.db.WithContext(ctx).First(&device, "id = ?", id).Error
+	err := conn(ctx, r.db).First(&device, "id = ?", id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -45,7 +99,7 @@ func (r *DeviceGormRepository) FindByID(ctx context.Context, id uuid.UUID) (*ent
 func (r *DeviceGormRepository) FindByHardwareID(ctx context.Context, hardwareID string) (*entity.Device, error) {
 	var device entity.Device
 	// It returns `gorm.ErrRecordNotFound` if no record is found.
-	err := r.db.WithContext(ctx).Where("hardware_id = ?", hardwareID).First(&device).Error
+	err := conn(ctx, r.db).Where("hardware_id = ?", hardwareID).First(&device).Error
 	if err != nil {
 		return nil, err
 	}
@@ -53,11 +107,31 @@ func (r *DeviceGormRepository) FindByHardwareID(ctx context.Context, hardwareID
 	return &device, nil
 }
 
-// FindAll retrieves all devices.
-func (r *DeviceGormRepository) FindAll(ctx context.Context) ([]*entity.Device, error) {
+// FindByHardwareIDs finds the devices with any of the hardware IDs.
+func (r *DeviceGormRepository) FindByHardwareIDs(ctx context.Context, hardwareIDs []string) ([]*entity.Device, error) {
+	var devices []*entity.Device
+
+	if len(hardwareIDs) == 0 {
+		return devices, nil
+	}
+
+	// It returns an empty slice if no devices are found.
+	err := conn(ctx, r.db).Where("hardware_id IN ?", hardwareIDs).Find(&devices).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+// FindByQuery retrieves a page of the devices matching the query, in its sort order.
+func (r *DeviceGormRepository) FindByQuery(
+	ctx context.Context,
+	query repository.DeviceQuery,
+) ([]*entity.Device, error) {
 	var devices []*entity.Device
 	// It returns an empty slice if no devices are found.
-	err := r.db.WithContext(ctx).Find(&devices).Error
+	err := queryDevices(conn(ctx, r.db), query).Find(&devices).Error
 	if err != nil {
 		return nil, err
 	}
@@ -65,11 +139,101 @@ func (r *DeviceGormRepository) FindAll(ctx context.Context) ([]*entity.Device, e
 	return devices, nil
 }
 
-// Delete removes a device by its UUID.
-func (r *DeviceGormRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	// It deletes a record by its primary key.
-	// If the record to be deleted is not found, GORM does not return an error, but RowsAffected will be 0.
-	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&entity.Device{}) //nolint:exhaustruct
+// FindEach reads the devices matching the query through a row cursor, in its sort order,
+// and calls fn with each of them.
+func (r *DeviceGormRepository) FindEach(
+	ctx context.Context,
+	query repository.DeviceQuery,
+	fn func(device *entity.Device) error,
+) error {
+	db := conn(ctx, r.db)
+
+	rows, err := queryDevices(db.Model(&entity.Device{}), query).Rows() //nolint:exhaustruct
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var device entity.Device
+
+		err = db.ScanRows(rows, &device)
+		if err != nil {
+			return err
+		}
+
+		err = fn(&device)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// FindAncestors finds the ancestors of a device with a recursive query, from its parent up to the root.
+// The path of visited devices stops the recursion, should the stored topology ever contain a cycle.
+func (r *DeviceGormRepository) FindAncestors(ctx context.Context, id uuid.UUID) ([]*entity.Device, error) {
+	var devices []*entity.Device
+
+	err := conn(ctx, r.db).Raw(`
+		WITH RECURSIVE ancestors (id, depth, path) AS (
+			SELECT parent_id, 1, ARRAY[id] FROM devices WHERE id = ? AND parent_id IS NOT NULL
+			UNION ALL
+			SELECT d.parent_id, a.depth + 1, a.path || d.id
+			FROM ancestors a JOIN devices d ON d.id = a.id
+			WHERE d.parent_id IS NOT NULL AND NOT d.id = ANY(a.path)
+		)
+		SELECT devices.* FROM devices JOIN ancestors ON devices.id = ancestors.id ORDER BY ancestors.depth`,
+		id,
+	).Scan(&devices).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+// LockTopology takes a transaction-level advisory lock, which is released when the transaction ends.
+// It must be called within a transaction; otherwise the lock is released right away.
+func (r *DeviceGormRepository) LockTopology(ctx context.Context) error {
+	return conn(ctx, r.db).Exec("SELECT pg_advisory_xact_lock(?)", deviceTopologyLockKey).Error
+}
+
+// Count returns the number of devices matching the filter.
+func (r *DeviceGormRepository) Count(ctx context.Context, filter repository.DeviceFilter) (int64, error) {
+	var count int64
+
+	err := filterDevices(conn(ctx, r.db).Model(&entity.Device{}), filter).Count(&count).Error //nolint:exhaustruct
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// RecordPresence updates the presence columns of a device. UpdateColumns leaves the update time alone,
+// and the version is not incremented, so that presence does not conflict with changes made through the API.
+func (r *DeviceGormRepository) RecordPresence(
+	ctx context.Context,
+	id uuid.UUID,
+	presence repository.DevicePresence,
+) error {
+	columns := map[string]any{"connection_state": presence.State}
+
+	if presence.SeenAt != nil {
+		columns["last_seen_at"] = *presence.SeenAt
+	}
+
+	if presence.IP != "" {
+		columns["last_ip"] = presence.IP
+	}
+
+	result := conn(ctx, r.db).
+		Model(&entity.Device{}). //nolint:exhaustruct
+		Where("id = ?", id).
+		UpdateColumns(columns)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -80,3 +244,281 @@ func (r *DeviceGormRepository) Delete(ctx context.Context, id uuid.UUID) error {
 
 	return nil
 }
+
+// MarkStale marks the CONNECTED devices last seen before seenBefore as STALE, in one statement.
+func (r *DeviceGormRepository) MarkStale(ctx context.Context, seenBefore time.Time) (int64, error) {
+	result := conn(ctx, r.db).
+		Model(&entity.Device{}). //nolint:exhaustruct
+		Where("connection_state = ? AND last_seen_at < ?", connectionstate.Connected, seenBefore).
+		UpdateColumn("connection_state", connectionstate.Stale)
+
+	return result.RowsAffected, result.Error
+}
+
+// Delete removes a device by its UUID, if its version is still the given one.
+func (r *DeviceGormRepository) Delete(ctx context.Context, id uuid.UUID, version int64) error {
+	// If no record matches, GORM does not return an error, but RowsAffected will be 0.
+	result := conn(ctx, r.db).
+		Where("id = ? AND version = ?", id, version).
+		Delete(&entity.Device{}) //nolint:exhaustruct
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return r.versionConflict(ctx, id, version)
+	}
+
+	return nil
+}
+
+// versionConflict explains why a conditional write matched no row: the device either has another version,
+// or does not exist anymore.
+func (r *DeviceGormRepository) versionConflict(ctx context.Context, id uuid.UUID, version int64) error {
+	var current entity.Device
+
+	err := conn(ctx, r.db).Select("version").First(&current, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.ErrDeviceNotFound
+		}
+
+		return err
+	}
+
+	return &entity.VersionConflictError{ID: id, Expected: version, Actual: current.Version}
+}
+
+// queryDevices adds the conditions, the order and the limit of the query to a device query.
+// Paging is keyset-based: the page starts after the (sort key, ID) of the cursor.
+// An unknown sort field sorts by creation time.
+func queryDevices(db *gorm.DB, query repository.DeviceQuery) *gorm.DB {
+	db = filterDevices(db, query.Filter)
+
+	sortBy := query.SortBy
+	if !sortBy.IsValid() {
+		sortBy = repository.DeviceSortCreatedAt
+	}
+
+	// The column name is safe to concatenate, because it is one of the known sort fields.
+	column := string(sortBy)
+	direction, comparison := "ASC", ">"
+
+	if query.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if query.After != nil {
+		var key any
+
+		switch sortBy {
+		case repository.DeviceSortName:
+			key = query.After.Name
+		case repository.DeviceSortUpdatedAt:
+			key = query.After.UpdatedAt
+		default:
+			key = query.After.CreatedAt
+		}
+
+		db = db.Where("("+column+", id) "+comparison+" (?, ?)", key, query.After.ID)
+	}
+
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+
+	return db.Order(column + " " + direction).Order("id " + direction)
+}
+
+// filterDevices adds the conditions of the filter to a device query.
+func filterDevices(db *gorm.DB, filter repository.DeviceFilter) *gorm.DB {
+	if filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
+	}
+
+	if filter.HardwareIDPrefix != "" {
+		db = db.Where("hardware_id LIKE ?", escapeLike(filter.HardwareIDPrefix)+"%")
+	}
+
+	if filter.NameContains != "" {
+		db = db.Where("name ILIKE ?", "%"+escapeLike(filter.NameContains)+"%")
+	}
+
+	for _, condition := range filter.Metadata {
+		db = filterMetadata(db, condition)
+	}
+
+	if filter.Group != nil {
+		db = filterGroup(db, filter.Group)
+	}
+
+	if filter.ParentID != nil {
+		db = db.Where("parent_id = ?", *filter.ParentID)
+	}
+
+	if filter.Online != nil {
+		if *filter.Online {
+			db = db.Where("connection_state = ?", connectionstate.Connected)
+		} else {
+			db = db.Where("connection_state <> ?", connectionstate.Connected)
+		}
+	}
+
+	if filter.LastSeenBefore != nil {
+		db = db.Where("(last_seen_at IS NULL OR last_seen_at < ?)", *filter.LastSeenBefore)
+	}
+
+	return db
+}
+
+// filterGroup adds the membership of a device group to a device query:
+// the device has been added to the group, or its metadata satisfies the rule of the group.
+func filterGroup(db *gorm.DB, group *repository.GroupMembership) *gorm.DB {
+	// The conditions are built on new sessions, so that they are grouped in parentheses within the query.
+	newSession := &gorm.Session{NewDB: true} //nolint:exhaustruct
+	members := db.Session(newSession).
+		Where("id IN (SELECT device_id FROM device_group_members WHERE group_id = ?)", group.GroupID)
+
+	if len(group.Rule) > 0 {
+		rule := db.Session(newSession)
+		for _, condition := range group.Rule {
+			rule = filterMetadata(rule, condition)
+		}
+
+		if rule.Error != nil {
+			_ = db.AddError(rule.Error)
+		}
+
+		members = members.Or(rule)
+	}
+
+	return db.Where(members)
+}
+
+// filterMetadata adds a condition on the metadata to a device query.
+// Equality uses the containment operator @> and the existence checks use the ? operator,
+// so that they are served by the GIN index on the metadata.
+func filterMetadata(db *gorm.DB, condition repository.MetadataCondition) *gorm.DB {
+	path := textArrayLiteral(condition.Path)
+	parent := textArrayLiteral(condition.Path[:len(condition.Path)-1])
+	key := condition.Path[len(condition.Path)-1]
+
+	switch condition.Operator {
+	case repository.MetadataEquals:
+		return db.Where("metadata @> ?::jsonb", containmentJSON(condition.Path, condition.Value))
+	case repository.MetadataNotEquals:
+		return db.Where("NOT COALESCE(metadata @> ?::jsonb, FALSE)", containmentJSON(condition.Path, condition.Value))
+	case repository.MetadataExists:
+		if len(condition.Path) == 1 {
+			return db.Where("metadata ? ?", jsonbQuestionMark, key)
+		}
+
+		return db.Where("metadata #> ?::text[] ? ?", parent, jsonbQuestionMark, key)
+	case repository.MetadataNotExists:
+		return db.Where("NOT COALESCE(metadata #> ?::text[] ? ?, FALSE)", parent, jsonbQuestionMark, key)
+	case repository.MetadataGreater, repository.MetadataGreaterOrEqual,
+		repository.MetadataLess, repository.MetadataLessOrEqual:
+		sql, vars := orderedMetadataCondition(path, condition.Operator, condition.Value)
+
+		return db.Where(sql, vars...)
+	default:
+		_ = db.AddError(fmt.Errorf("%w: %q", errUnknownMetadataOperator, condition.Operator))
+
+		return db
+	}
+}
+
+// orderedMetadataCondition returns the SQL and the parameters of an ordering comparison of the value at the path.
+//
+// A number is compared numerically with numbers. A value that looks like a version, such as 2.4 or "2.4.1",
+// is compared with the strings that look like versions component by component, so that "2.10" follows "2.4".
+// Any other string is compared with strings as text. Values of another JSON type do not match.
+// Each branch checks the JSON type and the format of the stored value first, so that the casts cannot fail.
+func orderedMetadataCondition(path string, operator repository.MetadataOperator, value any) (string, []any) {
+	raw := fmt.Sprint(value)
+	_, number := value.(json.Number)
+	version := isMetadataVersion(raw)
+
+	// The operator is safe to concatenate, because it is one of the known comparisons.
+	comparison := " " + string(operator) + " "
+
+	var (
+		branches []string
+		vars     []any
+	)
+
+	if number {
+		branches = append(branches, "WHEN jsonb_typeof(metadata #> ?::text[]) = 'number' "+
+			"THEN (metadata #>> ?::text[])::numeric"+comparison+"?::numeric")
+		vars = append(vars, path, path, raw)
+	}
+
+	switch {
+	case version:
+		branches = append(branches, "WHEN jsonb_typeof(metadata #> ?::text[]) = 'string' "+
+			"AND (metadata #>> ?::text[]) ~ ? "+
+			"THEN string_to_array(metadata #>> ?::text[], '.')::numeric[]"+comparison+
+			"string_to_array(?, '.')::numeric[]")
+		vars = append(vars, path, path, metadataVersionPattern, path, raw)
+	case !number:
+		branches = append(branches, "WHEN jsonb_typeof(metadata #> ?::text[]) = 'string' "+
+			"THEN (metadata #>> ?::text[])"+comparison+"?::text")
+		vars = append(vars, path, path, raw)
+	}
+
+	return "CASE " + strings.Join(branches, " ") + " END", vars
+}
+
+// metadataVersionPattern is the PostgreSQL regular expression of the strings isMetadataVersion accepts.
+const metadataVersionPattern = `^[0-9]+(\.[0-9]+)*$`
+
+// isMetadataVersion reports whether s looks like a version: numbers separated by dots, such as "2.4.1".
+func isMetadataVersion(s string) bool {
+	for part := range strings.SplitSeq(s, ".") {
+		if part == "" || strings.Trim(part, "0123456789") != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// jsonbQuestionMark writes the ? operator of jsonb into a condition.
+// GORM replaces every ? of a condition with a parameter, so the operator is passed as an expression.
+var jsonbQuestionMark = clause.Expr{SQL: "?", Vars: nil, WithoutParentheses: false} //nolint:gochecknoglobals
+
+// errUnknownMetadataOperator is returned when a metadata condition has an operator that cannot be translated.
+var errUnknownMetadataOperator = errors.New("unknown metadata operator")
+
+// containmentJSON returns the JSON object that has the value at the path, for the @> operator.
+func containmentJSON(path []string, value any) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		value = map[string]any{path[i]: value}
+	}
+
+	// Marshaling cannot fail, because the value is a JSON scalar nested in objects.
+	data, _ := json.Marshal(value) //nolint:errchkjson
+
+	return string(data)
+}
+
+// textArrayLiteral returns the PostgreSQL array literal of the strings, for a path parameter of type text[].
+func textArrayLiteral(elements []string) string {
+	quoted := make([]string, 0, len(elements))
+	for _, element := range elements {
+		quoted = append(quoted, `"`+arrayElementEscaper.Replace(element)+`"`)
+	}
+
+	return "{" + strings.Join(quoted, ",") + "}"
+}
+
+// arrayElementEscaper escapes the characters that are special in a quoted element of an array literal.
+var arrayElementEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`) //nolint:gochecknoglobals
+
+// likeEscaper escapes the wildcards of a LIKE pattern, using the default escape character of PostgreSQL.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`) //nolint:gochecknoglobals
+
+// escapeLike makes a string match itself literally in a LIKE pattern.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
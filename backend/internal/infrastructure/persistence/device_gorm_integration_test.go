@@ -2,9 +2,15 @@ package persistence_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
+	"backend/internal/domain/VO/connectionstate"
+	"backend/internal/domain/VO/devicestatus"
 	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
 	"backend/internal/infrastructure/persistence"
 
 	"github.com/google/uuid"
@@ -111,6 +117,461 @@ func TestDeviceGormRepository_Integration(t *testing.T) {
 		)
 	})
 
+	t.Run("CreateAll - Creates devices and finds them by hardware ID", func(t *testing.T) {
+		cleanupTable(t)
+
+		devices := make([]*entity.Device, 0, 3)
+
+		for _, hardwareID := range []string{"hw-bulk-01", "hw-bulk-02", "hw-bulk-03"} {
+			device, err := entity.NewDevice(hardwareID, nil, map[string]any{"site": "Osaka"})
+			require.NoError(t, err)
+
+			devices = append(devices, device)
+		}
+
+		require.NoError(t, repo.CreateAll(ctx, devices))
+
+		for _, device := range devices {
+			assert.NotEqual(t, uuid.Nil, device.ID, "ID should be assigned after saving")
+			assert.Equal(t, int64(1), device.Version)
+		}
+
+		found, err := repo.FindByHardwareIDs(ctx, []string{"hw-bulk-01", "hw-bulk-03", "hw-unknown"})
+		require.NoError(t, err)
+
+		hardwareIDs := make([]string, 0, len(found))
+		for _, device := range found {
+			hardwareIDs = append(hardwareIDs, device.HardwareID)
+		}
+
+		assert.ElementsMatch(t, []string{"hw-bulk-01", "hw-bulk-03"}, hardwareIDs)
+
+		// A duplicate hardware ID fails the whole statement.
+		duplicate, err := entity.NewDevice("hw-bulk-02", nil, nil)
+		require.NoError(t, err)
+		other, err := entity.NewDevice("hw-bulk-04", nil, nil)
+		require.NoError(t, err)
+		require.Error(t, repo.CreateAll(ctx, []*entity.Device{other, duplicate}))
+
+		_, err = repo.FindByHardwareID(ctx, "hw-bulk-04")
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Save(Update) - Rejects a stale version", func(t *testing.T) {
+		cleanupTable(t)
+
+		device, err := entity.NewDevice("hw-version-01", nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, repo.Save(ctx, device))
+		assert.Equal(t, int64(1), device.Version)
+
+		// Two operators read the same version.
+		first, err := repo.FindByID(ctx, device.ID)
+		require.NoError(t, err)
+		second, err := repo.FindByID(ctx, device.ID)
+		require.NoError(t, err)
+
+		first.Name = "first"
+		require.NoError(t, repo.Save(ctx, first))
+		assert.Equal(t, int64(2), first.Version)
+
+		// The second update is based on the version the first one replaced.
+		second.Name = "second"
+		err = repo.Save(ctx, second)
+
+		var conflictErr *entity.VersionConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		assert.Equal(t, int64(1), conflictErr.Expected)
+		assert.Equal(t, int64(2), conflictErr.Actual)
+		assert.Equal(t, int64(1), second.Version, "the version is not changed by a failed update")
+
+		// Deleting the stale version fails as well.
+		err = repo.Delete(ctx, device.ID, second.Version)
+		require.ErrorIs(t, err, entity.ErrVersionConflict)
+
+		foundDevice, err := repo.FindByID(ctx, device.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "first", foundDevice.Name)
+		assert.Equal(t, int64(2), foundDevice.Version)
+
+		// An update of a deleted device reports that it does not exist.
+		require.NoError(t, repo.Delete(ctx, device.ID, foundDevice.Version))
+		require.ErrorIs(t, repo.Save(ctx, foundDevice), entity.ErrDeviceNotFound)
+	})
+
+	t.Run("Save(Update) - Persists status transitions", func(t *testing.T) {
+		cleanupTable(t)
+
+		// Prepare and save test data.
+		savedDevice, err := entity.NewDevice("hw-status-01", nil, nil)
+		require.NoError(t, err)
+		err = repo.Save(ctx, savedDevice)
+		require.NoError(t, err)
+
+		// A new device is UNREGISTERED.
+		foundDevice, err := repo.FindByID(ctx, savedDevice.ID)
+		require.NoError(t, err)
+		assert.Equal(t, devicestatus.Unregistered, foundDevice.Status)
+
+		// Activate and save.
+		require.NoError(t, foundDevice.Activate())
+		err = repo.Save(ctx, foundDevice)
+		require.NoError(t, err)
+
+		// Re-fetch from the DB and verify.
+		foundDevice, err = repo.FindByID(ctx, savedDevice.ID)
+		require.NoError(t, err)
+		assert.Equal(t, devicestatus.Active, foundDevice.Status)
+	})
+
+	// FindByQuery and Count
+	t.Run("FindByQuery - Filters, sorts and pages through devices", func(t *testing.T) {
+		cleanupTable(t)
+
+		// Prepare and save test data. The names are saved in an order different from the creation order.
+		for _, data := range []struct{ hardwareID, name string }{
+			{hardwareID: "hw-query-01", name: "Beta"},
+			{hardwareID: "hw-query-02", name: "Alpha"},
+			{hardwareID: "hw-query_03", name: "Gamma"},
+			{hardwareID: "gw-query-01", name: "Delta"},
+		} {
+			device, err := entity.NewDevice(data.hardwareID, &data.name, nil)
+			require.NoError(t, err)
+			require.NoError(t, repo.Save(ctx, device))
+		}
+
+		hardwareIDs := func(devices []*entity.Device) []string {
+			ids := make([]string, 0, len(devices))
+			for _, device := range devices {
+				ids = append(ids, device.HardwareID)
+			}
+
+			return ids
+		}
+
+		// Page through the devices by creation time, two at a time.
+		query := repository.DeviceQuery{ //nolint:exhaustruct
+			SortBy: repository.DeviceSortCreatedAt,
+			Limit:  2,
+		}
+		first, err := repo.FindByQuery(ctx, query)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"hw-query-01", "hw-query-02"}, hardwareIDs(first))
+
+		last := first[len(first)-1]
+		query.After = &repository.DeviceCursor{ID: last.ID, CreatedAt: last.CreatedAt} //nolint:exhaustruct
+		second, err := repo.FindByQuery(ctx, query)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"hw-query_03", "gw-query-01"}, hardwareIDs(second))
+
+		// Sort by name in descending order.
+		byName, err := repo.FindByQuery(ctx, repository.DeviceQuery{ //nolint:exhaustruct
+			SortBy:     repository.DeviceSortName,
+			Descending: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"hw-query_03", "gw-query-01", "hw-query-01", "hw-query-02"}, hardwareIDs(byName))
+
+		// The wildcards of the hardware ID prefix are matched literally, and the name is matched ignoring case.
+		filter := repository.DeviceFilter{HardwareIDPrefix: "hw-query_", NameContains: "AMM"} //nolint:exhaustruct
+
+		filtered, err := repo.FindByQuery(ctx, repository.DeviceQuery{Filter: filter}) //nolint:exhaustruct
+		require.NoError(t, err)
+		assert.Equal(t, []string{"hw-query_03"}, hardwareIDs(filtered))
+
+		count, err := repo.Count(ctx, repository.DeviceFilter{HardwareIDPrefix: "hw-"}) //nolint:exhaustruct
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+
+		count, err = repo.Count(ctx, repository.DeviceFilter{Status: devicestatus.Active}) //nolint:exhaustruct
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("FindEach - Reads all the matching devices in order", func(t *testing.T) {
+		cleanupTable(t)
+
+		for _, data := range []struct{ hardwareID, name string }{
+			{hardwareID: "hw-each-01", name: "Beta"},
+			{hardwareID: "hw-each-02", name: "Alpha"},
+			{hardwareID: "gw-each-01", name: "Gamma"},
+		} {
+			device, err := entity.NewDevice(data.hardwareID, &data.name, map[string]any{"location": map[string]any{
+				"building": "Factory-A",
+			}})
+			require.NoError(t, err)
+			require.NoError(t, repo.Save(ctx, device))
+		}
+
+		var names []string
+
+		query := repository.DeviceQuery{ //nolint:exhaustruct
+			Filter: repository.DeviceFilter{HardwareIDPrefix: "hw-"}, //nolint:exhaustruct
+			SortBy: repository.DeviceSortName,
+		}
+		err := repo.FindEach(ctx, query, func(device *entity.Device) error {
+			assert.Equal(t, map[string]any{"building": "Factory-A"}, device.Metadata["location"])
+			names = append(names, device.Name)
+
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Alpha", "Beta"}, names)
+
+		// The iteration stops at the first error of the callback.
+		errStop := errors.New("stop")
+		calls := 0
+
+		err = repo.FindEach(ctx, repository.DeviceQuery{}, func(*entity.Device) error { //nolint:exhaustruct
+			calls++
+
+			return errStop
+		})
+		require.ErrorIs(t, err, errStop)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("FindByQuery - Filters devices by metadata", func(t *testing.T) {
+		cleanupTable(t)
+
+		// Prepare and save test data. The firmware version of hw-meta-03 is a string.
+		newMetadata := func(building string, version any) map[string]any {
+			return map[string]any{
+				"location": map[string]any{"building": building},
+				"firmware": map[string]any{"version": version},
+			}
+		}
+
+		for hardwareID, metadata := range map[string]map[string]any{
+			"hw-meta-01": newMetadata("Factory-A", 2.5),
+			"hw-meta-02": newMetadata("Factory-B", 2.3),
+			"hw-meta-03": newMetadata("Factory-A", "2.4"),
+			"hw-meta-04": {"decommissioned": true},
+		} {
+			device, err := entity.NewDevice(hardwareID, nil, metadata)
+			require.NoError(t, err)
+			require.NoError(t, repo.Save(ctx, device))
+		}
+
+		tests := []struct {
+			name      string
+			condition repository.MetadataCondition
+			want      []string
+		}{
+			{
+				name: "equals",
+				condition: repository.MetadataCondition{
+					Path: []string{"location", "building"}, Operator: repository.MetadataEquals, Value: "Factory-A",
+				},
+				want: []string{"hw-meta-01", "hw-meta-03"},
+			},
+			{
+				name: "not equals includes devices without the path",
+				condition: repository.MetadataCondition{
+					Path: []string{"location", "building"}, Operator: repository.MetadataNotEquals, Value: "Factory-A",
+				},
+				want: []string{"hw-meta-02", "hw-meta-04"},
+			},
+			{
+				name: "numbers are compared numerically and version strings as versions",
+				condition: repository.MetadataCondition{
+					Path:     []string{"firmware", "version"},
+					Operator: repository.MetadataGreaterOrEqual,
+					Value:    json.Number("2.4"),
+				},
+				want: []string{"hw-meta-01", "hw-meta-03"},
+			},
+			{
+				name: "versions are compared component by component",
+				condition: repository.MetadataCondition{
+					Path: []string{"firmware", "version"}, Operator: repository.MetadataLess, Value: "2.10",
+				},
+				want: []string{"hw-meta-03"},
+			},
+			{
+				name: "other types do not match",
+				condition: repository.MetadataCondition{
+					Path:     []string{"location"},
+					Operator: repository.MetadataGreaterOrEqual,
+					Value:    json.Number("0"),
+				},
+				want: []string{},
+			},
+			{
+				name: "strings are compared as strings",
+				condition: repository.MetadataCondition{
+					Path: []string{"location", "building"}, Operator: repository.MetadataGreater, Value: "Factory-A",
+				},
+				want: []string{"hw-meta-02"},
+			},
+			{
+				name: "nested path exists",
+				condition: repository.MetadataCondition{
+					Path: []string{"firmware", "version"}, Operator: repository.MetadataExists, Value: nil,
+				},
+				want: []string{"hw-meta-01", "hw-meta-02", "hw-meta-03"},
+			},
+			{
+				name: "top-level path does not exist",
+				condition: repository.MetadataCondition{
+					Path: []string{"location"}, Operator: repository.MetadataNotExists, Value: nil,
+				},
+				want: []string{"hw-meta-04"},
+			},
+			{
+				name: "keys are passed as parameters",
+				condition: repository.MetadataCondition{
+					Path: []string{`location'"}`, "building"}, Operator: repository.MetadataExists, Value: nil,
+				},
+				want: []string{},
+			},
+		}
+
+		for _, tt := range tests {
+			filter := repository.DeviceFilter{ //nolint:exhaustruct
+				Metadata: []repository.MetadataCondition{tt.condition},
+			}
+
+			devices, err := repo.FindByQuery(ctx, repository.DeviceQuery{ //nolint:exhaustruct
+				Filter: filter,
+				SortBy: repository.DeviceSortCreatedAt,
+			})
+			require.NoError(t, err, tt.name)
+
+			hardwareIDs := make([]string, 0, len(devices))
+			for _, device := range devices {
+				hardwareIDs = append(hardwareIDs, device.HardwareID)
+			}
+
+			assert.ElementsMatch(t, tt.want, hardwareIDs, tt.name)
+
+			count, err := repo.Count(ctx, filter)
+			require.NoError(t, err, tt.name)
+			assert.Equal(t, int64(len(tt.want)), count, tt.name)
+		}
+	})
+
+	t.Run("FindAncestors - Walks up the parents and filters children", func(t *testing.T) {
+		cleanupTable(t)
+
+		// gateway <- hub <- sensor
+		devices := make([]*entity.Device, 0, 3)
+
+		for _, hardwareID := range []string{"gw-topo-01", "hub-topo-01", "sensor-topo-01"} {
+			device, err := entity.NewDevice(hardwareID, nil, nil)
+			require.NoError(t, err)
+
+			if len(devices) > 0 {
+				require.NoError(t, device.AttachTo(devices[len(devices)-1], nil))
+			}
+
+			require.NoError(t, repo.Save(ctx, device))
+
+			devices = append(devices, device)
+		}
+
+		ancestors, err := repo.FindAncestors(ctx, devices[2].ID)
+		require.NoError(t, err)
+		require.Len(t, ancestors, 2)
+		assert.Equal(t, devices[1].ID, ancestors[0].ID)
+		assert.Equal(t, devices[0].ID, ancestors[1].ID)
+
+		ancestors, err = repo.FindAncestors(ctx, devices[0].ID)
+		require.NoError(t, err)
+		assert.Empty(t, ancestors)
+
+		children, err := repo.FindByQuery(ctx, repository.DeviceQuery{ //nolint:exhaustruct
+			Filter: repository.DeviceFilter{ParentID: &devices[0].ID}, //nolint:exhaustruct
+		})
+		require.NoError(t, err)
+		require.Len(t, children, 1)
+		assert.Equal(t, devices[1].ID, children[0].ID)
+
+		require.NoError(t, repo.LockTopology(ctx))
+
+		// Deleting a parent leaves its children without one.
+		require.NoError(t, repo.Delete(ctx, devices[1].ID, devices[1].Version))
+
+		found, err := repo.FindByID(ctx, devices[2].ID)
+		require.NoError(t, err)
+		assert.Nil(t, found.ParentID)
+	})
+
+	t.Run("RecordPresence - Tracks presence apart from the versioned updates", func(t *testing.T) {
+		cleanupTable(t)
+
+		seen, err := entity.NewDevice("hw-presence-01", nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, repo.Save(ctx, seen))
+
+		neverSeen, err := entity.NewDevice("hw-presence-02", nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, repo.Save(ctx, neverSeen))
+
+		seenAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Microsecond)
+		err = repo.RecordPresence(ctx, seen.ID, repository.DevicePresence{
+			State:  connectionstate.Connected,
+			SeenAt: &seenAt,
+			IP:     "192.0.2.10",
+		})
+		require.NoError(t, err)
+
+		err = repo.RecordPresence(ctx, uuid.New(), repository.DevicePresence{
+			State: connectionstate.Disconnected, SeenAt: nil, IP: "",
+		})
+		require.ErrorIs(t, err, entity.ErrDeviceNotFound)
+
+		found, err := repo.FindByID(ctx, seen.ID)
+		require.NoError(t, err)
+		assert.Equal(t, connectionstate.Connected, found.ConnectionState)
+		require.NotNil(t, found.LastSeenAt)
+		assert.True(t, seenAt.Equal(*found.LastSeenAt))
+		assert.Equal(t, "192.0.2.10", found.LastIP)
+		assert.Equal(t, seen.Version, found.Version, "presence does not change the version")
+
+		// An update made with the device read before the presence leaves the presence alone.
+		seen.Name = "renamed"
+		require.NoError(t, repo.Save(ctx, seen))
+
+		found, err = repo.FindByID(ctx, seen.ID)
+		require.NoError(t, err)
+		assert.Equal(t, connectionstate.Connected, found.ConnectionState)
+		assert.Equal(t, "192.0.2.10", found.LastIP)
+
+		online := true
+		devices, err := repo.FindByQuery(ctx, repository.DeviceQuery{ //nolint:exhaustruct
+			Filter: repository.DeviceFilter{Online: &online}, //nolint:exhaustruct
+		})
+		require.NoError(t, err)
+		require.Len(t, devices, 1)
+		assert.Equal(t, seen.ID, devices[0].ID)
+
+		// The devices never seen are included in the devices last seen before a time.
+		before := seenAt.Add(time.Minute)
+		count, err := repo.Count(ctx, repository.DeviceFilter{LastSeenBefore: &before}) //nolint:exhaustruct
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+
+		before = seenAt
+		count, err = repo.Count(ctx, repository.DeviceFilter{LastSeenBefore: &before}) //nolint:exhaustruct
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+
+		// Only the CONNECTED devices last seen before the time are marked as STALE.
+		marked, err := repo.MarkStale(ctx, seenAt)
+		require.NoError(t, err)
+		assert.Zero(t, marked)
+
+		marked, err = repo.MarkStale(ctx, seenAt.Add(time.Second))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), marked)
+
+		online = false
+		count, err = repo.Count(ctx, repository.DeviceFilter{Online: &online}) //nolint:exhaustruct
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+
 	// Delete
 	t.Run("Delete - Deletes an existing device", func(t *testing.T) {
 		cleanupTable(t)
@@ -123,7 +584,7 @@ func TestDeviceGormRepository_Integration(t *testing.T) {
 		require.NoError(t, err)
 
 		// Execute.
-		err = repo.Delete(ctx, savedDevice.ID)
+		err = repo.Delete(ctx, savedDevice.ID, savedDevice.Version)
 		require.NoError(t, err)
 
 		// Verify that re-fetching from the DB fails (record not found).
@@ -0,0 +1,155 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+// SQLSTATE codes of the constraint violations translated into domain errors.
+const (
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+)
+
+// deviceGroupMembersGroupFK is the foreign key from the members to their group, as named by the migration.
+const deviceGroupMembersGroupFK = "fk_device_group_members_group"
+
+// DeviceGroupGormRepository is the GORM implementation of the DeviceGroupRepository.
+type DeviceGroupGormRepository struct {
+	db *gorm.DB
+}
+
+// NewDeviceGroupGormRepository creates a new instance of DeviceGroupGormRepository.
+//
+//nolint:ireturn
+func NewDeviceGroupGormRepository(db *gorm.DB) repository.DeviceGroupRepository {
+	return &DeviceGroupGormRepository{db: db}
+}
+
+// Save creates a new device group or updates an existing one.
+// The unique index on the name decides whether it is taken, so that concurrent requests cannot both take it.
+func (r *DeviceGroupGormRepository) Save(ctx context.Context, group *entity.DeviceGroup) error {
+	var err error
+	if group.ID == uuid.Nil {
+		err = conn(ctx, r.db).Create(group).Error
+	} else {
+		err = conn(ctx, r.db).Save(group).Error
+	}
+
+	if hasSQLState(err, pgUniqueViolation) {
+		return entity.ErrDeviceGroupNameTaken
+	}
+
+	return err
+}
+
+// FindByID finds a device group by its UUID.
+func (r *DeviceGroupGormRepository) FindByID(ctx context.Context, id uuid.UUID) (*entity.DeviceGroup, error) {
+	var group entity.DeviceGroup
+	// It returns `gorm.ErrRecordNotFound` if no record is found.
+	err := conn(ctx, r.db).First(&group, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+// FindAll retrieves all the device groups, sorted by name.
+func (r *DeviceGroupGormRepository) FindAll(ctx context.Context) ([]*entity.DeviceGroup, error) {
+	var groups []*entity.DeviceGroup
+	// It returns an empty slice if no groups are found.
+	err := conn(ctx, r.db).Order("name ASC").Find(&groups).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// Delete removes a device group. Its members are removed by the ON DELETE CASCADE of the foreign key.
+func (r *DeviceGroupGormRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result := conn(ctx, r.db).Where("id = ?", id).Delete(&entity.DeviceGroup{}) //nolint:exhaustruct
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return entity.ErrDeviceGroupNotFound
+	}
+
+	return nil
+}
+
+// AddMembers adds devices to a device group in one statement.
+// The devices that are already members are left as they are, and are not returned.
+func (r *DeviceGroupGormRepository) AddMembers(
+	ctx context.Context,
+	groupID uuid.UUID,
+	deviceIDs []uuid.UUID,
+) ([]uuid.UUID, error) {
+	added := make([]uuid.UUID, 0, len(deviceIDs))
+
+	if len(deviceIDs) == 0 {
+		return added, nil
+	}
+
+	ids := make([]string, 0, len(deviceIDs))
+	for _, id := range deviceIDs {
+		ids = append(ids, id.String())
+	}
+
+	// The IDs are passed as one array parameter, because GORM would expand a slice into a list.
+	err := conn(ctx, r.db).Raw(
+		"INSERT INTO device_group_members (group_id, device_id) "+
+			"SELECT ?, device_id FROM unnest(?::uuid[]) AS device_id "+
+			"ON CONFLICT DO NOTHING RETURNING device_id",
+		groupID, textArrayLiteral(ids),
+	).Scan(&added).Error
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgForeignKeyViolation {
+		// The group may have been deleted meanwhile; otherwise one of the devices does not exist.
+		if pgErr.ConstraintName == deviceGroupMembersGroupFK {
+			return nil, entity.ErrDeviceGroupNotFound
+		}
+
+		return nil, entity.ErrDeviceNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return added, nil
+}
+
+// RemoveMember removes a device from a device group.
+func (r *DeviceGroupGormRepository) RemoveMember(ctx context.Context, groupID, deviceID uuid.UUID) error {
+	result := conn(ctx, r.db).
+		Where("group_id = ? AND device_id = ?", groupID, deviceID).
+		Delete(&entity.DeviceGroupMember{}) //nolint:exhaustruct
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return entity.ErrDeviceGroupMemberNotFound
+	}
+
+	return nil
+}
+
+// hasSQLState reports whether err is a PostgreSQL error with the SQLSTATE code.
+func hasSQLState(err error, code string) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
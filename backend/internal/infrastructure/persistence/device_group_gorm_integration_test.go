@@ -0,0 +1,181 @@
+package persistence_test
+
+import (
+	"context"
+	"testing"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"backend/internal/infrastructure/persistence"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// TestDeviceGroupGormRepository_Integration performs integration tests for
+// the GORM device group repository against a real database.
+func TestDeviceGroupGormRepository_Integration(t *testing.T) {
+	// testDB is initialized in main_test.go.
+	if testDB == nil {
+		t.Fatal("testDB is not initialized")
+	}
+
+	repo := persistence.NewDeviceGroupGormRepository(testDB)
+	deviceRepo := persistence.NewDeviceGormRepository(testDB)
+	ctx := context.Background()
+
+	// saveDevices creates devices with the metadata, in order.
+	saveDevices := func(t *testing.T, metadata ...map[string]any) []*entity.Device {
+		t.Helper()
+
+		devices := make([]*entity.Device, 0, len(metadata))
+
+		for i, values := range metadata {
+			device, err := entity.NewDevice("hw-group-0"+string(rune('1'+i)), nil, values)
+			require.NoError(t, err)
+			require.NoError(t, deviceRepo.Save(ctx, device))
+
+			devices = append(devices, device)
+		}
+
+		return devices
+	}
+
+	t.Run("Save - Creates and updates a group, and rejects a taken name", func(t *testing.T) {
+		cleanupTable(t)
+
+		group, err := entity.NewDeviceGroup("Factory A", "Devices of the factory", []string{"location.building=A"})
+		require.NoError(t, err)
+		require.NoError(t, repo.Save(ctx, group))
+		assert.NotEqual(t, uuid.Nil, group.ID)
+
+		found, err := repo.FindByID(ctx, group.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Factory A", found.Name)
+		assert.Equal(t, entity.JSONBStrings{"location.building=A"}, found.Rule)
+
+		found.Rule = nil
+		found.Description = ""
+		require.NoError(t, repo.Save(ctx, found))
+
+		found, err = repo.FindByID(ctx, group.ID)
+		require.NoError(t, err)
+		assert.Empty(t, found.Rule)
+		assert.Empty(t, found.Description)
+
+		other, err := entity.NewDeviceGroup("Factory A", "", nil)
+		require.NoError(t, err)
+		require.ErrorIs(t, repo.Save(ctx, other), entity.ErrDeviceGroupNameTaken)
+
+		other.Name = "Depot"
+		require.NoError(t, repo.Save(ctx, other))
+
+		groups, err := repo.FindAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, groups, 2)
+		assert.Equal(t, "Depot", groups[0].Name)
+	})
+
+	t.Run("AddMembers - Adds the devices that are not members yet", func(t *testing.T) {
+		cleanupTable(t)
+
+		devices := saveDevices(t, nil, nil)
+		group, err := entity.NewDeviceGroup("Line 1", "", nil)
+		require.NoError(t, err)
+		require.NoError(t, repo.Save(ctx, group))
+
+		added, err := repo.AddMembers(ctx, group.ID, []uuid.UUID{devices[0].ID})
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{devices[0].ID}, added)
+
+		added, err = repo.AddMembers(ctx, group.ID, []uuid.UUID{devices[0].ID, devices[1].ID})
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{devices[1].ID}, added)
+
+		// None is added if any of the devices does not exist.
+		_, err = repo.AddMembers(ctx, group.ID, []uuid.UUID{devices[0].ID, uuid.New()})
+		require.ErrorIs(t, err, entity.ErrDeviceNotFound)
+
+		_, err = repo.AddMembers(ctx, uuid.New(), []uuid.UUID{devices[0].ID})
+		require.ErrorIs(t, err, entity.ErrDeviceGroupNotFound)
+
+		require.NoError(t, repo.RemoveMember(ctx, group.ID, devices[0].ID))
+		require.ErrorIs(t, repo.RemoveMember(ctx, group.ID, devices[0].ID), entity.ErrDeviceGroupMemberNotFound)
+	})
+
+	t.Run("DeviceFilter - Selects the members added to a group or matching its rule", func(t *testing.T) {
+		cleanupTable(t)
+
+		devices := saveDevices(t,
+			map[string]any{"location": map[string]any{"building": "A"}},
+			map[string]any{"location": map[string]any{"building": "B"}},
+			map[string]any{"location": map[string]any{"building": "A"}, "spare": true},
+			nil,
+		)
+		group, err := entity.NewDeviceGroup("Building A", "", []string{"location.building=A"})
+		require.NoError(t, err)
+		require.NoError(t, repo.Save(ctx, group))
+
+		_, err = repo.AddMembers(ctx, group.ID, []uuid.UUID{devices[1].ID, devices[2].ID})
+		require.NoError(t, err)
+
+		rule := []repository.MetadataCondition{{
+			Path: []string{"location", "building"}, Operator: repository.MetadataEquals, Value: "A",
+		}}
+		filter := repository.DeviceFilter{ //nolint:exhaustruct
+			Metadata: []repository.MetadataCondition{{
+				Path: []string{"spare"}, Operator: repository.MetadataNotExists, Value: nil,
+			}},
+			Group: &repository.GroupMembership{GroupID: group.ID, Rule: rule},
+		}
+
+		found, err := deviceRepo.FindByQuery(ctx, repository.DeviceQuery{ //nolint:exhaustruct
+			Filter: filter,
+			SortBy: repository.DeviceSortCreatedAt,
+		})
+		require.NoError(t, err)
+
+		// The other conditions of the filter still apply to the members.
+		ids := make([]uuid.UUID, 0, len(found))
+		for _, device := range found {
+			ids = append(ids, device.ID)
+		}
+
+		assert.Equal(t, []uuid.UUID{devices[0].ID, devices[1].ID}, ids)
+
+		// Without the rule, only the added devices are members.
+		filter.Group.Rule = nil
+
+		count, err := deviceRepo.Count(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Delete - Deletes a group with its members but not its devices", func(t *testing.T) {
+		cleanupTable(t)
+
+		devices := saveDevices(t, nil)
+		group, err := entity.NewDeviceGroup("Line 1", "", nil)
+		require.NoError(t, err)
+		require.NoError(t, repo.Save(ctx, group))
+
+		_, err = repo.AddMembers(ctx, group.ID, []uuid.UUID{devices[0].ID})
+		require.NoError(t, err)
+
+		require.NoError(t, repo.Delete(ctx, group.ID))
+		require.ErrorIs(t, repo.Delete(ctx, group.ID), entity.ErrDeviceGroupNotFound)
+
+		_, err = repo.FindByID(ctx, group.ID)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		_, err = deviceRepo.FindByID(ctx, devices[0].ID)
+		require.NoError(t, err)
+
+		var members int64
+
+		require.NoError(t, testDB.Model(&entity.DeviceGroupMember{}).Count(&members).Error) //nolint:exhaustruct
+		assert.Zero(t, members)
+	})
+}
@@ -0,0 +1,74 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+// DeviceTwinGormRepository is the GORM implementation of the DeviceTwinRepository.
+type DeviceTwinGormRepository struct {
+	db *gorm.DB
+}
+
+// NewDeviceTwinGormRepository creates a new instance of DeviceTwinGormRepository.
+//
+//nolint:ireturn
+func NewDeviceTwinGormRepository(db *gorm.DB) repository.DeviceTwinRepository {
+	return &DeviceTwinGormRepository{db: db}
+}
+
+// FindByDeviceID finds the twin of a device.
+func (r *DeviceTwinGormRepository) FindByDeviceID(ctx context.Context, deviceID uuid.UUID) (*entity.DeviceTwin, error) {
+	var twin entity.DeviceTwin
+	// It returns `gorm.ErrRecordNotFound` if no record is found.
+	err := conn(ctx, r.db).First(&twin, "device_id = ?", deviceID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &twin, nil
+}
+
+// SaveDesired upserts the desired state of a twin, conditional on the previous desired version.
+func (r *DeviceTwinGormRepository) SaveDesired(ctx context.Context, twin *entity.DeviceTwin) error {
+	return r.saveState(ctx, twin, "desired")
+}
+
+// SaveReported upserts the reported state of a twin, conditional on the previous reported version.
+func (r *DeviceTwinGormRepository) SaveReported(ctx context.Context, twin *entity.DeviceTwin) error {
+	return r.saveState(ctx, twin, "reported")
+}
+
+// saveState inserts a twin with one of its states, or updates only that state if the twin exists
+// and the stored version of the state is the one before the version of the twin.
+// A twin is only inserted when none of its states has been saved yet, so the other state is left empty.
+func (r *DeviceTwinGormRepository) saveState(ctx context.Context, twin *entity.DeviceTwin, state string) error {
+	columns := []string{state, state + "_version", state + "_updated_at"}
+
+	result := conn(ctx, r.db).Clauses(clause.OnConflict{ //nolint:exhaustruct
+		Columns:   []clause.Column{{Name: "device_id"}}, //nolint:exhaustruct
+		DoUpdates: clause.AssignmentColumns(columns),
+		Where: clause.Where{Exprs: []clause.Expression{clause.Expr{ //nolint:exhaustruct
+			SQL: "device_twins." + state + "_version = excluded." + state + "_version - 1",
+		}}},
+	}).Select(append([]string{"device_id"}, columns...)).Create(twin)
+	if hasSQLState(result.Error, pgForeignKeyViolation) {
+		return entity.ErrDeviceNotFound
+	}
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return entity.ErrDeviceTwinVersionConflict
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package persistence_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend/internal/domain/entity"
+	"backend/internal/infrastructure/persistence"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// TestDeviceTwinGormRepository_Integration performs integration tests for
+// the GORM device twin repository against a real database.
+func TestDeviceTwinGormRepository_Integration(t *testing.T) {
+	// testDB is initialized in main_test.go.
+	if testDB == nil {
+		t.Fatal("testDB is not initialized")
+	}
+
+	repo := persistence.NewDeviceTwinGormRepository(testDB)
+	deviceRepo := persistence.NewDeviceGormRepository(testDB)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	t.Run("SaveDesired and SaveReported - Save each state separately", func(t *testing.T) {
+		cleanupTable(t)
+
+		device, err := entity.NewDevice("hw-twin-01", nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, deviceRepo.Save(ctx, device))
+
+		_, err = repo.FindByDeviceID(ctx, device.ID)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		desired := entity.NewDeviceTwin(device.ID)
+		desired.SetDesired(entity.JSONBMap{"config": map[string]any{"mode": "eco"}}, now)
+		require.NoError(t, repo.SaveDesired(ctx, desired))
+
+		// The reported state is saved from a twin read before the desired state was set.
+		reported := entity.NewDeviceTwin(device.ID)
+		reported.SetReported(entity.JSONBMap{"firmware": "1.2.0"}, now)
+		require.NoError(t, repo.SaveReported(ctx, reported))
+
+		found, err := repo.FindByDeviceID(ctx, device.ID)
+		require.NoError(t, err)
+		assert.Equal(t, entity.JSONBMap{"config": map[string]any{"mode": "eco"}}, found.Desired)
+		assert.Equal(t, int64(1), found.DesiredVersion)
+		require.NotNil(t, found.DesiredUpdatedAt)
+		assert.True(t, found.DesiredUpdatedAt.Equal(now))
+		assert.Equal(t, entity.JSONBMap{"firmware": "1.2.0"}, found.Reported)
+		assert.Equal(t, int64(1), found.ReportedVersion)
+
+		found.SetDesired(entity.JSONBMap{}, now)
+		require.NoError(t, repo.SaveDesired(ctx, found))
+
+		found, err = repo.FindByDeviceID(ctx, device.ID)
+		require.NoError(t, err)
+		assert.Empty(t, found.Desired)
+		assert.Equal(t, int64(2), found.DesiredVersion)
+		assert.Equal(t, int64(1), found.ReportedVersion)
+	})
+
+	t.Run("SaveDesired - Rejects a stale twin and an unknown device", func(t *testing.T) {
+		cleanupTable(t)
+
+		device, err := entity.NewDevice("hw-twin-01", nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, deviceRepo.Save(ctx, device))
+
+		first := entity.NewDeviceTwin(device.ID)
+		first.SetDesired(entity.JSONBMap{"mode": "eco"}, now)
+		require.NoError(t, repo.SaveDesired(ctx, first))
+
+		stale := entity.NewDeviceTwin(device.ID)
+		stale.SetDesired(entity.JSONBMap{"mode": "normal"}, now)
+		require.ErrorIs(t, repo.SaveDesired(ctx, stale), entity.ErrDeviceTwinVersionConflict)
+
+		unknown := entity.NewDeviceTwin(uuid.New())
+		unknown.SetReported(entity.JSONBMap{"mode": "eco"}, now)
+		require.ErrorIs(t, repo.SaveReported(ctx, unknown), entity.ErrDeviceNotFound)
+	})
+
+	t.Run("Delete - Deleting a device deletes its twin", func(t *testing.T) {
+		cleanupTable(t)
+
+		device, err := entity.NewDevice("hw-twin-01", nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, deviceRepo.Save(ctx, device))
+
+		twin := entity.NewDeviceTwin(device.ID)
+		twin.SetDesired(entity.JSONBMap{"mode": "eco"}, now)
+		require.NoError(t, repo.SaveDesired(ctx, twin))
+		require.NoError(t, deviceRepo.Delete(ctx, device.ID, device.Version))
+
+		_, err = repo.FindByDeviceID(ctx, device.ID)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
@@ -0,0 +1,105 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+// EnrollmentTokenGormRepository is the GORM implementation of the EnrollmentTokenRepository.
+type EnrollmentTokenGormRepository struct {
+	db *gorm.DB
+}
+
+// NewEnrollmentTokenGormRepository creates a new instance of EnrollmentTokenGormRepository.
+//
+//nolint:ireturn
+func NewEnrollmentTokenGormRepository(db *gorm.DB) repository.EnrollmentTokenRepository {
+	return &EnrollmentTokenGormRepository{db: db}
+}
+
+// Save stores a newly created enrollment token.
+func (r *EnrollmentTokenGormRepository) Save(ctx context.Context, token *entity.EnrollmentToken) error {
+	return conn(ctx, r.db).Create(token).Error
+}
+
+// FindByID finds an enrollment token by its UUID.
+func (r *EnrollmentTokenGormRepository) FindByID(ctx context.Context, id uuid.UUID) (*entity.EnrollmentToken, error) {
+	var token entity.EnrollmentToken
+	// It returns `gorm.ErrRecordNotFound` if no record is found.
+	err := conn(ctx, r.db).First(&token, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// FindByDeviceID retrieves all enrollment tokens of a device, newest first.
+func (r *EnrollmentTokenGormRepository) FindByDeviceID(
+	ctx context.Context,
+	deviceID uuid.UUID,
+) ([]*entity.EnrollmentToken, error) {
+	var tokens []*entity.EnrollmentToken
+	// It returns an empty slice if no tokens are found.
+	err := conn(ctx, r.db).Where("device_id = ?", deviceID).Order("created_at DESC").Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// FindByTokenHash finds an enrollment token by the hash of its secret.
+func (r *EnrollmentTokenGormRepository) FindByTokenHash(
+	ctx context.Context,
+	tokenHash string,
+) (*entity.EnrollmentToken, error) {
+	var token entity.EnrollmentToken
+	// It returns `gorm.ErrRecordNotFound` if no record is found.
+	err := conn(ctx, r.db).Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkUsed marks an unused enrollment token as used.
+// The condition on `used_at` and `revoked_at` makes it atomic, so a token can be consumed only once
+// even if it is presented concurrently, and never after it has been revoked.
+func (r *EnrollmentTokenGormRepository) MarkUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	return r.updatePending(ctx, id, "used_at", usedAt)
+}
+
+// Revoke marks an unused enrollment token as revoked.
+func (r *EnrollmentTokenGormRepository) Revoke(ctx context.Context, id uuid.UUID, revokedAt time.Time) error {
+	return r.updatePending(ctx, id, "revoked_at", revokedAt)
+}
+
+// updatePending sets a timestamp column of a token that has been neither used nor revoked.
+func (r *EnrollmentTokenGormRepository) updatePending(
+	ctx context.Context,
+	id uuid.UUID,
+	column string,
+	at time.Time,
+) error {
+	result := conn(ctx, r.db).
+		Model(&entity.EnrollmentToken{}). //nolint:exhaustruct
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update(column, at)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return entity.ErrEnrollmentTokenUsed
+	}
+
+	return nil
+}
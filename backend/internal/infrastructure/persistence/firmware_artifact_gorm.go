@@ -0,0 +1,105 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+// FirmwareArtifactGormRepository is the GORM implementation of the FirmwareArtifactRepository.
+type FirmwareArtifactGormRepository struct {
+	db *gorm.DB
+}
+
+// NewFirmwareArtifactGormRepository creates a new instance of FirmwareArtifactGormRepository.
+//
+//nolint:ireturn
+func NewFirmwareArtifactGormRepository(db *gorm.DB) repository.FirmwareArtifactRepository {
+	return &FirmwareArtifactGormRepository{db: db}
+}
+
+// Save stores a newly uploaded firmware artifact.
+// The unique index on the version decides whether it is taken, so that concurrent uploads cannot both take it.
+func (r *FirmwareArtifactGormRepository) Save(ctx context.Context, artifact *entity.FirmwareArtifact) error {
+	err := conn(ctx, r.db).Create(artifact).Error
+	if hasSQLState(err, pgUniqueViolation) {
+		return entity.ErrFirmwareVersionTaken
+	}
+
+	return err
+}
+
+// FindByID finds a firmware artifact by its UUID.
+func (r *FirmwareArtifactGormRepository) FindByID(ctx context.Context, id uuid.UUID) (*entity.FirmwareArtifact, error) {
+	var artifact entity.FirmwareArtifact
+	// It returns `gorm.ErrRecordNotFound` if no record is found.
+	err := conn(ctx, r.db).First(&artifact, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &artifact, nil
+}
+
+// FindAll retrieves all the firmware artifacts, newest first.
+func (r *FirmwareArtifactGormRepository) FindAll(ctx context.Context) ([]*entity.FirmwareArtifact, error) {
+	var artifacts []*entity.FirmwareArtifact
+	// It returns an empty slice if no artifacts are found.
+	err := conn(ctx, r.db).Order("created_at DESC, id").Find(&artifacts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return artifacts, nil
+}
+
+// Delete removes a firmware artifact. The foreign key of the campaigns rejects it while a campaign refers to it.
+func (r *FirmwareArtifactGormRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result := conn(ctx, r.db).Where("id = ?", id).Delete(&entity.FirmwareArtifact{}) //nolint:exhaustruct
+	if hasSQLState(result.Error, pgForeignKeyViolation) {
+		return entity.ErrFirmwareArtifactInUse
+	}
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return entity.ErrFirmwareArtifactNotFound
+	}
+
+	return nil
+}
+
+// SaveManifest stores the signed manifest of a firmware artifact, replacing the previous one.
+func (r *FirmwareArtifactGormRepository) SaveManifest(ctx context.Context, manifest *entity.FirmwareManifest) error {
+	err := conn(ctx, r.db).Clauses(clause.OnConflict{ //nolint:exhaustruct
+		Columns:   []clause.Column{{Name: "firmware_id"}}, //nolint:exhaustruct
+		UpdateAll: true,
+	}).Create(manifest).Error
+	if hasSQLState(err, pgForeignKeyViolation) {
+		return entity.ErrFirmwareArtifactNotFound
+	}
+
+	return err
+}
+
+// FindManifest finds the signed manifest of a firmware artifact.
+func (r *FirmwareArtifactGormRepository) FindManifest(
+	ctx context.Context,
+	firmwareID uuid.UUID,
+) (*entity.FirmwareManifest, error) {
+	var manifest entity.FirmwareManifest
+	// It returns `gorm.ErrRecordNotFound` if no record is found.
+	err := conn(ctx, r.db).First(&manifest, "firmware_id = ?", firmwareID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
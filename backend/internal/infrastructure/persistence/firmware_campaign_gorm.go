@@ -0,0 +1,237 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"backend/internal/domain/VO/campaignstatus"
+	"backend/internal/domain/VO/updatestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+// firmwareUpdateBatchSize is the number of firmware updates inserted by each statement.
+const firmwareUpdateBatchSize = 1000
+
+// FirmwareCampaignGormRepository is the GORM implementation of the FirmwareCampaignRepository.
+type FirmwareCampaignGormRepository struct {
+	db *gorm.DB
+}
+
+// NewFirmwareCampaignGormRepository creates a new instance of FirmwareCampaignGormRepository.
+//
+//nolint:ireturn
+func NewFirmwareCampaignGormRepository(db *gorm.DB) repository.FirmwareCampaignRepository {
+	return &FirmwareCampaignGormRepository{db: db}
+}
+
+// Save creates a new firmware campaign, or updates an existing one if its stored version is still campaign.Version.
+func (r *FirmwareCampaignGormRepository) Save(ctx context.Context, campaign *entity.FirmwareCampaign) error {
+	if campaign.ID == uuid.Nil {
+		campaign.Version = 1
+
+		err := conn(ctx, r.db).Create(campaign).Error
+		if hasSQLState(err, pgForeignKeyViolation) {
+			return entity.ErrFirmwareArtifactNotFound
+		}
+
+		return err
+	}
+
+	version := campaign.Version
+	campaign.Version++
+
+	// GORM adds the primary key of the model to the conditions,
+	// so only the row with both the ID and the expected version is updated.
+	result := conn(ctx, r.db).Model(campaign).
+		Select("*").Omit("created_at").
+		Where("version = ?", version).
+		Updates(campaign)
+	if result.Error != nil || result.RowsAffected == 0 {
+		campaign.Version = version
+	}
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return entity.ErrFirmwareCampaignConflict
+	}
+
+	return nil
+}
+
+// FindByID finds a firmware campaign by its UUID.
+func (r *FirmwareCampaignGormRepository) FindByID(ctx context.Context, id uuid.UUID) (*entity.FirmwareCampaign, error) {
+	var campaign entity.FirmwareCampaign
+	// It returns `gorm.ErrRecordNotFound` if no record is found.
+	err := conn(ctx, r.db).First(&campaign, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &campaign, nil
+}
+
+// FindAll retrieves all the firmware campaigns, newest first.
+func (r *FirmwareCampaignGormRepository) FindAll(ctx context.Context) ([]*entity.FirmwareCampaign, error) {
+	var campaigns []*entity.FirmwareCampaign
+	// It returns an empty slice if no campaigns are found.
+	err := conn(ctx, r.db).Order("created_at DESC, id").Find(&campaigns).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return campaigns, nil
+}
+
+// FindByStatus retrieves the firmware campaigns with the status, oldest first.
+func (r *FirmwareCampaignGormRepository) FindByStatus(
+	ctx context.Context,
+	status campaignstatus.Status,
+) ([]*entity.FirmwareCampaign, error) {
+	var campaigns []*entity.FirmwareCampaign
+	// It returns an empty slice if no campaigns are found.
+	err := conn(ctx, r.db).Where("status = ?", status).Order("created_at, id").Find(&campaigns).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return campaigns, nil
+}
+
+// CreateUpdates inserts the updates of a new campaign in batches.
+func (r *FirmwareCampaignGormRepository) CreateUpdates(ctx context.Context, updates []*entity.FirmwareUpdate) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	err := conn(ctx, r.db).CreateInBatches(updates, firmwareUpdateBatchSize).Error
+	if hasSQLState(err, pgForeignKeyViolation) {
+		// The campaign has just been created, so one of the devices has been deleted meanwhile.
+		return entity.ErrDeviceNotFound
+	}
+
+	return err
+}
+
+// FindUpdate finds the update of a device in a campaign.
+func (r *FirmwareCampaignGormRepository) FindUpdate(
+	ctx context.Context,
+	campaignID, deviceID uuid.UUID,
+) (*entity.FirmwareUpdate, error) {
+	var update entity.FirmwareUpdate
+	// It returns `gorm.ErrRecordNotFound` if no record is found.
+	err := conn(ctx, r.db).First(&update, "campaign_id = ? AND device_id = ?", campaignID, deviceID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &update, nil
+}
+
+// FindUpdates retrieves the updates of a campaign matching the filter, by wave and then by device ID.
+func (r *FirmwareCampaignGormRepository) FindUpdates(
+	ctx context.Context,
+	campaignID uuid.UUID,
+	filter repository.FirmwareUpdateFilter,
+) ([]*entity.FirmwareUpdate, error) {
+	db := conn(ctx, r.db).Where("campaign_id = ?", campaignID)
+
+	if filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
+	}
+
+	if filter.MaxWave > 0 {
+		db = db.Where("wave <= ?", filter.MaxWave)
+	}
+
+	var updates []*entity.FirmwareUpdate
+	// It returns an empty slice if no updates are found.
+	err := db.Order("wave, device_id").Find(&updates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return updates, nil
+}
+
+// CountUpdates returns the number of updates of a campaign in each status.
+func (r *FirmwareCampaignGormRepository) CountUpdates(
+	ctx context.Context,
+	campaignID uuid.UUID,
+) (map[updatestatus.Status]int64, error) {
+	var rows []struct {
+		Status updatestatus.Status
+		Count  int64
+	}
+
+	err := conn(ctx, r.db).
+		Model(&entity.FirmwareUpdate{}). //nolint:exhaustruct
+		Select("status, COUNT(*) AS count").
+		Where("campaign_id = ?", campaignID).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[updatestatus.Status]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+
+	return counts, nil
+}
+
+// SaveUpdate stores the status of an update. The condition on the stored status makes it atomic,
+// so that an update is sent only once, and a report is not lost to a concurrent one.
+func (r *FirmwareCampaignGormRepository) SaveUpdate(
+	ctx context.Context,
+	update *entity.FirmwareUpdate,
+	from updatestatus.Status,
+) error {
+	result := conn(ctx, r.db).
+		Model(&entity.FirmwareUpdate{}). //nolint:exhaustruct
+		Where("campaign_id = ? AND device_id = ? AND status = ?", update.CampaignID, update.DeviceID, from).
+		Updates(map[string]any{
+			"status":         update.Status,
+			"progress":       update.Progress,
+			"failure_reason": update.FailureReason,
+			"sent_at":        update.SentAt,
+			"completed_at":   update.CompletedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return entity.ErrFirmwareUpdateStatusConflict
+	}
+
+	return nil
+}
+
+// FailOverdue marks the updates of a campaign that are in progress and were sent before sentBefore as FAILED.
+func (r *FirmwareCampaignGormRepository) FailOverdue(
+	ctx context.Context,
+	campaignID uuid.UUID,
+	sentBefore, now time.Time,
+	reason string,
+) (int64, error) {
+	inProgress := []updatestatus.Status{updatestatus.Sent, updatestatus.Downloading, updatestatus.Installing}
+
+	result := conn(ctx, r.db).
+		Model(&entity.FirmwareUpdate{}). //nolint:exhaustruct
+		Where("campaign_id = ? AND status IN ? AND sent_at < ?", campaignID, inProgress, sentBefore).
+		Updates(map[string]any{"status": updatestatus.Failed, "failure_reason": reason, "completed_at": now})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
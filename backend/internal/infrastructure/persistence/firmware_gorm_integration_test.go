@@ -0,0 +1,335 @@
+package persistence_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/campaignstatus"
+	"backend/internal/domain/VO/updatestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"backend/internal/infrastructure/persistence"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// TestFirmwareGormRepository_Integration performs integration tests for
+// the GORM firmware artifact and campaign repositories against a real database.
+func TestFirmwareGormRepository_Integration(t *testing.T) {
+	// testDB is initialized in main_test.go.
+	if testDB == nil {
+		t.Fatal("testDB is not initialized")
+	}
+
+	artifactRepo := persistence.NewFirmwareArtifactGormRepository(testDB)
+	campaignRepo := persistence.NewFirmwareCampaignGormRepository(testDB)
+	deviceRepo := persistence.NewDeviceGormRepository(testDB)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	newArtifact := func(t *testing.T, version string) *entity.FirmwareArtifact {
+		t.Helper()
+
+		artifact, err := entity.NewFirmwareArtifact(version, "release notes", 0, nil)
+		require.NoError(t, err)
+
+		artifact.Size = 1024
+		artifact.SHA256 = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+		artifact.Signature = []byte{0x30, 0x45}
+		artifact.SignatureAlgorithm = "ECDSA-SHA256"
+		artifact.StorageKey = "00112233445566778899aabbccddeeff"
+		require.NoError(t, artifactRepo.Save(ctx, artifact))
+
+		return artifact
+	}
+
+	// newCampaign creates a campaign for new devices, in waves of 2 devices.
+	newCampaign := func(t *testing.T, artifact *entity.FirmwareArtifact, devices int) *entity.FirmwareCampaign {
+		t.Helper()
+
+		deviceIDs := make([]uuid.UUID, 0, devices)
+		for range devices {
+			device, err := entity.NewDevice("hw-"+uuid.NewString(), nil, nil)
+			require.NoError(t, err)
+			require.NoError(t, deviceRepo.Save(ctx, device))
+
+			deviceIDs = append(deviceIDs, device.ID)
+		}
+
+		campaign, err := entity.NewFirmwareCampaign("Roll out", artifact.ID, []string{"location=A"}, 2, 10, time.Hour)
+		require.NoError(t, err)
+
+		updates := campaign.AssignWaves(deviceIDs)
+		require.NoError(t, campaignRepo.Save(ctx, campaign))
+
+		for _, update := range updates {
+			update.CampaignID = campaign.ID
+		}
+
+		require.NoError(t, campaignRepo.CreateUpdates(ctx, updates))
+
+		return campaign
+	}
+
+	t.Run("Artifact - Save, find and delete an artifact with a unique version", func(t *testing.T) {
+		cleanupTable(t)
+
+		artifact := newArtifact(t, "1.0.0")
+		assert.NotEqual(t, uuid.Nil, artifact.ID)
+
+		found, err := artifactRepo.FindByID(ctx, artifact.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "1.0.0", found.Version)
+		assert.Equal(t, artifact.SHA256, found.SHA256)
+		assert.Equal(t, artifact.Signature, found.Signature)
+
+		duplicate, err := entity.NewFirmwareArtifact("1.0.0", "", 0, nil)
+		require.NoError(t, err)
+		require.ErrorIs(t, artifactRepo.Save(ctx, duplicate), entity.ErrFirmwareVersionTaken)
+
+		newer := newArtifact(t, "1.1.0")
+
+		all, err := artifactRepo.FindAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 2)
+		assert.Equal(t, newer.ID, all[0].ID, "newest first")
+
+		require.NoError(t, artifactRepo.Delete(ctx, artifact.ID))
+		require.ErrorIs(t, artifactRepo.Delete(ctx, artifact.ID), entity.ErrFirmwareArtifactNotFound)
+
+		_, err = artifactRepo.FindByID(ctx, artifact.ID)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Artifact - An artifact rolled out by a campaign cannot be deleted", func(t *testing.T) {
+		cleanupTable(t)
+
+		artifact := newArtifact(t, "1.0.0")
+		newCampaign(t, artifact, 1)
+
+		require.ErrorIs(t, artifactRepo.Delete(ctx, artifact.ID), entity.ErrFirmwareArtifactInUse)
+	})
+
+	t.Run("Campaign - Save and update a campaign with optimistic locking", func(t *testing.T) {
+		cleanupTable(t)
+
+		artifact := newArtifact(t, "1.0.0")
+		campaign := newCampaign(t, artifact, 3)
+		assert.Equal(t, int64(1), campaign.Version)
+
+		found, err := campaignRepo.FindByID(ctx, campaign.ID)
+		require.NoError(t, err)
+		assert.Equal(t, entity.JSONBStrings{"location=A"}, found.Filter)
+		assert.Equal(t, 2, found.TotalWaves)
+		assert.Equal(t, campaignstatus.Running, found.Status)
+
+		require.True(t, campaign.StartNextWave())
+		require.NoError(t, campaignRepo.Save(ctx, campaign))
+		assert.Equal(t, int64(2), campaign.Version)
+
+		// The copy read before the change is stale.
+		require.NoError(t, found.Pause("stale"))
+		require.ErrorIs(t, campaignRepo.Save(ctx, found), entity.ErrFirmwareCampaignConflict)
+
+		running, err := campaignRepo.FindByStatus(ctx, campaignstatus.Running)
+		require.NoError(t, err)
+		require.Len(t, running, 1)
+		assert.Equal(t, 1, running[0].CurrentWave)
+
+		unknown, err := entity.NewFirmwareCampaign("Unknown", uuid.New(), nil, 1, 0, time.Hour)
+		require.NoError(t, err)
+		require.ErrorIs(t, campaignRepo.Save(ctx, unknown), entity.ErrFirmwareArtifactNotFound)
+	})
+
+	t.Run("Updates - Find, count and save the updates of a campaign", func(t *testing.T) {
+		cleanupTable(t)
+
+		artifact := newArtifact(t, "1.0.0")
+		campaign := newCampaign(t, artifact, 3)
+
+		firstWave, err := campaignRepo.FindUpdates(ctx, campaign.ID, repository.FirmwareUpdateFilter{
+			Status:  updatestatus.Pending,
+			MaxWave: 1,
+		})
+		require.NoError(t, err)
+		require.Len(t, firstWave, 2)
+
+		update := firstWave[0]
+		require.NoError(t, update.MarkSent(now))
+		require.NoError(t, campaignRepo.SaveUpdate(ctx, update, updatestatus.Pending))
+		require.ErrorIs(t, campaignRepo.SaveUpdate(ctx, update, updatestatus.Pending),
+			entity.ErrFirmwareUpdateStatusConflict, "the update is no longer PENDING")
+
+		require.NoError(t, update.Report(updatestatus.Downloading, 40, "", now))
+		require.NoError(t, campaignRepo.SaveUpdate(ctx, update, updatestatus.Sent))
+
+		found, err := campaignRepo.FindUpdate(ctx, campaign.ID, update.DeviceID)
+		require.NoError(t, err)
+		assert.Equal(t, updatestatus.Downloading, found.Status)
+		assert.Equal(t, 40, found.Progress)
+		require.NotNil(t, found.SentAt)
+		assert.True(t, found.SentAt.Equal(now))
+
+		_, err = campaignRepo.FindUpdate(ctx, campaign.ID, uuid.New())
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		counts, err := campaignRepo.CountUpdates(ctx, campaign.ID)
+		require.NoError(t, err)
+		assert.Equal(t, map[updatestatus.Status]int64{updatestatus.Pending: 2, updatestatus.Downloading: 1}, counts)
+
+		all, err := campaignRepo.FindUpdates(ctx, campaign.ID, repository.FirmwareUpdateFilter{
+			Status:  "",
+			MaxWave: 0,
+		})
+		require.NoError(t, err)
+		require.Len(t, all, 3)
+		assert.Equal(t, 2, all[2].Wave, "by wave")
+
+		unknownDevice := &entity.FirmwareUpdate{ //nolint:exhaustruct
+			CampaignID: campaign.ID,
+			DeviceID:   uuid.New(),
+			Wave:       1,
+			Status:     updatestatus.Pending,
+		}
+		require.ErrorIs(t, campaignRepo.CreateUpdates(ctx, []*entity.FirmwareUpdate{unknownDevice}),
+			entity.ErrDeviceNotFound)
+	})
+
+	t.Run("FailOverdue - Fail the updates in progress that were sent too long ago", func(t *testing.T) {
+		cleanupTable(t)
+
+		artifact := newArtifact(t, "1.0.0")
+		campaign := newCampaign(t, artifact, 3)
+
+		updates, err := campaignRepo.FindUpdates(ctx, campaign.ID, repository.FirmwareUpdateFilter{
+			Status:  "",
+			MaxWave: 0,
+		})
+		require.NoError(t, err)
+
+		// The first update is overdue, the second was sent recently, and the third is still PENDING.
+		require.NoError(t, updates[0].MarkSent(now.Add(-2*time.Hour)))
+		require.NoError(t, campaignRepo.SaveUpdate(ctx, updates[0], updatestatus.Pending))
+		require.NoError(t, updates[1].MarkSent(now))
+		require.NoError(t, campaignRepo.SaveUpdate(ctx, updates[1], updatestatus.Pending))
+
+		failed, err := campaignRepo.FailOverdue(ctx, campaign.ID, now.Add(-time.Hour), now, "timed out")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), failed)
+
+		found, err := campaignRepo.FindUpdate(ctx, campaign.ID, updates[0].DeviceID)
+		require.NoError(t, err)
+		assert.Equal(t, updatestatus.Failed, found.Status)
+		assert.Equal(t, "timed out", found.FailureReason)
+		require.NotNil(t, found.CompletedAt)
+		assert.True(t, found.CompletedAt.Equal(now))
+
+		counts, err := campaignRepo.CountUpdates(ctx, campaign.ID)
+		require.NoError(t, err)
+		assert.Equal(t, map[updatestatus.Status]int64{
+			updatestatus.Pending: 1,
+			updatestatus.Sent:    1,
+			updatestatus.Failed:  1,
+		}, counts)
+	})
+
+	t.Run("Cascade - Deleting a device removes its updates", func(t *testing.T) {
+		cleanupTable(t)
+
+		artifact := newArtifact(t, "1.0.0")
+		campaign := newCampaign(t, artifact, 2)
+
+		updates, err := campaignRepo.FindUpdates(ctx, campaign.ID, repository.FirmwareUpdateFilter{
+			Status:  "",
+			MaxWave: 0,
+		})
+		require.NoError(t, err)
+
+		device, err := deviceRepo.FindByID(ctx, updates[0].DeviceID)
+		require.NoError(t, err)
+		require.NoError(t, deviceRepo.Delete(ctx, device.ID, device.Version))
+
+		counts, err := campaignRepo.CountUpdates(ctx, campaign.ID)
+		require.NoError(t, err)
+		assert.Equal(t, map[updatestatus.Status]int64{updatestatus.Pending: 1}, counts)
+	})
+
+	t.Run("Manifest - Save, replace and find the manifest of an artifact", func(t *testing.T) {
+		cleanupTable(t)
+
+		keyRepo := persistence.NewFirmwareSigningKeyGormRepository(testDB)
+		key := entity.NewFirmwareSigningKey("00112233445566778899aabbccddeeff", "Ed25519", []byte{0x30, 0x2a})
+		require.NoError(t, keyRepo.Save(ctx, key))
+
+		artifact := newArtifact(t, "1.0.0")
+
+		_, err := artifactRepo.FindManifest(ctx, artifact.ID)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		manifest, err := entity.NewFirmwareManifest(artifact, key, now)
+		require.NoError(t, err)
+
+		manifest.Signature = []byte{0x01}
+		require.NoError(t, artifactRepo.SaveManifest(ctx, manifest))
+
+		manifest.Signature = []byte{0x02}
+		require.NoError(t, artifactRepo.SaveManifest(ctx, manifest), "a manifest is replaced")
+
+		found, err := artifactRepo.FindManifest(ctx, artifact.ID)
+		require.NoError(t, err)
+		assert.Equal(t, manifest.Payload, found.Payload)
+		assert.Equal(t, []byte{0x02}, found.Signature)
+		assert.Equal(t, key.ID, found.KeyID)
+
+		unknown := *manifest
+		unknown.FirmwareID = uuid.New()
+		require.ErrorIs(t, artifactRepo.SaveManifest(ctx, &unknown), entity.ErrFirmwareArtifactNotFound)
+
+		require.NoError(t, artifactRepo.Delete(ctx, artifact.ID))
+
+		_, err = artifactRepo.FindManifest(ctx, artifact.ID)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound, "the manifest is deleted with its artifact")
+	})
+
+	t.Run("SigningKey - A single key is active, and retired keys remain verifiable for a while", func(t *testing.T) {
+		cleanupTable(t)
+
+		keyRepo := persistence.NewFirmwareSigningKeyGormRepository(testDB)
+
+		_, err := keyRepo.FindActive(ctx)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		oldKey := entity.NewFirmwareSigningKey("00112233445566778899aabbccddeeff", "Ed25519", []byte{0x30})
+		require.NoError(t, keyRepo.Save(ctx, oldKey))
+
+		newKey := entity.NewFirmwareSigningKey("ffeeddccbbaa99887766554433221100", "ECDSA-P256-SHA256", []byte{0x30})
+		require.ErrorIs(t, keyRepo.Save(ctx, newKey), entity.ErrFirmwareSigningKeyConflict)
+
+		stale := *oldKey
+		require.NoError(t, oldKey.Retire(now, time.Hour))
+		require.NoError(t, keyRepo.Retire(ctx, oldKey))
+		require.NoError(t, stale.Retire(now, time.Hour))
+		require.ErrorIs(t, keyRepo.Retire(ctx, &stale), entity.ErrFirmwareSigningKeyConflict, "already retired")
+
+		require.NoError(t, keyRepo.Save(ctx, newKey))
+
+		active, err := keyRepo.FindActive(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, newKey.ID, active.ID)
+
+		verifiable, err := keyRepo.FindVerifiable(ctx, now)
+		require.NoError(t, err)
+		require.Len(t, verifiable, 2)
+		assert.Equal(t, newKey.ID, verifiable[0].ID, "newest first")
+		require.NotNil(t, verifiable[1].ExpiresAt)
+		assert.True(t, verifiable[1].ExpiresAt.Equal(now.Add(time.Hour)))
+
+		verifiable, err = keyRepo.FindVerifiable(ctx, now.Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, verifiable, 1, "the grace period of the retired key is over")
+	})
+}
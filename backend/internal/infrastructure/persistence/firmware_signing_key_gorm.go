@@ -0,0 +1,95 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+// firmwareSigningKeyLockKey is the key of the advisory lock that serializes the signing of firmware manifests
+// and the rotations of their keys.
+const firmwareSigningKeyLockKey = 0x66776b657973 // "fwkeys"
+
+// FirmwareSigningKeyGormRepository is the GORM implementation of the FirmwareSigningKeyRepository.
+type FirmwareSigningKeyGormRepository struct {
+	db *gorm.DB
+}
+
+// NewFirmwareSigningKeyGormRepository creates a new instance of FirmwareSigningKeyGormRepository.
+//
+//nolint:ireturn
+func NewFirmwareSigningKeyGormRepository(db *gorm.DB) repository.FirmwareSigningKeyRepository {
+	return &FirmwareSigningKeyGormRepository{db: db}
+}
+
+// Save stores a new active firmware signing key.
+// The partial unique index on the active key rejects it while another key is active,
+// so that concurrent rotations cannot both take over.
+func (r *FirmwareSigningKeyGormRepository) Save(ctx context.Context, key *entity.FirmwareSigningKey) error {
+	err := conn(ctx, r.db).Create(key).Error
+	if hasSQLState(err, pgUniqueViolation) {
+		return entity.ErrFirmwareSigningKeyConflict
+	}
+
+	return err
+}
+
+// Retire stores the retirement of a firmware signing key with a conditional update on the key being active.
+func (r *FirmwareSigningKeyGormRepository) Retire(ctx context.Context, key *entity.FirmwareSigningKey) error {
+	result := conn(ctx, r.db).
+		Model(&entity.FirmwareSigningKey{}). //nolint:exhaustruct
+		Where("id = ? AND retired_at IS NULL", key.ID).
+		Updates(map[string]any{
+			"retired_at": key.RetiredAt,
+			"expires_at": key.ExpiresAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return entity.ErrFirmwareSigningKeyConflict
+	}
+
+	return nil
+}
+
+// FindActive finds the active firmware signing key.
+func (r *FirmwareSigningKeyGormRepository) FindActive(ctx context.Context) (*entity.FirmwareSigningKey, error) {
+	var key entity.FirmwareSigningKey
+	// It returns `gorm.ErrRecordNotFound` if no record is found.
+	err := conn(ctx, r.db).First(&key, "retired_at IS NULL").Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// FindVerifiable retrieves the firmware signing keys that are active or within their grace period, newest first.
+func (r *FirmwareSigningKeyGormRepository) FindVerifiable(
+	ctx context.Context,
+	now time.Time,
+) ([]*entity.FirmwareSigningKey, error) {
+	var keys []*entity.FirmwareSigningKey
+	// It returns an empty slice if no keys are found.
+	err := conn(ctx, r.db).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Order("created_at DESC, id").
+		Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Lock takes a transaction-level advisory lock, which is released when the transaction ends.
+// It must be called within a transaction; otherwise the lock is released right away.
+func (r *FirmwareSigningKeyGormRepository) Lock(ctx context.Context) error {
+	return conn(ctx, r.db).Exec("SELECT pg_advisory_xact_lock(?)", firmwareSigningKeyLockKey).Error
+}
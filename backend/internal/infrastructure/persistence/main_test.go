@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"testing"
 
@@ -51,23 +52,25 @@ func setupTestDatabase() error {
 	}
 
 	// Run migrations.
-	migrationURL := "file://../../../../infra/db-auth/migrations"
-
-	// Use dsnTest directly as the second argument for migrate.New.
-	mi, err := migrate.New(migrationURL, dsnTest)
+	err := runMigrations("file://../../../../infra/db-auth/migrations", dsnTest)
 	if err != nil {
-		return fmt.Errorf("failed to create migrate instance: %w", err)
+		return err
 	}
 
-	// Clear the database state before running migrations.
-	err = mi.Down()
-	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		log.Printf("migrate down failed, but continuing test: %v", err)
+	// The telemetry schema is migrated into the same test database.
+	// Its tables do not overlap with the auth schema, but its version is tracked in a separate table.
+	telemetryDSN, err := url.Parse(dsnTest)
+	if err != nil {
+		return fmt.Errorf("failed to parse DSN_TEST as a URL: %w", err)
 	}
 
-	err = mi.Up()
-	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return fmt.Errorf("migrate up failed: %w", err)
+	query := telemetryDSN.Query()
+	query.Set("x-migrations-table", "telemetry_schema_migrations")
+	telemetryDSN.RawQuery = query.Encode()
+
+	err = runMigrations("file://../../../../infra/db-telemetry/migrations", telemetryDSN.String())
+	if err != nil {
+		return err
 	}
 
 	// Connect to the database using GORM.
@@ -84,11 +87,37 @@ func setupTestDatabase() error {
 	return nil
 }
 
+// runMigrations clears the database state of the migrations in migrationURL and applies them again.
+func runMigrations(migrationURL, dsn string) error {
+	mi, err := migrate.New(migrationURL, dsn)
+	if err != nil {
+		return fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+
+	// Clear the database state before running migrations.
+	err = mi.Down()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		log.Printf("migrate down failed, but continuing test: %v", err)
+	}
+
+	err = mi.Up()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("migrate up failed: %w", err)
+	}
+
+	return nil
+}
+
 func cleanupTable(t *testing.T) {
 	t.Helper()
 
-	err := testDB.Exec(fmt.Sprintf("TRUNCATE TABLE %s RESTART IDENTITY CASCADE;", "devices")).Error
+	// audit_logs does not reference devices, so it is not truncated by the cascade.
+	// firmware_campaigns and firmware_manifests are truncated with the firmware_artifacts they refer to.
+	// sensor_data belongs to the telemetry schema, and sensor_metrics is truncated with it.
+	tables := "devices, device_groups, audit_logs, firmware_artifacts, firmware_signing_keys, sensor_data"
+
+	err := testDB.Exec(fmt.Sprintf("TRUNCATE TABLE %s RESTART IDENTITY CASCADE;", tables)).Error
 	if err != nil {
-		t.Fatalf("failed to cleanup table (%s): %v", "devices", err)
+		t.Fatalf("failed to cleanup table (%s): %v", tables, err)
 	}
 }
@@ -0,0 +1,213 @@
+package persistence_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/revocationreason"
+	"backend/internal/domain/entity"
+	"backend/internal/infrastructure/persistence"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// TestProvisioningGormRepositories_Integration performs integration tests for
+// the enrollment token and certificate repositories and the transactor against a real database.
+func TestProvisioningGormRepositories_Integration(t *testing.T) {
+	// testDB is initialized in main_test.go.
+	if testDB == nil {
+		t.Fatal("testDB is not initialized")
+	}
+
+	deviceRepo := persistence.NewDeviceGormRepository(testDB)
+	tokenRepo := persistence.NewEnrollmentTokenGormRepository(testDB)
+	certRepo := persistence.NewCertificateGormRepository(testDB)
+	transactor := persistence.NewGormTransactor(testDB)
+	ctx := context.Background()
+
+	// createToken saves a device and an enrollment token for it.
+	createToken := func(t *testing.T, hardwareID, secret string) *entity.EnrollmentToken {
+		t.Helper()
+
+		device, err := entity.NewDevice(hardwareID, nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, deviceRepo.Save(ctx, device))
+
+		token := &entity.EnrollmentToken{
+			ID:        uuid.Nil,
+			DeviceID:  device.ID,
+			TokenHash: entity.HashEnrollmentToken(secret),
+			ExpiresAt: time.Now().Add(time.Hour),
+			UsedAt:    nil,
+			RevokedAt: nil,
+			CreatedAt: time.Time{},
+		}
+		require.NoError(t, testDB.Create(token).Error)
+
+		return token
+	}
+
+	t.Run("FindByTokenHash - Finds a token by the hash of its secret", func(t *testing.T) {
+		cleanupTable(t)
+
+		token := createToken(t, "hw-token-01", "secret-01")
+
+		found, err := tokenRepo.FindByTokenHash(ctx, entity.HashEnrollmentToken("secret-01"))
+		require.NoError(t, err)
+		assert.Equal(t, token.ID, found.ID)
+		assert.Nil(t, found.UsedAt)
+
+		_, err = tokenRepo.FindByTokenHash(ctx, entity.HashEnrollmentToken("unknown"))
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("MarkUsed - Consumes a token only once", func(t *testing.T) {
+		cleanupTable(t)
+
+		token := createToken(t, "hw-token-02", "secret-02")
+
+		err := tokenRepo.MarkUsed(ctx, token.ID, time.Now())
+		require.NoError(t, err)
+
+		err = tokenRepo.MarkUsed(ctx, token.ID, time.Now())
+		require.ErrorIs(t, err, entity.ErrEnrollmentTokenUsed)
+	})
+
+	t.Run("Revoke - Prevents a revoked token from being used", func(t *testing.T) {
+		cleanupTable(t)
+
+		token := createToken(t, "hw-token-04", "secret-04")
+
+		err := tokenRepo.Revoke(ctx, token.ID, time.Now())
+		require.NoError(t, err)
+
+		err = tokenRepo.MarkUsed(ctx, token.ID, time.Now())
+		require.ErrorIs(t, err, entity.ErrEnrollmentTokenUsed)
+
+		tokens, err := tokenRepo.FindByDeviceID(ctx, token.DeviceID)
+		require.NoError(t, err)
+		require.Len(t, tokens, 1)
+		assert.NotNil(t, tokens[0].RevokedAt)
+		assert.Nil(t, tokens[0].UsedAt)
+	})
+
+	t.Run("FindByDeviceID - Lists the certificates of a device, newest first", func(t *testing.T) {
+		cleanupTable(t)
+
+		token := createToken(t, "hw-cert-01", "secret-cert-01")
+		now := time.Now()
+
+		older := entity.NewCertificate(token.DeviceID, 2001, "fp-old", "pem-old", now.Add(-time.Hour), now)
+		newer := entity.NewCertificate(token.DeviceID, 2002, "fp-new", "pem-new", now, now.Add(time.Hour))
+		require.NoError(t, certRepo.Save(ctx, older))
+		require.NoError(t, certRepo.Save(ctx, newer))
+
+		certificates, err := certRepo.FindByDeviceID(ctx, token.DeviceID)
+		require.NoError(t, err)
+		require.Len(t, certificates, 2)
+		assert.Equal(t, int64(2002), certificates[0].SerialNumber)
+		assert.Equal(t, int64(2001), certificates[1].SerialNumber)
+
+		found, err := certRepo.FindBySerialNumber(ctx, 2001)
+		require.NoError(t, err)
+		assert.Equal(t, "fp-old", found.Fingerprint)
+		assert.Equal(t, "pem-old", found.PEMRaw)
+
+		_, err = certRepo.FindBySerialNumber(ctx, 9999)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Revoke - Revokes a certificate only once", func(t *testing.T) {
+		cleanupTable(t)
+
+		token := createToken(t, "hw-cert-02", "secret-cert-02")
+		now := time.Now()
+
+		active := entity.NewCertificate(token.DeviceID, 3001, "fp-1", "pem-1", now.Add(-time.Hour), now.Add(time.Hour))
+		expired := entity.NewCertificate(token.DeviceID, 3002, "fp-2", "pem-2", now.Add(-2*time.Hour), now.Add(-time.Hour))
+		require.NoError(t, certRepo.Save(ctx, active))
+		require.NoError(t, certRepo.Save(ctx, expired))
+
+		for _, certificate := range []*entity.Certificate{active, expired} {
+			require.NoError(t, certificate.Revoke(revocationreason.KeyCompromise, now))
+			require.NoError(t, certRepo.Revoke(ctx, certificate))
+		}
+
+		err := certRepo.Revoke(ctx, active)
+		require.ErrorIs(t, err, entity.ErrCertificateAlreadyRevoked)
+
+		found, err := certRepo.FindBySerialNumber(ctx, 3001)
+		require.NoError(t, err)
+		assert.True(t, found.IsRevoked)
+		require.NotNil(t, found.RevokedAt)
+		require.NotNil(t, found.RevocationReason)
+		assert.Equal(t, revocationreason.KeyCompromise, *found.RevocationReason)
+
+		// Expired certificates are left out of the CRL.
+		revoked, err := certRepo.FindRevoked(ctx, now)
+		require.NoError(t, err)
+		require.Len(t, revoked, 1)
+		assert.Equal(t, int64(3001), revoked[0].SerialNumber)
+	})
+
+	t.Run("WithinTransaction - Rolls back all writes on error", func(t *testing.T) {
+		cleanupTable(t)
+
+		token := createToken(t, "hw-token-03", "secret-03")
+		errAbort := errors.New("abort")
+
+		err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			err := tokenRepo.MarkUsed(ctx, token.ID, time.Now())
+			require.NoError(t, err)
+
+			err = certRepo.Save(ctx, entity.NewCertificate(token.DeviceID, 1001, "fp", "pem", time.Now(), time.Now()))
+			require.NoError(t, err)
+
+			return errAbort
+		})
+		require.ErrorIs(t, err, errAbort)
+
+		// The token is still unused and no certificate has been recorded.
+		found, err := tokenRepo.FindByTokenHash(ctx, entity.HashEnrollmentToken("secret-03"))
+		require.NoError(t, err)
+		assert.Nil(t, found.UsedAt)
+
+		var count int64
+		require.NoError(t, testDB.Model(&entity.Certificate{}).Count(&count).Error) //nolint:exhaustruct
+		assert.Zero(t, count)
+	})
+	t.Run("WithinSnapshot - Reads the data as of its first query and cannot write", func(t *testing.T) {
+		cleanupTable(t)
+
+		first, err := entity.NewDevice("hw-snapshot-01", nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, deviceRepo.Save(ctx, first))
+
+		err = transactor.WithinSnapshot(ctx, func(txCtx context.Context) error {
+			_, err := deviceRepo.FindByHardwareID(txCtx, "hw-snapshot-01")
+			require.NoError(t, err)
+
+			// A device created by another connection after the first query is not seen.
+			second, err := entity.NewDevice("hw-snapshot-02", nil, nil)
+			require.NoError(t, err)
+			require.NoError(t, deviceRepo.Save(ctx, second))
+
+			_, err = deviceRepo.FindByHardwareID(txCtx, "hw-snapshot-02")
+			require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+			third, err := entity.NewDevice("hw-snapshot-03", nil, nil)
+			require.NoError(t, err)
+
+			return deviceRepo.Save(txCtx, third)
+		})
+		require.Error(t, err, "a read-only transaction should reject writes")
+
+		_, err = deviceRepo.FindByHardwareID(ctx, "hw-snapshot-02")
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,109 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+// telemetryBatchSize is the number of rows inserted per statement.
+const telemetryBatchSize = 500
+
+// TelemetryGormRepository is the GORM implementation of the TelemetryRepository.
+//
+// It works on the telemetry database, so it never joins a transaction of the auth database carried by ctx.
+type TelemetryGormRepository struct {
+	db *gorm.DB
+}
+
+// NewTelemetryGormRepository creates a new instance of TelemetryGormRepository.
+//
+//nolint:ireturn
+func NewTelemetryGormRepository(db *gorm.DB) repository.TelemetryRepository {
+	return &TelemetryGormRepository{db: db}
+}
+
+// SaveBatch stores readings together with their metrics in one transaction.
+func (r *TelemetryGormRepository) SaveBatch(ctx context.Context, data []*entity.SensorData) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	metrics := make([]*entity.SensorMetric, 0, len(data))
+	for _, reading := range data {
+		metrics = append(metrics, reading.MetricRows()...)
+	}
+
+	// Redelivered readings conflict with the stored ones and are skipped.
+	skipDuplicates := clause.OnConflict{DoNothing: true} //nolint:exhaustruct
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(skipDuplicates).CreateInBatches(data, telemetryBatchSize).Error
+		if err != nil {
+			return err
+		}
+
+		if len(metrics) == 0 {
+			return nil
+		}
+
+		return tx.Clauses(skipDuplicates).CreateInBatches(metrics, telemetryBatchSize).Error
+	})
+}
+
+// FindByDeviceID retrieves up to limit readings of a device recorded in [from, to), newest first.
+func (r *TelemetryGormRepository) FindByDeviceID(
+	ctx context.Context,
+	deviceID uuid.UUID,
+	from, to time.Time,
+	limit int,
+) ([]*entity.SensorData, error) {
+	db := r.db.WithContext(ctx)
+
+	var data []*entity.SensorData
+	// It returns an empty slice if no readings are found.
+	err := db.
+		Where("device_id = ? AND recorded_at >= ? AND recorded_at < ?", deviceID, from, to).
+		Order("recorded_at DESC").
+		Limit(limit).
+		Find(&data).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	// The metrics of the returned readings lie between the oldest and the newest of them.
+	var metrics []*entity.SensorMetric
+
+	err = db.
+		Where("device_id = ? AND recorded_at >= ? AND recorded_at <= ?",
+			deviceID, data[len(data)-1].RecordedAt, data[0].RecordedAt).
+		Find(&metrics).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byTime := make(map[time.Time]*entity.SensorData, len(data))
+	for _, reading := range data {
+		reading.Metrics = make(map[string]float64)
+		byTime[reading.RecordedAt.UTC()] = reading
+	}
+
+	for _, metric := range metrics {
+		reading, ok := byTime[metric.RecordedAt.UTC()]
+		if ok {
+			reading.Metrics[metric.Name] = metric.Value
+		}
+	}
+
+	return data, nil
+}
@@ -0,0 +1,103 @@
+package persistence_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend/internal/domain/entity"
+	"backend/internal/infrastructure/persistence"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTelemetryGormRepository_Integration performs integration tests for
+// the GORM repository of sensor data against a real database.
+func TestTelemetryGormRepository_Integration(t *testing.T) {
+	// testDB is initialized in main_test.go.
+	if testDB == nil {
+		t.Fatal("testDB is not initialized")
+	}
+
+	repo := persistence.NewTelemetryGormRepository(testDB)
+	ctx := context.Background()
+	now := time.Now()
+
+	newReading := func(t *testing.T, deviceID uuid.UUID, age time.Duration, payload map[string]any) *entity.SensorData {
+		t.Helper()
+
+		reading, err := entity.NewSensorData(deviceID, now.Add(-age), now, payload)
+		require.NoError(t, err)
+
+		return reading
+	}
+
+	t.Run("SaveBatch - Stores readings with their metrics", func(t *testing.T) {
+		cleanupTable(t)
+
+		deviceID := uuid.New()
+		err := repo.SaveBatch(ctx, []*entity.SensorData{
+			newReading(t, deviceID, time.Minute, map[string]any{"temperature": 21.5, "status": "ok"}),
+			newReading(t, deviceID, 2*time.Minute, map[string]any{"temperature": 20.0, "humidity": 40.0}),
+			newReading(t, uuid.New(), time.Minute, map[string]any{"temperature": 30.0}), // another device
+		})
+		require.NoError(t, err)
+
+		found, err := repo.FindByDeviceID(ctx, deviceID, now.Add(-time.Hour), now, 10)
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+
+		// Newest first, with the payload and the typed metrics.
+		assert.True(t, found[0].RecordedAt.After(found[1].RecordedAt))
+		assert.Equal(t, "ok", found[0].Payload["status"])
+		assert.Equal(t, map[string]float64{"temperature": 21.5}, found[0].Metrics)
+		assert.Equal(t, map[string]float64{"temperature": 20.0, "humidity": 40.0}, found[1].Metrics)
+
+		var metricCount int64
+
+		require.NoError(t, testDB.Model(&entity.SensorMetric{}).Count(&metricCount).Error) //nolint:exhaustruct
+		assert.Equal(t, int64(4), metricCount)
+	})
+
+	t.Run("SaveBatch - Skips redelivered readings", func(t *testing.T) {
+		cleanupTable(t)
+
+		deviceID := uuid.New()
+		reading := newReading(t, deviceID, time.Minute, map[string]any{"temperature": 21.5})
+		require.NoError(t, repo.SaveBatch(ctx, []*entity.SensorData{reading}))
+
+		redelivered := newReading(t, deviceID, time.Minute, map[string]any{"temperature": 99.0})
+		newer := newReading(t, deviceID, 0, map[string]any{"temperature": 22.0})
+		require.NoError(t, repo.SaveBatch(ctx, []*entity.SensorData{redelivered, newer}))
+
+		found, err := repo.FindByDeviceID(ctx, deviceID, now.Add(-time.Hour), now.Add(time.Second), 10)
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+		assert.InDelta(t, 21.5, found[1].Metrics["temperature"], 0, "the first delivery is kept")
+	})
+
+	t.Run("FindByDeviceID - Applies the range and the limit", func(t *testing.T) {
+		cleanupTable(t)
+
+		deviceID := uuid.New()
+		batch := make([]*entity.SensorData, 0)
+
+		for i := range 5 {
+			batch = append(batch, newReading(t, deviceID, time.Duration(i+1)*time.Hour, map[string]any{"index": i}))
+		}
+
+		require.NoError(t, repo.SaveBatch(ctx, batch))
+
+		found, err := repo.FindByDeviceID(ctx, deviceID, now.Add(-4*time.Hour), now.Add(-90*time.Minute), 2)
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+		assert.InDelta(t, 1.0, found[0].Metrics["index"], 0)
+		assert.InDelta(t, 2.0, found[1].Metrics["index"], 0)
+
+		found, err = repo.FindByDeviceID(ctx, uuid.New(), now.Add(-24*time.Hour), now, 10)
+		require.NoError(t, err)
+		assert.Empty(t, found)
+	})
+}
@@ -0,0 +1,52 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/gorm"
+
+	"backend/internal/domain/repository"
+)
+
+// txContextKey is the context key under which the current transaction is stored.
+type txContextKey struct{}
+
+// GormTransactor is the GORM implementation of the Transactor.
+type GormTransactor struct {
+	db *gorm.DB
+}
+
+// NewGormTransactor creates a new instance of GormTransactor.
+//
+//nolint:ireturn
+func NewGormTransactor(db *gorm.DB) repository.Transactor {
+	return &GormTransactor{db: db}
+}
+
+// WithinTransaction runs fn within a transaction.
+// If ctx already carries a transaction, fn joins it through a savepoint.
+func (t *GormTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return conn(ctx, t.db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txContextKey{}, tx))
+	})
+}
+
+// WithinSnapshot runs fn within a REPEATABLE READ, READ ONLY transaction,
+// so that all its queries see the snapshot taken by the first one.
+// If ctx already carries a transaction, fn joins it through a savepoint and sees what it sees.
+func (t *GormTransactor) WithinSnapshot(ctx context.Context, fn func(ctx context.Context) error) error {
+	return conn(ctx, t.db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txContextKey{}, tx))
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+}
+
+// conn returns the transaction carried by ctx, or db bound to ctx if there is none.
+// Every repository method must use it, so that it participates in the current transaction.
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txContextKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,379 @@
+// Package pki provides the private certificate authority of the platform.
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"math/big"
+	"net/url"
+	"os"
+	"time"
+
+	"backend/internal/domain/service"
+)
+
+const (
+	// DefaultCertPath is the default path of the issuing CA certificate in the `/app/certs` volume.
+	DefaultCertPath = "/app/certs/ca.crt"
+	// DefaultKeyPath is the default path of the issuing CA private key in the `/app/certs` volume.
+	DefaultKeyPath = "/app/certs/ca.key"
+	// DefaultValidity is the default validity period of issued client certificates.
+	DefaultValidity = 365 * 24 * time.Hour
+	// minRSAKeyBits is the minimum accepted RSA key size of a CSR.
+	minRSAKeyBits = 2048
+	// clockSkewAllowance backdates NotBefore so that devices with a slightly late clock accept the certificate.
+	clockSkewAllowance = 5 * time.Minute
+)
+
+// CA is a certificate authority that signs device CSRs with an issuing CA key.
+// It implements service.CertificateSigner.
+type CA struct {
+	// cert is the issuing CA certificate.
+	cert *x509.Certificate
+	// chainPEM is the PEM-encoded chain, starting with the issuing CA certificate.
+	chainPEM string
+	signer   crypto.Signer
+	validity time.Duration
+	// ocspServers are the OCSP responder URLs added to the AIA extension of issued certificates.
+	ocspServers []string
+	now         func() time.Time
+}
+
+// LoadCA loads the issuing CA certificate (optionally followed by its chain) and private key from PEM files.
+func LoadCA(certPath, keyPath string, validity time.Duration) (*CA, error) {
+	certPEM, err := os.ReadFile(certPath) //nolint:gosec // The path is given by the operator.
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+
+	keyPEM, err := os.ReadFile(keyPath) //nolint:gosec // The path is given by the operator.
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA private key: %w", err)
+	}
+
+	return NewCA(certPEM, keyPEM, validity)
+}
+
+// NewCA creates a CA from a PEM-encoded certificate chain and private key.
+// The first certificate of the chain must be the issuing CA certificate matching the private key.
+func NewCA(certPEM, keyPEM []byte, validity time.Duration) (*CA, error) {
+	chain, err := parseCertificates(certPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := chain[0]
+	if !issuer.IsCA {
+		return nil, ErrNotCACertificate
+	}
+
+	signer, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	if !publicKeyEqual(issuer.PublicKey, signer.Public()) {
+		return nil, ErrKeyMismatch
+	}
+
+	if validity <= 0 {
+		validity = DefaultValidity
+	}
+
+	var chainPEM bytes.Buffer
+	for _, cert := range chain {
+		_ = pem.Encode(&chainPEM, &pem.Block{Type: "CERTIFICATE", Headers: nil, Bytes: cert.Raw})
+	}
+
+	return &CA{
+		cert:        issuer,
+		chainPEM:    chainPEM.String(),
+		signer:      signer,
+		validity:    validity,
+		ocspServers: nil,
+		now:         time.Now,
+	}, nil
+}
+
+// Certificate returns the issuing CA certificate.
+func (ca *CA) Certificate() *x509.Certificate {
+	return ca.cert
+}
+
+// SetOCSPServers sets the OCSP responder URLs added to the AIA extension of issued certificates.
+// It must be called before the CA is used.
+func (ca *CA) SetOCSPServers(servers []string) {
+	ca.ocspServers = servers
+}
+
+// CAChainPEM returns the PEM-encoded CA certificate chain, starting with the issuing CA.
+func (ca *CA) CAChainPEM() string {
+	return ca.chainPEM
+}
+
+// SignCSR validates the CSR and issues a client certificate bound to the device.
+//
+// The CSR must be correctly self-signed, use an acceptable key (RSA >= 2048 bits, ECDSA P-256/P-384/P-521
+// or Ed25519), and have the device's hardware ID as its subject common name.
+// The issued certificate has the device ID as its subject common name and as a `urn:uuid:` SAN,
+// and the hardware ID as its subject serial number attribute.
+func (ca *CA) SignCSR(_ context.Context, req service.CertificateSignRequest) (*service.IssuedCertificate, error) {
+	csr, err := parseCSR(req.CSR)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateCSR(csr, req.HardwareID)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	deviceURI, err := deviceIDURI(req.DeviceID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	now := ca.now()
+	notBefore := now.Add(-clockSkewAllowance)
+
+	notAfter := now.Add(ca.validity)
+	if notAfter.After(ca.cert.NotAfter) {
+		// A certificate must not outlive its issuer.
+		notAfter = ca.cert.NotAfter
+	}
+
+	template := &x509.Certificate{ //nolint:exhaustruct
+		SerialNumber: big.NewInt(serial),
+		Subject: pkix.Name{ //nolint:exhaustruct
+			CommonName:   req.DeviceID.String(),
+			SerialNumber: req.HardwareID,
+		},
+		URIs:                  deviceURI,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              keyUsageFor(csr.PublicKey),
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  false,
+		OCSPServer:            ca.ocspServers,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, csr.PublicKey, ca.signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign certificate: %w", err)
+	}
+
+	return &service.IssuedCertificate{
+		SerialNumber:   serial,
+		Fingerprint:    Fingerprint(der),
+		CertificatePEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Headers: nil, Bytes: der})),
+		NotBefore:      notBefore,
+		NotAfter:       notAfter,
+	}, nil
+}
+
+// SignRevocationList signs a certificate revocation list with the issuing CA key and returns it DER-encoded.
+// The issuing CA certificate must allow the cRLSign key usage.
+func (ca *CA) SignRevocationList(_ context.Context, list service.RevocationList) ([]byte, error) {
+	entries := make([]x509.RevocationListEntry, 0, len(list.Entries))
+	for _, entry := range list.Entries {
+		entries = append(entries, x509.RevocationListEntry{ //nolint:exhaustruct
+			SerialNumber:   big.NewInt(entry.SerialNumber),
+			RevocationTime: entry.RevokedAt,
+			ReasonCode:     entry.ReasonCode,
+		})
+	}
+
+	template := &x509.RevocationList{ //nolint:exhaustruct
+		RevokedCertificateEntries: entries,
+		Number:                    big.NewInt(list.Number),
+		ThisUpdate:                list.ThisUpdate,
+		NextUpdate:                list.NextUpdate,
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, template, ca.cert, ca.signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign revocation list: %w", err)
+	}
+
+	return der, nil
+}
+
+// Fingerprint returns the hex-encoded SHA-256 hash of a DER-encoded certificate.
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func parseCSR(csrPEM []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || (block.Type != "CERTIFICATE REQUEST" && block.Type != "NEW CERTIFICATE REQUEST") {
+		return nil, fmt.Errorf("%w: no PEM-encoded certificate request found", service.ErrInvalidCSR)
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", service.ErrInvalidCSR, err)
+	}
+
+	return csr, nil
+}
+
+func validateCSR(csr *x509.CertificateRequest, hardwareID string) error {
+	err := csr.CheckSignature()
+	if err != nil {
+		return fmt.Errorf("%w: bad signature: %w", service.ErrInvalidCSR, err)
+	}
+
+	err = validatePublicKey(csr.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	if csr.Subject.CommonName != hardwareID {
+		return fmt.Errorf("%w: subject common name %q does not match the hardware ID",
+			service.ErrInvalidCSR, csr.Subject.CommonName)
+	}
+
+	return nil
+}
+
+func validatePublicKey(pub any) error {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minRSAKeyBits {
+			return fmt.Errorf("%w: RSA key must be at least %d bits, got %d",
+				service.ErrInvalidCSR, minRSAKeyBits, key.N.BitLen())
+		}
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256(), elliptic.P384(), elliptic.P521():
+		default:
+			return fmt.Errorf("%w: unsupported ECDSA curve %s", service.ErrInvalidCSR, key.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+	default:
+		return fmt.Errorf("%w: unsupported public key algorithm %T", service.ErrInvalidCSR, pub)
+	}
+
+	return nil
+}
+
+func keyUsageFor(pub any) x509.KeyUsage {
+	usage := x509.KeyUsageDigitalSignature
+	if _, ok := pub.(*rsa.PublicKey); ok {
+		usage |= x509.KeyUsageKeyEncipherment
+	}
+
+	return usage
+}
+
+// newSerialNumber returns a random, positive serial number that fits in a signed 64-bit integer.
+func newSerialNumber() (int64, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	return n.Int64() + 1, nil
+}
+
+func parseCertificates(certPEM []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+
+	rest := certPEM
+	for {
+		var block *pem.Block
+
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+		}
+
+		chain = append(chain, cert)
+	}
+
+	if len(chain) == 0 {
+		return nil, ErrNoCertificate
+	}
+
+	return chain, nil
+}
+
+func parsePrivateKey(keyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, ErrNoPrivateKey
+	}
+
+	var (
+		key any
+		err error
+	)
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedPrivateKey, block.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedPrivateKey, key)
+	}
+
+	return signer, nil
+}
+
+// deviceIDURI returns the `urn:uuid:` SAN URI that binds a certificate to a device ID.
+func deviceIDURI(deviceID string) ([]*url.URL, error) {
+	uri, err := url.Parse("urn:uuid:" + deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build device URI: %w", err)
+	}
+
+	return []*url.URL{uri}, nil
+}
+
+func publicKeyEqual(a, b crypto.PublicKey) bool {
+	key, ok := a.(interface{ Equal(x crypto.PublicKey) bool })
+
+	return ok && key.Equal(b)
+}
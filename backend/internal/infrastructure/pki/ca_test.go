@@ -0,0 +1,253 @@
+package pki_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"backend/internal/domain/service"
+	"backend/internal/infrastructure/pki"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCA generates a self-signed root CA and returns its PEM-encoded certificate and PKCS#8 private key.
+func newTestCA(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{ //nolint:exhaustruct
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"}, //nolint:exhaustruct
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Headers: nil, Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Headers: nil, Bytes: keyDER})
+}
+
+// newTestCSR generates a PEM-encoded CSR with the given common name.
+func newTestCSR(t *testing.T, key crypto.Signer, commonName string) []byte {
+	t.Helper()
+
+	template := &x509.CertificateRequest{ //nolint:exhaustruct
+		Subject: pkix.Name{CommonName: commonName}, //nolint:exhaustruct
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Headers: nil, Bytes: der})
+}
+
+func TestLoadCA(t *testing.T) {
+	t.Parallel()
+
+	certPEM, keyPEM := newTestCA(t)
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "ca.crt")
+	keyPath := filepath.Join(dir, "ca.key")
+	require.NoError(t, os.WriteFile(certPath, certPEM, 0o600))
+	require.NoError(t, os.WriteFile(keyPath, keyPEM, 0o600))
+
+	t.Run("success: load from files", func(t *testing.T) {
+		t.Parallel()
+
+		ca, err := pki.LoadCA(certPath, keyPath, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "Test Root CA", ca.Certificate().Subject.CommonName)
+		assert.Equal(t, string(certPEM), ca.CAChainPEM())
+	})
+
+	t.Run("failure: missing file", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := pki.LoadCA(filepath.Join(dir, "missing.crt"), keyPath, 0)
+		require.Error(t, err)
+	})
+
+	t.Run("failure: key does not match certificate", func(t *testing.T) {
+		t.Parallel()
+
+		_, otherKeyPEM := newTestCA(t)
+		_, err := pki.NewCA(certPEM, otherKeyPEM, 0)
+		require.ErrorIs(t, err, pki.ErrKeyMismatch)
+	})
+}
+
+func TestSignCSR(t *testing.T) {
+	t.Parallel()
+
+	certPEM, keyPEM := newTestCA(t)
+	ca, err := pki.NewCA(certPEM, keyPEM, 24*time.Hour)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	weakRSAKey, err := rsa.GenerateKey(rand.Reader, 1024) //nolint:gosec // Intentionally weak for the test.
+	require.NoError(t, err)
+
+	tamperedCSR := newTestCSR(t, ecKey, "hw-sign-001")
+	block, _ := pem.Decode(tamperedCSR)
+	block.Bytes[len(block.Bytes)-1] ^= 0xff
+	tamperedCSR = pem.EncodeToMemory(block)
+
+	tests := []struct {
+		name       string
+		desc       string
+		csr        []byte
+		hardwareID string
+		wantErr    bool
+	}{
+		{
+			name:       "success: ECDSA P-256 key",
+			desc:       "Verify that a CSR with an ECDSA P-256 key is signed.",
+			csr:        newTestCSR(t, ecKey, "hw-sign-001"),
+			hardwareID: "hw-sign-001",
+			wantErr:    false,
+		},
+		{
+			name:       "success: RSA 2048 key",
+			desc:       "Verify that a CSR with an RSA 2048-bit key is signed.",
+			csr:        newTestCSR(t, rsaKey, "hw-sign-002"),
+			hardwareID: "hw-sign-002",
+			wantErr:    false,
+		},
+		{
+			name:       "failure: RSA key too small",
+			desc:       "Verify that a CSR with an RSA key smaller than 2048 bits is rejected.",
+			csr:        newTestCSR(t, weakRSAKey, "hw-sign-003"),
+			hardwareID: "hw-sign-003",
+			wantErr:    true,
+		},
+		{
+			name:       "failure: subject does not match hardware ID",
+			desc:       "Verify that a CSR whose common name is not the hardware ID is rejected.",
+			csr:        newTestCSR(t, ecKey, "someone-else"),
+			hardwareID: "hw-sign-004",
+			wantErr:    true,
+		},
+		{
+			name:       "failure: bad signature",
+			desc:       "Verify that a CSR with a tampered signature is rejected.",
+			csr:        tamperedCSR,
+			hardwareID: "hw-sign-001",
+			wantErr:    true,
+		},
+		{
+			name:       "failure: not PEM",
+			desc:       "Verify that a non-PEM input is rejected.",
+			csr:        []byte("not a csr"),
+			hardwareID: "hw-sign-005",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			deviceID := uuid.New()
+
+			got, err := ca.SignCSR(context.Background(), service.CertificateSignRequest{
+				CSR:        tt.csr,
+				DeviceID:   deviceID,
+				HardwareID: tt.hardwareID,
+			})
+
+			if tt.wantErr {
+				require.ErrorIs(t, err, service.ErrInvalidCSR)
+				require.Nil(t, got)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Positive(t, got.SerialNumber)
+
+			block, _ := pem.Decode([]byte(got.CertificatePEM))
+			require.NotNil(t, block)
+			cert, err := x509.ParseCertificate(block.Bytes)
+			require.NoError(t, err)
+
+			// The certificate binds the device ID and the hardware ID.
+			assert.Equal(t, deviceID.String(), cert.Subject.CommonName)
+			assert.Equal(t, tt.hardwareID, cert.Subject.SerialNumber)
+			require.Len(t, cert.URIs, 1)
+			assert.Equal(t, "urn:uuid:"+deviceID.String(), cert.URIs[0].String())
+			assert.Equal(t, got.SerialNumber, cert.SerialNumber.Int64())
+			assert.Equal(t, pki.Fingerprint(cert.Raw), got.Fingerprint)
+			assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, cert.ExtKeyUsage)
+			assert.False(t, cert.IsCA)
+
+			// The certificate chains to the CA.
+			roots := x509.NewCertPool()
+			roots.AddCert(ca.Certificate())
+			_, err = cert.Verify(x509.VerifyOptions{ //nolint:exhaustruct
+				Roots:     roots,
+				KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			})
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSignRevocationList(t *testing.T) {
+	t.Parallel()
+
+	certPEM, keyPEM := newTestCA(t)
+	ca, err := pki.NewCA(certPEM, keyPEM, 0)
+	require.NoError(t, err)
+
+	now := time.Now().Truncate(time.Second)
+	revokedAt := now.Add(-time.Hour)
+
+	der, err := ca.SignRevocationList(context.Background(), service.RevocationList{
+		Number:     42,
+		ThisUpdate: now,
+		NextUpdate: now.Add(24 * time.Hour),
+		Entries: []service.RevokedCertificate{
+			{SerialNumber: 1001, RevokedAt: revokedAt, ReasonCode: 1},
+		},
+	})
+	require.NoError(t, err)
+
+	crl, err := x509.ParseRevocationList(der)
+	require.NoError(t, err)
+	require.NoError(t, crl.CheckSignatureFrom(ca.Certificate()))
+
+	assert.Equal(t, int64(42), crl.Number.Int64())
+	assert.True(t, now.Equal(crl.ThisUpdate))
+	assert.True(t, now.Add(24*time.Hour).Equal(crl.NextUpdate))
+	require.Len(t, crl.RevokedCertificateEntries, 1)
+	assert.Equal(t, int64(1001), crl.RevokedCertificateEntries[0].SerialNumber.Int64())
+	assert.True(t, revokedAt.Equal(crl.RevokedCertificateEntries[0].RevocationTime))
+	assert.Equal(t, 1, crl.RevokedCertificateEntries[0].ReasonCode)
+}
@@ -0,0 +1,58 @@
+package pki
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// crlFileMode is the permission of the CRL file. A CRL is public information.
+const crlFileMode = 0o644
+
+// CRLFile writes certificate revocation lists to a PEM file, e.g. for the `crlfile` option of Mosquitto.
+// It implements service.RevocationListPublisher.
+type CRLFile struct {
+	path string
+}
+
+// NewCRLFile creates a CRLFile that writes to the given path.
+func NewCRLFile(path string) *CRLFile {
+	return &CRLFile{path: path}
+}
+
+// PublishRevocationList writes the PEM-encoded revocation list to the file.
+// The file is replaced atomically, so a reader never sees a partially written CRL.
+func (f *CRLFile) PublishRevocationList(_ context.Context, der []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), ".crl-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create CRL file: %w", err)
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }() // No-op once the file has been renamed.
+
+	err = pem.Encode(tmp, &pem.Block{Type: "X509 CRL", Headers: nil, Bytes: der})
+	if err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("failed to write CRL file: %w", err)
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write CRL file: %w", err)
+	}
+
+	err = os.Chmod(tmp.Name(), crlFileMode)
+	if err != nil {
+		return fmt.Errorf("failed to write CRL file: %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), f.path)
+	if err != nil {
+		return fmt.Errorf("failed to replace CRL file: %w", err)
+	}
+
+	return nil
+}
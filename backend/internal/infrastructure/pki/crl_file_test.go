@@ -0,0 +1,50 @@
+package pki_test
+
+import (
+	"context"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"backend/internal/infrastructure/pki"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCRLFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ca.crl")
+	file := pki.NewCRLFile(path)
+
+	t.Run("success: the file is replaced with the PEM-encoded CRL", func(t *testing.T) {
+		t.Parallel()
+
+		require.NoError(t, file.PublishRevocationList(context.Background(), []byte("first")))
+		require.NoError(t, file.PublishRevocationList(context.Background(), []byte("second")))
+
+		data, err := os.ReadFile(path) //nolint:gosec // The path is created by the test.
+		require.NoError(t, err)
+
+		block, rest := pem.Decode(data)
+		require.NotNil(t, block)
+		assert.Equal(t, "X509 CRL", block.Type)
+		assert.Equal(t, []byte("second"), block.Bytes)
+		assert.Empty(t, rest)
+
+		// No temporary files are left behind.
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("failure: the directory does not exist", func(t *testing.T) {
+		t.Parallel()
+
+		missing := pki.NewCRLFile(filepath.Join(dir, "missing", "ca.crl"))
+		require.Error(t, missing.PublishRevocationList(context.Background(), []byte("crl")))
+	})
+}
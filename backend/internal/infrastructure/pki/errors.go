@@ -0,0 +1,18 @@
+package pki
+
+import "errors"
+
+var (
+	// ErrNoCertificate is returned when no PEM-encoded certificate is found.
+	ErrNoCertificate = errors.New("no CA certificate found")
+	// ErrNoPrivateKey is returned when no PEM-encoded private key is found.
+	ErrNoPrivateKey = errors.New("no CA private key found")
+	// ErrUnsupportedPrivateKey is returned when the private key type cannot be used for signing.
+	ErrUnsupportedPrivateKey = errors.New("unsupported CA private key")
+	// ErrNotCACertificate is returned when the issuing certificate is not a CA certificate.
+	ErrNotCACertificate = errors.New("certificate is not a CA certificate")
+	// ErrKeyMismatch is returned when the private key does not match the CA certificate.
+	ErrKeyMismatch = errors.New("CA private key does not match the certificate")
+	// ErrInvalidFirmwareDigest is returned when the firmware hash to sign is not a SHA-256 hash.
+	ErrInvalidFirmwareDigest = errors.New("firmware digest is not a SHA-256 hash")
+)
@@ -0,0 +1,56 @@
+package pki
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+
+	"backend/internal/domain/service"
+)
+
+// Signature algorithms of firmware images, after the type of the issuing CA key.
+const (
+	FirmwareSignatureECDSA   = "ECDSA-SHA256"
+	FirmwareSignatureRSA     = "RSA-PKCS1v15-SHA256"
+	FirmwareSignatureEd25519 = "Ed25519"
+)
+
+// SignFirmware signs the SHA-256 hash of a firmware image with the issuing CA key,
+// so that devices can verify the image with the CA chain they received when they were provisioned.
+// ECDSA signatures are ASN.1-encoded, and Ed25519 signs the hash itself rather than the image.
+// It implements service.FirmwareSigner.
+func (ca *CA) SignFirmware(_ context.Context, digest []byte) (*service.FirmwareSignature, error) {
+	if len(digest) != sha256.Size {
+		return nil, fmt.Errorf("%w: got %d bytes", ErrInvalidFirmwareDigest, len(digest))
+	}
+
+	var (
+		algorithm string
+		opts      crypto.SignerOpts = crypto.SHA256
+	)
+
+	switch ca.signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		algorithm = FirmwareSignatureECDSA
+	case *rsa.PublicKey:
+		algorithm = FirmwareSignatureRSA
+	case ed25519.PublicKey:
+		algorithm = FirmwareSignatureEd25519
+		// Ed25519 cannot sign a pre-computed hash, so the hash is signed as the message.
+		opts = crypto.Hash(0)
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedPrivateKey, ca.signer.Public())
+	}
+
+	signature, err := ca.signer.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign firmware: %w", err)
+	}
+
+	return &service.FirmwareSignature{Algorithm: algorithm, Signature: signature}, nil
+}
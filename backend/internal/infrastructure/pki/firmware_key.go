@@ -0,0 +1,180 @@
+package pki
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"backend/internal/domain/service"
+)
+
+const (
+	// DefaultFirmwareKeyDir is the default directory of the firmware signing keys in the `/app/certs` volume.
+	DefaultFirmwareKeyDir = "/app/certs/firmware-signing-keys"
+	// firmwareKeyDirMode keeps the private keys readable by the backend only.
+	firmwareKeyDirMode = 0o700
+	// firmwareKeyIDLength is the number of bytes of the hash of the public key that make up a key ID.
+	firmwareKeyIDLength = 16
+)
+
+// FirmwareKeyStore keeps the private keys that sign firmware manifests as PKCS #8 PEM files in a directory,
+// named after their key IDs. It implements service.FirmwareKeyStore.
+type FirmwareKeyStore struct {
+	dir string
+}
+
+// NewFirmwareKeyStore creates a FirmwareKeyStore in dir, creating the directory if it does not exist.
+func NewFirmwareKeyStore(dir string) (*FirmwareKeyStore, error) {
+	err := os.MkdirAll(dir, firmwareKeyDirMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create firmware key directory: %w", err)
+	}
+
+	return &FirmwareKeyStore{dir: dir}, nil
+}
+
+// GenerateKey generates a key for the algorithm and writes it to a new file. The key ID is the hex-encoded
+// beginning of the SHA-256 hash of the public key, so devices can tell which key signed a manifest.
+func (s *FirmwareKeyStore) GenerateKey(_ context.Context, algorithm string) (*service.FirmwarePublicKey, error) {
+	var (
+		signer crypto.Signer
+		err    error
+	)
+
+	switch algorithm {
+	case service.FirmwareKeyEd25519:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	case service.FirmwareKeyECDSAP256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("%w: %q", service.ErrUnsupportedKeyAlgorithm, algorithm)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate firmware signing key: %w", err)
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal firmware signing key: %w", err)
+	}
+
+	privateKey, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal firmware signing key: %w", err)
+	}
+
+	hash := sha256.Sum256(publicKey)
+	keyID := hex.EncodeToString(hash[:firmwareKeyIDLength])
+
+	err = s.write(keyID, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Headers: nil, Bytes: privateKey}))
+	if err != nil {
+		return nil, err
+	}
+
+	return &service.FirmwarePublicKey{ID: keyID, Algorithm: algorithm, PublicKey: publicKey}, nil
+}
+
+// Sign signs a manifest with the key: Ed25519 keys sign the manifest itself,
+// and ECDSA keys its SHA-256 hash, with an ASN.1-encoded signature.
+func (s *FirmwareKeyStore) Sign(_ context.Context, keyID string, manifest []byte) ([]byte, error) {
+	path, err := s.path(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM, err := os.ReadFile(path) //nolint:gosec // The path is made of a validated key ID.
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", service.ErrFirmwareKeyNotFound, keyID)
+		}
+
+		return nil, fmt.Errorf("failed to read firmware signing key: %w", err)
+	}
+
+	signer, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	message, opts := manifest, crypto.SignerOpts(crypto.Hash(0))
+
+	if _, ok := signer.(*ecdsa.PrivateKey); ok {
+		hash := sha256.Sum256(manifest)
+		message, opts = hash[:], crypto.SHA256
+	}
+
+	signature, err := signer.Sign(rand.Reader, message, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign firmware manifest: %w", err)
+	}
+
+	return signature, nil
+}
+
+// DeleteKey removes the file of the key. A key that does not exist is ignored.
+func (s *FirmwareKeyStore) DeleteKey(_ context.Context, keyID string) error {
+	path, err := s.path(keyID)
+	if err != nil {
+		return nil //nolint:nilerr // An invalid key ID cannot name a stored key.
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete firmware signing key: %w", err)
+	}
+
+	return nil
+}
+
+// write writes a private key to its file atomically, so that a key is never read partially written.
+func (s *FirmwareKeyStore) write(keyID string, keyPEM []byte) error {
+	tmp, err := os.CreateTemp(s.dir, ".key-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create firmware signing key file: %w", err)
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }() // No-op once the file has been renamed.
+
+	// CreateTemp creates the file with mode 0600, so the key is never readable by others.
+	_, err = tmp.Write(keyPEM)
+	if err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("failed to write firmware signing key file: %w", err)
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write firmware signing key file: %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), filepath.Join(s.dir, keyID+".key"))
+	if err != nil {
+		return fmt.Errorf("failed to store firmware signing key file: %w", err)
+	}
+
+	return nil
+}
+
+// path returns the path of the file of a key. Key IDs are validated, so that they cannot escape the directory.
+func (s *FirmwareKeyStore) path(keyID string) (string, error) {
+	decoded, err := hex.DecodeString(keyID)
+	if err != nil || len(decoded) != firmwareKeyIDLength {
+		return "", fmt.Errorf("%w: %q", service.ErrFirmwareKeyNotFound, keyID)
+	}
+
+	return filepath.Join(s.dir, keyID+".key"), nil
+}
@@ -0,0 +1,100 @@
+package pki_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"backend/internal/domain/service"
+	"backend/internal/infrastructure/pki"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFirmwareKeyStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	manifest := []byte(`{"version":"1.2.0"}`)
+
+	t.Run("success: Ed25519 keys sign the manifest", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		store, err := pki.NewFirmwareKeyStore(dir)
+		require.NoError(t, err)
+
+		key, err := store.GenerateKey(ctx, service.FirmwareKeyEd25519)
+		require.NoError(t, err)
+		assert.Len(t, key.ID, 32)
+		assert.Equal(t, service.FirmwareKeyEd25519, key.Algorithm)
+
+		info, err := os.Stat(filepath.Join(dir, key.ID+".key"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "the private key is readable by the backend only")
+
+		signature, err := store.Sign(ctx, key.ID, manifest)
+		require.NoError(t, err)
+
+		pub, err := x509.ParsePKIXPublicKey(key.PublicKey)
+		require.NoError(t, err)
+		edPub, ok := pub.(ed25519.PublicKey)
+		require.True(t, ok)
+		assert.True(t, ed25519.Verify(edPub, manifest, signature))
+	})
+
+	t.Run("success: ECDSA keys sign the hash of the manifest", func(t *testing.T) {
+		t.Parallel()
+
+		store, err := pki.NewFirmwareKeyStore(t.TempDir())
+		require.NoError(t, err)
+
+		key, err := store.GenerateKey(ctx, service.FirmwareKeyECDSAP256)
+		require.NoError(t, err)
+
+		signature, err := store.Sign(ctx, key.ID, manifest)
+		require.NoError(t, err)
+
+		pub, err := x509.ParsePKIXPublicKey(key.PublicKey)
+		require.NoError(t, err)
+		ecPub, ok := pub.(*ecdsa.PublicKey)
+		require.True(t, ok)
+
+		digest := sha256.Sum256(manifest)
+		assert.True(t, ecdsa.VerifyASN1(ecPub, digest[:], signature))
+	})
+
+	t.Run("success: a deleted key no longer signs", func(t *testing.T) {
+		t.Parallel()
+
+		store, err := pki.NewFirmwareKeyStore(t.TempDir())
+		require.NoError(t, err)
+
+		key, err := store.GenerateKey(ctx, service.FirmwareKeyEd25519)
+		require.NoError(t, err)
+		require.NoError(t, store.DeleteKey(ctx, key.ID))
+		require.NoError(t, store.DeleteKey(ctx, key.ID), "deleting a missing key is not an error")
+
+		_, err = store.Sign(ctx, key.ID, manifest)
+		require.ErrorIs(t, err, service.ErrFirmwareKeyNotFound)
+	})
+
+	t.Run("failure: unknown algorithms and key IDs are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		store, err := pki.NewFirmwareKeyStore(t.TempDir())
+		require.NoError(t, err)
+
+		_, err = store.GenerateKey(ctx, "RSA")
+		require.ErrorIs(t, err, service.ErrUnsupportedKeyAlgorithm)
+
+		_, err = store.Sign(ctx, "../ca", manifest)
+		require.ErrorIs(t, err, service.ErrFirmwareKeyNotFound)
+	})
+}
@@ -0,0 +1,47 @@
+package pki_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"testing"
+
+	"backend/internal/infrastructure/pki"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignFirmware(t *testing.T) {
+	t.Parallel()
+
+	certPEM, keyPEM := newTestCA(t)
+	ca, err := pki.NewCA(certPEM, keyPEM, 0)
+	require.NoError(t, err)
+
+	t.Run("success: the hash is signed with the CA key", func(t *testing.T) {
+		t.Parallel()
+
+		digest := sha256.Sum256([]byte("firmware image"))
+
+		signature, err := ca.SignFirmware(context.Background(), digest[:])
+		require.NoError(t, err)
+		assert.Equal(t, pki.FirmwareSignatureECDSA, signature.Algorithm)
+
+		pub, ok := ca.Certificate().PublicKey.(*ecdsa.PublicKey)
+		require.True(t, ok)
+		assert.True(t, ecdsa.VerifyASN1(pub, digest[:], signature.Signature))
+
+		// The signature is checked the way devices do, with the CA certificate.
+		require.NoError(t, ca.Certificate().CheckSignature(
+			x509.ECDSAWithSHA256, []byte("firmware image"), signature.Signature))
+	})
+
+	t.Run("failure: the digest is not a SHA-256 hash", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ca.SignFirmware(context.Background(), []byte("firmware image"))
+		require.ErrorIs(t, err, pki.ErrInvalidFirmwareDigest)
+	})
+}
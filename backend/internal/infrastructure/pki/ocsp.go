@@ -0,0 +1,408 @@
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"backend/internal/domain/service"
+)
+
+const (
+	// DefaultOCSPSignerValidity is the validity period of the delegated OCSP signing certificate.
+	DefaultOCSPSignerValidity = 30 * 24 * time.Hour
+	// maxOCSPNonceLength bounds the nonce echoed in responses. RFC 8954 allows up to 32 octets.
+	maxOCSPNonceLength = 32
+)
+
+//nolint:gochecknoglobals
+var (
+	oidOCSPBasic         = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+	oidOCSPNonce         = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 2}
+	oidOCSPNoCheck       = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 5}
+	oidECDSAWithSHA256   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	ocspCertIDHashByOIDs = map[string]crypto.Hash{
+		"1.3.14.3.2.26":          crypto.SHA1,
+		"2.16.840.1.101.3.4.2.1": crypto.SHA256,
+		"2.16.840.1.101.3.4.2.2": crypto.SHA384,
+		"2.16.840.1.101.3.4.2.3": crypto.SHA512,
+	}
+)
+
+// The ASN.1 structures of RFC 6960. They are declared here, as golang.org/x/crypto/ocsp
+// neither exposes request extensions nor writes response extensions, which the nonce needs.
+type (
+	ocspRequestASN1 struct {
+		TBSRequest        ocspTBSRequest
+		OptionalSignature asn1.RawValue `asn1:"explicit,tag:0,optional"`
+	}
+
+	ocspTBSRequest struct {
+		Version           int           `asn1:"explicit,tag:0,default:0,optional"`
+		RequestorName     asn1.RawValue `asn1:"explicit,tag:1,optional"`
+		RequestList       []ocspSingleRequest
+		RequestExtensions []pkix.Extension `asn1:"explicit,tag:2,optional"`
+	}
+
+	ocspSingleRequest struct {
+		CertID                  asn1.RawValue
+		SingleRequestExtensions []pkix.Extension `asn1:"explicit,tag:0,optional"`
+	}
+
+	ocspCertID struct {
+		HashAlgorithm pkix.AlgorithmIdentifier
+		NameHash      []byte
+		IssuerKeyHash []byte
+		SerialNumber  *big.Int
+	}
+
+	ocspResponseASN1 struct {
+		Status   asn1.Enumerated
+		Response ocspResponseBytes `asn1:"explicit,tag:0,optional"`
+	}
+
+	ocspResponseBytes struct {
+		ResponseType asn1.ObjectIdentifier
+		Response     []byte
+	}
+
+	ocspBasicResponse struct {
+		TBSResponseData    asn1.RawValue
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		Signature          asn1.BitString
+		Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+	}
+
+	ocspResponseData struct {
+		Version            int `asn1:"optional,default:0,explicit,tag:0"`
+		RawResponderID     asn1.RawValue
+		ProducedAt         time.Time `asn1:"generalized"`
+		Responses          []ocspSingleResponse
+		ResponseExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+	}
+
+	ocspSingleResponse struct {
+		CertID     asn1.RawValue
+		Good       asn1.Flag       `asn1:"tag:0,optional"`
+		Revoked    ocspRevokedInfo `asn1:"tag:1,optional"`
+		Unknown    asn1.Flag       `asn1:"tag:2,optional"`
+		ThisUpdate time.Time       `asn1:"generalized"`
+		NextUpdate time.Time       `asn1:"generalized,explicit,tag:0,optional"`
+	}
+
+	ocspRevokedInfo struct {
+		RevocationTime time.Time       `asn1:"generalized"`
+		Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+	}
+)
+
+// OCSPResponder answers OCSP requests about certificates issued by the CA.
+// Responses are signed by a delegated OCSP signing certificate, which is issued from the CA
+// and renewed when half of its validity period has passed.
+// It implements service.OCSPResponder.
+type OCSPResponder struct {
+	ca       *CA
+	validity time.Duration
+
+	mu     sync.Mutex
+	cert   *x509.Certificate
+	signer crypto.Signer
+}
+
+// NewOCSPResponder creates an OCSPResponder and issues its signing certificate from the CA.
+func NewOCSPResponder(ca *CA, validity time.Duration) (*OCSPResponder, error) {
+	if validity <= 0 {
+		validity = DefaultOCSPSignerValidity
+	}
+
+	responder := &OCSPResponder{
+		ca:       ca,
+		validity: validity,
+		mu:       sync.Mutex{},
+		cert:     nil,
+		signer:   nil,
+	}
+
+	_, _, err := responder.currentSigner()
+	if err != nil {
+		return nil, err
+	}
+
+	return responder, nil
+}
+
+// Certificate returns the current delegated OCSP signing certificate.
+func (r *OCSPResponder) Certificate() *x509.Certificate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.cert
+}
+
+// ParseOCSPRequest parses a DER-encoded OCSP request.
+// As in the lightweight profile of RFC 5019, a request must ask about exactly one certificate.
+func (r *OCSPResponder) ParseOCSPRequest(der []byte) (*service.OCSPRequest, error) {
+	var req ocspRequestASN1
+
+	rest, err := asn1.Unmarshal(der, &req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", service.ErrMalformedOCSPRequest, err)
+	}
+
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing data", service.ErrMalformedOCSPRequest)
+	}
+
+	if len(req.TBSRequest.RequestList) != 1 {
+		return nil, fmt.Errorf("%w: %d certificates requested, only one is supported",
+			service.ErrMalformedOCSPRequest, len(req.TBSRequest.RequestList))
+	}
+
+	rawCertID := req.TBSRequest.RequestList[0].CertID.FullBytes
+
+	var certID ocspCertID
+
+	_, err = asn1.Unmarshal(rawCertID, &certID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", service.ErrMalformedOCSPRequest, err)
+	}
+
+	err = r.checkIssuer(certID)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := findOCSPNonce(req.TBSRequest.RequestExtensions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &service.OCSPRequest{
+		SerialNumber: certID.SerialNumber,
+		CertID:       rawCertID,
+		Nonce:        nonce,
+	}, nil
+}
+
+// SignOCSPResponse signs the response with the delegated OCSP signing certificate and returns it DER-encoded.
+func (r *OCSPResponder) SignOCSPResponse(_ context.Context, response service.OCSPResponse) ([]byte, error) {
+	cert, signer, err := r.currentSigner()
+	if err != nil {
+		return nil, err
+	}
+
+	single := ocspSingleResponse{ //nolint:exhaustruct
+		CertID:     asn1.RawValue{FullBytes: response.Request.CertID}, //nolint:exhaustruct
+		ThisUpdate: response.ThisUpdate.UTC(),
+		NextUpdate: response.NextUpdate.UTC(),
+	}
+
+	switch response.Status {
+	case service.OCSPGood:
+		single.Good = true
+	case service.OCSPRevoked:
+		single.Revoked = ocspRevokedInfo{
+			RevocationTime: response.RevokedAt.UTC(),
+			Reason:         asn1.Enumerated(response.ReasonCode),
+		}
+	case service.OCSPUnknown:
+		single.Unknown = true
+	}
+
+	var extensions []pkix.Extension
+
+	nonce := response.Request.Nonce
+	if nonce != nil {
+		extensions = append(extensions, pkix.Extension{Id: oidOCSPNonce, Critical: false, Value: nonce})
+	}
+
+	// The responder is identified by name: [1] EXPLICIT Name.
+	responderID := asn1.RawValue{ //nolint:exhaustruct
+		Class:      asn1.ClassContextSpecific,
+		Tag:        1,
+		IsCompound: true,
+		Bytes:      cert.RawSubject,
+	}
+
+	tbs, err := asn1.Marshal(ocspResponseData{
+		Version:            0,
+		RawResponderID:     responderID,
+		ProducedAt:         r.ca.now().UTC().Truncate(time.Second),
+		Responses:          []ocspSingleResponse{single},
+		ResponseExtensions: extensions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OCSP response: %w", err)
+	}
+
+	digest := sha256.Sum256(tbs)
+
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign OCSP response: %w", err)
+	}
+
+	basic, err := asn1.Marshal(ocspBasicResponse{
+		TBSResponseData:    asn1.RawValue{FullBytes: tbs},                                   //nolint:exhaustruct
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256},         //nolint:exhaustruct
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)}, //nolint:mnd
+		Certificates:       []asn1.RawValue{{FullBytes: cert.Raw}},                          //nolint:exhaustruct
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OCSP response: %w", err)
+	}
+
+	der, err := asn1.Marshal(ocspResponseASN1{
+		Status:   0, // successful
+		Response: ocspResponseBytes{ResponseType: oidOCSPBasic, Response: basic},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OCSP response: %w", err)
+	}
+
+	return der, nil
+}
+
+// currentSigner returns the delegated OCSP signing certificate and key, renewing them if needed.
+func (r *OCSPResponder) currentSigner() (*x509.Certificate, crypto.Signer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.ca.now()
+	if r.cert != nil && now.Before(r.cert.NotAfter.Add(-r.validity/2)) { //nolint:mnd
+		return r.cert, r.signer, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate OCSP signing key: %w", err)
+	}
+
+	cert, err := r.ca.issueOCSPSigningCertificate(key.Public(), r.validity)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r.cert = cert
+	r.signer = key
+
+	return r.cert, r.signer, nil
+}
+
+// checkIssuer verifies that the CertID identifies the CA as the issuer of the certificate.
+func (r *OCSPResponder) checkIssuer(certID ocspCertID) error {
+	hash, ok := ocspCertIDHashByOIDs[certID.HashAlgorithm.Algorithm.String()]
+	if !ok || !hash.Available() {
+		return fmt.Errorf("%w: unsupported hash algorithm %s",
+			service.ErrMalformedOCSPRequest, certID.HashAlgorithm.Algorithm)
+	}
+
+	if certID.SerialNumber == nil {
+		return fmt.Errorf("%w: missing serial number", service.ErrMalformedOCSPRequest)
+	}
+
+	var publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+
+	_, err := asn1.Unmarshal(r.ca.cert.RawSubjectPublicKeyInfo, &publicKeyInfo)
+	if err != nil {
+		return fmt.Errorf("failed to parse CA public key: %w", err)
+	}
+
+	h := hash.New()
+	h.Write(r.ca.cert.RawSubject)
+	nameHash := h.Sum(nil)
+
+	h.Reset()
+	h.Write(publicKeyInfo.PublicKey.RightAlign())
+	keyHash := h.Sum(nil)
+
+	if !bytes.Equal(certID.NameHash, nameHash) || !bytes.Equal(certID.IssuerKeyHash, keyHash) {
+		return service.ErrOCSPUnauthorized
+	}
+
+	return nil
+}
+
+// issueOCSPSigningCertificate issues a delegated OCSP signing certificate (RFC 6960, section 4.2.2.2).
+// It carries id-pkix-ocsp-nocheck, so clients do not check the revocation of the responder itself.
+func (ca *CA) issueOCSPSigningCertificate(pub crypto.PublicKey, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := ca.now()
+
+	notAfter := now.Add(validity)
+	if notAfter.After(ca.cert.NotAfter) {
+		notAfter = ca.cert.NotAfter
+	}
+
+	template := &x509.Certificate{ //nolint:exhaustruct
+		SerialNumber: big.NewInt(serial),
+		Subject: pkix.Name{ //nolint:exhaustruct
+			CommonName: ca.cert.Subject.CommonName + " OCSP Responder",
+		},
+		NotBefore:             now.Add(-clockSkewAllowance),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+		BasicConstraintsValid: true,
+		IsCA:                  false,
+		ExtraExtensions: []pkix.Extension{
+			{Id: oidOCSPNoCheck, Critical: false, Value: asn1.NullBytes},
+		},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, pub, ca.signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue OCSP signing certificate: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OCSP signing certificate: %w", err)
+	}
+
+	return cert, nil
+}
+
+// findOCSPNonce returns the value of the nonce extension, or nil if there is none.
+// The value is returned still DER-encoded, so that it can be echoed in the response as is.
+func findOCSPNonce(extensions []pkix.Extension) ([]byte, error) {
+	for _, extension := range extensions {
+		if !extension.Id.Equal(oidOCSPNonce) {
+			continue
+		}
+
+		// The extension value is the DER encoding of an OCTET STRING holding the nonce itself.
+		var nonce []byte
+
+		rest, err := asn1.Unmarshal(extension.Value, &nonce)
+		if err != nil || len(rest) != 0 {
+			return nil, fmt.Errorf("%w: malformed nonce", service.ErrMalformedOCSPRequest)
+		}
+
+		if len(nonce) == 0 || len(nonce) > maxOCSPNonceLength {
+			return nil, fmt.Errorf("%w: invalid nonce length %d", service.ErrMalformedOCSPRequest, len(nonce))
+		}
+
+		return extension.Value, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,278 @@
+package pki_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"backend/internal/domain/service"
+	"backend/internal/infrastructure/pki"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+)
+
+// testOCSPRequest mirrors the OCSPRequest structure of RFC 6960 to add a nonce to a request.
+type testOCSPRequest struct {
+	TBSRequest struct {
+		Version           int           `asn1:"explicit,tag:0,default:0,optional"`
+		RequestorName     asn1.RawValue `asn1:"explicit,tag:1,optional"`
+		RequestList       []asn1.RawValue
+		RequestExtensions []pkix.Extension `asn1:"explicit,tag:2,optional"`
+	}
+}
+
+// testOCSPResponseExtensions extracts the response extensions, which golang.org/x/crypto/ocsp does not parse.
+func testOCSPResponseExtensions(t *testing.T, der []byte) []pkix.Extension {
+	t.Helper()
+
+	var response struct {
+		Status   asn1.Enumerated
+		Response struct {
+			ResponseType asn1.ObjectIdentifier
+			Response     []byte
+		} `asn1:"explicit,tag:0,optional"`
+	}
+
+	_, err := asn1.Unmarshal(der, &response)
+	require.NoError(t, err)
+
+	var basic struct {
+		TBSResponseData    asn1.RawValue
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		Signature          asn1.BitString
+		Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+	}
+
+	_, err = asn1.Unmarshal(response.Response.Response, &basic)
+	require.NoError(t, err)
+
+	var data struct {
+		Version            int `asn1:"optional,default:0,explicit,tag:0"`
+		RawResponderID     asn1.RawValue
+		ProducedAt         time.Time `asn1:"generalized"`
+		Responses          []asn1.RawValue
+		ResponseExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+	}
+
+	_, err = asn1.Unmarshal(basic.TBSResponseData.FullBytes, &data)
+	require.NoError(t, err)
+
+	return data.ResponseExtensions
+}
+
+// newTestDeviceCertificate issues a device certificate from the CA.
+func newTestDeviceCertificate(t *testing.T, ca *pki.CA) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	issued, err := ca.SignCSR(context.Background(), service.CertificateSignRequest{
+		CSR:        newTestCSR(t, key, "hw-ocsp-001"),
+		DeviceID:   uuid.New(),
+		HardwareID: "hw-ocsp-001",
+	})
+	require.NoError(t, err)
+
+	block, _ := pem.Decode([]byte(issued.CertificatePEM))
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+
+	return cert
+}
+
+func TestOCSPResponder(t *testing.T) {
+	t.Parallel()
+
+	certPEM, keyPEM := newTestCA(t)
+	ca, err := pki.NewCA(certPEM, keyPEM, 0)
+	require.NoError(t, err)
+	ca.SetOCSPServers([]string{"http://ocsp.example.com/ocsp"})
+
+	responder, err := pki.NewOCSPResponder(ca, 0)
+	require.NoError(t, err)
+
+	deviceCert := newTestDeviceCertificate(t, ca)
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	t.Run("issued certificates carry the OCSP URL", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, []string{"http://ocsp.example.com/ocsp"}, deviceCert.OCSPServer)
+	})
+
+	t.Run("the signing certificate is delegated by the CA", func(t *testing.T) {
+		t.Parallel()
+
+		signerCert := responder.Certificate()
+		require.NoError(t, signerCert.CheckSignatureFrom(ca.Certificate()))
+		assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning}, signerCert.ExtKeyUsage)
+		assert.False(t, signerCert.IsCA)
+	})
+
+	tests := []struct {
+		name       string
+		desc       string
+		hash       crypto.Hash
+		status     service.OCSPCertStatus
+		wantStatus int
+	}{
+		{
+			name:       "good with SHA-1 CertID",
+			desc:       "Verify that a good response is signed for a SHA-1 CertID.",
+			hash:       crypto.SHA1,
+			status:     service.OCSPGood,
+			wantStatus: ocsp.Good,
+		},
+		{
+			name:       "revoked with SHA-256 CertID",
+			desc:       "Verify that a revoked response carries the revocation time and reason.",
+			hash:       crypto.SHA256,
+			status:     service.OCSPRevoked,
+			wantStatus: ocsp.Revoked,
+		},
+		{
+			name:       "unknown",
+			desc:       "Verify that an unknown response is signed.",
+			hash:       crypto.SHA256,
+			status:     service.OCSPUnknown,
+			wantStatus: ocsp.Unknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			reqDER, err := ocsp.CreateRequest(deviceCert, ca.Certificate(), &ocsp.RequestOptions{Hash: tt.hash})
+			require.NoError(t, err)
+
+			req, err := responder.ParseOCSPRequest(reqDER)
+			require.NoError(t, err)
+			assert.Equal(t, 0, deviceCert.SerialNumber.Cmp(req.SerialNumber))
+			assert.Nil(t, req.Nonce)
+
+			der, err := responder.SignOCSPResponse(ctx, service.OCSPResponse{
+				Request:    req,
+				Status:     tt.status,
+				RevokedAt:  now.Add(-time.Hour),
+				ReasonCode: ocsp.KeyCompromise,
+				ThisUpdate: now,
+				NextUpdate: now.Add(time.Hour),
+			})
+			require.NoError(t, err)
+
+			// The response is verified with an independent implementation.
+			resp, err := ocsp.ParseResponseForCert(der, deviceCert, ca.Certificate())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.Status)
+			assert.Equal(t, tt.hash, resp.IssuerHash)
+			assert.True(t, now.Equal(resp.ThisUpdate))
+			assert.True(t, now.Add(time.Hour).Equal(resp.NextUpdate))
+
+			if tt.status == service.OCSPRevoked {
+				assert.True(t, now.Add(-time.Hour).Equal(resp.RevokedAt))
+				assert.Equal(t, ocsp.KeyCompromise, resp.RevocationReason)
+			}
+		})
+	}
+
+	t.Run("the nonce is echoed in the response extensions", func(t *testing.T) {
+		t.Parallel()
+
+		reqDER, err := ocsp.CreateRequest(deviceCert, ca.Certificate(), nil)
+		require.NoError(t, err)
+
+		var withNonce testOCSPRequest
+
+		_, err = asn1.Unmarshal(reqDER, &withNonce)
+		require.NoError(t, err)
+
+		nonce, err := asn1.Marshal([]byte("0123456789abcdef"))
+		require.NoError(t, err)
+
+		nonceOID := asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 2}
+		withNonce.TBSRequest.RequestExtensions = []pkix.Extension{{Id: nonceOID, Critical: false, Value: nonce}}
+		reqDER, err = asn1.Marshal(withNonce)
+		require.NoError(t, err)
+
+		req, err := responder.ParseOCSPRequest(reqDER)
+		require.NoError(t, err)
+		assert.Equal(t, nonce, req.Nonce)
+
+		der, err := responder.SignOCSPResponse(ctx, service.OCSPResponse{
+			Request:    req,
+			Status:     service.OCSPGood,
+			RevokedAt:  time.Time{},
+			ReasonCode: 0,
+			ThisUpdate: now,
+			NextUpdate: now.Add(time.Hour),
+		})
+		require.NoError(t, err)
+
+		_, err = ocsp.ParseResponseForCert(der, deviceCert, ca.Certificate())
+		require.NoError(t, err)
+
+		extensions := testOCSPResponseExtensions(t, der)
+		require.Len(t, extensions, 1)
+		assert.True(t, extensions[0].Id.Equal(nonceOID))
+		assert.Equal(t, nonce, extensions[0].Value)
+	})
+
+	t.Run("failure: nonce longer than 32 octets", func(t *testing.T) {
+		t.Parallel()
+
+		reqDER, err := ocsp.CreateRequest(deviceCert, ca.Certificate(), nil)
+		require.NoError(t, err)
+
+		var withNonce testOCSPRequest
+
+		_, err = asn1.Unmarshal(reqDER, &withNonce)
+		require.NoError(t, err)
+
+		nonce, err := asn1.Marshal(make([]byte, 33))
+		require.NoError(t, err)
+
+		nonceOID := asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 2}
+		withNonce.TBSRequest.RequestExtensions = []pkix.Extension{{Id: nonceOID, Critical: false, Value: nonce}}
+		reqDER, err = asn1.Marshal(withNonce)
+		require.NoError(t, err)
+
+		_, err = responder.ParseOCSPRequest(reqDER)
+		require.ErrorIs(t, err, service.ErrMalformedOCSPRequest)
+	})
+
+	t.Run("failure: certificate of another CA", func(t *testing.T) {
+		t.Parallel()
+
+		otherCertPEM, otherKeyPEM := newTestCA(t)
+		otherCA, err := pki.NewCA(otherCertPEM, otherKeyPEM, 0)
+		require.NoError(t, err)
+
+		reqDER, err := ocsp.CreateRequest(newTestDeviceCertificate(t, otherCA), otherCA.Certificate(), nil)
+		require.NoError(t, err)
+
+		_, err = responder.ParseOCSPRequest(reqDER)
+		require.ErrorIs(t, err, service.ErrOCSPUnauthorized)
+	})
+
+	t.Run("failure: malformed request", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := responder.ParseOCSPRequest([]byte("not an ocsp request"))
+		require.ErrorIs(t, err, service.ErrMalformedOCSPRequest)
+	})
+}
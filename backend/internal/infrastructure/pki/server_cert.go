@@ -0,0 +1,151 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultServerCertValidity is the validity period of the server certificate of the embedded MQTT broker.
+const DefaultServerCertValidity = 30 * 24 * time.Hour
+
+// ServerCertificate is a TLS server certificate for the embedded MQTT broker.
+// It is issued from the CA for the given host names and IP addresses,
+// and renewed when half of its validity period has passed.
+type ServerCertificate struct {
+	ca       *CA
+	hosts    []string
+	validity time.Duration
+
+	mu   sync.Mutex
+	cert *tls.Certificate
+}
+
+// NewServerCertificate creates a ServerCertificate and issues the certificate from the CA.
+func NewServerCertificate(ca *CA, hosts []string, validity time.Duration) (*ServerCertificate, error) {
+	if validity <= 0 {
+		validity = DefaultServerCertValidity
+	}
+
+	server := &ServerCertificate{
+		ca:       ca,
+		hosts:    hosts,
+		validity: validity,
+		mu:       sync.Mutex{},
+		cert:     nil,
+	}
+
+	_, err := server.GetCertificate(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return server, nil
+}
+
+// GetCertificate returns the current certificate, renewing it if needed.
+// It can be used as tls.Config.GetCertificate.
+func (s *ServerCertificate) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.ca.now()
+	if s.cert != nil && now.Before(s.cert.Leaf.NotAfter.Add(-s.validity/2)) { //nolint:mnd
+		return s.cert, nil
+	}
+
+	cert, err := s.ca.issueServerCertificate(s.hosts, s.validity)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cert = cert
+
+	return s.cert, nil
+}
+
+// ClientCAs returns the pool of CA certificates that verify the client certificates of devices.
+func (ca *CA) ClientCAs() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	return pool
+}
+
+// issueServerCertificate issues a TLS server certificate with a new key for the given host names and IP addresses.
+func (ca *CA) issueServerCertificate(hosts []string, validity time.Duration) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate server key: %w", err)
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := ca.now()
+
+	notAfter := now.Add(validity)
+	if notAfter.After(ca.cert.NotAfter) {
+		notAfter = ca.cert.NotAfter
+	}
+
+	template := &x509.Certificate{ //nolint:exhaustruct
+		SerialNumber: big.NewInt(serial),
+		Subject: pkix.Name{ //nolint:exhaustruct
+			CommonName: ca.cert.Subject.CommonName + " MQTT Broker",
+		},
+		NotBefore:             now.Add(-clockSkewAllowance),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  false,
+	}
+
+	for _, host := range hosts {
+		ip := net.ParseIP(host)
+		if ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue server certificate: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse server certificate: %w", err)
+	}
+
+	// The chain lets devices that only trust a root CA verify the issuing CA.
+	chain := [][]byte{der}
+
+	chainCerts, err := parseCertificates([]byte(ca.chainPEM))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cert := range chainCerts {
+		chain = append(chain, cert.Raw)
+	}
+
+	return &tls.Certificate{ //nolint:exhaustruct
+		Certificate: chain,
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
@@ -0,0 +1,86 @@
+package pki_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"testing"
+	"time"
+
+	"backend/internal/domain/service"
+	"backend/internal/infrastructure/pki"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerCertificate(t *testing.T) {
+	t.Parallel()
+
+	certPEM, keyPEM := newTestCA(t)
+	ca, err := pki.NewCA(certPEM, keyPEM, 0)
+	require.NoError(t, err)
+
+	server, err := pki.NewServerCertificate(ca, []string{"mqtt.example.com", "127.0.0.1"}, time.Hour)
+	require.NoError(t, err)
+
+	got, err := server.GetCertificate(nil)
+	require.NoError(t, err)
+	require.NotNil(t, got.Leaf)
+
+	t.Run("success: certificate is issued for the hosts", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, []string{"mqtt.example.com"}, got.Leaf.DNSNames)
+		require.Len(t, got.Leaf.IPAddresses, 1)
+		assert.True(t, got.Leaf.IPAddresses[0].Equal(net.IPv4(127, 0, 0, 1)))
+		assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, got.Leaf.ExtKeyUsage)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), got.Leaf.NotAfter, time.Minute)
+		require.Len(t, got.Certificate, 2, "the chain includes the CA certificate")
+
+		roots := x509.NewCertPool()
+		roots.AddCert(ca.Certificate())
+		_, err := got.Leaf.Verify(x509.VerifyOptions{ //nolint:exhaustruct
+			DNSName: "mqtt.example.com",
+			Roots:   roots,
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("success: certificate is reused until renewal", func(t *testing.T) {
+		t.Parallel()
+
+		again, err := server.GetCertificate(nil)
+		require.NoError(t, err)
+		assert.Same(t, got, again)
+	})
+
+	t.Run("success: client CAs verify device certificates", func(t *testing.T) {
+		t.Parallel()
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		issued, err := ca.SignCSR(context.Background(), service.CertificateSignRequest{
+			CSR:        newTestCSR(t, key, "hw-broker-001"),
+			DeviceID:   uuid.New(),
+			HardwareID: "hw-broker-001",
+		})
+		require.NoError(t, err)
+
+		block, _ := pem.Decode([]byte(issued.CertificatePEM))
+		cert, err := x509.ParseCertificate(block.Bytes)
+		require.NoError(t, err)
+
+		_, err = cert.Verify(x509.VerifyOptions{ //nolint:exhaustruct
+			Roots:     ca.ClientCAs(),
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,132 @@
+// Package storage keeps the files of the platform, such as firmware images, on the local filesystem.
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"backend/internal/domain/service"
+)
+
+const (
+	// firmwareDirMode is the permission of the firmware directory.
+	firmwareDirMode = 0o750
+	// firmwareKeyBytes is the number of random bytes of a firmware image key.
+	firmwareKeyBytes = 16
+)
+
+// FirmwareFileStorage keeps firmware images as files in a directory, named after random hex keys.
+// It implements service.FirmwareStorage.
+type FirmwareFileStorage struct {
+	dir string
+}
+
+// NewFirmwareFileStorage creates a FirmwareFileStorage in dir, creating the directory if it does not exist.
+func NewFirmwareFileStorage(dir string) (*FirmwareFileStorage, error) {
+	err := os.MkdirAll(dir, firmwareDirMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create firmware directory: %w", err)
+	}
+
+	return &FirmwareFileStorage{dir: dir}, nil
+}
+
+// Put writes the image to a temporary file while hashing it, and renames it once it is complete,
+// so that an image is never seen partially written.
+func (s *FirmwareFileStorage) Put(_ context.Context, r io.Reader) (*service.StoredFirmwareImage, error) {
+	key, err := newFirmwareKey()
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".firmware-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create firmware file: %w", err)
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }() // No-op once the file has been renamed.
+
+	hash := sha256.New()
+
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if err != nil {
+		_ = tmp.Close()
+
+		return nil, fmt.Errorf("failed to write firmware file: %w", err)
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write firmware file: %w", err)
+	}
+
+	err = os.Rename(tmp.Name(), filepath.Join(s.dir, key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to store firmware file: %w", err)
+	}
+
+	return &service.StoredFirmwareImage{Key: key, Size: size, SHA256: hash.Sum(nil)}, nil
+}
+
+// Open opens the image stored under key.
+func (s *FirmwareFileStorage) Open(_ context.Context, key string) (io.ReadSeekCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path) //nolint:gosec // The key is checked to be a plain file name.
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", service.ErrFirmwareImageNotFound, key)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open firmware file: %w", err)
+	}
+
+	return file, nil
+}
+
+// Delete removes the image stored under key, if there is one.
+func (s *FirmwareFileStorage) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete firmware file: %w", err)
+	}
+
+	return nil
+}
+
+// path returns the path of the file of key. Keys are hex strings, so that they never leave the directory.
+func (s *FirmwareFileStorage) path(key string) (string, error) {
+	decoded, err := hex.DecodeString(key)
+	if err != nil || len(decoded) != firmwareKeyBytes {
+		return "", fmt.Errorf("%w: %q", service.ErrFirmwareImageNotFound, key)
+	}
+
+	return filepath.Join(s.dir, key), nil
+}
+
+func newFirmwareKey() (string, error) {
+	key := make([]byte, firmwareKeyBytes)
+
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate firmware key: %w", err)
+	}
+
+	return hex.EncodeToString(key), nil
+}
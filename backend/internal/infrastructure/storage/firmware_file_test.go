@@ -0,0 +1,92 @@
+package storage_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"backend/internal/domain/service"
+	"backend/internal/infrastructure/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errBrokenReader = errors.New("broken reader")
+
+type brokenReader struct{}
+
+func (brokenReader) Read([]byte) (int, error) {
+	return 0, errBrokenReader
+}
+
+func TestFirmwareFileStorage(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("success: an image is stored, opened and deleted", func(t *testing.T) {
+		t.Parallel()
+
+		dir := filepath.Join(t.TempDir(), "firmware")
+		files, err := storage.NewFirmwareFileStorage(dir)
+		require.NoError(t, err)
+
+		image, err := files.Put(ctx, strings.NewReader("firmware image"))
+		require.NoError(t, err)
+
+		sum := sha256.Sum256([]byte("firmware image"))
+		assert.Equal(t, int64(len("firmware image")), image.Size)
+		assert.Equal(t, sum[:], image.SHA256)
+
+		file, err := files.Open(ctx, image.Key)
+		require.NoError(t, err)
+
+		data, err := io.ReadAll(file)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+		assert.Equal(t, "firmware image", string(data))
+
+		// No temporary files are left behind.
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+
+		require.NoError(t, files.Delete(ctx, image.Key))
+		require.NoError(t, files.Delete(ctx, image.Key))
+
+		_, err = files.Open(ctx, image.Key)
+		require.ErrorIs(t, err, service.ErrFirmwareImageNotFound)
+	})
+
+	t.Run("failure: nothing is kept when the image cannot be read", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		files, err := storage.NewFirmwareFileStorage(dir)
+		require.NoError(t, err)
+
+		_, err = files.Put(ctx, brokenReader{})
+		require.ErrorIs(t, err, errBrokenReader)
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("failure: keys cannot leave the directory", func(t *testing.T) {
+		t.Parallel()
+
+		files, err := storage.NewFirmwareFileStorage(t.TempDir())
+		require.NoError(t, err)
+
+		_, err = files.Open(ctx, "../../etc/passwd")
+		require.ErrorIs(t, err, service.ErrFirmwareImageNotFound)
+		require.ErrorIs(t, files.Delete(ctx, "../secret"), service.ErrFirmwareImageNotFound)
+	})
+}
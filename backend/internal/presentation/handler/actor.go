@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"strings"
+
+	"backend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActorHeader is the request header that names who performs the request, e.g. an operator's user name.
+// It is recorded as the actor of audit log entries.
+const ActorHeader = "X-Actor"
+
+// Actor is a middleware that attributes the operations of a request to the actor named in ActorHeader.
+// Requests without the header are attributed to the system.
+func Actor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := strings.TrimSpace(c.GetHeader(ActorHeader))
+		if actor != "" {
+			c.Request = c.Request.WithContext(usecase.WithActor(c.Request.Context(), actor))
+		}
+
+		c.Next()
+	}
+}
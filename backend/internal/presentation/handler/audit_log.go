@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// errInvalidQueryParameter is returned when a query parameter cannot be parsed.
+var errInvalidQueryParameter = errors.New("invalid query parameter")
+
+// AuditLogHandler handles HTTP requests and calls the AuditLogUsecase.
+type AuditLogHandler struct {
+	uc usecase.AuditLogUsecase
+}
+
+// NewAuditLogHandler creates a new instance of AuditLogHandler.
+func NewAuditLogHandler(uc usecase.AuditLogUsecase) *AuditLogHandler {
+	return &AuditLogHandler{uc: uc}
+}
+
+// ListAuditLogs handles GET /audit-logs to query the audit trail, newest first.
+// It accepts the query parameters deviceId, action, actor, from and to (RFC 3339), limit and beforeId.
+func (h *AuditLogHandler) ListAuditLogs(c *gin.Context) {
+	input, err := parseListAuditLogsInput(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	outputs, err := h.uc.ListAuditLogs(c.Request.Context(), input)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidAuditLogFilter) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+			return
+		}
+
+		log.Printf("failed to list audit logs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, outputs)
+}
+
+// VerifyAuditLogs handles GET /audit-logs/verify to verify the hash chain of the audit trail.
+// A broken chain is reported in the body with "valid": false, not as an error status.
+func (h *AuditLogHandler) VerifyAuditLogs(c *gin.Context) {
+	output, err := h.uc.VerifyAuditLogs(c.Request.Context())
+	if err != nil {
+		log.Printf("failed to verify audit logs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// parseListAuditLogsInput reads the filters of an audit log query from the query string.
+func parseListAuditLogsInput(c *gin.Context) (usecase.ListAuditLogsInput, error) {
+	input := usecase.ListAuditLogsInput{ //nolint:exhaustruct
+		Action: c.Query("action"),
+		Actor:  c.Query("actor"),
+	}
+
+	deviceID := c.Query("deviceId")
+	if deviceID != "" {
+		id, err := uuid.Parse(deviceID)
+		if err != nil {
+			return input, fmt.Errorf("%w: deviceId must be a UUID", errInvalidQueryParameter)
+		}
+
+		input.DeviceID = &id
+	}
+
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		return input, err
+	}
+
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		return input, err
+	}
+
+	input.From, input.To = from, to
+
+	limit := c.Query("limit")
+	if limit != "" {
+		input.Limit, err = strconv.Atoi(limit)
+		if err != nil || input.Limit <= 0 {
+			return input, fmt.Errorf("%w: limit must be a positive integer", errInvalidQueryParameter)
+		}
+	}
+
+	beforeID := c.Query("beforeId")
+	if beforeID != "" {
+		input.BeforeID, err = strconv.ParseInt(beforeID, 10, 64)
+		if err != nil || input.BeforeID <= 0 {
+			return input, fmt.Errorf("%w: beforeId must be a positive integer", errInvalidQueryParameter)
+		}
+	}
+
+	return input, nil
+}
+
+// parseTimeQuery parses an optional RFC 3339 timestamp from the query string.
+func parseTimeQuery(c *gin.Context, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be an RFC 3339 timestamp", errInvalidQueryParameter, key)
+	}
+
+	return &parsed, nil
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/internal/domain/VO/revocationreason"
+	"backend/internal/domain/entity"
+	"backend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CertificateHandler handles HTTP requests and calls the CertificateUsecase.
+type CertificateHandler struct {
+	uc usecase.CertificateUsecase
+}
+
+// NewCertificateHandler creates a new instance of CertificateHandler.
+func NewCertificateHandler(uc usecase.CertificateUsecase) *CertificateHandler {
+	return &CertificateHandler{uc: uc}
+}
+
+// ListDeviceCertificates handles GET /devices/:id/certificates to list the certificates issued to a device.
+func (h *CertificateHandler) ListDeviceCertificates(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+
+		return
+	}
+
+	outputs, err := h.uc.ListDeviceCertificates(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, entity.ErrDeviceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrDeviceNotFound.Error()})
+
+			return
+		}
+
+		log.Printf("failed to list certificates: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, outputs)
+}
+
+// GetCertificate handles GET /certificates/:serial to retrieve a specific certificate.
+// The serial number is accepted in decimal, or in hexadecimal with a "0x" prefix.
+func (h *CertificateHandler) GetCertificate(c *gin.Context) {
+	serialNumber, err := parseSerialNumber(c.Param("serial"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid serial number"})
+
+		return
+	}
+
+	output, err := h.uc.GetCertificate(c.Request.Context(), serialNumber)
+	if err != nil {
+		if errors.Is(err, entity.ErrCertificateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrCertificateNotFound.Error()})
+
+			return
+		}
+
+		log.Printf("failed to get certificate: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// RevokeCertificate handles POST /certificates/:serial/revoke to revoke a specific certificate.
+// The body is optional and may specify an RFC 5280 reason, e.g. {"reason": "keyCompromise"}.
+func (h *CertificateHandler) RevokeCertificate(c *gin.Context) {
+	serialNumber, err := parseSerialNumber(c.Param("serial"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid serial number"})
+
+		return
+	}
+
+	var input usecase.RevokeCertificateInput
+
+	err = c.ShouldBindJSON(&input)
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+
+		return
+	}
+
+	input.SerialNumber = serialNumber // Set the serial number from the URL into the input struct.
+
+	output, err := h.uc.RevokeCertificate(c.Request.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, revocationreason.ErrInvalidReason):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, entity.ErrCertificateNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrCertificateNotFound.Error()})
+		case errors.Is(err, entity.ErrCertificateAlreadyRevoked):
+			c.JSON(http.StatusConflict, gin.H{"error": entity.ErrCertificateAlreadyRevoked.Error()})
+		default:
+			log.Printf("failed to revoke certificate: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		}
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// parseSerialNumber parses a certificate serial number in decimal or "0x"-prefixed hexadecimal.
+func parseSerialNumber(s string) (int64, error) {
+	hex, isHex := strings.CutPrefix(strings.ToLower(s), "0x")
+	if isHex {
+		return strconv.ParseInt(hex, 16, 64) //nolint:wrapcheck
+	}
+
+	return strconv.ParseInt(s, 10, 64) //nolint:wrapcheck
+}
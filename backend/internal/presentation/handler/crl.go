@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"backend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CRLHandler handles HTTP requests and calls the CRLUsecase.
+type CRLHandler struct {
+	uc usecase.CRLUsecase
+}
+
+// NewCRLHandler creates a new instance of CRLHandler.
+func NewCRLHandler(uc usecase.CRLUsecase) *CRLHandler {
+	return &CRLHandler{uc: uc}
+}
+
+// GetCRL handles GET /crl to download the current certificate revocation list.
+// The CRL is DER-encoded by default, or PEM-encoded with `?format=pem`.
+func (h *CRLHandler) GetCRL(c *gin.Context) {
+	format := c.DefaultQuery("format", "der")
+	if format != "der" && format != "pem" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format: must be der or pem"})
+
+		return
+	}
+
+	output, err := h.uc.GetCRL(c.Request.Context())
+	if err != nil {
+		log.Printf("failed to get CRL: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+
+		return
+	}
+
+	// The CRL changes on every revocation, so clients must revalidate.
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Last-Modified", output.ThisUpdate.UTC().Format(http.TimeFormat))
+	c.Header("Expires", output.NextUpdate.UTC().Format(http.TimeFormat))
+
+	if format == "pem" {
+		c.Data(http.StatusOK, "application/x-pem-file", output.PEM())
+
+		return
+	}
+
+	c.Data(http.StatusOK, "application/pkix-crl", output.DER)
+}
@@ -2,10 +2,18 @@
 package handler
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"backend/internal/domain/VO/devicestatus"
 	"backend/internal/domain/entity"
 	"backend/internal/usecase"
 
@@ -13,14 +21,40 @@ import (
 	"github.com/google/uuid"
 )
 
+// metadataQueryPrefix is the prefix of the query parameters that are conditions on the metadata of devices.
+const metadataQueryPrefix = "metadata."
+
+// Media types of the patch documents accepted by PATCH /devices/:id.
+const (
+	mediaTypeMergePatch = "application/merge-patch+json"
+	mediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+// patchFormats maps the media types of the patch documents to their formats.
+var patchFormats = map[string]usecase.PatchFormat{ //nolint:gochecknoglobals
+	mediaTypeMergePatch: usecase.PatchFormatMerge,
+	mediaTypeJSONPatch:  usecase.PatchFormatJSON,
+}
+
+// deviceStatusTransition is a usecase method that changes the status of a device.
+type deviceStatusTransition func(ctx context.Context, id uuid.UUID) (*usecase.DeviceOutput, error)
+
+// DeviceHandlerConfig configures the DeviceHandler.
+type DeviceHandlerConfig struct {
+	// RequireIfMatch rejects PUT, PATCH and DELETE requests without an If-Match header
+	// with 428 Precondition Required, so that no client can overwrite a device without having read it.
+	RequireIfMatch bool
+}
+
 // DeviceHandler handles HTTP requests and calls the DeviceUsecase.
 type DeviceHandler struct {
-	uc usecase.DeviceUsecase
+	uc     usecase.DeviceUsecase
+	config DeviceHandlerConfig
 }
 
 // NewDeviceHandler creates a new instance of DeviceHandler.
-func NewDeviceHandler(uc usecase.DeviceUsecase) *DeviceHandler {
-	return &DeviceHandler{uc: uc}
+func NewDeviceHandler(uc usecase.DeviceUsecase, config DeviceHandlerConfig) *DeviceHandler {
+	return &DeviceHandler{uc: uc, config: config}
 }
 
 // CreateDevice handles POST /devices to create a new device.
@@ -42,6 +76,7 @@ func (h *DeviceHandler) CreateDevice(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", deviceETag(output.Version))
 	c.JSON(http.StatusCreated, output)
 }
 
@@ -68,28 +103,125 @@ func (h *DeviceHandler) GetDevice(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", deviceETag(output.Version))
 	c.JSON(http.StatusOK, output)
 }
 
-// ListDevices handles GET /devices to retrieve all devices.
+// ListDevices handles GET /devices to retrieve a page of devices.
+// It accepts the query parameters status, hardwareIdPrefix, name (substring), online (true or false),
+// lastSeenBefore (RFC 3339), sort, cursor and limit, and conditions on the metadata
+// such as metadata.location.building=Factory-A or metadata.firmware.version>=2.4.
 func (h *DeviceHandler) ListDevices(c *gin.Context) {
-	outputs, err := h.uc.ListDevices(c.Request.Context())
+	input, err := parseListDevicesQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	output, err := h.uc.ListDevices(c.Request.Context(), input)
 	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidDeviceQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+			return
+		}
+
 		log.Printf("failed to list devices: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
 
 		return
 	}
 
-	// Ensure the response is an empty array `[]` instead of `null` if the slice is empty.
-	if outputs == nil {
-		outputs = []*usecase.DeviceOutput{}
+	c.JSON(http.StatusOK, output)
+}
+
+// parseListDevicesQuery reads the filters, the sort order and the page of a device list from the query string.
+func parseListDevicesQuery(c *gin.Context) (usecase.ListDevicesInput, error) {
+	metadata, err := parseMetadataQuery(c.Request.URL.RawQuery)
+	if err != nil {
+		return usecase.ListDevicesInput{}, err //nolint:exhaustruct
+	}
+
+	online, lastSeenBefore, err := parsePresenceQuery(c)
+	if err != nil {
+		return usecase.ListDevicesInput{}, err //nolint:exhaustruct
+	}
+
+	input := usecase.ListDevicesInput{
+		Status:           c.Query("status"),
+		HardwareIDPrefix: c.Query("hardwareIdPrefix"),
+		NameContains:     c.Query("name"),
+		Metadata:         metadata,
+		Online:           online,
+		LastSeenBefore:   lastSeenBefore,
+		Sort:             c.Query("sort"),
+		Cursor:           c.Query("cursor"),
+		Limit:            0,
+	}
+
+	limit := c.Query("limit")
+	if limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed <= 0 {
+			return input, fmt.Errorf("%w: limit must be a positive integer", errInvalidQueryParameter)
+		}
+
+		input.Limit = parsed
+	}
+
+	return input, nil
+}
+
+// parsePresenceQuery reads the optional online and lastSeenBefore filters from the query string.
+func parsePresenceQuery(c *gin.Context) (*bool, *time.Time, error) {
+	var online *bool
+
+	value := c.Query("online")
+	if value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: online must be true or false", errInvalidQueryParameter)
+		}
+
+		online = &parsed
+	}
+
+	lastSeenBefore, err := parseTimeQuery(c, "lastSeenBefore")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return online, lastSeenBefore, nil
+}
+
+// parseMetadataQuery collects the conditions on the metadata from the raw query string, without the "metadata." prefix.
+// They are not read with c.Query, because conditions such as metadata.version>=2.4 or !metadata.location
+// are not key=value pairs.
+func parseMetadataQuery(rawQuery string) ([]string, error) {
+	var conditions []string
+
+	for term := range strings.SplitSeq(rawQuery, "&") {
+		condition, err := url.QueryUnescape(term)
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed query string", errInvalidQueryParameter)
+		}
+
+		negation := ""
+		if strings.HasPrefix(condition, "!") {
+			negation, condition = "!", condition[1:]
+		}
+
+		if strings.HasPrefix(condition, metadataQueryPrefix) {
+			conditions = append(conditions, negation+strings.TrimPrefix(condition, metadataQueryPrefix))
+		}
 	}
 
-	c.JSON(http.StatusOK, outputs)
+	return conditions, nil
 }
 
 // UpdateDevice handles PUT /devices/:id to update a specific device.
+// If the If-Match header does not match the ETag of the device, it responds with 412 Precondition Failed.
 func (h *DeviceHandler) UpdateDevice(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -98,6 +230,11 @@ func (h *DeviceHandler) UpdateDevice(c *gin.Context) {
 		return
 	}
 
+	version, ok := h.expectedVersion(c)
+	if !ok {
+		return
+	}
+
 	var input usecase.UpdateDeviceInput
 
 	err = c.ShouldBindJSON(&input)
@@ -108,25 +245,90 @@ func (h *DeviceHandler) UpdateDevice(c *gin.Context) {
 	}
 
 	input.ID = id // Set the ID from the URL into the input struct.
+	input.ExpectedVersion = version
 
 	output, err := h.uc.UpdateDevice(c.Request.Context(), input)
 	if err != nil {
-		if errors.Is(err, usecase.ErrDBFindByID) || errors.Is(err, entity.ErrDeviceNotFound) {
+		switch {
+		case errors.Is(err, usecase.ErrDBFindByID) || errors.Is(err, entity.ErrDeviceNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrDeviceNotFound.Error()})
-
-			return
+		case errors.Is(err, entity.ErrVersionConflict):
+			writeVersionConflict(c, err)
+		default:
+			log.Printf("failed to update device: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
 		}
 
-		log.Printf("failed to update device: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	c.Header("ETag", deviceETag(output.Version))
+	c.JSON(http.StatusOK, output)
+}
+
+// PatchDevice handles PATCH /devices/:id to partially update a specific device.
+// The body is a JSON Merge Patch (application/merge-patch+json) or a JSON Patch (application/json-patch+json)
+// of the document {"name": ..., "metadata": {...}}. A patch that cannot be applied is reported as 422,
+// and an If-Match header that does not match the ETag of the device as 412.
+func (h *DeviceHandler) PatchDevice(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
 
 		return
 	}
 
+	version, ok := h.expectedVersion(c)
+	if !ok {
+		return
+	}
+
+	format, ok := patchFormats[c.ContentType()]
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error": "Content-Type must be " + mediaTypeMergePatch + " or " + mediaTypeJSONPatch,
+		})
+
+		return
+	}
+
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+
+		return
+	}
+
+	output, err := h.uc.PatchDevice(c.Request.Context(), usecase.PatchDeviceInput{
+		ID:              id,
+		Format:          format,
+		Patch:           patch,
+		ExpectedVersion: version,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrDBFindByID) || errors.Is(err, entity.ErrDeviceNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrDeviceNotFound.Error()})
+		case errors.Is(err, entity.ErrVersionConflict):
+			writeVersionConflict(c, err)
+		case errors.Is(err, entity.ErrMalformedPatch):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, entity.ErrInvalidPatch):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			log.Printf("failed to patch device: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		}
+
+		return
+	}
+
+	c.Header("ETag", deviceETag(output.Version))
 	c.JSON(http.StatusOK, output)
 }
 
 // DeleteDevice handles DELETE /devices/:id to delete a specific device.
+// If the If-Match header does not match the ETag of the device, it responds with 412 Precondition Failed.
 func (h *DeviceHandler) DeleteDevice(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
@@ -135,19 +337,131 @@ func (h *DeviceHandler) DeleteDevice(c *gin.Context) {
 		return
 	}
 
-	err = h.uc.DeleteDevice(c.Request.Context(), id)
+	version, ok := h.expectedVersion(c)
+	if !ok {
+		return
+	}
+
+	err = h.uc.DeleteDevice(c.Request.Context(), usecase.DeleteDeviceInput{ID: id, ExpectedVersion: version})
 	if err != nil {
-		if errors.Is(err, usecase.ErrDBDelete) || errors.Is(err, entity.ErrDeviceNotFound) {
+		switch {
+		// A conflict is also wrapped in ErrDBDelete, so it is checked first.
+		case errors.Is(err, entity.ErrVersionConflict):
+			writeVersionConflict(c, err)
+		case errors.Is(err, usecase.ErrDBDelete) || errors.Is(err, entity.ErrDeviceNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrDeviceNotFound.Error()})
+		default:
+			log.Printf("failed to delete device: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		}
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ActivateDevice handles POST /devices/:id/status/activate to activate a specific device.
+func (h *DeviceHandler) ActivateDevice(c *gin.Context) {
+	h.changeDeviceStatus(c, h.uc.ActivateDevice)
+}
+
+// SuspendDevice handles POST /devices/:id/status/suspend to suspend a specific device.
+func (h *DeviceHandler) SuspendDevice(c *gin.Context) {
+	h.changeDeviceStatus(c, h.uc.SuspendDevice)
+}
+
+// RevokeDevice handles POST /devices/:id/status/revoke to revoke a specific device.
+func (h *DeviceHandler) RevokeDevice(c *gin.Context) {
+	h.changeDeviceStatus(c, h.uc.RevokeDevice)
+}
+
+// changeDeviceStatus applies a status transition and writes the response.
+// An illegal transition is reported as 409 Conflict.
+func (h *DeviceHandler) changeDeviceStatus(c *gin.Context, transition deviceStatusTransition) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+
+		return
+	}
+
+	output, err := transition(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, usecase.ErrDBFindByID) || errors.Is(err, entity.ErrDeviceNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrDeviceNotFound.Error()})
 
 			return
 		}
 
-		log.Printf("failed to delete device: %v", err)
+		var transitionErr *devicestatus.TransitionError
+		if errors.As(err, &transitionErr) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": devicestatus.ErrInvalidTransition.Error(),
+				"from":  transitionErr.From,
+				"to":    transitionErr.To,
+			})
+
+			return
+		}
+
+		log.Printf("failed to change device status: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
 
 		return
 	}
 
-	c.Status(http.StatusNoContent)
+	c.JSON(http.StatusOK, output)
+}
+
+// expectedVersion reads the device version the If-Match header requires; zero means any version.
+// If the request cannot proceed, it writes the response and returns false:
+// 428 if the header is required but missing, and 412 if the header cannot match any ETag of a device.
+func (h *DeviceHandler) expectedVersion(c *gin.Context) (int64, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+
+	switch header {
+	case "":
+		if h.config.RequireIfMatch {
+			c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required"})
+
+			return 0, false
+		}
+
+		return 0, true
+	case "*":
+		return 0, true
+	}
+
+	// If-Match uses the strong comparison, so a weak entity tag (W/"...") never matches.
+	// Only a single entity tag is supported, because a device has a single current version.
+	if len(header) < 2 || !strings.HasPrefix(header, `"`) || !strings.HasSuffix(header, `"`) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "If-Match must be a single strong ETag of the device"})
+
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64)
+	if err != nil || version <= 0 {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": entity.ErrVersionConflict.Error()})
+
+		return 0, false
+	}
+
+	return version, true
+}
+
+// deviceETag returns the entity tag of a version of a device.
+func deviceETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// writeVersionConflict responds with 412 Precondition Failed and the ETag of the current version of the device.
+func writeVersionConflict(c *gin.Context, err error) {
+	var conflictErr *entity.VersionConflictError
+	if errors.As(err, &conflictErr) {
+		c.Header("ETag", deviceETag(conflictErr.Actual))
+	}
+
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": entity.ErrVersionConflict.Error()})
 }
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/internal/domain/entity"
+	"backend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DeviceCommandHandler handles HTTP requests and calls the DeviceCommandUsecase.
+type DeviceCommandHandler struct {
+	uc usecase.DeviceCommandUsecase
+}
+
+// NewDeviceCommandHandler creates a new instance of DeviceCommandHandler.
+func NewDeviceCommandHandler(uc usecase.DeviceCommandUsecase) *DeviceCommandHandler {
+	return &DeviceCommandHandler{uc: uc}
+}
+
+// SendDeviceCommand handles POST /devices/:id/commands to send a command to a device.
+// The body is {"name": "reboot", "payload": {...}, "ttlSeconds": 300}, where only the name is required.
+func (h *DeviceCommandHandler) SendDeviceCommand(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+
+		return
+	}
+
+	var input usecase.SendDeviceCommandInput
+
+	err = c.ShouldBindJSON(&input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+
+		return
+	}
+
+	input.DeviceID = id // Set the ID from the URL into the input struct.
+
+	output, err := h.uc.SendCommand(c.Request.Context(), input)
+	if err != nil {
+		writeDeviceCommandError(c, "failed to send device command", err)
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, output)
+}
+
+// GetDeviceCommand handles GET /devices/:id/commands/:commandId to retrieve the outcome of a command.
+// With ?wait=<seconds>, the request waits up to that long for a pending command to be answered or to expire.
+func (h *DeviceCommandHandler) GetDeviceCommand(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+
+		return
+	}
+
+	commandID, err := uuid.Parse(c.Param("commandId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid command ID"})
+
+		return
+	}
+
+	wait, err := parseWaitQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	output, err := h.uc.GetCommand(c.Request.Context(), usecase.GetDeviceCommandInput{
+		DeviceID:  id,
+		CommandID: commandID,
+		Wait:      wait,
+	})
+	if err != nil {
+		writeDeviceCommandError(c, "failed to get device command", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// parseWaitQuery parses the wait query parameter in seconds. It returns zero if it is not set.
+func parseWaitQuery(c *gin.Context) (time.Duration, error) {
+	value := c.Query("wait")
+	if value == "" {
+		return 0, nil
+	}
+
+	maxSeconds := int(usecase.MaxDeviceCommandWait / time.Second)
+
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 || seconds > maxSeconds {
+		return 0, fmt.Errorf("%w: wait must be between 0 and %d seconds", errInvalidQueryParameter, maxSeconds)
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// writeDeviceCommandError responds with the status of an error of the DeviceCommandUsecase.
+// Unexpected errors are logged with the message and reported as 500 Internal Server Error.
+func writeDeviceCommandError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, entity.ErrDeviceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrDeviceNotFound.Error()})
+	case errors.Is(err, entity.ErrDeviceCommandNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrDeviceCommandNotFound.Error()})
+	case errors.Is(err, entity.ErrInvalidCommandName), errors.Is(err, usecase.ErrInvalidTTL):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrDeviceNotActive):
+		// Only ACTIVE devices can connect, so a command for another device could never be delivered.
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+	}
+}
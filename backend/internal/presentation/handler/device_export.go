@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"backend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deviceExportMethod is the custom method of GET /devices:export.
+const deviceExportMethod = ":export"
+
+// exportContentTypes maps the formats of an export to the media types of the response.
+var exportContentTypes = map[usecase.ExportFormat]string{ //nolint:gochecknoglobals
+	usecase.ExportFormatCSV:    "text/csv; charset=utf-8",
+	usecase.ExportFormatNDJSON: "application/x-ndjson",
+	usecase.ExportFormatJSON:   "application/json; charset=utf-8",
+}
+
+// DeviceExportHandler handles HTTP requests and calls the DeviceExportUsecase.
+type DeviceExportHandler struct {
+	uc usecase.DeviceExportUsecase
+}
+
+// NewDeviceExportHandler creates a new instance of DeviceExportHandler.
+func NewDeviceExportHandler(uc usecase.DeviceExportUsecase) *DeviceExportHandler {
+	return &DeviceExportHandler{uc: uc}
+}
+
+// ExportDevices handles GET /devices:export to stream all the devices matching the filters of GET /devices
+// as CSV, NDJSON or JSON, chosen by the format query parameter (json by default).
+func (h *DeviceExportHandler) ExportDevices(c *gin.Context) {
+	if c.Param("method") != deviceExportMethod {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+
+		return
+	}
+
+	format := usecase.ExportFormat(c.DefaultQuery("format", string(usecase.ExportFormatJSON)))
+
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv, ndjson or json"})
+
+		return
+	}
+
+	metadata, err := parseMetadataQuery(c.Request.URL.RawQuery)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	online, lastSeenBefore, err := parsePresenceQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	input := usecase.ExportDevicesInput{
+		Format:           format,
+		Status:           c.Query("status"),
+		HardwareIDPrefix: c.Query("hardwareIdPrefix"),
+		NameContains:     c.Query("name"),
+		Metadata:         metadata,
+		Online:           online,
+		LastSeenBefore:   lastSeenBefore,
+		Sort:             c.Query("sort"),
+	}
+
+	// The headers are sent with the first device, so they are set before the export starts.
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="devices.`+string(format)+`"`)
+
+	err = h.uc.ExportDevices(c.Request.Context(), input, c.Writer)
+	if err == nil {
+		return
+	}
+
+	if c.Writer.Written() {
+		// The status has been sent, so the failure can only be told by not ending the response properly.
+		log.Printf("failed to export devices after the response started: %v", err)
+		abortResponse(c)
+
+		return
+	}
+
+	// The error is not a file to download.
+	c.Header("Content-Type", "")
+	c.Header("Content-Disposition", "")
+
+	switch {
+	case errors.Is(err, usecase.ErrInvalidDeviceQuery):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("failed to export devices: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+	}
+}
+
+// abortResponse closes the connection of a response that has started, so that the client sees
+// an incomplete response instead of a truncated file. It does nothing if the connection cannot be taken over,
+// e.g. over HTTP/2.
+func abortResponse(c *gin.Context) {
+	// Gin refuses to hijack the connection once the response has been written to,
+	// so the connection is taken over from the underlying writer.
+	var writer http.ResponseWriter = c.Writer
+	if unwrapper, ok := writer.(interface{ Unwrap() http.ResponseWriter }); ok {
+		writer = unwrapper.Unwrap()
+	}
+
+	conn, _, err := http.NewResponseController(writer).Hijack()
+	if err != nil {
+		return
+	}
+
+	_ = conn.Close()
+}
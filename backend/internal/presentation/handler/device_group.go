@@ -0,0 +1,271 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"backend/internal/domain/entity"
+	"backend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DeviceGroupHandler handles HTTP requests and calls the DeviceGroupUsecase.
+type DeviceGroupHandler struct {
+	uc usecase.DeviceGroupUsecase
+}
+
+// NewDeviceGroupHandler creates a new instance of DeviceGroupHandler.
+func NewDeviceGroupHandler(uc usecase.DeviceGroupUsecase) *DeviceGroupHandler {
+	return &DeviceGroupHandler{uc: uc}
+}
+
+// CreateDeviceGroup handles POST /device-groups to create a new device group.
+func (h *DeviceGroupHandler) CreateDeviceGroup(c *gin.Context) {
+	var input usecase.CreateDeviceGroupInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+
+		return
+	}
+
+	output, err := h.uc.CreateDeviceGroup(c.Request.Context(), input)
+	if err != nil {
+		writeDeviceGroupError(c, "failed to create device group", err)
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, output)
+}
+
+// ListDeviceGroups handles GET /device-groups to list all the device groups.
+func (h *DeviceGroupHandler) ListDeviceGroups(c *gin.Context) {
+	outputs, err := h.uc.ListDeviceGroups(c.Request.Context())
+	if err != nil {
+		log.Printf("failed to list device groups: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, outputs)
+}
+
+// GetDeviceGroup handles GET /device-groups/:id to retrieve a specific device group.
+func (h *DeviceGroupHandler) GetDeviceGroup(c *gin.Context) {
+	id, ok := parseDeviceGroupID(c)
+	if !ok {
+		return
+	}
+
+	output, err := h.uc.GetDeviceGroup(c.Request.Context(), id)
+	if err != nil {
+		writeDeviceGroupError(c, "failed to get device group", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// UpdateDeviceGroup handles PUT /device-groups/:id to update the name, description or rule of a device group.
+// Omitted fields are left as they are.
+func (h *DeviceGroupHandler) UpdateDeviceGroup(c *gin.Context) {
+	id, ok := parseDeviceGroupID(c)
+	if !ok {
+		return
+	}
+
+	var input usecase.UpdateDeviceGroupInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+
+		return
+	}
+
+	input.ID = id // Set the ID from the URL into the input struct.
+
+	output, err := h.uc.UpdateDeviceGroup(c.Request.Context(), input)
+	if err != nil {
+		writeDeviceGroupError(c, "failed to update device group", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// DeleteDeviceGroup handles DELETE /device-groups/:id to delete a device group. Its devices are not deleted.
+func (h *DeviceGroupHandler) DeleteDeviceGroup(c *gin.Context) {
+	id, ok := parseDeviceGroupID(c)
+	if !ok {
+		return
+	}
+
+	err := h.uc.DeleteDeviceGroup(c.Request.Context(), id)
+	if err != nil {
+		writeDeviceGroupError(c, "failed to delete device group", err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AddDeviceGroupMembers handles POST /device-groups/:id/members to add devices to a device group.
+// If any of the devices does not exist, none is added and it responds with 422 Unprocessable Entity.
+func (h *DeviceGroupHandler) AddDeviceGroupMembers(c *gin.Context) {
+	id, ok := parseDeviceGroupID(c)
+	if !ok {
+		return
+	}
+
+	var input usecase.AddDeviceGroupMembersInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+
+		return
+	}
+
+	input.GroupID = id
+
+	output, err := h.uc.AddDeviceGroupMembers(c.Request.Context(), input)
+	if err != nil {
+		writeDeviceGroupError(c, "failed to add device group members", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// RemoveDeviceGroupMember handles DELETE /device-groups/:id/members/:deviceId to remove a device
+// that has been added to a device group.
+func (h *DeviceGroupHandler) RemoveDeviceGroupMember(c *gin.Context) {
+	id, ok := parseDeviceGroupID(c)
+	if !ok {
+		return
+	}
+
+	deviceID, err := uuid.Parse(c.Param("deviceId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+
+		return
+	}
+
+	err = h.uc.RemoveDeviceGroupMember(c.Request.Context(), usecase.RemoveDeviceGroupMemberInput{
+		GroupID:  id,
+		DeviceID: deviceID,
+	})
+	if err != nil {
+		writeDeviceGroupError(c, "failed to remove device group member", err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeviceGroupDevices handles GET /device-groups/:id/devices to retrieve a page of the members of a group,
+// both added and matching its rule. It accepts the query parameters of GET /devices.
+func (h *DeviceGroupHandler) ListDeviceGroupDevices(c *gin.Context) {
+	id, ok := parseDeviceGroupID(c)
+	if !ok {
+		return
+	}
+
+	input, err := parseListDevicesQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	output, err := h.uc.ListDeviceGroupDevices(c.Request.Context(), id, input)
+	if err != nil {
+		writeDeviceGroupError(c, "failed to list device group devices", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// ApplyDeviceGroupAction handles POST /device-groups/:id/actions/:action to apply suspend, revoke-certificates
+// or patch-metadata to every member of a group. The body carries the parameters of the action, such as
+// {"reason": "keyCompromise"} or {"metadata": {...}}. It responds with the outcome even if some members failed.
+func (h *DeviceGroupHandler) ApplyDeviceGroupAction(c *gin.Context) {
+	id, ok := parseDeviceGroupID(c)
+	if !ok {
+		return
+	}
+
+	var input usecase.ApplyDeviceGroupActionInput
+
+	// The body is optional, as suspend has no parameters.
+	err := c.ShouldBindJSON(&input)
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+
+		return
+	}
+
+	input.GroupID = id
+	input.Action = usecase.DeviceGroupAction(c.Param("action"))
+
+	output, err := h.uc.ApplyDeviceGroupAction(c.Request.Context(), input)
+	if err != nil {
+		writeDeviceGroupError(c, "failed to apply device group action", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// parseDeviceGroupID reads the ID of the device group from the path.
+// If it is invalid, it responds with 400 Bad Request and returns false.
+func parseDeviceGroupID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device group ID"})
+
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
+
+// writeDeviceGroupError responds with the status of an error of the DeviceGroupUsecase.
+// Unexpected errors are logged with the message and reported as 500 Internal Server Error.
+func writeDeviceGroupError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, entity.ErrDeviceGroupNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrDeviceGroupNotFound.Error()})
+	case errors.Is(err, entity.ErrDeviceGroupMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrDeviceGroupMemberNotFound.Error()})
+	case errors.Is(err, entity.ErrDeviceGroupNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": entity.ErrDeviceGroupNameTaken.Error()})
+	case errors.Is(err, entity.ErrDeviceNotFound):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": entity.ErrDeviceNotFound.Error()})
+	case errors.Is(err, entity.ErrDeviceGroupNameEmpty),
+		errors.Is(err, usecase.ErrInvalidDeviceGroupRule),
+		errors.Is(err, usecase.ErrInvalidDeviceGroupAction),
+		errors.Is(err, usecase.ErrInvalidDeviceQuery):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+	}
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"backend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deviceImportMethod is the custom method of POST /devices:import.
+// Gin cannot route a colon inside a path segment, so the route is /devices:method and the handler checks the method.
+const deviceImportMethod = ":import"
+
+// maxDeviceImportBodyBytes is the maximum size of an import file.
+const maxDeviceImportBodyBytes = 32 << 20
+
+// importFormats maps the media types of the import files to their formats.
+var importFormats = map[string]usecase.ImportFormat{ //nolint:gochecknoglobals
+	"text/csv":             usecase.ImportFormatCSV,
+	"application/x-ndjson": usecase.ImportFormatNDJSON,
+	"application/jsonl":    usecase.ImportFormatNDJSON,
+}
+
+// DeviceImportHandler handles HTTP requests and calls the DeviceImportUsecase.
+type DeviceImportHandler struct {
+	uc usecase.DeviceImportUsecase
+}
+
+// NewDeviceImportHandler creates a new instance of DeviceImportHandler.
+func NewDeviceImportHandler(uc usecase.DeviceImportUsecase) *DeviceImportHandler {
+	return &DeviceImportHandler{uc: uc}
+}
+
+// ImportDevices handles POST /devices:import to create devices from a CSV (text/csv)
+// or NDJSON (application/x-ndjson) file, and responds with the outcome of each row.
+// It accepts the query parameters dryRun, enrollmentTokens and enrollmentTokenTtlSeconds.
+func (h *DeviceImportHandler) ImportDevices(c *gin.Context) {
+	if c.Param("method") != deviceImportMethod {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+
+		return
+	}
+
+	format, ok := importFormats[c.ContentType()]
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be text/csv or application/x-ndjson"})
+
+		return
+	}
+
+	input, err := parseImportDevicesQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	input.Format = format
+	input.Data = http.MaxBytesReader(c.Writer, c.Request.Body, maxDeviceImportBodyBytes)
+
+	output, err := h.uc.ImportDevices(c.Request.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidDeviceImport), errors.Is(err, usecase.ErrInvalidTTL):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Printf("failed to import devices: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		}
+
+		return
+	}
+
+	if input.CreateEnrollmentTokens {
+		// The secrets must not be kept by intermediaries.
+		c.Header("Cache-Control", "no-store")
+	}
+
+	status := http.StatusCreated
+	if output.DryRun {
+		status = http.StatusOK
+	}
+
+	c.JSON(status, output)
+}
+
+// parseImportDevicesQuery reads the options of an import from the query string.
+func parseImportDevicesQuery(c *gin.Context) (usecase.ImportDevicesInput, error) {
+	input := usecase.ImportDevicesInput{} //nolint:exhaustruct
+
+	var err error
+
+	dryRun := c.Query("dryRun")
+	if dryRun != "" {
+		input.DryRun, err = strconv.ParseBool(dryRun)
+		if err != nil {
+			return input, fmt.Errorf("%w: dryRun must be true or false", errInvalidQueryParameter)
+		}
+	}
+
+	enrollmentTokens := c.Query("enrollmentTokens")
+	if enrollmentTokens != "" {
+		input.CreateEnrollmentTokens, err = strconv.ParseBool(enrollmentTokens)
+		if err != nil {
+			return input, fmt.Errorf("%w: enrollmentTokens must be true or false", errInvalidQueryParameter)
+		}
+	}
+
+	ttlSeconds := c.Query("enrollmentTokenTtlSeconds")
+	if ttlSeconds != "" {
+		input.EnrollmentTokenTTLSeconds, err = strconv.ParseInt(ttlSeconds, 10, 64)
+		if err != nil {
+			return input, fmt.Errorf("%w: enrollmentTokenTtlSeconds must be an integer", errInvalidQueryParameter)
+		}
+	}
+
+	return input, nil
+}
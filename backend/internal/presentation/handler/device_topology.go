@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"backend/internal/domain/entity"
+	"backend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DeviceTopologyHandler handles HTTP requests and calls the DeviceTopologyUsecase.
+type DeviceTopologyHandler struct {
+	uc usecase.DeviceTopologyUsecase
+}
+
+// NewDeviceTopologyHandler creates a new instance of DeviceTopologyHandler.
+func NewDeviceTopologyHandler(uc usecase.DeviceTopologyUsecase) *DeviceTopologyHandler {
+	return &DeviceTopologyHandler{uc: uc}
+}
+
+// AttachChildDevice handles POST /devices/:id/children to attach a device to a parent, such as a gateway.
+// The body is {"deviceId": "..."}. A device that is the child of another parent must be detached first.
+func (h *DeviceTopologyHandler) AttachChildDevice(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+
+		return
+	}
+
+	var input usecase.AttachChildDeviceInput
+
+	err = c.ShouldBindJSON(&input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+
+		return
+	}
+
+	input.ParentID = id
+
+	output, err := h.uc.AttachChildDevice(c.Request.Context(), input)
+	if err != nil {
+		writeDeviceTopologyError(c, "failed to attach child device", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// DetachChildDevice handles DELETE /devices/:id/children/:childId to detach a device from its parent.
+func (h *DeviceTopologyHandler) DetachChildDevice(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+
+		return
+	}
+
+	childID, err := uuid.Parse(c.Param("childId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid child device ID"})
+
+		return
+	}
+
+	err = h.uc.DetachChildDevice(c.Request.Context(), usecase.DetachChildDeviceInput{ParentID: id, DeviceID: childID})
+	if err != nil {
+		writeDeviceTopologyError(c, "failed to detach child device", err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListChildDevices handles GET /devices/:id/children to retrieve a page of the children of a device.
+// It accepts the query parameters of GET /devices.
+func (h *DeviceTopologyHandler) ListChildDevices(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+
+		return
+	}
+
+	input, err := parseListDevicesQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	output, err := h.uc.ListChildDevices(c.Request.Context(), id, input)
+	if err != nil {
+		writeDeviceTopologyError(c, "failed to list child devices", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// ListAncestorDevices handles GET /devices/:id/ancestors to retrieve the ancestors of a device,
+// from its parent up to the root of its tree.
+func (h *DeviceTopologyHandler) ListAncestorDevices(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+
+		return
+	}
+
+	outputs, err := h.uc.ListAncestorDevices(c.Request.Context(), id)
+	if err != nil {
+		writeDeviceTopologyError(c, "failed to list ancestor devices", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, outputs)
+}
+
+// writeDeviceTopologyError responds with the status of an error of the DeviceTopologyUsecase.
+// Unexpected errors are logged with the message and reported as 500 Internal Server Error.
+func writeDeviceTopologyError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, entity.ErrDeviceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrDeviceNotFound.Error()})
+	case errors.Is(err, entity.ErrDeviceNotChild):
+		c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrDeviceNotChild.Error()})
+	case errors.Is(err, usecase.ErrChildDeviceNotFound):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": usecase.ErrChildDeviceNotFound.Error()})
+	case errors.Is(err, entity.ErrDeviceTopologyCycle), errors.Is(err, entity.ErrDeviceAlreadyAttached):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, entity.ErrVersionConflict):
+		// The child has been changed while it was being attached or detached, so the request can be retried.
+		c.JSON(http.StatusConflict, gin.H{"error": entity.ErrVersionConflict.Error()})
+	case errors.Is(err, usecase.ErrInvalidDeviceQuery):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+	}
+}
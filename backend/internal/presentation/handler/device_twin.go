@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"backend/internal/domain/entity"
+	"backend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DeviceTwinHandler handles HTTP requests and calls the DeviceTwinUsecase.
+type DeviceTwinHandler struct {
+	uc usecase.DeviceTwinUsecase
+}
+
+// NewDeviceTwinHandler creates a new instance of DeviceTwinHandler.
+func NewDeviceTwinHandler(uc usecase.DeviceTwinUsecase) *DeviceTwinHandler {
+	return &DeviceTwinHandler{uc: uc}
+}
+
+// GetDeviceTwin handles GET /devices/:id/twin to retrieve the desired and reported states of a device,
+// with the delta between them.
+func (h *DeviceTwinHandler) GetDeviceTwin(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+
+		return
+	}
+
+	output, err := h.uc.GetDeviceTwin(c.Request.Context(), id)
+	if err != nil {
+		writeDeviceTwinError(c, "failed to get device twin", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// SetDesiredState handles PUT /devices/:id/twin/desired to replace the desired state of a device.
+// The body is the new state, a JSON object.
+func (h *DeviceTwinHandler) SetDesiredState(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+
+		return
+	}
+
+	state, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+
+		return
+	}
+
+	output, err := h.uc.SetDesiredState(c.Request.Context(), usecase.SetDesiredStateInput{DeviceID: id, State: state})
+	if err != nil {
+		writeDeviceTwinError(c, "failed to set desired state", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// PatchDesiredState handles PATCH /devices/:id/twin/desired to partially update the desired state of a device.
+// The body is a JSON Merge Patch (application/merge-patch+json) or a JSON Patch (application/json-patch+json)
+// of the state. A patch that cannot be applied is reported as 422.
+func (h *DeviceTwinHandler) PatchDesiredState(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+
+		return
+	}
+
+	format, ok := patchFormats[c.ContentType()]
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error": "Content-Type must be " + mediaTypeMergePatch + " or " + mediaTypeJSONPatch,
+		})
+
+		return
+	}
+
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+
+		return
+	}
+
+	output, err := h.uc.PatchDesiredState(c.Request.Context(), usecase.PatchDesiredStateInput{
+		DeviceID: id,
+		Format:   format,
+		Patch:    patch,
+	})
+	if err != nil {
+		writeDeviceTwinError(c, "failed to patch desired state", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// writeDeviceTwinError responds with the status of an error of the DeviceTwinUsecase.
+// Unexpected errors are logged with the message and reported as 500 Internal Server Error.
+func writeDeviceTwinError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, entity.ErrDeviceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrDeviceNotFound.Error()})
+	case errors.Is(err, entity.ErrDeviceTwinVersionConflict):
+		// The desired state has been changed by another request meanwhile, so the request can be retried.
+		c.JSON(http.StatusConflict, gin.H{"error": entity.ErrDeviceTwinVersionConflict.Error()})
+	case errors.Is(err, entity.ErrInvalidTwinState), errors.Is(err, entity.ErrMalformedPatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, entity.ErrInvalidPatch):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+	}
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"backend/internal/domain/entity"
+	"backend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// EnrollmentTokenHandler handles HTTP requests and calls the EnrollmentTokenUsecase.
+type EnrollmentTokenHandler struct {
+	uc usecase.EnrollmentTokenUsecase
+}
+
+// NewEnrollmentTokenHandler creates a new instance of EnrollmentTokenHandler.
+func NewEnrollmentTokenHandler(uc usecase.EnrollmentTokenUsecase) *EnrollmentTokenHandler {
+	return &EnrollmentTokenHandler{uc: uc}
+}
+
+// CreateEnrollmentToken handles POST /devices/:id/enrollment-tokens to issue a one-time token.
+// The plaintext token is included in this response only.
+func (h *EnrollmentTokenHandler) CreateEnrollmentToken(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+
+		return
+	}
+
+	var input usecase.CreateEnrollmentTokenInput
+
+	// The body is optional, as every field has a default.
+	err = c.ShouldBindJSON(&input)
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+
+		return
+	}
+
+	input.DeviceID = id // Set the ID from the URL into the input struct.
+
+	output, err := h.uc.CreateEnrollmentToken(c.Request.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrDeviceNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrDeviceNotFound.Error()})
+		case errors.Is(err, usecase.ErrInvalidTTL):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, usecase.ErrDeviceNotEnrollable):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("failed to create enrollment token: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		}
+
+		return
+	}
+
+	// The secret must not be kept by intermediaries.
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, output)
+}
+
+// ListEnrollmentTokens handles GET /devices/:id/enrollment-tokens to list the tokens of a device.
+func (h *EnrollmentTokenHandler) ListEnrollmentTokens(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+
+		return
+	}
+
+	outputs, err := h.uc.ListEnrollmentTokens(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, entity.ErrDeviceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrDeviceNotFound.Error()})
+
+			return
+		}
+
+		log.Printf("failed to list enrollment tokens: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, outputs)
+}
+
+// RevokeEnrollmentToken handles POST /devices/:id/enrollment-tokens/:tokenId/revoke to revoke a token.
+func (h *EnrollmentTokenHandler) RevokeEnrollmentToken(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+
+		return
+	}
+
+	tokenID, err := uuid.Parse(c.Param("tokenId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid enrollment token ID"})
+
+		return
+	}
+
+	output, err := h.uc.RevokeEnrollmentToken(c.Request.Context(), id, tokenID)
+	if err != nil {
+		switch {
+		case errors.Is(err, entity.ErrEnrollmentTokenNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrEnrollmentTokenNotFound.Error()})
+		case errors.Is(err, entity.ErrEnrollmentTokenUsed), errors.Is(err, entity.ErrEnrollmentTokenRevoked):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("failed to revoke enrollment token: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		}
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/service"
+	"backend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxFirmwareImageBytes is the maximum size of an uploaded firmware image.
+const maxFirmwareImageBytes = 256 << 20
+
+// FirmwareHandler handles HTTP requests and calls the FirmwareUsecase.
+type FirmwareHandler struct {
+	uc usecase.FirmwareUsecase
+}
+
+// NewFirmwareHandler creates a new instance of FirmwareHandler.
+func NewFirmwareHandler(uc usecase.FirmwareUsecase) *FirmwareHandler {
+	return &FirmwareHandler{uc: uc}
+}
+
+// UploadFirmware handles POST /firmware?version=1.2.0&description=... to upload a firmware image.
+// The body is the image itself, e.g. as application/octet-stream, and the response holds its hash and signature.
+// The query can also hold the lowest hardware revision the firmware runs on, e.g. minHardwareRevision=3,
+// and conditions on the metadata of the devices it is meant for, as in the device list, e.g. metadata.model=X100.
+func (h *FirmwareHandler) UploadFirmware(c *gin.Context) {
+	input, err := parseUploadFirmwareQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	input.Image = http.MaxBytesReader(c.Writer, c.Request.Body, maxFirmwareImageBytes)
+
+	output, err := h.uc.UploadFirmware(c.Request.Context(), input)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "firmware image is too large"})
+
+			return
+		}
+
+		writeFirmwareError(c, "failed to upload firmware", err)
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, output)
+}
+
+// ListFirmware handles GET /firmware to list all the firmware artifacts, newest first.
+func (h *FirmwareHandler) ListFirmware(c *gin.Context) {
+	outputs, err := h.uc.ListFirmware(c.Request.Context())
+	if err != nil {
+		log.Printf("failed to list firmware: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, outputs)
+}
+
+// GetFirmware handles GET /firmware/:id to retrieve a specific firmware artifact.
+func (h *FirmwareHandler) GetFirmware(c *gin.Context) {
+	id, ok := parseFirmwareID(c)
+	if !ok {
+		return
+	}
+
+	output, err := h.uc.GetFirmware(c.Request.Context(), id)
+	if err != nil {
+		writeFirmwareError(c, "failed to get firmware", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// DownloadFirmwareImage handles GET /firmware/:id/image to download the image of a firmware artifact.
+// Range requests are supported, so that devices can resume interrupted downloads.
+func (h *FirmwareHandler) DownloadFirmwareImage(c *gin.Context) {
+	id, ok := parseFirmwareID(c)
+	if !ok {
+		return
+	}
+
+	output, err := h.uc.OpenFirmwareImage(c.Request.Context(), id)
+	if err != nil {
+		writeFirmwareError(c, "failed to open firmware image", err)
+
+		return
+	}
+
+	defer func() { _ = output.Image.Close() }()
+
+	filename := "firmware-" + output.Firmware.Version + ".bin"
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	// The image of an artifact never changes, so its hash identifies the content.
+	c.Header("ETag", `"`+output.Firmware.SHA256+`"`)
+	http.ServeContent(c.Writer, c.Request, filename, output.Firmware.CreatedAt, output.Image)
+}
+
+// DeleteFirmware handles DELETE /firmware/:id to delete a firmware artifact that no campaign rolls out.
+func (h *FirmwareHandler) DeleteFirmware(c *gin.Context) {
+	id, ok := parseFirmwareID(c)
+	if !ok {
+		return
+	}
+
+	err := h.uc.DeleteFirmware(c.Request.Context(), id)
+	if err != nil {
+		writeFirmwareError(c, "failed to delete firmware", err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// parseUploadFirmwareQuery reads the description of an uploaded firmware image from the query.
+func parseUploadFirmwareQuery(c *gin.Context) (usecase.UploadFirmwareInput, error) {
+	target, err := parseMetadataQuery(c.Request.URL.RawQuery)
+	if err != nil {
+		return usecase.UploadFirmwareInput{}, err //nolint:exhaustruct
+	}
+
+	input := usecase.UploadFirmwareInput{
+		Version:             c.Query("version"),
+		Description:         c.Query("description"),
+		MinHardwareRevision: 0,
+		Target:              target,
+		Image:               nil,
+	}
+
+	minHardwareRevision := c.Query("minHardwareRevision")
+	if minHardwareRevision != "" {
+		input.MinHardwareRevision, err = strconv.Atoi(minHardwareRevision)
+		if err != nil || input.MinHardwareRevision < 0 {
+			return input, fmt.Errorf("%w: minHardwareRevision must be a non-negative integer", errInvalidQueryParameter)
+		}
+	}
+
+	return input, nil
+}
+
+// parseFirmwareID parses the firmware artifact ID in the URL, and responds with 400 Bad Request if it is invalid.
+func parseFirmwareID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid firmware ID"})
+
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
+
+// writeFirmwareError responds with the status of an error of the FirmwareUsecase.
+// Unexpected errors are logged with the message and reported as 500 Internal Server Error.
+func writeFirmwareError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, entity.ErrFirmwareArtifactNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrFirmwareArtifactNotFound.Error()})
+	case errors.Is(err, service.ErrFirmwareImageNotFound):
+		// The artifact exists, but its image has been lost from the storage.
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": service.ErrFirmwareImageNotFound.Error()})
+	case errors.Is(err, entity.ErrFirmwareVersionTaken), errors.Is(err, entity.ErrFirmwareArtifactInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, entity.ErrInvalidFirmwareVersion), errors.Is(err, usecase.ErrEmptyFirmwareImage),
+		errors.Is(err, entity.ErrInvalidHardwareRevision), errors.Is(err, usecase.ErrInvalidFirmwareTarget):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+	}
+}
@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"backend/internal/domain/VO/campaignstatus"
+	"backend/internal/domain/entity"
+	"backend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// FirmwareCampaignHandler handles HTTP requests and calls the FirmwareCampaignUsecase.
+type FirmwareCampaignHandler struct {
+	uc usecase.FirmwareCampaignUsecase
+}
+
+// NewFirmwareCampaignHandler creates a new instance of FirmwareCampaignHandler.
+func NewFirmwareCampaignHandler(uc usecase.FirmwareCampaignUsecase) *FirmwareCampaignHandler {
+	return &FirmwareCampaignHandler{uc: uc}
+}
+
+// CreateFirmwareCampaign handles POST /firmware-campaigns to roll out a firmware artifact in waves.
+// The body is {"name": ..., "firmwareId": ..., "filter": ["location.building=Factory-A"], "waveSize": 50,
+// "failureThreshold": 10, "updateTimeoutSeconds": 3600}, and the first wave is sent right away.
+func (h *FirmwareCampaignHandler) CreateFirmwareCampaign(c *gin.Context) {
+	var input usecase.CreateFirmwareCampaignInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+
+		return
+	}
+
+	output, err := h.uc.CreateFirmwareCampaign(c.Request.Context(), input)
+	if err != nil {
+		writeFirmwareCampaignError(c, "failed to create firmware campaign", err)
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, output)
+}
+
+// ListFirmwareCampaigns handles GET /firmware-campaigns to list all the campaigns, newest first.
+func (h *FirmwareCampaignHandler) ListFirmwareCampaigns(c *gin.Context) {
+	outputs, err := h.uc.ListFirmwareCampaigns(c.Request.Context())
+	if err != nil {
+		log.Printf("failed to list firmware campaigns: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, outputs)
+}
+
+// GetFirmwareCampaign handles GET /firmware-campaigns/:id to retrieve a campaign and the number of its devices
+// in each update status.
+func (h *FirmwareCampaignHandler) GetFirmwareCampaign(c *gin.Context) {
+	id, ok := parseFirmwareCampaignID(c)
+	if !ok {
+		return
+	}
+
+	output, err := h.uc.GetFirmwareCampaign(c.Request.Context(), id)
+	if err != nil {
+		writeFirmwareCampaignError(c, "failed to get firmware campaign", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// ListFirmwareCampaignDevices handles GET /firmware-campaigns/:id/devices to retrieve the update of each device
+// of a campaign. With ?status=FAILED, only the updates with that status are listed.
+func (h *FirmwareCampaignHandler) ListFirmwareCampaignDevices(c *gin.Context) {
+	id, ok := parseFirmwareCampaignID(c)
+	if !ok {
+		return
+	}
+
+	outputs, err := h.uc.ListFirmwareCampaignDevices(c.Request.Context(), usecase.ListFirmwareCampaignDevicesInput{
+		CampaignID: id,
+		Status:     c.Query("status"),
+	})
+	if err != nil {
+		writeFirmwareCampaignError(c, "failed to list firmware campaign devices", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, outputs)
+}
+
+// PauseFirmwareCampaign handles POST /firmware-campaigns/:id/pause to stop a campaign from starting further waves.
+func (h *FirmwareCampaignHandler) PauseFirmwareCampaign(c *gin.Context) {
+	id, ok := parseFirmwareCampaignID(c)
+	if !ok {
+		return
+	}
+
+	output, err := h.uc.PauseFirmwareCampaign(c.Request.Context(), id)
+	if err != nil {
+		writeFirmwareCampaignError(c, "failed to pause firmware campaign", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// ResumeFirmwareCampaign handles POST /firmware-campaigns/:id/resume to resume a paused campaign.
+// The body is optional, and may raise the threshold of a campaign paused for its failures,
+// as in {"failureThreshold": 20}.
+func (h *FirmwareCampaignHandler) ResumeFirmwareCampaign(c *gin.Context) {
+	id, ok := parseFirmwareCampaignID(c)
+	if !ok {
+		return
+	}
+
+	var input usecase.ResumeFirmwareCampaignInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+
+		return
+	}
+
+	input.ID = id // Set the ID from the URL into the input struct.
+
+	output, err := h.uc.ResumeFirmwareCampaign(c.Request.Context(), input)
+	if err != nil {
+		writeFirmwareCampaignError(c, "failed to resume firmware campaign", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// CancelFirmwareCampaign handles POST /firmware-campaigns/:id/cancel to stop a campaign for good.
+func (h *FirmwareCampaignHandler) CancelFirmwareCampaign(c *gin.Context) {
+	id, ok := parseFirmwareCampaignID(c)
+	if !ok {
+		return
+	}
+
+	output, err := h.uc.CancelFirmwareCampaign(c.Request.Context(), id)
+	if err != nil {
+		writeFirmwareCampaignError(c, "failed to cancel firmware campaign", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// parseFirmwareCampaignID reads the ID of the firmware campaign from the path.
+// If it is invalid, it responds with 400 Bad Request and returns false.
+func parseFirmwareCampaignID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid firmware campaign ID"})
+
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
+
+// writeFirmwareCampaignError responds with the status of an error of the FirmwareCampaignUsecase.
+// Unexpected errors are logged with the message and reported as 500 Internal Server Error.
+func writeFirmwareCampaignError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, entity.ErrFirmwareCampaignNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrFirmwareCampaignNotFound.Error()})
+	case errors.Is(err, entity.ErrFirmwareArtifactNotFound):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": entity.ErrFirmwareArtifactNotFound.Error()})
+	case errors.Is(err, usecase.ErrNoFirmwareCampaignDevices):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, campaignstatus.ErrInvalidTransition), errors.Is(err, entity.ErrFirmwareCampaignConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, entity.ErrFirmwareCampaignNameEmpty),
+		errors.Is(err, entity.ErrInvalidWaveSize),
+		errors.Is(err, entity.ErrInvalidFailureThreshold),
+		errors.Is(err, entity.ErrFirmwareUpdateTimeoutInvalid),
+		errors.Is(err, usecase.ErrInvalidFirmwareCampaignFilter),
+		errors.Is(err, usecase.ErrInvalidFirmwareUpdateFilter):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+	}
+}
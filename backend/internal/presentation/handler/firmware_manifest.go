@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/service"
+	"backend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FirmwareManifestHandler handles HTTP requests and calls the FirmwareManifestUsecase.
+type FirmwareManifestHandler struct {
+	uc usecase.FirmwareManifestUsecase
+}
+
+// NewFirmwareManifestHandler creates a new instance of FirmwareManifestHandler.
+func NewFirmwareManifestHandler(uc usecase.FirmwareManifestUsecase) *FirmwareManifestHandler {
+	return &FirmwareManifestHandler{uc: uc}
+}
+
+// GetFirmwareManifest handles GET /firmware/:id/manifest to retrieve the signed manifest of a firmware artifact.
+func (h *FirmwareManifestHandler) GetFirmwareManifest(c *gin.Context) {
+	id, ok := parseFirmwareID(c)
+	if !ok {
+		return
+	}
+
+	output, err := h.uc.GetFirmwareManifest(c.Request.Context(), id)
+	if err != nil {
+		writeFirmwareManifestError(c, "failed to get firmware manifest", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
+
+// ListFirmwareSigningKeys handles GET /firmware-signing-keys to list the public keys that devices verify
+// the manifests with: the active key and the retired keys still in their grace period.
+func (h *FirmwareManifestHandler) ListFirmwareSigningKeys(c *gin.Context) {
+	outputs, err := h.uc.ListFirmwareSigningKeys(c.Request.Context())
+	if err != nil {
+		writeFirmwareManifestError(c, "failed to list firmware signing keys", err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, outputs)
+}
+
+// RotateFirmwareSigningKey handles POST /firmware-signing-keys/rotate to replace the active key with a new one.
+// The body is optional: {"algorithm": "ECDSA-P256-SHA256", "gracePeriodSeconds": 86400}.
+func (h *FirmwareManifestHandler) RotateFirmwareSigningKey(c *gin.Context) {
+	var input usecase.RotateFirmwareSigningKeyInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+
+		return
+	}
+
+	output, err := h.uc.RotateFirmwareSigningKey(c.Request.Context(), input)
+	if err != nil {
+		writeFirmwareManifestError(c, "failed to rotate firmware signing key", err)
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, output)
+}
+
+// writeFirmwareManifestError responds with the status of an error of the FirmwareManifestUsecase.
+// Unexpected errors are logged with the message and reported as 500 Internal Server Error.
+func writeFirmwareManifestError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, entity.ErrFirmwareArtifactNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrFirmwareArtifactNotFound.Error()})
+	case errors.Is(err, entity.ErrFirmwareManifestNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrFirmwareManifestNotFound.Error()})
+	case errors.Is(err, service.ErrUnsupportedKeyAlgorithm), errors.Is(err, entity.ErrInvalidGracePeriod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, entity.ErrFirmwareSigningKeyConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+	}
+}
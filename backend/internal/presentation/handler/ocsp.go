@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"backend/internal/domain/service"
+	"backend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ocsp"
+)
+
+// maxOCSPRequestSize bounds the size of an OCSP request body. Single-certificate requests are far smaller.
+const maxOCSPRequestSize = 16 * 1024
+
+// OCSPHandler handles HTTP requests and calls the OCSPUsecase.
+type OCSPHandler struct {
+	uc usecase.OCSPUsecase
+}
+
+// NewOCSPHandler creates a new instance of OCSPHandler.
+func NewOCSPHandler(uc usecase.OCSPUsecase) *OCSPHandler {
+	return &OCSPHandler{uc: uc}
+}
+
+// HandleGet handles GET /ocsp/{request}, where the request is the base64-encoded DER (RFC 6960, Appendix A.1).
+func (h *OCSPHandler) HandleGet(c *gin.Context) {
+	// The base64 alphabet includes '/', so the request is captured by a wildcard parameter.
+	encoded := strings.TrimPrefix(c.Param("request"), "/")
+
+	der, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		h.write(c, ocsp.MalformedRequestErrorResponse)
+
+		return
+	}
+
+	h.respond(c, der)
+}
+
+// HandlePost handles POST /ocsp, where the body is the DER-encoded request (RFC 6960, Appendix A.1).
+func (h *OCSPHandler) HandlePost(c *gin.Context) {
+	der, err := io.ReadAll(io.LimitReader(c.Request.Body, maxOCSPRequestSize+1))
+	if err != nil || len(der) > maxOCSPRequestSize {
+		h.write(c, ocsp.MalformedRequestErrorResponse)
+
+		return
+	}
+
+	h.respond(c, der)
+}
+
+// respond answers the request. Errors are reported as unsigned OCSP error responses, as clients expect.
+func (h *OCSPHandler) respond(c *gin.Context, der []byte) {
+	response, err := h.uc.Respond(c.Request.Context(), der)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrMalformedOCSPRequest):
+			response = ocsp.MalformedRequestErrorResponse
+		case errors.Is(err, service.ErrOCSPUnauthorized):
+			response = ocsp.UnauthorizedErrorResponse
+		default:
+			log.Printf("failed to answer OCSP request: %v", err)
+
+			response = ocsp.InternalErrorErrorResponse
+		}
+	}
+
+	h.write(c, response)
+}
+
+func (h *OCSPHandler) write(c *gin.Context, response []byte) {
+	// Revocations take effect immediately, so intermediaries must not serve stale responses.
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "application/ocsp-response", response)
+}
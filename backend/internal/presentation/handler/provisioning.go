@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/service"
+	"backend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProvisioningHandler handles HTTP requests and calls the ProvisioningUsecase.
+type ProvisioningHandler struct {
+	uc usecase.ProvisioningUsecase
+}
+
+// NewProvisioningHandler creates a new instance of ProvisioningHandler.
+func NewProvisioningHandler(uc usecase.ProvisioningUsecase) *ProvisioningHandler {
+	return &ProvisioningHandler{uc: uc}
+}
+
+// Provision handles POST /api/provision to issue a client certificate to a device.
+func (h *ProvisioningHandler) Provision(c *gin.Context) {
+	var input usecase.ProvisionInput
+
+	err := c.ShouldBindJSON(&input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+
+		return
+	}
+
+	output, err := h.uc.Provision(c.Request.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidEnrollmentToken),
+			errors.Is(err, entity.ErrEnrollmentTokenExpired),
+			errors.Is(err, entity.ErrEnrollmentTokenUsed),
+			errors.Is(err, entity.ErrEnrollmentTokenRevoked):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidCSR):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, devicestatus.ErrInvalidTransition), errors.Is(err, usecase.ErrDeviceSuspended):
+			c.JSON(http.StatusForbidden, gin.H{"error": "device cannot be provisioned in its current status"})
+		default:
+			log.Printf("failed to provision device: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		}
+
+		return
+	}
+
+	c.JSON(http.StatusOK, output)
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"backend/internal/domain/entity"
+	"backend/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TelemetryHandler handles HTTP requests and calls the TelemetryUsecase.
+type TelemetryHandler struct {
+	uc usecase.TelemetryUsecase
+}
+
+// NewTelemetryHandler creates a new instance of TelemetryHandler.
+func NewTelemetryHandler(uc usecase.TelemetryUsecase) *TelemetryHandler {
+	return &TelemetryHandler{uc: uc}
+}
+
+// ListSensorData handles GET /devices/:id/sensor-data to retrieve the readings of a device, newest first.
+// It accepts the query parameters from and to (RFC 3339) and limit.
+func (h *TelemetryHandler) ListSensorData(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+
+		return
+	}
+
+	input, err := parseListSensorDataInput(c, id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+		return
+	}
+
+	outputs, err := h.uc.ListSensorData(c.Request.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidSensorDataQuery):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, entity.ErrDeviceNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": entity.ErrDeviceNotFound.Error()})
+		default:
+			log.Printf("failed to list sensor data: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		}
+
+		return
+	}
+
+	c.JSON(http.StatusOK, outputs)
+}
+
+// parseListSensorDataInput reads the range of a sensor data query from the query string.
+func parseListSensorDataInput(c *gin.Context, deviceID uuid.UUID) (usecase.ListSensorDataInput, error) {
+	input := usecase.ListSensorDataInput{DeviceID: deviceID} //nolint:exhaustruct
+
+	from, err := parseTimeQuery(c, "from")
+	if err != nil {
+		return input, err
+	}
+
+	to, err := parseTimeQuery(c, "to")
+	if err != nil {
+		return input, err
+	}
+
+	input.From, input.To = from, to
+
+	limit := c.Query("limit")
+	if limit != "" {
+		input.Limit, err = strconv.Atoi(limit)
+		if err != nil || input.Limit <= 0 {
+			return input, fmt.Errorf("%w: limit must be a positive integer", errInvalidQueryParameter)
+		}
+	}
+
+	return input, nil
+}
@@ -0,0 +1,680 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/domain/entity"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultBrokerAddr is the default listen address of the embedded broker.
+	DefaultBrokerAddr = ":8883"
+	// DeviceTopicRoot is the first topic level of the device namespaces.
+	// A device may only publish and subscribe to topics under "devices/<its device ID>/".
+	DeviceTopicRoot = "devices"
+	// DefaultMaxPacketSize is the default size limit of the packets sent by devices.
+	DefaultMaxPacketSize = 256 * 1024
+
+	handshakeTimeout = 10 * time.Second
+	connectTimeout   = 10 * time.Second
+	writeTimeout     = 10 * time.Second
+	acceptRetryDelay = 100 * time.Millisecond
+)
+
+// BrokerConfig holds the settings of the embedded broker.
+type BrokerConfig struct {
+	// Addr is the TCP address to listen on. If empty, DefaultBrokerAddr is used.
+	Addr string
+	// TLSConfig holds the server certificate and the CA pool (ClientCAs) that verifies the client certificates.
+	// Client certificates are always required.
+	TLSConfig *tls.Config
+	// TelemetryTopic is the topic filter of the sensor data. If empty, DefaultTopic is used.
+	TelemetryTopic string
+	// MaxPacketSize is the size limit of the packets sent by devices. If zero, DefaultMaxPacketSize is used.
+	MaxPacketSize int
+}
+
+// Broker is an MQTT 3.1.1 broker that devices connect to with their client certificates.
+//
+// It authenticates devices with the DeviceConnectionUsecase, records their connections, disconnections
+// and packets with the DevicePresenceUsecase, restricts each device to its own topics
+// and passes the sensor data directly to the IngestionUsecase, including the data gateways report
+// for their children under their own topics. The states devices report on TwinReportedTopic are passed
+// to the DeviceTwinUsecase, the answers to commands on CommandResponseTopic to the DeviceCommandUsecase,
+// and the progress of firmware updates on FirmwareProgressTopic to the FirmwareCampaignUsecase.
+// Messages are delivered to subscribers at QoS 0, and sessions, retained messages and wills are not kept.
+type Broker struct {
+	config         BrokerConfig
+	connections    usecase.DeviceConnectionUsecase
+	presence       usecase.DevicePresenceUsecase
+	ingestion      usecase.IngestionUsecase
+	twins          usecase.DeviceTwinUsecase
+	commands       usecase.DeviceCommandUsecase
+	firmware       usecase.FirmwareCampaignUsecase
+	telemetryTopic deviceTopic
+	twinTopic      deviceTopic
+	responseTopic  deviceTopic
+	firmwareTopic  deviceTopic
+
+	mu       sync.Mutex
+	sessions map[uuid.UUID]*session
+}
+
+// session is the connection of an authenticated device.
+type session struct {
+	deviceID uuid.UUID
+	conn     net.Conn
+	writeMu  sync.Mutex
+
+	// subscriptions are guarded by the mutex of the broker.
+	subscriptions map[string]struct{}
+	// pendingQoS2 are the QoS 2 packet IDs that have been processed, but not released by the device.
+	pendingQoS2 map[uint16]struct{}
+}
+
+// NewBroker creates a new instance of Broker.
+func NewBroker(
+	config BrokerConfig,
+	connections usecase.DeviceConnectionUsecase,
+	presence usecase.DevicePresenceUsecase,
+	ingestion usecase.IngestionUsecase,
+	twins usecase.DeviceTwinUsecase,
+	commands usecase.DeviceCommandUsecase,
+	firmware usecase.FirmwareCampaignUsecase,
+) (*Broker, error) {
+	if config.TLSConfig == nil {
+		return nil, ErrTLSConfigRequired
+	}
+
+	if config.Addr == "" {
+		config.Addr = DefaultBrokerAddr
+	}
+
+	if config.TelemetryTopic == "" {
+		config.TelemetryTopic = DefaultTopic
+	}
+
+	if config.MaxPacketSize <= 0 {
+		config.MaxPacketSize = DefaultMaxPacketSize
+	}
+
+	telemetryTopic, err := parseDeviceTopic(config.TelemetryTopic)
+	if err != nil {
+		return nil, err
+	}
+
+	twinTopic, err := parseDeviceTopic(TwinReportedTopic)
+	if err != nil {
+		return nil, err
+	}
+
+	responseTopic, err := parseDeviceTopic(CommandResponseTopic)
+	if err != nil {
+		return nil, err
+	}
+
+	firmwareTopic, err := parseDeviceTopic(FirmwareProgressTopic)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Broker{
+		config:         config,
+		connections:    connections,
+		presence:       presence,
+		ingestion:      ingestion,
+		twins:          twins,
+		commands:       commands,
+		firmware:       firmware,
+		telemetryTopic: telemetryTopic,
+		twinTopic:      twinTopic,
+		responseTopic:  responseTopic,
+		firmwareTopic:  firmwareTopic,
+		mu:             sync.Mutex{},
+		sessions:       make(map[uuid.UUID]*session),
+	}, nil
+}
+
+// Run listens on the configured address and serves devices until ctx is done.
+func (b *Broker) Run(ctx context.Context) error {
+	var listenConfig net.ListenConfig
+
+	listener, err := listenConfig.Listen(ctx, "tcp", b.config.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", b.config.Addr, err)
+	}
+
+	log.Printf("MQTT broker listening on %s", b.config.Addr)
+
+	return b.Serve(ctx, listener)
+}
+
+// Serve accepts connections on the listener until ctx is done, and then closes all connections.
+// The listener is wrapped with TLS, so it must accept plain TCP connections.
+func (b *Broker) Serve(ctx context.Context, listener net.Listener) error {
+	tlsConfig := b.config.TLSConfig.Clone()
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	tlsConfig.MinVersion = max(tlsConfig.MinVersion, tls.VersionTLS12)
+
+	tlsListener := tls.NewListener(listener, tlsConfig)
+	stop := context.AfterFunc(ctx, func() { _ = tlsListener.Close() })
+
+	defer stop()
+
+	var connections sync.WaitGroup
+	defer connections.Wait()
+
+	for {
+		conn, err := tlsListener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			if errors.Is(err, net.ErrClosed) {
+				return fmt.Errorf("mqtt listener closed: %w", err)
+			}
+
+			log.Printf("failed to accept MQTT connection: %v", err)
+			time.Sleep(acceptRetryDelay)
+
+			continue
+		}
+
+		connections.Go(func() { b.serveConn(ctx, conn) })
+	}
+}
+
+// Publish delivers a message from the platform to the devices subscribed to the topic.
+// It returns an error wrapping ErrNoSubscribers if no device is subscribed to it.
+func (b *Broker) Publish(topic string, payload []byte) error {
+	if !isValidTopicName(topic) {
+		return fmt.Errorf("%w: %q", ErrInvalidTopic, topic)
+	}
+
+	if b.deliver(topic, payload) == 0 {
+		return fmt.Errorf("%w: %s", ErrNoSubscribers, topic)
+	}
+
+	return nil
+}
+
+// serveConn authenticates a device and serves its connection until it disconnects or ctx is done.
+func (b *Broker) serveConn(ctx context.Context, conn net.Conn) {
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	defer func() { _ = conn.Close() }()
+
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return
+	}
+
+	handshakeCtx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	err := tlsConn.HandshakeContext(handshakeCtx)
+
+	cancel()
+
+	if err != nil {
+		log.Printf("MQTT TLS handshake with %s failed: %v", conn.RemoteAddr(), err)
+
+		return
+	}
+
+	deviceID, serialNumber, err := deviceIdentity(tlsConn.ConnectionState().PeerCertificates[0])
+	if err != nil {
+		log.Printf("MQTT connection from %s rejected: %v", conn.RemoteAddr(), err)
+
+		return
+	}
+
+	reader := bufio.NewReader(conn)
+
+	returnCode, keepAlive, err := b.connect(ctx, conn, reader, deviceID, serialNumber)
+	if err != nil || returnCode != connackAccepted {
+		log.Printf("MQTT connection of device %s rejected: %v", deviceID, err)
+
+		if returnCode != connackAccepted {
+			_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			_, _ = conn.Write(encodeConnack(returnCode))
+		}
+
+		return
+	}
+
+	s := &session{
+		deviceID:      deviceID,
+		conn:          conn,
+		writeMu:       sync.Mutex{},
+		subscriptions: make(map[string]struct{}),
+		pendingQoS2:   make(map[uint16]struct{}),
+	}
+
+	b.register(s)
+	defer b.disconnect(ctx, s)
+
+	err = s.write(encodeConnack(connackAccepted))
+	if err != nil {
+		return
+	}
+
+	log.Printf("MQTT device %s connected from %s", deviceID, conn.RemoteAddr())
+
+	err = b.presence.RecordConnect(ctx, usecase.RecordDeviceConnectInput{
+		DeviceID: deviceID,
+		IP:       remoteIP(conn.RemoteAddr()),
+	})
+	if err != nil {
+		log.Printf("failed to record the connection of MQTT device %s: %v", deviceID, err)
+	}
+
+	err = b.serveSession(ctx, s, reader, keepAlive)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+		log.Printf("MQTT device %s disconnected: %v", deviceID, err)
+
+		return
+	}
+
+	log.Printf("MQTT device %s disconnected", deviceID)
+}
+
+// connect reads the CONNECT packet and authenticates the device.
+// It returns the CONNACK return code to send, and the keep alive interval of the accepted connection.
+func (b *Broker) connect(
+	ctx context.Context,
+	conn net.Conn,
+	reader *bufio.Reader,
+	deviceID uuid.UUID,
+	serialNumber int64,
+) (byte, time.Duration, error) {
+	_ = conn.SetReadDeadline(time.Now().Add(connectTimeout))
+
+	p, err := readPacket(reader, b.config.MaxPacketSize)
+	if err != nil {
+		return connackAccepted, 0, err
+	}
+
+	if p.packetType() != packetConnect {
+		return connackAccepted, 0, fmt.Errorf("%w: expected CONNECT", ErrMalformedPacket)
+	}
+
+	connect, err := parseConnect(p)
+	if err != nil {
+		return connackAccepted, 0, err
+	}
+
+	if connect.protocolName != protocolName || connect.protocolLevel != protocolLevel311 {
+		return connackUnacceptableProtocol, 0, fmt.Errorf(
+			"unsupported protocol %q level %d", connect.protocolName, connect.protocolLevel,
+		)
+	}
+
+	// Without a kept session, a client ID is only meaningful to the device, so any client ID is accepted.
+	err = b.connections.Authenticate(ctx, usecase.AuthenticateDeviceInput{
+		DeviceID:     deviceID,
+		SerialNumber: serialNumber,
+	})
+	if err != nil {
+		if errors.Is(err, usecase.ErrDeviceUnauthorized) {
+			return connackNotAuthorized, 0, err
+		}
+
+		return connackServerUnavailable, 0, err
+	}
+
+	// The connection is closed if nothing is received within one and a half keep alive intervals.
+	return connackAccepted, time.Duration(connect.keepAlive) * time.Second * 3 / 2, nil //nolint:mnd
+}
+
+// serveSession reads the packets of an authenticated device until it disconnects.
+// It returns nil on DISCONNECT, and an error if the connection is lost or the device violates the protocol.
+func (b *Broker) serveSession(ctx context.Context, s *session, reader *bufio.Reader, keepAlive time.Duration) error {
+	for {
+		deadline := time.Time{}
+		if keepAlive > 0 {
+			deadline = time.Now().Add(keepAlive)
+		}
+
+		_ = s.conn.SetReadDeadline(deadline)
+
+		p, err := readPacket(reader, b.config.MaxPacketSize)
+		if err != nil {
+			return err
+		}
+
+		// Any packet shows that the device is alive, PINGREQ included; most are not written.
+		err = b.presence.RecordActivity(ctx, s.deviceID)
+		if err != nil {
+			log.Printf("failed to record the activity of MQTT device %s: %v", s.deviceID, err)
+		}
+
+		switch p.packetType() {
+		case packetPublish:
+			err = b.handlePublish(ctx, s, p)
+		case packetPubrel:
+			err = s.handlePubrel(p)
+		case packetSubscribe:
+			err = b.handleSubscribe(s, p)
+		case packetUnsubscribe:
+			err = b.handleUnsubscribe(s, p)
+		case packetPingreq:
+			err = s.write(encodePacket(packetPingresp<<packetTypeShift, nil))
+		case packetPuback, packetPubrec, packetPubcomp:
+			// Messages are delivered at QoS 0, so there is nothing to acknowledge.
+		case packetDisconnect:
+			return nil
+		default:
+			err = fmt.Errorf("%w: unexpected packet type %d", ErrMalformedPacket, p.packetType())
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handlePublish authorizes and routes a message published by a device, and acknowledges it.
+func (b *Broker) handlePublish(ctx context.Context, s *session, p packet) error {
+	publish, err := parsePublish(p)
+	if err != nil {
+		return err
+	}
+
+	if !isValidTopicName(publish.topic) {
+		return fmt.Errorf("%w: invalid topic name %q", ErrMalformedPacket, publish.topic)
+	}
+
+	// MQTT 3.1.1 has no negative acknowledgement, so an unauthorized publication closes the connection.
+	if !s.isAuthorized(publish.topic) {
+		return fmt.Errorf("%w: %s", ErrNotAuthorized, publish.topic)
+	}
+
+	_, duplicate := s.pendingQoS2[publish.packetID]
+	if publish.qos < 2 || !duplicate { //nolint:mnd
+		err = b.route(ctx, s, publish)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch publish.qos {
+	case 1:
+		return s.write(encodePacketID(packetPuback, 0, publish.packetID))
+	case 2: //nolint:mnd
+		s.pendingQoS2[publish.packetID] = struct{}{}
+
+		return s.write(encodePacketID(packetPubrec, 0, publish.packetID))
+	default:
+		return nil
+	}
+}
+
+// route passes sensor data to the ingestion, reported states to the twins, answers to the commands
+// and the progress of firmware updates to the campaigns, and delivers the message to the subscribers.
+// It returns an error if the device may no longer send data, which closes the connection.
+// A gateway that reports for a device that is not its ACTIVE child only has the message dropped.
+func (b *Broker) route(ctx context.Context, s *session, publish publishPacket) error {
+	// The message is acknowledged once it is handled, so a disconnect does not cancel it.
+	ctx = context.WithoutCancel(ctx)
+
+	input, err := b.telemetryTopic.ingestInput(publish.topic, publish.payload)
+	if err == nil && reporterID(input) == s.deviceID {
+		err = routingError(publish.topic, b.ingestion.Ingest(ctx, input))
+		if err != nil {
+			return err
+		}
+	}
+
+	deviceID, err := b.twinTopic.DeviceID(publish.topic)
+	if err == nil && deviceID == s.deviceID {
+		_, err = b.twins.ReportState(ctx, usecase.ReportDeviceStateInput{DeviceID: deviceID, State: publish.payload})
+
+		err = routingError(publish.topic, err)
+		if err != nil {
+			return err
+		}
+	}
+
+	deviceID, err = b.responseTopic.DeviceID(publish.topic)
+	if err == nil && deviceID == s.deviceID {
+		err = b.commands.AcknowledgeCommand(ctx, usecase.AcknowledgeDeviceCommandInput{
+			DeviceID: deviceID,
+			Response: publish.payload,
+		})
+
+		err = routingError(publish.topic, err)
+		if err != nil {
+			return err
+		}
+	}
+
+	deviceID, err = b.firmwareTopic.DeviceID(publish.topic)
+	if err == nil && deviceID == s.deviceID {
+		err = b.firmware.ReportFirmwareProgress(ctx, usecase.ReportFirmwareProgressInput{
+			DeviceID: deviceID,
+			Report:   publish.payload,
+		})
+
+		err = routingError(publish.topic, err)
+		if err != nil {
+			return err
+		}
+	}
+
+	b.deliver(publish.topic, publish.payload)
+
+	return nil
+}
+
+// routingError returns the errors of the usecases after which the device may no longer send data.
+// Any other error is logged, and the message is dropped.
+func routingError(topic string, err error) error {
+	switch {
+	case errors.Is(err, usecase.ErrDeviceNotActive), errors.Is(err, entity.ErrDeviceNotFound),
+		errors.Is(err, usecase.ErrIngestionStopped):
+		return err
+	case err != nil:
+		log.Printf("dropped MQTT message on %s: %v", topic, err)
+	}
+
+	return nil
+}
+
+// deliver sends a message at QoS 0 to every session with a matching subscription,
+// and returns the number of sessions it was sent to.
+func (b *Broker) deliver(topic string, payload []byte) int {
+	b.mu.Lock()
+
+	var targets []*session
+
+	for _, s := range b.sessions {
+		for filter := range s.subscriptions {
+			if topicMatches(filter, topic) {
+				targets = append(targets, s)
+
+				break
+			}
+		}
+	}
+
+	b.mu.Unlock()
+
+	if len(targets) == 0 {
+		return 0
+	}
+
+	encoded := encodePublish(topic, payload)
+	for _, s := range targets {
+		err := s.write(encoded)
+		if err != nil {
+			// The read loop of the session notices the closed connection and cleans up.
+			_ = s.conn.Close()
+		}
+	}
+
+	return len(targets)
+}
+
+// handleSubscribe grants the subscriptions within the namespace of the device at QoS 0.
+func (b *Broker) handleSubscribe(s *session, p packet) error {
+	packetID, subscriptions, err := parseSubscribe(p)
+	if err != nil {
+		return err
+	}
+
+	returnCodes := make([]byte, 0, len(subscriptions))
+
+	b.mu.Lock()
+
+	for _, sub := range subscriptions {
+		if !isValidTopicFilter(sub.filter) || !s.isAuthorized(sub.filter) {
+			returnCodes = append(returnCodes, subackFailure)
+
+			continue
+		}
+
+		s.subscriptions[sub.filter] = struct{}{}
+		returnCodes = append(returnCodes, 0) // granted QoS 0
+	}
+
+	b.mu.Unlock()
+
+	return s.write(encodeSuback(packetID, returnCodes))
+}
+
+func (b *Broker) handleUnsubscribe(s *session, p packet) error {
+	packetID, filters, err := parseUnsubscribe(p)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+
+	for _, filter := range filters {
+		delete(s.subscriptions, filter)
+	}
+
+	b.mu.Unlock()
+
+	return s.write(encodePacketID(packetUnsuback, 0, packetID))
+}
+
+// register adds the session of a device. An existing session of the same device is taken over.
+func (b *Broker) register(s *session) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	previous, ok := b.sessions[s.deviceID]
+	if ok {
+		log.Printf("MQTT device %s reconnected, closing the previous connection", s.deviceID)
+
+		_ = previous.conn.Close()
+	}
+
+	b.sessions[s.deviceID] = s
+}
+
+// unregister removes the session of a device, unless it has already been taken over.
+// It reports whether the session was removed.
+func (b *Broker) unregister(s *session) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.sessions[s.deviceID] != s {
+		return false
+	}
+
+	delete(b.sessions, s.deviceID)
+
+	return true
+}
+
+// disconnect unregisters the session of a device and records that the device has disconnected.
+// A session that has been taken over is not recorded, as the device is still connected through the new one.
+func (b *Broker) disconnect(ctx context.Context, s *session) {
+	if !b.unregister(s) {
+		return
+	}
+
+	// The disconnection is recorded even when the broker shuts down.
+	err := b.presence.RecordDisconnect(context.WithoutCancel(ctx), s.deviceID)
+	if err != nil {
+		log.Printf("failed to record the disconnection of MQTT device %s: %v", s.deviceID, err)
+	}
+}
+
+// remoteIP returns the IP address of a remote address, or an empty string if it has none.
+func remoteIP(addr net.Addr) string {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return ""
+	}
+
+	return tcpAddr.IP.String()
+}
+
+// handlePubrel completes the delivery of a QoS 2 message.
+func (s *session) handlePubrel(p packet) error {
+	if p.flags() != pubrelFixedHeaderFlags {
+		return ErrMalformedPacket
+	}
+
+	packetID, err := parsePacketID(p)
+	if err != nil {
+		return err
+	}
+
+	delete(s.pendingQoS2, packetID)
+
+	return s.write(encodePacketID(packetPubcomp, 0, packetID))
+}
+
+// isAuthorized reports whether the device may use a topic name or filter: it must be under "devices/<device ID>/".
+func (s *session) isAuthorized(topic string) bool {
+	levels := strings.SplitN(topic, "/", 3) //nolint:mnd
+
+	return len(levels) == 3 && levels[0] == DeviceTopicRoot && levels[1] == s.deviceID.String() //nolint:mnd
+}
+
+func (s *session) write(data []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	_ = s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+	_, err := s.conn.Write(data)
+	if err != nil {
+		return fmt.Errorf("failed to write to device %s: %w", s.deviceID, err)
+	}
+
+	return nil
+}
+
+// deviceIdentity reads the device ID and the serial number from a client certificate issued by the platform CA.
+func deviceIdentity(cert *x509.Certificate) (uuid.UUID, int64, error) {
+	deviceID, err := uuid.Parse(cert.Subject.CommonName)
+	if err != nil {
+		return uuid.Nil, 0, fmt.Errorf("%w: subject %q", ErrNoDeviceIdentity, cert.Subject.CommonName)
+	}
+
+	if !cert.SerialNumber.IsInt64() {
+		return uuid.Nil, 0, fmt.Errorf("%w: serial number %s", ErrNoDeviceIdentity, cert.SerialNumber)
+	}
+
+	return deviceID, cert.SerialNumber.Int64(), nil
+}
@@ -0,0 +1,551 @@
+package mqtt_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/service"
+	"backend/internal/presentation/mqtt"
+	"backend/internal/usecase"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// FakeDeviceConnectionUsecase authorizes the devices with the registered certificates.
+type FakeDeviceConnectionUsecase struct {
+	mu           sync.Mutex
+	certificates map[int64]uuid.UUID
+}
+
+// NewFakeDeviceConnectionUsecase creates a new FakeDeviceConnectionUsecase.
+func NewFakeDeviceConnectionUsecase() *FakeDeviceConnectionUsecase {
+	return &FakeDeviceConnectionUsecase{
+		mu:           sync.Mutex{},
+		certificates: make(map[int64]uuid.UUID),
+	}
+}
+
+// Allow registers the certificate of a device.
+func (f *FakeDeviceConnectionUsecase) Allow(deviceID uuid.UUID, cert tls.Certificate) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.certificates[cert.Leaf.SerialNumber.Int64()] = deviceID
+}
+
+// Authenticate authorizes a device if its certificate is registered.
+func (f *FakeDeviceConnectionUsecase) Authenticate(_ context.Context, input usecase.AuthenticateDeviceInput) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	deviceID, ok := f.certificates[input.SerialNumber]
+	if !ok || deviceID != input.DeviceID {
+		return usecase.ErrDeviceUnauthorized
+	}
+
+	return nil
+}
+
+// FakeDevicePresenceUsecase records the presence events of the devices.
+type FakeDevicePresenceUsecase struct {
+	mu          sync.Mutex
+	connectIPs  map[uuid.UUID]string
+	disconnects map[uuid.UUID]int
+	activities  map[uuid.UUID]int
+}
+
+// NewFakeDevicePresenceUsecase creates a new FakeDevicePresenceUsecase.
+func NewFakeDevicePresenceUsecase() *FakeDevicePresenceUsecase {
+	return &FakeDevicePresenceUsecase{
+		mu:          sync.Mutex{},
+		connectIPs:  make(map[uuid.UUID]string),
+		disconnects: make(map[uuid.UUID]int),
+		activities:  make(map[uuid.UUID]int),
+	}
+}
+
+// RecordConnect records the IP address a device connected from.
+func (f *FakeDevicePresenceUsecase) RecordConnect(_ context.Context, input usecase.RecordDeviceConnectInput) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.connectIPs[input.DeviceID] = input.IP
+
+	return nil
+}
+
+// RecordDisconnect counts the disconnections of a device.
+func (f *FakeDevicePresenceUsecase) RecordDisconnect(_ context.Context, deviceID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.disconnects[deviceID]++
+
+	return nil
+}
+
+// RecordActivity counts the activity of a device.
+func (f *FakeDevicePresenceUsecase) RecordActivity(_ context.Context, deviceID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.activities[deviceID]++
+
+	return nil
+}
+
+// Run does nothing, because no device is flagged as stale.
+func (f *FakeDevicePresenceUsecase) Run(_ context.Context) {}
+
+// ConnectIP returns the IP address the device last connected from, and whether it has connected.
+func (f *FakeDevicePresenceUsecase) ConnectIP(deviceID uuid.UUID) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ip, ok := f.connectIPs[deviceID]
+
+	return ip, ok
+}
+
+// Disconnects returns the number of disconnections of the device.
+func (f *FakeDevicePresenceUsecase) Disconnects(deviceID uuid.UUID) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.disconnects[deviceID]
+}
+
+// Activities returns the number of times the activity of the device was recorded.
+func (f *FakeDevicePresenceUsecase) Activities(deviceID uuid.UUID) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.activities[deviceID]
+}
+
+// brokerTest is an embedded broker serving on a random local port.
+type brokerTest struct {
+	pki         *testPKI
+	broker      *mqtt.Broker
+	url         string
+	connections *FakeDeviceConnectionUsecase
+	presence    *FakeDevicePresenceUsecase
+	ingestion   *FakeIngestionUsecase
+	twins       *FakeDeviceTwinUsecase
+	commands    *FakeDeviceCommandUsecase
+	firmware    *FakeFirmwareCampaignUsecase
+}
+
+func newBrokerTest(t *testing.T) *brokerTest {
+	t.Helper()
+
+	pki := newTestPKI(t)
+	serverCert := pki.issue(t, "broker", x509.ExtKeyUsageServerAuth)
+	connections := NewFakeDeviceConnectionUsecase()
+	presence := NewFakeDevicePresenceUsecase()
+	ingestion := &FakeIngestionUsecase{}       //nolint:exhaustruct
+	twins := &FakeDeviceTwinUsecase{}          //nolint:exhaustruct
+	commands := &FakeDeviceCommandUsecase{}    //nolint:exhaustruct
+	firmware := &FakeFirmwareCampaignUsecase{} //nolint:exhaustruct
+
+	broker, err := mqtt.NewBroker(mqtt.BrokerConfig{
+		Addr: "",
+		TLSConfig: &tls.Config{ //nolint:exhaustruct
+			Certificates: []tls.Certificate{serverCert},
+			ClientCAs:    pki.pool,
+		},
+		TelemetryTopic: "",
+		MaxPacketSize:  0,
+	}, connections, presence, ingestion, twins, commands, firmware)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+
+	wg.Go(func() {
+		assert.NoError(t, broker.Serve(ctx, listener))
+	})
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	return &brokerTest{
+		pki:         pki,
+		broker:      broker,
+		url:         "tls://" + listener.Addr().String(),
+		connections: connections,
+		presence:    presence,
+		ingestion:   ingestion,
+		twins:       twins,
+		commands:    commands,
+		firmware:    firmware,
+	}
+}
+
+// newDevice issues a certificate for a new device and allows it to connect.
+func (bt *brokerTest) newDevice(t *testing.T) (uuid.UUID, tls.Certificate) {
+	t.Helper()
+
+	deviceID := uuid.New()
+	cert := bt.pki.issue(t, deviceID.String(), x509.ExtKeyUsageClientAuth)
+	bt.connections.Allow(deviceID, cert)
+
+	return deviceID, cert
+}
+
+// connect connects a device and returns the client and a channel that is closed when the connection is lost.
+func (bt *brokerTest) connect(t *testing.T, cert tls.Certificate) (paho.Client, <-chan struct{}, error) {
+	t.Helper()
+
+	lost := make(chan struct{})
+
+	client := paho.NewClient(paho.NewClientOptions().
+		AddBroker(bt.url).
+		SetClientID(cert.Leaf.Subject.CommonName).
+		SetAutoReconnect(false).
+		SetProtocolVersion(4).    // without falling back to MQTT 3.1 after a refusal
+		SetTLSConfig(&tls.Config{ //nolint:exhaustruct
+			RootCAs:      bt.pki.pool,
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}).
+		SetConnectionLostHandler(func(_ paho.Client, _ error) { close(lost) }))
+
+	token := client.Connect()
+	require.True(t, token.WaitTimeout(5*time.Second))
+
+	err := token.Error()
+	if err == nil {
+		t.Cleanup(func() { client.Disconnect(0) })
+	}
+
+	return client, lost, err
+}
+
+func waitToken(t *testing.T, token paho.Token) {
+	t.Helper()
+
+	require.True(t, token.WaitTimeout(5*time.Second))
+	require.NoError(t, token.Error())
+}
+
+func TestBroker(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success: telemetry is ingested", func(t *testing.T) {
+		t.Parallel()
+
+		bt := newBrokerTest(t)
+		deviceID, cert := bt.newDevice(t)
+
+		client, _, err := bt.connect(t, cert)
+		require.NoError(t, err)
+
+		topic := "devices/" + deviceID.String() + "/telemetry"
+		waitToken(t, client.Publish(topic, 1, false, `{"temperature": 21.5}`))
+		waitToken(t, client.Publish(topic, 2, false, `{"temperature": 22.0}`))
+
+		inputs := bt.ingestion.Inputs()
+		require.Len(t, inputs, 2, "the readings are ingested before they are acknowledged")
+		assert.Equal(t, deviceID, inputs[0].DeviceID)
+		assert.JSONEq(t, `{"temperature": 21.5}`, string(inputs[0].Payload))
+		assert.JSONEq(t, `{"temperature": 22.0}`, string(inputs[1].Payload))
+	})
+
+	t.Run("success: gateway reports for its children", func(t *testing.T) {
+		t.Parallel()
+
+		bt := newBrokerTest(t)
+		gatewayID, cert := bt.newDevice(t)
+
+		client, lost, err := bt.connect(t, cert)
+		require.NoError(t, err)
+
+		childID := uuid.New()
+		waitToken(t, client.Publish(
+			"devices/"+gatewayID.String()+"/telemetry/"+childID.String(), 1, false, `{"temperature": 21.5}`,
+		))
+
+		inputs := bt.ingestion.Inputs()
+		require.Len(t, inputs, 1)
+		assert.Equal(t, childID, inputs[0].DeviceID)
+		assert.Equal(t, gatewayID, inputs[0].GatewayID)
+
+		// A reading for a device that is not a child is dropped, but the gateway stays connected.
+		bt.ingestion.SetErr(usecase.ErrNotGatewayChild)
+		waitToken(t, client.Publish(
+			"devices/"+gatewayID.String()+"/telemetry/"+uuid.NewString(), 1, false, `{"temperature": 21.5}`,
+		))
+
+		select {
+		case <-lost:
+			t.Fatal("connection was closed")
+		default:
+		}
+	})
+
+	t.Run("success: reported states go to the twins, and deltas to the device", func(t *testing.T) {
+		t.Parallel()
+
+		bt := newBrokerTest(t)
+		deviceID, cert := bt.newDevice(t)
+
+		client, _, err := bt.connect(t, cert)
+		require.NoError(t, err)
+
+		deltas := make(chan string, 1)
+		deltaTopic := "devices/" + deviceID.String() + "/twin/delta"
+		waitToken(t, client.Subscribe(deltaTopic, 1, func(_ paho.Client, m paho.Message) {
+			deltas <- string(m.Payload())
+		}))
+		waitToken(t, client.Publish("devices/"+deviceID.String()+"/twin/reported", 1, false, `{"led": "on"}`))
+
+		reports := bt.twins.Reports()
+		require.Len(t, reports, 1, "the state is reported before it is acknowledged")
+		assert.Equal(t, deviceID, reports[0].DeviceID)
+		assert.JSONEq(t, `{"led": "on"}`, string(reports[0].State))
+		assert.Empty(t, bt.ingestion.Inputs())
+
+		publisher := mqtt.NewDevicePublisher()
+		publisher.Attach(bt.broker)
+		require.NoError(t, publisher.PublishDelta(context.Background(), service.DeviceTwinDelta{
+			DeviceID: deviceID,
+			Version:  3,
+			State:    map[string]any{"led": "off"},
+		}))
+
+		select {
+		case got := <-deltas:
+			assert.JSONEq(t, `{"version": 3, "state": {"led": "off"}}`, got)
+		case <-time.After(5 * time.Second):
+			t.Fatal("delta was not delivered")
+		}
+	})
+
+	t.Run("success: commands go to the device once it subscribes, and answers to the commands", func(t *testing.T) {
+		t.Parallel()
+
+		bt := newBrokerTest(t)
+		deviceID, cert := bt.newDevice(t)
+
+		client, _, err := bt.connect(t, cert)
+		require.NoError(t, err)
+
+		publisher := mqtt.NewDevicePublisher()
+		publisher.Attach(bt.broker)
+
+		command := service.DeviceCommandMessage{
+			ID:        uuid.New(),
+			DeviceID:  deviceID,
+			Name:      "reboot",
+			Payload:   map[string]any{},
+			ExpiresAt: time.Now().Add(time.Minute),
+		}
+		require.ErrorIs(t, publisher.PublishCommand(context.Background(), command), mqtt.ErrNoSubscribers,
+			"a command is not sent before the device subscribes")
+
+		received := make(chan string, 1)
+		waitToken(t, client.Subscribe("devices/"+deviceID.String()+"/commands", 1, func(_ paho.Client, m paho.Message) {
+			received <- string(m.Payload())
+		}))
+		require.NoError(t, publisher.PublishCommand(context.Background(), command))
+
+		select {
+		case got := <-received:
+			assert.Contains(t, got, `"name":"reboot"`)
+		case <-time.After(5 * time.Second):
+			t.Fatal("command was not delivered")
+		}
+
+		response := `{"id": "` + command.ID.String() + `", "status": "FAILED", "reason": "busy"}`
+		waitToken(t, client.Publish("devices/"+deviceID.String()+"/commands/response", 1, false, response))
+
+		answers := bt.commands.Answers()
+		require.Len(t, answers, 1, "the answer is passed on before it is acknowledged")
+		assert.Equal(t, deviceID, answers[0].DeviceID)
+		assert.JSONEq(t, response, string(answers[0].Response))
+	})
+
+	t.Run("success: the progress of firmware updates goes to the campaigns", func(t *testing.T) {
+		t.Parallel()
+
+		bt := newBrokerTest(t)
+		deviceID, cert := bt.newDevice(t)
+
+		client, _, err := bt.connect(t, cert)
+		require.NoError(t, err)
+
+		progress := `{"campaignId": "` + uuid.NewString() + `", "status": "SUCCEEDED"}`
+		waitToken(t, client.Publish("devices/"+deviceID.String()+"/firmware/progress", 1, false, progress))
+
+		reports := bt.firmware.Reports()
+		require.Len(t, reports, 1, "the progress is passed on before it is acknowledged")
+		assert.Equal(t, deviceID, reports[0].DeviceID)
+		assert.JSONEq(t, progress, string(reports[0].Report))
+		assert.Empty(t, bt.ingestion.Inputs(), "the progress is not ingested")
+	})
+
+	t.Run("success: messages are delivered to the own topics", func(t *testing.T) {
+		t.Parallel()
+
+		bt := newBrokerTest(t)
+		deviceID, cert := bt.newDevice(t)
+
+		client, _, err := bt.connect(t, cert)
+		require.NoError(t, err)
+
+		received := make(chan string, 1)
+		token := client.Subscribe("devices/"+deviceID.String()+"/commands/#", 1, func(_ paho.Client, m paho.Message) {
+			received <- m.Topic() + " " + string(m.Payload())
+		})
+		waitToken(t, token)
+
+		otherID := uuid.New()
+		token = client.Subscribe("devices/"+otherID.String()+"/#", 1, nil)
+		waitToken(t, token)
+
+		subscribeToken, ok := token.(*paho.SubscribeToken)
+		require.True(t, ok)
+		assert.Equal(t, byte(0x80), subscribeToken.Result()["devices/"+otherID.String()+"/#"],
+			"subscriptions to other devices are refused")
+
+		require.NoError(t, bt.broker.Publish("devices/"+deviceID.String()+"/commands/reboot", []byte(`{}`)))
+		err = bt.broker.Publish("devices/"+otherID.String()+"/commands", []byte(`{}`))
+		require.ErrorIs(t, err, mqtt.ErrNoSubscribers)
+
+		select {
+		case got := <-received:
+			assert.Equal(t, "devices/"+deviceID.String()+"/commands/reboot {}", got)
+		case <-time.After(5 * time.Second):
+			t.Fatal("message was not delivered")
+		}
+	})
+
+	t.Run("failure: publishing to another device closes the connection", func(t *testing.T) {
+		t.Parallel()
+
+		bt := newBrokerTest(t)
+		_, cert := bt.newDevice(t)
+
+		client, lost, err := bt.connect(t, cert)
+		require.NoError(t, err)
+
+		client.Publish("devices/"+uuid.NewString()+"/telemetry", 1, false, `{"temperature": 21.5}`)
+
+		select {
+		case <-lost:
+		case <-time.After(5 * time.Second):
+			t.Fatal("connection was not closed")
+		}
+
+		assert.Empty(t, bt.ingestion.Inputs())
+	})
+
+	t.Run("failure: unauthorized device is rejected", func(t *testing.T) {
+		t.Parallel()
+
+		bt := newBrokerTest(t)
+		cert := bt.pki.issue(t, uuid.NewString(), x509.ExtKeyUsageClientAuth) // e.g. revoked
+
+		_, _, err := bt.connect(t, cert)
+		require.ErrorIs(t, err, packets.ErrorRefusedNotAuthorised)
+	})
+
+	t.Run("failure: certificate of another CA is rejected", func(t *testing.T) {
+		t.Parallel()
+
+		bt := newBrokerTest(t)
+		otherPKI := newTestPKI(t)
+		deviceID := uuid.New()
+		cert := otherPKI.issue(t, deviceID.String(), x509.ExtKeyUsageClientAuth)
+		bt.connections.Allow(deviceID, cert)
+
+		_, _, err := bt.connect(t, cert)
+		require.Error(t, err)
+	})
+
+	t.Run("failure: inactive device is disconnected", func(t *testing.T) {
+		t.Parallel()
+
+		bt := newBrokerTest(t)
+		deviceID, cert := bt.newDevice(t)
+
+		client, lost, err := bt.connect(t, cert)
+		require.NoError(t, err)
+
+		bt.ingestion.SetErr(usecase.ErrDeviceNotActive)
+		client.Publish("devices/"+deviceID.String()+"/telemetry", 1, false, `{"temperature": 21.5}`)
+
+		select {
+		case <-lost:
+		case <-time.After(5 * time.Second):
+			t.Fatal("connection was not closed")
+		}
+	})
+
+	t.Run("success: reconnect takes over the previous connection", func(t *testing.T) {
+		t.Parallel()
+
+		bt := newBrokerTest(t)
+		deviceID, cert := bt.newDevice(t)
+
+		_, lost, err := bt.connect(t, cert)
+		require.NoError(t, err)
+
+		_, _, err = bt.connect(t, cert)
+		require.NoError(t, err)
+
+		select {
+		case <-lost:
+		case <-time.After(5 * time.Second):
+			t.Fatal("previous connection was not closed")
+		}
+
+		// The device is still connected through the new connection.
+		assert.Never(t, func() bool {
+			return bt.presence.Disconnects(deviceID) > 0
+		}, 100*time.Millisecond, 10*time.Millisecond)
+	})
+
+	t.Run("success: presence follows the connection", func(t *testing.T) {
+		t.Parallel()
+
+		bt := newBrokerTest(t)
+		deviceID, cert := bt.newDevice(t)
+
+		client, _, err := bt.connect(t, cert)
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			_, ok := bt.presence.ConnectIP(deviceID)
+
+			return ok
+		}, 5*time.Second, 10*time.Millisecond)
+
+		ip, _ := bt.presence.ConnectIP(deviceID)
+		assert.Equal(t, "127.0.0.1", ip)
+
+		waitToken(t, client.Publish("devices/"+deviceID.String()+"/telemetry", 1, false, `{"temperature": 21.5}`))
+		assert.Positive(t, bt.presence.Activities(deviceID), "the packets of the device are its activity")
+
+		client.Disconnect(250)
+
+		require.Eventually(t, func() bool {
+			return bt.presence.Disconnects(deviceID) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
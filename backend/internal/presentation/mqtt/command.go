@@ -0,0 +1,58 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/internal/domain/service"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// CommandTopic is the topic filter on which devices receive their commands, as
+	// {"id": ..., "name": ..., "payload": {...}, "expiresAt": ...}. The "+" level is the device ID.
+	CommandTopic = "devices/+/commands"
+	// CommandResponseTopic is the topic filter on which devices answer their commands, as
+	// {"id": <command ID>, "status": "ACKED" or "FAILED", "result": {...}, "reason": ...}.
+	CommandResponseTopic = "devices/+/commands/response"
+)
+
+// commandMessage is the payload of a message on CommandTopic.
+type commandMessage struct {
+	ID        uuid.UUID      `json:"id"`
+	Name      string         `json:"name"`
+	Payload   map[string]any `json:"payload"`
+	ExpiresAt time.Time      `json:"expiresAt"`
+}
+
+// PublishCommand publishes a command to its device on CommandTopic through every attached broker.
+// It returns an error wrapping ErrNoSubscribers if no broker could deliver it, e.g. because the device
+// is not connected to the embedded broker.
+func (p *DevicePublisher) PublishCommand(_ context.Context, command service.DeviceCommandMessage) error {
+	payload, err := json.Marshal(commandMessage{
+		ID:        command.ID,
+		Name:      command.Name,
+		Payload:   command.Payload,
+		ExpiresAt: command.ExpiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal command: %w", err)
+	}
+
+	topic := strings.Replace(CommandTopic, "+", command.DeviceID.String(), 1)
+
+	delivered, err := p.publish(topic, payload)
+	if delivered {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: %s", ErrNoSubscribers, topic)
+}
@@ -0,0 +1,30 @@
+package mqtt
+
+import "errors"
+
+var (
+	// ErrInvalidTopicFilter is returned when the topic filter does not have exactly one "+" level for the device ID.
+	ErrInvalidTopicFilter = errors.New("topic filter must have exactly one \"+\" level for the device id and no \"#\"")
+	// ErrInvalidTopic is returned when the device ID cannot be read from the topic of a message.
+	ErrInvalidTopic = errors.New("invalid topic")
+	// ErrConnect is returned when the subscriber cannot connect to the broker.
+	ErrConnect = errors.New("failed to connect to mqtt broker")
+	// ErrNoCACertificate is returned when the CA file does not contain any certificate.
+	ErrNoCACertificate = errors.New("no CA certificate found")
+	// ErrMalformedPacket is returned when a client sends a packet that violates the MQTT 3.1.1 protocol.
+	ErrMalformedPacket = errors.New("malformed mqtt packet")
+	// ErrPacketTooLarge is returned when a client sends a packet larger than the broker accepts.
+	ErrPacketTooLarge = errors.New("mqtt packet too large")
+	// ErrNotAuthorized is returned when a client publishes or subscribes to a topic outside of its own namespace.
+	ErrNotAuthorized = errors.New("not authorized for topic")
+	// ErrTLSConfigRequired is returned when the embedded broker is created without a TLS configuration.
+	ErrTLSConfigRequired = errors.New("tls config is required")
+	// ErrNoDeviceIdentity is returned when a client certificate does not identify a device.
+	ErrNoDeviceIdentity = errors.New("client certificate does not identify a device")
+	// ErrNotConnected is returned when the subscriber publishes a message while it is not connected to the broker.
+	ErrNotConnected = errors.New("not connected to mqtt broker")
+	// ErrPublishTimeout is returned when the broker does not acknowledge a message published by the subscriber.
+	ErrPublishTimeout = errors.New("timed out publishing to mqtt broker")
+	// ErrNoSubscribers is returned when a message for a device is published while the device is not subscribed.
+	ErrNoSubscribers = errors.New("no subscribers for topic")
+)
@@ -0,0 +1,6 @@
+package mqtt
+
+// FirmwareProgressTopic is the topic filter on which devices report the progress of their firmware update,
+// as {"campaignId": ..., "status": "DOWNLOADING", "INSTALLING", "SUCCEEDED" or "FAILED", "progress": <percent>,
+// "error": ...}. The "+" level is the device ID. The updates themselves are sent as "firmware-update" commands.
+const FirmwareProgressTopic = "devices/+/firmware/progress"
@@ -0,0 +1,419 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// MQTT 3.1.1 control packet types.
+const (
+	packetConnect     byte = 1
+	packetConnack     byte = 2
+	packetPublish     byte = 3
+	packetPuback      byte = 4
+	packetPubrec      byte = 5
+	packetPubrel      byte = 6
+	packetPubcomp     byte = 7
+	packetSubscribe   byte = 8
+	packetSuback      byte = 9
+	packetUnsubscribe byte = 10
+	packetUnsuback    byte = 11
+	packetPingreq     byte = 12
+	packetPingresp    byte = 13
+	packetDisconnect  byte = 14
+)
+
+// CONNACK return codes.
+const (
+	connackAccepted             byte = 0
+	connackUnacceptableProtocol byte = 1
+	connackServerUnavailable    byte = 3
+	connackNotAuthorized        byte = 5
+	subackFailure               byte = 0x80
+	protocolLevel311            byte = 4
+	protocolName                     = "MQTT"
+	maxRemainingLengthBytes          = 4
+	connectFlagReserved         byte = 0x01
+	connectFlagCleanSession     byte = 0x02
+	connectFlagWill             byte = 0x04
+	connectFlagWillQoS          byte = 0x18
+	connectFlagWillRetain       byte = 0x20
+	connectFlagPassword         byte = 0x40
+	connectFlagUsername         byte = 0x80
+	publishFlagQoSShift              = 1
+	publishFlagQoSMask          byte = 0x03
+	subscribeFixedHeaderFlags   byte = 0x02
+	unsubscribeFixedHeaderFlags byte = 0x02
+	pubrelFixedHeaderFlags      byte = 0x02
+	packetTypeShift                  = 4
+	packetFlagsMask             byte = 0x0f
+)
+
+// packet is an MQTT control packet: the first byte of the fixed header and the rest of the packet.
+type packet struct {
+	header byte
+	body   []byte
+}
+
+func (p packet) packetType() byte {
+	return p.header >> packetTypeShift
+}
+
+func (p packet) flags() byte {
+	return p.header & packetFlagsMask
+}
+
+// connectPacket holds the fields of a CONNECT packet that the broker uses.
+type connectPacket struct {
+	protocolName  string
+	protocolLevel byte
+	cleanSession  bool
+	keepAlive     uint16
+	clientID      string
+}
+
+// publishPacket holds the fields of a PUBLISH packet.
+type publishPacket struct {
+	qos      byte
+	dup      bool
+	topic    string
+	packetID uint16
+	payload  []byte
+}
+
+// subscription is a topic filter of a SUBSCRIBE packet with its requested QoS.
+type subscription struct {
+	filter string
+	qos    byte
+}
+
+// readPacket reads a packet. Packets larger than maxSize bytes are rejected before their body is read.
+func readPacket(reader *bufio.Reader, maxSize int) (packet, error) {
+	header, err := reader.ReadByte()
+	if err != nil {
+		return packet{}, err //nolint:wrapcheck // io.EOF is checked by the caller.
+	}
+
+	length, err := readRemainingLength(reader)
+	if err != nil {
+		return packet{}, err
+	}
+
+	if length > maxSize {
+		return packet{}, fmt.Errorf("%w: %d bytes", ErrPacketTooLarge, length)
+	}
+
+	body := make([]byte, length)
+
+	_, err = io.ReadFull(reader, body)
+	if err != nil {
+		return packet{}, fmt.Errorf("failed to read packet: %w", err)
+	}
+
+	return packet{header: header, body: body}, nil
+}
+
+// readRemainingLength reads the variable length encoding of the fixed header (at most 4 bytes).
+func readRemainingLength(reader io.ByteReader) (int, error) {
+	length := 0
+
+	for i := range maxRemainingLengthBytes {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read remaining length: %w", err)
+		}
+
+		length |= int(b&0x7f) << (7 * i) //nolint:mnd
+		if b&0x80 == 0 {
+			return length, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: remaining length exceeds %d bytes", ErrMalformedPacket, maxRemainingLengthBytes)
+}
+
+// encodePacket encodes a packet with its fixed header.
+func encodePacket(header byte, body []byte) []byte {
+	encoded := make([]byte, 0, 1+maxRemainingLengthBytes+len(body))
+	encoded = append(encoded, header)
+
+	length := len(body)
+	for {
+		b := byte(length & 0x7f) //nolint:mnd
+		length >>= 7
+
+		if length == 0 {
+			encoded = append(encoded, b)
+
+			break
+		}
+
+		encoded = append(encoded, b|0x80) //nolint:mnd
+	}
+
+	return append(encoded, body...)
+}
+
+// bodyReader reads the fields of a packet body.
+type bodyReader struct {
+	data []byte
+}
+
+func (r *bodyReader) byte() (byte, error) {
+	if len(r.data) < 1 {
+		return 0, ErrMalformedPacket
+	}
+
+	b := r.data[0]
+	r.data = r.data[1:]
+
+	return b, nil
+}
+
+func (r *bodyReader) uint16() (uint16, error) {
+	if len(r.data) < 2 { //nolint:mnd
+		return 0, ErrMalformedPacket
+	}
+
+	v := binary.BigEndian.Uint16(r.data)
+	r.data = r.data[2:]
+
+	return v, nil
+}
+
+// bytes reads binary data prefixed with its 2-byte length.
+func (r *bodyReader) bytes() ([]byte, error) {
+	length, err := r.uint16()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(r.data) < int(length) {
+		return nil, ErrMalformedPacket
+	}
+
+	b := r.data[:length]
+	r.data = r.data[length:]
+
+	return b, nil
+}
+
+// string reads a UTF-8 string prefixed with its 2-byte length.
+func (r *bodyReader) string() (string, error) {
+	b, err := r.bytes()
+
+	return string(b), err
+}
+
+// rest returns the unread data.
+func (r *bodyReader) rest() []byte {
+	b := r.data
+	r.data = nil
+
+	return b
+}
+
+func (r *bodyReader) empty() bool {
+	return len(r.data) == 0
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s))) //nolint:gosec // Topics are shorter than 65536 bytes.
+
+	return append(b, s...)
+}
+
+// parseConnect parses a CONNECT packet. The will message and the credentials are read, but not used.
+func parseConnect(p packet) (connectPacket, error) {
+	reader := &bodyReader{data: p.body}
+
+	name, err := reader.string()
+	if err != nil {
+		return connectPacket{}, err
+	}
+
+	level, err := reader.byte()
+	if err != nil {
+		return connectPacket{}, err
+	}
+
+	connect := connectPacket{protocolName: name, protocolLevel: level} //nolint:exhaustruct
+	if name != protocolName || level != protocolLevel311 {
+		// The rest of the packet may have a different layout, so it is not parsed.
+		return connect, nil
+	}
+
+	flags, err := reader.byte()
+	if err != nil || flags&connectFlagReserved != 0 {
+		return connectPacket{}, ErrMalformedPacket
+	}
+
+	connect.cleanSession = flags&connectFlagCleanSession != 0
+
+	connect.keepAlive, err = reader.uint16()
+	if err != nil {
+		return connectPacket{}, err
+	}
+
+	connect.clientID, err = reader.string()
+	if err != nil {
+		return connectPacket{}, err
+	}
+
+	fields := 0
+	if flags&connectFlagWill != 0 {
+		fields += 2 // will topic and will message
+	} else if flags&(connectFlagWillQoS|connectFlagWillRetain) != 0 {
+		return connectPacket{}, ErrMalformedPacket
+	}
+
+	if flags&connectFlagUsername != 0 {
+		fields++
+	}
+
+	if flags&connectFlagPassword != 0 {
+		fields++
+	}
+
+	for range fields {
+		_, err = reader.bytes()
+		if err != nil {
+			return connectPacket{}, err
+		}
+	}
+
+	return connect, nil
+}
+
+// parsePublish parses a PUBLISH packet.
+func parsePublish(p packet) (publishPacket, error) {
+	reader := &bodyReader{data: p.body}
+
+	publish := publishPacket{ //nolint:exhaustruct
+		qos: (p.flags() >> publishFlagQoSShift) & publishFlagQoSMask,
+		dup: p.flags()&0x08 != 0, //nolint:mnd
+	}
+	if publish.qos > 2 { //nolint:mnd
+		return publishPacket{}, fmt.Errorf("%w: invalid QoS", ErrMalformedPacket)
+	}
+
+	var err error
+
+	publish.topic, err = reader.string()
+	if err != nil {
+		return publishPacket{}, err
+	}
+
+	if publish.qos > 0 {
+		publish.packetID, err = reader.uint16()
+		if err != nil {
+			return publishPacket{}, err
+		}
+	}
+
+	publish.payload = reader.rest()
+
+	return publish, nil
+}
+
+// parseSubscribe parses a SUBSCRIBE packet.
+func parseSubscribe(p packet) (uint16, []subscription, error) {
+	if p.flags() != subscribeFixedHeaderFlags {
+		return 0, nil, ErrMalformedPacket
+	}
+
+	reader := &bodyReader{data: p.body}
+
+	packetID, err := reader.uint16()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var subscriptions []subscription
+
+	for !reader.empty() {
+		filter, err := reader.string()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		qos, err := reader.byte()
+		if err != nil || qos > 2 { //nolint:mnd
+			return 0, nil, ErrMalformedPacket
+		}
+
+		subscriptions = append(subscriptions, subscription{filter: filter, qos: qos})
+	}
+
+	if len(subscriptions) == 0 {
+		return 0, nil, ErrMalformedPacket
+	}
+
+	return packetID, subscriptions, nil
+}
+
+// parseUnsubscribe parses an UNSUBSCRIBE packet.
+func parseUnsubscribe(p packet) (uint16, []string, error) {
+	if p.flags() != unsubscribeFixedHeaderFlags {
+		return 0, nil, ErrMalformedPacket
+	}
+
+	reader := &bodyReader{data: p.body}
+
+	packetID, err := reader.uint16()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var filters []string
+
+	for !reader.empty() {
+		filter, err := reader.string()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		filters = append(filters, filter)
+	}
+
+	if len(filters) == 0 {
+		return 0, nil, ErrMalformedPacket
+	}
+
+	return packetID, filters, nil
+}
+
+// parsePacketID parses the body of a packet that only carries a packet identifier, e.g. PUBREL.
+func parsePacketID(p packet) (uint16, error) {
+	reader := &bodyReader{data: p.body}
+
+	packetID, err := reader.uint16()
+	if err != nil || !reader.empty() {
+		return 0, ErrMalformedPacket
+	}
+
+	return packetID, nil
+}
+
+func encodeConnack(returnCode byte) []byte {
+	return encodePacket(packetConnack<<packetTypeShift, []byte{0, returnCode})
+}
+
+func encodePacketID(packetType byte, flags byte, packetID uint16) []byte {
+	return encodePacket(packetType<<packetTypeShift|flags, binary.BigEndian.AppendUint16(nil, packetID))
+}
+
+func encodeSuback(packetID uint16, returnCodes []byte) []byte {
+	body := binary.BigEndian.AppendUint16(nil, packetID)
+
+	return encodePacket(packetSuback<<packetTypeShift, append(body, returnCodes...))
+}
+
+// encodePublish encodes a PUBLISH packet with QoS 0, which is how the broker delivers messages.
+func encodePublish(topic string, payload []byte) []byte {
+	body := appendString(make([]byte, 0, 2+len(topic)+len(payload)), topic) //nolint:mnd
+
+	return encodePacket(packetPublish<<packetTypeShift, append(body, payload...))
+}
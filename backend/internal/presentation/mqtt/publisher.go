@@ -0,0 +1,58 @@
+package mqtt
+
+import (
+	"errors"
+	"sync"
+)
+
+// MessagePublisher publishes a message to a topic, like the embedded broker or the subscriber on an external broker.
+type MessagePublisher interface {
+	Publish(topic string, payload []byte) error
+}
+
+// DevicePublisher sends messages from the platform to the devices, such as twin deltas and commands,
+// through the brokers attached to it. It implements service.DeviceTwinPublisher and service.DeviceCommandPublisher.
+type DevicePublisher struct {
+	mu         sync.Mutex
+	publishers []MessagePublisher
+}
+
+// NewDevicePublisher creates a new instance of DevicePublisher without any broker.
+func NewDevicePublisher() *DevicePublisher {
+	return &DevicePublisher{mu: sync.Mutex{}, publishers: nil}
+}
+
+// Attach adds a broker the messages are published to.
+func (p *DevicePublisher) Attach(publisher MessagePublisher) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.publishers = append(p.publishers, publisher)
+}
+
+// publish sends a message through every attached broker. It reports whether any broker accepted it,
+// and returns the errors of the brokers that failed. A broker the device is not subscribed to is not a failure.
+func (p *DevicePublisher) publish(topic string, payload []byte) (bool, error) {
+	p.mu.Lock()
+	publishers := p.publishers
+	p.mu.Unlock()
+
+	var (
+		delivered bool
+		errs      []error
+	)
+
+	for _, publisher := range publishers {
+		err := publisher.Publish(topic, payload)
+
+		switch {
+		case err == nil:
+			delivered = true
+		case errors.Is(err, ErrNoSubscribers):
+		default:
+			errs = append(errs, err)
+		}
+	}
+
+	return delivered, errors.Join(errs...)
+}
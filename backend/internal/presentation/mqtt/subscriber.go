@@ -0,0 +1,271 @@
+// Package mqtt provides the MQTT interface of the backend, which receives sensor data, twin states,
+// answers to commands and the progress of firmware updates from devices,
+// and sends them the deltas of their twins and their commands.
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/usecase"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultTopic is the topic filter the backend subscribes to. The "+" level is the device ID.
+	DefaultTopic = "devices/+/telemetry"
+	// DefaultClientID is the MQTT client ID of the backend.
+	DefaultClientID = "iot-backend"
+	// DefaultQoS is the QoS level of the subscription.
+	DefaultQoS = 1
+
+	connectRetryInterval = 5 * time.Second
+	publishTimeout       = 5 * time.Second
+	disconnectQuiesce    = 250 // milliseconds to wait for in-flight work when disconnecting
+)
+
+// SubscriberConfig holds the settings of the connection to the MQTT broker.
+type SubscriberConfig struct {
+	// BrokerURL is the URL of the broker, e.g. "tls://mqtt-broker:8883".
+	BrokerURL string
+	// ClientID is the MQTT client ID. If empty, DefaultClientID is used.
+	ClientID string
+	// Topic is the topic filter to subscribe to. If empty, DefaultTopic is used.
+	Topic string
+	// QoS is the QoS level of the subscription.
+	QoS byte
+	// TLSConfig holds the client certificate and the CA of the broker for mTLS. It is required for "tls://" URLs.
+	TLSConfig *tls.Config
+}
+
+// Subscriber subscribes to the sensor data topics of the devices and passes the messages to the IngestionUsecase.
+// It also subscribes to the topics on which gateways report for their children, e.g. "devices/+/telemetry/+",
+// to TwinReportedTopic, whose messages are passed to the DeviceTwinUsecase,
+// to CommandResponseTopic, whose messages are passed to the DeviceCommandUsecase,
+// and to FirmwareProgressTopic, whose messages are passed to the FirmwareCampaignUsecase.
+type Subscriber struct {
+	config        SubscriberConfig
+	ingestion     usecase.IngestionUsecase
+	twins         usecase.DeviceTwinUsecase
+	commands      usecase.DeviceCommandUsecase
+	firmware      usecase.FirmwareCampaignUsecase
+	topic         deviceTopic
+	twinTopic     deviceTopic
+	responseTopic deviceTopic
+	firmwareTopic deviceTopic
+
+	mu sync.Mutex
+	// client is the client of the current run, or nil if the subscriber is not running.
+	client paho.Client
+}
+
+// NewSubscriber creates a new instance of Subscriber.
+func NewSubscriber(
+	config SubscriberConfig,
+	ingestion usecase.IngestionUsecase,
+	twins usecase.DeviceTwinUsecase,
+	commands usecase.DeviceCommandUsecase,
+	firmware usecase.FirmwareCampaignUsecase,
+) (*Subscriber, error) {
+	if config.ClientID == "" {
+		config.ClientID = DefaultClientID
+	}
+
+	if config.Topic == "" {
+		config.Topic = DefaultTopic
+	}
+
+	topic, err := parseDeviceTopic(config.Topic)
+	if err != nil {
+		return nil, err
+	}
+
+	twinTopic, err := parseDeviceTopic(TwinReportedTopic)
+	if err != nil {
+		return nil, err
+	}
+
+	responseTopic, err := parseDeviceTopic(CommandResponseTopic)
+	if err != nil {
+		return nil, err
+	}
+
+	firmwareTopic, err := parseDeviceTopic(FirmwareProgressTopic)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Subscriber{
+		config:        config,
+		ingestion:     ingestion,
+		twins:         twins,
+		commands:      commands,
+		firmware:      firmware,
+		topic:         topic,
+		twinTopic:     twinTopic,
+		responseTopic: responseTopic,
+		firmwareTopic: firmwareTopic,
+		mu:            sync.Mutex{},
+		client:        nil,
+	}, nil
+}
+
+// Run connects to the broker and receives messages until ctx is done, and then disconnects.
+// The connection is retried until it succeeds, and it is re-established automatically when it is lost.
+func (s *Subscriber) Run(ctx context.Context) error {
+	// Messages received while disconnecting are still queued, so ctx is not passed on to the ingestion.
+	handlerCtx := context.WithoutCancel(ctx)
+
+	opts := paho.NewClientOptions().
+		AddBroker(s.config.BrokerURL).
+		SetClientID(s.config.ClientID).
+		SetTLSConfig(s.config.TLSConfig).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(connectRetryInterval).
+		SetOnConnectHandler(func(client paho.Client) {
+			// The subscriptions are renewed on every connection, because the session is not kept by the broker.
+			topics := []string{
+				s.config.Topic, s.topic.childFilter(), TwinReportedTopic, CommandResponseTopic, FirmwareProgressTopic,
+			}
+
+			filters := make(map[string]byte, len(topics))
+			for _, topic := range topics {
+				filters[topic] = s.config.QoS
+			}
+
+			token := client.SubscribeMultiple(filters, func(_ paho.Client, message paho.Message) {
+				s.handle(handlerCtx, message.Topic(), message.Payload())
+			})
+			token.Wait()
+
+			err := token.Error()
+			if err != nil {
+				log.Printf("failed to subscribe to %s: %v", strings.Join(topics, ", "), err)
+
+				return
+			}
+
+			log.Printf("MQTT subscriber connected to %s, subscribed to %s",
+				s.config.BrokerURL, strings.Join(topics, ", "))
+		}).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			log.Printf("MQTT connection lost, reconnecting: %v", err)
+		})
+
+	client := paho.NewClient(opts)
+	s.setClient(client)
+
+	defer func() {
+		s.setClient(nil)
+		client.Disconnect(disconnectQuiesce)
+	}()
+
+	token := client.Connect()
+	select {
+	case <-token.Done():
+		err := token.Error()
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrConnect, err)
+		}
+	case <-ctx.Done():
+		return nil
+	}
+
+	<-ctx.Done()
+
+	return nil
+}
+
+// Publish publishes a message to the broker at the QoS of the subscription, e.g. the delta of a device twin
+// or a command.
+// It returns ErrNotConnected if the subscriber is not connected.
+func (s *Subscriber) Publish(topic string, payload []byte) error {
+	s.mu.Lock()
+	client := s.client
+	s.mu.Unlock()
+
+	if client == nil || !client.IsConnectionOpen() {
+		return ErrNotConnected
+	}
+
+	token := client.Publish(topic, s.config.QoS, false, payload)
+	if !token.WaitTimeout(publishTimeout) {
+		return fmt.Errorf("%w: %s", ErrPublishTimeout, topic)
+	}
+
+	return token.Error()
+}
+
+func (s *Subscriber) setClient(client paho.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.client = client
+}
+
+// handle passes a message to the DeviceTwinUsecase if it is on TwinReportedTopic, to the DeviceCommandUsecase
+// if it is on CommandResponseTopic, to the FirmwareCampaignUsecase if it is on FirmwareProgressTopic,
+// and to the IngestionUsecase otherwise.
+// Messages that cannot be handled are logged and dropped.
+func (s *Subscriber) handle(ctx context.Context, topic string, payload []byte) {
+	deviceID, err := s.twinTopic.DeviceID(topic)
+	if err == nil {
+		_, err = s.twins.ReportState(ctx, usecase.ReportDeviceStateInput{DeviceID: deviceID, State: payload})
+		if err != nil {
+			log.Printf("dropped MQTT message on %s: %v", topic, err)
+		}
+
+		return
+	}
+
+	deviceID, err = s.responseTopic.DeviceID(topic)
+	if err == nil {
+		err = s.commands.AcknowledgeCommand(ctx, usecase.AcknowledgeDeviceCommandInput{
+			DeviceID: deviceID,
+			Response: payload,
+		})
+		if err != nil {
+			log.Printf("dropped MQTT message on %s: %v", topic, err)
+		}
+
+		return
+	}
+
+	deviceID, err = s.firmwareTopic.DeviceID(topic)
+	if err == nil {
+		err = s.firmware.ReportFirmwareProgress(ctx, usecase.ReportFirmwareProgressInput{
+			DeviceID: deviceID,
+			Report:   payload,
+		})
+		if err != nil {
+			log.Printf("dropped MQTT message on %s: %v", topic, err)
+		}
+
+		return
+	}
+
+	input, err := s.topic.ingestInput(topic, payload)
+	if err != nil {
+		log.Printf("dropped MQTT message: %v", err)
+
+		return
+	}
+
+	err = s.ingestion.Ingest(ctx, input)
+	if err != nil {
+		log.Printf("dropped MQTT message on %s: %v", topic, err)
+	}
+}
+
+// DeviceIDFromTopic reads the device ID from the "+" level of a topic that matches the topic filter.
+func (s *Subscriber) DeviceIDFromTopic(topic string) (uuid.UUID, error) {
+	return s.topic.DeviceID(topic)
+}
@@ -0,0 +1,573 @@
+package mqtt_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/service"
+	"backend/internal/presentation/mqtt"
+	"backend/internal/usecase"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// FakeIngestionUsecase records the ingested readings for testing.
+type FakeIngestionUsecase struct {
+	mu     sync.Mutex
+	inputs []usecase.IngestSensorDataInput
+	err    error
+}
+
+// Ingest records a reading, or returns the error set by SetErr.
+func (f *FakeIngestionUsecase) Ingest(_ context.Context, input usecase.IngestSensorDataInput) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+
+	f.inputs = append(f.inputs, input)
+
+	return nil
+}
+
+// SetErr makes Ingest fail with err.
+func (f *FakeIngestionUsecase) SetErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.err = err
+}
+
+// Run does nothing, because the readings are not written.
+func (f *FakeIngestionUsecase) Run(_ context.Context) {}
+
+// Inputs returns a copy of the recorded readings.
+func (f *FakeIngestionUsecase) Inputs() []usecase.IngestSensorDataInput {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]usecase.IngestSensorDataInput(nil), f.inputs...)
+}
+
+// FakeDeviceTwinUsecase records the reported states for testing.
+type FakeDeviceTwinUsecase struct {
+	mu      sync.Mutex
+	reports []usecase.ReportDeviceStateInput
+}
+
+// GetDeviceTwin returns an empty twin.
+func (f *FakeDeviceTwinUsecase) GetDeviceTwin(
+	_ context.Context,
+	deviceID uuid.UUID,
+) (*usecase.DeviceTwinOutput, error) {
+	return usecase.NewDeviceTwinOutput(entity.NewDeviceTwin(deviceID)), nil
+}
+
+// SetDesiredState is not used by the MQTT interface.
+func (f *FakeDeviceTwinUsecase) SetDesiredState(
+	ctx context.Context,
+	input usecase.SetDesiredStateInput,
+) (*usecase.DeviceTwinOutput, error) {
+	return f.GetDeviceTwin(ctx, input.DeviceID)
+}
+
+// PatchDesiredState is not used by the MQTT interface.
+func (f *FakeDeviceTwinUsecase) PatchDesiredState(
+	ctx context.Context,
+	input usecase.PatchDesiredStateInput,
+) (*usecase.DeviceTwinOutput, error) {
+	return f.GetDeviceTwin(ctx, input.DeviceID)
+}
+
+// ReportState records a reported state.
+func (f *FakeDeviceTwinUsecase) ReportState(
+	ctx context.Context,
+	input usecase.ReportDeviceStateInput,
+) (*usecase.DeviceTwinOutput, error) {
+	f.mu.Lock()
+	f.reports = append(f.reports, input)
+	f.mu.Unlock()
+
+	return f.GetDeviceTwin(ctx, input.DeviceID)
+}
+
+// Reports returns a copy of the recorded reported states.
+func (f *FakeDeviceTwinUsecase) Reports() []usecase.ReportDeviceStateInput {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]usecase.ReportDeviceStateInput(nil), f.reports...)
+}
+
+// FakeDeviceCommandUsecase records the answers to commands for testing.
+type FakeDeviceCommandUsecase struct {
+	mu      sync.Mutex
+	answers []usecase.AcknowledgeDeviceCommandInput
+}
+
+// SendCommand is not used by the MQTT interface.
+func (f *FakeDeviceCommandUsecase) SendCommand(
+	_ context.Context,
+	_ usecase.SendDeviceCommandInput,
+) (*usecase.DeviceCommandOutput, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// GetCommand is not used by the MQTT interface.
+func (f *FakeDeviceCommandUsecase) GetCommand(
+	_ context.Context,
+	_ usecase.GetDeviceCommandInput,
+) (*usecase.DeviceCommandOutput, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// AcknowledgeCommand records an answer.
+func (f *FakeDeviceCommandUsecase) AcknowledgeCommand(
+	_ context.Context,
+	input usecase.AcknowledgeDeviceCommandInput,
+) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.answers = append(f.answers, input)
+
+	return nil
+}
+
+// Run does nothing, because the commands are not stored.
+func (f *FakeDeviceCommandUsecase) Run(_ context.Context) {}
+
+// Answers returns a copy of the recorded answers.
+func (f *FakeDeviceCommandUsecase) Answers() []usecase.AcknowledgeDeviceCommandInput {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]usecase.AcknowledgeDeviceCommandInput(nil), f.answers...)
+}
+
+// FakeFirmwareCampaignUsecase records the progress of firmware updates for testing.
+type FakeFirmwareCampaignUsecase struct {
+	mu      sync.Mutex
+	reports []usecase.ReportFirmwareProgressInput
+}
+
+// CreateFirmwareCampaign is not used by the MQTT interface.
+func (f *FakeFirmwareCampaignUsecase) CreateFirmwareCampaign(
+	_ context.Context,
+	_ usecase.CreateFirmwareCampaignInput,
+) (*usecase.FirmwareCampaignOutput, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// GetFirmwareCampaign is not used by the MQTT interface.
+func (f *FakeFirmwareCampaignUsecase) GetFirmwareCampaign(
+	_ context.Context,
+	_ uuid.UUID,
+) (*usecase.FirmwareCampaignOutput, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// ListFirmwareCampaigns is not used by the MQTT interface.
+func (f *FakeFirmwareCampaignUsecase) ListFirmwareCampaigns(
+	_ context.Context,
+) ([]*usecase.FirmwareCampaignOutput, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// ListFirmwareCampaignDevices is not used by the MQTT interface.
+func (f *FakeFirmwareCampaignUsecase) ListFirmwareCampaignDevices(
+	_ context.Context,
+	_ usecase.ListFirmwareCampaignDevicesInput,
+) ([]*usecase.FirmwareUpdateOutput, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// PauseFirmwareCampaign is not used by the MQTT interface.
+func (f *FakeFirmwareCampaignUsecase) PauseFirmwareCampaign(
+	_ context.Context,
+	_ uuid.UUID,
+) (*usecase.FirmwareCampaignOutput, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// ResumeFirmwareCampaign is not used by the MQTT interface.
+func (f *FakeFirmwareCampaignUsecase) ResumeFirmwareCampaign(
+	_ context.Context,
+	_ usecase.ResumeFirmwareCampaignInput,
+) (*usecase.FirmwareCampaignOutput, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// CancelFirmwareCampaign is not used by the MQTT interface.
+func (f *FakeFirmwareCampaignUsecase) CancelFirmwareCampaign(
+	_ context.Context,
+	_ uuid.UUID,
+) (*usecase.FirmwareCampaignOutput, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// ReportFirmwareProgress records a report.
+func (f *FakeFirmwareCampaignUsecase) ReportFirmwareProgress(
+	_ context.Context,
+	input usecase.ReportFirmwareProgressInput,
+) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.reports = append(f.reports, input)
+
+	return nil
+}
+
+// Run does nothing, because there are no campaigns.
+func (f *FakeFirmwareCampaignUsecase) Run(_ context.Context) {}
+
+// Reports returns a copy of the recorded reports.
+func (f *FakeFirmwareCampaignUsecase) Reports() []usecase.ReportFirmwareProgressInput {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]usecase.ReportFirmwareProgressInput(nil), f.reports...)
+}
+
+// testPKI is a CA that issues the certificates of the test broker and its clients.
+type testPKI struct {
+	dir  string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+// newTestPKI creates a self-signed CA and writes its certificate to ca.crt in a temporary directory.
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{ //nolint:exhaustruct
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test MQTT CA"}, //nolint:exhaustruct
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	dir := t.TempDir()
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Headers: nil, Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.crt"), certPEM, 0o600))
+
+	return &testPKI{dir: dir, cert: cert, key: key, pool: pool}
+}
+
+// issue issues a certificate and writes it to <name>.crt and <name>.key.
+func (p *testPKI) issue(t *testing.T, name string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{ //nolint:exhaustruct
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name}, //nolint:exhaustruct
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, p.cert, key.Public(), p.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Headers: nil, Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Headers: nil, Bytes: keyDER})
+	require.NoError(t, os.WriteFile(filepath.Join(p.dir, name+".crt"), certPEM, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(p.dir, name+".key"), keyPEM, 0o600))
+
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	return certificate
+}
+
+func (p *testPKI) path(name string) string {
+	return filepath.Join(p.dir, name)
+}
+
+// TestNewSubscriber tests the validation of the topic filter.
+func TestNewSubscriber(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		desc    string
+		topic   string
+		wantErr bool
+	}{
+		{name: "default topic", desc: "Verify that an empty topic uses the default.", topic: "", wantErr: false},
+		{name: "custom topic", desc: "Verify that a custom topic is accepted.", topic: "site/a/+/data", wantErr: false},
+		{name: "no wildcard", desc: "Verify that a topic without \"+\" is rejected.", topic: "x", wantErr: true},
+		{name: "two wildcards", desc: "Verify that an ambiguous topic is rejected.", topic: "+/+/data", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := mqtt.NewSubscriber(mqtt.SubscriberConfig{ //nolint:exhaustruct
+				BrokerURL: "tcp://127.0.0.1:1883",
+				Topic:     tt.topic,
+			}, &FakeIngestionUsecase{}, &FakeDeviceTwinUsecase{}, &FakeDeviceCommandUsecase{}, //nolint:exhaustruct
+				&FakeFirmwareCampaignUsecase{}) //nolint:exhaustruct
+
+			if tt.wantErr {
+				require.ErrorIs(t, err, mqtt.ErrInvalidTopicFilter)
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+// TestDeviceIDFromTopic tests reading the device ID from the topic of a message.
+func TestDeviceIDFromTopic(t *testing.T) {
+	t.Parallel()
+
+	subscriber, err := mqtt.NewSubscriber(mqtt.SubscriberConfig{ //nolint:exhaustruct
+		BrokerURL: "tcp://127.0.0.1:1883",
+	}, &FakeIngestionUsecase{}, &FakeDeviceTwinUsecase{}, &FakeDeviceCommandUsecase{}, //nolint:exhaustruct
+		&FakeFirmwareCampaignUsecase{}) //nolint:exhaustruct
+	require.NoError(t, err)
+
+	deviceID := uuid.New()
+
+	got, err := subscriber.DeviceIDFromTopic("devices/" + deviceID.String() + "/telemetry")
+	require.NoError(t, err)
+	assert.Equal(t, deviceID, got)
+
+	for _, topic := range []string{"devices/not-a-uuid/telemetry", "devices/" + deviceID.String()} {
+		_, err = subscriber.DeviceIDFromTopic(topic)
+		require.ErrorIs(t, err, mqtt.ErrInvalidTopic, topic)
+	}
+}
+
+// TestSubscriber tests receiving sensor data from an in-process broker over mTLS.
+func TestSubscriber(t *testing.T) {
+	t.Parallel()
+
+	pki := newTestPKI(t)
+	serverCert := pki.issue(t, "broker", x509.ExtKeyUsageServerAuth)
+	deviceCert := pki.issue(t, "device", x509.ExtKeyUsageClientAuth)
+	pki.issue(t, "backend", x509.ExtKeyUsageClientAuth)
+
+	broker := newTestBroker(t, &tls.Config{ //nolint:exhaustruct
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    pki.pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	})
+
+	tlsConfig, err := mqtt.LoadTLSConfig(pki.path("ca.crt"), pki.path("backend.crt"), pki.path("backend.key"))
+	require.NoError(t, err)
+
+	ingestion := &FakeIngestionUsecase{}       //nolint:exhaustruct
+	twins := &FakeDeviceTwinUsecase{}          //nolint:exhaustruct
+	commands := &FakeDeviceCommandUsecase{}    //nolint:exhaustruct
+	firmware := &FakeFirmwareCampaignUsecase{} //nolint:exhaustruct
+	subscriber, err := mqtt.NewSubscriber(mqtt.SubscriberConfig{
+		BrokerURL: broker.URL(),
+		ClientID:  "backend-test",
+		Topic:     mqtt.DefaultTopic,
+		QoS:       mqtt.DefaultQoS,
+		TLSConfig: tlsConfig,
+	}, ingestion, twins, commands, firmware)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, subscriber.Publish("devices/x/twin/delta", []byte(`{}`)), mqtt.ErrNotConnected)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+
+	wg.Go(func() {
+		assert.NoError(t, subscriber.Run(ctx))
+	})
+
+	// A device publishes its readings with its own client certificate.
+	device := paho.NewClient(paho.NewClientOptions().
+		AddBroker(broker.URL()).
+		SetClientID("device-test").
+		SetTLSConfig(&tls.Config{ //nolint:exhaustruct
+			RootCAs:      pki.pool,
+			Certificates: []tls.Certificate{deviceCert},
+			MinVersion:   tls.VersionTLS12,
+		}))
+	token := device.Connect()
+	require.True(t, token.WaitTimeout(5*time.Second))
+	require.NoError(t, token.Error())
+
+	defer device.Disconnect(0)
+
+	publish := func(topic, payload string) {
+		token := device.Publish(topic, 1, false, payload)
+		require.True(t, token.WaitTimeout(5*time.Second))
+		require.NoError(t, token.Error())
+	}
+
+	deviceID := uuid.New()
+	topic := "devices/" + deviceID.String() + "/telemetry"
+
+	// Publish until the subscriber has subscribed and received the reading.
+	require.Eventually(t, func() bool {
+		publish(topic, `{"temperature": 21.5}`)
+
+		return len(ingestion.Inputs()) > 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// Messages on other topics or without a device ID are not ingested.
+	// The broker delivers in order, so they would have arrived before the last reading.
+	publish("devices/not-a-uuid/telemetry", `{"invalid": true}`)
+	publish("devices/"+deviceID.String()+"/status", `{"invalid": true}`)
+	publish(topic, `{"temperature": 22.0}`)
+
+	require.Eventually(t, func() bool {
+		inputs := ingestion.Inputs()
+
+		return string(inputs[len(inputs)-1].Payload) == `{"temperature": 22.0}`
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for _, input := range ingestion.Inputs() {
+		assert.Equal(t, deviceID, input.DeviceID)
+		assert.Equal(t, uuid.Nil, input.GatewayID)
+		assert.NotContains(t, string(input.Payload), "invalid")
+	}
+
+	// A gateway reports for its children under its own topic, followed by the ID of the child.
+	childID := uuid.New()
+	publish(topic+"/"+childID.String(), `{"temperature": 23.0}`)
+
+	require.Eventually(t, func() bool {
+		inputs := ingestion.Inputs()
+
+		return inputs[len(inputs)-1].DeviceID == childID
+	}, 5*time.Second, 10*time.Millisecond)
+
+	inputs := ingestion.Inputs()
+	assert.Equal(t, deviceID, inputs[len(inputs)-1].GatewayID)
+
+	// Reported states are passed to the twins, and the deltas are published to the devices.
+	publish("devices/"+deviceID.String()+"/twin/reported", `{"config": {"sync_interval_sec": 30}}`)
+
+	require.Eventually(t, func() bool { return len(twins.Reports()) > 0 }, 5*time.Second, 10*time.Millisecond)
+
+	report := twins.Reports()[0]
+	assert.Equal(t, deviceID, report.DeviceID)
+	assert.JSONEq(t, `{"config": {"sync_interval_sec": 30}}`, string(report.State))
+	assert.Len(t, ingestion.Inputs(), len(inputs), "reported states are not ingested")
+
+	deltas := make(chan string, 1)
+	token = device.Subscribe("devices/"+deviceID.String()+"/twin/delta", 1, func(_ paho.Client, m paho.Message) {
+		deltas <- string(m.Payload())
+	})
+	require.True(t, token.WaitTimeout(5*time.Second))
+	require.NoError(t, token.Error())
+
+	publisher := mqtt.NewDevicePublisher()
+	publisher.Attach(subscriber)
+	require.NoError(t, publisher.PublishDelta(ctx, service.DeviceTwinDelta{
+		DeviceID: deviceID,
+		Version:  2,
+		State:    map[string]any{"config": map[string]any{"sync_interval_sec": 60}},
+	}))
+
+	select {
+	case got := <-deltas:
+		assert.JSONEq(t, `{"version": 2, "state": {"config": {"sync_interval_sec": 60}}}`, got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("delta was not delivered")
+	}
+
+	// Commands are published to the devices, and their answers are passed to the commands.
+	received := make(chan string, 1)
+	token = device.Subscribe("devices/"+deviceID.String()+"/commands", 1, func(_ paho.Client, m paho.Message) {
+		received <- string(m.Payload())
+	})
+	require.True(t, token.WaitTimeout(5*time.Second))
+	require.NoError(t, token.Error())
+
+	commandID := uuid.New()
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, publisher.PublishCommand(ctx, service.DeviceCommandMessage{
+		ID:        commandID,
+		DeviceID:  deviceID,
+		Name:      "reboot",
+		Payload:   map[string]any{"delaySec": 5},
+		ExpiresAt: expiresAt,
+	}))
+
+	select {
+	case got := <-received:
+		assert.JSONEq(t, `{"id": "`+commandID.String()+`", "name": "reboot", "payload": {"delaySec": 5},
+			"expiresAt": "2030-01-01T00:00:00Z"}`, got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("command was not delivered")
+	}
+
+	response := `{"id": "` + commandID.String() + `", "status": "ACKED"}`
+	publish("devices/"+deviceID.String()+"/commands/response", response)
+
+	require.Eventually(t, func() bool { return len(commands.Answers()) > 0 }, 5*time.Second, 10*time.Millisecond)
+
+	answer := commands.Answers()[0]
+	assert.Equal(t, deviceID, answer.DeviceID)
+	assert.JSONEq(t, response, string(answer.Response))
+
+	// The progress of firmware updates is passed to the campaigns.
+	progress := `{"campaignId": "` + uuid.NewString() + `", "status": "DOWNLOADING", "progress": 40}`
+	publish("devices/"+deviceID.String()+"/firmware/progress", progress)
+
+	require.Eventually(t, func() bool { return len(firmware.Reports()) > 0 }, 5*time.Second, 10*time.Millisecond)
+
+	firmwareReport := firmware.Reports()[0]
+	assert.Equal(t, deviceID, firmwareReport.DeviceID)
+	assert.JSONEq(t, progress, string(firmwareReport.Report))
+
+	// Run returns when ctx is done.
+	cancel()
+	wg.Wait()
+}
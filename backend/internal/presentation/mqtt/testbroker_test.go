@@ -0,0 +1,286 @@
+package mqtt_test
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// MQTT 3.1.1 control packet types used by the test broker.
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetUnsubscribe = 10
+	packetUnsuback    = 11
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+)
+
+var errMalformedPacket = errors.New("malformed packet")
+
+// testBroker is a minimal in-process MQTT 3.1.1 broker for testing.
+// It accepts QoS 0 and 1 publications and delivers them to the matching subscriptions at QoS 0.
+// Sessions, retained messages and wills are not supported.
+type testBroker struct {
+	listener net.Listener
+
+	mu            sync.Mutex
+	subscriptions map[*testBrokerConn][]string
+	wg            sync.WaitGroup
+}
+
+// testBrokerConn is a client connection of the test broker.
+type testBrokerConn struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+}
+
+// newTestBroker starts a test broker on a random local port with the given TLS configuration.
+func newTestBroker(t *testing.T, tlsConfig *tls.Config) *testBroker {
+	t.Helper()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	if err != nil {
+		t.Fatalf("failed to start test broker: %v", err)
+	}
+
+	broker := &testBroker{
+		listener:      listener,
+		mu:            sync.Mutex{},
+		subscriptions: make(map[*testBrokerConn][]string),
+		wg:            sync.WaitGroup{},
+	}
+
+	broker.wg.Go(broker.serve)
+	t.Cleanup(broker.close)
+
+	return broker
+}
+
+// URL returns the broker URL for MQTT clients.
+func (b *testBroker) URL() string {
+	return "tls://" + b.listener.Addr().String()
+}
+
+func (b *testBroker) close() {
+	_ = b.listener.Close()
+
+	b.mu.Lock()
+	for client := range b.subscriptions {
+		_ = client.conn.Close()
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
+
+func (b *testBroker) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		client := &testBrokerConn{conn: conn, writeMu: sync.Mutex{}}
+
+		b.mu.Lock()
+		b.subscriptions[client] = nil
+		b.mu.Unlock()
+
+		b.wg.Go(func() {
+			defer func() {
+				b.mu.Lock()
+				delete(b.subscriptions, client)
+				b.mu.Unlock()
+
+				_ = conn.Close()
+			}()
+
+			_ = b.handle(client)
+		})
+	}
+}
+
+// handle reads the packets of a client until it disconnects.
+func (b *testBroker) handle(client *testBrokerConn) error {
+	reader := bufio.NewReader(client.conn)
+
+	for {
+		header, body, err := readPacket(reader)
+		if err != nil {
+			return err
+		}
+
+		switch header >> 4 {
+		case packetConnect:
+			err = client.write(packetConnack<<4, []byte{0, 0})
+		case packetPublish:
+			err = b.publish(client, header, body)
+		case packetSubscribe:
+			err = b.subscribe(client, body)
+		case packetUnsubscribe:
+			err = client.write(packetUnsuback<<4, body[:2])
+		case packetPingreq:
+			err = client.write(packetPingresp<<4, nil)
+		case packetDisconnect:
+			return nil
+		default:
+			return errMalformedPacket
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (b *testBroker) publish(client *testBrokerConn, header byte, body []byte) error {
+	topic, rest, err := readString(body)
+	if err != nil {
+		return err
+	}
+
+	qos := (header >> 1) & 0x03
+	if qos > 0 {
+		if len(rest) < 2 { //nolint:mnd
+			return errMalformedPacket
+		}
+
+		err = client.write(packetPuback<<4, rest[:2])
+		if err != nil {
+			return err
+		}
+
+		rest = rest[2:]
+	}
+
+	delivery := appendString(nil, topic)
+	delivery = append(delivery, rest...)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for subscriber, filters := range b.subscriptions {
+		for _, filter := range filters {
+			if topicMatches(filter, topic) {
+				_ = subscriber.write(packetPublish<<4, delivery)
+
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *testBroker) subscribe(client *testBrokerConn, body []byte) error {
+	if len(body) < 2 { //nolint:mnd
+		return errMalformedPacket
+	}
+
+	ack := append([]byte(nil), body[:2]...)
+	rest := body[2:]
+
+	var filters []string
+
+	for len(rest) > 0 {
+		filter, next, err := readString(rest)
+		if err != nil || len(next) < 1 {
+			return errMalformedPacket
+		}
+
+		filters = append(filters, filter)
+		ack = append(ack, 0) // granted QoS 0
+		rest = next[1:]
+	}
+
+	b.mu.Lock()
+	b.subscriptions[client] = append(b.subscriptions[client], filters...)
+	b.mu.Unlock()
+
+	return client.write(packetSuback<<4, ack)
+}
+
+func (c *testBrokerConn) write(header byte, body []byte) error {
+	packet := []byte{header}
+	packet = binary.AppendUvarint(packet, uint64(len(body))) // MQTT uses the same variable length encoding
+	packet = append(packet, body...)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_, err := c.conn.Write(packet)
+
+	return err
+}
+
+// readPacket reads the fixed header and the body of a packet.
+func readPacket(reader *bufio.Reader) (byte, []byte, error) {
+	header, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	body := make([]byte, length)
+
+	_, err = io.ReadFull(reader, body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return header, body, nil
+}
+
+// readString reads a length-prefixed UTF-8 string.
+func readString(data []byte) (string, []byte, error) {
+	if len(data) < 2 { //nolint:mnd
+		return "", nil, errMalformedPacket
+	}
+
+	length := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+length {
+		return "", nil, errMalformedPacket
+	}
+
+	return string(data[2 : 2+length]), data[2+length:], nil
+}
+
+func appendString(data []byte, s string) []byte {
+	data = binary.BigEndian.AppendUint16(data, uint16(len(s))) //nolint:gosec
+
+	return append(data, s...)
+}
+
+// topicMatches reports whether a topic matches a topic filter with "+" and "#" wildcards.
+func topicMatches(filter, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+
+		if i >= len(topicLevels) || (level != "+" && level != topicLevels[i]) {
+			return false
+		}
+	}
+
+	return len(filterLevels) == len(topicLevels)
+}
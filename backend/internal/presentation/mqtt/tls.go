@@ -0,0 +1,41 @@
+package mqtt
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+const (
+	// DefaultCertPath is the default path of the client certificate of the backend in the `/app/certs` volume.
+	DefaultCertPath = "/app/certs/backend.crt"
+	// DefaultKeyPath is the default path of the private key of the backend in the `/app/certs` volume.
+	DefaultKeyPath = "/app/certs/backend.key"
+)
+
+// LoadTLSConfig creates the mTLS configuration of the connection to the broker.
+// The broker is verified with the CA certificates in caPath, and the backend authenticates
+// itself with the client certificate and private key in certPath and keyPath.
+func LoadTLSConfig(caPath, certPath, keyPath string) (*tls.Config, error) {
+	caPEM, err := os.ReadFile(caPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("%w: %s", ErrNoCACertificate, caPath)
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+
+	return &tls.Config{ //nolint:exhaustruct
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
@@ -0,0 +1,157 @@
+package mqtt
+
+import (
+	"fmt"
+	"strings"
+
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+)
+
+// deviceTopic is a topic filter whose single "+" level is the device ID, e.g. "devices/+/telemetry".
+// A gateway reports the readings of its children on the filter followed by the ID of the child,
+// e.g. "devices/<gateway ID>/telemetry/<child ID>", within its own namespace.
+type deviceTopic struct {
+	filter        string
+	levels        int
+	deviceIDLevel int
+}
+
+// parseDeviceTopic parses a topic filter with exactly one "+" level for the device ID.
+func parseDeviceTopic(filter string) (deviceTopic, error) {
+	levels := strings.Split(filter, "/")
+	deviceIDLevel := -1
+
+	for i, level := range levels {
+		if level == "#" {
+			return deviceTopic{}, fmt.Errorf("%w: %q", ErrInvalidTopicFilter, filter)
+		}
+
+		if level != "+" {
+			continue
+		}
+
+		if deviceIDLevel >= 0 {
+			return deviceTopic{}, fmt.Errorf("%w: %q", ErrInvalidTopicFilter, filter)
+		}
+
+		deviceIDLevel = i
+	}
+
+	if deviceIDLevel < 0 {
+		return deviceTopic{}, fmt.Errorf("%w: %q", ErrInvalidTopicFilter, filter)
+	}
+
+	return deviceTopic{filter: filter, levels: len(levels), deviceIDLevel: deviceIDLevel}, nil
+}
+
+// DeviceID reads the device ID from a topic that matches the filter.
+func (t deviceTopic) DeviceID(topic string) (uuid.UUID, error) {
+	if !topicMatches(t.filter, topic) {
+		return uuid.Nil, fmt.Errorf("%w: %q does not match %q", ErrInvalidTopic, topic, t.filter)
+	}
+
+	deviceID, err := uuid.Parse(strings.Split(topic, "/")[t.deviceIDLevel])
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %q does not contain a device id", ErrInvalidTopic, topic)
+	}
+
+	return deviceID, nil
+}
+
+// childFilter is the topic filter of the readings a gateway reports on behalf of its children.
+func (t deviceTopic) childFilter() string {
+	return t.filter + "/+"
+}
+
+// ingestInput reads who reported a reading from a topic that matches the filter or its child filter.
+// On the child filter, the device ID of the filter is the gateway, and the last level is the device.
+func (t deviceTopic) ingestInput(topic string, payload []byte) (usecase.IngestSensorDataInput, error) {
+	input := usecase.IngestSensorDataInput{DeviceID: uuid.Nil, GatewayID: uuid.Nil, Payload: payload}
+
+	if !topicMatches(t.childFilter(), topic) {
+		deviceID, err := t.DeviceID(topic)
+		if err != nil {
+			return input, err
+		}
+
+		input.DeviceID = deviceID
+
+		return input, nil
+	}
+
+	levels := strings.Split(topic, "/")
+
+	gatewayID, err := t.DeviceID(strings.Join(levels[:t.levels], "/"))
+	if err != nil {
+		return input, err
+	}
+
+	childID, err := uuid.Parse(levels[t.levels])
+	if err != nil {
+		return input, fmt.Errorf("%w: %q does not contain a child device id", ErrInvalidTopic, topic)
+	}
+
+	input.DeviceID, input.GatewayID = childID, gatewayID
+
+	return input, nil
+}
+
+// reporterID returns the device that published a reading: the gateway, or the device itself.
+func reporterID(input usecase.IngestSensorDataInput) uuid.UUID {
+	if input.GatewayID != uuid.Nil {
+		return input.GatewayID
+	}
+
+	return input.DeviceID
+}
+
+// topicMatches reports whether a topic name matches a topic filter with "+" and "#" wildcards.
+// Topics starting with "$" are not matched by wildcards at the first level.
+func topicMatches(filter, topic string) bool {
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+
+		if i >= len(topicLevels) || (level != "+" && level != topicLevels[i]) {
+			return false
+		}
+	}
+
+	return len(filterLevels) == len(topicLevels)
+}
+
+// isValidTopicFilter reports whether a topic filter is well-formed:
+// "+" and "#" must occupy a whole level, and "#" must be the last level.
+func isValidTopicFilter(filter string) bool {
+	if filter == "" {
+		return false
+	}
+
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		if strings.ContainsAny(level, "+#") && len(level) > 1 {
+			return false
+		}
+
+		if level == "#" && i != len(levels)-1 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isValidTopicName reports whether a topic name can be published to. It must not contain wildcards.
+func isValidTopicName(topic string) bool {
+	return topic != "" && !strings.ContainsAny(topic, "+#")
+}
@@ -0,0 +1,39 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"backend/internal/domain/service"
+)
+
+const (
+	// TwinReportedTopic is the topic filter on which devices publish their reported state. The "+" level is
+	// the device ID. The payload is a JSON Merge Patch of the reported state.
+	TwinReportedTopic = "devices/+/twin/reported"
+	// TwinDeltaTopic is the topic filter on which devices receive the delta of their twin,
+	// as {"version": <desired version>, "state": {...}}.
+	TwinDeltaTopic = "devices/+/twin/delta"
+)
+
+// twinDeltaMessage is the payload of a message on TwinDeltaTopic.
+type twinDeltaMessage struct {
+	Version int64          `json:"version"`
+	State   map[string]any `json:"state"`
+}
+
+// PublishDelta publishes a delta to its device on TwinDeltaTopic through every attached broker.
+// Until a broker is attached, or while the device is not subscribed, the deltas are dropped,
+// and the device receives them when it reports its state next.
+func (p *DevicePublisher) PublishDelta(_ context.Context, delta service.DeviceTwinDelta) error {
+	payload, err := json.Marshal(twinDeltaMessage{Version: delta.Version, State: delta.State})
+	if err != nil {
+		return fmt.Errorf("failed to marshal twin delta: %w", err)
+	}
+
+	_, err = p.publish(strings.Replace(TwinDeltaTopic, "+", delta.DeviceID.String(), 1), payload)
+
+	return err
+}
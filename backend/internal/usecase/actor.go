@@ -0,0 +1,25 @@
+package usecase
+
+import (
+	"context"
+
+	"backend/internal/domain/entity"
+)
+
+// actorContextKey is the context key under which the actor of a request is stored.
+type actorContextKey struct{}
+
+// WithActor returns a copy of ctx that attributes the operations performed with it to actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFromContext returns the actor stored by WithActor, or entity.SystemActor if there is none.
+func ActorFromContext(ctx context.Context) string {
+	actor, ok := ctx.Value(actorContextKey{}).(string)
+	if !ok || actor == "" {
+		return entity.SystemActor
+	}
+
+	return actor
+}
@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+// auditEvent describes a mutation to be recorded in the audit trail.
+type auditEvent struct {
+	action   entity.AuditAction
+	deviceID uuid.UUID
+	target   string
+	// before and after are snapshots of the target; only the fields that changed are recorded.
+	before any
+	after  any
+}
+
+// recordAudit records audit log entries attributed to the actor in ctx.
+// It should be called within the transaction of the mutation, so the mutation is not kept without its entries.
+func recordAudit(ctx context.Context, logger repository.AuditLogger, events ...auditEvent) error {
+	actor := ActorFromContext(ctx)
+
+	for _, event := range events {
+		auditLog, err := entity.NewAuditLog(
+			event.action, event.deviceID, event.target, actor, event.before, event.after,
+		)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrAuditLog, err)
+		}
+
+		err = logger.Record(ctx, auditLog)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrAuditLog, err)
+		}
+	}
+
+	return nil
+}
+
+// deviceTarget, enrollmentTokenTarget, certificateTarget, deviceGroupTarget, deviceCommandTarget, firmwareTarget,
+// firmwareCampaignTarget and firmwareSigningKeyTarget identify the target of an audit log entry.
+func deviceTarget(id uuid.UUID) string {
+	return "device/" + id.String()
+}
+
+func enrollmentTokenTarget(id uuid.UUID) string {
+	return "enrollment-token/" + id.String()
+}
+
+func certificateTarget(serialNumber int64) string {
+	return "certificate/" + strconv.FormatInt(serialNumber, 10)
+}
+
+func deviceGroupTarget(id uuid.UUID) string {
+	return "device-group/" + id.String()
+}
+
+func deviceCommandTarget(id uuid.UUID) string {
+	return "device-command/" + id.String()
+}
+
+func firmwareTarget(id uuid.UUID) string {
+	return "firmware/" + id.String()
+}
+
+func firmwareCampaignTarget(id uuid.UUID) string {
+	return "firmware-campaign/" + id.String()
+}
+
+func firmwareSigningKeyTarget(id string) string {
+	return "firmware-signing-key/" + id
+}
+
+// deviceSnapshot returns the audited fields of a device.
+// Timestamps are left out, as they change with every update.
+func deviceSnapshot(device *entity.Device) map[string]any {
+	return map[string]any{
+		"hardwareId": device.HardwareID,
+		"name":       device.Name,
+		"status":     device.Status.String(),
+		"metadata":   device.Metadata,
+		"parentId":   device.ParentID,
+	}
+}
+
+// enrollmentTokenSnapshot returns the audited fields of an enrollment token. The secret hash is never recorded.
+func enrollmentTokenSnapshot(token *entity.EnrollmentToken, now time.Time) map[string]any {
+	return map[string]any{
+		"state":     string(token.State(now)),
+		"expiresAt": token.ExpiresAt,
+		"usedAt":    token.UsedAt,
+		"revokedAt": token.RevokedAt,
+	}
+}
+
+// certificateSnapshot returns the audited fields of a certificate.
+func certificateSnapshot(certificate *entity.Certificate) map[string]any {
+	snapshot := map[string]any{
+		"serialNumber": certificate.SerialNumber,
+		"fingerprint":  certificate.Fingerprint,
+		"validFrom":    certificate.ValidFrom,
+		"validTo":      certificate.ValidTo,
+		"revoked":      certificate.IsRevoked,
+	}
+
+	if certificate.IsRevoked {
+		revokedAt, reason := revocationDetails(certificate)
+		snapshot["revokedAt"] = revokedAt
+		snapshot["revocationReason"] = reason.String()
+	}
+
+	return snapshot
+}
+
+// deviceGroupSnapshot returns the audited fields of a device group. Its members are audited one by one.
+func deviceGroupSnapshot(group *entity.DeviceGroup) map[string]any {
+	return map[string]any{
+		"name":        group.Name,
+		"description": group.Description,
+		"rule":        []string(group.Rule),
+	}
+}
+
+// deviceTwinSnapshot returns the audited fields of a device twin: its desired state.
+// The reported state is not audited, as it is changed by the device rather than by an actor.
+func deviceTwinSnapshot(twin *entity.DeviceTwin) map[string]any {
+	return map[string]any{
+		"desired":        twin.Desired,
+		"desiredVersion": twin.DesiredVersion,
+	}
+}
+
+// deviceCommandSnapshot returns the audited fields of a device command when it is sent.
+// Its delivery and the answer of the device are not audited, as they do not come from an actor.
+func deviceCommandSnapshot(command *entity.DeviceCommand) map[string]any {
+	return map[string]any{
+		"name":      command.Name,
+		"payload":   command.Payload,
+		"expiresAt": command.ExpiresAt,
+	}
+}
+
+// firmwareSnapshot returns the audited fields of a firmware artifact. The signature is left out,
+// as it follows from the hash.
+func firmwareSnapshot(artifact *entity.FirmwareArtifact) map[string]any {
+	return map[string]any{
+		"version":             artifact.Version,
+		"description":         artifact.Description,
+		"size":                artifact.Size,
+		"sha256":              artifact.SHA256,
+		"minHardwareRevision": artifact.MinHardwareRevision,
+		"target":              []string(artifact.Target),
+	}
+}
+
+// firmwareCampaignSnapshot returns the audited fields of a firmware campaign.
+// Its progress through the waves is not audited, as it does not come from an actor.
+func firmwareCampaignSnapshot(campaign *entity.FirmwareCampaign) map[string]any {
+	return map[string]any{
+		"name":                 campaign.Name,
+		"firmwareId":           campaign.FirmwareID,
+		"filter":               []string(campaign.Filter),
+		"waveSize":             campaign.WaveSize,
+		"failureThreshold":     campaign.FailureThreshold,
+		"updateTimeoutSeconds": campaign.UpdateTimeoutSeconds,
+		"status":               string(campaign.Status),
+		"statusReason":         campaign.StatusReason,
+	}
+}
+
+// firmwareSigningKeySnapshot returns the audited fields of a firmware signing key. The public key is left out,
+// as the ID is derived from it.
+func firmwareSigningKeySnapshot(key *entity.FirmwareSigningKey) map[string]any {
+	return map[string]any{
+		"algorithm": key.Algorithm,
+		"retiredAt": key.RetiredAt,
+		"expiresAt": key.ExpiresAt,
+	}
+}
@@ -0,0 +1,176 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+const (
+	// DefaultAuditLogLimit is the number of entries returned when the query does not specify a limit.
+	DefaultAuditLogLimit = 100
+	// MaxAuditLogLimit is the maximum number of entries a query can request.
+	MaxAuditLogLimit = 1000
+	// auditLogVerifyBatchSize is the number of entries read at a time while verifying the hash chain.
+	auditLogVerifyBatchSize = 1000
+)
+
+// AuditLogUsecase defines the interface for querying the audit trail.
+type AuditLogUsecase interface {
+	// ListAuditLogs retrieves the audit log entries matching the filter, newest first.
+	ListAuditLogs(ctx context.Context, input ListAuditLogsInput) ([]*AuditLogOutput, error)
+	// VerifyAuditLogs walks the hash chain from the oldest entry and reports the first broken link.
+	VerifyAuditLogs(ctx context.Context) (*AuditLogVerificationOutput, error)
+}
+
+// auditLogUsecase is the implementation of the AuditLogUsecase interface.
+type auditLogUsecase struct {
+	auditLogRepo repository.AuditLogRepository
+	signingKey   []byte
+}
+
+// NewAuditLogUsecase creates a new instance of auditLogUsecase.
+// signingKey is the key entries are signed with; if it is empty, signatures are not verified.
+//
+//nolint:ireturn
+func NewAuditLogUsecase(auditLogRepo repository.AuditLogRepository, signingKey []byte) AuditLogUsecase {
+	return &auditLogUsecase{auditLogRepo: auditLogRepo, signingKey: signingKey}
+}
+
+// ListAuditLogs retrieves the audit log entries matching the filter, newest first.
+func (uc *auditLogUsecase) ListAuditLogs(ctx context.Context, input ListAuditLogsInput) ([]*AuditLogOutput, error) {
+	action := entity.AuditAction(input.Action)
+	if action != "" && !action.IsValid() {
+		return nil, fmt.Errorf("%w: unknown action %q", ErrInvalidAuditLogFilter, input.Action)
+	}
+
+	if input.From != nil && input.To != nil && !input.From.Before(*input.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidAuditLogFilter)
+	}
+
+	limit := input.Limit
+	if limit == 0 {
+		limit = DefaultAuditLogLimit
+	}
+
+	if limit < 0 || limit > MaxAuditLogLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidAuditLogFilter, MaxAuditLogLimit)
+	}
+
+	auditLogs, err := uc.auditLogRepo.FindAll(ctx, repository.AuditLogFilter{
+		DeviceID: input.DeviceID,
+		Action:   action,
+		Actor:    input.Actor,
+		From:     input.From,
+		To:       input.To,
+		BeforeID: input.BeforeID,
+		Limit:    limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDBFindAuditLogs, err)
+	}
+
+	outputs := make([]*AuditLogOutput, 0, len(auditLogs))
+
+	for _, auditLog := range auditLogs {
+		outputs = append(outputs, NewAuditLogOutput(auditLog))
+	}
+
+	return outputs, nil
+}
+
+// VerifyAuditLogs walks the hash chain from the oldest entry and reports the first broken link.
+//
+// Entries written before the chain was introduced are skipped, but only before the first chained entry.
+// Every entry written since then is chained, so unchained entries that are not followed by a chained entry
+// are reported as a break: otherwise stripping the hash from every entry would hide any tampering.
+// Once a signed entry has been seen, every later entry must be signed, so that signatures cannot be stripped
+// from entries written after the key was configured.
+func (uc *auditLogUsecase) VerifyAuditLogs(ctx context.Context) (*AuditLogVerificationOutput, error) {
+	output := &AuditLogVerificationOutput{
+		Valid:          true,
+		CheckedCount:   0,
+		UnchainedCount: 0,
+		LastID:         0,
+		LastHash:       "",
+		FirstBrokenID:  nil,
+		Reason:         "",
+	}
+	prevHash := ""
+	signed := false
+
+	var firstUnchainedID *int64
+
+	for {
+		batch, err := uc.auditLogRepo.FindAfter(ctx, output.LastID, auditLogVerifyBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrDBFindAuditLogs, err)
+		}
+
+		for _, auditLog := range batch {
+			err := uc.verifyLink(auditLog, prevHash, signed)
+			if errors.Is(err, errAuditLogUnchained) {
+				if firstUnchainedID == nil {
+					firstUnchainedID = &auditLog.ID
+				}
+
+				output.UnchainedCount++
+				output.LastID = auditLog.ID
+
+				continue
+			}
+
+			if err != nil {
+				output.Valid = false
+				output.FirstBrokenID = &auditLog.ID
+				output.Reason = err.Error()
+
+				return output, nil
+			}
+
+			prevHash = auditLog.Hash
+			signed = signed || auditLog.Signature != ""
+			output.CheckedCount++
+			output.LastID = auditLog.ID
+			output.LastHash = auditLog.Hash
+		}
+
+		if len(batch) < auditLogVerifyBatchSize {
+			break
+		}
+	}
+
+	if output.CheckedCount == 0 && firstUnchainedID != nil {
+		output.Valid = false
+		output.FirstBrokenID = firstUnchainedID
+		output.Reason = fmt.Errorf("%w: no chained entry follows the entries without a hash",
+			entity.ErrAuditLogChainBroken).Error()
+	}
+
+	return output, nil
+}
+
+// errAuditLogUnchained is returned by verifyLink for an entry written before the chain was introduced.
+var errAuditLogUnchained = errors.New("audit log entry is not chained")
+
+// verifyLink checks one entry against the hash of the previous chained entry, or "" if there is none yet.
+func (uc *auditLogUsecase) verifyLink(auditLog *entity.AuditLog, prevHash string, signed bool) error {
+	if !auditLog.IsChained() {
+		// An entry written before the chain has none of the chain columns,
+		// so a leftover one means that the hash was removed.
+		if prevHash == "" && auditLog.PrevHash == "" && auditLog.Signature == "" {
+			return errAuditLogUnchained
+		}
+
+		return fmt.Errorf("%w: entry has no hash", entity.ErrAuditLogChainBroken)
+	}
+
+	if prevHash == "" {
+		prevHash = entity.AuditLogGenesisHash
+	}
+
+	return auditLog.VerifyChain(prevHash, uc.signingKey, signed)
+}
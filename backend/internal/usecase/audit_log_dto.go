@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+)
+
+// ListAuditLogsInput is the input data for querying the audit trail.
+// Zero-valued fields do not filter.
+type ListAuditLogsInput struct {
+	DeviceID *uuid.UUID
+	Action   string
+	Actor    string
+	From     *time.Time // Inclusive
+	To       *time.Time // Exclusive
+	BeforeID int64      // Optional: only entries older than this ID, for paging.
+	Limit    int        // Optional: if zero, DefaultAuditLogLimit is used.
+}
+
+// AuditLogOutput is the output data for displaying an AuditLog entry.
+type AuditLogOutput struct {
+	ID             int64           `json:"id"`
+	TargetDeviceID *uuid.UUID      `json:"targetDeviceId,omitempty"`
+	Action         string          `json:"action"`
+	Actor          string          `json:"actor"`
+	Details        json.RawMessage `json:"details,omitempty"`
+	PrevHash       string          `json:"prevHash,omitempty"`
+	Hash           string          `json:"hash,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+// AuditLogVerificationOutput is the result of verifying the hash chain of the audit trail.
+type AuditLogVerificationOutput struct {
+	// Valid is false if a broken link was found.
+	Valid bool `json:"valid"`
+	// CheckedCount is the number of chained entries verified before the first broken link.
+	CheckedCount int64 `json:"checkedCount"`
+	// UnchainedCount is the number of entries written before the chain was introduced.
+	UnchainedCount int64 `json:"unchainedCount"`
+	// LastID and LastHash identify the last verified entry.
+	// Keeping LastHash elsewhere allows detecting the deletion of the newest entries later.
+	LastID   int64  `json:"lastId"`
+	LastHash string `json:"lastHash,omitempty"`
+	// FirstBrokenID is the ID of the first entry that does not verify, and Reason explains why.
+	FirstBrokenID *int64 `json:"firstBrokenId,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// NewAuditLogOutput creates a new AuditLogOutput from an entity.
+func NewAuditLogOutput(auditLog *entity.AuditLog) *AuditLogOutput {
+	// Entries written by other tools may not contain JSON, so they are returned as a JSON string.
+	details := json.RawMessage(auditLog.Details)
+	if auditLog.Details != "" && !json.Valid(details) {
+		details, _ = json.Marshal(auditLog.Details) //nolint:errchkjson // Marshaling a string cannot fail.
+	}
+
+	return &AuditLogOutput{
+		ID:             auditLog.ID,
+		TargetDeviceID: auditLog.TargetDeviceID,
+		Action:         string(auditLog.Action),
+		Actor:          auditLog.Actor,
+		Details:        details,
+		PrevHash:       auditLog.PrevHash,
+		Hash:           auditLog.Hash,
+		CreatedAt:      auditLog.CreatedAt,
+	}
+}
@@ -0,0 +1,545 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// FakeAuditLogger is an in-memory implementation of the AuditLogRepository for testing.
+type FakeAuditLogger struct {
+	mu   sync.RWMutex
+	logs []*entity.AuditLog
+	// SigningKey signs the recorded entries if it is not empty.
+	SigningKey []byte
+	// for controlling error case
+	RecordErr  error
+	FindAllErr error
+}
+
+// NewFakeAuditLogger creates a new FakeAuditLogger.
+func NewFakeAuditLogger() *FakeAuditLogger {
+	return &FakeAuditLogger{
+		mu:         sync.RWMutex{},
+		logs:       nil,
+		SigningKey: nil,
+		RecordErr:  nil,
+		FindAllErr: nil,
+	}
+}
+
+// Record appends an entry to the in-memory hash chain and assigns its ID.
+// A creation time set by the test is kept; otherwise, the current time is used.
+func (l *FakeAuditLogger) Record(_ context.Context, log *entity.AuditLog) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.RecordErr != nil {
+		return l.RecordErr
+	}
+
+	// The chain starts over after entries written before it was introduced.
+	prevHash := entity.AuditLogGenesisHash
+	if len(l.logs) > 0 && l.logs[len(l.logs)-1].IsChained() {
+		prevHash = l.logs[len(l.logs)-1].Hash
+	}
+
+	now := log.CreatedAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	log.ID = int64(len(l.logs) + 1)
+	log.Chain(prevHash, now, l.SigningKey)
+	l.logs = append(l.logs, log)
+
+	return nil
+}
+
+// FindAfter retrieves up to limit entries with an ID greater than afterID, oldest first.
+func (l *FakeAuditLogger) FindAfter(_ context.Context, afterID int64, limit int) ([]*entity.AuditLog, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.FindAllErr != nil {
+		return nil, l.FindAllErr
+	}
+
+	logs := make([]*entity.AuditLog, 0)
+
+	for _, log := range l.logs {
+		if log.ID > afterID && len(logs) < limit {
+			logs = append(logs, log)
+		}
+	}
+
+	return logs, nil
+}
+
+// FindAll retrieves the entries matching the filter, newest first.
+func (l *FakeAuditLogger) FindAll(_ context.Context, filter repository.AuditLogFilter) ([]*entity.AuditLog, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.FindAllErr != nil {
+		return nil, l.FindAllErr
+	}
+
+	logs := make([]*entity.AuditLog, 0)
+
+	for i := len(l.logs) - 1; i >= 0 && (filter.Limit == 0 || len(logs) < filter.Limit); i-- {
+		log := l.logs[i]
+
+		switch {
+		case filter.DeviceID != nil && (log.TargetDeviceID == nil || *log.TargetDeviceID != *filter.DeviceID),
+			filter.Action != "" && log.Action != filter.Action,
+			filter.Actor != "" && log.Actor != filter.Actor,
+			filter.From != nil && log.CreatedAt.Before(*filter.From),
+			filter.To != nil && !log.CreatedAt.Before(*filter.To),
+			filter.BeforeID > 0 && log.ID >= filter.BeforeID:
+			continue
+		}
+
+		logs = append(logs, log)
+	}
+
+	return logs, nil
+}
+
+// Logs returns the recorded entries in the order they were recorded.
+func (l *FakeAuditLogger) Logs() []*entity.AuditLog {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return append([]*entity.AuditLog(nil), l.logs...)
+}
+
+// Actions returns the actions of the recorded entries in the order they were recorded.
+func (l *FakeAuditLogger) Actions() []entity.AuditAction {
+	logs := l.Logs()
+	actions := make([]entity.AuditAction, 0, len(logs))
+
+	for _, log := range logs {
+		actions = append(actions, log.Action)
+	}
+
+	return actions
+}
+
+// auditDetails decodes the details of an audit log entry.
+func auditDetails(t *testing.T, log *entity.AuditLog) entity.AuditDetails {
+	t.Helper()
+
+	var details entity.AuditDetails
+	require.NoError(t, json.Unmarshal([]byte(log.Details), &details))
+
+	return details
+}
+
+// TestListAuditLogs tests the ListAuditLogs method.
+func TestListAuditLogs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	deviceA, deviceB := uuid.New(), uuid.New()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	logger := NewFakeAuditLogger()
+
+	for i, entry := range []struct {
+		action   entity.AuditAction
+		deviceID uuid.UUID
+		actor    string
+	}{
+		{action: entity.AuditDeviceCreate, deviceID: deviceA, actor: "alice"},
+		{action: entity.AuditDeviceCreate, deviceID: deviceB, actor: "bob"},
+		{action: entity.AuditTokenCreate, deviceID: deviceA, actor: "alice"},
+		{action: entity.AuditDeviceDelete, deviceID: deviceB, actor: ""},
+	} {
+		after := map[string]any{"i": i}
+		log, err := entity.NewAuditLog(entry.action, entry.deviceID, "device/x", entry.actor, nil, after)
+		require.NoError(t, err)
+		log.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		require.NoError(t, logger.Record(ctx, log))
+	}
+
+	from := base.Add(time.Hour)
+	to := base.Add(3 * time.Hour)
+
+	tests := []struct {
+		name    string
+		desc    string
+		input   usecase.ListAuditLogsInput
+		wantIDs []int64
+		wantErr error
+	}{
+		{
+			name:    "success: no filter",
+			desc:    "Verify that all entries are returned, newest first.",
+			input:   usecase.ListAuditLogsInput{}, //nolint:exhaustruct
+			wantIDs: []int64{4, 3, 2, 1},
+			wantErr: nil,
+		},
+		{
+			name:    "success: filter by device",
+			desc:    "Verify that only the entries of the device are returned.",
+			input:   usecase.ListAuditLogsInput{DeviceID: &deviceA}, //nolint:exhaustruct
+			wantIDs: []int64{3, 1},
+			wantErr: nil,
+		},
+		{
+			name:    "success: filter by action and actor",
+			desc:    "Verify that the action and actor filters are combined.",
+			input:   usecase.ListAuditLogsInput{Action: "DEVICE_CREATE", Actor: "bob"}, //nolint:exhaustruct
+			wantIDs: []int64{2},
+			wantErr: nil,
+		},
+		{
+			name:    "success: entries without an actor are attributed to the system",
+			desc:    "Verify that an empty actor is recorded as the system actor.",
+			input:   usecase.ListAuditLogsInput{Actor: entity.SystemActor}, //nolint:exhaustruct
+			wantIDs: []int64{4},
+			wantErr: nil,
+		},
+		{
+			name:    "success: filter by time range",
+			desc:    "Verify that from is inclusive and to is exclusive.",
+			input:   usecase.ListAuditLogsInput{From: &from, To: &to}, //nolint:exhaustruct
+			wantIDs: []int64{3, 2},
+			wantErr: nil,
+		},
+		{
+			name:    "success: paging",
+			desc:    "Verify that limit and beforeId page through the entries.",
+			input:   usecase.ListAuditLogsInput{BeforeID: 4, Limit: 2}, //nolint:exhaustruct
+			wantIDs: []int64{3, 2},
+			wantErr: nil,
+		},
+		{
+			name:    "failure: unknown action",
+			desc:    "Verify that an unknown action is rejected.",
+			input:   usecase.ListAuditLogsInput{Action: "device_create"}, //nolint:exhaustruct
+			wantIDs: nil,
+			wantErr: usecase.ErrInvalidAuditLogFilter,
+		},
+		{
+			name:    "failure: empty time range",
+			desc:    "Verify that a range whose start is not before its end is rejected.",
+			input:   usecase.ListAuditLogsInput{From: &to, To: &from}, //nolint:exhaustruct
+			wantIDs: nil,
+			wantErr: usecase.ErrInvalidAuditLogFilter,
+		},
+		{
+			name:    "failure: limit too large",
+			desc:    "Verify that a limit above the maximum is rejected.",
+			input:   usecase.ListAuditLogsInput{Limit: usecase.MaxAuditLogLimit + 1}, //nolint:exhaustruct
+			wantIDs: nil,
+			wantErr: usecase.ErrInvalidAuditLogFilter,
+		},
+	}
+
+	uc := usecase.NewAuditLogUsecase(logger, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := uc.ListAuditLogs(ctx, tt.input)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			ids := make([]int64, 0, len(got))
+			for _, output := range got {
+				ids = append(ids, output.ID)
+			}
+
+			assert.Equal(t, tt.wantIDs, ids)
+		})
+	}
+
+	t.Run("failure: repository error", func(t *testing.T) {
+		t.Parallel()
+
+		failing := NewFakeAuditLogger()
+		failing.FindAllErr = assert.AnError
+
+		uc := usecase.NewAuditLogUsecase(failing, nil)
+
+		_, err := uc.ListAuditLogs(ctx, usecase.ListAuditLogsInput{}) //nolint:exhaustruct
+		require.ErrorIs(t, err, usecase.ErrDBFindAuditLogs)
+	})
+}
+
+// TestVerifyAuditLogs tests the VerifyAuditLogs method.
+func TestVerifyAuditLogs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	key := []byte("audit-key")
+
+	tests := []struct {
+		name          string
+		desc          string
+		unchained     int
+		count         int
+		signingKey    []byte
+		verifyKey     []byte
+		tamper        func(logger *FakeAuditLogger)
+		wantBrokenID  int64
+		wantErr       error
+		wantChecked   int64
+		wantUnchained int64
+	}{
+		{
+			name:          "success: intact chain",
+			desc:          "Verify that an untouched chain is valid.",
+			unchained:     0,
+			count:         5,
+			signingKey:    key,
+			verifyKey:     key,
+			tamper:        nil,
+			wantBrokenID:  0,
+			wantErr:       nil,
+			wantChecked:   5,
+			wantUnchained: 0,
+		},
+		{
+			name:          "success: entries written before the chain are skipped",
+			desc:          "Verify that unchained entries before the first chained entry are counted, not rejected.",
+			unchained:     2,
+			count:         3,
+			signingKey:    nil,
+			verifyKey:     nil,
+			tamper:        nil,
+			wantBrokenID:  0,
+			wantErr:       nil,
+			wantChecked:   3,
+			wantUnchained: 2,
+		},
+		{
+			name:       "failure: edited details",
+			desc:       "Verify that editing the content of an entry is detected at that entry.",
+			unchained:  0,
+			count:      5,
+			signingKey: nil,
+			verifyKey:  nil,
+			tamper: func(logger *FakeAuditLogger) {
+				logger.logs[2].Details = `{"target":"device/forged"}`
+			},
+			wantBrokenID:  3,
+			wantErr:       entity.ErrAuditLogHashMismatch,
+			wantChecked:   2,
+			wantUnchained: 0,
+		},
+		{
+			name:       "failure: deleted entry",
+			desc:       "Verify that deleting an entry is detected at the following entry.",
+			unchained:  0,
+			count:      5,
+			signingKey: nil,
+			verifyKey:  nil,
+			tamper: func(logger *FakeAuditLogger) {
+				logger.logs = slices.Delete(logger.logs, 2, 3)
+			},
+			wantBrokenID:  4,
+			wantErr:       entity.ErrAuditLogChainBroken,
+			wantChecked:   2,
+			wantUnchained: 0,
+		},
+		{
+			name:       "failure: rehashed entry without the key",
+			desc:       "Verify that an entry rehashed by someone without the key fails the signature check.",
+			unchained:  0,
+			count:      3,
+			signingKey: key,
+			verifyKey:  key,
+			tamper: func(logger *FakeAuditLogger) {
+				forged, next := logger.logs[1], logger.logs[2]
+				forged.Actor = "mallory"
+				forged.Hash = forged.ComputeHash()
+				next.PrevHash = forged.Hash
+				next.Hash = next.ComputeHash()
+			},
+			wantBrokenID:  2,
+			wantErr:       entity.ErrAuditLogSignatureInvalid,
+			wantChecked:   1,
+			wantUnchained: 0,
+		},
+		{
+			name:       "failure: stripped signature",
+			desc:       "Verify that an unsigned entry after a signed one is rejected.",
+			unchained:  0,
+			count:      3,
+			signingKey: key,
+			verifyKey:  key,
+			tamper: func(logger *FakeAuditLogger) {
+				logger.logs[2].Signature = ""
+			},
+			wantBrokenID:  3,
+			wantErr:       entity.ErrAuditLogSignatureInvalid,
+			wantChecked:   2,
+			wantUnchained: 0,
+		},
+		{
+			name:       "failure: hash removed after the chain started",
+			desc:       "Verify that an entry without a hash in the middle of the chain is rejected.",
+			unchained:  0,
+			count:      3,
+			signingKey: nil,
+			verifyKey:  nil,
+			tamper: func(logger *FakeAuditLogger) {
+				logger.logs[1].Hash = ""
+			},
+			wantBrokenID:  2,
+			wantErr:       entity.ErrAuditLogChainBroken,
+			wantChecked:   1,
+			wantUnchained: 0,
+		},
+		{
+			name:       "failure: hash removed from the first chained entry",
+			desc:       "Verify that an entry that keeps its previous hash is not taken for a pre-chain entry.",
+			unchained:  0,
+			count:      3,
+			signingKey: nil,
+			verifyKey:  nil,
+			tamper: func(logger *FakeAuditLogger) {
+				logger.logs[0].Hash = ""
+			},
+			wantBrokenID:  1,
+			wantErr:       entity.ErrAuditLogChainBroken,
+			wantChecked:   0,
+			wantUnchained: 0,
+		},
+		{
+			name:       "failure: chain removed from every entry",
+			desc:       "Verify that a log in which no entry has a hash is not reported as intact.",
+			unchained:  0,
+			count:      3,
+			signingKey: key,
+			verifyKey:  key,
+			tamper: func(logger *FakeAuditLogger) {
+				for _, log := range logger.logs {
+					log.PrevHash, log.Hash, log.Signature = "", "", ""
+				}
+			},
+			wantBrokenID:  1,
+			wantErr:       entity.ErrAuditLogChainBroken,
+			wantChecked:   0,
+			wantUnchained: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			logger := NewFakeAuditLogger()
+			logger.SigningKey = tt.signingKey
+
+			// Entries written before the chain was introduced have no hash.
+			for i := range tt.unchained {
+				log, err := entity.NewAuditLog(entity.AuditDeviceCreate, uuid.New(), "device/x", "alice", nil, nil)
+				require.NoError(t, err)
+
+				log.ID = int64(i + 1)
+				logger.logs = append(logger.logs, log)
+			}
+
+			for range tt.count {
+				log, err := entity.NewAuditLog(entity.AuditDeviceUpdate, uuid.New(), "device/x", "alice", nil, nil)
+				require.NoError(t, err)
+				require.NoError(t, logger.Record(ctx, log))
+			}
+
+			if tt.tamper != nil {
+				tt.tamper(logger)
+			}
+
+			got, err := usecase.NewAuditLogUsecase(logger, tt.verifyKey).VerifyAuditLogs(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantChecked, got.CheckedCount)
+			assert.Equal(t, tt.wantUnchained, got.UnchainedCount)
+
+			if tt.wantErr == nil {
+				assert.True(t, got.Valid)
+				assert.Nil(t, got.FirstBrokenID)
+				assert.Equal(t, logger.Logs()[len(logger.Logs())-1].Hash, got.LastHash)
+
+				return
+			}
+
+			assert.False(t, got.Valid)
+			require.NotNil(t, got.FirstBrokenID)
+			assert.Equal(t, tt.wantBrokenID, *got.FirstBrokenID)
+			assert.Contains(t, got.Reason, tt.wantErr.Error())
+		})
+	}
+
+	t.Run("failure: repository error", func(t *testing.T) {
+		t.Parallel()
+
+		failing := NewFakeAuditLogger()
+		failing.FindAllErr = assert.AnError
+
+		_, err := usecase.NewAuditLogUsecase(failing, nil).VerifyAuditLogs(ctx)
+		require.ErrorIs(t, err, usecase.ErrDBFindAuditLogs)
+	})
+}
+
+// TestActorFromContext tests that the actor of a request is carried by the context.
+func TestActorFromContext(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, entity.SystemActor, usecase.ActorFromContext(context.Background()))
+	assert.Equal(t, "alice", usecase.ActorFromContext(usecase.WithActor(context.Background(), "alice")))
+}
+
+// TestAuditLogFailure tests that a mutation fails if its audit log entry cannot be recorded.
+func TestAuditLogFailure(t *testing.T) {
+	t.Parallel()
+
+	ctx := usecase.WithActor(context.Background(), "alice")
+
+	t.Run("success: the actor in the context is recorded", func(t *testing.T) {
+		t.Parallel()
+
+		auditLogger := NewFakeAuditLogger()
+		uc := usecase.NewDeviceUsecase(NewFakeDeviceRepository(), auditLogger, FakeTransactor{})
+
+		_, err := uc.CreateDevice(ctx, usecase.CreateDeviceInput{HardwareID: "hw-audit-001", Name: "", Metadata: nil})
+		require.NoError(t, err)
+		require.Len(t, auditLogger.Logs(), 1)
+		assert.Equal(t, "alice", auditLogger.Logs()[0].Actor)
+	})
+
+	t.Run("failure: the entry cannot be recorded", func(t *testing.T) {
+		t.Parallel()
+
+		auditLogger := NewFakeAuditLogger()
+		auditLogger.RecordErr = assert.AnError
+		uc := usecase.NewDeviceUsecase(NewFakeDeviceRepository(), auditLogger, FakeTransactor{})
+
+		got, err := uc.CreateDevice(ctx, usecase.CreateDeviceInput{HardwareID: "hw-audit-002", Name: "", Metadata: nil})
+		require.ErrorIs(t, err, usecase.ErrAuditLog)
+		require.ErrorIs(t, err, assert.AnError)
+		require.Nil(t, got)
+	})
+}
@@ -0,0 +1,267 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/VO/revocationreason"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+// CertificateUsecase defines the interface for inspecting issued certificates.
+type CertificateUsecase interface {
+	// ListDeviceCertificates retrieves the certificate history of a device, newest first.
+	ListDeviceCertificates(ctx context.Context, deviceID uuid.UUID) ([]*CertificateOutput, error)
+	// GetCertificate retrieves a certificate by its serial number.
+	GetCertificate(ctx context.Context, serialNumber int64) (*CertificateOutput, error)
+	// RevokeCertificate revokes a certificate, regenerates the CRL and drops cached OCSP responses.
+	RevokeCertificate(ctx context.Context, input RevokeCertificateInput) (*CertificateOutput, error)
+	// RevokeDeviceCertificates revokes the certificates of the devices that have neither expired nor been revoked,
+	// and regenerates the CRL once for all of them. A failure for one device does not stop the others:
+	// the outcome of each device is reported in the order of the input.
+	RevokeDeviceCertificates(
+		ctx context.Context,
+		input RevokeDeviceCertificatesInput,
+	) ([]*DeviceCertificatesRevocation, error)
+}
+
+// certificateUsecase is the implementation of the CertificateUsecase interface.
+type certificateUsecase struct {
+	deviceRepo  repository.DeviceRepository
+	certRepo    repository.CertificateRepository
+	crl         CRLUsecase
+	ocsp        OCSPUsecase
+	auditLogger repository.AuditLogger
+	transactor  repository.Transactor
+	now         func() time.Time
+}
+
+// NewCertificateUsecase creates a new instance of certificateUsecase.
+//
+//nolint:ireturn
+func NewCertificateUsecase(
+	deviceRepo repository.DeviceRepository,
+	certRepo repository.CertificateRepository,
+	crl CRLUsecase,
+	ocsp OCSPUsecase,
+	auditLogger repository.AuditLogger,
+	transactor repository.Transactor,
+) CertificateUsecase {
+	return &certificateUsecase{
+		deviceRepo:  deviceRepo,
+		certRepo:    certRepo,
+		crl:         crl,
+		ocsp:        ocsp,
+		auditLogger: auditLogger,
+		transactor:  transactor,
+		now:         time.Now,
+	}
+}
+
+// ListDeviceCertificates retrieves the certificate history of a device, newest first.
+func (uc *certificateUsecase) ListDeviceCertificates(
+	ctx context.Context,
+	deviceID uuid.UUID,
+) ([]*CertificateOutput, error) {
+	_, err := uc.deviceRepo.FindByID(ctx, deviceID)
+	if err != nil {
+		if isNotFound(err, entity.ErrDeviceNotFound) {
+			return nil, entity.ErrDeviceNotFound
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrDBFindByID, err)
+	}
+
+	certificates, err := uc.certRepo.FindByDeviceID(ctx, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDBFindCertificate, err)
+	}
+
+	now := uc.now()
+	outputs := make([]*CertificateOutput, 0, len(certificates))
+
+	for _, certificate := range certificates {
+		output, err := NewCertificateOutput(certificate, now)
+		if err != nil {
+			return nil, err
+		}
+
+		outputs = append(outputs, output)
+	}
+
+	return outputs, nil
+}
+
+// GetCertificate retrieves a certificate by its serial number.
+func (uc *certificateUsecase) GetCertificate(ctx context.Context, serialNumber int64) (*CertificateOutput, error) {
+	certificate, err := uc.certRepo.FindBySerialNumber(ctx, serialNumber)
+	if err != nil {
+		if isNotFound(err, entity.ErrCertificateNotFound) {
+			return nil, entity.ErrCertificateNotFound
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrDBFindCertificate, err)
+	}
+
+	return NewCertificateOutput(certificate, uc.now())
+}
+
+// RevokeCertificate revokes a certificate, regenerates the CRL and drops cached OCSP responses.
+func (uc *certificateUsecase) RevokeCertificate(
+	ctx context.Context,
+	input RevokeCertificateInput,
+) (*CertificateOutput, error) {
+	reason, err := revocationreason.Parse(input.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	certificate, err := uc.certRepo.FindBySerialNumber(ctx, input.SerialNumber)
+	if err != nil {
+		if isNotFound(err, entity.ErrCertificateNotFound) {
+			return nil, entity.ErrCertificateNotFound
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrDBFindCertificate, err)
+	}
+
+	now := uc.now()
+
+	err = uc.revoke(ctx, certificate, reason, now)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.refreshCRL(ctx, fmt.Sprintf("certificate %d", certificate.SerialNumber))
+
+	return NewCertificateOutput(certificate, now)
+}
+
+// RevokeDeviceCertificates revokes the unexpired certificates of the devices that have not been revoked yet.
+func (uc *certificateUsecase) RevokeDeviceCertificates(
+	ctx context.Context,
+	input RevokeDeviceCertificatesInput,
+) ([]*DeviceCertificatesRevocation, error) {
+	reason, err := revocationreason.Parse(input.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	now := uc.now()
+	outcomes := make([]*DeviceCertificatesRevocation, 0, len(input.DeviceIDs))
+	revoked := 0
+
+	for _, deviceID := range input.DeviceIDs {
+		outcome := &DeviceCertificatesRevocation{DeviceID: deviceID, SerialNumbers: nil, Err: nil}
+		outcomes = append(outcomes, outcome)
+
+		certificates, err := uc.certRepo.FindByDeviceID(ctx, deviceID)
+		if err != nil {
+			outcome.Err = fmt.Errorf("%w: %w", ErrDBFindCertificate, err)
+
+			continue
+		}
+
+		for _, certificate := range certificates {
+			state := certificate.State(now)
+			if state != entity.CertificateValid && state != entity.CertificateNotYetValid {
+				continue
+			}
+
+			err = uc.revoke(ctx, certificate, reason, now)
+			if errors.Is(err, entity.ErrCertificateAlreadyRevoked) {
+				// It has been revoked concurrently, which is what was asked for.
+				continue
+			}
+
+			if err != nil {
+				outcome.Err = err
+
+				break
+			}
+
+			outcome.SerialNumbers = append(outcome.SerialNumbers, certificate.SerialNumber)
+			revoked++
+		}
+	}
+
+	if revoked > 0 {
+		uc.refreshCRL(ctx, fmt.Sprintf("%d certificates", revoked))
+	}
+
+	return outcomes, nil
+}
+
+// revoke persists the revocation of a certificate together with its audit log entry,
+// and drops the cached OCSP responses for it.
+func (uc *certificateUsecase) revoke(
+	ctx context.Context,
+	certificate *entity.Certificate,
+	reason revocationreason.Reason,
+	now time.Time,
+) error {
+	before := certificateSnapshot(certificate)
+
+	err := certificate.Revoke(reason, now)
+	if err != nil {
+		return err
+	}
+
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := uc.certRepo.Revoke(ctx, certificate)
+		if err != nil {
+			if errors.Is(err, entity.ErrCertificateAlreadyRevoked) {
+				return err
+			}
+
+			return fmt.Errorf("%w: %w", ErrDBRevokeCertificate, err)
+		}
+
+		return recordAudit(ctx, uc.auditLogger, auditEvent{
+			action:   entity.AuditCertificateRevoke,
+			deviceID: certificate.DeviceID,
+			target:   certificateTarget(certificate.SerialNumber),
+			before:   before,
+			after:    certificateSnapshot(certificate),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	uc.ocsp.InvalidateOCSPCache(certificate.SerialNumber)
+
+	return nil
+}
+
+// refreshCRL regenerates the CRL after revocations, described by revoked for the log.
+// The revocations are already persisted, so a failure here does not fail the request.
+// The CRL is marked as outdated and regenerated on the next request or by the scheduler.
+func (uc *certificateUsecase) refreshCRL(ctx context.Context, revoked string) {
+	_, err := uc.crl.RefreshCRL(ctx)
+	if err != nil {
+		log.Printf("%s revoked, but failed to refresh CRL: %v", revoked, err)
+	}
+}
+
+// revocationDetails returns when and why a revoked certificate was revoked.
+// Certificates revoked before the details were recorded fall back to their creation time and "unspecified".
+func revocationDetails(certificate *entity.Certificate) (time.Time, revocationreason.Reason) {
+	revokedAt := certificate.CreatedAt
+	if certificate.RevokedAt != nil {
+		revokedAt = *certificate.RevokedAt
+	}
+
+	reason := revocationreason.Unspecified
+	if certificate.RevocationReason != nil {
+		reason = *certificate.RevocationReason
+	}
+
+	return revokedAt, reason
+}
@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+)
+
+// CertificateOutput is the output data for displaying Certificate information.
+// The details are parsed from the stored PEM, so they show exactly what was issued.
+type CertificateOutput struct {
+	SerialNumber       int64      `json:"serialNumber,string"` // Encoded as a string, as it may exceed 2^53.
+	SerialNumberHex    string     `json:"serialNumberHex"`
+	DeviceID           uuid.UUID  `json:"deviceId"`
+	Subject            string     `json:"subject"`
+	Issuer             string     `json:"issuer"`
+	KeyAlgorithm       string     `json:"keyAlgorithm"`
+	SignatureAlgorithm string     `json:"signatureAlgorithm"`
+	URIs               []string   `json:"uris,omitempty"`
+	NotBefore          time.Time  `json:"notBefore"`
+	NotAfter           time.Time  `json:"notAfter"`
+	Fingerprint        string     `json:"fingerprint"` // Hex-encoded SHA-256 of the DER-encoded certificate
+	State              string     `json:"state"`
+	RevokedAt          *time.Time `json:"revokedAt,omitempty"`
+	RevocationReason   string     `json:"revocationReason,omitempty"`
+	PEM                string     `json:"pem"`
+	CreatedAt          time.Time  `json:"createdAt"`
+}
+
+// NewCertificateOutput creates a new CertificateOutput from an entity by parsing its PEM.
+func NewCertificateOutput(certificate *entity.Certificate, now time.Time) (*CertificateOutput, error) {
+	block, _ := pem.Decode([]byte(certificate.PEMRaw))
+	if block == nil {
+		return nil, fmt.Errorf("%w: serial %d", ErrCertificateParse, certificate.SerialNumber)
+	}
+
+	parsed, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: serial %d: %w", ErrCertificateParse, certificate.SerialNumber, err)
+	}
+
+	var revocationReason string
+	if certificate.RevocationReason != nil {
+		revocationReason = certificate.RevocationReason.String()
+	}
+
+	uris := make([]string, 0, len(parsed.URIs))
+	for _, uri := range parsed.URIs {
+		uris = append(uris, uri.String())
+	}
+
+	return &CertificateOutput{
+		SerialNumber:       certificate.SerialNumber,
+		SerialNumberHex:    fmt.Sprintf("%x", parsed.SerialNumber),
+		DeviceID:           certificate.DeviceID,
+		Subject:            parsed.Subject.String(),
+		Issuer:             parsed.Issuer.String(),
+		KeyAlgorithm:       describePublicKey(parsed.PublicKey),
+		SignatureAlgorithm: parsed.SignatureAlgorithm.String(),
+		URIs:               uris,
+		NotBefore:          parsed.NotBefore,
+		NotAfter:           parsed.NotAfter,
+		Fingerprint:        certificate.Fingerprint,
+		State:              string(certificate.State(now)),
+		RevokedAt:          certificate.RevokedAt,
+		RevocationReason:   revocationReason,
+		PEM:                certificate.PEMRaw,
+		CreatedAt:          certificate.CreatedAt,
+	}, nil
+}
+
+// RevokeCertificateInput is the input data for revoking a Certificate.
+type RevokeCertificateInput struct {
+	SerialNumber int64 `json:"-"` // Populated from the URL path parameter.
+	// Reason is an RFC 5280 CRLReason name (e.g. "keyCompromise") or code. It defaults to "unspecified".
+	Reason string `json:"reason"`
+}
+
+// RevokeDeviceCertificatesInput is the input data for revoking the certificates of devices.
+type RevokeDeviceCertificatesInput struct {
+	DeviceIDs []uuid.UUID
+	// Reason is an RFC 5280 CRLReason name (e.g. "keyCompromise") or code. It defaults to "unspecified".
+	Reason string
+}
+
+// DeviceCertificatesRevocation is the outcome of revoking the certificates of a device.
+type DeviceCertificatesRevocation struct {
+	DeviceID uuid.UUID
+	// SerialNumbers are the certificates that have been revoked. It is empty if the device had none to revoke.
+	SerialNumbers []int64
+	// Err is the error that stopped the revocation of the certificates of the device, if any.
+	// The certificates in SerialNumbers have been revoked nevertheless.
+	Err error
+}
+
+// describePublicKey returns a human-readable description of a public key, e.g. "ECDSA P-256" or "RSA 2048".
+func describePublicKey(pub any) string {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return fmt.Sprintf("%T", pub)
+	}
+}
@@ -0,0 +1,599 @@
+package usecase_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/connectionstate"
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/VO/revocationreason"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/service"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// FakeCertificateRepository is an in-memory implementation of the CertificateRepository for testing.
+type FakeCertificateRepository struct {
+	mu           sync.RWMutex
+	certificates map[int64]*entity.Certificate
+	// for controlling error case
+	SaveErr   error
+	FindErr   error
+	RevokeErr error
+}
+
+// NewFakeCertificateRepository creates a new FakeCertificateRepository.
+func NewFakeCertificateRepository() *FakeCertificateRepository {
+	return &FakeCertificateRepository{
+		mu:           sync.RWMutex{},
+		certificates: make(map[int64]*entity.Certificate),
+		SaveErr:      nil,
+		FindErr:      nil,
+		RevokeErr:    nil,
+	}
+}
+
+// Save stores a certificate in the in-memory store.
+func (r *FakeCertificateRepository) Save(_ context.Context, certificate *entity.Certificate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.SaveErr != nil {
+		return r.SaveErr
+	}
+
+	r.certificates[certificate.SerialNumber] = certificate
+
+	return nil
+}
+
+// FindBySerialNumber retrieves a certificate by its serial number from the in-memory store.
+func (r *FakeCertificateRepository) FindBySerialNumber(
+	_ context.Context,
+	serialNumber int64,
+) (*entity.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.FindErr != nil {
+		return nil, r.FindErr
+	}
+
+	certificate, ok := r.certificates[serialNumber]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return certificate, nil
+}
+
+// FindByDeviceID retrieves all certificates of a device from the in-memory store.
+func (r *FakeCertificateRepository) FindByDeviceID(
+	_ context.Context,
+	deviceID uuid.UUID,
+) ([]*entity.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.FindErr != nil {
+		return nil, r.FindErr
+	}
+
+	certificates := make([]*entity.Certificate, 0, len(r.certificates))
+
+	for _, certificate := range r.certificates {
+		if certificate.DeviceID == deviceID {
+			certificates = append(certificates, certificate)
+		}
+	}
+
+	return certificates, nil
+}
+
+// FindRevoked retrieves all revoked, unexpired certificates from the in-memory store.
+func (r *FakeCertificateRepository) FindRevoked(_ context.Context, at time.Time) ([]*entity.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.FindErr != nil {
+		return nil, r.FindErr
+	}
+
+	certificates := make([]*entity.Certificate, 0, len(r.certificates))
+
+	for _, certificate := range r.certificates {
+		if certificate.IsRevoked && certificate.ValidTo.After(at) {
+			certificates = append(certificates, certificate)
+		}
+	}
+
+	return certificates, nil
+}
+
+// Revoke stores the revocation of a certificate in the in-memory store.
+func (r *FakeCertificateRepository) Revoke(_ context.Context, certificate *entity.Certificate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.RevokeErr != nil {
+		return r.RevokeErr
+	}
+
+	r.certificates[certificate.SerialNumber] = certificate
+
+	return nil
+}
+
+// FakeRevocationListSigner is a fake implementation of service.RevocationListSigner for testing.
+type FakeRevocationListSigner struct {
+	mu    sync.Mutex
+	lists []service.RevocationList
+	// for controlling error case
+	SignErr error
+}
+
+// NewFakeRevocationListSigner creates a new FakeRevocationListSigner.
+func NewFakeRevocationListSigner() *FakeRevocationListSigner {
+	return &FakeRevocationListSigner{
+		mu:      sync.Mutex{},
+		lists:   nil,
+		SignErr: nil,
+	}
+}
+
+// SignRevocationList records the list and returns a fake DER encoding.
+func (s *FakeRevocationListSigner) SignRevocationList(_ context.Context, list service.RevocationList) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.SignErr != nil {
+		return nil, s.SignErr
+	}
+
+	s.lists = append(s.lists, list)
+
+	return []byte(fmt.Sprintf("crl-%d", list.Number)), nil
+}
+
+// Lists returns the signed revocation lists.
+func (s *FakeRevocationListSigner) Lists() []service.RevocationList {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.lists)
+}
+
+// newTestCertificate creates a self-signed certificate entity for a device.
+func newTestCertificate(t *testing.T, deviceID uuid.UUID, serialNumber int64) *entity.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	now := time.Now()
+	template := &x509.Certificate{ //nolint:exhaustruct
+		SerialNumber: big.NewInt(serialNumber),
+		Subject:      pkix.Name{CommonName: deviceID.String()}, //nolint:exhaustruct
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+
+	return entity.NewCertificate(
+		deviceID,
+		serialNumber,
+		"fingerprint",
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Headers: nil, Bytes: der})),
+		template.NotBefore,
+		template.NotAfter,
+	)
+}
+
+// newTestCRLUsecase creates a CRLUsecase with a fake signer and no publisher.
+func newTestCRLUsecase(certRepo *FakeCertificateRepository) usecase.CRLUsecase { //nolint:ireturn
+	return usecase.NewCRLUsecase(certRepo, NewFakeRevocationListSigner(), nil, usecase.CRLConfig{
+		Validity:      0,
+		RefreshBefore: 0,
+	})
+}
+
+// newTestOCSPUsecase creates an OCSPUsecase with a fake responder.
+func newTestOCSPUsecase(certRepo *FakeCertificateRepository) usecase.OCSPUsecase { //nolint:ireturn
+	return usecase.NewOCSPUsecase(certRepo, NewFakeOCSPResponder(), usecase.OCSPConfig{Validity: 0})
+}
+
+// TestListDeviceCertificates tests the ListDeviceCertificates method.
+func TestListDeviceCertificates(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	device := &entity.Device{
+		ID:              uuid.New(),
+		HardwareID:      "hw-cert-001",
+		Name:            "Certificate Device",
+		Status:          devicestatus.Active,
+		Metadata:        nil,
+		Version:         1,
+		ParentID:        nil,
+		ConnectionState: connectionstate.Disconnected,
+		LastSeenAt:      nil,
+		LastIP:          "",
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	deviceRepo := NewFakeDeviceRepository()
+	deviceRepo.devices[device.ID] = device
+	certRepo := NewFakeCertificateRepository()
+	certificate := newTestCertificate(t, device.ID, 255)
+	require.NoError(t, certRepo.Save(ctx, certificate))
+	require.NoError(t, certRepo.Save(ctx, newTestCertificate(t, uuid.New(), 256))) // another device
+
+	uc := usecase.NewCertificateUsecase(
+		deviceRepo, certRepo, newTestCRLUsecase(certRepo), newTestOCSPUsecase(certRepo),
+		NewFakeAuditLogger(), FakeTransactor{},
+	)
+
+	t.Run("success: list the certificates of a device", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := uc.ListDeviceCertificates(ctx, device.ID)
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		assert.Equal(t, int64(255), got[0].SerialNumber)
+		assert.Equal(t, "ff", got[0].SerialNumberHex)
+		assert.Equal(t, "CN="+device.ID.String(), got[0].Subject)
+		assert.Equal(t, "ECDSA P-256", got[0].KeyAlgorithm)
+		assert.Equal(t, "ECDSA-SHA256", got[0].SignatureAlgorithm)
+		assert.Equal(t, string(entity.CertificateValid), got[0].State)
+	})
+
+	t.Run("failure: device not found", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := uc.ListDeviceCertificates(ctx, uuid.New())
+		require.ErrorIs(t, err, entity.ErrDeviceNotFound)
+	})
+}
+
+// TestGetCertificate tests the GetCertificate method.
+func TestGetCertificate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	deviceID := uuid.New()
+
+	tests := []struct {
+		name         string
+		desc         string
+		serialNumber int64
+		repoSetup    func(*FakeCertificateRepository)
+		wantState    entity.CertificateState
+		wantErr      error
+	}{
+		{
+			name:         "success: get a valid certificate",
+			desc:         "Verify that the parsed details of an existing certificate are returned.",
+			serialNumber: 1001,
+			repoSetup:    nil,
+			wantState:    entity.CertificateValid,
+			wantErr:      nil,
+		},
+		{
+			name:         "success: get a revoked certificate",
+			desc:         "Verify that a revoked certificate is reported as REVOKED.",
+			serialNumber: 1001,
+			repoSetup: func(repo *FakeCertificateRepository) {
+				repo.certificates[1001].IsRevoked = true
+			},
+			wantState: entity.CertificateRevoked,
+			wantErr:   nil,
+		},
+		{
+			name:         "failure: certificate not found",
+			desc:         "Verify that a 'certificate not found' error is returned for an unknown serial.",
+			serialNumber: 9999,
+			repoSetup:    nil,
+			wantState:    "",
+			wantErr:      entity.ErrCertificateNotFound,
+		},
+		{
+			name:         "failure: stored PEM is corrupted",
+			desc:         "Verify that a certificate whose PEM cannot be parsed is reported.",
+			serialNumber: 1001,
+			repoSetup: func(repo *FakeCertificateRepository) {
+				repo.certificates[1001].PEMRaw = "corrupted"
+			},
+			wantState: "",
+			wantErr:   usecase.ErrCertificateParse,
+		},
+		{
+			name:         "failure: repository returns a generic error",
+			desc:         "Verify that an unexpected error from the repository is propagated.",
+			serialNumber: 1001,
+			repoSetup: func(repo *FakeCertificateRepository) {
+				repo.FindErr = assert.AnError
+			},
+			wantState: "",
+			wantErr:   usecase.ErrDBFindCertificate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			certRepo := NewFakeCertificateRepository()
+			require.NoError(t, certRepo.Save(ctx, newTestCertificate(t, deviceID, 1001)))
+
+			if tt.repoSetup != nil {
+				tt.repoSetup(certRepo)
+			}
+
+			uc := usecase.NewCertificateUsecase(
+				NewFakeDeviceRepository(), certRepo, newTestCRLUsecase(certRepo), newTestOCSPUsecase(certRepo),
+				NewFakeAuditLogger(), FakeTransactor{},
+			)
+
+			got, err := uc.GetCertificate(ctx, tt.serialNumber)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.serialNumber, got.SerialNumber)
+			assert.Equal(t, deviceID, got.DeviceID)
+			assert.Equal(t, string(tt.wantState), got.State)
+		})
+	}
+}
+
+// TestRevokeCertificate tests the RevokeCertificate method.
+func TestRevokeCertificate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	deviceID := uuid.New()
+
+	tests := []struct {
+		name       string
+		desc       string
+		input      usecase.RevokeCertificateInput
+		repoSetup  func(*FakeCertificateRepository)
+		signErr    error
+		wantReason revocationreason.Reason
+		wantErr    error
+	}{
+		{
+			name:       "success: revoke with a reason",
+			desc:       "Verify that the certificate is revoked and the CRL is regenerated with it.",
+			input:      usecase.RevokeCertificateInput{SerialNumber: 1001, Reason: "keyCompromise"},
+			repoSetup:  nil,
+			signErr:    nil,
+			wantReason: revocationreason.KeyCompromise,
+			wantErr:    nil,
+		},
+		{
+			name:       "success: revoke without a reason",
+			desc:       "Verify that the reason defaults to unspecified.",
+			input:      usecase.RevokeCertificateInput{SerialNumber: 1001, Reason: ""},
+			repoSetup:  nil,
+			signErr:    nil,
+			wantReason: revocationreason.Unspecified,
+			wantErr:    nil,
+		},
+		{
+			name:       "success: CRL generation fails",
+			desc:       "Verify that the revocation succeeds even if the CRL cannot be regenerated.",
+			input:      usecase.RevokeCertificateInput{SerialNumber: 1001, Reason: "superseded"},
+			repoSetup:  nil,
+			signErr:    assert.AnError,
+			wantReason: revocationreason.Superseded,
+			wantErr:    nil,
+		},
+		{
+			name:       "failure: invalid reason",
+			desc:       "Verify that an unknown reason is rejected.",
+			input:      usecase.RevokeCertificateInput{SerialNumber: 1001, Reason: "certificateHold"},
+			repoSetup:  nil,
+			signErr:    nil,
+			wantReason: 0,
+			wantErr:    revocationreason.ErrInvalidReason,
+		},
+		{
+			name:       "failure: certificate not found",
+			desc:       "Verify that a 'certificate not found' error is returned for an unknown serial.",
+			input:      usecase.RevokeCertificateInput{SerialNumber: 9999, Reason: ""},
+			repoSetup:  nil,
+			signErr:    nil,
+			wantReason: 0,
+			wantErr:    entity.ErrCertificateNotFound,
+		},
+		{
+			name:  "failure: already revoked",
+			desc:  "Verify that a certificate cannot be revoked twice.",
+			input: usecase.RevokeCertificateInput{SerialNumber: 1001, Reason: ""},
+			repoSetup: func(repo *FakeCertificateRepository) {
+				repo.certificates[1001].IsRevoked = true
+			},
+			signErr:    nil,
+			wantReason: 0,
+			wantErr:    entity.ErrCertificateAlreadyRevoked,
+		},
+		{
+			name:  "failure: concurrent revocation",
+			desc:  "Verify that a revocation lost to a concurrent one is reported as already revoked.",
+			input: usecase.RevokeCertificateInput{SerialNumber: 1001, Reason: ""},
+			repoSetup: func(repo *FakeCertificateRepository) {
+				repo.RevokeErr = entity.ErrCertificateAlreadyRevoked
+			},
+			signErr:    nil,
+			wantReason: 0,
+			wantErr:    entity.ErrCertificateAlreadyRevoked,
+		},
+		{
+			name:  "failure: repository returns a generic error",
+			desc:  "Verify that an unexpected error while persisting the revocation is propagated.",
+			input: usecase.RevokeCertificateInput{SerialNumber: 1001, Reason: ""},
+			repoSetup: func(repo *FakeCertificateRepository) {
+				repo.RevokeErr = assert.AnError
+			},
+			signErr:    nil,
+			wantReason: 0,
+			wantErr:    usecase.ErrDBRevokeCertificate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			certRepo := NewFakeCertificateRepository()
+			require.NoError(t, certRepo.Save(ctx, newTestCertificate(t, deviceID, 1001)))
+
+			if tt.repoSetup != nil {
+				tt.repoSetup(certRepo)
+			}
+
+			signer := NewFakeRevocationListSigner()
+			signer.SignErr = tt.signErr
+			crl := usecase.NewCRLUsecase(certRepo, signer, nil, usecase.CRLConfig{Validity: 0, RefreshBefore: 0})
+			responder := NewFakeOCSPResponder()
+			ocsp := usecase.NewOCSPUsecase(certRepo, responder, usecase.OCSPConfig{Validity: 0})
+			auditLogger := NewFakeAuditLogger()
+			uc := usecase.NewCertificateUsecase(
+				NewFakeDeviceRepository(), certRepo, crl, ocsp, auditLogger, FakeTransactor{},
+			)
+
+			// Cache an OCSP response reporting the certificate as good.
+			_, err := ocsp.Respond(ctx, []byte("1001"))
+			require.NoError(t, err)
+
+			got, err := uc.RevokeCertificate(ctx, tt.input)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+				assert.Empty(t, signer.Lists())
+				assert.Empty(t, auditLogger.Logs())
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, string(entity.CertificateRevoked), got.State)
+			assert.Equal(t, tt.wantReason.String(), got.RevocationReason)
+			assert.NotNil(t, got.RevokedAt)
+
+			// The revocation is recorded with its reason.
+			require.Equal(t, []entity.AuditAction{entity.AuditCertificateRevoke}, auditLogger.Actions())
+			details := auditDetails(t, auditLogger.Logs()[0])
+			assert.Equal(t, "certificate/1001", details.Target)
+			assert.Equal(t, false, details.Before["revoked"])
+			assert.Equal(t, true, details.After["revoked"])
+			assert.Equal(t, tt.wantReason.String(), details.After["revocationReason"])
+
+			// The cached OCSP response has been dropped.
+			_, err = ocsp.Respond(ctx, []byte("1001"))
+			require.NoError(t, err)
+
+			responses := responder.Responses()
+			require.Len(t, responses, 2)
+			assert.Equal(t, service.OCSPRevoked, responses[1].Status)
+
+			if tt.signErr != nil {
+				return
+			}
+
+			// The CRL has been regenerated with the revoked certificate.
+			lists := signer.Lists()
+			require.Len(t, lists, 1)
+			require.Len(t, lists[0].Entries, 1)
+			assert.Equal(t, int64(1001), lists[0].Entries[0].SerialNumber)
+			assert.Equal(t, tt.wantReason.Code(), lists[0].Entries[0].ReasonCode)
+		})
+	}
+}
+
+// TestRevokeDeviceCertificates tests the RevokeDeviceCertificates method.
+func TestRevokeDeviceCertificates(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	deviceID, otherID := uuid.New(), uuid.New()
+
+	certRepo := NewFakeCertificateRepository()
+	require.NoError(t, certRepo.Save(ctx, newTestCertificate(t, deviceID, 1001)))
+
+	expired := newTestCertificate(t, deviceID, 1002)
+	expired.ValidTo = time.Now().Add(-time.Minute)
+	require.NoError(t, certRepo.Save(ctx, expired))
+
+	signer := NewFakeRevocationListSigner()
+	crl := usecase.NewCRLUsecase(certRepo, signer, nil, usecase.CRLConfig{Validity: 0, RefreshBefore: 0})
+	auditLogger := NewFakeAuditLogger()
+	uc := usecase.NewCertificateUsecase(
+		NewFakeDeviceRepository(), certRepo, crl, newTestOCSPUsecase(certRepo), auditLogger, FakeTransactor{},
+	)
+
+	_, err := uc.RevokeDeviceCertificates(ctx, usecase.RevokeDeviceCertificatesInput{
+		DeviceIDs: []uuid.UUID{deviceID},
+		Reason:    "certificateHold",
+	})
+	require.ErrorIs(t, err, revocationreason.ErrInvalidReason)
+
+	got, err := uc.RevokeDeviceCertificates(ctx, usecase.RevokeDeviceCertificatesInput{
+		DeviceIDs: []uuid.UUID{deviceID, otherID},
+		Reason:    "superseded",
+	})
+	require.NoError(t, err)
+
+	// Only the certificate that is still valid is revoked, and a device without any is reported as such.
+	require.Len(t, got, 2)
+	assert.Equal(t, deviceID, got[0].DeviceID)
+	assert.Equal(t, []int64{1001}, got[0].SerialNumbers)
+	require.NoError(t, got[0].Err)
+	assert.Equal(t, otherID, got[1].DeviceID)
+	assert.Empty(t, got[1].SerialNumbers)
+	require.NoError(t, got[1].Err)
+	assert.False(t, expired.IsRevoked)
+	assert.Equal(t, []entity.AuditAction{entity.AuditCertificateRevoke}, auditLogger.Actions())
+
+	// The CRL is regenerated once.
+	lists := signer.Lists()
+	require.Len(t, lists, 1)
+	require.Len(t, lists[0].Entries, 1)
+	assert.Equal(t, revocationreason.Superseded.Code(), lists[0].Entries[0].ReasonCode)
+
+	// Nothing is left to revoke, so the CRL is not regenerated again.
+	got, err = uc.RevokeDeviceCertificates(ctx, usecase.RevokeDeviceCertificatesInput{
+		DeviceIDs: []uuid.UUID{deviceID},
+		Reason:    "",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, got[0].SerialNumbers)
+	assert.Len(t, signer.Lists(), 1)
+}
@@ -0,0 +1,196 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"backend/internal/domain/repository"
+	"backend/internal/domain/service"
+)
+
+const (
+	// DefaultCRLValidity is the default interval between the thisUpdate and nextUpdate of a CRL.
+	DefaultCRLValidity = 24 * time.Hour
+	// DefaultCRLRefreshBefore is the default margin before nextUpdate at which the CRL is regenerated.
+	DefaultCRLRefreshBefore = time.Hour
+	// crlRetryInterval is the wait before retrying a failed scheduled refresh.
+	crlRetryInterval = time.Minute
+)
+
+// CRLConfig configures the certificate revocation list.
+type CRLConfig struct {
+	// Validity is the interval between the thisUpdate and nextUpdate of a CRL.
+	Validity time.Duration
+	// RefreshBefore is how long before nextUpdate the CRL is regenerated by the scheduler.
+	// It must be shorter than Validity.
+	RefreshBefore time.Duration
+}
+
+// CRLUsecase defines the interface for generating the certificate revocation list.
+type CRLUsecase interface {
+	// GetCRL returns the current CRL. It is regenerated if it is missing, outdated or has expired.
+	GetCRL(ctx context.Context) (*CRLOutput, error)
+	// RefreshCRL regenerates, caches and publishes the CRL.
+	RefreshCRL(ctx context.Context) (*CRLOutput, error)
+	// Run regenerates the CRL immediately and then before every nextUpdate, until ctx is done.
+	Run(ctx context.Context)
+}
+
+// crlUsecase is the implementation of the CRLUsecase interface.
+type crlUsecase struct {
+	certRepo repository.CertificateRepository
+	signer   service.RevocationListSigner
+	// publisher is optional. It is nil if the CRL is only served by the API.
+	publisher     service.RevocationListPublisher
+	config        CRLConfig
+	now           func() time.Time
+	retryInterval time.Duration
+
+	// mu serializes the generation of CRLs, so CRL numbers are strictly increasing.
+	mu      sync.Mutex
+	current *CRLOutput
+	// stale is set when a generation failed, e.g. right after a revocation.
+	stale bool
+}
+
+// NewCRLUsecase creates a new instance of crlUsecase.
+// publisher may be nil. Zero values in config are replaced by the defaults.
+//
+//nolint:ireturn
+func NewCRLUsecase(
+	certRepo repository.CertificateRepository,
+	signer service.RevocationListSigner,
+	publisher service.RevocationListPublisher,
+	config CRLConfig,
+) CRLUsecase {
+	if config.Validity <= 0 {
+		config.Validity = DefaultCRLValidity
+	}
+
+	if config.RefreshBefore <= 0 || config.RefreshBefore >= config.Validity {
+		config.RefreshBefore = min(DefaultCRLRefreshBefore, config.Validity/2) //nolint:mnd
+	}
+
+	return &crlUsecase{
+		certRepo:      certRepo,
+		signer:        signer,
+		publisher:     publisher,
+		config:        config,
+		now:           time.Now,
+		retryInterval: crlRetryInterval,
+		mu:            sync.Mutex{},
+		current:       nil,
+		stale:         false,
+	}
+}
+
+// GetCRL returns the current CRL. It is regenerated if it is missing, outdated or has expired.
+func (uc *crlUsecase) GetCRL(ctx context.Context) (*CRLOutput, error) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if uc.current != nil && !uc.stale && uc.now().Before(uc.current.NextUpdate) {
+		return uc.current, nil
+	}
+
+	return uc.refresh(ctx)
+}
+
+// RefreshCRL regenerates, caches and publishes the CRL.
+func (uc *crlUsecase) RefreshCRL(ctx context.Context) (*CRLOutput, error) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	return uc.refresh(ctx)
+}
+
+// Run regenerates the CRL immediately and then before every nextUpdate, until ctx is done.
+// A failed refresh is retried after a short interval.
+func (uc *crlUsecase) Run(ctx context.Context) {
+	var wait time.Duration
+
+	for {
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return
+		case <-timer.C:
+		}
+
+		output, err := uc.RefreshCRL(ctx)
+		if err != nil {
+			log.Printf("failed to refresh CRL: %v", err)
+
+			wait = uc.retryInterval
+
+			continue
+		}
+
+		wait = output.NextUpdate.Add(-uc.config.RefreshBefore).Sub(uc.now())
+	}
+}
+
+// refresh generates a new CRL from the revoked certificates. uc.mu must be held.
+func (uc *crlUsecase) refresh(ctx context.Context) (*CRLOutput, error) {
+	// Until a new CRL is generated, the cached one must not be served.
+	uc.stale = true
+	now := uc.now()
+
+	certificates, err := uc.certRepo.FindRevoked(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDBFindCertificate, err)
+	}
+
+	entries := make([]service.RevokedCertificate, 0, len(certificates))
+	for _, certificate := range certificates {
+		revokedAt, reason := revocationDetails(certificate)
+
+		entries = append(entries, service.RevokedCertificate{
+			SerialNumber: certificate.SerialNumber,
+			RevokedAt:    revokedAt,
+			ReasonCode:   reason.Code(),
+		})
+	}
+
+	// The Unix time keeps CRL numbers increasing across restarts.
+	number := now.Unix()
+	if uc.current != nil && number <= uc.current.Number {
+		number = uc.current.Number + 1
+	}
+
+	list := service.RevocationList{
+		Number:     number,
+		ThisUpdate: now,
+		NextUpdate: now.Add(uc.config.Validity),
+		Entries:    entries,
+	}
+
+	der, err := uc.signer.SignRevocationList(ctx, list)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCRLSign, err)
+	}
+
+	uc.current = &CRLOutput{
+		Number:       list.Number,
+		ThisUpdate:   list.ThisUpdate,
+		NextUpdate:   list.NextUpdate,
+		RevokedCount: len(entries),
+		DER:          der,
+	}
+	uc.stale = false
+
+	if uc.publisher != nil {
+		err = uc.publisher.PublishRevocationList(ctx, der)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCRLPublish, err)
+		}
+	}
+
+	return uc.current, nil
+}
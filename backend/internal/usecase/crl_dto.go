@@ -0,0 +1,21 @@
+package usecase
+
+import (
+	"encoding/pem"
+	"time"
+)
+
+// CRLOutput is a signed certificate revocation list.
+type CRLOutput struct {
+	Number       int64
+	ThisUpdate   time.Time
+	NextUpdate   time.Time
+	RevokedCount int
+	// DER is the DER-encoded CRL.
+	DER []byte
+}
+
+// PEM returns the PEM-encoded CRL.
+func (o *CRLOutput) PEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Headers: nil, Bytes: o.DER})
+}
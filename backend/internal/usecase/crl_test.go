@@ -0,0 +1,181 @@
+package usecase_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/revocationreason"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// FakeRevocationListPublisher is a fake implementation of service.RevocationListPublisher for testing.
+type FakeRevocationListPublisher struct {
+	mu        sync.Mutex
+	published [][]byte
+	// for controlling error case
+	PublishErr error
+}
+
+// PublishRevocationList records the published revocation list.
+func (p *FakeRevocationListPublisher) PublishRevocationList(_ context.Context, der []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.PublishErr != nil {
+		return p.PublishErr
+	}
+
+	p.published = append(p.published, der)
+
+	return nil
+}
+
+// Published returns the published revocation lists.
+func (p *FakeRevocationListPublisher) Published() [][]byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.published
+}
+
+// TestGetCRL tests the GetCRL and RefreshCRL methods.
+func TestGetCRL(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("success: the CRL is cached until it is refreshed", func(t *testing.T) {
+		t.Parallel()
+
+		certRepo := NewFakeCertificateRepository()
+		signer := NewFakeRevocationListSigner()
+		publisher := &FakeRevocationListPublisher{mu: sync.Mutex{}, published: nil, PublishErr: nil}
+		uc := usecase.NewCRLUsecase(certRepo, signer, publisher, usecase.CRLConfig{Validity: time.Hour, RefreshBefore: 0})
+
+		first, err := uc.GetCRL(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, first.RevokedCount)
+		assert.Equal(t, time.Hour, first.NextUpdate.Sub(first.ThisUpdate))
+
+		cached, err := uc.GetCRL(ctx)
+		require.NoError(t, err)
+		assert.Same(t, first, cached)
+
+		refreshed, err := uc.RefreshCRL(ctx)
+		require.NoError(t, err)
+		assert.Greater(t, refreshed.Number, first.Number, "CRL numbers must increase")
+
+		assert.Len(t, signer.Lists(), 2)
+		assert.Equal(t, [][]byte{first.DER, refreshed.DER}, publisher.Published())
+	})
+
+	t.Run("success: only revoked, unexpired certificates are listed", func(t *testing.T) {
+		t.Parallel()
+
+		certRepo := NewFakeCertificateRepository()
+		valid := newTestCertificate(t, uuid.New(), 1)
+		revoked := newTestCertificate(t, uuid.New(), 2)
+		require.NoError(t, revoked.Revoke(revocationreason.KeyCompromise, time.Now()))
+		expired := newTestCertificate(t, uuid.New(), 3)
+		require.NoError(t, expired.Revoke(revocationreason.Superseded, time.Now()))
+		expired.ValidTo = time.Now().Add(-time.Minute)
+
+		require.NoError(t, certRepo.Save(ctx, valid))
+		require.NoError(t, certRepo.Save(ctx, revoked))
+		require.NoError(t, certRepo.Save(ctx, expired))
+
+		signer := NewFakeRevocationListSigner()
+		uc := usecase.NewCRLUsecase(certRepo, signer, nil, usecase.CRLConfig{Validity: 0, RefreshBefore: 0})
+
+		got, err := uc.GetCRL(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, got.RevokedCount)
+
+		lists := signer.Lists()
+		require.Len(t, lists, 1)
+		require.Len(t, lists[0].Entries, 1)
+		assert.Equal(t, int64(2), lists[0].Entries[0].SerialNumber)
+		assert.Equal(t, revocationreason.KeyCompromise.Code(), lists[0].Entries[0].ReasonCode)
+		assert.Equal(t, *revoked.RevokedAt, lists[0].Entries[0].RevokedAt)
+	})
+
+	t.Run("failure: a failed refresh is not served from the cache", func(t *testing.T) {
+		t.Parallel()
+
+		certRepo := NewFakeCertificateRepository()
+		signer := NewFakeRevocationListSigner()
+		uc := usecase.NewCRLUsecase(certRepo, signer, nil, usecase.CRLConfig{Validity: 0, RefreshBefore: 0})
+
+		_, err := uc.GetCRL(ctx)
+		require.NoError(t, err)
+
+		signer.SignErr = assert.AnError
+		_, err = uc.RefreshCRL(ctx)
+		require.ErrorIs(t, err, usecase.ErrCRLSign)
+
+		// The cached CRL may be missing a revocation, so it must be regenerated.
+		_, err = uc.GetCRL(ctx)
+		require.ErrorIs(t, err, usecase.ErrCRLSign)
+
+		certRepo.FindErr = assert.AnError
+		signer.SignErr = nil
+		_, err = uc.GetCRL(ctx)
+		require.ErrorIs(t, err, usecase.ErrDBFindCertificate)
+	})
+
+	t.Run("failure: publisher returns an error", func(t *testing.T) {
+		t.Parallel()
+
+		publisher := &FakeRevocationListPublisher{mu: sync.Mutex{}, published: nil, PublishErr: assert.AnError}
+		uc := usecase.NewCRLUsecase(NewFakeCertificateRepository(), NewFakeRevocationListSigner(), publisher,
+			usecase.CRLConfig{Validity: 0, RefreshBefore: 0})
+
+		_, err := uc.RefreshCRL(ctx)
+		require.ErrorIs(t, err, usecase.ErrCRLPublish)
+
+		// The CRL itself is valid, so it is still served by the API.
+		_, err = uc.GetCRL(ctx)
+		require.NoError(t, err)
+	})
+}
+
+// TestCRLRun tests that Run regenerates the CRL before nextUpdate until the context is done.
+func TestCRLRun(t *testing.T) {
+	t.Parallel()
+
+	signer := NewFakeRevocationListSigner()
+	uc := usecase.NewCRLUsecase(NewFakeCertificateRepository(), signer, nil, usecase.CRLConfig{
+		Validity:      300 * time.Millisecond,
+		RefreshBefore: 250 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		uc.Run(ctx)
+		close(done)
+	}()
+
+	// The CRL is generated immediately and then every 50ms.
+	require.Eventually(t, func() bool { return len(signer.Lists()) >= 3 }, 2*time.Second, 10*time.Millisecond)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after the context was canceled")
+	}
+
+	lists := signer.Lists()
+	for i := 1; i < len(lists); i++ {
+		assert.Greater(t, lists[i].Number, lists[i-1].Number)
+	}
+}
@@ -18,24 +18,41 @@ type DeviceUsecase interface {
 	CreateDevice(ctx context.Context, input CreateDeviceInput) (*DeviceOutput, error)
 	// GetDevice retrieves a device by its ID.
 	GetDevice(ctx context.Context, id uuid.UUID) (*DeviceOutput, error)
-	// ListDevices retrieves all devices.
-	ListDevices(ctx context.Context) ([]*DeviceOutput, error)
+	// ListDevices retrieves a page of the devices matching the filters.
+	ListDevices(ctx context.Context, input ListDevicesInput) (*DeviceListOutput, error)
 	// UpdateDevice updates an existing device.
+	// If the device is not at the expected version, the error wraps entity.ErrVersionConflict.
 	UpdateDevice(ctx context.Context, input UpdateDeviceInput) (*DeviceOutput, error)
-	// DeleteDevice deletes a device by its ID.
-	DeleteDevice(ctx context.Context, id uuid.UUID) error
+	// PatchDevice partially updates an existing device with a JSON Merge Patch or a JSON Patch.
+	// If the device is not at the expected version, the error wraps entity.ErrVersionConflict.
+	PatchDevice(ctx context.Context, input PatchDeviceInput) (*DeviceOutput, error)
+	// DeleteDevice deletes a device by its ID. Its children are detached from it.
+	// If the device is not at the expected version, the error wraps entity.ErrVersionConflict.
+	DeleteDevice(ctx context.Context, input DeleteDeviceInput) error
+	// ActivateDevice transitions a device to ACTIVE.
+	ActivateDevice(ctx context.Context, id uuid.UUID) (*DeviceOutput, error)
+	// SuspendDevice transitions a device to SUSPENDED.
+	SuspendDevice(ctx context.Context, id uuid.UUID) (*DeviceOutput, error)
+	// RevokeDevice transitions a device to REVOKED.
+	RevokeDevice(ctx context.Context, id uuid.UUID) (*DeviceOutput, error)
 }
 
 // deviceUsecase is the implementation of the DeviceUsecase interface.
 type deviceUsecase struct {
-	deviceRepo repository.DeviceRepository
+	deviceRepo  repository.DeviceRepository
+	auditLogger repository.AuditLogger
+	transactor  repository.Transactor
 }
 
 // NewDeviceUsecase creates a new instance of deviceUsecase.
 //
 //nolint:ireturn
-func NewDeviceUsecase(repo repository.DeviceRepository) DeviceUsecase {
-	return &deviceUsecase{deviceRepo: repo}
+func NewDeviceUsecase(
+	repo repository.DeviceRepository,
+	auditLogger repository.AuditLogger,
+	transactor repository.Transactor,
+) DeviceUsecase {
+	return &deviceUsecase{deviceRepo: repo, auditLogger: auditLogger, transactor: transactor}
 }
 
 // CreateDevice registers a new device.
@@ -52,10 +69,23 @@ func (uc *deviceUsecase) CreateDevice(ctx context.Context, input CreateDeviceInp
 		return nil, fmt.Errorf("failed to create new device entity: %w", err)
 	}
 
-	// Save via the repository.
-	err = uc.deviceRepo.Save(ctx, device)
+	// Save via the repository, together with the audit log entry.
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := uc.deviceRepo.Save(ctx, device)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+
+		return recordAudit(ctx, uc.auditLogger, auditEvent{
+			action:   entity.AuditDeviceCreate,
+			deviceID: device.ID,
+			target:   deviceTarget(device.ID),
+			before:   nil,
+			after:    deviceSnapshot(device),
+		})
+	})
 	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		return nil, err
 	}
 
 	return NewDeviceOutput(device), nil // Convert to output DTO and return.
@@ -75,20 +105,14 @@ func (uc *deviceUsecase) GetDevice(ctx context.Context, id uuid.UUID) (*DeviceOu
 	return NewDeviceOutput(device), nil
 }
 
-// ListDevices retrieves all devices.
-func (uc *deviceUsecase) ListDevices(ctx context.Context) ([]*DeviceOutput, error) {
-	devices, err := uc.deviceRepo.FindAll(ctx)
+// ListDevices retrieves a page of the devices matching the filters.
+func (uc *deviceUsecase) ListDevices(ctx context.Context, input ListDevicesInput) (*DeviceListOutput, error) {
+	query, err := newDeviceQuery(input)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrDBFindAll, err)
-	}
-
-	outputs := make([]*DeviceOutput, 0, len(devices))
-
-	for _, device := range devices {
-		outputs = append(outputs, NewDeviceOutput(device))
+		return nil, err
 	}
 
-	return outputs, nil
+	return listDevicePage(ctx, uc.deviceRepo, query)
 }
 
 // UpdateDevice updates an existing device.
@@ -103,6 +127,13 @@ func (uc *deviceUsecase) UpdateDevice(ctx context.Context, input UpdateDeviceInp
 		return nil, fmt.Errorf("%w: %w", ErrDBFindByID, err)
 	}
 
+	err = checkVersion(device, input.ExpectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	before := deviceSnapshot(device)
+
 	// Update the entity's values.
 	// HardwareID is device-specific and should not be updated.
 	// input.Name is a *string, so check for nil.
@@ -115,21 +146,216 @@ func (uc *deviceUsecase) UpdateDevice(ctx context.Context, input UpdateDeviceInp
 		device.Metadata = input.Metadata
 	}
 
-	// Update via the repository.
-	err = uc.deviceRepo.Save(ctx, device)
+	// Update via the repository, together with the audit log entry.
+	err = uc.saveWithAudit(ctx, device, entity.AuditDeviceUpdate, before)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDeviceOutput(device), nil
+}
+
+// PatchDevice partially updates an existing device with a JSON Merge Patch or a JSON Patch.
+// If the patch cannot be parsed or applied, the error wraps entity.ErrMalformedPatch or entity.ErrInvalidPatch.
+func (uc *deviceUsecase) PatchDevice(ctx context.Context, input PatchDeviceInput) (*DeviceOutput, error) {
+	var apply func(device *entity.Device) error
+
+	switch input.Format {
+	case PatchFormatMerge:
+		apply = func(device *entity.Device) error { return device.MergePatch(input.Patch) }
+	case PatchFormatJSON:
+		apply = func(device *entity.Device) error { return device.JSONPatch(input.Patch) }
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedPatchFormat, input.Format)
+	}
+
+	device, err := uc.deviceRepo.FindByID(ctx, input.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, entity.ErrDeviceNotFound
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrDBFindByID, err)
+	}
+
+	err = checkVersion(device, input.ExpectedVersion)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		return nil, err
+	}
+
+	before := deviceSnapshot(device)
+
+	err = apply(device)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.saveWithAudit(ctx, device, entity.AuditDeviceUpdate, before)
+	if err != nil {
+		return nil, err
 	}
 
 	return NewDeviceOutput(device), nil
 }
 
 // DeleteDevice deletes a device by its ID.
-func (uc *deviceUsecase) DeleteDevice(ctx context.Context, id uuid.UUID) error {
-	err := uc.deviceRepo.Delete(ctx, id)
+func (uc *deviceUsecase) DeleteDevice(ctx context.Context, input DeleteDeviceInput) error {
+	// The device is loaded first, so that the audit log entry records what was deleted.
+	device, err := uc.deviceRepo.FindByID(ctx, input.ID)
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrDBDelete, err)
+		if isNotFound(err, entity.ErrDeviceNotFound) {
+			return fmt.Errorf("%w: %w", ErrDBDelete, entity.ErrDeviceNotFound)
+		}
+
+		return fmt.Errorf("%w: %w", ErrDBFindByID, err)
+	}
+
+	err = checkVersion(device, input.ExpectedVersion)
+	if err != nil {
+		return err
+	}
+
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		// The children are detached first, so that their versions and the audit trail record the change.
+		err := uc.deviceRepo.LockTopology(ctx)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrDBDeviceTopology, err)
+		}
+
+		err = detachChildDevices(ctx, uc.deviceRepo, uc.auditLogger, device.ID)
+		if err != nil {
+			return err
+		}
+
+		// The deletion is conditional on the version that was read, so that the audit log entry is accurate.
+		err = uc.deviceRepo.Delete(ctx, device.ID, device.Version)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrDBDelete, err)
+		}
+
+		return recordAudit(ctx, uc.auditLogger, auditEvent{
+			action:   entity.AuditDeviceDelete,
+			deviceID: device.ID,
+			target:   deviceTarget(device.ID),
+			before:   deviceSnapshot(device),
+			after:    nil,
+		})
+	})
+}
+
+// ActivateDevice transitions a device to ACTIVE.
+func (uc *deviceUsecase) ActivateDevice(ctx context.Context, id uuid.UUID) (*DeviceOutput, error) {
+	return uc.changeStatus(ctx, id, (*entity.Device).Activate)
+}
+
+// SuspendDevice transitions a device to SUSPENDED.
+func (uc *deviceUsecase) SuspendDevice(ctx context.Context, id uuid.UUID) (*DeviceOutput, error) {
+	return uc.changeStatus(ctx, id, (*entity.Device).Suspend)
+}
+
+// RevokeDevice transitions a device to REVOKED.
+func (uc *deviceUsecase) RevokeDevice(ctx context.Context, id uuid.UUID) (*DeviceOutput, error) {
+	return uc.changeStatus(ctx, id, (*entity.Device).Revoke)
+}
+
+// changeStatus applies a status transition to a device and saves it.
+// If the transition is not allowed, the error wraps devicestatus.ErrInvalidTransition.
+func (uc *deviceUsecase) changeStatus(
+	ctx context.Context,
+	id uuid.UUID,
+	transition func(*entity.Device) error,
+) (*DeviceOutput, error) {
+	device, err := uc.deviceRepo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, entity.ErrDeviceNotFound
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrDBFindByID, err)
+	}
+
+	before := deviceSnapshot(device)
+
+	err = transition(device)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.saveWithAudit(ctx, device, entity.AuditDeviceStatusChange, before)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDeviceOutput(device), nil
+}
+
+// saveWithAudit saves a changed device and records the change in the audit trail within one transaction.
+func (uc *deviceUsecase) saveWithAudit(
+	ctx context.Context,
+	device *entity.Device,
+	action entity.AuditAction,
+	before map[string]any,
+) error {
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := uc.deviceRepo.Save(ctx, device)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+
+		return recordAudit(ctx, uc.auditLogger, auditEvent{
+			action:   action,
+			deviceID: device.ID,
+			target:   deviceTarget(device.ID),
+			before:   before,
+			after:    deviceSnapshot(device),
+		})
+	})
+}
+
+// checkVersion returns a *entity.VersionConflictError if the device is not at the expected version.
+// An expected version of zero matches any version.
+func checkVersion(device *entity.Device, expected int64) error {
+	if expected != 0 && expected != device.Version {
+		return &entity.VersionConflictError{ID: device.ID, Expected: expected, Actual: device.Version}
 	}
 
 	return nil
 }
+
+// listDevicePage reads the page of devices specified by a validated query, with the cursor of the next page.
+func listDevicePage(
+	ctx context.Context,
+	deviceRepo repository.DeviceRepository,
+	query repository.DeviceQuery,
+) (*DeviceListOutput, error) {
+	// One more device than requested is read to tell whether there is a next page.
+	limit := query.Limit
+	query.Limit++
+
+	devices, err := deviceRepo.FindByQuery(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDBFindAll, err)
+	}
+
+	total, err := deviceRepo.Count(ctx, query.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDBFindAll, err)
+	}
+
+	output := &DeviceListOutput{
+		Devices:    make([]*DeviceOutput, 0, min(len(devices), limit)),
+		NextCursor: "",
+		TotalCount: total,
+	}
+
+	if len(devices) > limit {
+		devices = devices[:limit]
+		output.NextCursor = encodeDeviceCursor(query, devices[limit-1])
+	}
+
+	for _, device := range devices {
+		output.Devices = append(output.Devices, NewDeviceOutput(device))
+	}
+
+	return output, nil
+}
@@ -0,0 +1,414 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/VO/commandstatus"
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"backend/internal/domain/service"
+)
+
+const (
+	// DefaultDeviceCommandTTL is the default time a device has to answer a command.
+	DefaultDeviceCommandTTL = 5 * time.Minute
+	// DefaultDeviceCommandMaxTTL is the default upper bound of the TTL an operator can request.
+	DefaultDeviceCommandMaxTTL = 24 * time.Hour
+	// DefaultDeviceCommandDispatchInterval is the default interval at which the QUEUED commands are published again.
+	DefaultDeviceCommandDispatchInterval = 10 * time.Second
+	// MaxDeviceCommandWait is the longest a request can wait for the outcome of a command.
+	MaxDeviceCommandWait = time.Minute
+
+	// commandPollInterval is the interval at which a waiting request reads the command again,
+	// in case it was answered through another instance of the backend.
+	commandPollInterval = time.Second
+	// commandDispatchBatchSize is the number of QUEUED commands published again at each dispatch.
+	commandDispatchBatchSize = 100
+	// maxAcknowledgeAttempts is the number of times an answer is applied, if the command is marked as SENT
+	// concurrently.
+	maxAcknowledgeAttempts = 2
+)
+
+// DeviceCommandConfig configures the lifetime and the delivery of device commands.
+type DeviceCommandConfig struct {
+	// DefaultTTL is used when the request does not specify a TTL.
+	DefaultTTL time.Duration
+	// MaxTTL is the maximum TTL a request can specify.
+	MaxTTL time.Duration
+	// DispatchInterval is the interval at which the overdue commands are expired
+	// and the QUEUED commands are published again.
+	DispatchInterval time.Duration
+}
+
+// withDefaults returns the config with zero values replaced by the defaults.
+func (c DeviceCommandConfig) withDefaults() DeviceCommandConfig {
+	if c.DefaultTTL <= 0 {
+		c.DefaultTTL = DefaultDeviceCommandTTL
+	}
+
+	if c.MaxTTL <= 0 {
+		c.MaxTTL = DefaultDeviceCommandMaxTTL
+	}
+
+	if c.DispatchInterval <= 0 {
+		c.DispatchInterval = DefaultDeviceCommandDispatchInterval
+	}
+
+	return c
+}
+
+// ttl returns the lifetime of a command requested for ttlSeconds, or the default TTL if ttlSeconds is zero.
+func (c DeviceCommandConfig) ttl(ttlSeconds int64) (time.Duration, error) {
+	if ttlSeconds == 0 {
+		return c.DefaultTTL, nil
+	}
+
+	return secondsToDuration(ttlSeconds, 1, c.MaxTTL, ErrInvalidTTL)
+}
+
+// DeviceCommandUsecase defines the interface for sending commands to devices and tracking their outcome.
+type DeviceCommandUsecase interface {
+	// SendCommand queues a command for an ACTIVE device and publishes it.
+	SendCommand(ctx context.Context, input SendDeviceCommandInput) (*DeviceCommandOutput, error)
+	// GetCommand retrieves a command of a device, optionally waiting for it to complete.
+	GetCommand(ctx context.Context, input GetDeviceCommandInput) (*DeviceCommandOutput, error)
+	// AcknowledgeCommand records the answer of a device to one of its commands.
+	AcknowledgeCommand(ctx context.Context, input AcknowledgeDeviceCommandInput) error
+	// Run expires the overdue commands and publishes the QUEUED commands again at every dispatch interval,
+	// until ctx is done.
+	Run(ctx context.Context)
+}
+
+// deviceCommandUsecase is the implementation of the DeviceCommandUsecase interface.
+type deviceCommandUsecase struct {
+	deviceRepo  repository.DeviceRepository
+	commandRepo repository.DeviceCommandRepository
+	auditLogger repository.AuditLogger
+	transactor  repository.Transactor
+	publisher   service.DeviceCommandPublisher
+	config      DeviceCommandConfig
+	waiters     *commandWaiters
+	now         func() time.Time
+}
+
+// deviceCommandResponse is the answer of a device to a command.
+type deviceCommandResponse struct {
+	ID     uuid.UUID      `json:"id"`
+	Status string         `json:"status"`
+	Result map[string]any `json:"result"`
+	Reason string         `json:"reason"`
+}
+
+// NewDeviceCommandUsecase creates a new instance of deviceCommandUsecase.
+// Zero values in config are replaced by the defaults.
+//
+//nolint:ireturn
+func NewDeviceCommandUsecase(
+	deviceRepo repository.DeviceRepository,
+	commandRepo repository.DeviceCommandRepository,
+	auditLogger repository.AuditLogger,
+	transactor repository.Transactor,
+	publisher service.DeviceCommandPublisher,
+	config DeviceCommandConfig,
+) DeviceCommandUsecase {
+	return &deviceCommandUsecase{
+		deviceRepo:  deviceRepo,
+		commandRepo: commandRepo,
+		auditLogger: auditLogger,
+		transactor:  transactor,
+		publisher:   publisher,
+		config:      config.withDefaults(),
+		waiters:     &commandWaiters{mu: sync.Mutex{}, waiters: make(map[uuid.UUID][]chan struct{})},
+		now:         time.Now,
+	}
+}
+
+// SendCommand queues a command for an ACTIVE device and records it in the audit trail.
+// Once it is committed, the command is published and marked as SENT. If it cannot be published,
+// e.g. because the device is not connected, it stays QUEUED and is published again by Run.
+func (uc *deviceCommandUsecase) SendCommand(
+	ctx context.Context,
+	input SendDeviceCommandInput,
+) (*DeviceCommandOutput, error) {
+	ttl, err := uc.config.ttl(input.TTLSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	var command *entity.DeviceCommand
+
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		device, err := findDevice(ctx, uc.deviceRepo, input.DeviceID)
+		if err != nil {
+			return err
+		}
+
+		if device.Status != devicestatus.Active {
+			return fmt.Errorf("%w: %s", ErrDeviceNotActive, device.Status)
+		}
+
+		command, err = entity.NewDeviceCommand(device.ID, input.Name, input.Payload, ttl, uc.now())
+		if err != nil {
+			return err
+		}
+
+		err = uc.commandRepo.Save(ctx, command)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+
+		return recordAudit(ctx, uc.auditLogger, auditEvent{
+			action:   entity.AuditDeviceCommandSend,
+			deviceID: device.ID,
+			target:   deviceCommandTarget(command.ID),
+			before:   nil,
+			after:    deviceCommandSnapshot(command),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.deliver(ctx, command)
+
+	return NewDeviceCommandOutput(command, uc.now()), nil
+}
+
+// GetCommand retrieves a command of a device. If input.Wait is set and the command is still pending,
+// it waits up to input.Wait (at most MaxDeviceCommandWait) for the command to be answered or to expire,
+// and returns the command as it is then.
+func (uc *deviceCommandUsecase) GetCommand(
+	ctx context.Context,
+	input GetDeviceCommandInput,
+) (*DeviceCommandOutput, error) {
+	deadline := uc.now().Add(min(input.Wait, MaxDeviceCommandWait))
+
+	for {
+		// The waiter is registered before the command is read, so that an answer in between is not missed.
+		answered, stop := uc.waiters.wait(input.CommandID)
+
+		command, err := uc.findCommand(ctx, input.DeviceID, input.CommandID)
+		if err != nil {
+			stop()
+
+			return nil, err
+		}
+
+		now := uc.now()
+
+		remaining := min(deadline.Sub(now), command.ExpiresAt.Sub(now))
+		if command.StatusAt(now).IsTerminal() || remaining <= 0 {
+			stop()
+
+			return NewDeviceCommandOutput(command, now), nil
+		}
+
+		timer := time.NewTimer(min(remaining, commandPollInterval))
+
+		select {
+		case <-ctx.Done():
+			// The client went away, so the command is returned as it is.
+			timer.Stop()
+			stop()
+
+			return NewDeviceCommandOutput(command, uc.now()), nil
+		case <-answered:
+		case <-timer.C:
+		}
+
+		timer.Stop()
+		stop()
+	}
+}
+
+// AcknowledgeCommand records the answer of a device to one of its commands, and wakes the requests
+// waiting for it. A command can only be answered once, and not after it has expired.
+func (uc *deviceCommandUsecase) AcknowledgeCommand(ctx context.Context, input AcknowledgeDeviceCommandInput) error {
+	var response deviceCommandResponse
+
+	err := json.Unmarshal(input.Response, &response)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCommandResponse, err)
+	}
+
+	status := commandstatus.Status(response.Status)
+	if status != commandstatus.Acked && status != commandstatus.Failed {
+		return fmt.Errorf("%w: status %q", ErrInvalidCommandResponse, response.Status)
+	}
+
+	// The answer is applied again if the command was marked as SENT meanwhile.
+	for attempt := 1; ; attempt++ {
+		command, err := uc.findCommand(ctx, input.DeviceID, response.ID)
+		if err != nil {
+			return err
+		}
+
+		from := command.Status
+
+		err = command.Complete(status, response.Result, response.Reason, uc.now())
+		if err != nil {
+			return err
+		}
+
+		err = uc.commandRepo.UpdateStatus(ctx, command, from)
+		if err == nil {
+			break
+		}
+
+		if !errors.Is(err, entity.ErrDeviceCommandStatusConflict) || attempt == maxAcknowledgeAttempts {
+			return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+	}
+
+	uc.waiters.notify(response.ID)
+
+	return nil
+}
+
+// Run expires the overdue commands and publishes the QUEUED commands again at every dispatch interval,
+// until ctx is done.
+func (uc *deviceCommandUsecase) Run(ctx context.Context) {
+	ticker := time.NewTicker(uc.config.DispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		uc.dispatch(ctx)
+	}
+}
+
+// dispatch expires the overdue commands and publishes the oldest QUEUED commands again.
+func (uc *deviceCommandUsecase) dispatch(ctx context.Context) {
+	now := uc.now()
+
+	_, err := uc.commandRepo.ExpireOverdue(ctx, now)
+	if err != nil {
+		log.Printf("failed to expire device commands: %v", err)
+	}
+
+	commands, err := uc.commandRepo.FindQueued(ctx, now, commandDispatchBatchSize)
+	if err != nil {
+		log.Printf("failed to find queued device commands: %v", err)
+
+		return
+	}
+
+	for _, command := range commands {
+		uc.deliver(ctx, command)
+	}
+}
+
+// deliver publishes a QUEUED command and marks it as SENT. A command that cannot be published stays QUEUED.
+// The command is updated to its stored state if the device answered before it was marked as SENT.
+func (uc *deviceCommandUsecase) deliver(ctx context.Context, command *entity.DeviceCommand) {
+	err := uc.publisher.PublishCommand(ctx, service.DeviceCommandMessage{
+		ID:        command.ID,
+		DeviceID:  command.DeviceID,
+		Name:      command.Name,
+		Payload:   command.Payload,
+		ExpiresAt: command.ExpiresAt,
+	})
+	if err != nil {
+		return
+	}
+
+	sent := *command
+
+	err = sent.MarkSent(uc.now())
+	if err != nil {
+		return
+	}
+
+	err = uc.commandRepo.UpdateStatus(ctx, &sent, commandstatus.Queued)
+	if err == nil {
+		*command = sent
+
+		return
+	}
+
+	if !errors.Is(err, entity.ErrDeviceCommandStatusConflict) {
+		log.Printf("failed to mark device command %s as sent: %v", command.ID, err)
+
+		return
+	}
+
+	stored, err := uc.commandRepo.FindByID(ctx, command.DeviceID, command.ID)
+	if err == nil {
+		*command = *stored
+	}
+}
+
+// findCommand retrieves a command of a device.
+func (uc *deviceCommandUsecase) findCommand(
+	ctx context.Context,
+	deviceID, commandID uuid.UUID,
+) (*entity.DeviceCommand, error) {
+	command, err := uc.commandRepo.FindByID(ctx, deviceID, commandID)
+	if err != nil {
+		if isNotFound(err, entity.ErrDeviceCommandNotFound) {
+			return nil, entity.ErrDeviceCommandNotFound
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrDBFindByID, err)
+	}
+
+	return command, nil
+}
+
+// commandWaiters wakes the requests waiting for the outcome of a command when this instance receives
+// the answer of the device.
+type commandWaiters struct {
+	mu      sync.Mutex
+	waiters map[uuid.UUID][]chan struct{}
+}
+
+// wait registers a waiter for a command. The returned channel is closed when the command is answered,
+// and the returned function unregisters the waiter.
+func (w *commandWaiters) wait(commandID uuid.UUID) (<-chan struct{}, func()) {
+	answered := make(chan struct{})
+
+	w.mu.Lock()
+	w.waiters[commandID] = append(w.waiters[commandID], answered)
+	w.mu.Unlock()
+
+	return answered, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		remaining := slices.DeleteFunc(w.waiters[commandID], func(c chan struct{}) bool { return c == answered })
+		if len(remaining) == 0 {
+			delete(w.waiters, commandID)
+
+			return
+		}
+
+		w.waiters[commandID] = remaining
+	}
+}
+
+// notify wakes the waiters of a command.
+func (w *commandWaiters) notify(commandID uuid.UUID) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, answered := range w.waiters[commandID] {
+		close(answered)
+	}
+
+	delete(w.waiters, commandID)
+}
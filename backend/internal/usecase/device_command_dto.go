@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+)
+
+// SendDeviceCommandInput is the input data for sending a command to a device.
+type SendDeviceCommandInput struct {
+	DeviceID   uuid.UUID      `json:"-"`
+	Name       string         `json:"name"`       // Required, e.g. "reboot".
+	Payload    map[string]any `json:"payload"`    // Optional: the parameters of the command.
+	TTLSeconds int64          `json:"ttlSeconds"` // Optional: if zero, the default TTL is used.
+}
+
+// GetDeviceCommandInput is the input data for retrieving the outcome of a command.
+type GetDeviceCommandInput struct {
+	DeviceID  uuid.UUID
+	CommandID uuid.UUID
+	// Wait is how long to wait for the command to complete, if it is still pending.
+	// Optional: if zero, the current status is returned immediately.
+	Wait time.Duration
+}
+
+// AcknowledgeDeviceCommandInput is the input data for the answer of a device to a command, e.g. over MQTT.
+type AcknowledgeDeviceCommandInput struct {
+	DeviceID uuid.UUID
+	// Response is the JSON answer of the device:
+	// {"id": <command ID>, "status": "ACKED" or "FAILED", "result": {...}, "reason": "..."}.
+	Response []byte
+}
+
+// DeviceCommandOutput is the output data of a device command.
+type DeviceCommandOutput struct {
+	ID            uuid.UUID      `json:"id"`
+	DeviceID      uuid.UUID      `json:"deviceId"`
+	Name          string         `json:"name"`
+	Payload       map[string]any `json:"payload"`
+	Status        string         `json:"status"`
+	Result        map[string]any `json:"result,omitempty"`
+	FailureReason string         `json:"failureReason,omitempty"`
+	ExpiresAt     time.Time      `json:"expiresAt"`
+	SentAt        *time.Time     `json:"sentAt,omitempty"`
+	CompletedAt   *time.Time     `json:"completedAt,omitempty"`
+	CreatedAt     time.Time      `json:"createdAt"`
+}
+
+// NewDeviceCommandOutput creates a new DeviceCommandOutput from a DeviceCommand entity.
+// The status is the one at now, so a pending command past its TTL is reported as EXPIRED.
+func NewDeviceCommandOutput(command *entity.DeviceCommand, now time.Time) *DeviceCommandOutput {
+	return &DeviceCommandOutput{
+		ID:            command.ID,
+		DeviceID:      command.DeviceID,
+		Name:          command.Name,
+		Payload:       command.Payload,
+		Status:        string(command.StatusAt(now)),
+		Result:        command.Result,
+		FailureReason: command.FailureReason,
+		ExpiresAt:     command.ExpiresAt,
+		SentAt:        command.SentAt,
+		CompletedAt:   command.CompletedAt,
+		CreatedAt:     command.CreatedAt,
+	}
+}
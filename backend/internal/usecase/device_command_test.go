@@ -0,0 +1,569 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/commandstatus"
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/service"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// FakeDeviceCommandRepository is an in-memory implementation of the DeviceCommandRepository for testing.
+type FakeDeviceCommandRepository struct {
+	mu       sync.Mutex
+	commands map[uuid.UUID]entity.DeviceCommand
+	created  []uuid.UUID
+}
+
+// NewFakeDeviceCommandRepository creates a new FakeDeviceCommandRepository.
+func NewFakeDeviceCommandRepository() *FakeDeviceCommandRepository {
+	return &FakeDeviceCommandRepository{
+		mu:       sync.Mutex{},
+		commands: make(map[uuid.UUID]entity.DeviceCommand),
+		created:  nil,
+	}
+}
+
+// Save stores a copy of a new command and assigns its ID.
+func (r *FakeDeviceCommandRepository) Save(_ context.Context, command *entity.DeviceCommand) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	command.ID = uuid.New()
+	command.CreatedAt = time.Now()
+	r.commands[command.ID] = *command
+	r.created = append(r.created, command.ID)
+
+	return nil
+}
+
+// FindByID returns a copy of a command of the device.
+func (r *FakeDeviceCommandRepository) FindByID(
+	_ context.Context,
+	deviceID, id uuid.UUID,
+) (*entity.DeviceCommand, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	command, ok := r.commands[id]
+	if !ok || command.DeviceID != deviceID {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &command, nil
+}
+
+// FindQueued returns copies of the QUEUED commands that have not expired, oldest first.
+func (r *FakeDeviceCommandRepository) FindQueued(
+	_ context.Context,
+	now time.Time,
+	limit int,
+) ([]*entity.DeviceCommand, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var commands []*entity.DeviceCommand
+
+	for _, id := range r.created {
+		command := r.commands[id]
+		if command.Status == commandstatus.Queued && command.ExpiresAt.After(now) && len(commands) < limit {
+			commands = append(commands, &command)
+		}
+	}
+
+	return commands, nil
+}
+
+// UpdateStatus stores a copy of the command if its stored status is still from.
+func (r *FakeDeviceCommandRepository) UpdateStatus(
+	_ context.Context,
+	command *entity.DeviceCommand,
+	from commandstatus.Status,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.commands[command.ID].Status != from {
+		return entity.ErrDeviceCommandStatusConflict
+	}
+
+	r.commands[command.ID] = *command
+
+	return nil
+}
+
+// ExpireOverdue marks the pending commands whose TTL has passed as EXPIRED.
+func (r *FakeDeviceCommandRepository) ExpireOverdue(_ context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var expired int64
+
+	for id, command := range r.commands {
+		pending := slices.Contains([]commandstatus.Status{commandstatus.Queued, commandstatus.Sent}, command.Status)
+		if pending && !now.Before(command.ExpiresAt) {
+			command.Status = commandstatus.Expired
+			command.CompletedAt = &now
+			r.commands[id] = command
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// setExpiresAt changes the expiry of a stored command, to test commands that expire.
+func (r *FakeDeviceCommandRepository) setExpiresAt(id uuid.UUID, expiresAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	command := r.commands[id]
+	command.ExpiresAt = expiresAt
+	r.commands[id] = command
+}
+
+// status returns the stored status of a command.
+func (r *FakeDeviceCommandRepository) status(id uuid.UUID) commandstatus.Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.commands[id].Status
+}
+
+// FakeDeviceCommandPublisher is a fake implementation of service.DeviceCommandPublisher for testing.
+type FakeDeviceCommandPublisher struct {
+	mu       sync.Mutex
+	messages []service.DeviceCommandMessage
+	// for controlling error case
+	PublishErr error
+	// OnPublish is called with every published command, e.g. to answer it as the device would.
+	OnPublish func(command service.DeviceCommandMessage)
+}
+
+// PublishCommand records the published command.
+func (p *FakeDeviceCommandPublisher) PublishCommand(_ context.Context, command service.DeviceCommandMessage) error {
+	p.mu.Lock()
+
+	if p.PublishErr != nil {
+		p.mu.Unlock()
+
+		return p.PublishErr
+	}
+
+	p.messages = append(p.messages, command)
+	onPublish := p.OnPublish
+	p.mu.Unlock()
+
+	if onPublish != nil {
+		onPublish(command)
+	}
+
+	return nil
+}
+
+// SetPublishErr makes PublishCommand fail with err.
+func (p *FakeDeviceCommandPublisher) SetPublishErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.PublishErr = err
+}
+
+// Messages returns the published commands.
+func (p *FakeDeviceCommandPublisher) Messages() []service.DeviceCommandMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]service.DeviceCommandMessage(nil), p.messages...)
+}
+
+// deviceCommandTest holds a DeviceCommandUsecase and its fakes.
+type deviceCommandTest struct {
+	uc          usecase.DeviceCommandUsecase
+	deviceRepo  *FakeDeviceRepository
+	commandRepo *FakeDeviceCommandRepository
+	auditLogger *FakeAuditLogger
+	publisher   *FakeDeviceCommandPublisher
+}
+
+func newDeviceCommandTest() *deviceCommandTest {
+	tt := &deviceCommandTest{
+		uc:          nil,
+		deviceRepo:  NewFakeDeviceRepository(),
+		commandRepo: NewFakeDeviceCommandRepository(),
+		auditLogger: NewFakeAuditLogger(),
+		publisher:   &FakeDeviceCommandPublisher{}, //nolint:exhaustruct
+	}
+	tt.uc = usecase.NewDeviceCommandUsecase(
+		tt.deviceRepo, tt.commandRepo, tt.auditLogger, FakeTransactor{}, tt.publisher, usecase.DeviceCommandConfig{
+			DefaultTTL:       0,
+			MaxTTL:           time.Hour,
+			DispatchInterval: 10 * time.Millisecond,
+		},
+	)
+
+	return tt
+}
+
+// addDevice stores a device with the status.
+func (tt *deviceCommandTest) addDevice(status devicestatus.Status) *entity.Device {
+	device := newTestDevice("hw-command-01", status)
+	tt.deviceRepo.devices[device.ID] = device
+
+	return device
+}
+
+// send sends a command to the device and fails the test on error.
+func (tt *deviceCommandTest) send(t *testing.T, deviceID uuid.UUID) *usecase.DeviceCommandOutput {
+	t.Helper()
+
+	output, err := tt.uc.SendCommand(context.Background(), usecase.SendDeviceCommandInput{
+		DeviceID:   deviceID,
+		Name:       "reboot",
+		Payload:    nil,
+		TTLSeconds: 0,
+	})
+	require.NoError(t, err)
+
+	return output
+}
+
+// answer sends the answer of the device to a command.
+func (tt *deviceCommandTest) answer(deviceID uuid.UUID, response string) error {
+	return tt.uc.AcknowledgeCommand(context.Background(), usecase.AcknowledgeDeviceCommandInput{
+		DeviceID: deviceID,
+		Response: []byte(response),
+	})
+}
+
+// TestSendDeviceCommand tests the SendCommand and Run methods.
+func TestSendDeviceCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("success: the command is published and audited", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newDeviceCommandTest()
+		device := tt.addDevice(devicestatus.Active)
+
+		output, err := tt.uc.SendCommand(ctx, usecase.SendDeviceCommandInput{
+			DeviceID:   device.ID,
+			Name:       " rotate-certificate ",
+			Payload:    map[string]any{"keyType": "ecdsa"},
+			TTLSeconds: 60,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "rotate-certificate", output.Name)
+		assert.Equal(t, string(commandstatus.Sent), output.Status)
+		assert.NotNil(t, output.SentAt)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), output.ExpiresAt, 5*time.Second)
+		assert.Equal(t, commandstatus.Sent, tt.commandRepo.status(output.ID))
+
+		messages := tt.publisher.Messages()
+		require.Len(t, messages, 1)
+		assert.Equal(t, service.DeviceCommandMessage{
+			ID:        output.ID,
+			DeviceID:  device.ID,
+			Name:      "rotate-certificate",
+			Payload:   map[string]any{"keyType": "ecdsa"},
+			ExpiresAt: output.ExpiresAt,
+		}, messages[0])
+
+		logs := tt.auditLogger.Logs()
+		require.Len(t, logs, 1)
+		assert.Equal(t, entity.AuditDeviceCommandSend, logs[0].Action)
+		details := auditDetails(t, logs[0])
+		assert.Equal(t, "device-command/"+output.ID.String(), details.Target)
+		assert.Equal(t, "rotate-certificate", details.After["name"])
+	})
+
+	t.Run("success: the command stays queued until it can be published", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newDeviceCommandTest()
+		device := tt.addDevice(devicestatus.Active)
+		tt.publisher.SetPublishErr(errors.New("device is not connected"))
+
+		output := tt.send(t, device.ID)
+		assert.Equal(t, string(commandstatus.Queued), output.Status)
+		assert.WithinDuration(t, time.Now().Add(usecase.DefaultDeviceCommandTTL), output.ExpiresAt, 5*time.Second)
+
+		// An overdue command is expired instead of being published.
+		overdue := tt.send(t, device.ID)
+		tt.commandRepo.setExpiresAt(overdue.ID, time.Now().Add(-time.Second))
+
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		go tt.uc.Run(runCtx)
+
+		tt.publisher.SetPublishErr(nil)
+
+		require.Eventually(t, func() bool {
+			return tt.commandRepo.status(output.ID) == commandstatus.Sent
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, commandstatus.Expired, tt.commandRepo.status(overdue.ID))
+		assert.Len(t, tt.publisher.Messages(), 1)
+	})
+
+	t.Run("success: a command answered while it is being published is returned as answered", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newDeviceCommandTest()
+		device := tt.addDevice(devicestatus.Active)
+		tt.publisher.OnPublish = func(command service.DeviceCommandMessage) {
+			assert.NoError(t, tt.answer(device.ID, `{"id": "`+command.ID.String()+`", "status": "ACKED"}`))
+		}
+
+		output := tt.send(t, device.ID)
+		assert.Equal(t, string(commandstatus.Acked), output.Status)
+		assert.Equal(t, commandstatus.Acked, tt.commandRepo.status(output.ID))
+	})
+
+	tests := []struct {
+		name    string
+		status  devicestatus.Status
+		input   func(deviceID uuid.UUID) usecase.SendDeviceCommandInput
+		wantErr error
+	}{
+		{
+			name:   "failure: device is not active",
+			status: devicestatus.Suspended,
+			input: func(deviceID uuid.UUID) usecase.SendDeviceCommandInput {
+				return usecase.SendDeviceCommandInput{DeviceID: deviceID, Name: "reboot", Payload: nil, TTLSeconds: 0}
+			},
+			wantErr: usecase.ErrDeviceNotActive,
+		},
+		{
+			name:   "failure: device not found",
+			status: devicestatus.Active,
+			input: func(uuid.UUID) usecase.SendDeviceCommandInput {
+				return usecase.SendDeviceCommandInput{DeviceID: uuid.New(), Name: "reboot", Payload: nil, TTLSeconds: 0}
+			},
+			wantErr: entity.ErrDeviceNotFound,
+		},
+		{
+			name:   "failure: name is empty",
+			status: devicestatus.Active,
+			input: func(deviceID uuid.UUID) usecase.SendDeviceCommandInput {
+				return usecase.SendDeviceCommandInput{DeviceID: deviceID, Name: " ", Payload: nil, TTLSeconds: 0}
+			},
+			wantErr: entity.ErrInvalidCommandName,
+		},
+		{
+			name:   "failure: TTL is longer than the maximum",
+			status: devicestatus.Active,
+			input: func(deviceID uuid.UUID) usecase.SendDeviceCommandInput {
+				return usecase.SendDeviceCommandInput{
+					DeviceID: deviceID, Name: "reboot", Payload: nil, TTLSeconds: 3601,
+				}
+			},
+			wantErr: usecase.ErrInvalidTTL,
+		},
+		{
+			name:   "failure: TTL overflows a duration",
+			status: devicestatus.Active,
+			input: func(deviceID uuid.UUID) usecase.SendDeviceCommandInput {
+				return usecase.SendDeviceCommandInput{
+					DeviceID: deviceID, Name: "reboot", Payload: nil, TTLSeconds: math.MaxInt64,
+				}
+			},
+			wantErr: usecase.ErrInvalidTTL,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt := newDeviceCommandTest()
+			device := tt.addDevice(test.status)
+
+			_, err := tt.uc.SendCommand(ctx, test.input(device.ID))
+			require.ErrorIs(t, err, test.wantErr)
+			assert.Empty(t, tt.publisher.Messages())
+			assert.Empty(t, tt.auditLogger.Logs())
+		})
+	}
+}
+
+// TestAcknowledgeDeviceCommand tests the AcknowledgeCommand method.
+func TestAcknowledgeDeviceCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tt := newDeviceCommandTest()
+	device := tt.addDevice(devicestatus.Active)
+
+	acked := tt.send(t, device.ID)
+	require.NoError(t, tt.answer(device.ID,
+		`{"id": "`+acked.ID.String()+`", "status": "ACKED", "result": {"uptime": 0}}`))
+
+	output, err := tt.uc.GetCommand(ctx, usecase.GetDeviceCommandInput{
+		DeviceID: device.ID, CommandID: acked.ID, Wait: 0,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, string(commandstatus.Acked), output.Status)
+	assert.Equal(t, map[string]any{"uptime": 0.0}, output.Result)
+	assert.NotNil(t, output.CompletedAt)
+
+	failed := tt.send(t, device.ID)
+	require.NoError(t, tt.answer(device.ID, `{"id": "`+failed.ID.String()+`", "status": "FAILED", "reason": "busy"}`))
+
+	output, err = tt.uc.GetCommand(ctx, usecase.GetDeviceCommandInput{
+		DeviceID: device.ID, CommandID: failed.ID, Wait: 0,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, string(commandstatus.Failed), output.Status)
+	assert.Equal(t, "busy", output.FailureReason)
+
+	expired := tt.send(t, device.ID)
+	tt.commandRepo.setExpiresAt(expired.ID, time.Now().Add(-time.Second))
+
+	other := tt.addDevice(devicestatus.Active)
+
+	tests := []struct {
+		name     string
+		deviceID uuid.UUID
+		response string
+		wantErr  error
+	}{
+		{
+			name:     "failure: command already answered",
+			deviceID: device.ID,
+			response: `{"id": "` + acked.ID.String() + `", "status": "FAILED"}`,
+			wantErr:  commandstatus.ErrInvalidTransition,
+		},
+		{
+			name:     "failure: command expired",
+			deviceID: device.ID,
+			response: `{"id": "` + expired.ID.String() + `", "status": "ACKED"}`,
+			wantErr:  entity.ErrDeviceCommandExpired,
+		},
+		{
+			name:     "failure: command of another device",
+			deviceID: other.ID,
+			response: `{"id": "` + failed.ID.String() + `", "status": "ACKED"}`,
+			wantErr:  entity.ErrDeviceCommandNotFound,
+		},
+		{
+			name:     "failure: status is not an answer",
+			deviceID: device.ID,
+			response: `{"id": "` + expired.ID.String() + `", "status": "SENT"}`,
+			wantErr:  usecase.ErrInvalidCommandResponse,
+		},
+		{
+			name:     "failure: response is not JSON",
+			deviceID: device.ID,
+			response: `ACKED`,
+			wantErr:  usecase.ErrInvalidCommandResponse,
+		},
+	}
+
+	for _, test := range tests {
+		require.ErrorIs(t, tt.answer(test.deviceID, test.response), test.wantErr, test.name)
+	}
+
+	assert.Equal(t, commandstatus.Acked, tt.commandRepo.status(acked.ID))
+	assert.Equal(t, commandstatus.Sent, tt.commandRepo.status(expired.ID))
+}
+
+// TestGetDeviceCommand tests the GetCommand method, with and without waiting.
+func TestGetDeviceCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("success: returns a pending command immediately without wait", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newDeviceCommandTest()
+		device := tt.addDevice(devicestatus.Active)
+		sent := tt.send(t, device.ID)
+
+		output, err := tt.uc.GetCommand(ctx, usecase.GetDeviceCommandInput{
+			DeviceID: device.ID, CommandID: sent.ID, Wait: 0,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, string(commandstatus.Sent), output.Status)
+	})
+
+	t.Run("success: waits until the command is answered", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newDeviceCommandTest()
+		device := tt.addDevice(devicestatus.Active)
+		sent := tt.send(t, device.ID)
+
+		time.AfterFunc(50*time.Millisecond, func() {
+			assert.NoError(t, tt.answer(device.ID, `{"id": "`+sent.ID.String()+`", "status": "ACKED"}`))
+		})
+
+		start := time.Now()
+		output, err := tt.uc.GetCommand(ctx, usecase.GetDeviceCommandInput{
+			DeviceID: device.ID, CommandID: sent.ID, Wait: 30 * time.Second,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, string(commandstatus.Acked), output.Status)
+		assert.Less(t, time.Since(start), 5*time.Second, "the answer wakes the request")
+	})
+
+	t.Run("success: waits until the command expires", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newDeviceCommandTest()
+		device := tt.addDevice(devicestatus.Active)
+		sent := tt.send(t, device.ID)
+		tt.commandRepo.setExpiresAt(sent.ID, time.Now().Add(100*time.Millisecond))
+
+		output, err := tt.uc.GetCommand(ctx, usecase.GetDeviceCommandInput{
+			DeviceID: device.ID, CommandID: sent.ID, Wait: 30 * time.Second,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, string(commandstatus.Expired), output.Status)
+	})
+
+	t.Run("success: returns the pending command when the client goes away", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newDeviceCommandTest()
+		device := tt.addDevice(devicestatus.Active)
+		sent := tt.send(t, device.ID)
+
+		waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		output, err := tt.uc.GetCommand(waitCtx, usecase.GetDeviceCommandInput{
+			DeviceID: device.ID, CommandID: sent.ID, Wait: 30 * time.Second,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, string(commandstatus.Sent), output.Status)
+	})
+
+	t.Run("failure: command not found", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newDeviceCommandTest()
+		device := tt.addDevice(devicestatus.Active)
+
+		_, err := tt.uc.GetCommand(ctx, usecase.GetDeviceCommandInput{
+			DeviceID: device.ID, CommandID: uuid.New(), Wait: time.Second,
+		})
+		require.ErrorIs(t, err, entity.ErrDeviceCommandNotFound)
+	})
+}
@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+// DeviceConnectionUsecase defines the interface for authorizing the connections of devices, e.g. to the MQTT broker.
+type DeviceConnectionUsecase interface {
+	// Authenticate checks that a device may connect with the presented client certificate.
+	// It returns ErrDeviceUnauthorized if the device may not connect.
+	Authenticate(ctx context.Context, input AuthenticateDeviceInput) error
+}
+
+// deviceConnectionUsecase is the implementation of the DeviceConnectionUsecase interface.
+type deviceConnectionUsecase struct {
+	deviceRepo repository.DeviceRepository
+	certRepo   repository.CertificateRepository
+	now        func() time.Time
+}
+
+// NewDeviceConnectionUsecase creates a new instance of deviceConnectionUsecase.
+//
+//nolint:ireturn
+func NewDeviceConnectionUsecase(
+	deviceRepo repository.DeviceRepository,
+	certRepo repository.CertificateRepository,
+) DeviceConnectionUsecase {
+	return &deviceConnectionUsecase{
+		deviceRepo: deviceRepo,
+		certRepo:   certRepo,
+		now:        time.Now,
+	}
+}
+
+// Authenticate checks that a device may connect with the presented client certificate.
+//
+// The chain of the certificate is verified by the TLS handshake. In addition, the certificate must have been
+// issued to the device and not be revoked, so that a revoked certificate is rejected immediately,
+// without waiting for the next CRL. The device itself must be ACTIVE.
+func (uc *deviceConnectionUsecase) Authenticate(ctx context.Context, input AuthenticateDeviceInput) error {
+	certificate, err := uc.certRepo.FindBySerialNumber(ctx, input.SerialNumber)
+	if err != nil {
+		if isNotFound(err, entity.ErrCertificateNotFound) {
+			return fmt.Errorf("%w: unknown certificate %d", ErrDeviceUnauthorized, input.SerialNumber)
+		}
+
+		return fmt.Errorf("%w: %w", ErrDBFindCertificate, err)
+	}
+
+	if certificate.DeviceID != input.DeviceID {
+		return fmt.Errorf("%w: certificate %d was not issued to the device", ErrDeviceUnauthorized, input.SerialNumber)
+	}
+
+	state := certificate.State(uc.now())
+	if state != entity.CertificateValid {
+		return fmt.Errorf("%w: certificate %d is %s", ErrDeviceUnauthorized, input.SerialNumber, state)
+	}
+
+	device, err := uc.deviceRepo.FindByID(ctx, input.DeviceID)
+	if err != nil {
+		if isNotFound(err, entity.ErrDeviceNotFound) {
+			return fmt.Errorf("%w: %w", ErrDeviceUnauthorized, entity.ErrDeviceNotFound)
+		}
+
+		return fmt.Errorf("%w: %w", ErrDBFindByID, err)
+	}
+
+	if device.Status != devicestatus.Active {
+		return fmt.Errorf("%w: device is %s", ErrDeviceUnauthorized, device.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,11 @@
+package usecase
+
+import "github.com/google/uuid"
+
+// AuthenticateDeviceInput is the identity a device presents with its client certificate.
+type AuthenticateDeviceInput struct {
+	// DeviceID is the device ID in the subject of the certificate.
+	DeviceID uuid.UUID
+	// SerialNumber is the serial number of the certificate.
+	SerialNumber int64
+}
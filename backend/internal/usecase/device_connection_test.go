@@ -0,0 +1,122 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/VO/revocationreason"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAuthenticateDevice tests the Authenticate method.
+func TestAuthenticateDevice(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	deviceRepo := NewFakeDeviceRepository()
+	certRepo := NewFakeCertificateRepository()
+
+	active := newIngestionTestDevice(deviceRepo, devicestatus.Active)
+	suspended := newIngestionTestDevice(deviceRepo, devicestatus.Suspended)
+
+	require.NoError(t, certRepo.Save(ctx, newTestCertificate(t, active.ID, 1001)))
+	require.NoError(t, certRepo.Save(ctx, newTestCertificate(t, suspended.ID, 1002)))
+
+	revoked := newTestCertificate(t, active.ID, 1003)
+	require.NoError(t, revoked.Revoke(revocationreason.KeyCompromise, time.Now()))
+	require.NoError(t, certRepo.Save(ctx, revoked))
+
+	expired := newTestCertificate(t, active.ID, 1004)
+	expired.ValidTo = time.Now().Add(-time.Minute)
+	require.NoError(t, certRepo.Save(ctx, expired))
+
+	unknownDeviceID := uuid.New()
+	require.NoError(t, certRepo.Save(ctx, newTestCertificate(t, unknownDeviceID, 1005)))
+
+	uc := usecase.NewDeviceConnectionUsecase(deviceRepo, certRepo)
+
+	tests := []struct {
+		name    string
+		desc    string
+		input   usecase.AuthenticateDeviceInput
+		wantErr error
+	}{
+		{
+			name:    "success: active device with a valid certificate",
+			desc:    "Verify that an ACTIVE device with its own valid certificate may connect.",
+			input:   usecase.AuthenticateDeviceInput{DeviceID: active.ID, SerialNumber: 1001},
+			wantErr: nil,
+		},
+		{
+			name:    "failure: unknown certificate",
+			desc:    "Verify that a certificate that the platform has not issued is rejected.",
+			input:   usecase.AuthenticateDeviceInput{DeviceID: active.ID, SerialNumber: 9999},
+			wantErr: usecase.ErrDeviceUnauthorized,
+		},
+		{
+			name:    "failure: certificate of another device",
+			desc:    "Verify that a certificate issued to another device is rejected.",
+			input:   usecase.AuthenticateDeviceInput{DeviceID: active.ID, SerialNumber: 1002},
+			wantErr: usecase.ErrDeviceUnauthorized,
+		},
+		{
+			name:    "failure: revoked certificate",
+			desc:    "Verify that a revoked certificate is rejected before the CRL is updated.",
+			input:   usecase.AuthenticateDeviceInput{DeviceID: active.ID, SerialNumber: 1003},
+			wantErr: usecase.ErrDeviceUnauthorized,
+		},
+		{
+			name:    "failure: expired certificate",
+			desc:    "Verify that an expired certificate is rejected.",
+			input:   usecase.AuthenticateDeviceInput{DeviceID: active.ID, SerialNumber: 1004},
+			wantErr: usecase.ErrDeviceUnauthorized,
+		},
+		{
+			name:    "failure: suspended device",
+			desc:    "Verify that a SUSPENDED device is rejected even with a valid certificate.",
+			input:   usecase.AuthenticateDeviceInput{DeviceID: suspended.ID, SerialNumber: 1002},
+			wantErr: usecase.ErrDeviceUnauthorized,
+		},
+		{
+			name:    "failure: deleted device",
+			desc:    "Verify that the certificate of a deleted device is rejected.",
+			input:   usecase.AuthenticateDeviceInput{DeviceID: unknownDeviceID, SerialNumber: 1005},
+			wantErr: usecase.ErrDeviceUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := uc.Authenticate(ctx, tt.input)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("failure: repository error", func(t *testing.T) {
+		t.Parallel()
+
+		failingRepo := NewFakeCertificateRepository()
+		failingRepo.FindErr = errors.New("db error")
+
+		err := usecase.NewDeviceConnectionUsecase(deviceRepo, failingRepo).
+			Authenticate(ctx, usecase.AuthenticateDeviceInput{DeviceID: active.ID, SerialNumber: 1001})
+		require.ErrorIs(t, err, usecase.ErrDBFindCertificate)
+		require.NotErrorIs(t, err, usecase.ErrDeviceUnauthorized)
+	})
+}
@@ -20,6 +20,67 @@ type UpdateDeviceInput struct {
 	ID       uuid.UUID
 	Name     *string        // Optional: if nil, the name will not be updated.
 	Metadata map[string]any // Optional: if nil, the metadata will not be updated.
+	// ExpectedVersion is the version the update is based on, e.g. from an If-Match header.
+	// Optional: if zero, the device is updated whatever its version.
+	ExpectedVersion int64 `json:"-"`
+}
+
+// PatchFormat is the format of a patch document.
+type PatchFormat string
+
+const (
+	// PatchFormatMerge is a JSON Merge Patch (RFC 7396).
+	PatchFormatMerge PatchFormat = "merge-patch"
+	// PatchFormatJSON is a JSON Patch (RFC 6902).
+	PatchFormatJSON PatchFormat = "json-patch"
+)
+
+// PatchDeviceInput is the input data for partially updating a Device.
+// The patch applies to the document {"name": ..., "metadata": {...}}.
+type PatchDeviceInput struct {
+	ID     uuid.UUID
+	Format PatchFormat
+	Patch  []byte
+	// ExpectedVersion is the version the patch is based on. Optional: if zero, any version is patched.
+	ExpectedVersion int64
+}
+
+// DeleteDeviceInput is the input data for deleting a Device.
+type DeleteDeviceInput struct {
+	ID uuid.UUID
+	// ExpectedVersion is the version the deletion is based on. Optional: if zero, any version is deleted.
+	ExpectedVersion int64
+}
+
+// ListDevicesInput is the input data for querying devices.
+// Zero-valued fields do not filter.
+type ListDevicesInput struct {
+	Status           string
+	HardwareIDPrefix string
+	NameContains     string
+	// Metadata are conditions on the metadata, all of which must hold, e.g. "location.building=Factory-A",
+	// "firmware.version>=2.4", "location" (the path exists) or "!location" (the path does not exist).
+	// The operators are =, !=, >, >=, < and <=.
+	Metadata []string
+	// Online, if true, only matches the CONNECTED devices, and if false, the DISCONNECTED and STALE ones.
+	Online *bool
+	// LastSeenBefore only matches the devices last seen before it, including those never seen.
+	LastSeenBefore *time.Time
+	// Sort is one of name, createdAt and updatedAt, prefixed with "-" for descending order.
+	// If empty, DefaultDeviceSort is used.
+	Sort string
+	// Cursor is the NextCursor of the previous page. It must be used with the same sort order.
+	Cursor string
+	Limit  int // Optional: if zero, DefaultDeviceLimit is used.
+}
+
+// DeviceListOutput is the output data for a page of devices.
+type DeviceListOutput struct {
+	Devices []*DeviceOutput `json:"devices"`
+	// NextCursor is the cursor of the next page. It is empty on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+	// TotalCount is the number of devices matching the filters, over all pages.
+	TotalCount int64 `json:"totalCount"`
 }
 
 // DeviceOutput is the output data for displaying Device information.
@@ -27,9 +88,17 @@ type DeviceOutput struct {
 	ID         uuid.UUID      `json:"id"`
 	HardwareID string         `json:"hardwareId"`
 	Name       string         `json:"name,omitempty"`
+	Status     string         `json:"status"`
 	Metadata   map[string]any `json:"metadata,omitempty"`
-	CreatedAt  time.Time      `json:"createdAt"`
-	UpdatedAt  time.Time      `json:"updatedAt"`
+	Version    int64          `json:"version"`
+	ParentID   *uuid.UUID     `json:"parentId,omitempty"`
+	// ConnectionState is CONNECTED, DISCONNECTED or STALE, and Online is whether it is CONNECTED.
+	ConnectionState string     `json:"connectionState"`
+	Online          bool       `json:"online"`
+	LastSeenAt      *time.Time `json:"lastSeenAt,omitempty"`
+	LastIP          string     `json:"lastIp,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
 }
 
 // NewDeviceOutput creates a new DeviceOutput from an entity.
@@ -38,8 +107,17 @@ func NewDeviceOutput(device *entity.Device) *DeviceOutput {
 		ID:         device.ID,
 		HardwareID: device.HardwareID,
 		Name:       device.Name,
+		Status:     device.Status.String(),
 		Metadata:   device.Metadata,
-		CreatedAt:  device.CreatedAt,
-		UpdatedAt:  device.UpdatedAt,
+		Version:    device.Version,
+		ParentID:   device.ParentID,
+
+		ConnectionState: device.ConnectionState.String(),
+		Online:          device.ConnectionState.IsOnline(),
+		LastSeenAt:      device.LastSeenAt,
+		LastIP:          device.LastIP,
+
+		CreatedAt: device.CreatedAt,
+		UpdatedAt: device.UpdatedAt,
 	}
 }
@@ -0,0 +1,260 @@
+package usecase
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+// exportColumns are the columns of a CSV export file, before the metadata.<path> columns.
+var exportColumns = []string{ //nolint:gochecknoglobals
+	"id", importColumnHardwareID, importColumnName, "status", "version", "created_at", "updated_at",
+}
+
+// DeviceExportUsecase defines the interface for exporting devices in bulk.
+type DeviceExportUsecase interface {
+	// ExportDevices writes the devices matching the filters to w, in the format of the input.
+	// The devices are streamed from the repository instead of being loaded all at once.
+	// The input is validated before anything is written: if the error wraps ErrInvalidDeviceQuery,
+	// w has not been written to.
+	ExportDevices(ctx context.Context, input ExportDevicesInput, w io.Writer) error
+}
+
+// deviceExportUsecase is the implementation of the DeviceExportUsecase interface.
+type deviceExportUsecase struct {
+	deviceRepo repository.DeviceRepository
+	transactor repository.Transactor
+}
+
+// NewDeviceExportUsecase creates a new instance of deviceExportUsecase.
+//
+//nolint:ireturn
+func NewDeviceExportUsecase(
+	deviceRepo repository.DeviceRepository,
+	transactor repository.Transactor,
+) DeviceExportUsecase {
+	return &deviceExportUsecase{deviceRepo: deviceRepo, transactor: transactor}
+}
+
+// ExportDevices writes the devices matching the filters to w, in the format of the input.
+func (uc *deviceExportUsecase) ExportDevices(ctx context.Context, input ExportDevicesInput, w io.Writer) error {
+	filter, err := newDeviceFilter(input.Status, input.HardwareIDPrefix, input.NameContains, input.Metadata)
+	if err != nil {
+		return err
+	}
+
+	filter.Online, filter.LastSeenBefore = input.Online, input.LastSeenBefore
+
+	sortBy, descending, err := parseDeviceSort(input.Sort)
+	if err != nil {
+		return err
+	}
+
+	// Without a cursor and a limit, the query matches all the devices.
+	query := repository.DeviceQuery{Filter: filter, SortBy: sortBy, Descending: descending, After: nil, Limit: 0}
+	output := &exportWriter{w: w, err: nil}
+
+	var export func(ctx context.Context) error
+
+	switch input.Format {
+	case ExportFormatCSV:
+		export = func(ctx context.Context) error { return uc.exportCSV(ctx, query, output) }
+	case ExportFormatNDJSON:
+		export = func(ctx context.Context) error { return uc.exportNDJSON(ctx, query, output) }
+	case ExportFormatJSON:
+		export = func(ctx context.Context) error { return uc.exportJSON(ctx, query, output) }
+	default:
+		return fmt.Errorf("%w: unknown export format %q", ErrInvalidDeviceQuery, input.Format)
+	}
+
+	// The CSV export reads the devices twice, so both reads must see the same devices.
+	return uc.transactor.WithinSnapshot(ctx, export)
+}
+
+// exportCSV writes the devices as CSV, with their metadata flattened into metadata.<path> columns.
+func (uc *deviceExportUsecase) exportCSV(ctx context.Context, query repository.DeviceQuery, w *exportWriter) error {
+	// The metadata columns are only known once all the devices have been read,
+	// so they are collected by a first pass that does not keep the devices.
+	metadataColumns := make(map[string]bool)
+
+	err := uc.findEach(ctx, query, func(device *entity.Device) error {
+		for column := range flattenMetadata(device.Metadata) {
+			metadataColumns[column] = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	columns := slices.Sorted(maps.Keys(metadataColumns))
+	writer := csv.NewWriter(w)
+
+	err = writer.Write(slices.Concat(exportColumns, columns))
+	if err != nil {
+		return w.check(err)
+	}
+
+	err = uc.findEach(ctx, query, func(device *entity.Device) error {
+		cells := flattenMetadata(device.Metadata)
+		record := make([]string, 0, len(exportColumns)+len(columns))
+		record = append(record,
+			device.ID.String(),
+			device.HardwareID,
+			device.Name,
+			device.Status.String(),
+			strconv.FormatInt(device.Version, 10),
+			device.CreatedAt.Format(time.RFC3339Nano),
+			device.UpdatedAt.Format(time.RFC3339Nano),
+		)
+
+		for _, column := range columns {
+			record = append(record, cells[column])
+		}
+
+		return w.check(writer.Write(record))
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+
+	return w.check(writer.Error())
+}
+
+// exportNDJSON writes the devices as one JSON object per line.
+func (uc *deviceExportUsecase) exportNDJSON(ctx context.Context, query repository.DeviceQuery, w *exportWriter) error {
+	encoder := json.NewEncoder(w)
+
+	return uc.findEach(ctx, query, func(device *entity.Device) error {
+		return w.check(encoder.Encode(NewDeviceOutput(device)))
+	})
+}
+
+// exportJSON writes the devices as a JSON object {"devices": [...]}, one element at a time.
+func (uc *deviceExportUsecase) exportJSON(ctx context.Context, query repository.DeviceQuery, w *exportWriter) error {
+	separator := ""
+
+	// A write error is kept by w and checked once per device.
+	_, _ = io.WriteString(w, `{"devices":[`)
+
+	err := uc.findEach(ctx, query, func(device *entity.Device) error {
+		data, err := json.Marshal(NewDeviceOutput(device))
+		if err != nil {
+			return fmt.Errorf("failed to marshal device %s: %w", device.ID, err)
+		}
+
+		_, _ = io.WriteString(w, separator)
+		_, err = w.Write(data)
+		separator = ","
+
+		return w.check(err)
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]}\n")
+
+	return w.check(err)
+}
+
+// findEach calls fn with each of the devices matching the query.
+// The errors of fn are returned as they are, and those of the repository wrap ErrDBFindAll.
+func (uc *deviceExportUsecase) findEach(
+	ctx context.Context,
+	query repository.DeviceQuery,
+	fn func(device *entity.Device) error,
+) error {
+	var fnErr error
+
+	err := uc.deviceRepo.FindEach(ctx, query, func(device *entity.Device) error {
+		fnErr = fn(device)
+
+		return fnErr
+	})
+	if err != nil && fnErr == nil {
+		return fmt.Errorf("%w: %w", ErrDBFindAll, err)
+	}
+
+	return err
+}
+
+// exportWriter is the output of an export. It keeps the first write error,
+// so that the writes made after it fail without reaching the underlying writer.
+type exportWriter struct {
+	w   io.Writer
+	err error
+}
+
+// Write writes to the underlying writer, unless a previous write has failed.
+func (e *exportWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+
+	n, err := e.w.Write(p)
+	if err != nil {
+		e.err = fmt.Errorf("%w: %w", ErrDeviceExportWrite, err)
+	}
+
+	return n, e.err
+}
+
+// check returns the first write error if there has been one, since the error of a buffering writer
+// such as csv.Writer may not be the one returned by Write; otherwise it returns err.
+func (e *exportWriter) check(err error) error {
+	if e.err != nil {
+		return e.err
+	}
+
+	return err
+}
+
+// flattenMetadata returns the metadata.<path> cells of a CSV export for the metadata of a device.
+// Objects are flattened into one column per member, and other values, including arrays, fill a single cell.
+func flattenMetadata(metadata map[string]any) map[string]string {
+	cells := make(map[string]string)
+	flattenMetadataObject(cells, importMetadataColumnPrefix, metadata)
+
+	return cells
+}
+
+// flattenMetadataObject adds the cells of the members of an object, whose column names start with prefix.
+func flattenMetadataObject(cells map[string]string, prefix string, object map[string]any) {
+	for key, value := range object {
+		child, ok := value.(map[string]any)
+		if ok && len(child) > 0 {
+			flattenMetadataObject(cells, prefix+key+".", child)
+
+			continue
+		}
+
+		cells[prefix+key] = formatExportValue(value)
+	}
+}
+
+// formatExportValue writes a metadata value into a cell that parseImportValue reads back as the same value:
+// a string as it is, unless it would be read as another JSON value or skipped as an empty cell,
+// and anything else as JSON.
+func formatExportValue(value any) string {
+	text, ok := value.(string)
+	if ok && text != "" && !json.Valid([]byte(text)) {
+		return text
+	}
+
+	data, _ := json.Marshal(value) //nolint:errchkjson // The value was decoded from JSON.
+
+	return string(data)
+}
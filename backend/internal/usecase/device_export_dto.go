@@ -0,0 +1,32 @@
+package usecase
+
+import "time"
+
+// ExportFormat is the format of a device export.
+type ExportFormat string
+
+const (
+	// ExportFormatCSV is a CSV file with a header row. The columns are id, hardware_id, name, status, version,
+	// created_at, updated_at and one metadata.<path> column per value found in the metadata of the devices,
+	// as read by the import.
+	ExportFormatCSV ExportFormat = "csv"
+	// ExportFormatNDJSON is a file with one device per line, in the format of the device API.
+	ExportFormatNDJSON ExportFormat = "ndjson"
+	// ExportFormatJSON is a JSON object {"devices": [...]}, in the format of the device API.
+	ExportFormatJSON ExportFormat = "json"
+)
+
+// ExportDevicesInput is the input data for exporting devices.
+// The filters and the sort order are those of ListDevicesInput; zero-valued fields do not filter.
+type ExportDevicesInput struct {
+	Format           ExportFormat
+	Status           string
+	HardwareIDPrefix string
+	NameContains     string
+	Metadata         []string
+	Online           *bool
+	LastSeenBefore   *time.Time
+	// Sort is one of name, createdAt and updatedAt, prefixed with "-" for descending order.
+	// If empty, DefaultDeviceSort is used.
+	Sort string
+}
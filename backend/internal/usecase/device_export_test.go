@@ -0,0 +1,261 @@
+package usecase_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDeviceExportTest creates a DeviceExportUsecase with three devices, created one minute apart.
+func newDeviceExportTest(t *testing.T) (usecase.DeviceExportUsecase, *FakeDeviceRepository, []*entity.Device) {
+	t.Helper()
+
+	createdAt := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	deviceRepo := NewFakeDeviceRepository()
+	devices := []*entity.Device{
+		{
+			ID:         uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+			HardwareID: "hw-001",
+			Name:       "Sensor, 1",
+			Status:     devicestatus.Active,
+			Metadata: entity.JSONBMap{
+				"location": map[string]any{"building": "Factory-A", "floor": 2.0},
+				"tags":     []any{"a", "b"},
+				"serial":   "42",
+				"note":     "",
+				"empty":    map[string]any{},
+			},
+			Version:   3,
+			ParentID:  nil,
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt.Add(time.Hour),
+		},
+		{
+			ID:         uuid.MustParse("00000000-0000-0000-0000-000000000002"),
+			HardwareID: "hw-002",
+			Name:       "",
+			Status:     devicestatus.Unregistered,
+			Metadata:   entity.JSONBMap{"location": map[string]any{"zone": "shipping"}, "active": true},
+			Version:    1,
+			ParentID:   nil,
+			CreatedAt:  createdAt.Add(time.Minute),
+			UpdatedAt:  createdAt.Add(time.Minute),
+		},
+		{
+			ID:         uuid.MustParse("00000000-0000-0000-0000-000000000003"),
+			HardwareID: "gw-001",
+			Name:       "Gateway",
+			Status:     devicestatus.Active,
+			Metadata:   nil,
+			Version:    1,
+			ParentID:   nil,
+			CreatedAt:  createdAt.Add(2 * time.Minute),
+			UpdatedAt:  createdAt.Add(2 * time.Minute),
+		},
+	}
+
+	for _, device := range devices {
+		deviceRepo.devices[device.ID] = device
+	}
+
+	return usecase.NewDeviceExportUsecase(deviceRepo, FakeTransactor{}), deviceRepo, devices
+}
+
+// TestExportDevicesCSV tests the ExportDevices method with the CSV format.
+func TestExportDevicesCSV(t *testing.T) {
+	t.Parallel()
+
+	uc, _, _ := newDeviceExportTest(t)
+
+	var output bytes.Buffer
+
+	err := uc.ExportDevices(context.Background(), usecase.ExportDevicesInput{ //nolint:exhaustruct
+		Format:           usecase.ExportFormatCSV,
+		HardwareIDPrefix: "hw-",
+	}, &output)
+	require.NoError(t, err)
+
+	// The metadata is flattened into sorted metadata.<path> columns found in any of the devices.
+	// Strings that would be read back as another value are quoted, and other values are written as JSON.
+	want := "id,hardware_id,name,status,version,created_at,updated_at," +
+		"metadata.active,metadata.empty,metadata.location.building,metadata.location.floor,metadata.location.zone," +
+		"metadata.note,metadata.serial,metadata.tags\n" +
+		`00000000-0000-0000-0000-000000000001,hw-001,"Sensor, 1",ACTIVE,3,2026-04-01T09:00:00Z,2026-04-01T10:00:00Z,` +
+		`,{},Factory-A,2,,"""""","""42""","[""a"",""b""]"` + "\n" +
+		"00000000-0000-0000-0000-000000000002,hw-002,,UNREGISTERED,1,2026-04-01T09:01:00Z,2026-04-01T09:01:00Z," +
+		"true,,,,shipping,,,\n"
+	assert.Equal(t, want, output.String())
+}
+
+// TestExportDevicesJSON tests the ExportDevices method with the NDJSON and JSON formats.
+func TestExportDevicesJSON(t *testing.T) {
+	t.Parallel()
+
+	uc, _, devices := newDeviceExportTest(t)
+	ctx := context.Background()
+
+	t.Run("ndjson", func(t *testing.T) {
+		t.Parallel()
+
+		var output bytes.Buffer
+
+		err := uc.ExportDevices(ctx, usecase.ExportDevicesInput{ //nolint:exhaustruct
+			Format: usecase.ExportFormatNDJSON,
+			Sort:   "-createdAt",
+		}, &output)
+		require.NoError(t, err)
+
+		var hardwareIDs []string
+
+		scanner := bufio.NewScanner(&output)
+		for scanner.Scan() {
+			var device usecase.DeviceOutput
+
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &device))
+
+			hardwareIDs = append(hardwareIDs, device.HardwareID)
+		}
+
+		assert.Equal(t, []string{"gw-001", "hw-002", "hw-001"}, hardwareIDs)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		var output bytes.Buffer
+
+		err := uc.ExportDevices(ctx, usecase.ExportDevicesInput{ //nolint:exhaustruct
+			Format: usecase.ExportFormatJSON,
+		}, &output)
+		require.NoError(t, err)
+
+		var decoded struct {
+			Devices []*usecase.DeviceOutput `json:"devices"`
+		}
+
+		require.NoError(t, json.Unmarshal(output.Bytes(), &decoded))
+		require.Len(t, decoded.Devices, 3)
+		assert.Equal(t, usecase.NewDeviceOutput(devices[0]).ID, decoded.Devices[0].ID)
+		assert.Equal(t, map[string]any{"zone": "shipping"}, decoded.Devices[1].Metadata["location"])
+	})
+
+	t.Run("json without devices", func(t *testing.T) {
+		t.Parallel()
+
+		var output bytes.Buffer
+
+		err := uc.ExportDevices(ctx, usecase.ExportDevicesInput{ //nolint:exhaustruct
+			Format:       usecase.ExportFormatJSON,
+			NameContains: "nothing",
+		}, &output)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"devices": []}`, output.String())
+	})
+
+	t.Run("metadata filter", func(t *testing.T) {
+		t.Parallel()
+
+		// This subtest has its own repository, so that the other subtests do not change its last filter.
+		uc, deviceRepo, _ := newDeviceExportTest(t)
+
+		var output bytes.Buffer
+
+		err := uc.ExportDevices(ctx, usecase.ExportDevicesInput{ //nolint:exhaustruct
+			Format:   usecase.ExportFormatNDJSON,
+			Status:   "ACTIVE",
+			Metadata: []string{"location.building=Factory-A"},
+		}, &output)
+		require.NoError(t, err)
+
+		// The fake repository does not evaluate metadata conditions, so the filter is checked instead.
+		filter := deviceRepo.LastFilter()
+		assert.Equal(t, devicestatus.Active, filter.Status)
+		assert.Equal(t, []repository.MetadataCondition{{
+			Path:     []string{"location", "building"},
+			Operator: repository.MetadataEquals,
+			Value:    "Factory-A",
+		}}, filter.Metadata)
+	})
+}
+
+// failingWriter is an io.Writer that always fails, like the connection of a client that went away.
+type failingWriter struct{}
+
+// Write fails.
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errFailingWriter
+}
+
+// errFailingWriter is the error of failingWriter.
+var errFailingWriter = errors.New("connection reset")
+
+// TestExportDevicesErrors tests the errors of the ExportDevices method.
+func TestExportDevicesErrors(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("invalid input", func(t *testing.T) {
+		t.Parallel()
+
+		uc, _, _ := newDeviceExportTest(t)
+
+		for _, input := range []usecase.ExportDevicesInput{ //nolint:exhaustruct
+			{Format: "xml"},
+			{Format: usecase.ExportFormatCSV, Status: "BROKEN"},
+			{Format: usecase.ExportFormatJSON, Sort: "version"},
+			{Format: usecase.ExportFormatJSON, Metadata: []string{"a..b"}},
+		} {
+			var output bytes.Buffer
+
+			err := uc.ExportDevices(ctx, input, &output)
+			require.ErrorIs(t, err, usecase.ErrInvalidDeviceQuery)
+			assert.Empty(t, output.String(), "nothing should be written for an invalid input")
+		}
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		t.Parallel()
+
+		uc, deviceRepo, _ := newDeviceExportTest(t)
+		deviceRepo.QueryErr = errors.New("connection lost")
+
+		var output bytes.Buffer
+
+		input := usecase.ExportDevicesInput{Format: usecase.ExportFormatCSV} //nolint:exhaustruct
+
+		err := uc.ExportDevices(ctx, input, &output)
+		require.ErrorIs(t, err, usecase.ErrDBFindAll)
+		assert.Empty(t, output.String())
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		t.Parallel()
+
+		uc, _, _ := newDeviceExportTest(t)
+
+		for _, format := range []usecase.ExportFormat{
+			usecase.ExportFormatCSV, usecase.ExportFormatNDJSON, usecase.ExportFormatJSON,
+		} {
+			input := usecase.ExportDevicesInput{Format: format} //nolint:exhaustruct
+
+			err := uc.ExportDevices(ctx, input, failingWriter{})
+			require.ErrorIs(t, err, usecase.ErrDeviceExportWrite, "format %s", format)
+			require.ErrorIs(t, err, errFailingWriter, "format %s", format)
+			assert.NotErrorIs(t, err, usecase.ErrDBFindAll, "format %s", format)
+		}
+	})
+}
@@ -0,0 +1,552 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/VO/revocationreason"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+// DeviceGroupUsecase defines the interface for managing device groups and operating on their members.
+type DeviceGroupUsecase interface {
+	// CreateDeviceGroup creates a new device group.
+	CreateDeviceGroup(ctx context.Context, input CreateDeviceGroupInput) (*DeviceGroupOutput, error)
+	// GetDeviceGroup retrieves a device group by its ID.
+	GetDeviceGroup(ctx context.Context, id uuid.UUID) (*DeviceGroupOutput, error)
+	// ListDeviceGroups retrieves all the device groups, sorted by name.
+	ListDeviceGroups(ctx context.Context) ([]*DeviceGroupOutput, error)
+	// UpdateDeviceGroup updates the name, description or rule of a device group.
+	UpdateDeviceGroup(ctx context.Context, input UpdateDeviceGroupInput) (*DeviceGroupOutput, error)
+	// DeleteDeviceGroup deletes a device group. Its members are not deleted.
+	DeleteDeviceGroup(ctx context.Context, id uuid.UUID) error
+	// AddDeviceGroupMembers adds devices to a device group.
+	AddDeviceGroupMembers(ctx context.Context, input AddDeviceGroupMembersInput) (*DeviceGroupMembersOutput, error)
+	// RemoveDeviceGroupMember removes a device that has been added to a device group.
+	// A device that is a member through the rule of the group stays a member.
+	RemoveDeviceGroupMember(ctx context.Context, input RemoveDeviceGroupMemberInput) error
+	// ListDeviceGroupDevices retrieves a page of the members of a device group matching the filters.
+	ListDeviceGroupDevices(ctx context.Context, groupID uuid.UUID, input ListDevicesInput) (*DeviceListOutput, error)
+	// ApplyDeviceGroupAction applies an action to each of the members of a device group,
+	// and reports how many it succeeded, skipped or failed for.
+	ApplyDeviceGroupAction(ctx context.Context, input ApplyDeviceGroupActionInput) (*DeviceGroupActionOutput, error)
+}
+
+// deviceGroupUsecase is the implementation of the DeviceGroupUsecase interface.
+type deviceGroupUsecase struct {
+	groupRepo    repository.DeviceGroupRepository
+	deviceRepo   repository.DeviceRepository
+	devices      DeviceUsecase
+	certificates CertificateUsecase
+	auditLogger  repository.AuditLogger
+	transactor   repository.Transactor
+}
+
+// NewDeviceGroupUsecase creates a new instance of deviceGroupUsecase.
+// The actions on the members go through devices and certificates, so that each change is audited as usual.
+//
+//nolint:ireturn
+func NewDeviceGroupUsecase(
+	groupRepo repository.DeviceGroupRepository,
+	deviceRepo repository.DeviceRepository,
+	devices DeviceUsecase,
+	certificates CertificateUsecase,
+	auditLogger repository.AuditLogger,
+	transactor repository.Transactor,
+) DeviceGroupUsecase {
+	return &deviceGroupUsecase{
+		groupRepo:    groupRepo,
+		deviceRepo:   deviceRepo,
+		devices:      devices,
+		certificates: certificates,
+		auditLogger:  auditLogger,
+		transactor:   transactor,
+	}
+}
+
+// CreateDeviceGroup creates a new device group.
+// If the name is taken by another group, the error wraps entity.ErrDeviceGroupNameTaken.
+func (uc *deviceGroupUsecase) CreateDeviceGroup(
+	ctx context.Context,
+	input CreateDeviceGroupInput,
+) (*DeviceGroupOutput, error) {
+	_, err := parseDeviceGroupRule(input.Rule)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := entity.NewDeviceGroup(input.Name, input.Description, input.Rule)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.saveWithAudit(ctx, group, entity.AuditDeviceGroupCreate, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDeviceGroupOutput(group), nil
+}
+
+// GetDeviceGroup retrieves a device group by its ID.
+func (uc *deviceGroupUsecase) GetDeviceGroup(ctx context.Context, id uuid.UUID) (*DeviceGroupOutput, error) {
+	group, err := uc.findGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDeviceGroupOutput(group), nil
+}
+
+// ListDeviceGroups retrieves all the device groups, sorted by name.
+func (uc *deviceGroupUsecase) ListDeviceGroups(ctx context.Context) ([]*DeviceGroupOutput, error) {
+	groups, err := uc.groupRepo.FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDBFindAll, err)
+	}
+
+	outputs := make([]*DeviceGroupOutput, 0, len(groups))
+	for _, group := range groups {
+		outputs = append(outputs, NewDeviceGroupOutput(group))
+	}
+
+	return outputs, nil
+}
+
+// UpdateDeviceGroup updates the name, description or rule of a device group.
+func (uc *deviceGroupUsecase) UpdateDeviceGroup(
+	ctx context.Context,
+	input UpdateDeviceGroupInput,
+) (*DeviceGroupOutput, error) {
+	group, err := uc.findGroup(ctx, input.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := deviceGroupSnapshot(group)
+
+	if input.Name != nil {
+		err = group.Rename(*input.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if input.Description != nil {
+		group.Description = *input.Description
+	}
+
+	// input.Rule is a slice, so check for nil: an empty rule is a change.
+	if input.Rule != nil {
+		_, err = parseDeviceGroupRule(input.Rule)
+		if err != nil {
+			return nil, err
+		}
+
+		group.Rule = entity.JSONBStrings(input.Rule)
+	}
+
+	err = uc.saveWithAudit(ctx, group, entity.AuditDeviceGroupUpdate, before)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDeviceGroupOutput(group), nil
+}
+
+// DeleteDeviceGroup deletes a device group.
+func (uc *deviceGroupUsecase) DeleteDeviceGroup(ctx context.Context, id uuid.UUID) error {
+	// The group is loaded first, so that the audit log entry records what was deleted.
+	group, err := uc.findGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := uc.groupRepo.Delete(ctx, group.ID)
+		if err != nil {
+			if errors.Is(err, entity.ErrDeviceGroupNotFound) {
+				return err
+			}
+
+			return fmt.Errorf("%w: %w", ErrDBDelete, err)
+		}
+
+		return recordAudit(ctx, uc.auditLogger, auditEvent{
+			action:   entity.AuditDeviceGroupDelete,
+			deviceID: uuid.Nil,
+			target:   deviceGroupTarget(group.ID),
+			before:   deviceGroupSnapshot(group),
+			after:    nil,
+		})
+	})
+}
+
+// AddDeviceGroupMembers adds devices to a device group.
+// If any of the devices does not exist, none is added and the error wraps entity.ErrDeviceNotFound.
+func (uc *deviceGroupUsecase) AddDeviceGroupMembers(
+	ctx context.Context,
+	input AddDeviceGroupMembersInput,
+) (*DeviceGroupMembersOutput, error) {
+	group, err := uc.findGroup(ctx, input.GroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &DeviceGroupMembersOutput{Added: nil}
+
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		added, err := uc.groupRepo.AddMembers(ctx, group.ID, input.DeviceIDs)
+		if err != nil {
+			if errors.Is(err, entity.ErrDeviceNotFound) || errors.Is(err, entity.ErrDeviceGroupNotFound) {
+				return err
+			}
+
+			return fmt.Errorf("%w: %w", ErrDBDeviceGroupMembers, err)
+		}
+
+		events := make([]auditEvent, 0, len(added))
+		for _, deviceID := range added {
+			events = append(events, auditEvent{
+				action:   entity.AuditDeviceGroupMemberAdd,
+				deviceID: deviceID,
+				target:   deviceGroupTarget(group.ID),
+				before:   nil,
+				after:    map[string]any{"deviceId": deviceID},
+			})
+		}
+
+		output.Added = added
+
+		return recordAudit(ctx, uc.auditLogger, events...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// RemoveDeviceGroupMember removes a device that has been added to a device group.
+func (uc *deviceGroupUsecase) RemoveDeviceGroupMember(ctx context.Context, input RemoveDeviceGroupMemberInput) error {
+	group, err := uc.findGroup(ctx, input.GroupID)
+	if err != nil {
+		return err
+	}
+
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := uc.groupRepo.RemoveMember(ctx, group.ID, input.DeviceID)
+		if err != nil {
+			if errors.Is(err, entity.ErrDeviceGroupMemberNotFound) {
+				return err
+			}
+
+			return fmt.Errorf("%w: %w", ErrDBDeviceGroupMembers, err)
+		}
+
+		return recordAudit(ctx, uc.auditLogger, auditEvent{
+			action:   entity.AuditDeviceGroupMemberRemove,
+			deviceID: input.DeviceID,
+			target:   deviceGroupTarget(group.ID),
+			before:   map[string]any{"deviceId": input.DeviceID},
+			after:    nil,
+		})
+	})
+}
+
+// ListDeviceGroupDevices retrieves a page of the members of a device group matching the filters.
+func (uc *deviceGroupUsecase) ListDeviceGroupDevices(
+	ctx context.Context,
+	groupID uuid.UUID,
+	input ListDevicesInput,
+) (*DeviceListOutput, error) {
+	query, err := newDeviceQuery(input)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := uc.findGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	query.Filter.Group, err = groupMembership(group)
+	if err != nil {
+		return nil, err
+	}
+
+	return listDevicePage(ctx, uc.deviceRepo, query)
+}
+
+// ApplyDeviceGroupAction applies an action to each of the members of a device group.
+// The parameters of the action are checked before any member is changed; if they are invalid,
+// the error wraps ErrInvalidDeviceGroupAction. A failure for one member does not stop the others.
+func (uc *deviceGroupUsecase) ApplyDeviceGroupAction(
+	ctx context.Context,
+	input ApplyDeviceGroupActionInput,
+) (*DeviceGroupActionOutput, error) {
+	apply, err := uc.groupAction(input)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := uc.findGroup(ctx, input.GroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	memberIDs, err := uc.memberIDs(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &DeviceGroupActionOutput{
+		Action:    input.Action,
+		Matched:   len(memberIDs),
+		Succeeded: 0,
+		Skipped:   0,
+		Failed:    0,
+		Results:   []*DeviceGroupActionResult{},
+	}
+
+	for _, result := range apply(ctx, memberIDs) {
+		switch result.Status {
+		case DeviceGroupActionSucceeded:
+			output.Succeeded++
+
+			continue
+		case DeviceGroupActionSkipped:
+			output.Skipped++
+		case DeviceGroupActionFailed:
+			output.Failed++
+		}
+
+		output.Results = append(output.Results, result)
+	}
+
+	return output, nil
+}
+
+// deviceGroupActionFunc applies an action to the members of a device group and returns the outcome of each.
+type deviceGroupActionFunc func(ctx context.Context, deviceIDs []uuid.UUID) []*DeviceGroupActionResult
+
+// groupAction validates the parameters of an action and returns the function applying it.
+func (uc *deviceGroupUsecase) groupAction(input ApplyDeviceGroupActionInput) (deviceGroupActionFunc, error) {
+	switch input.Action {
+	case DeviceGroupActionSuspend:
+		return uc.suspendMembers, nil
+	case DeviceGroupActionRevokeCertificates:
+		// The reason is parsed again by the certificates, but an invalid one must not be found out per member.
+		_, err := revocationreason.Parse(input.Reason)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidDeviceGroupAction, err)
+		}
+
+		return func(ctx context.Context, deviceIDs []uuid.UUID) []*DeviceGroupActionResult {
+			return uc.revokeMemberCertificates(ctx, deviceIDs, input.Reason)
+		}, nil
+	case DeviceGroupActionPatchMetadata:
+		var metadata map[string]any
+
+		err := json.Unmarshal(input.Metadata, &metadata)
+		if err != nil || metadata == nil {
+			return nil, fmt.Errorf("%w: metadata must be a JSON object", ErrInvalidDeviceGroupAction)
+		}
+
+		// The metadata patch is nested into a merge patch of the device, which leaves the name as it is.
+		patch, err := json.Marshal(map[string]json.RawMessage{"metadata": input.Metadata})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidDeviceGroupAction, err)
+		}
+
+		return func(ctx context.Context, deviceIDs []uuid.UUID) []*DeviceGroupActionResult {
+			return uc.patchMembers(ctx, deviceIDs, patch)
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown action %q", ErrInvalidDeviceGroupAction, input.Action)
+	}
+}
+
+// suspendMembers suspends each of the devices. Devices that cannot be suspended, such as devices that are
+// already suspended or have been revoked, and devices deleted meanwhile are skipped.
+func (uc *deviceGroupUsecase) suspendMembers(ctx context.Context, deviceIDs []uuid.UUID) []*DeviceGroupActionResult {
+	results := make([]*DeviceGroupActionResult, 0, len(deviceIDs))
+
+	for _, deviceID := range deviceIDs {
+		_, err := uc.devices.SuspendDevice(ctx, deviceID)
+		results = append(results, newDeviceGroupActionResult(deviceID, err,
+			errors.Is(err, devicestatus.ErrInvalidTransition) || isNotFound(err, entity.ErrDeviceNotFound)))
+	}
+
+	return results
+}
+
+// patchMembers applies a merge patch to each of the devices. Devices deleted meanwhile are skipped.
+func (uc *deviceGroupUsecase) patchMembers(
+	ctx context.Context,
+	deviceIDs []uuid.UUID,
+	patch []byte,
+) []*DeviceGroupActionResult {
+	results := make([]*DeviceGroupActionResult, 0, len(deviceIDs))
+
+	for _, deviceID := range deviceIDs {
+		_, err := uc.devices.PatchDevice(ctx, PatchDeviceInput{
+			ID:              deviceID,
+			Format:          PatchFormatMerge,
+			Patch:           patch,
+			ExpectedVersion: 0,
+		})
+		results = append(results, newDeviceGroupActionResult(deviceID, err, isNotFound(err, entity.ErrDeviceNotFound)))
+	}
+
+	return results
+}
+
+// revokeMemberCertificates revokes the certificates of the devices at once, so that the CRL is regenerated
+// only once. Devices without certificates to revoke are skipped.
+func (uc *deviceGroupUsecase) revokeMemberCertificates(
+	ctx context.Context,
+	deviceIDs []uuid.UUID,
+	reason string,
+) []*DeviceGroupActionResult {
+	results := make([]*DeviceGroupActionResult, 0, len(deviceIDs))
+
+	outcomes, err := uc.certificates.RevokeDeviceCertificates(ctx, RevokeDeviceCertificatesInput{
+		DeviceIDs: deviceIDs,
+		Reason:    reason,
+	})
+	if err != nil {
+		// The reason has been validated, so this is not expected; every device is reported as failed.
+		for _, deviceID := range deviceIDs {
+			results = append(results, newDeviceGroupActionResult(deviceID, err, false))
+		}
+
+		return results
+	}
+
+	for _, outcome := range outcomes {
+		result := newDeviceGroupActionResult(outcome.DeviceID, outcome.Err, false)
+		if outcome.Err == nil && len(outcome.SerialNumbers) == 0 {
+			result.Status = DeviceGroupActionSkipped
+			result.Error = "device has no certificates to revoke"
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// newDeviceGroupActionResult returns the outcome of an action on a device from its error:
+// it succeeded without an error, and was skipped or failed otherwise.
+func newDeviceGroupActionResult(deviceID uuid.UUID, err error, skipped bool) *DeviceGroupActionResult {
+	result := &DeviceGroupActionResult{DeviceID: deviceID, Status: DeviceGroupActionSucceeded, Error: ""}
+
+	switch {
+	case err == nil:
+	case skipped:
+		result.Status, result.Error = DeviceGroupActionSkipped, err.Error()
+	default:
+		result.Status, result.Error = DeviceGroupActionFailed, err.Error()
+	}
+
+	return result
+}
+
+// memberIDs returns the IDs of the members of a device group, in the order they were created.
+// They are collected before any of them is changed, because the repository cannot be used while it reads them.
+func (uc *deviceGroupUsecase) memberIDs(ctx context.Context, group *entity.DeviceGroup) ([]uuid.UUID, error) {
+	membership, err := groupMembership(group)
+	if err != nil {
+		return nil, err
+	}
+
+	query := repository.DeviceQuery{
+		Filter:     repository.DeviceFilter{Group: membership}, //nolint:exhaustruct
+		SortBy:     repository.DeviceSortCreatedAt,
+		Descending: false,
+		After:      nil,
+		Limit:      0,
+	}
+
+	var deviceIDs []uuid.UUID
+
+	err = uc.deviceRepo.FindEach(ctx, query, func(device *entity.Device) error {
+		deviceIDs = append(deviceIDs, device.ID)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDBFindAll, err)
+	}
+
+	return deviceIDs, nil
+}
+
+// findGroup retrieves a device group, translating a missing group into entity.ErrDeviceGroupNotFound.
+func (uc *deviceGroupUsecase) findGroup(ctx context.Context, id uuid.UUID) (*entity.DeviceGroup, error) {
+	group, err := uc.groupRepo.FindByID(ctx, id)
+	if err != nil {
+		if isNotFound(err, entity.ErrDeviceGroupNotFound) {
+			return nil, entity.ErrDeviceGroupNotFound
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrDBFindByID, err)
+	}
+
+	return group, nil
+}
+
+// saveWithAudit saves a created or changed device group and records it in the audit trail within one transaction.
+func (uc *deviceGroupUsecase) saveWithAudit(
+	ctx context.Context,
+	group *entity.DeviceGroup,
+	action entity.AuditAction,
+	before map[string]any,
+) error {
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := uc.groupRepo.Save(ctx, group)
+		if err != nil {
+			if errors.Is(err, entity.ErrDeviceGroupNameTaken) {
+				return err
+			}
+
+			return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+
+		return recordAudit(ctx, uc.auditLogger, auditEvent{
+			action:   action,
+			deviceID: uuid.Nil,
+			target:   deviceGroupTarget(group.ID),
+			before:   before,
+			after:    deviceGroupSnapshot(group),
+		})
+	})
+}
+
+// groupMembership returns the filter matching the members of a device group.
+func groupMembership(group *entity.DeviceGroup) (*repository.GroupMembership, error) {
+	rule, err := parseDeviceGroupRule(group.Rule)
+	if err != nil {
+		return nil, err
+	}
+
+	return &repository.GroupMembership{GroupID: group.ID, Rule: rule}, nil
+}
+
+// parseDeviceGroupRule parses the conditions of the rule of a device group, which are those of a device query.
+// If they are invalid, the error wraps ErrInvalidDeviceGroupRule.
+func parseDeviceGroupRule(rule []string) ([]repository.MetadataCondition, error) {
+	filter, err := newDeviceFilter("", "", "", rule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDeviceGroupRule, err)
+	}
+
+	return filter.Metadata, nil
+}
@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+)
+
+// DeviceGroupAction is an operation applied to all the members of a device group.
+type DeviceGroupAction string
+
+const (
+	// DeviceGroupActionSuspend suspends the members. Members that cannot be suspended are skipped.
+	DeviceGroupActionSuspend DeviceGroupAction = "suspend"
+	// DeviceGroupActionRevokeCertificates revokes the certificates of the members that are still valid.
+	// Members without such certificates are skipped.
+	DeviceGroupActionRevokeCertificates DeviceGroupAction = "revoke-certificates"
+	// DeviceGroupActionPatchMetadata applies a JSON Merge Patch to the metadata of the members.
+	DeviceGroupActionPatchMetadata DeviceGroupAction = "patch-metadata"
+)
+
+// DeviceGroupActionStatus is the outcome of an action on a member of a device group.
+type DeviceGroupActionStatus string
+
+const (
+	// DeviceGroupActionSucceeded means that the action has been applied to the device.
+	DeviceGroupActionSucceeded DeviceGroupActionStatus = "succeeded"
+	// DeviceGroupActionSkipped means that the action did not apply to the device, e.g. it was already suspended.
+	DeviceGroupActionSkipped DeviceGroupActionStatus = "skipped"
+	// DeviceGroupActionFailed means that the action could not be applied to the device.
+	DeviceGroupActionFailed DeviceGroupActionStatus = "failed"
+)
+
+// CreateDeviceGroupInput is the input data for creating a DeviceGroup.
+type CreateDeviceGroupInput struct {
+	Name        string `json:"name"`        // Required
+	Description string `json:"description"` // Optional
+	// Rule is the dynamic membership rule: conditions on the metadata in the syntax of ListDevicesInput.Metadata,
+	// all of which a device must satisfy to be a member. Optional: without it, the group only has the devices
+	// added to it.
+	Rule []string `json:"rule"`
+}
+
+// UpdateDeviceGroupInput is the input data for updating a DeviceGroup.
+type UpdateDeviceGroupInput struct {
+	ID          uuid.UUID `json:"-"`
+	Name        *string   `json:"name"`        // Optional: if nil, the name will not be updated.
+	Description *string   `json:"description"` // Optional: if nil, the description will not be updated.
+	// Rule is optional: if nil, the rule will not be updated. An empty rule makes the group static.
+	Rule []string `json:"rule"`
+}
+
+// AddDeviceGroupMembersInput is the input data for adding devices to a DeviceGroup.
+type AddDeviceGroupMembersInput struct {
+	GroupID   uuid.UUID   `json:"-"`
+	DeviceIDs []uuid.UUID `json:"deviceIds"`
+}
+
+// RemoveDeviceGroupMemberInput is the input data for removing a device from a DeviceGroup.
+type RemoveDeviceGroupMemberInput struct {
+	GroupID  uuid.UUID
+	DeviceID uuid.UUID
+}
+
+// ApplyDeviceGroupActionInput is the input data for applying an action to the members of a DeviceGroup.
+type ApplyDeviceGroupActionInput struct {
+	GroupID uuid.UUID         `json:"-"`
+	Action  DeviceGroupAction `json:"-"`
+	// Metadata is the JSON Merge Patch of the metadata, for DeviceGroupActionPatchMetadata.
+	Metadata json.RawMessage `json:"metadata"`
+	// Reason is the revocation reason, for DeviceGroupActionRevokeCertificates. It defaults to "unspecified".
+	Reason string `json:"reason"`
+}
+
+// DeviceGroupOutput is the output data for displaying DeviceGroup information.
+type DeviceGroupOutput struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Rule        []string  `json:"rule"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// NewDeviceGroupOutput creates a new DeviceGroupOutput from an entity.
+func NewDeviceGroupOutput(group *entity.DeviceGroup) *DeviceGroupOutput {
+	rule := slices.Clone([]string(group.Rule))
+	if rule == nil {
+		rule = []string{}
+	}
+
+	return &DeviceGroupOutput{
+		ID:          group.ID,
+		Name:        group.Name,
+		Description: group.Description,
+		Rule:        rule,
+		CreatedAt:   group.CreatedAt,
+		UpdatedAt:   group.UpdatedAt,
+	}
+}
+
+// DeviceGroupMembersOutput is the output data for adding devices to a DeviceGroup.
+type DeviceGroupMembersOutput struct {
+	// Added are the devices that have been added. Devices that were already members are left out.
+	Added []uuid.UUID `json:"added"`
+}
+
+// DeviceGroupActionOutput is the report of an action on the members of a DeviceGroup.
+type DeviceGroupActionOutput struct {
+	Action DeviceGroupAction `json:"action"`
+	// Matched is the number of members the action was applied to.
+	Matched   int `json:"matched"`
+	Succeeded int `json:"succeeded"`
+	Skipped   int `json:"skipped"`
+	Failed    int `json:"failed"`
+	// Results explains the outcome of the members that were skipped or failed.
+	Results []*DeviceGroupActionResult `json:"results"`
+}
+
+// DeviceGroupActionResult is the outcome of an action on a member of a DeviceGroup.
+type DeviceGroupActionResult struct {
+	DeviceID uuid.UUID               `json:"deviceId"`
+	Status   DeviceGroupActionStatus `json:"status"`
+	Error    string                  `json:"error,omitempty"`
+}
@@ -0,0 +1,607 @@
+package usecase_test
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// FakeDeviceGroupRepository is an in-memory implementation of the DeviceGroupRepository for testing.
+// It stores copies of the groups, so that a change that is not saved is not kept.
+// It shares its members with the FakeDeviceRepository it was created for, so that group filters select them.
+type FakeDeviceGroupRepository struct {
+	mu         sync.RWMutex
+	groups     map[uuid.UUID]*entity.DeviceGroup
+	members    map[uuid.UUID][]uuid.UUID
+	deviceRepo *FakeDeviceRepository
+	// for controlling error case
+	SaveErr error
+}
+
+// NewFakeDeviceGroupRepository creates a new FakeDeviceGroupRepository for the devices of deviceRepo.
+func NewFakeDeviceGroupRepository(deviceRepo *FakeDeviceRepository) *FakeDeviceGroupRepository {
+	repo := &FakeDeviceGroupRepository{
+		mu:         sync.RWMutex{},
+		groups:     make(map[uuid.UUID]*entity.DeviceGroup),
+		members:    make(map[uuid.UUID][]uuid.UUID),
+		deviceRepo: deviceRepo,
+		SaveErr:    nil,
+	}
+	deviceRepo.groups = repo
+
+	return repo
+}
+
+// Save adds or updates a group in the in-memory store.
+// Like the unique index, it rejects a name taken by another group.
+func (r *FakeDeviceGroupRepository) Save(_ context.Context, group *entity.DeviceGroup) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.SaveErr != nil {
+		return r.SaveErr
+	}
+
+	for _, other := range r.groups {
+		if other.ID != group.ID && other.Name == group.Name {
+			return entity.ErrDeviceGroupNameTaken
+		}
+	}
+
+	if group.ID == uuid.Nil {
+		group.ID = uuid.New()
+		group.CreatedAt = time.Now()
+	}
+
+	group.UpdatedAt = time.Now()
+	stored := *group
+	r.groups[group.ID] = &stored
+
+	return nil
+}
+
+// FindByID retrieves a group by its ID from the in-memory store.
+func (r *FakeDeviceGroupRepository) FindByID(_ context.Context, id uuid.UUID) (*entity.DeviceGroup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	group, ok := r.groups[id]
+	if !ok {
+		return nil, entity.ErrDeviceGroupNotFound
+	}
+
+	found := *group
+
+	return &found, nil
+}
+
+// FindAll retrieves all the groups from the in-memory store, sorted by name.
+func (r *FakeDeviceGroupRepository) FindAll(_ context.Context) ([]*entity.DeviceGroup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	groups := make([]*entity.DeviceGroup, 0, len(r.groups))
+	for _, group := range r.groups {
+		groups = append(groups, group)
+	}
+
+	slices.SortFunc(groups, func(a, b *entity.DeviceGroup) int { return cmp.Compare(a.Name, b.Name) })
+
+	return groups, nil
+}
+
+// Delete removes a group and its members from the in-memory store.
+func (r *FakeDeviceGroupRepository) Delete(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.groups[id]; !ok {
+		return entity.ErrDeviceGroupNotFound
+	}
+
+	delete(r.groups, id)
+	delete(r.members, id)
+
+	return nil
+}
+
+// AddMembers adds the devices that exist in the FakeDeviceRepository to a group, or none if any does not.
+func (r *FakeDeviceGroupRepository) AddMembers(
+	ctx context.Context,
+	groupID uuid.UUID,
+	deviceIDs []uuid.UUID,
+) ([]uuid.UUID, error) {
+	// The devices are checked before the lock is taken, as the device repository takes this lock when filtering.
+	for _, deviceID := range deviceIDs {
+		_, err := r.deviceRepo.FindByID(ctx, deviceID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.groups[groupID]; !ok {
+		return nil, entity.ErrDeviceGroupNotFound
+	}
+
+	added := make([]uuid.UUID, 0, len(deviceIDs))
+
+	for _, deviceID := range deviceIDs {
+		if !slices.Contains(r.members[groupID], deviceID) {
+			r.members[groupID] = append(r.members[groupID], deviceID)
+			added = append(added, deviceID)
+		}
+	}
+
+	return added, nil
+}
+
+// RemoveMember removes a device from a group in the in-memory store.
+func (r *FakeDeviceGroupRepository) RemoveMember(_ context.Context, groupID, deviceID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	index := slices.Index(r.members[groupID], deviceID)
+	if index < 0 {
+		return entity.ErrDeviceGroupMemberNotFound
+	}
+
+	r.members[groupID] = slices.Delete(r.members[groupID], index, index+1)
+
+	return nil
+}
+
+// IsMember reports whether a device has been added to a group.
+func (r *FakeDeviceGroupRepository) IsMember(groupID, deviceID uuid.UUID) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Contains(r.members[groupID], deviceID)
+}
+
+// deviceGroupTest is the usecase under test with its fakes.
+type deviceGroupTest struct {
+	uc          usecase.DeviceGroupUsecase
+	deviceRepo  *FakeDeviceRepository
+	groupRepo   *FakeDeviceGroupRepository
+	certRepo    *FakeCertificateRepository
+	signer      *FakeRevocationListSigner
+	auditLogger *FakeAuditLogger
+}
+
+// newDeviceGroupTest creates a DeviceGroupUsecase whose device and certificate usecases share its fakes.
+func newDeviceGroupTest() *deviceGroupTest {
+	deviceRepo := NewFakeDeviceRepository()
+	groupRepo := NewFakeDeviceGroupRepository(deviceRepo)
+	certRepo := NewFakeCertificateRepository()
+	signer := NewFakeRevocationListSigner()
+	auditLogger := NewFakeAuditLogger()
+	crl := usecase.NewCRLUsecase(certRepo, signer, nil, usecase.CRLConfig{Validity: 0, RefreshBefore: 0})
+	certificates := usecase.NewCertificateUsecase(
+		deviceRepo, certRepo, crl, newTestOCSPUsecase(certRepo), auditLogger, FakeTransactor{},
+	)
+	devices := usecase.NewDeviceUsecase(deviceRepo, auditLogger, FakeTransactor{})
+
+	return &deviceGroupTest{
+		uc: usecase.NewDeviceGroupUsecase(
+			groupRepo, deviceRepo, devices, certificates, auditLogger, FakeTransactor{},
+		),
+		deviceRepo:  deviceRepo,
+		groupRepo:   groupRepo,
+		certRepo:    certRepo,
+		signer:      signer,
+		auditLogger: auditLogger,
+	}
+}
+
+// addDevice stores a device with the status, created one minute after the previous one.
+func (tt *deviceGroupTest) addDevice(hardwareID string, status devicestatus.Status) *entity.Device {
+	device := newTestDevice(hardwareID, status)
+	device.Metadata = entity.JSONBMap{"location": map[string]any{"building": "Factory-A"}}
+	device.CreatedAt = time.Date(2026, 4, 1, 9, len(tt.deviceRepo.devices), 0, 0, time.UTC)
+	device.UpdatedAt = time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	tt.deviceRepo.devices[device.ID] = device
+
+	return device
+}
+
+// TestCreateDeviceGroup tests the CreateDeviceGroup method.
+func TestCreateDeviceGroup(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("success: create a dynamic group", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newDeviceGroupTest()
+
+		got, err := tt.uc.CreateDeviceGroup(ctx, usecase.CreateDeviceGroupInput{
+			Name:        " Factory A ",
+			Description: "Devices of the factory",
+			Rule:        []string{"location.building=Factory-A"},
+		})
+		require.NoError(t, err)
+		assert.NotEqual(t, uuid.Nil, got.ID)
+		assert.Equal(t, "Factory A", got.Name)
+		assert.Equal(t, []string{"location.building=Factory-A"}, got.Rule)
+
+		require.Equal(t, []entity.AuditAction{entity.AuditDeviceGroupCreate}, tt.auditLogger.Actions())
+		details := auditDetails(t, tt.auditLogger.Logs()[0])
+		assert.Equal(t, "device-group/"+got.ID.String(), details.Target)
+		assert.Equal(t, "Factory A", details.After["name"])
+		assert.Nil(t, tt.auditLogger.Logs()[0].TargetDeviceID)
+	})
+
+	t.Run("success: a static group has an empty rule", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newDeviceGroupTest()
+
+		got, err := tt.uc.CreateDeviceGroup(ctx, usecase.CreateDeviceGroupInput{Name: "Spares"}) //nolint:exhaustruct
+		require.NoError(t, err)
+		assert.Equal(t, []string{}, got.Rule)
+	})
+
+	t.Run("failure: invalid input", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newDeviceGroupTest()
+
+		_, err := tt.uc.CreateDeviceGroup(ctx, usecase.CreateDeviceGroupInput{Name: " "}) //nolint:exhaustruct
+		require.ErrorIs(t, err, entity.ErrDeviceGroupNameEmpty)
+
+		_, err = tt.uc.CreateDeviceGroup(ctx, usecase.CreateDeviceGroupInput{ //nolint:exhaustruct
+			Name: "Broken",
+			Rule: []string{"location..building=A"},
+		})
+		require.ErrorIs(t, err, usecase.ErrInvalidDeviceGroupRule)
+		assert.Empty(t, tt.auditLogger.Logs())
+	})
+
+	t.Run("failure: name taken", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newDeviceGroupTest()
+
+		_, err := tt.uc.CreateDeviceGroup(ctx, usecase.CreateDeviceGroupInput{Name: "Spares"}) //nolint:exhaustruct
+		require.NoError(t, err)
+
+		_, err = tt.uc.CreateDeviceGroup(ctx, usecase.CreateDeviceGroupInput{Name: "Spares"}) //nolint:exhaustruct
+		require.ErrorIs(t, err, entity.ErrDeviceGroupNameTaken)
+	})
+
+	t.Run("failure: repository returns a generic error", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newDeviceGroupTest()
+		tt.groupRepo.SaveErr = assert.AnError
+
+		_, err := tt.uc.CreateDeviceGroup(ctx, usecase.CreateDeviceGroupInput{Name: "Spares"}) //nolint:exhaustruct
+		require.ErrorIs(t, err, usecase.ErrRepositorySave)
+	})
+}
+
+// TestUpdateAndDeleteDeviceGroup tests the UpdateDeviceGroup, ListDeviceGroups and DeleteDeviceGroup methods.
+func TestUpdateAndDeleteDeviceGroup(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tt := newDeviceGroupTest()
+
+	group, err := tt.uc.CreateDeviceGroup(ctx, usecase.CreateDeviceGroupInput{
+		Name:        "Factory A",
+		Description: "",
+		Rule:        []string{"location.building=Factory-A"},
+	})
+	require.NoError(t, err)
+
+	_, err = tt.uc.CreateDeviceGroup(ctx, usecase.CreateDeviceGroupInput{Name: "Depot"}) //nolint:exhaustruct
+	require.NoError(t, err)
+
+	// A nil rule is left as it is, and an empty one removes it.
+	name := "Factory B"
+	got, err := tt.uc.UpdateDeviceGroup(ctx, usecase.UpdateDeviceGroupInput{ //nolint:exhaustruct
+		ID:   group.ID,
+		Name: &name,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Factory B", got.Name)
+	assert.Equal(t, []string{"location.building=Factory-A"}, got.Rule)
+
+	got, err = tt.uc.UpdateDeviceGroup(ctx, usecase.UpdateDeviceGroupInput{ //nolint:exhaustruct
+		ID:   group.ID,
+		Rule: []string{},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, got.Rule)
+
+	// Only the changed fields are audited.
+	logs := tt.auditLogger.Logs()
+	require.Len(t, logs, 4)
+	details := auditDetails(t, logs[2])
+	assert.Equal(t, entity.AuditDeviceGroupUpdate, logs[2].Action)
+	assert.Equal(t, map[string]any{"name": "Factory A"}, details.Before)
+
+	depot := "Depot"
+	_, err = tt.uc.UpdateDeviceGroup(ctx, usecase.UpdateDeviceGroupInput{ //nolint:exhaustruct
+		ID:   group.ID,
+		Name: &depot,
+	})
+	require.ErrorIs(t, err, entity.ErrDeviceGroupNameTaken)
+
+	_, err = tt.uc.UpdateDeviceGroup(ctx, usecase.UpdateDeviceGroupInput{ //nolint:exhaustruct
+		ID:   group.ID,
+		Rule: []string{"a..b"},
+	})
+	require.ErrorIs(t, err, usecase.ErrInvalidDeviceGroupRule)
+
+	_, err = tt.uc.UpdateDeviceGroup(ctx, usecase.UpdateDeviceGroupInput{ID: uuid.New()}) //nolint:exhaustruct
+	require.ErrorIs(t, err, entity.ErrDeviceGroupNotFound)
+
+	groups, err := tt.uc.ListDeviceGroups(ctx)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "Depot", groups[0].Name)
+
+	require.NoError(t, tt.uc.DeleteDeviceGroup(ctx, group.ID))
+	require.ErrorIs(t, tt.uc.DeleteDeviceGroup(ctx, group.ID), entity.ErrDeviceGroupNotFound)
+
+	_, err = tt.uc.GetDeviceGroup(ctx, group.ID)
+	require.ErrorIs(t, err, entity.ErrDeviceGroupNotFound)
+	assert.Equal(t, entity.AuditDeviceGroupDelete, tt.auditLogger.Actions()[4])
+}
+
+// TestDeviceGroupMembers tests the AddDeviceGroupMembers, RemoveDeviceGroupMember and ListDeviceGroupDevices methods.
+func TestDeviceGroupMembers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tt := newDeviceGroupTest()
+	first := tt.addDevice("hw-001", devicestatus.Active)
+	second := tt.addDevice("hw-002", devicestatus.Active)
+	tt.addDevice("hw-003", devicestatus.Active)
+
+	group, err := tt.uc.CreateDeviceGroup(ctx, usecase.CreateDeviceGroupInput{Name: "Line 1"}) //nolint:exhaustruct
+	require.NoError(t, err)
+
+	// Devices that are already members are not added again.
+	added, err := tt.uc.AddDeviceGroupMembers(ctx, usecase.AddDeviceGroupMembersInput{
+		GroupID:   group.ID,
+		DeviceIDs: []uuid.UUID{first.ID},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{first.ID}, added.Added)
+
+	added, err = tt.uc.AddDeviceGroupMembers(ctx, usecase.AddDeviceGroupMembersInput{
+		GroupID:   group.ID,
+		DeviceIDs: []uuid.UUID{first.ID, second.ID},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{second.ID}, added.Added)
+
+	// Each addition is audited with the device.
+	logs := tt.auditLogger.Logs()
+	require.Len(t, logs, 3)
+	assert.Equal(t, entity.AuditDeviceGroupMemberAdd, logs[2].Action)
+	assert.Equal(t, &second.ID, logs[2].TargetDeviceID)
+
+	_, err = tt.uc.AddDeviceGroupMembers(ctx, usecase.AddDeviceGroupMembersInput{
+		GroupID:   group.ID,
+		DeviceIDs: []uuid.UUID{uuid.New()},
+	})
+	require.ErrorIs(t, err, entity.ErrDeviceNotFound)
+
+	_, err = tt.uc.AddDeviceGroupMembers(ctx, usecase.AddDeviceGroupMembersInput{
+		GroupID:   uuid.New(),
+		DeviceIDs: []uuid.UUID{first.ID},
+	})
+	require.ErrorIs(t, err, entity.ErrDeviceGroupNotFound)
+
+	page, err := tt.uc.ListDeviceGroupDevices(ctx, group.ID, usecase.ListDevicesInput{ //nolint:exhaustruct
+		Limit: 1,
+	})
+	require.NoError(t, err)
+	require.Len(t, page.Devices, 1)
+	assert.Equal(t, first.ID, page.Devices[0].ID)
+	assert.Equal(t, int64(2), page.TotalCount)
+	assert.NotEmpty(t, page.NextCursor)
+
+	err = tt.uc.RemoveDeviceGroupMember(ctx, usecase.RemoveDeviceGroupMemberInput{
+		GroupID:  group.ID,
+		DeviceID: first.ID,
+	})
+	require.NoError(t, err)
+
+	err = tt.uc.RemoveDeviceGroupMember(ctx, usecase.RemoveDeviceGroupMemberInput{
+		GroupID:  group.ID,
+		DeviceID: first.ID,
+	})
+	require.ErrorIs(t, err, entity.ErrDeviceGroupMemberNotFound)
+
+	page, err = tt.uc.ListDeviceGroupDevices(ctx, group.ID, usecase.ListDevicesInput{}) //nolint:exhaustruct
+	require.NoError(t, err)
+	require.Len(t, page.Devices, 1)
+	assert.Equal(t, second.ID, page.Devices[0].ID)
+
+	_, err = tt.uc.ListDeviceGroupDevices(ctx, group.ID, usecase.ListDevicesInput{Sort: "version"}) //nolint:exhaustruct
+	require.ErrorIs(t, err, usecase.ErrInvalidDeviceQuery)
+
+	_, err = tt.uc.ListDeviceGroupDevices(ctx, uuid.New(), usecase.ListDevicesInput{}) //nolint:exhaustruct
+	require.ErrorIs(t, err, entity.ErrDeviceGroupNotFound)
+}
+
+// TestListDeviceGroupDevicesRule tests that the devices of a group are selected by its rule as well.
+func TestListDeviceGroupDevicesRule(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tt := newDeviceGroupTest()
+
+	group, err := tt.uc.CreateDeviceGroup(ctx, usecase.CreateDeviceGroupInput{
+		Name:        "Factory A",
+		Description: "",
+		Rule:        []string{"location.building=Factory-A"},
+	})
+	require.NoError(t, err)
+
+	_, err = tt.uc.ListDeviceGroupDevices(ctx, group.ID, usecase.ListDevicesInput{ //nolint:exhaustruct
+		Status: "ACTIVE",
+	})
+	require.NoError(t, err)
+
+	// The fake repository does not evaluate the rule, so the filter is checked instead.
+	filter := tt.deviceRepo.LastFilter()
+	assert.Equal(t, devicestatus.Active, filter.Status)
+	assert.Equal(t, &repository.GroupMembership{
+		GroupID: group.ID,
+		Rule: []repository.MetadataCondition{{
+			Path:     []string{"location", "building"},
+			Operator: repository.MetadataEquals,
+			Value:    "Factory-A",
+		}},
+	}, filter.Group)
+}
+
+// TestApplyDeviceGroupAction tests the ApplyDeviceGroupAction method.
+func TestApplyDeviceGroupAction(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	// setup creates a group with an active device, an unregistered device and a revoked device.
+	setup := func(t *testing.T) (*deviceGroupTest, uuid.UUID, []*entity.Device) {
+		t.Helper()
+
+		tt := newDeviceGroupTest()
+		devices := []*entity.Device{
+			tt.addDevice("hw-001", devicestatus.Active),
+			tt.addDevice("hw-002", devicestatus.Unregistered),
+			tt.addDevice("hw-003", devicestatus.Revoked),
+		}
+		tt.addDevice("hw-004", devicestatus.Active) // not a member
+
+		group, err := tt.uc.CreateDeviceGroup(ctx, usecase.CreateDeviceGroupInput{Name: "Line 1"}) //nolint:exhaustruct
+		require.NoError(t, err)
+
+		_, err = tt.uc.AddDeviceGroupMembers(ctx, usecase.AddDeviceGroupMembersInput{
+			GroupID:   group.ID,
+			DeviceIDs: []uuid.UUID{devices[0].ID, devices[1].ID, devices[2].ID},
+		})
+		require.NoError(t, err)
+
+		return tt, group.ID, devices
+	}
+
+	t.Run("suspend", func(t *testing.T) {
+		t.Parallel()
+
+		tt, groupID, devices := setup(t)
+
+		got, err := tt.uc.ApplyDeviceGroupAction(ctx, usecase.ApplyDeviceGroupActionInput{ //nolint:exhaustruct
+			GroupID: groupID,
+			Action:  usecase.DeviceGroupActionSuspend,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, got.Matched)
+		assert.Equal(t, 1, got.Succeeded)
+		assert.Equal(t, 2, got.Skipped)
+		assert.Equal(t, 0, got.Failed)
+		require.Len(t, got.Results, 2)
+		assert.Equal(t, devices[1].ID, got.Results[0].DeviceID)
+		assert.Equal(t, usecase.DeviceGroupActionSkipped, got.Results[0].Status)
+		assert.Contains(t, got.Results[0].Error, devicestatus.ErrInvalidTransition.Error())
+		assert.Equal(t, devicestatus.Suspended, devices[0].Status)
+	})
+
+	t.Run("patch metadata", func(t *testing.T) {
+		t.Parallel()
+
+		tt, groupID, devices := setup(t)
+
+		got, err := tt.uc.ApplyDeviceGroupAction(ctx, usecase.ApplyDeviceGroupActionInput{ //nolint:exhaustruct
+			GroupID:  groupID,
+			Action:   usecase.DeviceGroupActionPatchMetadata,
+			Metadata: json.RawMessage(`{"firmware": {"channel": "beta"}, "location": null}`),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, got.Succeeded)
+		assert.Empty(t, got.Results)
+
+		for _, device := range devices {
+			assert.Equal(t, entity.JSONBMap{"firmware": map[string]any{"channel": "beta"}}, device.Metadata)
+		}
+	})
+
+	t.Run("revoke certificates", func(t *testing.T) {
+		t.Parallel()
+
+		tt, groupID, devices := setup(t)
+		require.NoError(t, tt.certRepo.Save(ctx, newTestCertificate(t, devices[0].ID, 1001)))
+		require.NoError(t, tt.certRepo.Save(ctx, newTestCertificate(t, devices[0].ID, 1002)))
+
+		revoked := newTestCertificate(t, devices[2].ID, 1003)
+		revoked.IsRevoked = true
+		require.NoError(t, tt.certRepo.Save(ctx, revoked))
+
+		got, err := tt.uc.ApplyDeviceGroupAction(ctx, usecase.ApplyDeviceGroupActionInput{ //nolint:exhaustruct
+			GroupID: groupID,
+			Action:  usecase.DeviceGroupActionRevokeCertificates,
+			Reason:  "keyCompromise",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, got.Succeeded)
+		assert.Equal(t, 2, got.Skipped)
+
+		for _, serialNumber := range []int64{1001, 1002} {
+			certificate, err := tt.certRepo.FindBySerialNumber(ctx, serialNumber)
+			require.NoError(t, err)
+			assert.True(t, certificate.IsRevoked)
+		}
+
+		// The CRL is regenerated once for all the revocations.
+		lists := tt.signer.Lists()
+		require.Len(t, lists, 1)
+		assert.Len(t, lists[0].Entries, 3)
+	})
+
+	t.Run("invalid action", func(t *testing.T) {
+		t.Parallel()
+
+		tt, groupID, devices := setup(t)
+
+		for _, input := range []usecase.ApplyDeviceGroupActionInput{ //nolint:exhaustruct
+			{GroupID: groupID, Action: "reboot"},
+			{GroupID: groupID, Action: usecase.DeviceGroupActionRevokeCertificates, Reason: "certificateHold"},
+			{GroupID: groupID, Action: usecase.DeviceGroupActionPatchMetadata},
+			{GroupID: groupID, Action: usecase.DeviceGroupActionPatchMetadata, Metadata: json.RawMessage(`[1]`)},
+			{GroupID: groupID, Action: usecase.DeviceGroupActionPatchMetadata, Metadata: json.RawMessage(`null`)},
+		} {
+			_, err := tt.uc.ApplyDeviceGroupAction(ctx, input)
+			require.ErrorIs(t, err, usecase.ErrInvalidDeviceGroupAction, "action %s", input.Action)
+		}
+
+		assert.Equal(t, devicestatus.Active, devices[0].Status)
+
+		_, err := tt.uc.ApplyDeviceGroupAction(ctx, usecase.ApplyDeviceGroupActionInput{ //nolint:exhaustruct
+			GroupID: uuid.New(),
+			Action:  usecase.DeviceGroupActionSuspend,
+		})
+		require.ErrorIs(t, err, entity.ErrDeviceGroupNotFound)
+	})
+}
@@ -0,0 +1,532 @@
+package usecase
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+const (
+	// DeviceImportBatchSize is the number of devices created by one INSERT statement during an import.
+	DeviceImportBatchSize = 500
+	// MaxDeviceImportRows is the maximum number of rows of an import file.
+	MaxDeviceImportRows = 10000
+	// maxImportLineBytes is the maximum length of a line of an NDJSON import file.
+	maxImportLineBytes = 1 << 20
+)
+
+// errImportLineNotSingleObject is reported for a line of an NDJSON file that has more than one JSON value.
+var errImportLineNotSingleObject = errors.New("line must be a single JSON object")
+
+// Columns of a CSV import file.
+const (
+	importColumnHardwareID     = "hardware_id"
+	importColumnName           = "name"
+	importColumnMetadata       = "metadata"
+	importMetadataColumnPrefix = "metadata."
+)
+
+// DeviceImportUsecase defines the interface for importing devices in bulk.
+type DeviceImportUsecase interface {
+	// ImportDevices creates the devices described by a CSV or NDJSON file and reports the outcome of each row.
+	// The devices are created within one transaction: if any of them cannot be saved, none is.
+	ImportDevices(ctx context.Context, input ImportDevicesInput) (*DeviceImportOutput, error)
+}
+
+// deviceImportUsecase is the implementation of the DeviceImportUsecase interface.
+type deviceImportUsecase struct {
+	deviceRepo  repository.DeviceRepository
+	tokenRepo   repository.EnrollmentTokenRepository
+	auditLogger repository.AuditLogger
+	transactor  repository.Transactor
+	tokenConfig EnrollmentTokenConfig
+	now         func() time.Time
+}
+
+// NewDeviceImportUsecase creates a new instance of deviceImportUsecase.
+// tokenConfig bounds the lifetime of the enrollment tokens issued for the imported devices;
+// zero values are replaced by the defaults.
+//
+//nolint:ireturn
+func NewDeviceImportUsecase(
+	deviceRepo repository.DeviceRepository,
+	tokenRepo repository.EnrollmentTokenRepository,
+	auditLogger repository.AuditLogger,
+	transactor repository.Transactor,
+	tokenConfig EnrollmentTokenConfig,
+) DeviceImportUsecase {
+	return &deviceImportUsecase{
+		deviceRepo:  deviceRepo,
+		tokenRepo:   tokenRepo,
+		auditLogger: auditLogger,
+		transactor:  transactor,
+		tokenConfig: tokenConfig.withDefaults(),
+		now:         time.Now,
+	}
+}
+
+// importRow is a row of an import file, with the device it describes if it is valid.
+type importRow struct {
+	output *DeviceImportRowOutput
+	device *entity.Device
+}
+
+// ndjsonImportRow is a line of an NDJSON import file.
+type ndjsonImportRow struct {
+	HardwareID string         `json:"hardware_id"`
+	Name       *string        `json:"name"`
+	Metadata   map[string]any `json:"metadata"`
+}
+
+// ImportDevices creates the devices described by a CSV or NDJSON file and reports the outcome of each row.
+// A file that cannot be read as a whole is rejected with ErrInvalidDeviceImport, while invalid rows are reported.
+func (uc *deviceImportUsecase) ImportDevices(
+	ctx context.Context,
+	input ImportDevicesInput,
+) (*DeviceImportOutput, error) {
+	ttl, err := uc.tokenConfig.ttl(input.EnrollmentTokenTTLSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := readImportRows(input.Format, input.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	// seen maps the hardware IDs of the valid rows read so far to their lines.
+	seen := make(map[string]int)
+
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		for batch := range slices.Chunk(rows, DeviceImportBatchSize) {
+			err := uc.importBatch(ctx, batch, seen, input, ttl)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	output := &DeviceImportOutput{
+		DryRun:     input.DryRun,
+		Created:    0,
+		Duplicates: 0,
+		Invalid:    0,
+		Rows:       make([]*DeviceImportRowOutput, 0, len(rows)),
+	}
+
+	for _, row := range rows {
+		switch row.output.Status {
+		case ImportRowCreated:
+			output.Created++
+		case ImportRowDuplicate:
+			output.Duplicates++
+		case ImportRowInvalid:
+			output.Invalid++
+		}
+
+		output.Rows = append(output.Rows, row.output)
+	}
+
+	return output, nil
+}
+
+// importBatch creates the devices of a batch of rows that are neither invalid nor duplicates,
+// together with their enrollment tokens and audit log entries. In a dry run, nothing is created.
+func (uc *deviceImportUsecase) importBatch(
+	ctx context.Context,
+	batch []*importRow,
+	seen map[string]int,
+	input ImportDevicesInput,
+	ttl time.Duration,
+) error {
+	candidates := make([]*importRow, 0, len(batch))
+
+	for _, row := range batch {
+		if row.device == nil {
+			continue
+		}
+
+		line, ok := seen[row.device.HardwareID]
+		if ok {
+			markDuplicate(row, fmt.Sprintf("hardware ID already appears on line %d", line))
+
+			continue
+		}
+
+		seen[row.device.HardwareID] = row.output.Line
+		candidates = append(candidates, row)
+	}
+
+	hardwareIDs := make([]string, 0, len(candidates))
+	for _, row := range candidates {
+		hardwareIDs = append(hardwareIDs, row.device.HardwareID)
+	}
+
+	existing, err := uc.deviceRepo.FindByHardwareIDs(ctx, hardwareIDs)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDBFindByHardwareID, err)
+	}
+
+	exists := make(map[string]bool, len(existing))
+	for _, device := range existing {
+		exists[device.HardwareID] = true
+	}
+
+	devices := make([]*entity.Device, 0, len(candidates))
+
+	for _, row := range candidates {
+		if exists[row.device.HardwareID] {
+			markDuplicate(row, "hardware ID already exists")
+
+			continue
+		}
+
+		row.output.Status = ImportRowCreated
+		devices = append(devices, row.device)
+	}
+
+	if input.DryRun || len(devices) == 0 {
+		return nil
+	}
+
+	err = uc.deviceRepo.CreateAll(ctx, devices)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+	}
+
+	now := uc.now()
+	events := make([]auditEvent, 0, 2*len(devices))
+
+	for _, row := range candidates {
+		if row.output.Status != ImportRowCreated {
+			continue
+		}
+
+		row.output.Device = NewDeviceOutput(row.device)
+		events = append(events, auditEvent{
+			action:   entity.AuditDeviceCreate,
+			deviceID: row.device.ID,
+			target:   deviceTarget(row.device.ID),
+			before:   nil,
+			after:    deviceSnapshot(row.device),
+		})
+
+		if !input.CreateEnrollmentTokens {
+			continue
+		}
+
+		token, secret, err := entity.NewEnrollmentToken(row.device.ID, ttl, now)
+		if err != nil {
+			return fmt.Errorf("failed to create new enrollment token entity: %w", err)
+		}
+
+		err = uc.tokenRepo.Save(ctx, token)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+
+		row.output.EnrollmentToken = &CreatedEnrollmentTokenOutput{
+			EnrollmentTokenOutput: *NewEnrollmentTokenOutput(token, now),
+			Token:                 secret,
+		}
+		events = append(events, auditEvent{
+			action:   entity.AuditTokenCreate,
+			deviceID: row.device.ID,
+			target:   enrollmentTokenTarget(token.ID),
+			before:   nil,
+			after:    enrollmentTokenSnapshot(token, now),
+		})
+	}
+
+	return recordAudit(ctx, uc.auditLogger, events...)
+}
+
+// markDuplicate reports a row as a duplicate, so that its device is not created.
+func markDuplicate(row *importRow, reason string) {
+	row.output.Status = ImportRowDuplicate
+	row.output.Error = reason
+	row.device = nil
+}
+
+// readImportRows reads the rows of an import file.
+func readImportRows(format ImportFormat, data io.Reader) ([]*importRow, error) {
+	switch format {
+	case ImportFormatCSV:
+		return readCSVImportRows(data)
+	case ImportFormatNDJSON:
+		return readNDJSONImportRows(data)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidDeviceImport, format)
+	}
+}
+
+// readCSVImportRows reads a CSV import file. The header row names the columns; see ImportFormatCSV.
+// A row with the wrong number of fields is reported as invalid, while a malformed file is rejected.
+func readCSVImportRows(data io.Reader) ([]*importRow, error) {
+	reader := csv.NewReader(data)
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: file is empty", ErrInvalidDeviceImport)
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDeviceImport, err)
+	}
+
+	columns, err := newCSVImportColumns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []*importRow
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidDeviceImport, err)
+		}
+
+		if len(rows) == MaxDeviceImportRows {
+			return nil, fmt.Errorf("%w: at most %d rows are allowed", ErrInvalidDeviceImport, MaxDeviceImportRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		if err != nil {
+			rows = append(rows, invalidImportRow(line, "", fmt.Sprintf("expected %d fields", len(header))))
+
+			continue
+		}
+
+		rows = append(rows, columns.row(line, record))
+	}
+
+	return rows, nil
+}
+
+// csvImportColumns are the indices of the columns of a CSV import file.
+type csvImportColumns struct {
+	hardwareID int
+	// name and metadata are -1 if the file does not have the column.
+	name     int
+	metadata int
+	// paths maps the indices of the metadata.<path> columns to their paths.
+	paths map[int][]string
+}
+
+// newCSVImportColumns finds the columns of a CSV import file in its header row.
+// It rejects unknown and repeated columns, so that a misspelt column is not silently ignored.
+func newCSVImportColumns(header []string) (*csvImportColumns, error) {
+	columns := &csvImportColumns{hardwareID: -1, name: -1, metadata: -1, paths: make(map[int][]string)}
+	names := make(map[string]bool, len(header))
+
+	for i, name := range header {
+		// Spreadsheet applications may start the file with a byte order mark.
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+
+		name = strings.TrimSpace(name)
+		if names[name] {
+			return nil, fmt.Errorf("%w: column %q is repeated", ErrInvalidDeviceImport, name)
+		}
+
+		names[name] = true
+
+		switch {
+		case name == importColumnHardwareID:
+			columns.hardwareID = i
+		case name == importColumnName:
+			columns.name = i
+		case name == importColumnMetadata:
+			columns.metadata = i
+		case strings.HasPrefix(name, importMetadataColumnPrefix):
+			path := strings.Split(strings.TrimPrefix(name, importMetadataColumnPrefix), ".")
+			if slices.Contains(path, "") {
+				return nil, fmt.Errorf("%w: invalid metadata path in column %q", ErrInvalidDeviceImport, name)
+			}
+
+			columns.paths[i] = path
+		default:
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidDeviceImport, name)
+		}
+	}
+
+	if columns.hardwareID < 0 {
+		return nil, fmt.Errorf("%w: column %q is required", ErrInvalidDeviceImport, importColumnHardwareID)
+	}
+
+	return columns, nil
+}
+
+// row reads a record of a CSV import file. Empty cells are ignored.
+func (c *csvImportColumns) row(line int, record []string) *importRow {
+	hardwareID := strings.TrimSpace(record[c.hardwareID])
+
+	var name *string
+	if c.name >= 0 && record[c.name] != "" {
+		name = &record[c.name]
+	}
+
+	metadata := make(map[string]any)
+
+	if c.metadata >= 0 && record[c.metadata] != "" {
+		err := json.Unmarshal([]byte(record[c.metadata]), &metadata)
+		if err != nil || metadata == nil {
+			return invalidImportRow(line, hardwareID, "metadata must be a JSON object")
+		}
+	}
+
+	// The columns are applied in order, so that the result does not depend on the order of the map.
+	for _, i := range slices.Sorted(maps.Keys(c.paths)) {
+		if record[i] == "" {
+			continue
+		}
+
+		err := setMetadataPath(metadata, c.paths[i], parseImportValue(record[i]))
+		if err != nil {
+			return invalidImportRow(line, hardwareID, err.Error())
+		}
+	}
+
+	return newImportRow(line, hardwareID, name, metadata)
+}
+
+// readNDJSONImportRows reads an NDJSON import file. Blank lines are skipped,
+// and a line that is not a JSON object with the known fields is reported as invalid.
+func readNDJSONImportRows(data io.Reader) ([]*importRow, error) {
+	scanner := bufio.NewScanner(data)
+	scanner.Buffer(nil, maxImportLineBytes)
+
+	var rows []*importRow
+
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		if len(rows) == MaxDeviceImportRows {
+			return nil, fmt.Errorf("%w: at most %d rows are allowed", ErrInvalidDeviceImport, MaxDeviceImportRows)
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.DisallowUnknownFields()
+
+		var decoded ndjsonImportRow
+
+		err := decoder.Decode(&decoded)
+		if err == nil && decoder.More() {
+			err = errImportLineNotSingleObject
+		}
+
+		if err != nil {
+			rows = append(rows, invalidImportRow(line, "", err.Error()))
+
+			continue
+		}
+
+		rows = append(rows, newImportRow(line, strings.TrimSpace(decoded.HardwareID), decoded.Name, decoded.Metadata))
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDeviceImport, err)
+	}
+
+	return rows, nil
+}
+
+// newImportRow validates the device described by a row through entity.NewDevice.
+func newImportRow(line int, hardwareID string, name *string, metadata map[string]any) *importRow {
+	device, err := entity.NewDevice(hardwareID, name, metadata)
+	if err != nil {
+		return invalidImportRow(line, hardwareID, err.Error())
+	}
+
+	return &importRow{
+		output: &DeviceImportRowOutput{
+			Line:            line,
+			HardwareID:      hardwareID,
+			Status:          "",
+			Error:           "",
+			Device:          nil,
+			EnrollmentToken: nil,
+		},
+		device: device,
+	}
+}
+
+// invalidImportRow returns a row reported as invalid for the reason.
+func invalidImportRow(line int, hardwareID, reason string) *importRow {
+	return &importRow{
+		output: &DeviceImportRowOutput{
+			Line:            line,
+			HardwareID:      hardwareID,
+			Status:          ImportRowInvalid,
+			Error:           reason,
+			Device:          nil,
+			EnrollmentToken: nil,
+		},
+		device: nil,
+	}
+}
+
+// parseImportValue reads the value of a metadata.<path> cell.
+// Valid JSON, such as a number, a boolean or a quoted string, is used as such; anything else is a plain string.
+func parseImportValue(cell string) any {
+	var value any
+
+	err := json.Unmarshal([]byte(cell), &value)
+	if err != nil {
+		return cell
+	}
+
+	return value
+}
+
+// setMetadataPath sets the value at a path of the metadata, creating the objects along the path.
+func setMetadataPath(metadata map[string]any, path []string, value any) error {
+	object := metadata
+
+	for i, key := range path[:len(path)-1] {
+		child, ok := object[key]
+		if !ok {
+			child = make(map[string]any)
+			object[key] = child
+		}
+
+		childObject, ok := child.(map[string]any)
+		if !ok {
+			return fmt.Errorf("metadata.%s is not an object", strings.Join(path[:i+1], "."))
+		}
+
+		object = childObject
+	}
+
+	object[path[len(path)-1]] = value
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package usecase
+
+import "io"
+
+// ImportFormat is the format of a device import file.
+type ImportFormat string
+
+const (
+	// ImportFormatCSV is a CSV file with a header row. The columns are hardware_id (required), name,
+	// metadata (a JSON object) and metadata.<path> columns that set a single value, e.g. metadata.location.building.
+	ImportFormatCSV ImportFormat = "csv"
+	// ImportFormatNDJSON is a file with one JSON object per line,
+	// e.g. {"hardware_id": "...", "name": "...", "metadata": {...}}.
+	ImportFormatNDJSON ImportFormat = "ndjson"
+)
+
+// ImportRowStatus is the outcome of a row of a device import.
+type ImportRowStatus string
+
+const (
+	// ImportRowCreated means that the device was created, or would be created in a dry run.
+	ImportRowCreated ImportRowStatus = "created"
+	// ImportRowDuplicate means that a device with the hardware ID already exists or appears earlier in the file.
+	ImportRowDuplicate ImportRowStatus = "duplicate"
+	// ImportRowInvalid means that the row could not be read or does not describe a valid device.
+	ImportRowInvalid ImportRowStatus = "invalid"
+)
+
+// ImportDevicesInput is the input data for importing devices from a file.
+type ImportDevicesInput struct {
+	Format ImportFormat
+	Data   io.Reader
+	// DryRun validates the file and reports the outcome of each row without creating anything.
+	DryRun bool
+	// CreateEnrollmentTokens issues an enrollment token for each created device.
+	CreateEnrollmentTokens bool
+	// EnrollmentTokenTTLSeconds is the lifetime of the tokens. Optional: if zero, the default TTL is used.
+	EnrollmentTokenTTLSeconds int64
+}
+
+// DeviceImportOutput is the report of a device import.
+type DeviceImportOutput struct {
+	DryRun     bool                     `json:"dryRun"`
+	Created    int                      `json:"created"`
+	Duplicates int                      `json:"duplicates"`
+	Invalid    int                      `json:"invalid"`
+	Rows       []*DeviceImportRowOutput `json:"rows"`
+}
+
+// DeviceImportRowOutput is the outcome of a row of a device import.
+type DeviceImportRowOutput struct {
+	// Line is the line of the file the row starts on, counting from 1.
+	Line       int             `json:"line"`
+	HardwareID string          `json:"hardwareId,omitempty"`
+	Status     ImportRowStatus `json:"status"`
+	// Error explains why the row is a duplicate or invalid.
+	Error  string        `json:"error,omitempty"`
+	Device *DeviceOutput `json:"device,omitempty"`
+	// EnrollmentToken is the token issued for the created device, with its secret.
+	EnrollmentToken *CreatedEnrollmentTokenOutput `json:"enrollmentToken,omitempty"`
+}
@@ -0,0 +1,323 @@
+package usecase_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/connectionstate"
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDeviceImportTest creates a DeviceImportUsecase with a device that already exists.
+func newDeviceImportTest(
+	t *testing.T,
+) (usecase.DeviceImportUsecase, *FakeDeviceRepository, *FakeEnrollmentTokenRepository, *FakeAuditLogger) {
+	t.Helper()
+
+	deviceRepo := NewFakeDeviceRepository()
+	existing := &entity.Device{
+		ID:              uuid.New(),
+		HardwareID:      "hw-existing",
+		Name:            "",
+		Status:          devicestatus.Active,
+		Metadata:        nil,
+		Version:         1,
+		ParentID:        nil,
+		ConnectionState: connectionstate.Disconnected,
+		LastSeenAt:      nil,
+		LastIP:          "",
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+	deviceRepo.devices[existing.ID] = existing
+
+	tokenRepo := NewFakeEnrollmentTokenRepository()
+	auditLogger := NewFakeAuditLogger()
+	uc := usecase.NewDeviceImportUsecase(
+		deviceRepo, tokenRepo, auditLogger, FakeTransactor{},
+		usecase.EnrollmentTokenConfig{DefaultTTL: time.Hour, MaxTTL: 2 * time.Hour},
+	)
+
+	return uc, deviceRepo, tokenRepo, auditLogger
+}
+
+// importRowStatuses returns the status of each row of an import report.
+func importRowStatuses(output *usecase.DeviceImportOutput) []usecase.ImportRowStatus {
+	statuses := make([]usecase.ImportRowStatus, 0, len(output.Rows))
+	for _, row := range output.Rows {
+		statuses = append(statuses, row.Status)
+	}
+
+	return statuses
+}
+
+// TestImportDevicesCSV tests the ImportDevices method with a CSV file.
+func TestImportDevicesCSV(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	uc, deviceRepo, tokenRepo, auditLogger := newDeviceImportTest(t)
+
+	// A byte order mark, a metadata column and metadata.<path> columns are accepted.
+	data := "\ufeffhardware_id,name,metadata,metadata.location.building,metadata.location.floor\n" +
+		`hw-001,Sensor 1,"{""firmware"": {""version"": ""2.4""}}",Factory-A,2` + "\n" +
+		"hw-002,,,,\n" +
+		"hw-001,Again,,,\n" +
+		"hw-existing,,,,\n" +
+		",No hardware ID,,,\n" +
+		"hw-003,Too few fields\n" +
+		`hw-004,,"[1, 2]",,` + "\n" +
+		`hw-005,,"{""location"": ""Factory-B""}",Factory-A,` + "\n"
+
+	output, err := uc.ImportDevices(ctx, usecase.ImportDevicesInput{
+		Format:                    usecase.ImportFormatCSV,
+		Data:                      strings.NewReader(data),
+		DryRun:                    false,
+		CreateEnrollmentTokens:    false,
+		EnrollmentTokenTTLSeconds: 0,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []usecase.ImportRowStatus{
+		usecase.ImportRowCreated,
+		usecase.ImportRowCreated,
+		usecase.ImportRowDuplicate,
+		usecase.ImportRowDuplicate,
+		usecase.ImportRowInvalid,
+		usecase.ImportRowInvalid,
+		usecase.ImportRowInvalid,
+		usecase.ImportRowInvalid,
+	}, importRowStatuses(output))
+	assert.Equal(t, 2, output.Created)
+	assert.Equal(t, 2, output.Duplicates)
+	assert.Equal(t, 4, output.Invalid)
+	assert.False(t, output.DryRun)
+
+	// The lines count the header row.
+	assert.Equal(t, 2, output.Rows[0].Line)
+	assert.Equal(t, "hardware ID already appears on line 2", output.Rows[2].Error)
+	assert.Equal(t, "hardware ID already exists", output.Rows[3].Error)
+	assert.Equal(t, entity.ErrHardwareIDEmpty.Error(), output.Rows[4].Error)
+	assert.Equal(t, "metadata.location is not an object", output.Rows[7].Error)
+
+	created := output.Rows[0].Device
+	require.NotNil(t, created)
+	assert.Equal(t, "Sensor 1", created.Name)
+	assert.Equal(t, map[string]any{
+		"firmware": map[string]any{"version": "2.4"},
+		"location": map[string]any{"building": "Factory-A", "floor": 2.0},
+	}, created.Metadata)
+
+	device, err := deviceRepo.FindByHardwareID(ctx, "hw-002")
+	require.NoError(t, err)
+	assert.Equal(t, devicestatus.Unregistered, device.Status)
+	assert.Empty(t, device.Name)
+	assert.Nil(t, output.Rows[1].EnrollmentToken)
+
+	assert.Empty(t, tokenRepo.tokens)
+
+	logs := auditLogger.Logs()
+	require.Len(t, logs, 2)
+	assert.Equal(t, entity.AuditDeviceCreate, logs[0].Action)
+}
+
+// TestImportDevicesNDJSON tests the ImportDevices method with an NDJSON file and enrollment tokens.
+func TestImportDevicesNDJSON(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	uc, deviceRepo, tokenRepo, auditLogger := newDeviceImportTest(t)
+
+	data := `{"hardware_id": "hw-101", "name": "Gateway", "metadata": {"site": "Osaka"}}` + "\n" +
+		"\n" +
+		`{"hardware_id": "hw-102"}` + "\n" +
+		`{"hardwareId": "hw-103"}` + "\n" +
+		`{"hardware_id": "hw-104", "metadata": "not an object"}` + "\n" +
+		`{"hardware_id": "hw-existing"}` + "\n"
+
+	output, err := uc.ImportDevices(ctx, usecase.ImportDevicesInput{
+		Format:                    usecase.ImportFormatNDJSON,
+		Data:                      strings.NewReader(data),
+		DryRun:                    false,
+		CreateEnrollmentTokens:    true,
+		EnrollmentTokenTTLSeconds: 600,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []usecase.ImportRowStatus{
+		usecase.ImportRowCreated,
+		usecase.ImportRowCreated,
+		usecase.ImportRowInvalid,
+		usecase.ImportRowInvalid,
+		usecase.ImportRowDuplicate,
+	}, importRowStatuses(output))
+
+	assert.Contains(t, output.Rows[2].Error, `unknown field "hardwareId"`)
+
+	// Blank lines are skipped, but still counted.
+	assert.Equal(t, []int{1, 3, 4, 5, 6}, []int{
+		output.Rows[0].Line, output.Rows[1].Line, output.Rows[2].Line, output.Rows[3].Line, output.Rows[4].Line,
+	})
+
+	// Each created device gets a token, whose secret is returned once.
+	for _, row := range output.Rows[:2] {
+		require.NotNil(t, row.EnrollmentToken, row.HardwareID)
+		assert.Equal(t, row.Device.ID, row.EnrollmentToken.DeviceID)
+		assert.NotEmpty(t, row.EnrollmentToken.Token)
+		assert.WithinDuration(t, time.Now().Add(10*time.Minute), row.EnrollmentToken.ExpiresAt, time.Minute)
+		assert.Contains(t, tokenRepo.tokens, row.EnrollmentToken.ID)
+	}
+
+	assert.Nil(t, output.Rows[4].EnrollmentToken)
+
+	device, err := deviceRepo.FindByHardwareID(ctx, "hw-101")
+	require.NoError(t, err)
+	assert.Equal(t, entity.JSONBMap{"site": "Osaka"}, device.Metadata)
+
+	// The devices and the tokens are audited.
+	assert.Len(t, auditLogger.Logs(), 4)
+}
+
+// TestImportDevicesDryRun tests that a dry run reports the outcome of each row without creating anything.
+func TestImportDevicesDryRun(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	uc, deviceRepo, tokenRepo, auditLogger := newDeviceImportTest(t)
+
+	output, err := uc.ImportDevices(ctx, usecase.ImportDevicesInput{
+		Format:                    usecase.ImportFormatCSV,
+		Data:                      strings.NewReader("hardware_id\nhw-201\nhw-existing\nhw-201\n"),
+		DryRun:                    true,
+		CreateEnrollmentTokens:    true,
+		EnrollmentTokenTTLSeconds: 0,
+	})
+	require.NoError(t, err)
+
+	assert.True(t, output.DryRun)
+	assert.Equal(t, []usecase.ImportRowStatus{
+		usecase.ImportRowCreated,
+		usecase.ImportRowDuplicate,
+		usecase.ImportRowDuplicate,
+	}, importRowStatuses(output))
+	assert.Nil(t, output.Rows[0].Device)
+	assert.Nil(t, output.Rows[0].EnrollmentToken)
+
+	_, err = deviceRepo.FindByHardwareID(ctx, "hw-201")
+	require.ErrorIs(t, err, entity.ErrDeviceNotFound)
+	assert.Empty(t, tokenRepo.tokens)
+	assert.Empty(t, auditLogger.Logs())
+}
+
+// TestImportDevicesErrors tests that files that cannot be read as a whole are rejected.
+func TestImportDevicesErrors(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		desc       string
+		format     usecase.ImportFormat
+		data       string
+		ttlSeconds int64
+		repoSetup  func(*FakeDeviceRepository)
+		wantErr    error
+	}{
+		{
+			name:       "failure: empty file",
+			desc:       "Verify that a CSV file without a header row is rejected.",
+			format:     usecase.ImportFormatCSV,
+			data:       "",
+			ttlSeconds: 0,
+			repoSetup:  nil,
+			wantErr:    usecase.ErrInvalidDeviceImport,
+		},
+		{
+			name:       "failure: missing hardware_id column",
+			desc:       "Verify that a CSV file without the hardware_id column is rejected.",
+			format:     usecase.ImportFormatCSV,
+			data:       "name\nSensor\n",
+			ttlSeconds: 0,
+			repoSetup:  nil,
+			wantErr:    usecase.ErrInvalidDeviceImport,
+		},
+		{
+			name:       "failure: unknown column",
+			desc:       "Verify that a misspelt column is not silently ignored.",
+			format:     usecase.ImportFormatCSV,
+			data:       "hardware_id,nmae\nhw-1,Sensor\n",
+			ttlSeconds: 0,
+			repoSetup:  nil,
+			wantErr:    usecase.ErrInvalidDeviceImport,
+		},
+		{
+			name:       "failure: malformed CSV",
+			desc:       "Verify that a CSV file with a bare quote is rejected.",
+			format:     usecase.ImportFormatCSV,
+			data:       "hardware_id,name\nhw-1,Se\"nsor\n",
+			ttlSeconds: 0,
+			repoSetup:  nil,
+			wantErr:    usecase.ErrInvalidDeviceImport,
+		},
+		{
+			name:       "failure: unsupported format",
+			desc:       "Verify that an unknown format is rejected.",
+			format:     usecase.ImportFormat("xlsx"),
+			data:       "",
+			ttlSeconds: 0,
+			repoSetup:  nil,
+			wantErr:    usecase.ErrInvalidDeviceImport,
+		},
+		{
+			name:       "failure: TTL above the maximum",
+			desc:       "Verify that the enrollment token TTL is bounded as for a single token.",
+			format:     usecase.ImportFormatNDJSON,
+			data:       `{"hardware_id": "hw-1"}`,
+			ttlSeconds: 3 * 3600,
+			repoSetup:  nil,
+			wantErr:    usecase.ErrInvalidTTL,
+		},
+		{
+			name:       "failure: repository save error",
+			desc:       "Verify that an error creating the devices fails the whole import.",
+			format:     usecase.ImportFormatNDJSON,
+			data:       `{"hardware_id": "hw-1"}`,
+			ttlSeconds: 0,
+			repoSetup: func(repo *FakeDeviceRepository) {
+				repo.SaveErr = assert.AnError
+			},
+			wantErr: usecase.ErrRepositorySave,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			uc, deviceRepo, _, auditLogger := newDeviceImportTest(t)
+			if tt.repoSetup != nil {
+				tt.repoSetup(deviceRepo)
+			}
+
+			output, err := uc.ImportDevices(ctx, usecase.ImportDevicesInput{
+				Format:                    tt.format,
+				Data:                      strings.NewReader(tt.data),
+				DryRun:                    false,
+				CreateEnrollmentTokens:    true,
+				EnrollmentTokenTTLSeconds: tt.ttlSeconds,
+			})
+			require.ErrorIs(t, err, tt.wantErr)
+			assert.Nil(t, output)
+			assert.Empty(t, auditLogger.Logs())
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/VO/connectionstate"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+const (
+	// DefaultDeviceHeartbeatInterval is the default time a connected device can go unheard before it is STALE.
+	DefaultDeviceHeartbeatInterval = 5 * time.Minute
+	// DefaultDevicePresenceSweepInterval is the default interval at which the devices gone unheard are flagged.
+	DefaultDevicePresenceSweepInterval = time.Minute
+	// DefaultDeviceActivityWriteInterval is the default minimum interval between two writes of the last-seen time
+	// of a device.
+	DefaultDeviceActivityWriteInterval = 30 * time.Second
+)
+
+// DevicePresenceConfig configures how the presence of devices is tracked.
+type DevicePresenceConfig struct {
+	// HeartbeatInterval is how long a CONNECTED device can go without being heard from before it is flagged
+	// as STALE. It should be longer than the MQTT keep alive of the devices, which ping at least that often.
+	HeartbeatInterval time.Duration
+	// SweepInterval is the interval at which the devices gone unheard are flagged as STALE.
+	SweepInterval time.Duration
+	// ActivityWriteInterval is the minimum interval between two writes of the last-seen time of a device,
+	// so that a device publishing often does not write it with every message.
+	// It is capped at half the heartbeat interval, so that an active device is never flagged.
+	ActivityWriteInterval time.Duration
+}
+
+// withDefaults returns the config with zero values replaced by the defaults.
+func (c DevicePresenceConfig) withDefaults() DevicePresenceConfig {
+	if c.HeartbeatInterval <= 0 {
+		c.HeartbeatInterval = DefaultDeviceHeartbeatInterval
+	}
+
+	if c.SweepInterval <= 0 {
+		c.SweepInterval = DefaultDevicePresenceSweepInterval
+	}
+
+	if c.ActivityWriteInterval <= 0 {
+		c.ActivityWriteInterval = DefaultDeviceActivityWriteInterval
+	}
+
+	c.ActivityWriteInterval = min(c.ActivityWriteInterval, c.HeartbeatInterval/2) //nolint:mnd
+
+	return c
+}
+
+// DevicePresenceUsecase defines the interface for tracking whether devices are online.
+type DevicePresenceUsecase interface {
+	// RecordConnect records that a device has connected, which makes it CONNECTED.
+	RecordConnect(ctx context.Context, input RecordDeviceConnectInput) error
+	// RecordDisconnect records that the connection of a device has closed, which makes it DISCONNECTED.
+	RecordDisconnect(ctx context.Context, deviceID uuid.UUID) error
+	// RecordActivity records that a device has been heard from, e.g. it published telemetry or pinged the broker,
+	// which makes it CONNECTED.
+	RecordActivity(ctx context.Context, deviceID uuid.UUID) error
+	// Run flags the CONNECTED devices not heard from within the heartbeat interval as STALE,
+	// at every sweep interval, until ctx is done.
+	Run(ctx context.Context)
+}
+
+// devicePresenceUsecase is the implementation of the DevicePresenceUsecase interface.
+type devicePresenceUsecase struct {
+	deviceRepo repository.DeviceRepository
+	config     DevicePresenceConfig
+	now        func() time.Time
+
+	// mu guards lastWrites, the time the activity of each device was last written.
+	mu         sync.Mutex
+	lastWrites map[uuid.UUID]time.Time
+}
+
+// NewDevicePresenceUsecase creates a new instance of devicePresenceUsecase.
+// Zero values in config are replaced by the defaults.
+//
+//nolint:ireturn
+func NewDevicePresenceUsecase(
+	deviceRepo repository.DeviceRepository,
+	config DevicePresenceConfig,
+) DevicePresenceUsecase {
+	return &devicePresenceUsecase{
+		deviceRepo: deviceRepo,
+		config:     config.withDefaults(),
+		now:        time.Now,
+		mu:         sync.Mutex{},
+		lastWrites: make(map[uuid.UUID]time.Time),
+	}
+}
+
+// RecordConnect records that a device has connected from an IP address.
+// Presence is not audited, as it is observed rather than changed by anyone.
+func (uc *devicePresenceUsecase) RecordConnect(ctx context.Context, input RecordDeviceConnectInput) error {
+	now := uc.now()
+
+	err := uc.record(ctx, input.DeviceID, repository.DevicePresence{
+		State:  connectionstate.Connected,
+		SeenAt: &now,
+		IP:     input.IP,
+	})
+	if err != nil {
+		return err
+	}
+
+	uc.mu.Lock()
+	uc.lastWrites[input.DeviceID] = now
+	uc.mu.Unlock()
+
+	return nil
+}
+
+// RecordDisconnect records that the connection of a device has closed.
+// The last-seen time is left alone: it is the last time the device itself was heard from.
+func (uc *devicePresenceUsecase) RecordDisconnect(ctx context.Context, deviceID uuid.UUID) error {
+	uc.mu.Lock()
+	delete(uc.lastWrites, deviceID)
+	uc.mu.Unlock()
+
+	return uc.record(ctx, deviceID, repository.DevicePresence{
+		State:  connectionstate.Disconnected,
+		SeenAt: nil,
+		IP:     "",
+	})
+}
+
+// RecordActivity records that a device has been heard from. The activity is only written
+// if it has not been written within the activity write interval, so most messages cost no write.
+func (uc *devicePresenceUsecase) RecordActivity(ctx context.Context, deviceID uuid.UUID) error {
+	now := uc.now()
+
+	uc.mu.Lock()
+
+	lastWrite, ok := uc.lastWrites[deviceID]
+	if ok && now.Sub(lastWrite) < uc.config.ActivityWriteInterval {
+		uc.mu.Unlock()
+
+		return nil
+	}
+
+	uc.lastWrites[deviceID] = now
+	uc.mu.Unlock()
+
+	err := uc.record(ctx, deviceID, repository.DevicePresence{
+		State:  connectionstate.Connected,
+		SeenAt: &now,
+		IP:     "",
+	})
+	if err != nil {
+		// The activity is written again with the next message.
+		uc.mu.Lock()
+		delete(uc.lastWrites, deviceID)
+		uc.mu.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// Run flags the devices gone unheard as STALE at every sweep interval, until ctx is done.
+func (uc *devicePresenceUsecase) Run(ctx context.Context) {
+	ticker := time.NewTicker(uc.config.SweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		uc.sweep(ctx)
+	}
+}
+
+// sweep flags the CONNECTED devices last seen before the heartbeat interval as STALE,
+// and forgets the writes old enough not to throttle the activity anymore.
+func (uc *devicePresenceUsecase) sweep(ctx context.Context) {
+	now := uc.now()
+
+	_, err := uc.deviceRepo.MarkStale(ctx, now.Add(-uc.config.HeartbeatInterval))
+	if err != nil {
+		log.Printf("failed to mark stale devices: %v", err)
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	for deviceID, lastWrite := range uc.lastWrites {
+		if now.Sub(lastWrite) >= uc.config.ActivityWriteInterval {
+			delete(uc.lastWrites, deviceID)
+		}
+	}
+}
+
+// record writes the presence of a device.
+func (uc *devicePresenceUsecase) record(
+	ctx context.Context,
+	deviceID uuid.UUID,
+	presence repository.DevicePresence,
+) error {
+	err := uc.deviceRepo.RecordPresence(ctx, deviceID, presence)
+	if err != nil {
+		if isNotFound(err, entity.ErrDeviceNotFound) {
+			return entity.ErrDeviceNotFound
+		}
+
+		return fmt.Errorf("%w: %w", ErrDBDevicePresence, err)
+	}
+
+	return nil
+}
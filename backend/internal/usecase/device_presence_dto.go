@@ -0,0 +1,10 @@
+package usecase
+
+import "github.com/google/uuid"
+
+// RecordDeviceConnectInput is a connection of a device, as the broker accepted it.
+type RecordDeviceConnectInput struct {
+	DeviceID uuid.UUID
+	// IP is the IP address the device connected from. Optional: if empty, the last IP address is kept.
+	IP string
+}
@@ -0,0 +1,222 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/connectionstate"
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDevicePresenceUsecase creates a DevicePresenceUsecase with the default config.
+//
+//nolint:ireturn
+func newTestDevicePresenceUsecase(deviceRepo *FakeDeviceRepository) usecase.DevicePresenceUsecase {
+	return usecase.NewDevicePresenceUsecase(deviceRepo, usecase.DevicePresenceConfig{
+		HeartbeatInterval:     0,
+		SweepInterval:         0,
+		ActivityWriteInterval: 0,
+	})
+}
+
+// Presence returns the presence of a device in the in-memory store.
+func (r *FakeDeviceRepository) Presence(id uuid.UUID) (connectionstate.State, *time.Time, string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	device, ok := r.devices[id]
+	if !ok {
+		return "", nil, ""
+	}
+
+	return device.ConnectionState, device.LastSeenAt, device.LastIP
+}
+
+// setConnectionState changes the connection state of a device in the in-memory store.
+func (r *FakeDeviceRepository) setConnectionState(id uuid.UUID, state connectionstate.State) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.devices[id].ConnectionState = state
+}
+
+// TestRecordDeviceConnection tests the RecordConnect and RecordDisconnect methods.
+func TestRecordDeviceConnection(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("success: a connection makes the device connected from its IP address", func(t *testing.T) {
+		t.Parallel()
+
+		deviceRepo := NewFakeDeviceRepository()
+		device := newIngestionTestDevice(deviceRepo, devicestatus.Active)
+		uc := newTestDevicePresenceUsecase(deviceRepo)
+
+		before := time.Now()
+
+		err := uc.RecordConnect(ctx, usecase.RecordDeviceConnectInput{DeviceID: device.ID, IP: "192.0.2.10"})
+		require.NoError(t, err)
+
+		state, lastSeenAt, lastIP := deviceRepo.Presence(device.ID)
+		assert.Equal(t, connectionstate.Connected, state)
+		require.NotNil(t, lastSeenAt)
+		assert.False(t, lastSeenAt.Before(before))
+		assert.Equal(t, "192.0.2.10", lastIP)
+		assert.Equal(t, int64(1), device.Version, "presence does not change the version")
+	})
+
+	t.Run("success: a disconnection keeps the last-seen time and IP address", func(t *testing.T) {
+		t.Parallel()
+
+		deviceRepo := NewFakeDeviceRepository()
+		device := newIngestionTestDevice(deviceRepo, devicestatus.Active)
+		uc := newTestDevicePresenceUsecase(deviceRepo)
+
+		require.NoError(t, uc.RecordConnect(ctx, usecase.RecordDeviceConnectInput{DeviceID: device.ID, IP: "::1"}))
+		_, connectedAt, _ := deviceRepo.Presence(device.ID)
+
+		require.NoError(t, uc.RecordDisconnect(ctx, device.ID))
+
+		state, lastSeenAt, lastIP := deviceRepo.Presence(device.ID)
+		assert.Equal(t, connectionstate.Disconnected, state)
+		assert.Equal(t, connectedAt, lastSeenAt)
+		assert.Equal(t, "::1", lastIP)
+	})
+
+	t.Run("failure: unknown device", func(t *testing.T) {
+		t.Parallel()
+
+		uc := newTestDevicePresenceUsecase(NewFakeDeviceRepository())
+
+		err := uc.RecordConnect(ctx, usecase.RecordDeviceConnectInput{DeviceID: uuid.New(), IP: ""})
+		require.ErrorIs(t, err, entity.ErrDeviceNotFound)
+
+		err = uc.RecordDisconnect(ctx, uuid.New())
+		require.ErrorIs(t, err, entity.ErrDeviceNotFound)
+	})
+
+	t.Run("failure: database error", func(t *testing.T) {
+		t.Parallel()
+
+		deviceRepo := NewFakeDeviceRepository()
+		device := newIngestionTestDevice(deviceRepo, devicestatus.Active)
+		deviceRepo.PresenceErr = errors.New("connection refused")
+		uc := newTestDevicePresenceUsecase(deviceRepo)
+
+		err := uc.RecordConnect(ctx, usecase.RecordDeviceConnectInput{DeviceID: device.ID, IP: ""})
+		require.ErrorIs(t, err, usecase.ErrDBDevicePresence)
+	})
+}
+
+// TestRecordDeviceActivity tests that the RecordActivity method only writes once per activity write interval.
+func TestRecordDeviceActivity(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("success: the first activity is written, and the following ones are not", func(t *testing.T) {
+		t.Parallel()
+
+		deviceRepo := NewFakeDeviceRepository()
+		device := newIngestionTestDevice(deviceRepo, devicestatus.Active)
+		uc := newTestDevicePresenceUsecase(deviceRepo)
+
+		require.NoError(t, uc.RecordActivity(ctx, device.ID))
+
+		state, lastSeenAt, _ := deviceRepo.Presence(device.ID)
+		assert.Equal(t, connectionstate.Connected, state)
+		require.NotNil(t, lastSeenAt)
+
+		deviceRepo.setConnectionState(device.ID, connectionstate.Stale)
+		require.NoError(t, uc.RecordActivity(ctx, device.ID))
+
+		state, _, _ = deviceRepo.Presence(device.ID)
+		assert.Equal(t, connectionstate.Stale, state, "the activity within the write interval is not written")
+	})
+
+	t.Run("success: the activity after a disconnection is written", func(t *testing.T) {
+		t.Parallel()
+
+		deviceRepo := NewFakeDeviceRepository()
+		device := newIngestionTestDevice(deviceRepo, devicestatus.Active)
+		uc := newTestDevicePresenceUsecase(deviceRepo)
+
+		require.NoError(t, uc.RecordConnect(ctx, usecase.RecordDeviceConnectInput{DeviceID: device.ID, IP: ""}))
+		require.NoError(t, uc.RecordDisconnect(ctx, device.ID))
+		require.NoError(t, uc.RecordActivity(ctx, device.ID))
+
+		state, _, _ := deviceRepo.Presence(device.ID)
+		assert.Equal(t, connectionstate.Connected, state)
+	})
+
+	t.Run("success: an activity that failed to be written is written with the next one", func(t *testing.T) {
+		t.Parallel()
+
+		deviceRepo := NewFakeDeviceRepository()
+		device := newIngestionTestDevice(deviceRepo, devicestatus.Active)
+		uc := newTestDevicePresenceUsecase(deviceRepo)
+
+		deviceRepo.PresenceErr = errors.New("connection refused")
+		require.ErrorIs(t, uc.RecordActivity(ctx, device.ID), usecase.ErrDBDevicePresence)
+
+		deviceRepo.PresenceErr = nil
+		require.NoError(t, uc.RecordActivity(ctx, device.ID))
+
+		state, _, _ := deviceRepo.Presence(device.ID)
+		assert.Equal(t, connectionstate.Connected, state)
+	})
+}
+
+// TestDevicePresenceRun tests that the Run method flags the devices gone unheard as STALE.
+func TestDevicePresenceRun(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	deviceRepo := NewFakeDeviceRepository()
+	connected := newIngestionTestDevice(deviceRepo, devicestatus.Active)
+	disconnected := newIngestionTestDevice(deviceRepo, devicestatus.Active)
+	uc := usecase.NewDevicePresenceUsecase(deviceRepo, usecase.DevicePresenceConfig{
+		HeartbeatInterval:     50 * time.Millisecond,
+		SweepInterval:         10 * time.Millisecond,
+		ActivityWriteInterval: 0,
+	})
+
+	require.NoError(t, uc.RecordConnect(ctx, usecase.RecordDeviceConnectInput{DeviceID: connected.ID, IP: ""}))
+	require.NoError(t, uc.RecordConnect(ctx, usecase.RecordDeviceConnectInput{DeviceID: disconnected.ID, IP: ""}))
+	require.NoError(t, uc.RecordDisconnect(ctx, disconnected.ID))
+
+	runCtx, cancel := context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+
+	wg.Go(func() { uc.Run(runCtx) })
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	require.Eventually(t, func() bool {
+		state, _, _ := deviceRepo.Presence(connected.ID)
+
+		return state == connectionstate.Stale
+	}, time.Second, 10*time.Millisecond)
+
+	state, _, _ := deviceRepo.Presence(disconnected.ID)
+	assert.Equal(t, connectionstate.Disconnected, state, "a disconnected device is not flagged")
+
+	// The activity write interval is capped at half the heartbeat interval, so the activity is written again.
+	require.NoError(t, uc.RecordActivity(ctx, connected.ID))
+
+	state, _, _ = deviceRepo.Presence(connected.ID)
+	assert.Equal(t, connectionstate.Connected, state, "a stale device that is heard from again is connected")
+}
@@ -0,0 +1,278 @@
+package usecase
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+const (
+	// DefaultDeviceLimit is the number of devices returned when the query does not specify a limit.
+	DefaultDeviceLimit = 100
+	// MaxDeviceLimit is the maximum number of devices a query can request.
+	MaxDeviceLimit = 1000
+	// DefaultDeviceSort is the sort order used when the query does not specify one.
+	DefaultDeviceSort = "createdAt"
+	// MaxDeviceMetadataConditions is the maximum number of metadata conditions a query can have.
+	MaxDeviceMetadataConditions = 10
+)
+
+// metadataOperators are the comparisons of a metadata condition, longest first so that ">=" is not read as ">".
+var metadataOperators = []repository.MetadataOperator{ //nolint:gochecknoglobals
+	repository.MetadataGreaterOrEqual,
+	repository.MetadataLessOrEqual,
+	repository.MetadataNotEquals,
+	repository.MetadataEquals,
+	repository.MetadataGreater,
+	repository.MetadataLess,
+}
+
+// deviceSortFields maps the sort fields of the API to the sort fields of the repository.
+var deviceSortFields = map[string]repository.DeviceSortField{ //nolint:gochecknoglobals
+	"createdAt": repository.DeviceSortCreatedAt,
+	"updatedAt": repository.DeviceSortUpdatedAt,
+	"name":      repository.DeviceSortName,
+}
+
+var (
+	// errMetadataValueNotScalar is returned when the value of a metadata condition is a JSON object or array.
+	errMetadataValueNotScalar = errors.New("metadata value must be a number, a string, a boolean or null")
+	// errMetadataValueNotOrdered is returned when a boolean or null is compared by order.
+	errMetadataValueNotOrdered = errors.New("metadata value must be a number or a string to be compared by order")
+)
+
+// deviceCursor is the content of an opaque device cursor.
+// It records the sort order it was issued for, so that it is not used to page through another order.
+type deviceCursor struct {
+	SortBy     repository.DeviceSortField `json:"sortBy"`
+	Descending bool                       `json:"desc,omitempty"`
+	ID         uuid.UUID                  `json:"id"`
+	CreatedAt  time.Time                  `json:"createdAt"`
+	UpdatedAt  time.Time                  `json:"updatedAt"`
+	Name       string                     `json:"name,omitempty"`
+}
+
+// newDeviceQuery validates a device query and converts it to a repository query.
+func newDeviceQuery(input ListDevicesInput) (repository.DeviceQuery, error) {
+	query := repository.DeviceQuery{
+		Filter:     repository.DeviceFilter{}, //nolint:exhaustruct
+		SortBy:     repository.DeviceSortCreatedAt,
+		Descending: false,
+		After:      nil,
+		Limit:      input.Limit,
+	}
+
+	var err error
+
+	query.Filter, err = newDeviceFilter(input.Status, input.HardwareIDPrefix, input.NameContains, input.Metadata)
+	if err != nil {
+		return query, err
+	}
+
+	query.Filter.Online, query.Filter.LastSeenBefore = input.Online, input.LastSeenBefore
+
+	query.SortBy, query.Descending, err = parseDeviceSort(input.Sort)
+	if err != nil {
+		return query, err
+	}
+
+	if query.Limit == 0 {
+		query.Limit = DefaultDeviceLimit
+	}
+
+	if query.Limit < 0 || query.Limit > MaxDeviceLimit {
+		return query, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidDeviceQuery, MaxDeviceLimit)
+	}
+
+	if input.Cursor != "" {
+		after, err := decodeDeviceCursor(input.Cursor, query)
+		if err != nil {
+			return query, err
+		}
+
+		query.After = after
+	}
+
+	return query, nil
+}
+
+// newDeviceFilter validates the filters of a device query and converts them to a repository filter.
+func newDeviceFilter(
+	status, hardwareIDPrefix, nameContains string,
+	metadata []string,
+) (repository.DeviceFilter, error) {
+	filter := repository.DeviceFilter{
+		Status:           "",
+		HardwareIDPrefix: hardwareIDPrefix,
+		NameContains:     nameContains,
+		Metadata:         nil,
+		Group:            nil,
+		ParentID:         nil,
+		Online:           nil,
+		LastSeenBefore:   nil,
+	}
+
+	if status != "" {
+		parsed, err := devicestatus.Parse(status)
+		if err != nil {
+			return filter, fmt.Errorf("%w: unknown status %q", ErrInvalidDeviceQuery, status)
+		}
+
+		filter.Status = parsed
+	}
+
+	if len(metadata) > MaxDeviceMetadataConditions {
+		return filter, fmt.Errorf(
+			"%w: at most %d metadata conditions are allowed", ErrInvalidDeviceQuery, MaxDeviceMetadataConditions,
+		)
+	}
+
+	for _, expression := range metadata {
+		condition, err := parseMetadataCondition(expression)
+		if err != nil {
+			return filter, err
+		}
+
+		filter.Metadata = append(filter.Metadata, condition)
+	}
+
+	return filter, nil
+}
+
+// parseDeviceSort reads a sort order such as "name" or "-createdAt", and returns its field and direction.
+// An empty sort order is DefaultDeviceSort.
+func parseDeviceSort(sort string) (repository.DeviceSortField, bool, error) {
+	if sort == "" {
+		sort = DefaultDeviceSort
+	}
+
+	sortBy, ok := deviceSortFields[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return "", false, fmt.Errorf("%w: unknown sort order %q", ErrInvalidDeviceQuery, sort)
+	}
+
+	return sortBy, strings.HasPrefix(sort, "-"), nil
+}
+
+// parseMetadataCondition parses a condition on the metadata, such as "location.building=Factory-A",
+// "firmware.version>=2.4", "location" (the path exists) or "!location" (the path does not exist).
+func parseMetadataCondition(expression string) (repository.MetadataCondition, error) {
+	condition := repository.MetadataCondition{Path: nil, Operator: repository.MetadataExists, Value: nil}
+	path := expression
+
+	index := strings.IndexAny(expression, "<>!=")
+
+	switch {
+	case index == 0 && strings.HasPrefix(expression, "!") && !strings.ContainsAny(expression[1:], "<>!="):
+		condition.Operator = repository.MetadataNotExists
+		path = expression[1:]
+	case index >= 0:
+		path = expression[:index]
+		rest := expression[index:]
+
+		for _, operator := range metadataOperators {
+			if strings.HasPrefix(rest, string(operator)) {
+				condition.Operator = operator
+
+				break
+			}
+		}
+
+		if condition.Operator == repository.MetadataExists {
+			return condition, fmt.Errorf("%w: unknown metadata operator in %q", ErrInvalidDeviceQuery, expression)
+		}
+
+		value, err := parseMetadataValue(rest[len(condition.Operator):], condition.Operator.IsOrdering())
+		if err != nil {
+			return condition, fmt.Errorf("%w: %w in %q", ErrInvalidDeviceQuery, err, expression)
+		}
+
+		condition.Value = value
+	}
+
+	condition.Path = strings.Split(path, ".")
+	if slices.Contains(condition.Path, "") {
+		return condition, fmt.Errorf("%w: invalid metadata path in %q", ErrInvalidDeviceQuery, expression)
+	}
+
+	return condition, nil
+}
+
+// parseMetadataValue reads the value of a metadata condition.
+// A JSON number, boolean, null or quoted string is used as such; anything else is a plain string.
+// Ordering comparisons only accept numbers and strings.
+func parseMetadataValue(raw string, ordering bool) (any, error) {
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+
+	var value any
+
+	err := decoder.Decode(&value)
+	if err != nil || decoder.More() {
+		return raw, nil
+	}
+
+	switch value.(type) {
+	case json.Number, string:
+		return value, nil
+	case bool, nil:
+		if ordering {
+			return nil, errMetadataValueNotOrdered
+		}
+
+		return value, nil
+	default:
+		return nil, errMetadataValueNotScalar
+	}
+}
+
+// encodeDeviceCursor returns the opaque cursor of the page that starts after the device.
+func encodeDeviceCursor(query repository.DeviceQuery, device *entity.Device) string {
+	// Marshaling cannot fail, because the cursor only has strings, times and UUIDs.
+	data, _ := json.Marshal(deviceCursor{ //nolint:errchkjson
+		SortBy:     query.SortBy,
+		Descending: query.Descending,
+		ID:         device.ID,
+		CreatedAt:  device.CreatedAt,
+		UpdatedAt:  device.UpdatedAt,
+		Name:       device.Name,
+	})
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeDeviceCursor decodes an opaque cursor and checks that it was issued for the sort order of the query.
+func decodeDeviceCursor(cursor string, query repository.DeviceQuery) (*repository.DeviceCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidDeviceQuery)
+	}
+
+	var decoded deviceCursor
+
+	err = json.Unmarshal(data, &decoded)
+	if err != nil || decoded.ID == uuid.Nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidDeviceQuery)
+	}
+
+	if decoded.SortBy != query.SortBy || decoded.Descending != query.Descending {
+		return nil, fmt.Errorf("%w: cursor was issued for another sort order", ErrInvalidDeviceQuery)
+	}
+
+	return &repository.DeviceCursor{
+		ID:        decoded.ID,
+		CreatedAt: decoded.CreatedAt,
+		UpdatedAt: decoded.UpdatedAt,
+		Name:      decoded.Name,
+	}, nil
+}
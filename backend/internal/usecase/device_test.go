@@ -2,12 +2,18 @@ package usecase_test
 
 import (
 	"context"
+	"encoding/json"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"backend/internal/domain/VO/connectionstate"
+	"backend/internal/domain/VO/devicestatus"
 	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
 	"backend/internal/usecase"
 
 	"github.com/google/uuid"
@@ -20,25 +26,57 @@ type FakeDeviceRepository struct {
 	mu      sync.RWMutex
 	devices map[uuid.UUID]*entity.Device
 	// for controlling error case
-	SaveErr    error
-	FindErr    error
-	FindAllErr error
-	DeleteErr  error
+	SaveErr     error
+	FindErr     error
+	QueryErr    error
+	DeleteErr   error
+	PresenceErr error
+	// lastFilter is the filter of the last query. Metadata conditions are only translated by the real repository,
+	// so the fake records them instead of evaluating them.
+	lastFilter repository.DeviceFilter
+	// groups holds the devices added to groups, if a FakeDeviceGroupRepository has been created for the fake.
+	// Like the metadata conditions, the rules of the groups are not evaluated.
+	groups *FakeDeviceGroupRepository
 }
 
 // NewFakeDeviceRepository creates a new FakeDeviceRepository.
 func NewFakeDeviceRepository() *FakeDeviceRepository {
 	return &FakeDeviceRepository{
-		mu:         sync.RWMutex{},
-		devices:    make(map[uuid.UUID]*entity.Device),
-		SaveErr:    nil,
-		FindErr:    nil,
-		FindAllErr: nil,
-		DeleteErr:  nil,
+		mu:          sync.RWMutex{},
+		devices:     make(map[uuid.UUID]*entity.Device),
+		SaveErr:     nil,
+		FindErr:     nil,
+		QueryErr:    nil,
+		DeleteErr:   nil,
+		PresenceErr: nil,
+		lastFilter:  repository.DeviceFilter{}, //nolint:exhaustruct
+		groups:      nil,
 	}
 }
 
-// Save adds or updates a device in the in-memory store.
+// newTestDevice creates a device with the hardware ID and status, as provisioned just now and never connected.
+// The tests change the fields they depend on, and store it in a FakeDeviceRepository.
+func newTestDevice(hardwareID string, status devicestatus.Status) *entity.Device {
+	now := time.Now()
+
+	return &entity.Device{
+		ID:              uuid.New(),
+		HardwareID:      hardwareID,
+		Name:            "",
+		Status:          status,
+		Metadata:        nil,
+		Version:         1,
+		ParentID:        nil,
+		ConnectionState: connectionstate.Disconnected,
+		LastSeenAt:      nil,
+		LastIP:          "",
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+}
+
+// Save adds or updates a device in the in-memory store, and increments its version.
+// The stored devices are shared with the callers, so concurrent updates are not detected.
 func (r *FakeDeviceRepository) Save(_ context.Context, device *entity.Device) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -49,13 +87,27 @@ func (r *FakeDeviceRepository) Save(_ context.Context, device *entity.Device) er
 
 	if device.ID == uuid.Nil {
 		device.ID = uuid.New()
+		device.Version = 0
 	}
 
+	device.Version++
 	r.devices[device.ID] = device
 
 	return nil
 }
 
+// CreateAll adds new devices to the in-memory store.
+func (r *FakeDeviceRepository) CreateAll(ctx context.Context, devices []*entity.Device) error {
+	for _, device := range devices {
+		err := r.Save(ctx, device)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // FindByID retrieves a device by its ID from the in-memory store.
 func (r *FakeDeviceRepository) FindByID(_ context.Context, id uuid.UUID) (*entity.Device, error) {
 	r.mu.RLock()
@@ -91,25 +143,286 @@ func (r *FakeDeviceRepository) FindByHardwareID(_ context.Context, hardwareID st
 	return nil, entity.ErrDeviceNotFound
 }
 
-// FindAll retrieves all devices from the in-memory store.
-func (r *FakeDeviceRepository) FindAll(_ context.Context) ([]*entity.Device, error) {
+// FindByHardwareIDs retrieves the devices with any of the hardware IDs from the in-memory store.
+func (r *FakeDeviceRepository) FindByHardwareIDs(_ context.Context, hardwareIDs []string) ([]*entity.Device, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.FindErr != nil {
+		return nil, r.FindErr
+	}
+
+	var devices []*entity.Device
+
+	for _, device := range r.devices {
+		if slices.Contains(hardwareIDs, device.HardwareID) {
+			devices = append(devices, device)
+		}
+	}
+
+	return devices, nil
+}
+
+// FindByQuery retrieves a page of the devices matching the query from the in-memory store.
+func (r *FakeDeviceRepository) FindByQuery(_ context.Context, query repository.DeviceQuery) ([]*entity.Device, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.QueryErr != nil {
+		return nil, r.QueryErr
+	}
+
+	r.lastFilter = query.Filter
+
+	devices := r.filter(query.Filter)
+	sort.Slice(devices, func(i, j int) bool {
+		return compareDevices(query, devices[i], devices[j]) < 0
+	})
+
+	if query.After != nil {
+		after := &entity.Device{ //nolint:exhaustruct
+			ID:        query.After.ID,
+			Name:      query.After.Name,
+			CreatedAt: query.After.CreatedAt,
+			UpdatedAt: query.After.UpdatedAt,
+		}
+		start := sort.Search(len(devices), func(i int) bool {
+			return compareDevices(query, devices[i], after) > 0
+		})
+		devices = devices[start:]
+	}
+
+	if query.Limit > 0 && len(devices) > query.Limit {
+		devices = devices[:query.Limit]
+	}
+
+	return devices, nil
+}
+
+// FindEach calls fn with each of the devices matching the query from the in-memory store.
+func (r *FakeDeviceRepository) FindEach(
+	ctx context.Context,
+	query repository.DeviceQuery,
+	fn func(device *entity.Device) error,
+) error {
+	devices, err := r.FindByQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	for _, device := range devices {
+		err = fn(device)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Count returns the number of devices matching the filter in the in-memory store.
+func (r *FakeDeviceRepository) Count(_ context.Context, filter repository.DeviceFilter) (int64, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	if r.FindAllErr != nil {
-		return nil, r.FindAllErr
+	if r.QueryErr != nil {
+		return 0, r.QueryErr
 	}
 
+	return int64(len(r.filter(filter))), nil
+}
+
+// FindAncestors retrieves the ancestors of a device from the in-memory store, from its parent up to the root.
+func (r *FakeDeviceRepository) FindAncestors(_ context.Context, id uuid.UUID) ([]*entity.Device, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.QueryErr != nil {
+		return nil, r.QueryErr
+	}
+
+	var ancestors []*entity.Device
+
+	device, ok := r.devices[id]
+	for ok && device.ParentID != nil && len(ancestors) <= len(r.devices) {
+		device, ok = r.devices[*device.ParentID]
+		if ok {
+			ancestors = append(ancestors, device)
+		}
+	}
+
+	return ancestors, nil
+}
+
+// LockTopology does nothing, as the tests do not attach devices concurrently.
+func (r *FakeDeviceRepository) LockTopology(context.Context) error {
+	return nil
+}
+
+// LastFilter returns the filter of the last query.
+func (r *FakeDeviceRepository) LastFilter() repository.DeviceFilter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.lastFilter
+}
+
+// filter returns the devices matching the filter, except for the metadata conditions and the group rules.
+// The caller must hold the lock.
+func (r *FakeDeviceRepository) filter(filter repository.DeviceFilter) []*entity.Device {
 	devices := make([]*entity.Device, 0, len(r.devices))
+
 	for _, device := range r.devices {
+		if filter.Status != "" && device.Status != filter.Status {
+			continue
+		}
+
+		if !strings.HasPrefix(device.HardwareID, filter.HardwareIDPrefix) {
+			continue
+		}
+
+		if !strings.Contains(strings.ToLower(device.Name), strings.ToLower(filter.NameContains)) {
+			continue
+		}
+
+		if filter.Group != nil && (r.groups == nil || !r.groups.IsMember(filter.Group.GroupID, device.ID)) {
+			continue
+		}
+
+		if filter.ParentID != nil && (device.ParentID == nil || *device.ParentID != *filter.ParentID) {
+			continue
+		}
+
+		if filter.Online != nil && device.ConnectionState.IsOnline() != *filter.Online {
+			continue
+		}
+
+		if filter.LastSeenBefore != nil && device.LastSeenAt != nil &&
+			!device.LastSeenAt.Before(*filter.LastSeenBefore) {
+			continue
+		}
+
+		if !matchesMetadataEquality(device.Metadata, filter.Metadata) {
+			continue
+		}
+
 		devices = append(devices, device)
 	}
 
-	return devices, nil
+	return devices
 }
 
-// Delete removes a device from the in-memory store.
-func (r *FakeDeviceRepository) Delete(_ context.Context, id uuid.UUID) error {
+// matchesMetadataEquality reports whether the metadata satisfies the equality conditions on string values.
+// The other conditions are left to the integration tests of the repository, and always match.
+func matchesMetadataEquality(metadata entity.JSONBMap, conditions []repository.MetadataCondition) bool {
+	for _, condition := range conditions {
+		want, ok := condition.Value.(string)
+		if !ok {
+			continue
+		}
+
+		var value any = map[string]any(metadata)
+		for _, key := range condition.Path {
+			object, _ := value.(map[string]any)
+			value = object[key]
+		}
+
+		switch condition.Operator {
+		case repository.MetadataEquals:
+			if value != want {
+				return false
+			}
+		case repository.MetadataNotEquals:
+			if value == want {
+				return false
+			}
+		default:
+		}
+	}
+
+	return true
+}
+
+// compareDevices compares two devices in the sort order of the query, breaking ties by ID.
+func compareDevices(query repository.DeviceQuery, a, b *entity.Device) int {
+	var result int
+
+	switch query.SortBy {
+	case repository.DeviceSortName:
+		result = strings.Compare(a.Name, b.Name)
+	case repository.DeviceSortUpdatedAt:
+		result = a.UpdatedAt.Compare(b.UpdatedAt)
+	default:
+		result = a.CreatedAt.Compare(b.CreatedAt)
+	}
+
+	if result == 0 {
+		result = strings.Compare(a.ID.String(), b.ID.String())
+	}
+
+	if query.Descending {
+		return -result
+	}
+
+	return result
+}
+
+// RecordPresence updates the presence of a device in the in-memory store, leaving its version alone.
+func (r *FakeDeviceRepository) RecordPresence(
+	_ context.Context,
+	id uuid.UUID,
+	presence repository.DevicePresence,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.PresenceErr != nil {
+		return r.PresenceErr
+	}
+
+	device, ok := r.devices[id]
+	if !ok {
+		return entity.ErrDeviceNotFound
+	}
+
+	device.ConnectionState = presence.State
+
+	if presence.SeenAt != nil {
+		seenAt := *presence.SeenAt
+		device.LastSeenAt = &seenAt
+	}
+
+	if presence.IP != "" {
+		device.LastIP = presence.IP
+	}
+
+	return nil
+}
+
+// MarkStale marks the CONNECTED devices of the in-memory store last seen before seenBefore as STALE.
+func (r *FakeDeviceRepository) MarkStale(_ context.Context, seenBefore time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.PresenceErr != nil {
+		return 0, r.PresenceErr
+	}
+
+	var marked int64
+
+	for _, device := range r.devices {
+		if device.ConnectionState == connectionstate.Connected &&
+			device.LastSeenAt != nil && device.LastSeenAt.Before(seenBefore) {
+			device.ConnectionState = connectionstate.Stale
+			marked++
+		}
+	}
+
+	return marked, nil
+}
+
+// Delete removes a device from the in-memory store, if it is at the given version.
+func (r *FakeDeviceRepository) Delete(_ context.Context, id uuid.UUID, version int64) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -117,10 +430,15 @@ func (r *FakeDeviceRepository) Delete(_ context.Context, id uuid.UUID) error {
 		return r.DeleteErr
 	}
 
-	if _, ok := r.devices[id]; !ok {
+	device, ok := r.devices[id]
+	if !ok {
 		return entity.ErrDeviceNotFound
 	}
 
+	if device.Version != version {
+		return &entity.VersionConflictError{ID: id, Expected: version, Actual: device.Version}
+	}
+
 	delete(r.devices, id)
 
 	return nil
@@ -154,6 +472,7 @@ func TestCreateDevice(t *testing.T) {
 				ID:         uuid.Nil, // ID is generated by the repository
 				HardwareID: "hw-create-001",
 				Name:       "Test Device C",
+				Status:     devicestatus.Unregistered.String(),
 				Metadata:   map[string]any{"os": "linux"},
 				CreatedAt:  time.Time{}, // Not checked in this test
 				UpdatedAt:  time.Time{}, // Not checked in this test
@@ -187,7 +506,8 @@ func TestCreateDevice(t *testing.T) {
 				tt.repoSetup(fakeRepo)
 			}
 
-			uc := usecase.NewDeviceUsecase(fakeRepo)
+			auditLogger := NewFakeAuditLogger()
+			uc := usecase.NewDeviceUsecase(fakeRepo, auditLogger, FakeTransactor{})
 
 			got, err := uc.CreateDevice(ctx, tt.input)
 
@@ -212,6 +532,16 @@ func TestCreateDevice(t *testing.T) {
 				require.NoError(t, findErr)
 				require.NotNil(t, savedDevice)
 				require.Equal(t, got.ID, savedDevice.ID)
+
+				// The creation is recorded with the new device as the after state.
+				logs := auditLogger.Logs()
+				require.Len(t, logs, 1)
+				require.Equal(t, entity.AuditDeviceCreate, logs[0].Action)
+				require.Equal(t, &got.ID, logs[0].TargetDeviceID)
+
+				details := auditDetails(t, logs[0])
+				require.Nil(t, details.Before)
+				require.Equal(t, tt.wantOutput.HardwareID, details.After["hardwareId"])
 			}
 		})
 	}
@@ -224,12 +554,18 @@ func TestGetDevice(t *testing.T) {
 	ctx := context.Background()
 
 	existingDevice := &entity.Device{
-		ID:         uuid.New(),
-		HardwareID: "hw-get-001",
-		Name:       "Test Device G",
-		Metadata:   map[string]any{"status": "active"},
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		ID:              uuid.New(),
+		HardwareID:      "hw-get-001",
+		Name:            "Test Device G",
+		Status:          devicestatus.Unregistered,
+		Metadata:        map[string]any{"status": "active"},
+		Version:         1,
+		ParentID:        nil,
+		ConnectionState: connectionstate.Disconnected,
+		LastSeenAt:      nil,
+		LastIP:          "",
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
 	}
 
 	tests := []struct {
@@ -252,6 +588,7 @@ func TestGetDevice(t *testing.T) {
 				ID:         existingDevice.ID,
 				HardwareID: existingDevice.HardwareID,
 				Name:       existingDevice.Name,
+				Status:     existingDevice.Status.String(),
 				Metadata:   existingDevice.Metadata,
 				CreatedAt:  existingDevice.CreatedAt,
 				UpdatedAt:  existingDevice.UpdatedAt,
@@ -290,7 +627,7 @@ func TestGetDevice(t *testing.T) {
 				tt.repoSetup(fakeRepo)
 			}
 
-			uc := usecase.NewDeviceUsecase(fakeRepo)
+			uc := usecase.NewDeviceUsecase(fakeRepo, NewFakeAuditLogger(), FakeTransactor{})
 
 			got, err := uc.GetDevice(ctx, tt.deviceID)
 
@@ -319,65 +656,171 @@ func TestListDevices(t *testing.T) {
 	t.Parallel()
 
 	ctx := context.Background()
-
-	device1 := &entity.Device{
-		ID:         uuid.New(),
-		HardwareID: "hw-list-001",
-		Name:       "Device 1",
-		Metadata:   nil,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+	base := time.Now().Add(-time.Hour)
+
+	newListDevice := func(hardwareID, name string, status devicestatus.Status, age int) *entity.Device {
+		return &entity.Device{
+			ID:              uuid.New(),
+			HardwareID:      hardwareID,
+			Name:            name,
+			Status:          status,
+			Metadata:        nil,
+			Version:         1,
+			ParentID:        nil,
+			ConnectionState: connectionstate.Disconnected,
+			LastSeenAt:      nil,
+			LastIP:          "",
+			CreatedAt:       base.Add(time.Duration(age) * time.Minute),
+			UpdatedAt:       base.Add(time.Duration(10-age) * time.Minute),
+		}
 	}
-	device2 := &entity.Device{
-		ID:         uuid.New(),
-		HardwareID: "hw-list-002",
-		Name:       "Device 2",
-		Metadata:   nil,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+	sensorA := newListDevice("hw-list-001", "Sensor Alpha", devicestatus.Active, 1)
+	sensorB := newListDevice("hw-list-002", "sensor beta", devicestatus.Unregistered, 2)
+	gateway := newListDevice("gw-list-001", "Gateway", devicestatus.Active, 3)
+	sensorC := newListDevice("hw-list_003", "Sensor Gamma", devicestatus.Suspended, 4)
+
+	outputs := func(devices ...*entity.Device) []*usecase.DeviceOutput {
+		result := make([]*usecase.DeviceOutput, 0, len(devices))
+		for _, device := range devices {
+			result = append(result, usecase.NewDeviceOutput(device))
+		}
+
+		return result
 	}
 
 	tests := []struct {
-		name          string
-		desc          string
-		repoSetup     func(*FakeDeviceRepository)
-		wantOutput    []*usecase.DeviceOutput
-		wantErr       bool
-		wantErrString string
+		name      string
+		desc      string
+		repoSetup func(*FakeDeviceRepository)
+		input     usecase.ListDevicesInput
+		want      []*usecase.DeviceOutput
+		wantTotal int64
+		wantNext  bool
+		wantErr   error
 	}{
 		{
-			name: "success: list all devices",
-			desc: "Verify that all existing devices are listed correctly when multiple devices exist.",
-			repoSetup: func(repo *FakeDeviceRepository) {
-				repo.devices[device1.ID] = device1
-				repo.devices[device2.ID] = device2
-			},
-			wantOutput: []*usecase.DeviceOutput{
-				usecase.NewDeviceOutput(device1),
-				usecase.NewDeviceOutput(device2),
-			},
-			wantErr:       false,
-			wantErrString: "",
+			name:      "success: default order is creation time",
+			desc:      "Verify that all devices are listed oldest first when no query is given.",
+			repoSetup: nil,
+			input:     usecase.ListDevicesInput{}, //nolint:exhaustruct
+			want:      outputs(sensorA, sensorB, gateway, sensorC),
+			wantTotal: 4,
+			wantNext:  false,
+			wantErr:   nil,
+		},
+		{
+			name:      "success: sort by name descending",
+			desc:      "Verify that a sort field prefixed with - sorts in descending order.",
+			repoSetup: nil,
+			input:     usecase.ListDevicesInput{Sort: "-name"}, //nolint:exhaustruct
+			want:      outputs(sensorB, sensorC, sensorA, gateway),
+			wantTotal: 4,
+			wantNext:  false,
+			wantErr:   nil,
+		},
+		{
+			name:      "success: sort by update time",
+			desc:      "Verify that devices can be sorted by their last update time.",
+			repoSetup: nil,
+			input:     usecase.ListDevicesInput{Sort: "updatedAt"}, //nolint:exhaustruct
+			want:      outputs(sensorC, gateway, sensorB, sensorA),
+			wantTotal: 4,
+			wantNext:  false,
+			wantErr:   nil,
+		},
+		{
+			name:      "success: filter by status",
+			desc:      "Verify that only devices in the given status are listed and counted.",
+			repoSetup: nil,
+			input:     usecase.ListDevicesInput{Status: "ACTIVE"}, //nolint:exhaustruct
+			want:      outputs(sensorA, gateway),
+			wantTotal: 2,
+			wantNext:  false,
+			wantErr:   nil,
+		},
+		{
+			name:      "success: filter by hardware ID prefix and name",
+			desc:      "Verify that the hardware ID prefix and the case-insensitive name substring are combined.",
+			repoSetup: nil,
+			input:     usecase.ListDevicesInput{HardwareIDPrefix: "hw-", NameContains: "SENSOR B"}, //nolint:exhaustruct
+			want:      outputs(sensorB),
+			wantTotal: 1,
+			wantNext:  false,
+			wantErr:   nil,
+		},
+		{
+			name:      "success: limit returns a next cursor",
+			desc:      "Verify that a limited page has a next cursor and the total count of all pages.",
+			repoSetup: nil,
+			input:     usecase.ListDevicesInput{Limit: 3}, //nolint:exhaustruct
+			want:      outputs(sensorA, sensorB, gateway),
+			wantTotal: 4,
+			wantNext:  true,
+			wantErr:   nil,
 		},
 		{
 			name: "success: no devices exist",
 			desc: "Verify that an empty list is returned when no devices exist.",
 			repoSetup: func(repo *FakeDeviceRepository) {
-				// No devices in repo
+				repo.devices = make(map[uuid.UUID]*entity.Device)
 			},
-			wantOutput:    []*usecase.DeviceOutput{},
-			wantErr:       false,
-			wantErrString: "",
+			input:     usecase.ListDevicesInput{}, //nolint:exhaustruct
+			want:      []*usecase.DeviceOutput{},
+			wantTotal: 0,
+			wantNext:  false,
+			wantErr:   nil,
+		},
+		{
+			name:      "failure: unknown status",
+			desc:      "Verify that an unknown status is rejected.",
+			repoSetup: nil,
+			input:     usecase.ListDevicesInput{Status: "active"}, //nolint:exhaustruct
+			want:      nil,
+			wantTotal: 0,
+			wantNext:  false,
+			wantErr:   usecase.ErrInvalidDeviceQuery,
+		},
+		{
+			name:      "failure: unknown sort field",
+			desc:      "Verify that an unknown sort field is rejected.",
+			repoSetup: nil,
+			input:     usecase.ListDevicesInput{Sort: "hardwareId"}, //nolint:exhaustruct
+			want:      nil,
+			wantTotal: 0,
+			wantNext:  false,
+			wantErr:   usecase.ErrInvalidDeviceQuery,
+		},
+		{
+			name:      "failure: limit too large",
+			desc:      "Verify that a limit above the maximum is rejected.",
+			repoSetup: nil,
+			input:     usecase.ListDevicesInput{Limit: usecase.MaxDeviceLimit + 1}, //nolint:exhaustruct
+			want:      nil,
+			wantTotal: 0,
+			wantNext:  false,
+			wantErr:   usecase.ErrInvalidDeviceQuery,
+		},
+		{
+			name:      "failure: malformed cursor",
+			desc:      "Verify that a cursor that was not issued by the API is rejected.",
+			repoSetup: nil,
+			input:     usecase.ListDevicesInput{Cursor: "not-a-cursor"}, //nolint:exhaustruct
+			want:      nil,
+			wantTotal: 0,
+			wantNext:  false,
+			wantErr:   usecase.ErrInvalidDeviceQuery,
 		},
 		{
 			name: "failure: repository returns an error",
-			desc: "Verify that the use case returns an error when the repository's FindAll method returns an error.",
+			desc: "Verify that the use case returns an error when the repository fails to query the devices.",
 			repoSetup: func(repo *FakeDeviceRepository) {
-				repo.FindAllErr = assert.AnError
+				repo.QueryErr = assert.AnError
 			},
-			wantOutput:    nil,
-			wantErr:       true,
-			wantErrString: assert.AnError.Error(),
+			input:     usecase.ListDevicesInput{}, //nolint:exhaustruct
+			want:      nil,
+			wantTotal: 0,
+			wantNext:  false,
+			wantErr:   usecase.ErrDBFindAll,
 		},
 	}
 
@@ -386,33 +829,176 @@ func TestListDevices(t *testing.T) {
 			t.Parallel()
 
 			fakeRepo := NewFakeDeviceRepository()
+			for _, device := range []*entity.Device{sensorA, sensorB, gateway, sensorC} {
+				fakeRepo.devices[device.ID] = device
+			}
+
 			if tt.repoSetup != nil {
 				tt.repoSetup(fakeRepo)
 			}
 
-			uc := usecase.NewDeviceUsecase(fakeRepo)
+			uc := usecase.NewDeviceUsecase(fakeRepo, NewFakeAuditLogger(), FakeTransactor{})
 
-			got, err := uc.ListDevices(ctx)
+			got, err := uc.ListDevices(ctx, tt.input)
 
-			if tt.wantErr {
-				require.Error(t, err)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
 
-				if tt.wantErrString != "" {
-					require.Contains(t, err.Error(), tt.wantErrString)
-				}
+				return
+			}
 
-				require.Nil(t, got)
-			} else {
-				require.NoError(t, err)
-				// Sort slices for consistent comparison
-				sort.Slice(got, func(i, j int) bool {
-					return got[i].ID.String() < got[j].ID.String()
-				})
-				sort.Slice(tt.wantOutput, func(i, j int) bool {
-					return tt.wantOutput[i].ID.String() < tt.wantOutput[j].ID.String()
-				})
-				require.Equal(t, tt.wantOutput, got)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Devices)
+			assert.Equal(t, tt.wantTotal, got.TotalCount)
+			assert.Equal(t, tt.wantNext, got.NextCursor != "")
+		})
+	}
+
+	t.Run("success: pages through all devices with the cursor", func(t *testing.T) {
+		t.Parallel()
+
+		fakeRepo := NewFakeDeviceRepository()
+		for _, device := range []*entity.Device{sensorA, sensorB, gateway, sensorC} {
+			fakeRepo.devices[device.ID] = device
+		}
+
+		uc := usecase.NewDeviceUsecase(fakeRepo, NewFakeAuditLogger(), FakeTransactor{})
+		input := usecase.ListDevicesInput{Sort: "-createdAt", Limit: 3} //nolint:exhaustruct
+
+		first, err := uc.ListDevices(ctx, input)
+		require.NoError(t, err)
+		assert.Equal(t, outputs(sensorC, gateway, sensorB), first.Devices)
+		require.NotEmpty(t, first.NextCursor)
+
+		input.Cursor = first.NextCursor
+		second, err := uc.ListDevices(ctx, input)
+		require.NoError(t, err)
+		assert.Equal(t, outputs(sensorA), second.Devices)
+		assert.Empty(t, second.NextCursor)
+		assert.Equal(t, int64(4), second.TotalCount)
+
+		// The cursor cannot be used with another sort order.
+		input.Sort = "createdAt"
+		_, err = uc.ListDevices(ctx, input)
+		require.ErrorIs(t, err, usecase.ErrInvalidDeviceQuery)
+	})
+}
+
+// TestListDevicesMetadata tests the parsing of the metadata conditions of the ListDevices method.
+func TestListDevicesMetadata(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		desc       string
+		conditions []string
+		want       []repository.MetadataCondition
+		wantErr    bool
+	}{
+		{
+			name:       "success: equality with a plain string",
+			desc:       "Verify that a value that is not JSON is compared as a string.",
+			conditions: []string{"location.building=Factory-A"},
+			want: []repository.MetadataCondition{
+				{Path: []string{"location", "building"}, Operator: repository.MetadataEquals, Value: "Factory-A"},
+			},
+			wantErr: false,
+		},
+		{
+			name:       "success: JSON scalars",
+			desc:       "Verify that numbers, booleans, null and quoted strings keep their JSON type.",
+			conditions: []string{"floor!=3", "outdoor=true", "owner=null", `serial="42"`},
+			want: []repository.MetadataCondition{
+				{Path: []string{"floor"}, Operator: repository.MetadataNotEquals, Value: json.Number("3")},
+				{Path: []string{"outdoor"}, Operator: repository.MetadataEquals, Value: true},
+				{Path: []string{"owner"}, Operator: repository.MetadataEquals, Value: nil},
+				{Path: []string{"serial"}, Operator: repository.MetadataEquals, Value: "42"},
+			},
+			wantErr: false,
+		},
+		{
+			name:       "success: ordering comparisons",
+			desc:       "Verify that the two-character operators are not read as their first character.",
+			conditions: []string{"firmware.version>=2.4", "temperature<-10", "room>B", "room<=C"},
+			want: []repository.MetadataCondition{
+				{
+					Path:     []string{"firmware", "version"},
+					Operator: repository.MetadataGreaterOrEqual,
+					Value:    json.Number("2.4"),
+				},
+				{Path: []string{"temperature"}, Operator: repository.MetadataLess, Value: json.Number("-10")},
+				{Path: []string{"room"}, Operator: repository.MetadataGreater, Value: "B"},
+				{Path: []string{"room"}, Operator: repository.MetadataLessOrEqual, Value: "C"},
+			},
+			wantErr: false,
+		},
+		{
+			name:       "success: existence checks",
+			desc:       "Verify that a bare path checks that it exists, and a path prefixed with ! that it does not.",
+			conditions: []string{"location.building", "!decommissioned"},
+			want: []repository.MetadataCondition{
+				{Path: []string{"location", "building"}, Operator: repository.MetadataExists, Value: nil},
+				{Path: []string{"decommissioned"}, Operator: repository.MetadataNotExists, Value: nil},
+			},
+			wantErr: false,
+		},
+		{
+			name:       "failure: empty path segment",
+			desc:       "Verify that a path with an empty key is rejected.",
+			conditions: []string{"location..building=A"},
+			want:       nil,
+			wantErr:    true,
+		},
+		{
+			name:       "failure: unknown operator",
+			desc:       "Verify that an operator other than the known comparisons is rejected.",
+			conditions: []string{"location!building"},
+			want:       nil,
+			wantErr:    true,
+		},
+		{
+			name:       "failure: boolean compared by order",
+			desc:       "Verify that only numbers and strings can be compared by order.",
+			conditions: []string{"outdoor>true"},
+			want:       nil,
+			wantErr:    true,
+		},
+		{
+			name:       "failure: object value",
+			desc:       "Verify that a JSON object is not accepted as a value.",
+			conditions: []string{`location={"building":"A"}`},
+			want:       nil,
+			wantErr:    true,
+		},
+		{
+			name:       "failure: too many conditions",
+			desc:       "Verify that the number of conditions is limited.",
+			conditions: slices.Repeat([]string{"a=1"}, usecase.MaxDeviceMetadataConditions+1),
+			want:       nil,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeRepo := NewFakeDeviceRepository()
+			uc := usecase.NewDeviceUsecase(fakeRepo, NewFakeAuditLogger(), FakeTransactor{})
+
+			_, err := uc.ListDevices(context.Background(), usecase.ListDevicesInput{ //nolint:exhaustruct
+				Metadata: tt.conditions,
+			})
+
+			if tt.wantErr {
+				require.ErrorIs(t, err, usecase.ErrInvalidDeviceQuery)
+
+				return
 			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, fakeRepo.LastFilter().Metadata)
 		})
 	}
 }
@@ -424,12 +1010,18 @@ func TestUpdateDevice(t *testing.T) {
 	ctx := context.Background()
 
 	existingDevice := &entity.Device{
-		ID:         uuid.New(),
-		HardwareID: "hw-update-001",
-		Name:       "Old Name",
-		Metadata:   map[string]any{"status": "inactive"},
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		ID:              uuid.New(),
+		HardwareID:      "hw-update-001",
+		Name:            "Old Name",
+		Status:          devicestatus.Unregistered,
+		Metadata:        map[string]any{"status": "inactive"},
+		Version:         1,
+		ParentID:        nil,
+		ConnectionState: connectionstate.Disconnected,
+		LastSeenAt:      nil,
+		LastIP:          "",
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
 	}
 	updatedName := "New Name"
 	updatedMetadata := map[string]any{"status": "active"}
@@ -447,9 +1039,10 @@ func TestUpdateDevice(t *testing.T) {
 			name: "success: update device name and metadata",
 			desc: "Verify that the name and metadata of an existing device are updated correctly.",
 			input: usecase.UpdateDeviceInput{
-				ID:       existingDevice.ID,
-				Name:     &updatedName,
-				Metadata: updatedMetadata,
+				ID:              existingDevice.ID,
+				Name:            &updatedName,
+				Metadata:        updatedMetadata,
+				ExpectedVersion: 0,
 			},
 			repoSetup: func(repo *FakeDeviceRepository) {
 				repo.devices[existingDevice.ID] = existingDevice
@@ -458,6 +1051,7 @@ func TestUpdateDevice(t *testing.T) {
 				ID:         existingDevice.ID,
 				HardwareID: existingDevice.HardwareID,
 				Name:       updatedName,
+				Status:     existingDevice.Status.String(),
 				Metadata:   updatedMetadata,
 				CreatedAt:  existingDevice.CreatedAt,
 				UpdatedAt:  existingDevice.UpdatedAt,
@@ -469,22 +1063,40 @@ func TestUpdateDevice(t *testing.T) {
 			name: "failure: target device not found",
 			desc: "Verify that a 'device not found' error is returned when the target device ID does not exist.",
 			input: usecase.UpdateDeviceInput{
-				ID:       uuid.New(), // non-existing ID
-				Name:     &updatedName,
-				Metadata: nil,
+				ID:              uuid.New(), // non-existing ID
+				Name:            &updatedName,
+				Metadata:        nil,
+				ExpectedVersion: 0,
 			},
 			repoSetup:     nil,
 			wantOutput:    nil,
 			wantErr:       true,
 			wantErrString: entity.ErrDeviceNotFound.Error(),
 		},
+		{
+			name: "failure: version conflict",
+			desc: "Verify that an update based on another version of the device is rejected.",
+			input: usecase.UpdateDeviceInput{
+				ID:              existingDevice.ID,
+				Name:            &updatedName,
+				Metadata:        nil,
+				ExpectedVersion: 5,
+			},
+			repoSetup: func(repo *FakeDeviceRepository) {
+				repo.devices[existingDevice.ID] = existingDevice
+			},
+			wantOutput:    nil,
+			wantErr:       true,
+			wantErrString: entity.ErrVersionConflict.Error(),
+		},
 		{
 			name: "failure: repository returns error on FindByID",
 			desc: "Verify that an error from FindByID is propagated.",
 			input: usecase.UpdateDeviceInput{
-				ID:       existingDevice.ID,
-				Name:     &updatedName,
-				Metadata: nil,
+				ID:              existingDevice.ID,
+				Name:            &updatedName,
+				Metadata:        nil,
+				ExpectedVersion: 0,
 			},
 			repoSetup: func(repo *FakeDeviceRepository) {
 				repo.FindErr = assert.AnError
@@ -497,9 +1109,10 @@ func TestUpdateDevice(t *testing.T) {
 			name: "failure: repository returns error on Save",
 			desc: "Verify that an error from Save is propagated.",
 			input: usecase.UpdateDeviceInput{
-				ID:       existingDevice.ID,
-				Name:     &updatedName,
-				Metadata: nil,
+				ID:              existingDevice.ID,
+				Name:            &updatedName,
+				Metadata:        nil,
+				ExpectedVersion: 0,
 			},
 			repoSetup: func(repo *FakeDeviceRepository) {
 				repo.devices[existingDevice.ID] = existingDevice
@@ -520,7 +1133,8 @@ func TestUpdateDevice(t *testing.T) {
 				tt.repoSetup(fakeRepo)
 			}
 
-			uc := usecase.NewDeviceUsecase(fakeRepo)
+			auditLogger := NewFakeAuditLogger()
+			uc := usecase.NewDeviceUsecase(fakeRepo, auditLogger, FakeTransactor{})
 
 			got, err := uc.UpdateDevice(ctx, tt.input)
 
@@ -546,11 +1160,199 @@ func TestUpdateDevice(t *testing.T) {
 				require.NotNil(t, savedDevice)
 				require.Equal(t, updatedName, savedDevice.Name)
 				require.Equal(t, entity.JSONBMap(updatedMetadata), savedDevice.Metadata)
+
+				// Only the changed fields are recorded.
+				logs := auditLogger.Logs()
+				require.Len(t, logs, 1)
+				require.Equal(t, entity.AuditDeviceUpdate, logs[0].Action)
+
+				details := auditDetails(t, logs[0])
+				require.Equal(t, updatedName, details.After["name"])
+				require.NotContains(t, details.After, "hardwareId")
+				require.NotContains(t, details.After, "status")
 			}
 		})
 	}
 }
 
+// TestPatchDevice tests the PatchDevice method.
+func TestPatchDevice(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	newPatchDevice := func(repo *FakeDeviceRepository) *entity.Device {
+		device := &entity.Device{
+			ID:              uuid.New(),
+			HardwareID:      "hw-patch-001",
+			Name:            "sensor",
+			Status:          devicestatus.Active,
+			Metadata:        entity.JSONBMap{"config": map[string]any{"sync_interval_sec": 60.0, "threshold": 40.0}},
+			Version:         1,
+			ParentID:        nil,
+			ConnectionState: connectionstate.Disconnected,
+			LastSeenAt:      nil,
+			LastIP:          "",
+			CreatedAt:       time.Now(),
+			UpdatedAt:       time.Now(),
+		}
+		repo.devices[device.ID] = device
+
+		return device
+	}
+
+	tests := []struct {
+		name         string
+		desc         string
+		format       usecase.PatchFormat
+		patch        string
+		repoSetup    func(*FakeDeviceRepository)
+		wantMetadata map[string]any
+		wantErr      error
+	}{
+		{
+			name:         "success: merge patch",
+			desc:         "Verify that a merge patch updates one nested metadata key and keeps the others.",
+			format:       usecase.PatchFormatMerge,
+			patch:        `{"metadata": {"config": {"sync_interval_sec": 30}}}`,
+			repoSetup:    nil,
+			wantMetadata: map[string]any{"config": map[string]any{"sync_interval_sec": 30.0, "threshold": 40.0}},
+			wantErr:      nil,
+		},
+		{
+			name:         "success: json patch",
+			desc:         "Verify that a JSON Patch is applied to the metadata.",
+			format:       usecase.PatchFormatJSON,
+			patch:        `[{"op": "remove", "path": "/metadata/config/threshold"}]`,
+			repoSetup:    nil,
+			wantMetadata: map[string]any{"config": map[string]any{"sync_interval_sec": 60.0}},
+			wantErr:      nil,
+		},
+		{
+			name:         "failure: patch cannot be applied",
+			desc:         "Verify that a failing test operation is reported and nothing is saved.",
+			format:       usecase.PatchFormatJSON,
+			patch:        `[{"op": "test", "path": "/metadata/config/threshold", "value": 50}]`,
+			repoSetup:    nil,
+			wantMetadata: nil,
+			wantErr:      entity.ErrInvalidPatch,
+		},
+		{
+			name:         "failure: malformed patch",
+			desc:         "Verify that a merge patch that is not a JSON object is reported.",
+			format:       usecase.PatchFormatMerge,
+			patch:        `[]`,
+			repoSetup:    nil,
+			wantMetadata: nil,
+			wantErr:      entity.ErrMalformedPatch,
+		},
+		{
+			name:         "failure: unsupported format",
+			desc:         "Verify that an unknown patch format is rejected.",
+			format:       usecase.PatchFormat("xml-patch"),
+			patch:        `<patch/>`,
+			repoSetup:    nil,
+			wantMetadata: nil,
+			wantErr:      usecase.ErrUnsupportedPatchFormat,
+		},
+		{
+			name:   "failure: repository save error",
+			desc:   "Verify that an error saving the patched device is returned.",
+			format: usecase.PatchFormatMerge,
+			patch:  `{"name": "renamed"}`,
+			repoSetup: func(repo *FakeDeviceRepository) {
+				repo.SaveErr = assert.AnError
+			},
+			wantMetadata: nil,
+			wantErr:      usecase.ErrRepositorySave,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeRepo := NewFakeDeviceRepository()
+			device := newPatchDevice(fakeRepo)
+
+			if tt.repoSetup != nil {
+				tt.repoSetup(fakeRepo)
+			}
+
+			auditLogger := NewFakeAuditLogger()
+			uc := usecase.NewDeviceUsecase(fakeRepo, auditLogger, FakeTransactor{})
+
+			got, err := uc.PatchDevice(ctx, usecase.PatchDeviceInput{
+				ID:              device.ID,
+				Format:          tt.format,
+				Patch:           []byte(tt.patch),
+				ExpectedVersion: 0,
+			})
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+				require.Empty(t, auditLogger.Logs())
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantMetadata, got.Metadata)
+			assert.Equal(t, "sensor", got.Name)
+
+			logs := auditLogger.Logs()
+			require.Len(t, logs, 1)
+			assert.Equal(t, entity.AuditDeviceUpdate, logs[0].Action)
+		})
+	}
+
+	t.Run("expected version", func(t *testing.T) {
+		t.Parallel()
+
+		fakeRepo := NewFakeDeviceRepository()
+		device := newPatchDevice(fakeRepo)
+		uc := usecase.NewDeviceUsecase(fakeRepo, NewFakeAuditLogger(), FakeTransactor{})
+
+		// A patch based on the current version is applied, and the version is incremented.
+		got, err := uc.PatchDevice(ctx, usecase.PatchDeviceInput{
+			ID:              device.ID,
+			Format:          usecase.PatchFormatMerge,
+			Patch:           []byte(`{"name": "renamed"}`),
+			ExpectedVersion: 1,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), got.Version)
+
+		// A patch based on the replaced version is rejected, with the current version.
+		_, err = uc.PatchDevice(ctx, usecase.PatchDeviceInput{
+			ID:              device.ID,
+			Format:          usecase.PatchFormatMerge,
+			Patch:           []byte(`{"name": "stale"}`),
+			ExpectedVersion: 1,
+		})
+
+		var conflictErr *entity.VersionConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		assert.Equal(t, int64(2), conflictErr.Actual)
+		assert.Equal(t, "renamed", device.Name)
+	})
+
+	t.Run("failure: device not found", func(t *testing.T) {
+		t.Parallel()
+
+		uc := usecase.NewDeviceUsecase(NewFakeDeviceRepository(), NewFakeAuditLogger(), FakeTransactor{})
+
+		_, err := uc.PatchDevice(ctx, usecase.PatchDeviceInput{
+			ID:              uuid.New(),
+			Format:          usecase.PatchFormatMerge,
+			Patch:           []byte(`{}`),
+			ExpectedVersion: 0,
+		})
+		require.ErrorIs(t, err, entity.ErrDeviceNotFound)
+	})
+}
+
 // TestDeleteDevice tests the DeleteDevice method.
 func TestDeleteDevice(t *testing.T) {
 	t.Parallel()
@@ -558,12 +1360,18 @@ func TestDeleteDevice(t *testing.T) {
 	ctx := context.Background()
 
 	existingDevice := &entity.Device{
-		ID:         uuid.New(),
-		HardwareID: "hw-delete-001",
-		Name:       "Test Device D",
-		Metadata:   nil,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		ID:              uuid.New(),
+		HardwareID:      "hw-delete-001",
+		Name:            "Test Device D",
+		Status:          devicestatus.Unregistered,
+		Metadata:        nil,
+		Version:         1,
+		ParentID:        nil,
+		ConnectionState: connectionstate.Disconnected,
+		LastSeenAt:      nil,
+		LastIP:          "",
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
 	}
 
 	tests := []struct {
@@ -615,9 +1423,10 @@ func TestDeleteDevice(t *testing.T) {
 				tt.repoSetup(fakeRepo)
 			}
 
-			uc := usecase.NewDeviceUsecase(fakeRepo)
+			auditLogger := NewFakeAuditLogger()
+			uc := usecase.NewDeviceUsecase(fakeRepo, auditLogger, FakeTransactor{})
 
-			err := uc.DeleteDevice(ctx, tt.deviceID)
+			err := uc.DeleteDevice(ctx, usecase.DeleteDeviceInput{ID: tt.deviceID, ExpectedVersion: 0})
 
 			if tt.wantErr {
 				require.Error(t, err)
@@ -630,7 +1439,207 @@ func TestDeleteDevice(t *testing.T) {
 				// Check the state of the fake repository
 				_, findErr := fakeRepo.FindByID(ctx, tt.deviceID)
 				require.ErrorIs(t, findErr, entity.ErrDeviceNotFound)
+
+				// The deletion is recorded with the deleted device as the before state.
+				logs := auditLogger.Logs()
+				require.Len(t, logs, 1)
+				require.Equal(t, entity.AuditDeviceDelete, logs[0].Action)
+
+				details := auditDetails(t, logs[0])
+				require.Equal(t, existingDevice.HardwareID, details.Before["hardwareId"])
+				require.Nil(t, details.After)
 			}
 		})
 	}
 }
+
+// TestDeleteDeviceVersionConflict tests that DeleteDevice only deletes the expected version of a device.
+func TestDeleteDeviceVersionConflict(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	fakeRepo := NewFakeDeviceRepository()
+	device := &entity.Device{
+		ID:              uuid.New(),
+		HardwareID:      "hw-delete-002",
+		Name:            "",
+		Status:          devicestatus.Active,
+		Metadata:        nil,
+		Version:         3,
+		ParentID:        nil,
+		ConnectionState: connectionstate.Disconnected,
+		LastSeenAt:      nil,
+		LastIP:          "",
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+	fakeRepo.devices[device.ID] = device
+
+	auditLogger := NewFakeAuditLogger()
+	uc := usecase.NewDeviceUsecase(fakeRepo, auditLogger, FakeTransactor{})
+
+	err := uc.DeleteDevice(ctx, usecase.DeleteDeviceInput{ID: device.ID, ExpectedVersion: 2})
+	require.ErrorIs(t, err, entity.ErrVersionConflict)
+	require.Empty(t, auditLogger.Logs())
+
+	_, err = fakeRepo.FindByID(ctx, device.ID)
+	require.NoError(t, err, "the device is not deleted")
+
+	err = uc.DeleteDevice(ctx, usecase.DeleteDeviceInput{ID: device.ID, ExpectedVersion: 3})
+	require.NoError(t, err)
+}
+
+// TestChangeDeviceStatus tests the ActivateDevice, SuspendDevice and RevokeDevice methods.
+func TestChangeDeviceStatus(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		desc          string
+		initialStatus devicestatus.Status
+		call          func(usecase.DeviceUsecase, context.Context, uuid.UUID) (*usecase.DeviceOutput, error)
+		repoSetup     func(*FakeDeviceRepository)
+		useUnknownID  bool
+		wantStatus    devicestatus.Status
+		wantErr       error
+	}{
+		{
+			name:          "success: activate an unregistered device",
+			desc:          "Verify that an UNREGISTERED device can be activated.",
+			initialStatus: devicestatus.Unregistered,
+			call:          usecase.DeviceUsecase.ActivateDevice,
+			repoSetup:     nil,
+			useUnknownID:  false,
+			wantStatus:    devicestatus.Active,
+			wantErr:       nil,
+		},
+		{
+			name:          "success: suspend an active device",
+			desc:          "Verify that an ACTIVE device can be suspended.",
+			initialStatus: devicestatus.Active,
+			call:          usecase.DeviceUsecase.SuspendDevice,
+			repoSetup:     nil,
+			useUnknownID:  false,
+			wantStatus:    devicestatus.Suspended,
+			wantErr:       nil,
+		},
+		{
+			name:          "success: revoke a suspended device",
+			desc:          "Verify that a SUSPENDED device can be revoked.",
+			initialStatus: devicestatus.Suspended,
+			call:          usecase.DeviceUsecase.RevokeDevice,
+			repoSetup:     nil,
+			useUnknownID:  false,
+			wantStatus:    devicestatus.Revoked,
+			wantErr:       nil,
+		},
+		{
+			name:          "failure: activate a revoked device",
+			desc:          "Verify that REVOKED is terminal and the stored status is left unchanged.",
+			initialStatus: devicestatus.Revoked,
+			call:          usecase.DeviceUsecase.ActivateDevice,
+			repoSetup:     nil,
+			useUnknownID:  false,
+			wantStatus:    devicestatus.Revoked,
+			wantErr:       devicestatus.ErrInvalidTransition,
+		},
+		{
+			name:          "failure: suspend an unregistered device",
+			desc:          "Verify that a device that has never been provisioned cannot be suspended.",
+			initialStatus: devicestatus.Unregistered,
+			call:          usecase.DeviceUsecase.SuspendDevice,
+			repoSetup:     nil,
+			useUnknownID:  false,
+			wantStatus:    devicestatus.Unregistered,
+			wantErr:       devicestatus.ErrInvalidTransition,
+		},
+		{
+			name:          "failure: device not found",
+			desc:          "Verify that a 'device not found' error is returned for a non-existent ID.",
+			initialStatus: devicestatus.Unregistered,
+			call:          usecase.DeviceUsecase.ActivateDevice,
+			repoSetup:     nil,
+			useUnknownID:  true,
+			wantStatus:    devicestatus.Unregistered,
+			wantErr:       entity.ErrDeviceNotFound,
+		},
+		{
+			name:          "failure: repository returns error on Save",
+			desc:          "Verify that an error from Save is propagated.",
+			initialStatus: devicestatus.Active,
+			call:          usecase.DeviceUsecase.SuspendDevice,
+			repoSetup: func(repo *FakeDeviceRepository) {
+				repo.SaveErr = assert.AnError
+			},
+			useUnknownID: false,
+			wantStatus:   devicestatus.Suspended,
+			wantErr:      usecase.ErrRepositorySave,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			device := &entity.Device{
+				ID:              uuid.New(),
+				HardwareID:      "hw-status-001",
+				Name:            "Test Device S",
+				Status:          tt.initialStatus,
+				Metadata:        nil,
+				Version:         1,
+				ParentID:        nil,
+				ConnectionState: connectionstate.Disconnected,
+				LastSeenAt:      nil,
+				LastIP:          "",
+				CreatedAt:       time.Now(),
+				UpdatedAt:       time.Now(),
+			}
+
+			fakeRepo := NewFakeDeviceRepository()
+			fakeRepo.devices[device.ID] = device
+
+			if tt.repoSetup != nil {
+				tt.repoSetup(fakeRepo)
+			}
+
+			auditLogger := NewFakeAuditLogger()
+			uc := usecase.NewDeviceUsecase(fakeRepo, auditLogger, FakeTransactor{})
+
+			targetID := device.ID
+			if tt.useUnknownID {
+				targetID = uuid.New()
+			}
+
+			got, err := tt.call(uc, ctx, targetID)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+				require.Empty(t, auditLogger.Logs())
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.NotNil(t, got)
+			require.Equal(t, tt.wantStatus.String(), got.Status)
+
+			savedDevice, findErr := fakeRepo.FindByID(ctx, device.ID)
+			require.NoError(t, findErr)
+			require.Equal(t, tt.wantStatus, savedDevice.Status)
+
+			// The transition is recorded as a diff of the status only.
+			logs := auditLogger.Logs()
+			require.Len(t, logs, 1)
+			require.Equal(t, entity.AuditDeviceStatusChange, logs[0].Action)
+
+			details := auditDetails(t, logs[0])
+			require.Equal(t, map[string]any{"status": tt.initialStatus.String()}, details.Before)
+			require.Equal(t, map[string]any{"status": tt.wantStatus.String()}, details.After)
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+// DeviceTopologyUsecase defines the interface for managing the parents and children of devices,
+// such as the sensors that sit behind a gateway.
+type DeviceTopologyUsecase interface {
+	// AttachChildDevice makes a device a child of a parent device.
+	AttachChildDevice(ctx context.Context, input AttachChildDeviceInput) (*DeviceOutput, error)
+	// DetachChildDevice removes a device from its parent.
+	DetachChildDevice(ctx context.Context, input DetachChildDeviceInput) error
+	// ListChildDevices retrieves a page of the children of a device matching the filters.
+	ListChildDevices(ctx context.Context, parentID uuid.UUID, input ListDevicesInput) (*DeviceListOutput, error)
+	// ListAncestorDevices retrieves the ancestors of a device, from its parent up to the root of its tree.
+	ListAncestorDevices(ctx context.Context, id uuid.UUID) ([]*DeviceOutput, error)
+}
+
+// deviceTopologyUsecase is the implementation of the DeviceTopologyUsecase interface.
+type deviceTopologyUsecase struct {
+	deviceRepo  repository.DeviceRepository
+	auditLogger repository.AuditLogger
+	transactor  repository.Transactor
+}
+
+// NewDeviceTopologyUsecase creates a new instance of deviceTopologyUsecase.
+//
+//nolint:ireturn
+func NewDeviceTopologyUsecase(
+	deviceRepo repository.DeviceRepository,
+	auditLogger repository.AuditLogger,
+	transactor repository.Transactor,
+) DeviceTopologyUsecase {
+	return &deviceTopologyUsecase{deviceRepo: deviceRepo, auditLogger: auditLogger, transactor: transactor}
+}
+
+// AttachChildDevice makes a device a child of a parent device.
+//
+// The devices are read after the topology has been locked, so that the check for cycles sees
+// the attachments made concurrently. If the parent does not exist, the error is entity.ErrDeviceNotFound,
+// and if the child does not, ErrChildDeviceNotFound. Attaching a device to its current parent does nothing.
+func (uc *deviceTopologyUsecase) AttachChildDevice(
+	ctx context.Context,
+	input AttachChildDeviceInput,
+) (*DeviceOutput, error) {
+	var child *entity.Device
+
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := uc.deviceRepo.LockTopology(ctx)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrDBDeviceTopology, err)
+		}
+
+		parent, err := findDevice(ctx, uc.deviceRepo, input.ParentID)
+		if err != nil {
+			return err
+		}
+
+		child, err = findDevice(ctx, uc.deviceRepo, input.DeviceID)
+		if err != nil {
+			if errors.Is(err, entity.ErrDeviceNotFound) {
+				return ErrChildDeviceNotFound
+			}
+
+			return err
+		}
+
+		if child.ParentID != nil && *child.ParentID == parent.ID {
+			return nil
+		}
+
+		ancestors, err := uc.deviceRepo.FindAncestors(ctx, parent.ID)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrDBDeviceTopology, err)
+		}
+
+		before := deviceSnapshot(child)
+
+		err = child.AttachTo(parent, ancestors)
+		if err != nil {
+			return err
+		}
+
+		return saveChildWithAudit(ctx, uc.deviceRepo, uc.auditLogger, child, entity.AuditDeviceAttach, before)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDeviceOutput(child), nil
+}
+
+// DetachChildDevice removes a device from its parent.
+// If the device is not a child of the parent, the error wraps entity.ErrDeviceNotChild.
+func (uc *deviceTopologyUsecase) DetachChildDevice(ctx context.Context, input DetachChildDeviceInput) error {
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := uc.deviceRepo.LockTopology(ctx)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrDBDeviceTopology, err)
+		}
+
+		child, err := findDevice(ctx, uc.deviceRepo, input.DeviceID)
+		if err != nil {
+			return err
+		}
+
+		before := deviceSnapshot(child)
+
+		err = child.Detach(input.ParentID)
+		if err != nil {
+			return err
+		}
+
+		return saveChildWithAudit(ctx, uc.deviceRepo, uc.auditLogger, child, entity.AuditDeviceDetach, before)
+	})
+}
+
+// ListChildDevices retrieves a page of the children of a device matching the filters.
+func (uc *deviceTopologyUsecase) ListChildDevices(
+	ctx context.Context,
+	parentID uuid.UUID,
+	input ListDevicesInput,
+) (*DeviceListOutput, error) {
+	query, err := newDeviceQuery(input)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, err := findDevice(ctx, uc.deviceRepo, parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	query.Filter.ParentID = &parent.ID
+
+	return listDevicePage(ctx, uc.deviceRepo, query)
+}
+
+// ListAncestorDevices retrieves the ancestors of a device, from its parent up to the root of its tree.
+// A device without a parent has no ancestors.
+func (uc *deviceTopologyUsecase) ListAncestorDevices(ctx context.Context, id uuid.UUID) ([]*DeviceOutput, error) {
+	device, err := findDevice(ctx, uc.deviceRepo, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors, err := uc.deviceRepo.FindAncestors(ctx, device.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDBDeviceTopology, err)
+	}
+
+	outputs := make([]*DeviceOutput, 0, len(ancestors))
+	for _, ancestor := range ancestors {
+		outputs = append(outputs, NewDeviceOutput(ancestor))
+	}
+
+	return outputs, nil
+}
+
+// detachChildDevices detaches all the children of a device, e.g. before it is deleted.
+// It must be called within a transaction that holds the lock on the topology.
+func detachChildDevices(
+	ctx context.Context,
+	deviceRepo repository.DeviceRepository,
+	auditLogger repository.AuditLogger,
+	parentID uuid.UUID,
+) error {
+	query := repository.DeviceQuery{ //nolint:exhaustruct
+		Filter: repository.DeviceFilter{ParentID: &parentID}, //nolint:exhaustruct
+		SortBy: repository.DeviceSortCreatedAt,
+	}
+
+	children, err := deviceRepo.FindByQuery(ctx, query)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDBDeviceTopology, err)
+	}
+
+	for _, child := range children {
+		before := deviceSnapshot(child)
+
+		err = child.Detach(parentID)
+		if err != nil {
+			return err
+		}
+
+		err = saveChildWithAudit(ctx, deviceRepo, auditLogger, child, entity.AuditDeviceDetach, before)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveChildWithAudit saves a device whose parent has changed and records the change in the audit trail.
+// It must be called within a transaction.
+func saveChildWithAudit(
+	ctx context.Context,
+	deviceRepo repository.DeviceRepository,
+	auditLogger repository.AuditLogger,
+	child *entity.Device,
+	action entity.AuditAction,
+	before map[string]any,
+) error {
+	err := deviceRepo.Save(ctx, child)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+	}
+
+	return recordAudit(ctx, auditLogger, auditEvent{
+		action:   action,
+		deviceID: child.ID,
+		target:   deviceTarget(child.ID),
+		before:   before,
+		after:    deviceSnapshot(child),
+	})
+}
+
+// findDevice retrieves a device by its ID. If it does not exist, the error is entity.ErrDeviceNotFound.
+func findDevice(ctx context.Context, deviceRepo repository.DeviceRepository, id uuid.UUID) (*entity.Device, error) {
+	device, err := deviceRepo.FindByID(ctx, id)
+	if err != nil {
+		if isNotFound(err, entity.ErrDeviceNotFound) {
+			return nil, entity.ErrDeviceNotFound
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrDBFindByID, err)
+	}
+
+	return device, nil
+}
@@ -0,0 +1,15 @@
+package usecase
+
+import "github.com/google/uuid"
+
+// AttachChildDeviceInput is the input data for attaching a device to a parent, such as a gateway.
+type AttachChildDeviceInput struct {
+	ParentID uuid.UUID `json:"-"`
+	DeviceID uuid.UUID `json:"deviceId"` // Required: the device to attach.
+}
+
+// DetachChildDeviceInput is the input data for detaching a device from its parent.
+type DetachChildDeviceInput struct {
+	ParentID uuid.UUID
+	DeviceID uuid.UUID
+}
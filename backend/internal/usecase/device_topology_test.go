@@ -0,0 +1,226 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deviceTopologyTest holds a DeviceTopologyUsecase and its fakes.
+type deviceTopologyTest struct {
+	uc          usecase.DeviceTopologyUsecase
+	deviceRepo  *FakeDeviceRepository
+	auditLogger *FakeAuditLogger
+}
+
+func newDeviceTopologyTest() *deviceTopologyTest {
+	deviceRepo := NewFakeDeviceRepository()
+	auditLogger := NewFakeAuditLogger()
+
+	return &deviceTopologyTest{
+		uc:          usecase.NewDeviceTopologyUsecase(deviceRepo, auditLogger, FakeTransactor{}),
+		deviceRepo:  deviceRepo,
+		auditLogger: auditLogger,
+	}
+}
+
+// addDevice stores an ACTIVE device, created one minute after the previous one.
+func (tt *deviceTopologyTest) addDevice(hardwareID string) *entity.Device {
+	device := newTestDevice(hardwareID, devicestatus.Active)
+	device.CreatedAt = time.Date(2026, 4, 1, 9, len(tt.deviceRepo.devices), 0, 0, time.UTC)
+	device.UpdatedAt = time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	tt.deviceRepo.devices[device.ID] = device
+
+	return device
+}
+
+// attach attaches child to parent, and fails the test if it cannot.
+func (tt *deviceTopologyTest) attach(t *testing.T, parent, child *entity.Device) {
+	t.Helper()
+
+	_, err := tt.uc.AttachChildDevice(context.Background(), usecase.AttachChildDeviceInput{
+		ParentID: parent.ID,
+		DeviceID: child.ID,
+	})
+	require.NoError(t, err)
+}
+
+// TestAttachChildDevice tests the AttachChildDevice method.
+func TestAttachChildDevice(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tt := newDeviceTopologyTest()
+
+	// gateway <- hub <- sensor
+	gateway := tt.addDevice("gw-001")
+	hub := tt.addDevice("hub-001")
+	sensor := tt.addDevice("sensor-001")
+
+	tt.attach(t, gateway, hub)
+
+	output, err := tt.uc.AttachChildDevice(ctx, usecase.AttachChildDeviceInput{ParentID: hub.ID, DeviceID: sensor.ID})
+	require.NoError(t, err)
+	assert.Equal(t, &hub.ID, output.ParentID)
+	assert.Equal(t, int64(2), output.Version)
+
+	logs := tt.auditLogger.Logs()
+	require.Len(t, logs, 2)
+	assert.Equal(t, entity.AuditDeviceAttach, logs[1].Action)
+	assert.Equal(t, &sensor.ID, logs[1].TargetDeviceID)
+
+	details := auditDetails(t, logs[1])
+	assert.Nil(t, details.Before["parentId"])
+	assert.Equal(t, hub.ID.String(), details.After["parentId"])
+
+	// Attaching a device to its current parent does nothing.
+	output, err = tt.uc.AttachChildDevice(ctx, usecase.AttachChildDeviceInput{ParentID: hub.ID, DeviceID: sensor.ID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), output.Version)
+	assert.Len(t, tt.auditLogger.Logs(), 2)
+
+	tests := []struct {
+		name    string
+		input   usecase.AttachChildDeviceInput
+		wantErr error
+	}{
+		{
+			name:    "failure: itself",
+			input:   usecase.AttachChildDeviceInput{ParentID: gateway.ID, DeviceID: gateway.ID},
+			wantErr: entity.ErrDeviceTopologyCycle,
+		},
+		{
+			name:    "failure: descendant as the parent",
+			input:   usecase.AttachChildDeviceInput{ParentID: sensor.ID, DeviceID: gateway.ID},
+			wantErr: entity.ErrDeviceTopologyCycle,
+		},
+		{
+			name:    "failure: child of another parent",
+			input:   usecase.AttachChildDeviceInput{ParentID: gateway.ID, DeviceID: sensor.ID},
+			wantErr: entity.ErrDeviceAlreadyAttached,
+		},
+		{
+			name:    "failure: unknown parent",
+			input:   usecase.AttachChildDeviceInput{ParentID: uuid.New(), DeviceID: sensor.ID},
+			wantErr: entity.ErrDeviceNotFound,
+		},
+		{
+			name:    "failure: unknown child",
+			input:   usecase.AttachChildDeviceInput{ParentID: gateway.ID, DeviceID: uuid.New()},
+			wantErr: usecase.ErrChildDeviceNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		_, err = tt.uc.AttachChildDevice(ctx, test.input)
+		require.ErrorIs(t, err, test.wantErr, test.name)
+	}
+
+	assert.Nil(t, gateway.ParentID, "the topology is left unchanged")
+	assert.Equal(t, &hub.ID, sensor.ParentID)
+	assert.Len(t, tt.auditLogger.Logs(), 2)
+}
+
+// TestDetachChildDevice tests the DetachChildDevice method.
+func TestDetachChildDevice(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tt := newDeviceTopologyTest()
+	gateway := tt.addDevice("gw-001")
+	other := tt.addDevice("gw-002")
+	sensor := tt.addDevice("sensor-001")
+
+	tt.attach(t, gateway, sensor)
+
+	err := tt.uc.DetachChildDevice(ctx, usecase.DetachChildDeviceInput{ParentID: other.ID, DeviceID: sensor.ID})
+	require.ErrorIs(t, err, entity.ErrDeviceNotChild)
+
+	err = tt.uc.DetachChildDevice(ctx, usecase.DetachChildDeviceInput{ParentID: gateway.ID, DeviceID: uuid.New()})
+	require.ErrorIs(t, err, entity.ErrDeviceNotFound)
+
+	err = tt.uc.DetachChildDevice(ctx, usecase.DetachChildDeviceInput{ParentID: gateway.ID, DeviceID: sensor.ID})
+	require.NoError(t, err)
+	assert.Nil(t, sensor.ParentID)
+	assert.Equal(t, []entity.AuditAction{entity.AuditDeviceAttach, entity.AuditDeviceDetach}, tt.auditLogger.Actions())
+
+	// The device can then be attached to another parent.
+	tt.attach(t, other, sensor)
+}
+
+// TestListChildAndAncestorDevices tests the ListChildDevices and ListAncestorDevices methods.
+func TestListChildAndAncestorDevices(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tt := newDeviceTopologyTest()
+	gateway := tt.addDevice("gw-001")
+	hub := tt.addDevice("hub-001")
+	sensors := []*entity.Device{tt.addDevice("sensor-001"), tt.addDevice("sensor-002"), tt.addDevice("sensor-003")}
+	tt.addDevice("sensor-004") // not attached
+
+	tt.attach(t, gateway, hub)
+
+	for _, sensor := range sensors {
+		tt.attach(t, hub, sensor)
+	}
+
+	page, err := tt.uc.ListChildDevices(ctx, hub.ID, usecase.ListDevicesInput{Limit: 2}) //nolint:exhaustruct
+	require.NoError(t, err)
+	require.Len(t, page.Devices, 2)
+	assert.Equal(t, sensors[0].ID, page.Devices[0].ID)
+	assert.Equal(t, int64(3), page.TotalCount)
+	assert.NotEmpty(t, page.NextCursor)
+
+	page, err = tt.uc.ListChildDevices(ctx, gateway.ID, usecase.ListDevicesInput{}) //nolint:exhaustruct
+	require.NoError(t, err)
+	require.Len(t, page.Devices, 1, "only the direct children are listed")
+	assert.Equal(t, hub.ID, page.Devices[0].ID)
+
+	_, err = tt.uc.ListChildDevices(ctx, uuid.New(), usecase.ListDevicesInput{}) //nolint:exhaustruct
+	require.ErrorIs(t, err, entity.ErrDeviceNotFound)
+
+	ancestors, err := tt.uc.ListAncestorDevices(ctx, sensors[2].ID)
+	require.NoError(t, err)
+	require.Len(t, ancestors, 2)
+	assert.Equal(t, hub.ID, ancestors[0].ID)
+	assert.Equal(t, gateway.ID, ancestors[1].ID)
+
+	ancestors, err = tt.uc.ListAncestorDevices(ctx, gateway.ID)
+	require.NoError(t, err)
+	assert.Empty(t, ancestors)
+
+	_, err = tt.uc.ListAncestorDevices(ctx, uuid.New())
+	require.ErrorIs(t, err, entity.ErrDeviceNotFound)
+}
+
+// TestDeleteDeviceDetachesChildren tests that deleting a device detaches its children.
+func TestDeleteDeviceDetachesChildren(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tt := newDeviceTopologyTest()
+	gateway := tt.addDevice("gw-001")
+	sensor := tt.addDevice("sensor-001")
+
+	tt.attach(t, gateway, sensor)
+
+	devices := usecase.NewDeviceUsecase(tt.deviceRepo, tt.auditLogger, FakeTransactor{})
+
+	err := devices.DeleteDevice(ctx, usecase.DeleteDeviceInput{ID: gateway.ID, ExpectedVersion: 0})
+	require.NoError(t, err)
+
+	assert.Nil(t, sensor.ParentID)
+	assert.Equal(t, int64(3), sensor.Version)
+	assert.Equal(t, []entity.AuditAction{
+		entity.AuditDeviceAttach, entity.AuditDeviceDetach, entity.AuditDeviceDelete,
+	}, tt.auditLogger.Actions())
+}
@@ -0,0 +1,251 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"backend/internal/domain/service"
+)
+
+// maxReportAttempts is the number of times a reported state is merged, if other reports of the device
+// are saved concurrently.
+const maxReportAttempts = 3
+
+// DeviceTwinUsecase defines the interface for managing the twins of devices: the configuration the platform
+// wants a device to apply, and the configuration the device reports it has applied.
+type DeviceTwinUsecase interface {
+	// GetDeviceTwin retrieves the twin of a device, with the delta between its desired and reported states.
+	GetDeviceTwin(ctx context.Context, deviceID uuid.UUID) (*DeviceTwinOutput, error)
+	// SetDesiredState replaces the desired state of a device and sends the delta to the device.
+	SetDesiredState(ctx context.Context, input SetDesiredStateInput) (*DeviceTwinOutput, error)
+	// PatchDesiredState partially updates the desired state of a device and sends the delta to the device.
+	PatchDesiredState(ctx context.Context, input PatchDesiredStateInput) (*DeviceTwinOutput, error)
+	// ReportState merges a state reported by a device into its reported state,
+	// and sends the remaining delta to the device.
+	ReportState(ctx context.Context, input ReportDeviceStateInput) (*DeviceTwinOutput, error)
+}
+
+// deviceTwinUsecase is the implementation of the DeviceTwinUsecase interface.
+type deviceTwinUsecase struct {
+	deviceRepo  repository.DeviceRepository
+	twinRepo    repository.DeviceTwinRepository
+	auditLogger repository.AuditLogger
+	transactor  repository.Transactor
+	publisher   service.DeviceTwinPublisher
+	now         func() time.Time
+}
+
+// NewDeviceTwinUsecase creates a new instance of deviceTwinUsecase.
+//
+//nolint:ireturn
+func NewDeviceTwinUsecase(
+	deviceRepo repository.DeviceRepository,
+	twinRepo repository.DeviceTwinRepository,
+	auditLogger repository.AuditLogger,
+	transactor repository.Transactor,
+	publisher service.DeviceTwinPublisher,
+) DeviceTwinUsecase {
+	return &deviceTwinUsecase{
+		deviceRepo:  deviceRepo,
+		twinRepo:    twinRepo,
+		auditLogger: auditLogger,
+		transactor:  transactor,
+		publisher:   publisher,
+		now:         time.Now,
+	}
+}
+
+// GetDeviceTwin retrieves the twin of a device. The states of a device whose twin has never been set are empty.
+func (uc *deviceTwinUsecase) GetDeviceTwin(ctx context.Context, deviceID uuid.UUID) (*DeviceTwinOutput, error) {
+	device, err := findDevice(ctx, uc.deviceRepo, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	twin, err := uc.findTwin(ctx, device.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDeviceTwinOutput(twin), nil
+}
+
+// SetDesiredState replaces the desired state of a device. The state must be a JSON object.
+func (uc *deviceTwinUsecase) SetDesiredState(
+	ctx context.Context,
+	input SetDesiredStateInput,
+) (*DeviceTwinOutput, error) {
+	state, err := entity.ParseTwinState(input.State)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.updateDesired(ctx, input.DeviceID, func(entity.JSONBMap) (entity.JSONBMap, error) {
+		return state, nil
+	})
+}
+
+// PatchDesiredState applies a JSON Merge Patch or a JSON Patch to the desired state of a device.
+func (uc *deviceTwinUsecase) PatchDesiredState(
+	ctx context.Context,
+	input PatchDesiredStateInput,
+) (*DeviceTwinOutput, error) {
+	var apply func(desired entity.JSONBMap) (entity.JSONBMap, error)
+
+	switch input.Format {
+	case PatchFormatMerge:
+		apply = func(desired entity.JSONBMap) (entity.JSONBMap, error) { return desired.MergePatch(input.Patch) }
+	case PatchFormatJSON:
+		apply = func(desired entity.JSONBMap) (entity.JSONBMap, error) { return desired.JSONPatch(input.Patch) }
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedPatchFormat, input.Format)
+	}
+
+	return uc.updateDesired(ctx, input.DeviceID, apply)
+}
+
+// ReportState merges a state reported by a device into its reported state. Only ACTIVE devices can report.
+//
+// The remaining delta is sent to the device, even if the reported state did not change,
+// so a device can ask for it by reporting an empty object, e.g. after it reconnects.
+func (uc *deviceTwinUsecase) ReportState(ctx context.Context, input ReportDeviceStateInput) (*DeviceTwinOutput, error) {
+	device, err := findDevice(ctx, uc.deviceRepo, input.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if device.Status != devicestatus.Active {
+		return nil, fmt.Errorf("%w: %s", ErrDeviceNotActive, device.Status)
+	}
+
+	var twin *entity.DeviceTwin
+
+	// The state is merged again into the latest reported state if another report was saved meanwhile.
+	for attempt := 1; ; attempt++ {
+		twin, err = uc.findTwin(ctx, device.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		reported, err := twin.Reported.MergePatch(input.State)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", entity.ErrInvalidTwinState, err)
+		}
+
+		if !twin.SetReported(reported, uc.now()) {
+			break
+		}
+
+		err = uc.twinRepo.SaveReported(ctx, twin)
+		if err == nil {
+			break
+		}
+
+		if !errors.Is(err, entity.ErrDeviceTwinVersionConflict) || attempt == maxReportAttempts {
+			return nil, fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+	}
+
+	uc.publishDelta(ctx, twin)
+
+	return NewDeviceTwinOutput(twin), nil
+}
+
+// updateDesired applies a change to the desired state of a device and records it in the audit trail.
+// If the state changed, the delta is sent to the device once the change is committed.
+func (uc *deviceTwinUsecase) updateDesired(
+	ctx context.Context,
+	deviceID uuid.UUID,
+	apply func(desired entity.JSONBMap) (entity.JSONBMap, error),
+) (*DeviceTwinOutput, error) {
+	var (
+		twin    *entity.DeviceTwin
+		changed bool
+	)
+
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		device, err := findDevice(ctx, uc.deviceRepo, deviceID)
+		if err != nil {
+			return err
+		}
+
+		twin, err = uc.findTwin(ctx, device.ID)
+		if err != nil {
+			return err
+		}
+
+		desired, err := apply(twin.Desired)
+		if err != nil {
+			return err
+		}
+
+		before := deviceTwinSnapshot(twin)
+
+		changed = twin.SetDesired(desired, uc.now())
+		if !changed {
+			return nil
+		}
+
+		err = uc.twinRepo.SaveDesired(ctx, twin)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+
+		return recordAudit(ctx, uc.auditLogger, auditEvent{
+			action:   entity.AuditDeviceTwinUpdate,
+			deviceID: device.ID,
+			target:   deviceTarget(device.ID),
+			before:   before,
+			after:    deviceTwinSnapshot(twin),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		uc.publishDelta(ctx, twin)
+	}
+
+	return NewDeviceTwinOutput(twin), nil
+}
+
+// findTwin retrieves the twin of a device, or a new twin if none of its states has been set yet.
+func (uc *deviceTwinUsecase) findTwin(ctx context.Context, deviceID uuid.UUID) (*entity.DeviceTwin, error) {
+	twin, err := uc.twinRepo.FindByDeviceID(ctx, deviceID)
+	if err != nil {
+		if isNotFound(err) {
+			return entity.NewDeviceTwin(deviceID), nil
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrDBFindByID, err)
+	}
+
+	return twin, nil
+}
+
+// publishDelta sends the delta of a twin to its device, if there is one. The state has already been saved,
+// so a failure is only logged: the device receives the delta again when it reports its state next.
+func (uc *deviceTwinUsecase) publishDelta(ctx context.Context, twin *entity.DeviceTwin) {
+	delta := twin.Delta()
+	if len(delta) == 0 {
+		return
+	}
+
+	err := uc.publisher.PublishDelta(ctx, service.DeviceTwinDelta{
+		DeviceID: twin.DeviceID,
+		Version:  twin.DesiredVersion,
+		State:    delta,
+	})
+	if err != nil {
+		log.Printf("failed to publish the twin delta of device %s: %v", twin.DeviceID, err)
+	}
+}
@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+)
+
+// SetDesiredStateInput is the input data for replacing the desired state of a device twin.
+type SetDesiredStateInput struct {
+	DeviceID uuid.UUID
+	// State is the new desired state, a JSON object.
+	State []byte
+}
+
+// PatchDesiredStateInput is the input data for partially updating the desired state of a device twin.
+type PatchDesiredStateInput struct {
+	DeviceID uuid.UUID
+	Format   PatchFormat
+	Patch    []byte
+}
+
+// ReportDeviceStateInput is the input data for a state reported by a device, e.g. over MQTT.
+type ReportDeviceStateInput struct {
+	DeviceID uuid.UUID
+	// State is a JSON Merge Patch of the reported state: the device only needs to report what changed,
+	// and null removes a value. An empty object only asks for the current delta.
+	State []byte
+}
+
+// DeviceTwinOutput is the output data of a device twin.
+type DeviceTwinOutput struct {
+	DeviceID          uuid.UUID      `json:"deviceId"`
+	Desired           map[string]any `json:"desired"`
+	DesiredVersion    int64          `json:"desiredVersion"`
+	DesiredUpdatedAt  *time.Time     `json:"desiredUpdatedAt,omitempty"`
+	Reported          map[string]any `json:"reported"`
+	ReportedVersion   int64          `json:"reportedVersion"`
+	ReportedUpdatedAt *time.Time     `json:"reportedUpdatedAt,omitempty"`
+	// Delta is the part of the desired state that the reported state does not match yet.
+	Delta map[string]any `json:"delta"`
+	// InSync reports whether the device has applied the whole desired state.
+	InSync bool `json:"inSync"`
+}
+
+// NewDeviceTwinOutput creates a new DeviceTwinOutput from a DeviceTwin entity.
+func NewDeviceTwinOutput(twin *entity.DeviceTwin) *DeviceTwinOutput {
+	delta := twin.Delta()
+
+	return &DeviceTwinOutput{
+		DeviceID:          twin.DeviceID,
+		Desired:           twin.Desired,
+		DesiredVersion:    twin.DesiredVersion,
+		DesiredUpdatedAt:  twin.DesiredUpdatedAt,
+		Reported:          twin.Reported,
+		ReportedVersion:   twin.ReportedVersion,
+		ReportedUpdatedAt: twin.ReportedUpdatedAt,
+		Delta:             delta,
+		InSync:            len(delta) == 0,
+	}
+}
@@ -0,0 +1,358 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/service"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// FakeDeviceTwinRepository is an in-memory implementation of the DeviceTwinRepository for testing.
+// Like the real repository, it saves each state separately, conditional on its previous version.
+type FakeDeviceTwinRepository struct {
+	mu    sync.Mutex
+	twins map[uuid.UUID]entity.DeviceTwin
+	// ConcurrentReports is the number of saves of a reported state that find another report saved meanwhile.
+	ConcurrentReports int
+}
+
+// NewFakeDeviceTwinRepository creates a new FakeDeviceTwinRepository.
+func NewFakeDeviceTwinRepository() *FakeDeviceTwinRepository {
+	return &FakeDeviceTwinRepository{
+		mu:                sync.Mutex{},
+		twins:             make(map[uuid.UUID]entity.DeviceTwin),
+		ConcurrentReports: 0,
+	}
+}
+
+// FindByDeviceID returns a copy of the stored twin.
+func (r *FakeDeviceTwinRepository) FindByDeviceID(_ context.Context, deviceID uuid.UUID) (*entity.DeviceTwin, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	twin, ok := r.twins[deviceID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &twin, nil
+}
+
+// SaveDesired stores the desired state of the twin.
+func (r *FakeDeviceTwinRepository) SaveDesired(_ context.Context, twin *entity.DeviceTwin) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.twins[twin.DeviceID]
+	if !ok {
+		stored = *entity.NewDeviceTwin(twin.DeviceID)
+	}
+
+	if stored.DesiredVersion != twin.DesiredVersion-1 {
+		return entity.ErrDeviceTwinVersionConflict
+	}
+
+	stored.Desired, stored.DesiredVersion, stored.DesiredUpdatedAt =
+		twin.Desired, twin.DesiredVersion, twin.DesiredUpdatedAt
+	r.twins[twin.DeviceID] = stored
+
+	return nil
+}
+
+// SaveReported stores the reported state of the twin.
+func (r *FakeDeviceTwinRepository) SaveReported(_ context.Context, twin *entity.DeviceTwin) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.twins[twin.DeviceID]
+	if !ok {
+		stored = *entity.NewDeviceTwin(twin.DeviceID)
+	}
+
+	if r.ConcurrentReports > 0 {
+		r.ConcurrentReports--
+		reported := entity.JSONBMap{"uptime": float64(r.ConcurrentReports)}
+		for key, value := range stored.Reported {
+			if key != "uptime" {
+				reported[key] = value
+			}
+		}
+
+		stored.SetReported(reported, time.Now())
+		r.twins[twin.DeviceID] = stored
+	}
+
+	if stored.ReportedVersion != twin.ReportedVersion-1 {
+		return entity.ErrDeviceTwinVersionConflict
+	}
+
+	stored.Reported, stored.ReportedVersion, stored.ReportedUpdatedAt =
+		twin.Reported, twin.ReportedVersion, twin.ReportedUpdatedAt
+	r.twins[twin.DeviceID] = stored
+
+	return nil
+}
+
+// FakeDeviceTwinPublisher is a fake implementation of service.DeviceTwinPublisher for testing.
+type FakeDeviceTwinPublisher struct {
+	mu     sync.Mutex
+	deltas []service.DeviceTwinDelta
+	// for controlling error case
+	PublishErr error
+}
+
+// PublishDelta records the published delta.
+func (p *FakeDeviceTwinPublisher) PublishDelta(_ context.Context, delta service.DeviceTwinDelta) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.PublishErr != nil {
+		return p.PublishErr
+	}
+
+	p.deltas = append(p.deltas, delta)
+
+	return nil
+}
+
+// Deltas returns the published deltas.
+func (p *FakeDeviceTwinPublisher) Deltas() []service.DeviceTwinDelta {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]service.DeviceTwinDelta(nil), p.deltas...)
+}
+
+// deviceTwinTest holds a DeviceTwinUsecase and its fakes.
+type deviceTwinTest struct {
+	uc          usecase.DeviceTwinUsecase
+	deviceRepo  *FakeDeviceRepository
+	twinRepo    *FakeDeviceTwinRepository
+	auditLogger *FakeAuditLogger
+	publisher   *FakeDeviceTwinPublisher
+}
+
+func newDeviceTwinTest() *deviceTwinTest {
+	tt := &deviceTwinTest{
+		uc:          nil,
+		deviceRepo:  NewFakeDeviceRepository(),
+		twinRepo:    NewFakeDeviceTwinRepository(),
+		auditLogger: NewFakeAuditLogger(),
+		publisher:   &FakeDeviceTwinPublisher{}, //nolint:exhaustruct
+	}
+	tt.uc = usecase.NewDeviceTwinUsecase(tt.deviceRepo, tt.twinRepo, tt.auditLogger, FakeTransactor{}, tt.publisher)
+
+	return tt
+}
+
+// addDevice stores a device with the status.
+func (tt *deviceTwinTest) addDevice(status devicestatus.Status) *entity.Device {
+	device := newTestDevice("hw-twin-01", status)
+	tt.deviceRepo.devices[device.ID] = device
+
+	return device
+}
+
+// TestUpdateDesiredState tests the SetDesiredState, PatchDesiredState and GetDeviceTwin methods.
+func TestUpdateDesiredState(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tt := newDeviceTwinTest()
+	device := tt.addDevice(devicestatus.Active)
+
+	output, err := tt.uc.GetDeviceTwin(ctx, device.ID)
+	require.NoError(t, err)
+	assert.Empty(t, output.Desired)
+	assert.Zero(t, output.DesiredVersion)
+	assert.True(t, output.InSync)
+
+	output, err = tt.uc.SetDesiredState(ctx, usecase.SetDesiredStateInput{
+		DeviceID: device.ID,
+		State:    []byte(`{"config": {"sync_interval_sec": 60, "mode": "eco"}}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), output.DesiredVersion)
+	assert.NotNil(t, output.DesiredUpdatedAt)
+	assert.False(t, output.InSync)
+	assert.Equal(t, map[string]any{"config": map[string]any{"sync_interval_sec": 60.0, "mode": "eco"}}, output.Delta)
+
+	output, err = tt.uc.PatchDesiredState(ctx, usecase.PatchDesiredStateInput{
+		DeviceID: device.ID,
+		Format:   usecase.PatchFormatMerge,
+		Patch:    []byte(`{"config": {"mode": null, "sync_interval_sec": 30}}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), output.DesiredVersion)
+	assert.Equal(t, map[string]any{"config": map[string]any{"sync_interval_sec": 30.0}}, output.Desired)
+
+	// A change that leaves the state as it is is neither saved nor audited nor published.
+	output, err = tt.uc.PatchDesiredState(ctx, usecase.PatchDesiredStateInput{
+		DeviceID: device.ID,
+		Format:   usecase.PatchFormatJSON,
+		Patch:    []byte(`[{"op": "replace", "path": "/config/sync_interval_sec", "value": 30}]`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), output.DesiredVersion)
+
+	deltas := tt.publisher.Deltas()
+	require.Len(t, deltas, 2)
+	assert.Equal(t, service.DeviceTwinDelta{
+		DeviceID: device.ID,
+		Version:  2,
+		State:    map[string]any{"config": map[string]any{"sync_interval_sec": 30.0}},
+	}, deltas[1])
+
+	logs := tt.auditLogger.Logs()
+	require.Len(t, logs, 2)
+	assert.Equal(t, entity.AuditDeviceTwinUpdate, logs[1].Action)
+
+	details := auditDetails(t, logs[1])
+	assert.Equal(t, map[string]any{"config": map[string]any{"sync_interval_sec": 60.0, "mode": "eco"}},
+		details.Before["desired"])
+	assert.Equal(t, 2.0, details.After["desiredVersion"])
+
+	// The state is saved even if the delta cannot be published, as the device receives it when it reports next.
+	tt.publisher.PublishErr = errors.New("broker unavailable")
+
+	output, err = tt.uc.SetDesiredState(ctx, usecase.SetDesiredStateInput{DeviceID: device.ID, State: []byte(`{}`)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), output.DesiredVersion)
+
+	output, err = tt.uc.GetDeviceTwin(ctx, device.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), output.DesiredVersion)
+	assert.Empty(t, output.Desired)
+
+	tests := []struct {
+		name    string
+		run     func() error
+		wantErr error
+	}{
+		{
+			name: "failure: state is not an object",
+			run: func() error {
+				_, err := tt.uc.SetDesiredState(ctx, usecase.SetDesiredStateInput{
+					DeviceID: device.ID, State: []byte(`[]`),
+				})
+
+				return err
+			},
+			wantErr: entity.ErrInvalidTwinState,
+		},
+		{
+			name: "failure: malformed patch",
+			run: func() error {
+				_, err := tt.uc.PatchDesiredState(ctx, usecase.PatchDesiredStateInput{
+					DeviceID: device.ID, Format: usecase.PatchFormatMerge, Patch: []byte(`{`),
+				})
+
+				return err
+			},
+			wantErr: entity.ErrMalformedPatch,
+		},
+		{
+			name: "failure: unknown device",
+			run: func() error {
+				_, err := tt.uc.SetDesiredState(ctx, usecase.SetDesiredStateInput{
+					DeviceID: uuid.New(), State: []byte(`{}`),
+				})
+
+				return err
+			},
+			wantErr: entity.ErrDeviceNotFound,
+		},
+		{
+			name: "failure: unknown device twin",
+			run: func() error {
+				_, err := tt.uc.GetDeviceTwin(ctx, uuid.New())
+
+				return err
+			},
+			wantErr: entity.ErrDeviceNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		require.ErrorIs(t, test.run(), test.wantErr, test.name)
+	}
+}
+
+// TestReportState tests the ReportState method.
+func TestReportState(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tt := newDeviceTwinTest()
+	device := tt.addDevice(devicestatus.Active)
+
+	_, err := tt.uc.SetDesiredState(ctx, usecase.SetDesiredStateInput{
+		DeviceID: device.ID,
+		State:    []byte(`{"config": {"sync_interval_sec": 60, "mode": "eco"}}`),
+	})
+	require.NoError(t, err)
+
+	// The device reports part of the desired state, and receives the remaining delta.
+	output, err := tt.uc.ReportState(ctx, usecase.ReportDeviceStateInput{
+		DeviceID: device.ID,
+		State:    []byte(`{"config": {"sync_interval_sec": 60}, "firmware": "1.2.0"}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), output.ReportedVersion)
+	assert.Equal(t, map[string]any{"config": map[string]any{"mode": "eco"}}, output.Delta)
+
+	deltas := tt.publisher.Deltas()
+	require.Len(t, deltas, 2)
+	assert.Equal(t, map[string]any{"config": map[string]any{"mode": "eco"}}, deltas[1].State)
+
+	// An empty report only asks for the delta.
+	output, err = tt.uc.ReportState(ctx, usecase.ReportDeviceStateInput{DeviceID: device.ID, State: []byte(`{}`)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), output.ReportedVersion)
+	assert.Len(t, tt.publisher.Deltas(), 3)
+
+	// A report is merged again into the reports saved meanwhile.
+	tt.twinRepo.ConcurrentReports = 1
+
+	output, err = tt.uc.ReportState(ctx, usecase.ReportDeviceStateInput{
+		DeviceID: device.ID,
+		State:    []byte(`{"config": {"mode": "eco"}, "firmware": null}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), output.ReportedVersion)
+	assert.Equal(t, map[string]any{
+		"config": map[string]any{"sync_interval_sec": 60.0, "mode": "eco"},
+		"uptime": 0.0,
+	}, output.Reported)
+	assert.True(t, output.InSync)
+	assert.Len(t, tt.publisher.Deltas(), 3, "nothing is published once the device is in sync")
+	assert.Len(t, tt.auditLogger.Logs(), 1, "reported states are not audited")
+
+	// A report keeps failing if it always finds another report saved meanwhile.
+	tt.twinRepo.ConcurrentReports = 3
+
+	_, err = tt.uc.ReportState(ctx, usecase.ReportDeviceStateInput{DeviceID: device.ID, State: []byte(`{"led": "on"}`)})
+	require.ErrorIs(t, err, entity.ErrDeviceTwinVersionConflict)
+
+	_, err = tt.uc.ReportState(ctx, usecase.ReportDeviceStateInput{DeviceID: device.ID, State: []byte(`"on"`)})
+	require.ErrorIs(t, err, entity.ErrInvalidTwinState)
+
+	suspended := tt.addDevice(devicestatus.Suspended)
+
+	_, err = tt.uc.ReportState(ctx, usecase.ReportDeviceStateInput{DeviceID: suspended.ID, State: []byte(`{}`)})
+	require.ErrorIs(t, err, usecase.ErrDeviceNotActive)
+
+	_, err = tt.uc.ReportState(ctx, usecase.ReportDeviceStateInput{DeviceID: uuid.New(), State: []byte(`{}`)})
+	require.ErrorIs(t, err, entity.ErrDeviceNotFound)
+}
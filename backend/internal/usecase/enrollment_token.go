@@ -0,0 +1,245 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+const (
+	// DefaultEnrollmentTokenTTL is the default lifetime of an enrollment token.
+	DefaultEnrollmentTokenTTL = 24 * time.Hour
+	// DefaultEnrollmentTokenMaxTTL is the default upper bound of the lifetime an operator can request.
+	DefaultEnrollmentTokenMaxTTL = 7 * 24 * time.Hour
+)
+
+// EnrollmentTokenConfig configures the lifetime of enrollment tokens.
+type EnrollmentTokenConfig struct {
+	// DefaultTTL is used when the request does not specify a TTL.
+	DefaultTTL time.Duration
+	// MaxTTL is the maximum TTL a request can specify.
+	MaxTTL time.Duration
+}
+
+// withDefaults returns the config with zero values replaced by the defaults.
+func (c EnrollmentTokenConfig) withDefaults() EnrollmentTokenConfig {
+	if c.DefaultTTL <= 0 {
+		c.DefaultTTL = DefaultEnrollmentTokenTTL
+	}
+
+	if c.MaxTTL <= 0 {
+		c.MaxTTL = DefaultEnrollmentTokenMaxTTL
+	}
+
+	return c
+}
+
+// ttl returns the lifetime of a token requested for ttlSeconds, or the default TTL if ttlSeconds is zero.
+func (c EnrollmentTokenConfig) ttl(ttlSeconds int64) (time.Duration, error) {
+	if ttlSeconds == 0 {
+		return c.DefaultTTL, nil
+	}
+
+	return secondsToDuration(ttlSeconds, 1, c.MaxTTL, ErrInvalidTTL)
+}
+
+// secondsToDuration converts a duration requested in seconds, which must be between minSeconds and maxDuration,
+// and wraps errInvalid otherwise.
+func secondsToDuration(seconds, minSeconds int64, maxDuration time.Duration, errInvalid error) (time.Duration, error) {
+	// The bound is checked in seconds first, so that a huge value cannot overflow the Duration.
+	maxSeconds := int64(maxDuration / time.Second)
+	if seconds < minSeconds || seconds > maxSeconds {
+		return 0, fmt.Errorf("%w: must be between %d and %d seconds", errInvalid, minSeconds, maxSeconds)
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// EnrollmentTokenUsecase defines the interface for managing enrollment tokens.
+type EnrollmentTokenUsecase interface {
+	// CreateEnrollmentToken issues a new one-time token for a device and returns its secret once.
+	CreateEnrollmentToken(ctx context.Context, input CreateEnrollmentTokenInput) (*CreatedEnrollmentTokenOutput, error)
+	// ListEnrollmentTokens retrieves all tokens of a device without their secrets.
+	ListEnrollmentTokens(ctx context.Context, deviceID uuid.UUID) ([]*EnrollmentTokenOutput, error)
+	// RevokeEnrollmentToken revokes an unused token of a device.
+	RevokeEnrollmentToken(ctx context.Context, deviceID, tokenID uuid.UUID) (*EnrollmentTokenOutput, error)
+}
+
+// enrollmentTokenUsecase is the implementation of the EnrollmentTokenUsecase interface.
+type enrollmentTokenUsecase struct {
+	deviceRepo  repository.DeviceRepository
+	tokenRepo   repository.EnrollmentTokenRepository
+	auditLogger repository.AuditLogger
+	transactor  repository.Transactor
+	config      EnrollmentTokenConfig
+	now         func() time.Time
+}
+
+// NewEnrollmentTokenUsecase creates a new instance of enrollmentTokenUsecase.
+// Zero values in config are replaced by the defaults.
+//
+//nolint:ireturn
+func NewEnrollmentTokenUsecase(
+	deviceRepo repository.DeviceRepository,
+	tokenRepo repository.EnrollmentTokenRepository,
+	auditLogger repository.AuditLogger,
+	transactor repository.Transactor,
+	config EnrollmentTokenConfig,
+) EnrollmentTokenUsecase {
+	return &enrollmentTokenUsecase{
+		deviceRepo:  deviceRepo,
+		tokenRepo:   tokenRepo,
+		auditLogger: auditLogger,
+		transactor:  transactor,
+		config:      config.withDefaults(),
+		now:         time.Now,
+	}
+}
+
+// CreateEnrollmentToken issues a new one-time token for a device and returns its secret once.
+func (uc *enrollmentTokenUsecase) CreateEnrollmentToken(
+	ctx context.Context,
+	input CreateEnrollmentTokenInput,
+) (*CreatedEnrollmentTokenOutput, error) {
+	ttl, err := uc.config.ttl(input.TTLSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	device, err := uc.findDevice(ctx, input.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if device.Status.IsTerminal() {
+		return nil, fmt.Errorf("%w: %s", ErrDeviceNotEnrollable, device.Status)
+	}
+
+	now := uc.now()
+
+	token, secret, err := entity.NewEnrollmentToken(device.ID, ttl, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new enrollment token entity: %w", err)
+	}
+
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := uc.tokenRepo.Save(ctx, token)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+
+		return recordAudit(ctx, uc.auditLogger, auditEvent{
+			action:   entity.AuditTokenCreate,
+			deviceID: device.ID,
+			target:   enrollmentTokenTarget(token.ID),
+			before:   nil,
+			after:    enrollmentTokenSnapshot(token, now),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreatedEnrollmentTokenOutput{
+		EnrollmentTokenOutput: *NewEnrollmentTokenOutput(token, now),
+		Token:                 secret,
+	}, nil
+}
+
+// ListEnrollmentTokens retrieves all tokens of a device without their secrets.
+func (uc *enrollmentTokenUsecase) ListEnrollmentTokens(
+	ctx context.Context,
+	deviceID uuid.UUID,
+) ([]*EnrollmentTokenOutput, error) {
+	_, err := uc.findDevice(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := uc.tokenRepo.FindByDeviceID(ctx, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDBFindEnrollmentToken, err)
+	}
+
+	now := uc.now()
+	outputs := make([]*EnrollmentTokenOutput, 0, len(tokens))
+
+	for _, token := range tokens {
+		outputs = append(outputs, NewEnrollmentTokenOutput(token, now))
+	}
+
+	return outputs, nil
+}
+
+// RevokeEnrollmentToken revokes an unused token of a device.
+func (uc *enrollmentTokenUsecase) RevokeEnrollmentToken(
+	ctx context.Context,
+	deviceID, tokenID uuid.UUID,
+) (*EnrollmentTokenOutput, error) {
+	token, err := uc.tokenRepo.FindByID(ctx, tokenID)
+	if err != nil {
+		if isNotFound(err, entity.ErrEnrollmentTokenNotFound) {
+			return nil, entity.ErrEnrollmentTokenNotFound
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrDBFindEnrollmentToken, err)
+	}
+
+	// A token of another device is treated as non-existent.
+	if token.DeviceID != deviceID {
+		return nil, entity.ErrEnrollmentTokenNotFound
+	}
+
+	now := uc.now()
+	before := enrollmentTokenSnapshot(token, now)
+
+	err = token.Revoke(now)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		// The repository re-checks the state atomically, in case the token was used in the meantime.
+		err := uc.tokenRepo.Revoke(ctx, token.ID, now)
+		if err != nil {
+			if errors.Is(err, entity.ErrEnrollmentTokenUsed) {
+				return err
+			}
+
+			return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+
+		return recordAudit(ctx, uc.auditLogger, auditEvent{
+			action:   entity.AuditTokenRevoke,
+			deviceID: token.DeviceID,
+			target:   enrollmentTokenTarget(token.ID),
+			before:   before,
+			after:    enrollmentTokenSnapshot(token, now),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEnrollmentTokenOutput(token, now), nil
+}
+
+// findDevice retrieves a device, mapping a missing record to entity.ErrDeviceNotFound.
+func (uc *enrollmentTokenUsecase) findDevice(ctx context.Context, id uuid.UUID) (*entity.Device, error) {
+	device, err := uc.deviceRepo.FindByID(ctx, id)
+	if err != nil {
+		if isNotFound(err, entity.ErrDeviceNotFound) {
+			return nil, entity.ErrDeviceNotFound
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrDBFindByID, err)
+	}
+
+	return device, nil
+}
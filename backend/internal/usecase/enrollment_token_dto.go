@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+)
+
+// CreateEnrollmentTokenInput is the input data for issuing an EnrollmentToken.
+type CreateEnrollmentTokenInput struct {
+	DeviceID   uuid.UUID
+	TTLSeconds int64 // Optional: if zero, the default TTL is used.
+}
+
+// EnrollmentTokenOutput is the output data for displaying EnrollmentToken information.
+// It never contains the secret or its hash.
+type EnrollmentTokenOutput struct {
+	ID        uuid.UUID  `json:"id"`
+	DeviceID  uuid.UUID  `json:"deviceId"`
+	State     string     `json:"state"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// CreatedEnrollmentTokenOutput is the output data for a newly issued EnrollmentToken.
+// Token is the plaintext secret, which is returned only once.
+type CreatedEnrollmentTokenOutput struct {
+	EnrollmentTokenOutput
+
+	Token string `json:"token"`
+}
+
+// NewEnrollmentTokenOutput creates a new EnrollmentTokenOutput from an entity.
+func NewEnrollmentTokenOutput(token *entity.EnrollmentToken, now time.Time) *EnrollmentTokenOutput {
+	return &EnrollmentTokenOutput{
+		ID:        token.ID,
+		DeviceID:  token.DeviceID,
+		State:     string(token.State(now)),
+		ExpiresAt: token.ExpiresAt,
+		UsedAt:    token.UsedAt,
+		RevokedAt: token.RevokedAt,
+		CreatedAt: token.CreatedAt,
+	}
+}
@@ -0,0 +1,296 @@
+package usecase_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEnrollmentTokenTestDevice creates a device with the given status for enrollment token tests.
+func newEnrollmentTokenTestDevice(status devicestatus.Status) *entity.Device {
+	device := newTestDevice("hw-token-"+uuid.NewString(), status)
+	device.Name = "Token Device"
+
+	return device
+}
+
+// TestCreateEnrollmentToken tests the CreateEnrollmentToken method.
+func TestCreateEnrollmentToken(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	config := usecase.EnrollmentTokenConfig{DefaultTTL: time.Hour, MaxTTL: 2 * time.Hour}
+
+	tests := []struct {
+		name         string
+		desc         string
+		deviceStatus devicestatus.Status
+		ttlSeconds   int64
+		unknownID    bool
+		wantTTL      time.Duration
+		wantErr      error
+	}{
+		{
+			name:         "success: default TTL",
+			desc:         "Verify that a token is issued with the default TTL when none is requested.",
+			deviceStatus: devicestatus.Unregistered,
+			ttlSeconds:   0,
+			unknownID:    false,
+			wantTTL:      time.Hour,
+			wantErr:      nil,
+		},
+		{
+			name:         "success: requested TTL",
+			desc:         "Verify that a token is issued with the requested TTL within the maximum.",
+			deviceStatus: devicestatus.Active,
+			ttlSeconds:   600,
+			unknownID:    false,
+			wantTTL:      10 * time.Minute,
+			wantErr:      nil,
+		},
+		{
+			name:         "failure: TTL above the maximum",
+			desc:         "Verify that a TTL longer than the maximum is rejected.",
+			deviceStatus: devicestatus.Unregistered,
+			ttlSeconds:   3 * 3600,
+			unknownID:    false,
+			wantTTL:      0,
+			wantErr:      usecase.ErrInvalidTTL,
+		},
+		{
+			name:         "failure: TTL overflowing a duration",
+			desc:         "Verify that a TTL too large for a time.Duration is rejected instead of wrapping around.",
+			deviceStatus: devicestatus.Unregistered,
+			ttlSeconds:   math.MaxInt64,
+			unknownID:    false,
+			wantTTL:      0,
+			wantErr:      usecase.ErrInvalidTTL,
+		},
+		{
+			name:         "failure: negative TTL",
+			desc:         "Verify that a negative TTL is rejected.",
+			deviceStatus: devicestatus.Unregistered,
+			ttlSeconds:   -1,
+			unknownID:    false,
+			wantTTL:      0,
+			wantErr:      usecase.ErrInvalidTTL,
+		},
+		{
+			name:         "failure: revoked device",
+			desc:         "Verify that no token is issued for a REVOKED device.",
+			deviceStatus: devicestatus.Revoked,
+			ttlSeconds:   0,
+			unknownID:    false,
+			wantTTL:      0,
+			wantErr:      usecase.ErrDeviceNotEnrollable,
+		},
+		{
+			name:         "failure: device not found",
+			desc:         "Verify that a 'device not found' error is returned for a non-existent device.",
+			deviceStatus: devicestatus.Unregistered,
+			ttlSeconds:   0,
+			unknownID:    true,
+			wantTTL:      0,
+			wantErr:      entity.ErrDeviceNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			device := newEnrollmentTokenTestDevice(tt.deviceStatus)
+			deviceRepo := NewFakeDeviceRepository()
+			deviceRepo.devices[device.ID] = device
+			tokenRepo := NewFakeEnrollmentTokenRepository()
+			auditLogger := NewFakeAuditLogger()
+
+			uc := usecase.NewEnrollmentTokenUsecase(deviceRepo, tokenRepo, auditLogger, FakeTransactor{}, config)
+
+			deviceID := device.ID
+			if tt.unknownID {
+				deviceID = uuid.New()
+			}
+
+			got, err := uc.CreateEnrollmentToken(ctx, usecase.CreateEnrollmentTokenInput{
+				DeviceID:   deviceID,
+				TTLSeconds: tt.ttlSeconds,
+			})
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+				require.Empty(t, tokenRepo.tokens)
+				require.Empty(t, auditLogger.Logs())
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.NotEmpty(t, got.Token)
+			assert.Equal(t, string(entity.EnrollmentTokenPending), got.State)
+			assert.WithinDuration(t, time.Now().Add(tt.wantTTL), got.ExpiresAt, 5*time.Second)
+
+			// Only the hash of the secret is stored.
+			stored, ok := tokenRepo.tokens[got.ID]
+			require.True(t, ok)
+			assert.Equal(t, entity.HashEnrollmentToken(got.Token), stored.TokenHash)
+			assert.NotContains(t, stored.TokenHash, got.Token)
+
+			// The issuance is recorded without the secret.
+			logs := auditLogger.Logs()
+			require.Len(t, logs, 1)
+			assert.Equal(t, entity.AuditTokenCreate, logs[0].Action)
+			assert.Equal(t, &device.ID, logs[0].TargetDeviceID)
+			assert.Equal(t, "enrollment-token/"+got.ID.String(), auditDetails(t, logs[0]).Target)
+			assert.NotContains(t, logs[0].Details, got.Token)
+			assert.NotContains(t, logs[0].Details, stored.TokenHash)
+		})
+	}
+}
+
+// TestListEnrollmentTokens tests the ListEnrollmentTokens method.
+func TestListEnrollmentTokens(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	device := newEnrollmentTokenTestDevice(devicestatus.Unregistered)
+	deviceRepo := NewFakeDeviceRepository()
+	deviceRepo.devices[device.ID] = device
+	tokenRepo := NewFakeEnrollmentTokenRepository()
+
+	uc := usecase.NewEnrollmentTokenUsecase(deviceRepo, tokenRepo, NewFakeAuditLogger(), FakeTransactor{},
+		usecase.EnrollmentTokenConfig{
+			DefaultTTL: 0,
+			MaxTTL:     0,
+		},
+	)
+
+	created, err := uc.CreateEnrollmentToken(ctx, usecase.CreateEnrollmentTokenInput{DeviceID: device.ID, TTLSeconds: 0})
+	require.NoError(t, err)
+
+	got, err := uc.ListEnrollmentTokens(ctx, device.ID)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, created.ID, got[0].ID)
+	assert.Equal(t, created.EnrollmentTokenOutput, *got[0])
+
+	_, err = uc.ListEnrollmentTokens(ctx, uuid.New())
+	require.ErrorIs(t, err, entity.ErrDeviceNotFound)
+}
+
+// TestRevokeEnrollmentToken tests the RevokeEnrollmentToken method.
+func TestRevokeEnrollmentToken(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	usedAt := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name          string
+		desc          string
+		tokenSetup    func(*entity.EnrollmentToken)
+		otherDevice   bool
+		wantErr       error
+		wantRevokedAt bool
+	}{
+		{
+			name:          "success: revoke a pending token",
+			desc:          "Verify that a pending token is revoked.",
+			tokenSetup:    nil,
+			otherDevice:   false,
+			wantErr:       nil,
+			wantRevokedAt: true,
+		},
+		{
+			name: "failure: token already used",
+			desc: "Verify that a consumed token cannot be revoked.",
+			tokenSetup: func(token *entity.EnrollmentToken) {
+				token.UsedAt = &usedAt
+			},
+			otherDevice:   false,
+			wantErr:       entity.ErrEnrollmentTokenUsed,
+			wantRevokedAt: false,
+		},
+		{
+			name: "failure: token already revoked",
+			desc: "Verify that revoking twice is reported.",
+			tokenSetup: func(token *entity.EnrollmentToken) {
+				token.RevokedAt = &usedAt
+			},
+			otherDevice:   false,
+			wantErr:       entity.ErrEnrollmentTokenRevoked,
+			wantRevokedAt: true,
+		},
+		{
+			name:          "failure: token of another device",
+			desc:          "Verify that a token cannot be revoked through another device's path.",
+			tokenSetup:    nil,
+			otherDevice:   true,
+			wantErr:       entity.ErrEnrollmentTokenNotFound,
+			wantRevokedAt: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			device := newEnrollmentTokenTestDevice(devicestatus.Unregistered)
+			deviceRepo := NewFakeDeviceRepository()
+			deviceRepo.devices[device.ID] = device
+			tokenRepo := NewFakeEnrollmentTokenRepository()
+
+			token, _, err := entity.NewEnrollmentToken(device.ID, time.Hour, time.Now())
+			require.NoError(t, err)
+			require.NoError(t, tokenRepo.Save(ctx, token))
+
+			if tt.tokenSetup != nil {
+				tt.tokenSetup(token)
+			}
+
+			auditLogger := NewFakeAuditLogger()
+			uc := usecase.NewEnrollmentTokenUsecase(deviceRepo, tokenRepo, auditLogger, FakeTransactor{},
+				usecase.EnrollmentTokenConfig{
+					DefaultTTL: 0,
+					MaxTTL:     0,
+				},
+			)
+
+			deviceID := device.ID
+			if tt.otherDevice {
+				deviceID = uuid.New()
+			}
+
+			got, err := uc.RevokeEnrollmentToken(ctx, deviceID, token.ID)
+
+			assert.Equal(t, tt.wantRevokedAt, tokenRepo.tokens[token.ID].RevokedAt != nil)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+				require.Empty(t, auditLogger.Logs())
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, string(entity.EnrollmentTokenRevoked), got.State)
+
+			// The revocation is recorded with the state change.
+			require.Equal(t, []entity.AuditAction{entity.AuditTokenRevoke}, auditLogger.Actions())
+			details := auditDetails(t, auditLogger.Logs()[0])
+			assert.Equal(t, string(entity.EnrollmentTokenPending), details.Before["state"])
+			assert.Equal(t, string(entity.EnrollmentTokenRevoked), details.After["state"])
+		})
+	}
+}
@@ -9,4 +9,95 @@ var (
 	ErrDBFindAll  = errors.New("db find all error")
 	ErrDBFindByID = errors.New("db find by id error")
 	ErrDBDelete   = errors.New("db delete error")
+	// ErrDBFindByHardwareID is returned when there is an error finding a device by its hardware ID.
+	ErrDBFindByHardwareID = errors.New("db find by hardware id error")
+	// ErrDBFindEnrollmentToken is returned when there is an error finding an enrollment token.
+	ErrDBFindEnrollmentToken = errors.New("db find enrollment token error")
+	// ErrInvalidEnrollmentToken is returned when provisioning is attempted with unknown or mismatching credentials.
+	ErrInvalidEnrollmentToken = errors.New("invalid enrollment token")
+	// ErrInvalidTTL is returned when a requested token or command lifetime is out of range.
+	ErrInvalidTTL = errors.New("invalid ttl")
+	// ErrDeviceNotEnrollable is returned when an enrollment token is requested for a device that cannot be provisioned.
+	ErrDeviceNotEnrollable = errors.New("device cannot be enrolled")
+	// ErrDBFindCertificate is returned when there is an error finding certificates.
+	ErrDBFindCertificate = errors.New("db find certificate error")
+	// ErrCertificateParse is returned when a stored certificate cannot be parsed.
+	ErrCertificateParse = errors.New("failed to parse stored certificate")
+	// ErrDBRevokeCertificate is returned when there is an error persisting the revocation of a certificate.
+	ErrDBRevokeCertificate = errors.New("db revoke certificate error")
+	// ErrCRLSign is returned when the certificate revocation list cannot be signed.
+	ErrCRLSign = errors.New("failed to sign crl")
+	// ErrCRLPublish is returned when the certificate revocation list cannot be published.
+	ErrCRLPublish = errors.New("failed to publish crl")
+	// ErrOCSPSign is returned when an OCSP response cannot be signed.
+	ErrOCSPSign = errors.New("failed to sign ocsp response")
+	// ErrAuditLog is returned when an audit log entry cannot be recorded.
+	ErrAuditLog = errors.New("failed to record audit log")
+	// ErrDBFindAuditLogs is returned when there is an error finding audit log entries.
+	ErrDBFindAuditLogs = errors.New("db find audit logs error")
+	// ErrInvalidAuditLogFilter is returned when an audit log query has an invalid filter.
+	ErrInvalidAuditLogFilter = errors.New("invalid audit log filter")
+	// ErrDBFindSensorData is returned when there is an error finding sensor data.
+	ErrDBFindSensorData = errors.New("db find sensor data error")
+	// ErrInvalidSensorDataQuery is returned when a sensor data query has an invalid range or limit.
+	ErrInvalidSensorDataQuery = errors.New("invalid sensor data query")
+	// ErrDeviceNotActive is returned when a device that is not ACTIVE reports sensor data.
+	ErrDeviceNotActive = errors.New("device is not active")
+	// ErrInvalidSensorDataPayload is returned when reported sensor data is not a JSON object.
+	ErrInvalidSensorDataPayload = errors.New("invalid sensor data payload")
+	// ErrDeviceUnauthorized is returned when a device presents a client certificate that does not allow it to connect.
+	ErrDeviceUnauthorized = errors.New("device is not authorized to connect")
+	// ErrIngestionStopped is returned when sensor data is reported after the ingestion has stopped.
+	ErrIngestionStopped = errors.New("ingestion has stopped")
+	// ErrInvalidDeviceQuery is returned when a device query has an invalid filter, sort order, cursor or limit.
+	ErrInvalidDeviceQuery = errors.New("invalid device query")
+	// ErrUnsupportedPatchFormat is returned when a device is patched with a format other than the known ones.
+	ErrUnsupportedPatchFormat = errors.New("unsupported patch format")
+	// ErrInvalidDeviceImport is returned when a device import file cannot be read, e.g. because it is not valid CSV,
+	// a column is unknown or it has too many rows.
+	ErrInvalidDeviceImport = errors.New("invalid device import")
+	// ErrDeviceExportWrite is returned when an export cannot be written, e.g. because the client went away.
+	ErrDeviceExportWrite = errors.New("failed to write device export")
+	// ErrInvalidDeviceGroupRule is returned when the membership rule of a device group is not a valid metadata filter.
+	ErrInvalidDeviceGroupRule = errors.New("invalid device group rule")
+	// ErrInvalidDeviceGroupAction is returned when an action on a device group is unknown or lacks its parameters.
+	ErrInvalidDeviceGroupAction = errors.New("invalid device group action")
+	// ErrDBDeviceGroupMembers is returned when there is an error adding or removing members of a device group.
+	ErrDBDeviceGroupMembers = errors.New("db device group members error")
+	// ErrChildDeviceNotFound is returned when attaching a device that does not exist to a parent.
+	ErrChildDeviceNotFound = errors.New("child device not found")
+	// ErrDBDeviceTopology is returned when there is an error reading or locking the topology of devices.
+	ErrDBDeviceTopology = errors.New("db device topology error")
+	// ErrNotGatewayChild is returned when a gateway reports sensor data for a device that is not its child.
+	ErrNotGatewayChild = errors.New("device is not a child of the gateway")
+	// ErrChildDeviceNotActive is returned when a gateway reports sensor data for a child that is not ACTIVE.
+	ErrChildDeviceNotActive = errors.New("child device is not active")
+	// ErrInvalidCommandResponse is returned when a device answers a command with a payload that is not
+	// {"id": ..., "status": "ACKED" or "FAILED", ...}.
+	ErrInvalidCommandResponse = errors.New("invalid device command response")
+	// ErrFirmwareStorage is returned when a firmware image cannot be written, read or deleted.
+	ErrFirmwareStorage = errors.New("firmware storage error")
+	// ErrFirmwareSign is returned when a firmware image cannot be signed.
+	ErrFirmwareSign = errors.New("failed to sign firmware")
+	// ErrEmptyFirmwareImage is returned when an uploaded firmware image has no content.
+	ErrEmptyFirmwareImage = errors.New("firmware image is empty")
+	// ErrInvalidFirmwareCampaignFilter is returned when the filter of a firmware campaign is not a valid
+	// metadata filter.
+	ErrInvalidFirmwareCampaignFilter = errors.New("invalid firmware campaign filter")
+	// ErrNoFirmwareCampaignDevices is returned when no device would be updated by a firmware campaign.
+	ErrNoFirmwareCampaignDevices = errors.New("no devices to update")
+	// ErrInvalidFirmwareReport is returned when a device reports the progress of a firmware update with a payload
+	// that is not {"campaignId": ..., "status": ..., "progress": ..., "error": ...}.
+	ErrInvalidFirmwareReport = errors.New("invalid firmware progress report")
+	// ErrInvalidFirmwareUpdateFilter is returned when the devices of a firmware campaign are listed with an unknown
+	// status.
+	ErrInvalidFirmwareUpdateFilter = errors.New("invalid firmware update filter")
+	// ErrInvalidFirmwareTarget is returned when the target of a firmware artifact is not a valid metadata filter.
+	ErrInvalidFirmwareTarget = errors.New("invalid firmware target")
+	// ErrDBDevicePresence is returned when there is an error recording the presence of devices.
+	ErrDBDevicePresence = errors.New("db device presence error")
+	// ErrDeviceSuspended is returned when a SUSPENDED device tries to provision itself again.
+	ErrDeviceSuspended = errors.New("device is suspended")
+	// ErrDBFirmwareSigningKeyLock is returned when there is an error locking the firmware signing keys.
+	ErrDBFirmwareSigningKeyLock = errors.New("db firmware signing key lock error")
 )
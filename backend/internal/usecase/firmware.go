@@ -0,0 +1,246 @@
+package usecase
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"backend/internal/domain/service"
+)
+
+// FirmwareUsecase defines the interface for managing the firmware registry.
+type FirmwareUsecase interface {
+	// UploadFirmware stores and signs a firmware image, and registers it under its version.
+	UploadFirmware(ctx context.Context, input UploadFirmwareInput) (*FirmwareOutput, error)
+	// GetFirmware retrieves a firmware artifact by its ID.
+	GetFirmware(ctx context.Context, id uuid.UUID) (*FirmwareOutput, error)
+	// ListFirmware retrieves all the firmware artifacts, newest first.
+	ListFirmware(ctx context.Context) ([]*FirmwareOutput, error)
+	// OpenFirmwareImage opens the image of a firmware artifact for download.
+	OpenFirmwareImage(ctx context.Context, id uuid.UUID) (*FirmwareImageOutput, error)
+	// DeleteFirmware deletes a firmware artifact and its image, unless a campaign rolls it out.
+	DeleteFirmware(ctx context.Context, id uuid.UUID) error
+}
+
+// firmwareUsecase is the implementation of the FirmwareUsecase interface.
+type firmwareUsecase struct {
+	artifactRepo repository.FirmwareArtifactRepository
+	storage      service.FirmwareStorage
+	signer       service.FirmwareSigner
+	manifests    FirmwareManifestUsecase
+	auditLogger  repository.AuditLogger
+	transactor   repository.Transactor
+}
+
+// NewFirmwareUsecase creates a new instance of firmwareUsecase.
+// The manifest of every uploaded artifact is signed through manifests.
+//
+//nolint:ireturn
+func NewFirmwareUsecase(
+	artifactRepo repository.FirmwareArtifactRepository,
+	storage service.FirmwareStorage,
+	signer service.FirmwareSigner,
+	manifests FirmwareManifestUsecase,
+	auditLogger repository.AuditLogger,
+	transactor repository.Transactor,
+) FirmwareUsecase {
+	return &firmwareUsecase{
+		artifactRepo: artifactRepo,
+		storage:      storage,
+		signer:       signer,
+		manifests:    manifests,
+		auditLogger:  auditLogger,
+		transactor:   transactor,
+	}
+}
+
+// UploadFirmware stores a firmware image while hashing it, signs its hash with the platform signing key,
+// and registers it under its version along with its signed manifest and an audit log entry.
+// If the version is taken, the error wraps entity.ErrFirmwareVersionTaken. The image is not kept if it fails.
+func (uc *firmwareUsecase) UploadFirmware(ctx context.Context, input UploadFirmwareInput) (*FirmwareOutput, error) {
+	_, err := newDeviceFilter("", "", "", input.Target)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFirmwareTarget, err)
+	}
+
+	artifact, err := entity.NewFirmwareArtifact(
+		input.Version, input.Description, input.MinHardwareRevision, input.Target,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := uc.storage.Put(ctx, input.Image)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFirmwareStorage, err)
+	}
+
+	err = uc.register(ctx, artifact, image)
+	if err != nil {
+		uc.deleteImage(ctx, image.Key)
+
+		return nil, err
+	}
+
+	return NewFirmwareOutput(artifact), nil
+}
+
+// GetFirmware retrieves a firmware artifact by its ID.
+func (uc *firmwareUsecase) GetFirmware(ctx context.Context, id uuid.UUID) (*FirmwareOutput, error) {
+	artifact, err := findFirmwareArtifact(ctx, uc.artifactRepo, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFirmwareOutput(artifact), nil
+}
+
+// ListFirmware retrieves all the firmware artifacts, newest first.
+func (uc *firmwareUsecase) ListFirmware(ctx context.Context) ([]*FirmwareOutput, error) {
+	artifacts, err := uc.artifactRepo.FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDBFindAll, err)
+	}
+
+	outputs := make([]*FirmwareOutput, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		outputs = append(outputs, NewFirmwareOutput(artifact))
+	}
+
+	return outputs, nil
+}
+
+// OpenFirmwareImage opens the image of a firmware artifact for download.
+func (uc *firmwareUsecase) OpenFirmwareImage(ctx context.Context, id uuid.UUID) (*FirmwareImageOutput, error) {
+	artifact, err := findFirmwareArtifact(ctx, uc.artifactRepo, id)
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := uc.storage.Open(ctx, artifact.StorageKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFirmwareStorage, err)
+	}
+
+	return &FirmwareImageOutput{Firmware: NewFirmwareOutput(artifact), Image: image}, nil
+}
+
+// DeleteFirmware deletes a firmware artifact along with an audit log entry, and then its image.
+// If a campaign rolls it out, the error wraps entity.ErrFirmwareArtifactInUse.
+func (uc *firmwareUsecase) DeleteFirmware(ctx context.Context, id uuid.UUID) error {
+	var artifact *entity.FirmwareArtifact
+
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		artifact, err = findFirmwareArtifact(ctx, uc.artifactRepo, id)
+		if err != nil {
+			return err
+		}
+
+		err = uc.artifactRepo.Delete(ctx, id)
+		if err != nil {
+			if errors.Is(err, entity.ErrFirmwareArtifactInUse) || errors.Is(err, entity.ErrFirmwareArtifactNotFound) {
+				return err
+			}
+
+			return fmt.Errorf("%w: %w", ErrDBDelete, err)
+		}
+
+		return recordAudit(ctx, uc.auditLogger, auditEvent{
+			action:   entity.AuditFirmwareDelete,
+			deviceID: uuid.Nil,
+			target:   firmwareTarget(id),
+			before:   firmwareSnapshot(artifact),
+			after:    nil,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	// The image is only deleted once the artifact is, so that it is never missing for an artifact.
+	uc.deleteImage(ctx, artifact.StorageKey)
+
+	return nil
+}
+
+// register signs a stored image and saves its artifact along with its signed manifest and an audit log entry.
+func (uc *firmwareUsecase) register(
+	ctx context.Context,
+	artifact *entity.FirmwareArtifact,
+	image *service.StoredFirmwareImage,
+) error {
+	if image.Size == 0 {
+		return ErrEmptyFirmwareImage
+	}
+
+	signature, err := uc.signer.SignFirmware(ctx, image.SHA256)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrFirmwareSign, err)
+	}
+
+	artifact.Size = image.Size
+	artifact.SHA256 = hex.EncodeToString(image.SHA256)
+	artifact.Signature = signature.Signature
+	artifact.SignatureAlgorithm = signature.Algorithm
+	artifact.StorageKey = image.Key
+
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := uc.artifactRepo.Save(ctx, artifact)
+		if err != nil {
+			if errors.Is(err, entity.ErrFirmwareVersionTaken) {
+				return err
+			}
+
+			return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+
+		_, err = uc.manifests.SignFirmwareManifest(ctx, artifact.ID)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, uc.auditLogger, auditEvent{
+			action:   entity.AuditFirmwareUpload,
+			deviceID: uuid.Nil,
+			target:   firmwareTarget(artifact.ID),
+			before:   nil,
+			after:    firmwareSnapshot(artifact),
+		})
+	})
+}
+
+// deleteImage deletes a firmware image that is no longer used. A failure only leaves an orphaned file behind,
+// so it is logged rather than returned.
+func (uc *firmwareUsecase) deleteImage(ctx context.Context, key string) {
+	err := uc.storage.Delete(ctx, key)
+	if err != nil {
+		log.Printf("failed to delete firmware image %s: %v", key, err)
+	}
+}
+
+// findFirmwareArtifact retrieves a firmware artifact, translating a missing artifact into
+// entity.ErrFirmwareArtifactNotFound.
+func findFirmwareArtifact(
+	ctx context.Context,
+	artifactRepo repository.FirmwareArtifactRepository,
+	id uuid.UUID,
+) (*entity.FirmwareArtifact, error) {
+	artifact, err := artifactRepo.FindByID(ctx, id)
+	if err != nil {
+		if isNotFound(err, entity.ErrFirmwareArtifactNotFound) {
+			return nil, entity.ErrFirmwareArtifactNotFound
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrDBFindByID, err)
+	}
+
+	return artifact, nil
+}
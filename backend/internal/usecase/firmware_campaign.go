@@ -0,0 +1,902 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/VO/campaignstatus"
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/VO/updatestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+const (
+	// DefaultFirmwareUpdateTimeout is the default time a device has to complete its firmware update.
+	DefaultFirmwareUpdateTimeout = time.Hour
+	// DefaultFirmwareUpdateMaxTimeout is the default upper bound of the update timeout an operator can request.
+	// The firmware updates are sent as device commands, so it must not exceed the maximum TTL of the commands.
+	DefaultFirmwareUpdateMaxTimeout = DefaultDeviceCommandMaxTTL
+	// DefaultFirmwareCampaignDispatchInterval is the default interval at which the running campaigns are advanced.
+	DefaultFirmwareCampaignDispatchInterval = 10 * time.Second
+
+	// FirmwareUpdateCommand is the name of the device command that asks a device to update its firmware.
+	// Its payload describes the image, where to download it and how to verify it.
+	FirmwareUpdateCommand = "firmware-update"
+
+	// firmwareUpdateTimedOut is the failure reason of the updates that were not completed in time.
+	firmwareUpdateTimedOut = "timed out"
+	// firmwareUpdateNotSent is the failure reason of the updates whose command could not be sent.
+	// The cause is logged rather than exposed, as it may be an internal error.
+	firmwareUpdateNotSent = "command dispatch failed"
+	// maxFirmwareReportAttempts is the number of times a report is applied, if the update changes concurrently.
+	maxFirmwareReportAttempts = 2
+)
+
+// FirmwareCampaignConfig configures the timeouts and the delivery of firmware updates.
+type FirmwareCampaignConfig struct {
+	// DefaultUpdateTimeout is used when a campaign does not specify an update timeout.
+	DefaultUpdateTimeout time.Duration
+	// MaxUpdateTimeout is the maximum update timeout a campaign can specify.
+	// It is capped by MaxCommandTTL, as must be DefaultUpdateTimeout.
+	MaxUpdateTimeout time.Duration
+	// MaxCommandTTL is the maximum TTL of the device commands. The updates are sent as commands that expire
+	// after the update timeout, so a longer timeout could not be sent.
+	MaxCommandTTL time.Duration
+	// DispatchInterval is the interval at which the running campaigns fail the overdue updates
+	// and start their next waves.
+	DispatchInterval time.Duration
+	// DownloadBaseURL is the base URL of the API that devices download the images from, e.g.
+	// "https://iot.example.com". If empty, devices are sent the path of the image only.
+	DownloadBaseURL string
+}
+
+// withDefaults returns the config with zero values replaced by the defaults.
+func (c FirmwareCampaignConfig) withDefaults() FirmwareCampaignConfig {
+	if c.DefaultUpdateTimeout <= 0 {
+		c.DefaultUpdateTimeout = DefaultFirmwareUpdateTimeout
+	}
+
+	if c.MaxUpdateTimeout <= 0 {
+		c.MaxUpdateTimeout = DefaultFirmwareUpdateMaxTimeout
+	}
+
+	if c.MaxCommandTTL <= 0 {
+		c.MaxCommandTTL = DefaultDeviceCommandMaxTTL
+	}
+
+	c.MaxUpdateTimeout = min(c.MaxUpdateTimeout, c.MaxCommandTTL)
+	c.DefaultUpdateTimeout = min(c.DefaultUpdateTimeout, c.MaxUpdateTimeout)
+
+	if c.DispatchInterval <= 0 {
+		c.DispatchInterval = DefaultFirmwareCampaignDispatchInterval
+	}
+
+	c.DownloadBaseURL = strings.TrimSuffix(c.DownloadBaseURL, "/")
+
+	return c
+}
+
+// updateTimeout returns the update timeout requested in seconds, or the default one if timeoutSeconds is zero.
+func (c FirmwareCampaignConfig) updateTimeout(timeoutSeconds int64) (time.Duration, error) {
+	if timeoutSeconds == 0 {
+		return c.DefaultUpdateTimeout, nil
+	}
+
+	return secondsToDuration(timeoutSeconds, 1, c.MaxUpdateTimeout, entity.ErrFirmwareUpdateTimeoutInvalid)
+}
+
+// FirmwareCampaignUsecase defines the interface for rolling out firmware to devices in waves.
+type FirmwareCampaignUsecase interface {
+	// CreateFirmwareCampaign creates a campaign for the ACTIVE devices matching a filter, and starts its first wave.
+	CreateFirmwareCampaign(ctx context.Context, input CreateFirmwareCampaignInput) (*FirmwareCampaignOutput, error)
+	// GetFirmwareCampaign retrieves a campaign by its ID, along with the number of its devices in each status.
+	GetFirmwareCampaign(ctx context.Context, id uuid.UUID) (*FirmwareCampaignOutput, error)
+	// ListFirmwareCampaigns retrieves all the campaigns, newest first.
+	ListFirmwareCampaigns(ctx context.Context) ([]*FirmwareCampaignOutput, error)
+	// ListFirmwareCampaignDevices retrieves the updates of the devices of a campaign, by wave.
+	ListFirmwareCampaignDevices(
+		ctx context.Context,
+		input ListFirmwareCampaignDevicesInput,
+	) ([]*FirmwareUpdateOutput, error)
+	// PauseFirmwareCampaign stops a running campaign from starting further waves.
+	PauseFirmwareCampaign(ctx context.Context, id uuid.UUID) (*FirmwareCampaignOutput, error)
+	// ResumeFirmwareCampaign resumes a paused campaign, optionally with a new failure threshold.
+	ResumeFirmwareCampaign(ctx context.Context, input ResumeFirmwareCampaignInput) (*FirmwareCampaignOutput, error)
+	// CancelFirmwareCampaign stops a campaign for good.
+	CancelFirmwareCampaign(ctx context.Context, id uuid.UUID) (*FirmwareCampaignOutput, error)
+	// ReportFirmwareProgress records the progress of its firmware update reported by a device.
+	ReportFirmwareProgress(ctx context.Context, input ReportFirmwareProgressInput) error
+	// Run advances the campaigns at every dispatch interval, until ctx is done.
+	Run(ctx context.Context)
+}
+
+// firmwareCampaignUsecase is the implementation of the FirmwareCampaignUsecase interface.
+type firmwareCampaignUsecase struct {
+	campaignRepo repository.FirmwareCampaignRepository
+	artifactRepo repository.FirmwareArtifactRepository
+	deviceRepo   repository.DeviceRepository
+	devices      DeviceUsecase
+	commands     DeviceCommandUsecase
+	manifests    FirmwareManifestUsecase
+	auditLogger  repository.AuditLogger
+	transactor   repository.Transactor
+	config       FirmwareCampaignConfig
+	now          func() time.Time
+}
+
+// firmwareProgressReport is the progress of a firmware update reported by a device.
+type firmwareProgressReport struct {
+	CampaignID uuid.UUID `json:"campaignId"`
+	Status     string    `json:"status"`
+	Progress   int       `json:"progress"`
+	Error      string    `json:"error"`
+}
+
+// NewFirmwareCampaignUsecase creates a new instance of firmwareCampaignUsecase.
+// The updates are sent through commands along with the manifests signed through manifests, and the new firmware
+// version of the updated devices is recorded through devices, so that both are audited as usual.
+// Zero values in config are replaced by the defaults.
+//
+//nolint:ireturn
+func NewFirmwareCampaignUsecase(
+	campaignRepo repository.FirmwareCampaignRepository,
+	artifactRepo repository.FirmwareArtifactRepository,
+	deviceRepo repository.DeviceRepository,
+	devices DeviceUsecase,
+	commands DeviceCommandUsecase,
+	manifests FirmwareManifestUsecase,
+	auditLogger repository.AuditLogger,
+	transactor repository.Transactor,
+	config FirmwareCampaignConfig,
+) FirmwareCampaignUsecase {
+	return &firmwareCampaignUsecase{
+		campaignRepo: campaignRepo,
+		artifactRepo: artifactRepo,
+		deviceRepo:   deviceRepo,
+		devices:      devices,
+		commands:     commands,
+		manifests:    manifests,
+		auditLogger:  auditLogger,
+		transactor:   transactor,
+		config:       config.withDefaults(),
+		now:          time.Now,
+	}
+}
+
+// CreateFirmwareCampaign creates a campaign for the ACTIVE devices matching the filter and the target of the firmware
+// that do not already report its version, splits them into waves in the order they were created,
+// and starts the first wave.
+// If no device is to be updated, the error wraps ErrNoFirmwareCampaignDevices.
+func (uc *firmwareCampaignUsecase) CreateFirmwareCampaign(
+	ctx context.Context,
+	input CreateFirmwareCampaignInput,
+) (*FirmwareCampaignOutput, error) {
+	timeout, err := uc.config.updateTimeout(input.UpdateTimeoutSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := newDeviceFilter("", "", "", input.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFirmwareCampaignFilter, err)
+	}
+
+	campaign, err := entity.NewFirmwareCampaign(
+		input.Name, input.FirmwareID, input.Filter, input.WaveSize, input.FailureThreshold, timeout,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	artifact, err := findFirmwareArtifact(ctx, uc.artifactRepo, input.FirmwareID)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := newDeviceFilter("", "", "", artifact.Target)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFirmwareTarget, err)
+	}
+
+	deviceIDs, err := uc.targetDeviceIDs(ctx, append(filter.Metadata, target.Metadata...), artifact)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(deviceIDs) == 0 {
+		return nil, ErrNoFirmwareCampaignDevices
+	}
+
+	updates := campaign.AssignWaves(deviceIDs)
+
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := uc.campaignRepo.Save(ctx, campaign)
+		if err != nil {
+			if errors.Is(err, entity.ErrFirmwareArtifactNotFound) {
+				return err
+			}
+
+			return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+
+		for _, update := range updates {
+			update.CampaignID = campaign.ID
+		}
+
+		err = uc.campaignRepo.CreateUpdates(ctx, updates)
+		if err != nil {
+			if errors.Is(err, entity.ErrDeviceNotFound) {
+				return err
+			}
+
+			return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+
+		return recordAudit(ctx, uc.auditLogger, auditEvent{
+			action:   entity.AuditFirmwareCampaignCreate,
+			deviceID: uuid.Nil,
+			target:   firmwareCampaignTarget(campaign.ID),
+			before:   nil,
+			after:    firmwareCampaignSnapshot(campaign),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.advanceOrLog(ctx, campaign)
+
+	return uc.output(ctx, campaign)
+}
+
+// GetFirmwareCampaign retrieves a campaign by its ID, along with the number of its devices in each status.
+func (uc *firmwareCampaignUsecase) GetFirmwareCampaign(
+	ctx context.Context,
+	id uuid.UUID,
+) (*FirmwareCampaignOutput, error) {
+	campaign, err := uc.findCampaign(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.output(ctx, campaign)
+}
+
+// ListFirmwareCampaigns retrieves all the campaigns, newest first.
+func (uc *firmwareCampaignUsecase) ListFirmwareCampaigns(ctx context.Context) ([]*FirmwareCampaignOutput, error) {
+	campaigns, err := uc.campaignRepo.FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDBFindAll, err)
+	}
+
+	outputs := make([]*FirmwareCampaignOutput, 0, len(campaigns))
+
+	for _, campaign := range campaigns {
+		output, err := uc.output(ctx, campaign)
+		if err != nil {
+			return nil, err
+		}
+
+		outputs = append(outputs, output)
+	}
+
+	return outputs, nil
+}
+
+// ListFirmwareCampaignDevices retrieves the updates of the devices of a campaign, by wave and then by device ID.
+func (uc *firmwareCampaignUsecase) ListFirmwareCampaignDevices(
+	ctx context.Context,
+	input ListFirmwareCampaignDevicesInput,
+) ([]*FirmwareUpdateOutput, error) {
+	filter := repository.FirmwareUpdateFilter{Status: "", MaxWave: 0}
+
+	if input.Status != "" {
+		status, err := updatestatus.Parse(strings.ToUpper(input.Status))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFirmwareUpdateFilter, err)
+		}
+
+		filter.Status = status
+	}
+
+	_, err := uc.findCampaign(ctx, input.CampaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates, err := uc.campaignRepo.FindUpdates(ctx, input.CampaignID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDBFindAll, err)
+	}
+
+	outputs := make([]*FirmwareUpdateOutput, 0, len(updates))
+	for _, update := range updates {
+		outputs = append(outputs, NewFirmwareUpdateOutput(update))
+	}
+
+	return outputs, nil
+}
+
+// PauseFirmwareCampaign stops a running campaign from starting further waves.
+// The devices already updating carry on.
+func (uc *firmwareCampaignUsecase) PauseFirmwareCampaign(
+	ctx context.Context,
+	id uuid.UUID,
+) (*FirmwareCampaignOutput, error) {
+	campaign, err := uc.changeStatus(ctx, id, entity.AuditFirmwareCampaignPause,
+		func(c *entity.FirmwareCampaign) error {
+			return c.Pause("paused by " + ActorFromContext(ctx))
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.output(ctx, campaign)
+}
+
+// ResumeFirmwareCampaign resumes a paused campaign, optionally with a new failure threshold, and carries on
+// with its waves. If the failures still exceed the threshold, the campaign is paused again.
+func (uc *firmwareCampaignUsecase) ResumeFirmwareCampaign(
+	ctx context.Context,
+	input ResumeFirmwareCampaignInput,
+) (*FirmwareCampaignOutput, error) {
+	campaign, err := uc.changeStatus(ctx, input.ID, entity.AuditFirmwareCampaignResume,
+		func(c *entity.FirmwareCampaign) error {
+			if input.FailureThreshold != nil {
+				err := c.SetFailureThreshold(*input.FailureThreshold)
+				if err != nil {
+					return err
+				}
+			}
+
+			return c.Resume()
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	uc.advanceOrLog(ctx, campaign)
+
+	return uc.output(ctx, campaign)
+}
+
+// CancelFirmwareCampaign stops a campaign for good. The devices already updating carry on,
+// and the devices of the waves that have not started stay PENDING.
+func (uc *firmwareCampaignUsecase) CancelFirmwareCampaign(
+	ctx context.Context,
+	id uuid.UUID,
+) (*FirmwareCampaignOutput, error) {
+	campaign, err := uc.changeStatus(ctx, id, entity.AuditFirmwareCampaignCancel, (*entity.FirmwareCampaign).Cancel)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.output(ctx, campaign)
+}
+
+// ReportFirmwareProgress records the progress of its firmware update reported by a device.
+// Once the device has installed the firmware, its version is recorded in the `firmware.version` metadata
+// of the device within the same transaction, so that an update is never SUCCEEDED without the new version.
+// Once the update is over, the campaign is advanced, so that the next wave starts without delay.
+func (uc *firmwareCampaignUsecase) ReportFirmwareProgress(
+	ctx context.Context,
+	input ReportFirmwareProgressInput,
+) error {
+	var report firmwareProgressReport
+
+	err := json.Unmarshal(input.Report, &report)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidFirmwareReport, err)
+	}
+
+	status, err := updatestatus.Parse(report.Status)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidFirmwareReport, err)
+	}
+
+	var (
+		update   *entity.FirmwareUpdate
+		campaign *entity.FirmwareCampaign
+	)
+
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		update, err = uc.applyReport(ctx, input.DeviceID, report, status)
+		if err != nil || !update.Status.IsTerminal() {
+			return err
+		}
+
+		campaign, err = uc.findCampaign(ctx, report.CampaignID)
+		if err != nil || update.Status != updatestatus.Succeeded {
+			return err
+		}
+
+		return uc.recordFirmwareVersion(ctx, campaign, input.DeviceID)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !update.Status.IsTerminal() {
+		return nil
+	}
+
+	uc.advanceOrLog(ctx, campaign)
+
+	return nil
+}
+
+// Run advances the campaigns at every dispatch interval, until ctx is done.
+func (uc *firmwareCampaignUsecase) Run(ctx context.Context) {
+	ticker := time.NewTicker(uc.config.DispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		uc.dispatch(ctx)
+	}
+}
+
+// dispatch advances each of the campaigns that may have updates in progress. The devices of a paused
+// or cancelled campaign carry on with their updates, so those campaigns are visited too, for their overdue updates.
+func (uc *firmwareCampaignUsecase) dispatch(ctx context.Context) {
+	for _, status := range []campaignstatus.Status{
+		campaignstatus.Running, campaignstatus.Paused, campaignstatus.Cancelled,
+	} {
+		campaigns, err := uc.campaignRepo.FindByStatus(ctx, status)
+		if err != nil {
+			log.Printf("failed to find %s firmware campaigns: %v", status, err)
+
+			continue
+		}
+
+		for _, campaign := range campaigns {
+			uc.advanceOrLog(ctx, campaign)
+		}
+	}
+}
+
+// advanceOrLog advances a campaign. Its failure is only logged, as the campaign is advanced again by Run.
+func (uc *firmwareCampaignUsecase) advanceOrLog(ctx context.Context, campaign *entity.FirmwareCampaign) {
+	err := uc.advance(ctx, campaign)
+	if err != nil {
+		log.Printf("failed to advance firmware campaign %s: %v", campaign.ID, err)
+	}
+}
+
+// advance moves a campaign forward: it fails the overdue updates, which a campaign that is not running
+// still has to do, and then, if the campaign is running, sends the pending updates of the current wave,
+// pauses the campaign if the failures exceed its threshold, and otherwise starts the next wave once
+// the updates of the current one are over, or completes the campaign after the last one.
+func (uc *firmwareCampaignUsecase) advance(ctx context.Context, campaign *entity.FirmwareCampaign) error {
+	now := uc.now()
+
+	_, err := uc.campaignRepo.FailOverdue(
+		ctx, campaign.ID, now.Add(-campaign.UpdateTimeout()), now, firmwareUpdateTimedOut,
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+	}
+
+	// The campaign is reloaded if it changes concurrently, so its status is checked again at every step.
+	for campaign.Status == campaignstatus.Running {
+		err = uc.sendWave(ctx, campaign)
+		if err != nil {
+			return err
+		}
+
+		counts, err := uc.campaignRepo.CountUpdates(ctx, campaign.ID)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrDBFindAll, err)
+		}
+
+		failed, pending, inProgress, sent := countUpdates(counts)
+
+		switch {
+		case campaign.ExceedsFailureThreshold(failed, sent):
+			return uc.pauseForFailures(ctx, campaign, failed, sent)
+		case inProgress > 0:
+			// The current wave is not over yet.
+			return nil
+		case pending == 0:
+			err = uc.saveProgress(ctx, campaign, (*entity.FirmwareCampaign).Complete)
+		default:
+			err = uc.saveProgress(ctx, campaign, func(c *entity.FirmwareCampaign) error {
+				if !c.StartNextWave() {
+					return &campaignstatus.TransitionError{
+						From: c.Status, To: campaignstatus.Running, Err: campaignstatus.ErrInvalidTransition,
+					}
+				}
+
+				return nil
+			})
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sendWave sends the pending updates of the waves that have started. Each update is marked as SENT before it is
+// sent, so that it is sent only once even if the campaign is advanced concurrently. An update that cannot be sent,
+// e.g. because the device is no longer ACTIVE, fails.
+func (uc *firmwareCampaignUsecase) sendWave(ctx context.Context, campaign *entity.FirmwareCampaign) error {
+	if campaign.CurrentWave == 0 {
+		return nil
+	}
+
+	updates, err := uc.campaignRepo.FindUpdates(ctx, campaign.ID, repository.FirmwareUpdateFilter{
+		Status:  updatestatus.Pending,
+		MaxWave: campaign.CurrentWave,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDBFindAll, err)
+	}
+
+	if len(updates) == 0 {
+		return nil
+	}
+
+	artifact, err := findFirmwareArtifact(ctx, uc.artifactRepo, campaign.FirmwareID)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := uc.manifests.GetFirmwareManifest(ctx, artifact.ID)
+	if err != nil {
+		return err
+	}
+
+	// A campaign created before the maximum TTL was lowered is still sent, with the longest TTL allowed.
+	ttlSeconds := min(campaign.UpdateTimeoutSeconds, int64(uc.config.MaxCommandTTL/time.Second))
+
+	for _, update := range updates {
+		err = update.MarkSent(uc.now())
+		if err != nil {
+			return err
+		}
+
+		err = uc.campaignRepo.SaveUpdate(ctx, update, updatestatus.Pending)
+		if errors.Is(err, entity.ErrFirmwareUpdateStatusConflict) {
+			// It has been sent concurrently.
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+
+		_, err = uc.commands.SendCommand(ctx, SendDeviceCommandInput{
+			DeviceID:   update.DeviceID,
+			Name:       FirmwareUpdateCommand,
+			Payload:    uc.updatePayload(campaign, artifact, manifest),
+			TTLSeconds: ttlSeconds,
+		})
+		if err != nil {
+			log.Printf("failed to send the firmware update of campaign %s to device %s: %v",
+				campaign.ID, update.DeviceID, err)
+			uc.failUpdate(ctx, update, firmwareUpdateNotSent)
+		}
+	}
+
+	return nil
+}
+
+// failUpdate fails an update that could not be sent. A failure is only logged,
+// as the update then fails once it times out.
+func (uc *firmwareCampaignUsecase) failUpdate(ctx context.Context, update *entity.FirmwareUpdate, reason string) {
+	from := update.Status
+
+	err := update.Fail(reason, uc.now())
+	if err == nil {
+		err = uc.campaignRepo.SaveUpdate(ctx, update, from)
+	}
+
+	if err != nil {
+		log.Printf("failed to fail the firmware update of device %s: %v", update.DeviceID, err)
+	}
+}
+
+// pauseForFailures pauses a campaign whose failures exceed its threshold, along with an audit log entry
+// attributed to the system.
+func (uc *firmwareCampaignUsecase) pauseForFailures(
+	ctx context.Context,
+	campaign *entity.FirmwareCampaign,
+	failed, sent int64,
+) error {
+	before := firmwareCampaignSnapshot(campaign)
+
+	reason := fmt.Sprintf("%d of %d updates failed, above the failure threshold of %d%%",
+		failed, sent, campaign.FailureThreshold)
+
+	err := campaign.Pause(reason)
+	if err != nil {
+		return err
+	}
+
+	return uc.saveWithAudit(WithActor(ctx, entity.SystemActor), campaign, entity.AuditFirmwareCampaignPause, before)
+}
+
+// saveProgress applies a change to a campaign that does not come from an actor, so it is not audited,
+// and saves it. If the campaign has changed concurrently, the change is dropped and the campaign is reloaded
+// instead, so that the caller decides again from its current state: applying the change to the reloaded
+// campaign could e.g. start a wave twice.
+func (uc *firmwareCampaignUsecase) saveProgress(
+	ctx context.Context,
+	campaign *entity.FirmwareCampaign,
+	change func(c *entity.FirmwareCampaign) error,
+) error {
+	next := *campaign
+
+	err := change(&next)
+	if err != nil {
+		return err
+	}
+
+	err = uc.campaignRepo.Save(ctx, &next)
+	if errors.Is(err, entity.ErrFirmwareCampaignConflict) {
+		reloaded, err := uc.findCampaign(ctx, campaign.ID)
+		if err != nil {
+			return err
+		}
+
+		*campaign = *reloaded
+
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+	}
+
+	*campaign = next
+
+	return nil
+}
+
+// changeStatus applies a change of status requested by an actor to a campaign, and saves it along with
+// an audit log entry.
+func (uc *firmwareCampaignUsecase) changeStatus(
+	ctx context.Context,
+	id uuid.UUID,
+	action entity.AuditAction,
+	change func(c *entity.FirmwareCampaign) error,
+) (*entity.FirmwareCampaign, error) {
+	campaign, err := uc.findCampaign(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := firmwareCampaignSnapshot(campaign)
+
+	err = change(campaign)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.saveWithAudit(ctx, campaign, action, before)
+	if err != nil {
+		return nil, err
+	}
+
+	return campaign, nil
+}
+
+// saveWithAudit saves a changed campaign and records it in the audit trail within one transaction.
+// If the campaign has changed concurrently, the error wraps entity.ErrFirmwareCampaignConflict.
+func (uc *firmwareCampaignUsecase) saveWithAudit(
+	ctx context.Context,
+	campaign *entity.FirmwareCampaign,
+	action entity.AuditAction,
+	before map[string]any,
+) error {
+	return uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := uc.campaignRepo.Save(ctx, campaign)
+		if err != nil {
+			if errors.Is(err, entity.ErrFirmwareCampaignConflict) {
+				return err
+			}
+
+			return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+
+		return recordAudit(ctx, uc.auditLogger, auditEvent{
+			action:   action,
+			deviceID: uuid.Nil,
+			target:   firmwareCampaignTarget(campaign.ID),
+			before:   before,
+			after:    firmwareCampaignSnapshot(campaign),
+		})
+	})
+}
+
+// applyReport applies a report to the update of a device, and applies it again if the update
+// changed meanwhile, e.g. because it was marked as SENT after the device started.
+func (uc *firmwareCampaignUsecase) applyReport(
+	ctx context.Context,
+	deviceID uuid.UUID,
+	report firmwareProgressReport,
+	status updatestatus.Status,
+) (*entity.FirmwareUpdate, error) {
+	for attempt := 1; ; attempt++ {
+		update, err := uc.campaignRepo.FindUpdate(ctx, report.CampaignID, deviceID)
+		if err != nil {
+			if isNotFound(err, entity.ErrFirmwareUpdateNotFound) {
+				return nil, entity.ErrFirmwareUpdateNotFound
+			}
+
+			return nil, fmt.Errorf("%w: %w", ErrDBFindByID, err)
+		}
+
+		from := update.Status
+
+		err = update.Report(status, report.Progress, report.Error, uc.now())
+		if err != nil {
+			return nil, err
+		}
+
+		err = uc.campaignRepo.SaveUpdate(ctx, update, from)
+		if err == nil {
+			return update, nil
+		}
+
+		if !errors.Is(err, entity.ErrFirmwareUpdateStatusConflict) || attempt == maxFirmwareReportAttempts {
+			return nil, fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+	}
+}
+
+// recordFirmwareVersion records the version of the firmware of a campaign in the `firmware.version` metadata
+// of a device that has installed it, so that it is left out of further campaigns for the same version.
+func (uc *firmwareCampaignUsecase) recordFirmwareVersion(
+	ctx context.Context,
+	campaign *entity.FirmwareCampaign,
+	deviceID uuid.UUID,
+) error {
+	artifact, err := findFirmwareArtifact(ctx, uc.artifactRepo, campaign.FirmwareID)
+	if err != nil {
+		return err
+	}
+
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"firmware": map[string]any{"version": artifact.Version}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal firmware version: %w", err)
+	}
+
+	_, err = uc.devices.PatchDevice(ctx, PatchDeviceInput{
+		ID:              deviceID,
+		Format:          PatchFormatMerge,
+		Patch:           patch,
+		ExpectedVersion: 0,
+	})
+
+	return err
+}
+
+// targetDeviceIDs returns the IDs of the ACTIVE devices satisfying the conditions that do not report
+// the version of the artifact yet, in the order they were created.
+// They are collected at once, because the repository cannot be used while it reads them.
+func (uc *firmwareCampaignUsecase) targetDeviceIDs(
+	ctx context.Context,
+	conditions []repository.MetadataCondition,
+	artifact *entity.FirmwareArtifact,
+) ([]uuid.UUID, error) {
+	conditions = append(conditions, repository.MetadataCondition{
+		Path:     []string{"firmware", "version"},
+		Operator: repository.MetadataNotEquals,
+		Value:    artifact.Version,
+	})
+
+	query := repository.DeviceQuery{
+		Filter: repository.DeviceFilter{ //nolint:exhaustruct
+			Status:   devicestatus.Active,
+			Metadata: conditions,
+		},
+		SortBy:     repository.DeviceSortCreatedAt,
+		Descending: false,
+		After:      nil,
+		Limit:      0,
+	}
+
+	var deviceIDs []uuid.UUID
+
+	err := uc.deviceRepo.FindEach(ctx, query, func(device *entity.Device) error {
+		deviceIDs = append(deviceIDs, device.ID)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDBFindAll, err)
+	}
+
+	return deviceIDs, nil
+}
+
+// updatePayload returns the payload of the command that asks a device to update its firmware.
+// The device verifies the signed manifest before it installs the image.
+func (uc *firmwareCampaignUsecase) updatePayload(
+	campaign *entity.FirmwareCampaign,
+	artifact *entity.FirmwareArtifact,
+	manifest *FirmwareManifestOutput,
+) map[string]any {
+	return map[string]any{
+		"campaignId":         campaign.ID.String(),
+		"firmwareId":         artifact.ID.String(),
+		"version":            artifact.Version,
+		"size":               artifact.Size,
+		"sha256":             artifact.SHA256,
+		"signature":          artifact.Signature,
+		"signatureAlgorithm": artifact.SignatureAlgorithm,
+		"url":                uc.config.DownloadBaseURL + "/firmware/" + artifact.ID.String() + "/image",
+		"manifest": map[string]any{
+			"payload":   manifest.Payload,
+			"signature": manifest.Signature,
+			"keyId":     manifest.KeyID,
+			"algorithm": manifest.Algorithm,
+		},
+	}
+}
+
+// output returns the output of a campaign along with the number of its devices in each status.
+func (uc *firmwareCampaignUsecase) output(
+	ctx context.Context,
+	campaign *entity.FirmwareCampaign,
+) (*FirmwareCampaignOutput, error) {
+	counts, err := uc.campaignRepo.CountUpdates(ctx, campaign.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDBFindAll, err)
+	}
+
+	return NewFirmwareCampaignOutput(campaign, counts), nil
+}
+
+// findCampaign retrieves a campaign, translating a missing campaign into entity.ErrFirmwareCampaignNotFound.
+func (uc *firmwareCampaignUsecase) findCampaign(ctx context.Context, id uuid.UUID) (*entity.FirmwareCampaign, error) {
+	campaign, err := uc.campaignRepo.FindByID(ctx, id)
+	if err != nil {
+		if isNotFound(err, entity.ErrFirmwareCampaignNotFound) {
+			return nil, entity.ErrFirmwareCampaignNotFound
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrDBFindByID, err)
+	}
+
+	return campaign, nil
+}
+
+// countUpdates sums the updates of a campaign: those that failed, those still pending,
+// those in progress on the devices, and those sent so far.
+func countUpdates(counts map[updatestatus.Status]int64) (int64, int64, int64, int64) {
+	var inProgress, sent int64
+
+	for status, count := range counts {
+		if status != updatestatus.Pending {
+			sent += count
+		}
+
+		if status.IsInProgress() {
+			inProgress += count
+		}
+	}
+
+	return counts[updatestatus.Failed], counts[updatestatus.Pending], inProgress, sent
+}
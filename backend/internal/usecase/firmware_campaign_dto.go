@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/VO/updatestatus"
+	"backend/internal/domain/entity"
+)
+
+// CreateFirmwareCampaignInput is the input data for creating a FirmwareCampaign.
+type CreateFirmwareCampaignInput struct {
+	Name       string    `json:"name"`       // Required
+	FirmwareID uuid.UUID `json:"firmwareId"` // Required
+	// Filter holds conditions on the metadata in the syntax of ListDevicesInput.Metadata, all of which a device
+	// must satisfy to be updated. Optional: without it, every ACTIVE device is updated.
+	// Devices that already report the version of the firmware are left out.
+	Filter []string `json:"filter"`
+	// WaveSize is the number of devices updated in each wave. Required.
+	WaveSize int `json:"waveSize"`
+	// FailureThreshold is the percentage of failed updates, among those sent so far, above which the campaign
+	// is paused. Optional: if zero, the first failure pauses it.
+	FailureThreshold int `json:"failureThreshold"`
+	// UpdateTimeoutSeconds is how long a device has to complete its update.
+	// Optional: if zero, the default update timeout is used.
+	UpdateTimeoutSeconds int64 `json:"updateTimeoutSeconds"`
+}
+
+// ResumeFirmwareCampaignInput is the input data for resuming a paused FirmwareCampaign.
+type ResumeFirmwareCampaignInput struct {
+	ID uuid.UUID `json:"-"`
+	// FailureThreshold is optional: if nil, the threshold is not changed.
+	// Raising it lets a campaign paused for its failures carry on.
+	FailureThreshold *int `json:"failureThreshold"`
+}
+
+// ListFirmwareCampaignDevicesInput is the input data for listing the devices of a FirmwareCampaign.
+type ListFirmwareCampaignDevicesInput struct {
+	CampaignID uuid.UUID
+	Status     string // Optional: only lists the updates with the status, e.g. "FAILED".
+}
+
+// ReportFirmwareProgressInput is the input data for the progress of a firmware update reported by a device,
+// e.g. over MQTT.
+type ReportFirmwareProgressInput struct {
+	DeviceID uuid.UUID
+	// Report is the JSON report of the device: {"campaignId": ..., "status": "DOWNLOADING", "INSTALLING",
+	// "SUCCEEDED" or "FAILED", "progress": <percent>, "error": "..."}.
+	Report []byte
+}
+
+// FirmwareCampaignOutput is the output data of a FirmwareCampaign.
+type FirmwareCampaignOutput struct {
+	ID                   uuid.UUID `json:"id"`
+	Name                 string    `json:"name"`
+	FirmwareID           uuid.UUID `json:"firmwareId"`
+	Filter               []string  `json:"filter"`
+	WaveSize             int       `json:"waveSize"`
+	FailureThreshold     int       `json:"failureThreshold"`
+	UpdateTimeoutSeconds int64     `json:"updateTimeoutSeconds"`
+	Status               string    `json:"status"`
+	StatusReason         string    `json:"statusReason,omitempty"`
+	CurrentWave          int       `json:"currentWave"`
+	TotalWaves           int       `json:"totalWaves"`
+	// Devices is the number of devices in each update status.
+	Devices   map[string]int64 `json:"devices"`
+	CreatedAt time.Time        `json:"createdAt"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+}
+
+// FirmwareUpdateOutput is the output data of the update of a device in a FirmwareCampaign.
+type FirmwareUpdateOutput struct {
+	DeviceID      uuid.UUID  `json:"deviceId"`
+	Wave          int        `json:"wave"`
+	Status        string     `json:"status"`
+	Progress      int        `json:"progress"`
+	FailureReason string     `json:"failureReason,omitempty"`
+	SentAt        *time.Time `json:"sentAt,omitempty"`
+	CompletedAt   *time.Time `json:"completedAt,omitempty"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// NewFirmwareCampaignOutput creates a new FirmwareCampaignOutput from a FirmwareCampaign entity
+// and the number of its updates in each status.
+func NewFirmwareCampaignOutput(
+	campaign *entity.FirmwareCampaign,
+	counts map[updatestatus.Status]int64,
+) *FirmwareCampaignOutput {
+	devices := make(map[string]int64, len(counts))
+	for status, count := range counts {
+		devices[string(status)] = count
+	}
+
+	return &FirmwareCampaignOutput{
+		ID:                   campaign.ID,
+		Name:                 campaign.Name,
+		FirmwareID:           campaign.FirmwareID,
+		Filter:               []string(campaign.Filter),
+		WaveSize:             campaign.WaveSize,
+		FailureThreshold:     campaign.FailureThreshold,
+		UpdateTimeoutSeconds: campaign.UpdateTimeoutSeconds,
+		Status:               string(campaign.Status),
+		StatusReason:         campaign.StatusReason,
+		CurrentWave:          campaign.CurrentWave,
+		TotalWaves:           campaign.TotalWaves,
+		Devices:              devices,
+		CreatedAt:            campaign.CreatedAt,
+		UpdatedAt:            campaign.UpdatedAt,
+	}
+}
+
+// NewFirmwareUpdateOutput creates a new FirmwareUpdateOutput from a FirmwareUpdate entity.
+func NewFirmwareUpdateOutput(update *entity.FirmwareUpdate) *FirmwareUpdateOutput {
+	return &FirmwareUpdateOutput{
+		DeviceID:      update.DeviceID,
+		Wave:          update.Wave,
+		Status:        string(update.Status),
+		Progress:      update.Progress,
+		FailureReason: update.FailureReason,
+		SentAt:        update.SentAt,
+		CompletedAt:   update.CompletedAt,
+		UpdatedAt:     update.UpdatedAt,
+	}
+}
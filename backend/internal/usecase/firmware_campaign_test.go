@@ -0,0 +1,801 @@
+package usecase_test
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/campaignstatus"
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/VO/updatestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// firmwareUpdateKey identifies the update of a device in a campaign.
+type firmwareUpdateKey struct {
+	campaignID uuid.UUID
+	deviceID   uuid.UUID
+}
+
+// FakeFirmwareCampaignRepository is an in-memory implementation of the FirmwareCampaignRepository for testing.
+// It stores copies of the campaigns and updates, so that a change that is not saved is not kept.
+type FakeFirmwareCampaignRepository struct {
+	mu        sync.Mutex
+	campaigns map[uuid.UUID]*entity.FirmwareCampaign
+	updates   map[firmwareUpdateKey]*entity.FirmwareUpdate
+	// ConcurrentChange, if set, is applied once to a stored campaign just before it is next updated,
+	// as if the campaign had been changed concurrently.
+	ConcurrentChange func(stored *entity.FirmwareCampaign)
+}
+
+// NewFakeFirmwareCampaignRepository creates a new FakeFirmwareCampaignRepository.
+func NewFakeFirmwareCampaignRepository() *FakeFirmwareCampaignRepository {
+	return &FakeFirmwareCampaignRepository{
+		mu:               sync.Mutex{},
+		campaigns:        make(map[uuid.UUID]*entity.FirmwareCampaign),
+		updates:          make(map[firmwareUpdateKey]*entity.FirmwareUpdate),
+		ConcurrentChange: nil,
+	}
+}
+
+// Save adds a campaign, or updates it if its stored version is still campaign.Version.
+func (r *FakeFirmwareCampaignRepository) Save(_ context.Context, campaign *entity.FirmwareCampaign) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if campaign.ID == uuid.Nil {
+		campaign.ID = uuid.New()
+		campaign.Version = 1
+		campaign.CreatedAt = time.Now()
+	} else {
+		stored, ok := r.campaigns[campaign.ID]
+		if ok && r.ConcurrentChange != nil {
+			r.ConcurrentChange(stored)
+			stored.Version++
+			r.ConcurrentChange = nil
+		}
+
+		if !ok || stored.Version != campaign.Version {
+			return entity.ErrFirmwareCampaignConflict
+		}
+
+		campaign.Version++
+	}
+
+	campaign.UpdatedAt = time.Now()
+	stored := *campaign
+	r.campaigns[campaign.ID] = &stored
+
+	return nil
+}
+
+// FindByID retrieves a campaign by its ID from the in-memory store.
+func (r *FakeFirmwareCampaignRepository) FindByID(_ context.Context, id uuid.UUID) (*entity.FirmwareCampaign, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	campaign, ok := r.campaigns[id]
+	if !ok {
+		return nil, entity.ErrFirmwareCampaignNotFound
+	}
+
+	found := *campaign
+
+	return &found, nil
+}
+
+// FindAll retrieves all the campaigns from the in-memory store, newest first.
+func (r *FakeFirmwareCampaignRepository) FindAll(ctx context.Context) ([]*entity.FirmwareCampaign, error) {
+	campaigns, err := r.FindByStatus(ctx, "")
+	slices.Reverse(campaigns)
+
+	return campaigns, err
+}
+
+// FindByStatus retrieves the campaigns with the status from the in-memory store, oldest first.
+// An empty status matches every campaign.
+func (r *FakeFirmwareCampaignRepository) FindByStatus(
+	_ context.Context,
+	status campaignstatus.Status,
+) ([]*entity.FirmwareCampaign, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	campaigns := make([]*entity.FirmwareCampaign, 0, len(r.campaigns))
+
+	for _, campaign := range r.campaigns {
+		if status == "" || campaign.Status == status {
+			found := *campaign
+			campaigns = append(campaigns, &found)
+		}
+	}
+
+	slices.SortFunc(campaigns, func(a, b *entity.FirmwareCampaign) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return campaigns, nil
+}
+
+// CreateUpdates adds the updates of a campaign to the in-memory store.
+func (r *FakeFirmwareCampaignRepository) CreateUpdates(_ context.Context, updates []*entity.FirmwareUpdate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, update := range updates {
+		update.UpdatedAt = time.Now()
+		stored := *update
+		r.updates[firmwareUpdateKey{update.CampaignID, update.DeviceID}] = &stored
+	}
+
+	return nil
+}
+
+// FindUpdate retrieves the update of a device in a campaign from the in-memory store.
+func (r *FakeFirmwareCampaignRepository) FindUpdate(
+	_ context.Context,
+	campaignID, deviceID uuid.UUID,
+) (*entity.FirmwareUpdate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	update, ok := r.updates[firmwareUpdateKey{campaignID, deviceID}]
+	if !ok {
+		return nil, entity.ErrFirmwareUpdateNotFound
+	}
+
+	found := *update
+
+	return &found, nil
+}
+
+// FindUpdates retrieves the updates of a campaign matching the filter, by wave and then by device ID.
+func (r *FakeFirmwareCampaignRepository) FindUpdates(
+	_ context.Context,
+	campaignID uuid.UUID,
+	filter repository.FirmwareUpdateFilter,
+) ([]*entity.FirmwareUpdate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var updates []*entity.FirmwareUpdate
+
+	for key, update := range r.updates {
+		if key.campaignID != campaignID ||
+			(filter.Status != "" && update.Status != filter.Status) ||
+			(filter.MaxWave > 0 && update.Wave > filter.MaxWave) {
+			continue
+		}
+
+		found := *update
+		updates = append(updates, &found)
+	}
+
+	slices.SortFunc(updates, func(a, b *entity.FirmwareUpdate) int {
+		return cmp.Or(cmp.Compare(a.Wave, b.Wave), cmp.Compare(a.DeviceID.String(), b.DeviceID.String()))
+	})
+
+	return updates, nil
+}
+
+// CountUpdates returns the number of updates of a campaign in each status.
+func (r *FakeFirmwareCampaignRepository) CountUpdates(
+	_ context.Context,
+	campaignID uuid.UUID,
+) (map[updatestatus.Status]int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[updatestatus.Status]int64)
+
+	for key, update := range r.updates {
+		if key.campaignID == campaignID {
+			counts[update.Status]++
+		}
+	}
+
+	return counts, nil
+}
+
+// SaveUpdate stores an update if its stored status is still from.
+func (r *FakeFirmwareCampaignRepository) SaveUpdate(
+	_ context.Context,
+	update *entity.FirmwareUpdate,
+	from updatestatus.Status,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := firmwareUpdateKey{update.CampaignID, update.DeviceID}
+
+	stored, ok := r.updates[key]
+	if !ok || stored.Status != from {
+		return entity.ErrFirmwareUpdateStatusConflict
+	}
+
+	update.UpdatedAt = time.Now()
+	saved := *update
+	r.updates[key] = &saved
+
+	return nil
+}
+
+// FailOverdue marks the updates of a campaign in progress that were sent before sentBefore as FAILED.
+func (r *FakeFirmwareCampaignRepository) FailOverdue(
+	_ context.Context,
+	campaignID uuid.UUID,
+	sentBefore, now time.Time,
+	reason string,
+) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var failed int64
+
+	for key, update := range r.updates {
+		if key.campaignID == campaignID && update.Status.IsInProgress() && update.SentAt.Before(sentBefore) {
+			update.Status = updatestatus.Failed
+			update.FailureReason = reason
+			update.CompletedAt = &now
+			failed++
+		}
+	}
+
+	return failed, nil
+}
+
+// status returns the stored status of the update of a device.
+func (r *FakeFirmwareCampaignRepository) status(campaignID, deviceID uuid.UUID) updatestatus.Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.updates[firmwareUpdateKey{campaignID, deviceID}].Status
+}
+
+// firmwareCampaignTest holds a FirmwareCampaignUsecase, along with the usecases and fakes it works with.
+type firmwareCampaignTest struct {
+	uc           usecase.FirmwareCampaignUsecase
+	firmware     *firmwareTest
+	commands     *deviceCommandTest
+	campaignRepo *FakeFirmwareCampaignRepository
+}
+
+// newFirmwareCampaignTest creates a FirmwareCampaignUsecase whose device, command and firmware usecases
+// share their fakes.
+func newFirmwareCampaignTest() *firmwareCampaignTest {
+	commands := newDeviceCommandTest()
+	firmware := newFirmwareTest()
+	firmware.auditLogger = commands.auditLogger
+	firmware.build()
+	campaignRepo := NewFakeFirmwareCampaignRepository()
+	devices := usecase.NewDeviceUsecase(commands.deviceRepo, commands.auditLogger, FakeTransactor{})
+
+	return &firmwareCampaignTest{
+		uc: usecase.NewFirmwareCampaignUsecase(
+			campaignRepo, firmware.artifactRepo, commands.deviceRepo, devices, commands.uc, firmware.manifests,
+			commands.auditLogger, FakeTransactor{}, usecase.FirmwareCampaignConfig{
+				DefaultUpdateTimeout: 0,
+				MaxUpdateTimeout:     2 * time.Hour,
+				MaxCommandTTL:        time.Hour,
+				DispatchInterval:     10 * time.Millisecond,
+				DownloadBaseURL:      "https://iot.example.com/",
+			},
+		),
+		firmware:     firmware,
+		commands:     commands,
+		campaignRepo: campaignRepo,
+	}
+}
+
+// addDevices stores ACTIVE devices, created one minute apart, with the metadata.
+func (tt *firmwareCampaignTest) addDevices(n int, metadata entity.JSONBMap) []*entity.Device {
+	devices := make([]*entity.Device, 0, n)
+
+	for range n {
+		device := tt.commands.addDevice(devicestatus.Active)
+		device.Metadata = metadata
+		device.CreatedAt = time.Date(2026, 4, 1, 9, len(tt.commands.deviceRepo.devices), 0, 0, time.UTC)
+		devices = append(devices, device)
+	}
+
+	return devices
+}
+
+// create creates a campaign rolling out a new firmware, and fails the test on error.
+func (tt *firmwareCampaignTest) create(
+	t *testing.T,
+	waveSize, failureThreshold int,
+	filter ...string,
+) *usecase.FirmwareCampaignOutput {
+	t.Helper()
+
+	firmware := tt.firmware.upload(t, "2.0.0", "firmware image")
+
+	output, err := tt.uc.CreateFirmwareCampaign(context.Background(), usecase.CreateFirmwareCampaignInput{
+		Name:                 "Roll out 2.0.0",
+		FirmwareID:           firmware.ID,
+		Filter:               filter,
+		WaveSize:             waveSize,
+		FailureThreshold:     failureThreshold,
+		UpdateTimeoutSeconds: 0,
+	})
+	require.NoError(t, err)
+
+	return output
+}
+
+// report reports the progress of its update as the device would.
+func (tt *firmwareCampaignTest) report(campaignID, deviceID uuid.UUID, report string) error {
+	return tt.uc.ReportFirmwareProgress(context.Background(), usecase.ReportFirmwareProgressInput{
+		DeviceID: deviceID,
+		Report:   []byte(`{"campaignId": "` + campaignID.String() + `", ` + report + `}`),
+	})
+}
+
+// sentTo returns the IDs of the devices that have been sent a firmware update command.
+func (tt *firmwareCampaignTest) sentTo() []uuid.UUID {
+	var deviceIDs []uuid.UUID
+
+	for _, message := range tt.commands.publisher.Messages() {
+		if message.Name == usecase.FirmwareUpdateCommand {
+			deviceIDs = append(deviceIDs, message.DeviceID)
+		}
+	}
+
+	return deviceIDs
+}
+
+// TestCreateFirmwareCampaign tests the CreateFirmwareCampaign method.
+func TestCreateFirmwareCampaign(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("success: the first wave is sent the firmware", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareCampaignTest()
+		devices := tt.addDevices(3, entity.JSONBMap{"location": map[string]any{"building": "Factory-A"}})
+		tt.addDevices(1, entity.JSONBMap{"location": map[string]any{"building": "Factory-B"}})
+
+		got := tt.create(t, 2, 50, "location.building=Factory-A")
+		assert.Equal(t, string(campaignstatus.Running), got.Status)
+		assert.Equal(t, 1, got.CurrentWave)
+		assert.Equal(t, 2, got.TotalWaves)
+		assert.Equal(t, map[string]int64{"SENT": 2, "PENDING": 1}, got.Devices)
+
+		assert.ElementsMatch(t, []uuid.UUID{devices[0].ID, devices[1].ID}, tt.sentTo())
+
+		message := tt.commands.publisher.Messages()[0]
+		assert.Equal(t, got.ID.String(), message.Payload["campaignId"])
+		assert.Equal(t, "2.0.0", message.Payload["version"])
+		assert.Equal(t,
+			"https://iot.example.com/firmware/"+got.FirmwareID.String()+"/image", message.Payload["url"])
+		assert.NotEmpty(t, message.Payload["sha256"])
+		assert.True(t, message.ExpiresAt.After(time.Now().Add(59*time.Minute)))
+
+		manifest, err := tt.firmware.manifests.GetFirmwareManifest(ctx, got.FirmwareID)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"payload":   manifest.Payload,
+			"signature": manifest.Signature,
+			"keyId":     manifest.KeyID,
+			"algorithm": manifest.Algorithm,
+		}, message.Payload["manifest"])
+
+		assert.Contains(t, tt.commands.auditLogger.Actions(), entity.AuditFirmwareCampaignCreate)
+	})
+
+	t.Run("success: the devices already running the version are left out", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareCampaignTest()
+		tt.addDevices(2, entity.JSONBMap{"firmware": map[string]any{"version": "2.0.0"}})
+		devices := tt.addDevices(1, entity.JSONBMap{"firmware": map[string]any{"version": "1.0.0"}})
+
+		got := tt.create(t, 10, 0)
+		assert.Equal(t, map[string]int64{"SENT": 1}, got.Devices)
+		assert.Equal(t, []uuid.UUID{devices[0].ID}, tt.sentTo())
+	})
+
+	t.Run("success: the devices outside the target of the firmware are left out", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareCampaignTest()
+		devices := tt.addDevices(1, entity.JSONBMap{"model": "X100"})
+		tt.addDevices(1, entity.JSONBMap{"model": "X200"})
+
+		firmware, err := tt.firmware.uc.UploadFirmware(ctx, usecase.UploadFirmwareInput{
+			Version:             "2.0.0",
+			Description:         "",
+			MinHardwareRevision: 0,
+			Target:              []string{"model=X100"},
+			Image:               strings.NewReader("firmware image"),
+		})
+		require.NoError(t, err)
+
+		got, err := tt.uc.CreateFirmwareCampaign(ctx, usecase.CreateFirmwareCampaignInput{
+			Name:                 "Roll out 2.0.0",
+			FirmwareID:           firmware.ID,
+			Filter:               nil,
+			WaveSize:             10,
+			FailureThreshold:     0,
+			UpdateTimeoutSeconds: 0,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int64{"SENT": 1}, got.Devices)
+		assert.Equal(t, []uuid.UUID{devices[0].ID}, tt.sentTo())
+	})
+
+	t.Run("failure: no devices to update", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareCampaignTest()
+		tt.addDevices(1, entity.JSONBMap{"firmware": map[string]any{"version": "2.0.0"}})
+		firmware := tt.firmware.upload(t, "2.0.0", "firmware image")
+
+		_, err := tt.uc.CreateFirmwareCampaign(ctx, usecase.CreateFirmwareCampaignInput{
+			Name:                 "Roll out 2.0.0",
+			FirmwareID:           firmware.ID,
+			Filter:               nil,
+			WaveSize:             10,
+			FailureThreshold:     0,
+			UpdateTimeoutSeconds: 0,
+		})
+		require.ErrorIs(t, err, usecase.ErrNoFirmwareCampaignDevices)
+	})
+
+	t.Run("failure: invalid input", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareCampaignTest()
+		tt.addDevices(1, nil)
+		firmware := tt.firmware.upload(t, "2.0.0", "firmware image")
+
+		tests := []struct {
+			name    string
+			input   usecase.CreateFirmwareCampaignInput
+			wantErr error
+		}{
+			{
+				name: "invalid filter",
+				input: usecase.CreateFirmwareCampaignInput{ //nolint:exhaustruct
+					Filter: []string{"location..building=A"},
+				},
+				wantErr: usecase.ErrInvalidFirmwareCampaignFilter,
+			},
+			{
+				name:    "zero wave size",
+				input:   usecase.CreateFirmwareCampaignInput{WaveSize: 0}, //nolint:exhaustruct
+				wantErr: entity.ErrInvalidWaveSize,
+			},
+			{
+				name:    "failure threshold above 100",
+				input:   usecase.CreateFirmwareCampaignInput{WaveSize: 1, FailureThreshold: 101}, //nolint:exhaustruct
+				wantErr: entity.ErrInvalidFailureThreshold,
+			},
+			{
+				name: "update timeout above the maximum",
+				input: usecase.CreateFirmwareCampaignInput{ //nolint:exhaustruct
+					WaveSize:             1,
+					UpdateTimeoutSeconds: 3 * 3600,
+				},
+				wantErr: entity.ErrFirmwareUpdateTimeoutInvalid,
+			},
+			{
+				name: "update timeout above the maximum TTL of the commands",
+				input: usecase.CreateFirmwareCampaignInput{ //nolint:exhaustruct
+					WaveSize:             1,
+					UpdateTimeoutSeconds: 5400,
+				},
+				wantErr: entity.ErrFirmwareUpdateTimeoutInvalid,
+			},
+			{
+				name: "update timeout overflowing a duration",
+				input: usecase.CreateFirmwareCampaignInput{ //nolint:exhaustruct
+					WaveSize:             1,
+					UpdateTimeoutSeconds: math.MaxInt64,
+				},
+				wantErr: entity.ErrFirmwareUpdateTimeoutInvalid,
+			},
+			{
+				name:    "unknown firmware",
+				input:   usecase.CreateFirmwareCampaignInput{WaveSize: 1, FirmwareID: uuid.New()}, //nolint:exhaustruct
+				wantErr: entity.ErrFirmwareArtifactNotFound,
+			},
+		}
+
+		for _, test := range tests {
+			input := test.input
+			input.Name = "Roll out 2.0.0"
+
+			if input.FirmwareID == uuid.Nil {
+				input.FirmwareID = firmware.ID
+			}
+
+			_, err := tt.uc.CreateFirmwareCampaign(ctx, input)
+			require.ErrorIs(t, err, test.wantErr, test.name)
+		}
+
+		assert.Empty(t, tt.sentTo())
+	})
+}
+
+// TestReportFirmwareProgress tests the ReportFirmwareProgress method and how the waves follow each other.
+func TestReportFirmwareProgress(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("success: the next wave starts once the current one is over, until completion", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareCampaignTest()
+		devices := tt.addDevices(3, entity.JSONBMap{"firmware": map[string]any{"version": "1.0.0"}})
+		campaign := tt.create(t, 2, 50)
+
+		require.NoError(t, tt.report(campaign.ID, devices[0].ID, `"status": "DOWNLOADING", "progress": 40`))
+		require.NoError(t, tt.report(campaign.ID, devices[0].ID, `"status": "SUCCEEDED"`))
+		assert.Len(t, tt.sentTo(), 2, "the second wave waits for the first one to be over")
+
+		require.NoError(t, tt.report(campaign.ID, devices[1].ID, `"status": "SUCCEEDED"`))
+
+		sent := tt.sentTo()
+		require.Len(t, sent, 3)
+		assert.Equal(t, devices[2].ID, sent[2])
+
+		got, err := tt.uc.GetFirmwareCampaign(ctx, campaign.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, got.CurrentWave)
+		assert.Equal(t, map[string]int64{"SUCCEEDED": 2, "SENT": 1}, got.Devices)
+
+		require.NoError(t, tt.report(campaign.ID, devices[2].ID, `"status": "SUCCEEDED"`))
+
+		got, err = tt.uc.GetFirmwareCampaign(ctx, campaign.ID)
+		require.NoError(t, err)
+		assert.Equal(t, string(campaignstatus.Completed), got.Status)
+
+		// The new version is recorded in the metadata of the devices.
+		stored, err := tt.commands.deviceRepo.FindByID(ctx, devices[0].ID)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"version": "2.0.0"}, stored.Metadata["firmware"])
+
+		updates, err := tt.uc.ListFirmwareCampaignDevices(ctx, usecase.ListFirmwareCampaignDevicesInput{
+			CampaignID: campaign.ID,
+			Status:     "succeeded",
+		})
+		require.NoError(t, err)
+		require.Len(t, updates, 3)
+		assert.Equal(t, 100, updates[0].Progress)
+		assert.NotNil(t, updates[0].CompletedAt)
+	})
+
+	t.Run("success: failures above the threshold pause the campaign until it is resumed", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareCampaignTest()
+		devices := tt.addDevices(4, nil)
+		campaign := tt.create(t, 2, 25)
+
+		require.NoError(t, tt.report(campaign.ID, devices[0].ID, `"status": "FAILED", "error": "flash error"`))
+
+		got, err := tt.uc.GetFirmwareCampaign(ctx, campaign.ID)
+		require.NoError(t, err)
+		assert.Equal(t, string(campaignstatus.Paused), got.Status)
+		assert.Contains(t, got.StatusReason, "1 of 2 updates failed")
+
+		logs := tt.commands.auditLogger.Logs()
+		pause := logs[len(logs)-1]
+		assert.Equal(t, entity.AuditFirmwareCampaignPause, pause.Action)
+		assert.Equal(t, entity.SystemActor, pause.Actor)
+
+		// The device still updating carries on, but the next wave does not start.
+		require.NoError(t, tt.report(campaign.ID, devices[1].ID, `"status": "SUCCEEDED"`))
+		assert.Len(t, tt.sentTo(), 2)
+
+		_, err = tt.uc.ResumeFirmwareCampaign(ctx, usecase.ResumeFirmwareCampaignInput{
+			ID:               campaign.ID,
+			FailureThreshold: nil,
+		})
+		require.NoError(t, err)
+
+		got, err = tt.uc.GetFirmwareCampaign(ctx, campaign.ID)
+		require.NoError(t, err)
+		assert.Equal(t, string(campaignstatus.Paused), got.Status, "the failures still exceed the threshold")
+
+		threshold := 50
+		got, err = tt.uc.ResumeFirmwareCampaign(ctx, usecase.ResumeFirmwareCampaignInput{
+			ID:               campaign.ID,
+			FailureThreshold: &threshold,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, string(campaignstatus.Running), got.Status)
+		assert.Equal(t, 2, got.CurrentWave)
+		assert.Len(t, tt.sentTo(), 4)
+	})
+
+	t.Run("success: updates that are not completed in time fail", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareCampaignTest()
+		devices := tt.addDevices(1, nil)
+		firmware := tt.firmware.upload(t, "2.0.0", "firmware image")
+
+		campaign, err := tt.uc.CreateFirmwareCampaign(ctx, usecase.CreateFirmwareCampaignInput{
+			Name:                 "Roll out 2.0.0",
+			FirmwareID:           firmware.ID,
+			Filter:               nil,
+			WaveSize:             1,
+			FailureThreshold:     100,
+			UpdateTimeoutSeconds: 1,
+		})
+		require.NoError(t, err)
+
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		go tt.uc.Run(runCtx)
+
+		assert.Eventually(t, func() bool {
+			return tt.campaignRepo.status(campaign.ID, devices[0].ID) == updatestatus.Failed
+		}, 3*time.Second, 10*time.Millisecond)
+
+		assert.Eventually(t, func() bool {
+			got, err := tt.uc.GetFirmwareCampaign(ctx, campaign.ID)
+
+			return err == nil && got.Status == string(campaignstatus.Completed)
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("success: a campaign changed concurrently is reloaded before the next wave starts", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareCampaignTest()
+		devices := tt.addDevices(3, nil)
+		campaign := tt.create(t, 1, 50)
+
+		// The failure threshold is raised while the first wave completes.
+		tt.campaignRepo.mu.Lock()
+		tt.campaignRepo.ConcurrentChange = func(stored *entity.FirmwareCampaign) {
+			stored.FailureThreshold = 80
+		}
+		tt.campaignRepo.mu.Unlock()
+
+		require.NoError(t, tt.report(campaign.ID, devices[0].ID, `"status": "SUCCEEDED"`))
+		assert.Equal(t, []uuid.UUID{devices[0].ID, devices[1].ID}, tt.sentTo(), "the second wave starts at once")
+
+		got, err := tt.uc.GetFirmwareCampaign(ctx, campaign.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, got.CurrentWave)
+		assert.Equal(t, 80, got.FailureThreshold, "the concurrent change is kept")
+	})
+
+	t.Run("success: updates of a paused campaign still time out", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareCampaignTest()
+		devices := tt.addDevices(2, nil)
+		firmware := tt.firmware.upload(t, "2.0.0", "firmware image")
+
+		campaign, err := tt.uc.CreateFirmwareCampaign(ctx, usecase.CreateFirmwareCampaignInput{
+			Name:                 "Roll out 2.0.0",
+			FirmwareID:           firmware.ID,
+			Filter:               nil,
+			WaveSize:             1,
+			FailureThreshold:     100,
+			UpdateTimeoutSeconds: 1,
+		})
+		require.NoError(t, err)
+
+		_, err = tt.uc.PauseFirmwareCampaign(ctx, campaign.ID)
+		require.NoError(t, err)
+
+		runCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		go tt.uc.Run(runCtx)
+
+		assert.Eventually(t, func() bool {
+			return tt.campaignRepo.status(campaign.ID, devices[0].ID) == updatestatus.Failed
+		}, 3*time.Second, 10*time.Millisecond)
+
+		// The campaign stays paused, so the next wave does not start.
+		got, err := tt.uc.GetFirmwareCampaign(ctx, campaign.ID)
+		require.NoError(t, err)
+		assert.Equal(t, string(campaignstatus.Paused), got.Status)
+		assert.Equal(t, updatestatus.Pending, tt.campaignRepo.status(campaign.ID, devices[1].ID))
+	})
+
+	t.Run("success: updates that cannot be sent fail", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareCampaignTest()
+		devices := tt.addDevices(2, nil)
+		campaign := tt.create(t, 1, 100)
+
+		// The device of the second wave is suspended before its wave starts, so its command is rejected.
+		devices[1].Status = devicestatus.Suspended
+
+		require.NoError(t, tt.report(campaign.ID, devices[0].ID, `"status": "SUCCEEDED"`))
+		assert.Equal(t, updatestatus.Failed, tt.campaignRepo.status(campaign.ID, devices[1].ID))
+
+		updates, err := tt.uc.ListFirmwareCampaignDevices(ctx, usecase.ListFirmwareCampaignDevicesInput{
+			CampaignID: campaign.ID,
+			Status:     "failed",
+		})
+		require.NoError(t, err)
+		require.Len(t, updates, 1)
+		assert.Equal(t, "command dispatch failed", updates[0].FailureReason, "the cause is not exposed")
+	})
+
+	t.Run("failure: the report is invalid or does not follow the update", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareCampaignTest()
+		devices := tt.addDevices(1, nil)
+		campaign := tt.create(t, 1, 0)
+
+		require.ErrorIs(t, tt.report(campaign.ID, devices[0].ID, `"status": "DONE"`), usecase.ErrInvalidFirmwareReport)
+		require.ErrorIs(t,
+			tt.report(campaign.ID, devices[0].ID, `"status": "INSTALLING", "progress": 120`),
+			entity.ErrInvalidFirmwareProgress)
+		require.ErrorIs(t,
+			tt.report(campaign.ID, devices[0].ID, `"status": "PENDING"`), updatestatus.ErrInvalidTransition)
+		require.ErrorIs(t,
+			tt.report(uuid.New(), devices[0].ID, `"status": "SUCCEEDED"`), entity.ErrFirmwareUpdateNotFound)
+
+		require.NoError(t, tt.report(campaign.ID, devices[0].ID, `"status": "SUCCEEDED"`))
+		require.ErrorIs(t,
+			tt.report(campaign.ID, devices[0].ID, `"status": "FAILED"`), updatestatus.ErrInvalidTransition)
+	})
+}
+
+// TestCancelFirmwareCampaign tests the PauseFirmwareCampaign and CancelFirmwareCampaign methods.
+func TestCancelFirmwareCampaign(t *testing.T) {
+	t.Parallel()
+
+	ctx := usecase.WithActor(context.Background(), "alice")
+
+	tt := newFirmwareCampaignTest()
+	devices := tt.addDevices(2, nil)
+	campaign := tt.create(t, 1, 100)
+
+	got, err := tt.uc.PauseFirmwareCampaign(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(campaignstatus.Paused), got.Status)
+	assert.Equal(t, "paused by alice", got.StatusReason)
+
+	got, err = tt.uc.CancelFirmwareCampaign(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(campaignstatus.Cancelled), got.Status)
+
+	logs := tt.commands.auditLogger.Logs()
+	cancel := logs[len(logs)-1]
+	assert.Equal(t, entity.AuditFirmwareCampaignCancel, cancel.Action)
+	assert.Equal(t, "alice", cancel.Actor)
+	assert.Equal(t, "firmware-campaign/"+campaign.ID.String(), auditDetails(t, cancel).Target)
+
+	// The device already updating can still report, but the next wave never starts.
+	require.NoError(t, tt.report(campaign.ID, devices[0].ID, `"status": "SUCCEEDED"`))
+	assert.Equal(t, updatestatus.Pending, tt.campaignRepo.status(campaign.ID, devices[1].ID))
+
+	_, err = tt.uc.ResumeFirmwareCampaign(ctx, usecase.ResumeFirmwareCampaignInput{
+		ID:               campaign.ID,
+		FailureThreshold: nil,
+	})
+	require.ErrorIs(t, err, campaignstatus.ErrInvalidTransition)
+
+	_, err = tt.uc.CancelFirmwareCampaign(ctx, uuid.New())
+	require.ErrorIs(t, err, entity.ErrFirmwareCampaignNotFound)
+}
@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+)
+
+// UploadFirmwareInput is the input data for uploading a firmware image.
+type UploadFirmwareInput struct {
+	Version     string // Required: the version devices report in their `firmware.version` metadata.
+	Description string // Optional
+	// MinHardwareRevision is the lowest hardware revision the firmware runs on. Optional: 0 for any revision.
+	MinHardwareRevision int
+	// Target holds conditions on the metadata in the syntax of ListDevicesInput.Metadata, all of which the devices
+	// the firmware is meant for satisfy. Optional: campaigns only update those devices.
+	Target []string
+	// Image is the content of the firmware image. It is read to the end.
+	Image io.Reader
+}
+
+// FirmwareOutput is the output data of a firmware artifact. Devices verify the image they download
+// with its SHA-256 hash and its signature.
+type FirmwareOutput struct {
+	ID          uuid.UUID `json:"id"`
+	Version     string    `json:"version"`
+	Description string    `json:"description"`
+	// MinHardwareRevision and Target are stated in the signed manifest, which devices check.
+	MinHardwareRevision int      `json:"minHardwareRevision"`
+	Target              []string `json:"target"`
+	Size                int64    `json:"size"`
+	SHA256              string   `json:"sha256"`
+	// Signature is base64-encoded in JSON.
+	Signature          []byte    `json:"signature"`
+	SignatureAlgorithm string    `json:"signatureAlgorithm"`
+	CreatedAt          time.Time `json:"createdAt"`
+}
+
+// FirmwareImageOutput is a firmware image opened for download, along with its artifact.
+// The caller must close the image.
+type FirmwareImageOutput struct {
+	Firmware *FirmwareOutput
+	Image    io.ReadSeekCloser
+}
+
+// NewFirmwareOutput creates a new FirmwareOutput from a FirmwareArtifact entity.
+func NewFirmwareOutput(artifact *entity.FirmwareArtifact) *FirmwareOutput {
+	return &FirmwareOutput{
+		ID:                  artifact.ID,
+		Version:             artifact.Version,
+		Description:         artifact.Description,
+		MinHardwareRevision: artifact.MinHardwareRevision,
+		Target:              []string(artifact.Target),
+		Size:                artifact.Size,
+		SHA256:              artifact.SHA256,
+		Signature:           artifact.Signature,
+		SignatureAlgorithm:  artifact.SignatureAlgorithm,
+		CreatedAt:           artifact.CreatedAt,
+	}
+}
@@ -0,0 +1,448 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"backend/internal/domain/service"
+)
+
+const (
+	// DefaultFirmwareKeyAlgorithm is the default algorithm of the keys that sign firmware manifests.
+	DefaultFirmwareKeyAlgorithm = service.FirmwareKeyEd25519
+	// DefaultFirmwareKeyGracePeriod is the default time the manifests signed by a retired key remain verifiable.
+	DefaultFirmwareKeyGracePeriod = 30 * 24 * time.Hour
+)
+
+// FirmwareManifestConfig configures the keys that sign firmware manifests.
+type FirmwareManifestConfig struct {
+	// KeyAlgorithm is the algorithm of the keys generated when a rotation does not specify one,
+	// e.g. service.FirmwareKeyEd25519.
+	KeyAlgorithm string
+	// GracePeriod is how long the manifests signed by a retired key remain verifiable,
+	// when a rotation does not specify it.
+	GracePeriod time.Duration
+}
+
+// withDefaults returns the config with zero values replaced by the defaults.
+func (c FirmwareManifestConfig) withDefaults() FirmwareManifestConfig {
+	if c.KeyAlgorithm == "" {
+		c.KeyAlgorithm = DefaultFirmwareKeyAlgorithm
+	}
+
+	if c.GracePeriod <= 0 {
+		c.GracePeriod = DefaultFirmwareKeyGracePeriod
+	}
+
+	return c
+}
+
+// gracePeriod returns the grace period requested in seconds, or the configured one if gracePeriodSeconds is nil.
+// Zero is allowed, so that a leaked key can be revoked immediately.
+func (c FirmwareManifestConfig) gracePeriod(gracePeriodSeconds *int64) (time.Duration, error) {
+	if gracePeriodSeconds == nil {
+		return c.GracePeriod, nil
+	}
+
+	return secondsToDuration(*gracePeriodSeconds, 0, math.MaxInt64, entity.ErrInvalidGracePeriod)
+}
+
+// FirmwareManifestUsecase defines the interface for signing the manifests of firmware artifacts,
+// which devices verify before they install an image, and for managing the keys that sign them.
+type FirmwareManifestUsecase interface {
+	// PrepareFirmwareSigningKey makes sure that there is an active key, so that manifests can be signed.
+	// It is called at startup.
+	PrepareFirmwareSigningKey(ctx context.Context) error
+	// GetFirmwareManifest retrieves the signed manifest of a firmware artifact.
+	GetFirmwareManifest(ctx context.Context, firmwareID uuid.UUID) (*FirmwareManifestOutput, error)
+	// SignFirmwareManifest signs the manifest of a firmware artifact with the active key,
+	// replacing its previous manifest.
+	SignFirmwareManifest(ctx context.Context, firmwareID uuid.UUID) (*FirmwareManifestOutput, error)
+	// ListFirmwareSigningKeys retrieves the keys that devices can verify manifests with, newest first.
+	ListFirmwareSigningKeys(ctx context.Context) ([]*FirmwareSigningKeyOutput, error)
+	// RotateFirmwareSigningKey replaces the active key with a new one and re-signs every manifest with it.
+	RotateFirmwareSigningKey(
+		ctx context.Context,
+		input RotateFirmwareSigningKeyInput,
+	) (*FirmwareSigningKeyOutput, error)
+}
+
+// firmwareManifestUsecase is the implementation of the FirmwareManifestUsecase interface.
+type firmwareManifestUsecase struct {
+	artifactRepo repository.FirmwareArtifactRepository
+	keyRepo      repository.FirmwareSigningKeyRepository
+	keyStore     service.FirmwareKeyStore
+	auditLogger  repository.AuditLogger
+	transactor   repository.Transactor
+	config       FirmwareManifestConfig
+	now          func() time.Time
+}
+
+// NewFirmwareManifestUsecase creates a new instance of firmwareManifestUsecase.
+// Zero values in config are replaced by the defaults.
+//
+//nolint:ireturn
+func NewFirmwareManifestUsecase(
+	artifactRepo repository.FirmwareArtifactRepository,
+	keyRepo repository.FirmwareSigningKeyRepository,
+	keyStore service.FirmwareKeyStore,
+	auditLogger repository.AuditLogger,
+	transactor repository.Transactor,
+	config FirmwareManifestConfig,
+) FirmwareManifestUsecase {
+	return &firmwareManifestUsecase{
+		artifactRepo: artifactRepo,
+		keyRepo:      keyRepo,
+		keyStore:     keyStore,
+		auditLogger:  auditLogger,
+		transactor:   transactor,
+		config:       config.withDefaults(),
+		now:          time.Now,
+	}
+}
+
+// PrepareFirmwareSigningKey generates a key if there is no active key yet, e.g. on the first startup,
+// and signs the manifest of every artifact with it in the same transaction.
+// If the transaction fails, the private half of the key is deleted.
+func (uc *firmwareManifestUsecase) PrepareFirmwareSigningKey(ctx context.Context) error {
+	var key *entity.FirmwareSigningKey
+
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := uc.lockKeys(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = uc.findActiveKey(ctx)
+		if !errors.Is(err, entity.ErrFirmwareSigningKeyNotFound) {
+			return err
+		}
+
+		key, err = uc.generateKey(ctx, "")
+		if err != nil {
+			return err
+		}
+
+		err = uc.saveKey(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		return uc.signAll(ctx, key)
+	})
+	if err != nil {
+		if key != nil {
+			uc.deleteKey(ctx, key.ID)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// GetFirmwareManifest retrieves the signed manifest of a firmware artifact.
+// It returns entity.ErrFirmwareManifestNotFound if the artifact exists but has no manifest.
+func (uc *firmwareManifestUsecase) GetFirmwareManifest(
+	ctx context.Context,
+	firmwareID uuid.UUID,
+) (*FirmwareManifestOutput, error) {
+	manifest, err := uc.artifactRepo.FindManifest(ctx, firmwareID)
+	if err == nil {
+		return NewFirmwareManifestOutput(manifest), nil
+	}
+
+	if !isNotFound(err, entity.ErrFirmwareManifestNotFound) {
+		return nil, fmt.Errorf("%w: %w", ErrDBFindByID, err)
+	}
+
+	_, err = findFirmwareArtifact(ctx, uc.artifactRepo, firmwareID)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, entity.ErrFirmwareManifestNotFound
+}
+
+// SignFirmwareManifest signs the manifest of a firmware artifact with the active key,
+// and stores it in place of the previous one.
+// It holds the lock on the keys until the end of the transaction, so that a concurrent rotation either
+// waits for the manifest to re-sign it, or has already made its key active.
+func (uc *firmwareManifestUsecase) SignFirmwareManifest(
+	ctx context.Context,
+	firmwareID uuid.UUID,
+) (*FirmwareManifestOutput, error) {
+	artifact, err := findFirmwareArtifact(ctx, uc.artifactRepo, firmwareID)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest *entity.FirmwareManifest
+
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := uc.lockKeys(ctx)
+		if err != nil {
+			return err
+		}
+
+		key, err := uc.findActiveKey(ctx)
+		if err != nil {
+			return err
+		}
+
+		manifest, err = uc.sign(ctx, artifact, key)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFirmwareManifestOutput(manifest), nil
+}
+
+// ListFirmwareSigningKeys retrieves the active key and the retired keys still in their grace period, newest first.
+// Devices trust the manifests signed by any of them.
+func (uc *firmwareManifestUsecase) ListFirmwareSigningKeys(ctx context.Context) ([]*FirmwareSigningKeyOutput, error) {
+	keys, err := uc.keyRepo.FindVerifiable(ctx, uc.now())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDBFindAll, err)
+	}
+
+	outputs := make([]*FirmwareSigningKeyOutput, 0, len(keys))
+	for _, key := range keys {
+		outputs = append(outputs, NewFirmwareSigningKeyOutput(key))
+	}
+
+	return outputs, nil
+}
+
+// RotateFirmwareSigningKey generates a new key and, in a single transaction, retires the active key for the grace
+// period, makes the new key active, re-signs the manifest of every artifact with it, and records audit log entries
+// for both keys. The lock on the keys makes a concurrent upload sign its manifest before or after the rotation.
+// The private half of the retired key is then deleted, as it no longer signs: the manifests it signed are verified
+// with its public half, which stays listed for the grace period.
+// If another rotation takes over concurrently, the error wraps entity.ErrFirmwareSigningKeyConflict.
+func (uc *firmwareManifestUsecase) RotateFirmwareSigningKey(
+	ctx context.Context,
+	input RotateFirmwareSigningKeyInput,
+) (*FirmwareSigningKeyOutput, error) {
+	gracePeriod, err := uc.config.gracePeriod(input.GracePeriodSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := uc.generateKey(ctx, input.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	var retired *entity.FirmwareSigningKey
+
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := uc.lockKeys(ctx)
+		if err != nil {
+			return err
+		}
+
+		retired, err = uc.retireActiveKey(ctx, gracePeriod)
+		if err != nil {
+			return err
+		}
+
+		err = uc.saveKey(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		err = uc.signAll(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, uc.auditLogger, auditEvent{
+			action:   entity.AuditFirmwareSigningKeyRotate,
+			deviceID: uuid.Nil,
+			target:   firmwareSigningKeyTarget(key.ID),
+			before:   nil,
+			after:    firmwareSigningKeySnapshot(key),
+		})
+	})
+	if err != nil {
+		uc.deleteKey(ctx, key.ID)
+
+		return nil, err
+	}
+
+	if retired != nil {
+		uc.deleteKey(ctx, retired.ID)
+	}
+
+	return NewFirmwareSigningKeyOutput(key), nil
+}
+
+// lockKeys serializes the signing of manifests and the rotations of the keys until the end of the transaction.
+func (uc *firmwareManifestUsecase) lockKeys(ctx context.Context) error {
+	err := uc.keyRepo.Lock(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDBFirmwareSigningKeyLock, err)
+	}
+
+	return nil
+}
+
+// findActiveKey retrieves the active key, translating a missing key into entity.ErrFirmwareSigningKeyNotFound.
+func (uc *firmwareManifestUsecase) findActiveKey(ctx context.Context) (*entity.FirmwareSigningKey, error) {
+	key, err := uc.keyRepo.FindActive(ctx)
+	if err != nil {
+		if isNotFound(err, entity.ErrFirmwareSigningKeyNotFound) {
+			return nil, entity.ErrFirmwareSigningKeyNotFound
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrDBFindByID, err)
+	}
+
+	return key, nil
+}
+
+// generateKey generates a new key in the key store, with the default algorithm if algorithm is empty.
+func (uc *firmwareManifestUsecase) generateKey(
+	ctx context.Context,
+	algorithm string,
+) (*entity.FirmwareSigningKey, error) {
+	if algorithm == "" {
+		algorithm = uc.config.KeyAlgorithm
+	}
+
+	publicKey, err := uc.keyStore.GenerateKey(ctx, algorithm)
+	if err != nil {
+		if errors.Is(err, service.ErrUnsupportedKeyAlgorithm) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrFirmwareSign, err)
+	}
+
+	return entity.NewFirmwareSigningKey(publicKey.ID, publicKey.Algorithm, publicKey.PublicKey), nil
+}
+
+// saveKey stores a new active key.
+func (uc *firmwareManifestUsecase) saveKey(ctx context.Context, key *entity.FirmwareSigningKey) error {
+	err := uc.keyRepo.Save(ctx, key)
+	if err != nil {
+		if errors.Is(err, entity.ErrFirmwareSigningKeyConflict) {
+			return err
+		}
+
+		return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+	}
+
+	return nil
+}
+
+// retireActiveKey retires the active key for the grace period along with an audit log entry, and returns it.
+// It returns nil if there is none.
+func (uc *firmwareManifestUsecase) retireActiveKey(
+	ctx context.Context,
+	gracePeriod time.Duration,
+) (*entity.FirmwareSigningKey, error) {
+	key, err := uc.findActiveKey(ctx)
+	if errors.Is(err, entity.ErrFirmwareSigningKeyNotFound) {
+		return nil, nil //nolint:nilnil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	before := firmwareSigningKeySnapshot(key)
+
+	err = key.Retire(uc.now(), gracePeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.keyRepo.Retire(ctx, key)
+	if err != nil {
+		if errors.Is(err, entity.ErrFirmwareSigningKeyConflict) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrRepositorySave, err)
+	}
+
+	err = recordAudit(ctx, uc.auditLogger, auditEvent{
+		action:   entity.AuditFirmwareSigningKeyRotate,
+		deviceID: uuid.Nil,
+		target:   firmwareSigningKeyTarget(key.ID),
+		before:   before,
+		after:    firmwareSigningKeySnapshot(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// signAll re-signs the manifest of every artifact with the key.
+func (uc *firmwareManifestUsecase) signAll(ctx context.Context, key *entity.FirmwareSigningKey) error {
+	artifacts, err := uc.artifactRepo.FindAll(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDBFindAll, err)
+	}
+
+	for _, artifact := range artifacts {
+		_, err = uc.sign(ctx, artifact, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sign signs the manifest of an artifact with the key, and stores it.
+func (uc *firmwareManifestUsecase) sign(
+	ctx context.Context,
+	artifact *entity.FirmwareArtifact,
+	key *entity.FirmwareSigningKey,
+) (*entity.FirmwareManifest, error) {
+	manifest, err := entity.NewFirmwareManifest(artifact, key, uc.now())
+	if err != nil {
+		return nil, err
+	}
+
+	manifest.Signature, err = uc.keyStore.Sign(ctx, key.ID, manifest.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFirmwareSign, err)
+	}
+
+	err = uc.artifactRepo.SaveManifest(ctx, manifest)
+	if err != nil {
+		if errors.Is(err, entity.ErrFirmwareArtifactNotFound) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrRepositorySave, err)
+	}
+
+	return manifest, nil
+}
+
+// deleteKey deletes the private half of a key that does not sign. A failure only leaves an unused key behind,
+// so it is logged rather than returned.
+func (uc *firmwareManifestUsecase) deleteKey(ctx context.Context, keyID string) {
+	err := uc.keyStore.DeleteKey(ctx, keyID)
+	if err != nil {
+		log.Printf("failed to delete firmware signing key %s: %v", keyID, err)
+	}
+}
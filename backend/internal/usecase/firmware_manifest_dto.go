@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"encoding/pem"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+)
+
+// RotateFirmwareSigningKeyInput is the input data for rotating the key that signs firmware manifests.
+type RotateFirmwareSigningKeyInput struct {
+	// Algorithm is the algorithm of the new key, "Ed25519" or "ECDSA-P256-SHA256".
+	// Optional: if empty, the default algorithm is used.
+	Algorithm string `json:"algorithm"`
+	// GracePeriodSeconds is how long the manifests signed by the retired key remain verifiable.
+	// Optional: if nil, the default grace period is used. Zero stops trusting them at once, e.g. for a leaked key.
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds"`
+}
+
+// FirmwareManifestOutput is the output data of a signed firmware manifest. Devices verify the signature
+// over the payload exactly as received with the key of KeyID, and then read the payload.
+type FirmwareManifestOutput struct {
+	FirmwareID uuid.UUID `json:"firmwareId"`
+	KeyID      string    `json:"keyId"`
+	Algorithm  string    `json:"algorithm"`
+	// Payload is the JSON manifest, base64-encoded in JSON: {"firmwareId": ..., "version": ..., "sha256": ...,
+	// "size": ..., "minHardwareRevision": ..., "target": [...], "keyId": ..., "issuedAt": ...}.
+	Payload []byte `json:"payload"`
+	// Signature is base64-encoded in JSON.
+	Signature []byte    `json:"signature"`
+	IssuedAt  time.Time `json:"issuedAt"`
+}
+
+// FirmwareSigningKeyOutput is the output data of a key that signs firmware manifests.
+type FirmwareSigningKeyOutput struct {
+	ID        string `json:"id"`
+	Algorithm string `json:"algorithm"`
+	// PublicKey is the PEM-encoded public key, which devices verify the manifests with.
+	PublicKey string `json:"publicKey"`
+	// Active is true for the key that signs the manifests.
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RetiredAt *time.Time `json:"retiredAt"`
+	// ExpiresAt is when the manifests signed by a retired key stop being trusted.
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// NewFirmwareManifestOutput creates a new FirmwareManifestOutput from a FirmwareManifest entity.
+func NewFirmwareManifestOutput(manifest *entity.FirmwareManifest) *FirmwareManifestOutput {
+	return &FirmwareManifestOutput{
+		FirmwareID: manifest.FirmwareID,
+		KeyID:      manifest.KeyID,
+		Algorithm:  manifest.Algorithm,
+		Payload:    manifest.Payload,
+		Signature:  manifest.Signature,
+		IssuedAt:   manifest.IssuedAt,
+	}
+}
+
+// NewFirmwareSigningKeyOutput creates a new FirmwareSigningKeyOutput from a FirmwareSigningKey entity.
+func NewFirmwareSigningKeyOutput(key *entity.FirmwareSigningKey) *FirmwareSigningKeyOutput {
+	return &FirmwareSigningKeyOutput{
+		ID:        key.ID,
+		Algorithm: key.Algorithm,
+		PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Headers: nil, Bytes: key.PublicKey})),
+		Active:    key.IsActive(),
+		CreatedAt: key.CreatedAt,
+		RetiredAt: key.RetiredAt,
+		ExpiresAt: key.ExpiresAt,
+	}
+}
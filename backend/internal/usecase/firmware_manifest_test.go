@@ -0,0 +1,420 @@
+package usecase_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/service"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// FakeFirmwareSigningKeyRepository is an in-memory implementation of the FirmwareSigningKeyRepository for testing.
+type FakeFirmwareSigningKeyRepository struct {
+	mu   sync.Mutex
+	keys []*entity.FirmwareSigningKey
+}
+
+// NewFakeFirmwareSigningKeyRepository creates a new FakeFirmwareSigningKeyRepository.
+func NewFakeFirmwareSigningKeyRepository() *FakeFirmwareSigningKeyRepository {
+	return &FakeFirmwareSigningKeyRepository{mu: sync.Mutex{}, keys: nil}
+}
+
+// Save adds a key to the in-memory store. Like the partial unique index, it rejects a second active key.
+func (r *FakeFirmwareSigningKeyRepository) Save(_ context.Context, key *entity.FirmwareSigningKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, other := range r.keys {
+		if other.IsActive() {
+			return entity.ErrFirmwareSigningKeyConflict
+		}
+	}
+
+	// The keys are created a second apart, so that they are ordered.
+	key.CreatedAt = time.Date(2026, 4, 1, 9, 0, len(r.keys), 0, time.UTC)
+	stored := *key
+	r.keys = append(r.keys, &stored)
+
+	return nil
+}
+
+// Retire stores the retirement of a key if it is still active.
+func (r *FakeFirmwareSigningKeyRepository) Retire(_ context.Context, key *entity.FirmwareSigningKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.keys {
+		if stored.ID == key.ID && stored.IsActive() {
+			stored.RetiredAt = key.RetiredAt
+			stored.ExpiresAt = key.ExpiresAt
+
+			return nil
+		}
+	}
+
+	return entity.ErrFirmwareSigningKeyConflict
+}
+
+// FindActive retrieves the active key from the in-memory store.
+func (r *FakeFirmwareSigningKeyRepository) FindActive(_ context.Context) (*entity.FirmwareSigningKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.keys {
+		if stored.IsActive() {
+			found := *stored
+
+			return &found, nil
+		}
+	}
+
+	return nil, entity.ErrFirmwareSigningKeyNotFound
+}
+
+// FindVerifiable retrieves the keys still verifiable at now from the in-memory store, newest first.
+func (r *FakeFirmwareSigningKeyRepository) FindVerifiable(
+	_ context.Context,
+	now time.Time,
+) ([]*entity.FirmwareSigningKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var keys []*entity.FirmwareSigningKey
+
+	for _, stored := range slices.Backward(r.keys) {
+		if stored.IsVerifiableAt(now) {
+			found := *stored
+			keys = append(keys, &found)
+		}
+	}
+
+	return keys, nil
+}
+
+// Lock does nothing, as the tests do not sign manifests and rotate keys concurrently.
+func (r *FakeFirmwareSigningKeyRepository) Lock(context.Context) error {
+	return nil
+}
+
+// FakeFirmwareKeyStore is an in-memory implementation of service.FirmwareKeyStore for testing.
+// It only generates Ed25519 keys, whose signatures the tests verify.
+type FakeFirmwareKeyStore struct {
+	mu   sync.Mutex
+	keys map[string]ed25519.PrivateKey
+}
+
+// NewFakeFirmwareKeyStore creates a new FakeFirmwareKeyStore.
+func NewFakeFirmwareKeyStore() *FakeFirmwareKeyStore {
+	return &FakeFirmwareKeyStore{mu: sync.Mutex{}, keys: make(map[string]ed25519.PrivateKey)}
+}
+
+// GenerateKey generates an Ed25519 key, identified by a random ID.
+func (s *FakeFirmwareKeyStore) GenerateKey(_ context.Context, algorithm string) (*service.FirmwarePublicKey, error) {
+	if algorithm != service.FirmwareKeyEd25519 {
+		return nil, fmt.Errorf("%w: %q", service.ErrUnsupportedKeyAlgorithm, algorithm)
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := uuid.NewString()
+	s.keys[id] = privateKey
+
+	return &service.FirmwarePublicKey{ID: id, Algorithm: algorithm, PublicKey: der}, nil
+}
+
+// Sign signs the manifest with the key.
+func (s *FakeFirmwareKeyStore) Sign(_ context.Context, keyID string, manifest []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	privateKey, ok := s.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", service.ErrFirmwareKeyNotFound, keyID)
+	}
+
+	return ed25519.Sign(privateKey, manifest), nil
+}
+
+// DeleteKey removes the key.
+func (s *FakeFirmwareKeyStore) DeleteKey(_ context.Context, keyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, keyID)
+
+	return nil
+}
+
+// Len returns the number of stored keys.
+func (s *FakeFirmwareKeyStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.keys)
+}
+
+// verifyManifest checks the signature of a manifest with the public key devices would use, and decodes it.
+func verifyManifest(
+	t *testing.T,
+	manifest *usecase.FirmwareManifestOutput,
+	keys []*usecase.FirmwareSigningKeyOutput,
+) entity.FirmwareManifestContent {
+	t.Helper()
+
+	index := slices.IndexFunc(keys, func(key *usecase.FirmwareSigningKeyOutput) bool {
+		return key.ID == manifest.KeyID
+	})
+	require.GreaterOrEqual(t, index, 0, "the key of the manifest is listed")
+
+	block, _ := pem.Decode([]byte(keys[index].PublicKey))
+	require.NotNil(t, block)
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	require.NoError(t, err)
+
+	ed25519Key, ok := publicKey.(ed25519.PublicKey)
+	require.True(t, ok)
+	require.True(t, ed25519.Verify(ed25519Key, manifest.Payload, manifest.Signature), "the signature is valid")
+
+	var content entity.FirmwareManifestContent
+	require.NoError(t, json.Unmarshal(manifest.Payload, &content))
+	assert.Equal(t, manifest.KeyID, content.KeyID)
+
+	return content
+}
+
+// TestGetFirmwareManifest tests the GetFirmwareManifest and ListFirmwareSigningKeys methods.
+func TestGetFirmwareManifest(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("success: the manifest of an uploaded firmware is signed with the active key", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareTest()
+		firmware := tt.upload(t, "1.2.0", "firmware image")
+
+		manifest, err := tt.manifests.GetFirmwareManifest(ctx, firmware.ID)
+		require.NoError(t, err)
+		assert.Equal(t, service.FirmwareKeyEd25519, manifest.Algorithm)
+
+		keys, err := tt.manifests.ListFirmwareSigningKeys(ctx)
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		assert.True(t, keys[0].Active)
+
+		content := verifyManifest(t, manifest, keys)
+		assert.Equal(t, firmware.ID, content.FirmwareID)
+		assert.Equal(t, "1.2.0", content.Version)
+		assert.Equal(t, firmware.SHA256, content.SHA256)
+		assert.Equal(t, firmware.Size, content.Size)
+		assert.Equal(t, []string{}, content.Target)
+	})
+
+	t.Run("failure: an artifact without a manifest is not signed on read", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareTest()
+		artifact, err := entity.NewFirmwareArtifact("1.0.0", "", 3, []string{"model=X100"})
+		require.NoError(t, err)
+		require.NoError(t, tt.artifactRepo.Save(ctx, artifact))
+
+		_, err = tt.manifests.GetFirmwareManifest(ctx, artifact.ID)
+		require.ErrorIs(t, err, entity.ErrFirmwareManifestNotFound)
+
+		_, err = tt.artifactRepo.FindManifest(ctx, artifact.ID)
+		require.ErrorIs(t, err, entity.ErrFirmwareManifestNotFound)
+	})
+
+	t.Run("failure: the firmware does not exist", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareTest()
+
+		_, err := tt.manifests.GetFirmwareManifest(ctx, uuid.New())
+		require.ErrorIs(t, err, entity.ErrFirmwareArtifactNotFound)
+	})
+}
+
+// TestPrepareFirmwareSigningKey tests the PrepareFirmwareSigningKey method.
+func TestPrepareFirmwareSigningKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("success: a key is generated at startup and signs the existing artifacts", func(t *testing.T) {
+		t.Parallel()
+
+		keyRepo := NewFakeFirmwareSigningKeyRepository()
+		keyStore := NewFakeFirmwareKeyStore()
+		artifactRepo := NewFakeFirmwareArtifactRepository()
+		manifests := usecase.NewFirmwareManifestUsecase(
+			artifactRepo, keyRepo, keyStore, NewFakeAuditLogger(), FakeTransactor{}, usecase.FirmwareManifestConfig{
+				KeyAlgorithm: "",
+				GracePeriod:  0,
+			},
+		)
+
+		artifact, err := entity.NewFirmwareArtifact("1.0.0", "", 3, []string{"model=X100"})
+		require.NoError(t, err)
+		require.NoError(t, artifactRepo.Save(ctx, artifact))
+
+		// The GET paths do not generate a key.
+		keys, err := manifests.ListFirmwareSigningKeys(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+		assert.Equal(t, 0, keyStore.Len())
+
+		require.NoError(t, manifests.PrepareFirmwareSigningKey(ctx))
+
+		manifest, err := manifests.GetFirmwareManifest(ctx, artifact.ID)
+		require.NoError(t, err)
+
+		keys, err = manifests.ListFirmwareSigningKeys(ctx)
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+
+		content := verifyManifest(t, manifest, keys)
+		assert.Equal(t, 3, content.MinHardwareRevision)
+		assert.Equal(t, []string{"model=X100"}, content.Target)
+
+		// On the next startup, the active key is kept.
+		require.NoError(t, manifests.PrepareFirmwareSigningKey(ctx))
+
+		again, err := manifests.ListFirmwareSigningKeys(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, keys, again)
+		assert.Equal(t, 1, keyStore.Len())
+	})
+}
+
+// TestRotateFirmwareSigningKey tests the RotateFirmwareSigningKey method.
+func TestRotateFirmwareSigningKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("success: the manifests are re-signed and the old key stays verifiable", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareTest()
+		firmware := tt.upload(t, "1.2.0", "firmware image")
+
+		before, err := tt.manifests.GetFirmwareManifest(ctx, firmware.ID)
+		require.NoError(t, err)
+
+		gracePeriod := int64(3600)
+
+		key, err := tt.manifests.RotateFirmwareSigningKey(ctx, usecase.RotateFirmwareSigningKeyInput{
+			Algorithm:          "",
+			GracePeriodSeconds: &gracePeriod,
+		})
+		require.NoError(t, err)
+		assert.True(t, key.Active)
+		assert.NotEqual(t, before.KeyID, key.ID)
+		assert.Equal(t, 1, tt.keyStore.Len(), "the private half of the old key is deleted")
+
+		after, err := tt.manifests.GetFirmwareManifest(ctx, firmware.ID)
+		require.NoError(t, err)
+		assert.Equal(t, key.ID, after.KeyID)
+
+		keys, err := tt.manifests.ListFirmwareSigningKeys(ctx)
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+		assert.Equal(t, key.ID, keys[0].ID, "newest first")
+		assert.False(t, keys[1].Active)
+		require.NotNil(t, keys[1].ExpiresAt)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), *keys[1].ExpiresAt, 5*time.Second)
+
+		// A device that fetched the manifest before the rotation can still verify it.
+		verifyManifest(t, before, keys)
+		verifyManifest(t, after, keys)
+
+		logs := tt.auditLogger.Logs()
+		require.Len(t, logs, 3)
+		assert.Equal(t, entity.AuditFirmwareSigningKeyRotate, logs[1].Action)
+		assert.Equal(t, "firmware-signing-key/"+before.KeyID, auditDetails(t, logs[1]).Target)
+		assert.Equal(t, "firmware-signing-key/"+key.ID, auditDetails(t, logs[2]).Target)
+	})
+
+	t.Run("success: without a grace period, the old key is no longer listed", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareTest()
+		tt.upload(t, "1.2.0", "firmware image")
+
+		gracePeriod := int64(0)
+
+		key, err := tt.manifests.RotateFirmwareSigningKey(ctx, usecase.RotateFirmwareSigningKeyInput{
+			Algorithm:          service.FirmwareKeyEd25519,
+			GracePeriodSeconds: &gracePeriod,
+		})
+		require.NoError(t, err)
+
+		keys, err := tt.manifests.ListFirmwareSigningKeys(ctx)
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		assert.Equal(t, key.ID, keys[0].ID)
+	})
+
+	t.Run("failure: unsupported algorithm or out of range grace period", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareTest()
+		tt.upload(t, "1.2.0", "firmware image")
+
+		_, err := tt.manifests.RotateFirmwareSigningKey(ctx, usecase.RotateFirmwareSigningKeyInput{
+			Algorithm:          "RSA",
+			GracePeriodSeconds: nil,
+		})
+		require.ErrorIs(t, err, service.ErrUnsupportedKeyAlgorithm)
+
+		gracePeriod := int64(-1)
+
+		_, err = tt.manifests.RotateFirmwareSigningKey(ctx, usecase.RotateFirmwareSigningKeyInput{
+			Algorithm:          "",
+			GracePeriodSeconds: &gracePeriod,
+		})
+		require.ErrorIs(t, err, entity.ErrInvalidGracePeriod)
+
+		gracePeriod = math.MaxInt64
+
+		_, err = tt.manifests.RotateFirmwareSigningKey(ctx, usecase.RotateFirmwareSigningKeyInput{
+			Algorithm:          "",
+			GracePeriodSeconds: &gracePeriod,
+		})
+		require.ErrorIs(t, err, entity.ErrInvalidGracePeriod)
+
+		keys, err := tt.manifests.ListFirmwareSigningKeys(ctx)
+		require.NoError(t, err)
+		require.Len(t, keys, 1, "the active key is unchanged")
+		assert.Equal(t, 1, tt.keyStore.Len())
+	})
+}
@@ -0,0 +1,459 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/service"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// FakeFirmwareArtifactRepository is an in-memory implementation of the FirmwareArtifactRepository for testing.
+type FakeFirmwareArtifactRepository struct {
+	mu        sync.RWMutex
+	artifacts map[uuid.UUID]*entity.FirmwareArtifact
+	manifests map[uuid.UUID]*entity.FirmwareManifest
+	// inUse holds the artifacts that campaigns refer to, which cannot be deleted.
+	inUse map[uuid.UUID]bool
+}
+
+// NewFakeFirmwareArtifactRepository creates a new FakeFirmwareArtifactRepository.
+func NewFakeFirmwareArtifactRepository() *FakeFirmwareArtifactRepository {
+	return &FakeFirmwareArtifactRepository{
+		mu:        sync.RWMutex{},
+		artifacts: make(map[uuid.UUID]*entity.FirmwareArtifact),
+		manifests: make(map[uuid.UUID]*entity.FirmwareManifest),
+		inUse:     make(map[uuid.UUID]bool),
+	}
+}
+
+// Save adds an artifact to the in-memory store. Like the unique index, it rejects a version that is taken.
+func (r *FakeFirmwareArtifactRepository) Save(_ context.Context, artifact *entity.FirmwareArtifact) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, other := range r.artifacts {
+		if other.Version == artifact.Version {
+			return entity.ErrFirmwareVersionTaken
+		}
+	}
+
+	artifact.ID = uuid.New()
+	artifact.CreatedAt = time.Now()
+	stored := *artifact
+	r.artifacts[artifact.ID] = &stored
+
+	return nil
+}
+
+// FindByID retrieves an artifact by its ID from the in-memory store.
+func (r *FakeFirmwareArtifactRepository) FindByID(_ context.Context, id uuid.UUID) (*entity.FirmwareArtifact, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	artifact, ok := r.artifacts[id]
+	if !ok {
+		return nil, entity.ErrFirmwareArtifactNotFound
+	}
+
+	found := *artifact
+
+	return &found, nil
+}
+
+// FindAll retrieves all the artifacts from the in-memory store, newest first.
+func (r *FakeFirmwareArtifactRepository) FindAll(_ context.Context) ([]*entity.FirmwareArtifact, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	artifacts := make([]*entity.FirmwareArtifact, 0, len(r.artifacts))
+	for _, artifact := range r.artifacts {
+		found := *artifact
+		artifacts = append(artifacts, &found)
+	}
+
+	slices.SortFunc(artifacts, func(a, b *entity.FirmwareArtifact) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return artifacts, nil
+}
+
+// Delete removes an artifact from the in-memory store, unless a campaign refers to it.
+func (r *FakeFirmwareArtifactRepository) Delete(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.artifacts[id]; !ok {
+		return entity.ErrFirmwareArtifactNotFound
+	}
+
+	if r.inUse[id] {
+		return entity.ErrFirmwareArtifactInUse
+	}
+
+	delete(r.artifacts, id)
+	delete(r.manifests, id)
+
+	return nil
+}
+
+// SaveManifest stores the manifest of an artifact in place of the previous one.
+func (r *FakeFirmwareArtifactRepository) SaveManifest(_ context.Context, manifest *entity.FirmwareManifest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.artifacts[manifest.FirmwareID]; !ok {
+		return entity.ErrFirmwareArtifactNotFound
+	}
+
+	stored := *manifest
+	r.manifests[manifest.FirmwareID] = &stored
+
+	return nil
+}
+
+// FindManifest retrieves the manifest of an artifact from the in-memory store.
+func (r *FakeFirmwareArtifactRepository) FindManifest(
+	_ context.Context,
+	firmwareID uuid.UUID,
+) (*entity.FirmwareManifest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	manifest, ok := r.manifests[firmwareID]
+	if !ok {
+		return nil, entity.ErrFirmwareManifestNotFound
+	}
+
+	found := *manifest
+
+	return &found, nil
+}
+
+// FakeFirmwareStorage is an in-memory implementation of service.FirmwareStorage for testing.
+type FakeFirmwareStorage struct {
+	mu     sync.Mutex
+	images map[string][]byte
+}
+
+// NewFakeFirmwareStorage creates a new FakeFirmwareStorage.
+func NewFakeFirmwareStorage() *FakeFirmwareStorage {
+	return &FakeFirmwareStorage{mu: sync.Mutex{}, images: make(map[string][]byte)}
+}
+
+// Put reads the image into memory.
+func (s *FakeFirmwareStorage) Put(_ context.Context, r io.Reader) (*service.StoredFirmwareImage, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := uuid.NewString()
+	s.images[key] = data
+	sum := sha256.Sum256(data)
+
+	return &service.StoredFirmwareImage{Key: key, Size: int64(len(data)), SHA256: sum[:]}, nil
+}
+
+// Open returns a reader of the image stored under key.
+func (s *FakeFirmwareStorage) Open(_ context.Context, key string) (io.ReadSeekCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.images[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", service.ErrFirmwareImageNotFound, key)
+	}
+
+	return nopCloser{bytes.NewReader(data)}, nil
+}
+
+// Delete removes the image stored under key.
+func (s *FakeFirmwareStorage) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.images, key)
+
+	return nil
+}
+
+// Len returns the number of stored images.
+func (s *FakeFirmwareStorage) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.images)
+}
+
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// FakeFirmwareSigner is a fake implementation of service.FirmwareSigner for testing.
+// Its signature is the hash itself, reversed.
+type FakeFirmwareSigner struct {
+	// for controlling error case
+	SignErr error
+}
+
+// SignFirmware returns the reversed hash as the signature.
+func (s *FakeFirmwareSigner) SignFirmware(_ context.Context, digest []byte) (*service.FirmwareSignature, error) {
+	if s.SignErr != nil {
+		return nil, s.SignErr
+	}
+
+	signature := slices.Clone(digest)
+	slices.Reverse(signature)
+
+	return &service.FirmwareSignature{Algorithm: "TEST", Signature: signature}, nil
+}
+
+// firmwareTest holds a FirmwareUsecase, the FirmwareManifestUsecase signing its manifests, and their fakes.
+type firmwareTest struct {
+	uc           usecase.FirmwareUsecase
+	manifests    usecase.FirmwareManifestUsecase
+	artifactRepo *FakeFirmwareArtifactRepository
+	keyRepo      *FakeFirmwareSigningKeyRepository
+	keyStore     *FakeFirmwareKeyStore
+	storage      *FakeFirmwareStorage
+	signer       *FakeFirmwareSigner
+	auditLogger  *FakeAuditLogger
+}
+
+func newFirmwareTest() *firmwareTest {
+	tt := &firmwareTest{
+		uc:           nil,
+		manifests:    nil,
+		artifactRepo: NewFakeFirmwareArtifactRepository(),
+		keyRepo:      NewFakeFirmwareSigningKeyRepository(),
+		keyStore:     NewFakeFirmwareKeyStore(),
+		storage:      NewFakeFirmwareStorage(),
+		signer:       &FakeFirmwareSigner{}, //nolint:exhaustruct
+		auditLogger:  NewFakeAuditLogger(),
+	}
+	tt.build()
+
+	// The signing key is prepared as at startup. The fakes only fail on a broken random source.
+	err := tt.manifests.PrepareFirmwareSigningKey(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	return tt
+}
+
+// build creates the usecases from the fakes, e.g. once the audit logger has been replaced.
+func (tt *firmwareTest) build() {
+	tt.manifests = usecase.NewFirmwareManifestUsecase(
+		tt.artifactRepo, tt.keyRepo, tt.keyStore, tt.auditLogger, FakeTransactor{}, usecase.FirmwareManifestConfig{
+			KeyAlgorithm: "",
+			GracePeriod:  0,
+		},
+	)
+	tt.uc = usecase.NewFirmwareUsecase(
+		tt.artifactRepo, tt.storage, tt.signer, tt.manifests, tt.auditLogger, FakeTransactor{},
+	)
+}
+
+// upload uploads an image for the version and fails the test on error.
+func (tt *firmwareTest) upload(t *testing.T, version, image string) *usecase.FirmwareOutput {
+	t.Helper()
+
+	output, err := tt.uc.UploadFirmware(context.Background(), usecase.UploadFirmwareInput{
+		Version:             version,
+		Description:         "",
+		MinHardwareRevision: 0,
+		Target:              nil,
+		Image:               strings.NewReader(image),
+	})
+	require.NoError(t, err)
+
+	return output
+}
+
+// TestUploadFirmware tests the UploadFirmware method.
+func TestUploadFirmware(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("success: the image is stored, hashed, signed and audited", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareTest()
+
+		got, err := tt.uc.UploadFirmware(ctx, usecase.UploadFirmwareInput{
+			Version:             " 1.2.0 ",
+			Description:         "Fixes the sensor drift",
+			MinHardwareRevision: 2,
+			Target:              []string{"model=X100"},
+			Image:               strings.NewReader("firmware image"),
+		})
+		require.NoError(t, err)
+
+		sum := sha256.Sum256([]byte("firmware image"))
+		signature := slices.Clone(sum[:])
+		slices.Reverse(signature)
+
+		assert.Equal(t, "1.2.0", got.Version)
+		assert.Equal(t, int64(len("firmware image")), got.Size)
+		assert.Equal(t, hex.EncodeToString(sum[:]), got.SHA256)
+		assert.Equal(t, signature, got.Signature)
+		assert.Equal(t, "TEST", got.SignatureAlgorithm)
+		assert.Equal(t, 2, got.MinHardwareRevision)
+		assert.Equal(t, []string{"model=X100"}, got.Target)
+
+		manifest, err := tt.manifests.GetFirmwareManifest(ctx, got.ID)
+		require.NoError(t, err)
+		assert.Contains(t, string(manifest.Payload), `"sha256":"`+got.SHA256+`"`)
+
+		require.Equal(t, []entity.AuditAction{entity.AuditFirmwareUpload}, tt.auditLogger.Actions())
+		details := auditDetails(t, tt.auditLogger.Logs()[0])
+		assert.Equal(t, "firmware/"+got.ID.String(), details.Target)
+		assert.Equal(t, "1.2.0", details.After["version"])
+
+		image, err := tt.uc.OpenFirmwareImage(ctx, got.ID)
+		require.NoError(t, err)
+
+		data, err := io.ReadAll(image.Image)
+		require.NoError(t, err)
+		require.NoError(t, image.Image.Close())
+		assert.Equal(t, "firmware image", string(data))
+	})
+
+	t.Run("failure: the image is not kept when the version is taken", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareTest()
+		tt.upload(t, "1.2.0", "first image")
+
+		_, err := tt.uc.UploadFirmware(ctx, usecase.UploadFirmwareInput{
+			Version:             "1.2.0",
+			Description:         "",
+			MinHardwareRevision: 0,
+			Target:              nil,
+			Image:               strings.NewReader("second image"),
+		})
+		require.ErrorIs(t, err, entity.ErrFirmwareVersionTaken)
+		assert.Equal(t, 1, tt.storage.Len())
+		assert.Len(t, tt.auditLogger.Logs(), 1)
+	})
+
+	t.Run("failure: the image cannot be signed", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareTest()
+		tt.signer.SignErr = errors.New("key unavailable")
+
+		_, err := tt.uc.UploadFirmware(ctx, usecase.UploadFirmwareInput{
+			Version:             "1.2.0",
+			Description:         "",
+			MinHardwareRevision: 0,
+			Target:              nil,
+			Image:               strings.NewReader("firmware image"),
+		})
+		require.ErrorIs(t, err, usecase.ErrFirmwareSign)
+		assert.Equal(t, 0, tt.storage.Len())
+	})
+
+	t.Run("failure: invalid version, empty image or invalid target", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareTest()
+
+		_, err := tt.uc.UploadFirmware(ctx, usecase.UploadFirmwareInput{
+			Version:             " ",
+			Description:         "",
+			MinHardwareRevision: 0,
+			Target:              nil,
+			Image:               strings.NewReader("firmware image"),
+		})
+		require.ErrorIs(t, err, entity.ErrInvalidFirmwareVersion)
+
+		_, err = tt.uc.UploadFirmware(ctx, usecase.UploadFirmwareInput{
+			Version:             "1.2.0",
+			Description:         "",
+			MinHardwareRevision: 0,
+			Target:              nil,
+			Image:               strings.NewReader(""),
+		})
+		require.ErrorIs(t, err, usecase.ErrEmptyFirmwareImage)
+
+		_, err = tt.uc.UploadFirmware(ctx, usecase.UploadFirmwareInput{
+			Version:             "1.2.0",
+			Description:         "",
+			MinHardwareRevision: 0,
+			Target:              []string{"hardware..model=X100"},
+			Image:               strings.NewReader("firmware image"),
+		})
+		require.ErrorIs(t, err, usecase.ErrInvalidFirmwareTarget)
+		assert.Equal(t, 0, tt.storage.Len())
+		assert.Empty(t, tt.auditLogger.Logs())
+	})
+}
+
+// TestDeleteFirmware tests the DeleteFirmware method.
+func TestDeleteFirmware(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("success: the artifact and its image are deleted", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareTest()
+		firmware := tt.upload(t, "1.2.0", "firmware image")
+
+		require.NoError(t, tt.uc.DeleteFirmware(ctx, firmware.ID))
+		assert.Equal(t, 0, tt.storage.Len())
+
+		_, err := tt.uc.GetFirmware(ctx, firmware.ID)
+		require.ErrorIs(t, err, entity.ErrFirmwareArtifactNotFound)
+
+		assert.Equal(t,
+			[]entity.AuditAction{entity.AuditFirmwareUpload, entity.AuditFirmwareDelete}, tt.auditLogger.Actions())
+	})
+
+	t.Run("failure: a campaign rolls the artifact out", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareTest()
+		firmware := tt.upload(t, "1.2.0", "firmware image")
+		tt.artifactRepo.inUse[firmware.ID] = true
+
+		require.ErrorIs(t, tt.uc.DeleteFirmware(ctx, firmware.ID), entity.ErrFirmwareArtifactInUse)
+		assert.Equal(t, 1, tt.storage.Len())
+	})
+
+	t.Run("failure: the artifact does not exist", func(t *testing.T) {
+		t.Parallel()
+
+		tt := newFirmwareTest()
+
+		require.ErrorIs(t, tt.uc.DeleteFirmware(ctx, uuid.New()), entity.ErrFirmwareArtifactNotFound)
+	})
+}
@@ -0,0 +1,311 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+const (
+	// DefaultIngestionBatchSize is the number of readings written to the telemetry database at once.
+	DefaultIngestionBatchSize = 500
+	// DefaultIngestionFlushInterval is the longest time a reading waits in the queue before it is written.
+	DefaultIngestionFlushInterval = time.Second
+	// SensorDataTimestampField is the payload field a device can use to report when it took the reading.
+	SensorDataTimestampField = "timestamp"
+)
+
+// IngestionConfig holds the settings of the ingestion of sensor data.
+type IngestionConfig struct {
+	// BatchSize is the number of readings written at once. The queue holds up to twice as many readings.
+	BatchSize int
+	// FlushInterval is the longest time a reading waits in the queue before it is written.
+	FlushInterval time.Duration
+}
+
+// IngestionUsecase defines the interface for ingesting sensor data reported by devices, e.g. over MQTT.
+type IngestionUsecase interface {
+	// Ingest validates a reading reported by a device and queues it for writing.
+	// It blocks while the queue is full.
+	Ingest(ctx context.Context, input IngestSensorDataInput) error
+	// Run writes the queued readings in batches until ctx is done, and then writes the remaining readings.
+	Run(ctx context.Context)
+}
+
+// ingestionUsecase is the implementation of the IngestionUsecase interface.
+type ingestionUsecase struct {
+	deviceRepo repository.DeviceRepository
+	telemetry  TelemetryUsecase
+	presence   DevicePresenceUsecase
+	config     IngestionConfig
+	queue      chan RecordSensorDataInput
+	stopped    chan struct{}
+	now        func() time.Time
+
+	// mu guards lastReceivedAt, the time of receipt last given to a reading without a timestamp.
+	mu             sync.Mutex
+	lastReceivedAt time.Time
+}
+
+// NewIngestionUsecase creates a new instance of ingestionUsecase.
+//
+//nolint:ireturn
+func NewIngestionUsecase(
+	deviceRepo repository.DeviceRepository,
+	telemetry TelemetryUsecase,
+	presence DevicePresenceUsecase,
+	config IngestionConfig,
+) IngestionUsecase {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultIngestionBatchSize
+	}
+
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultIngestionFlushInterval
+	}
+
+	return &ingestionUsecase{
+		deviceRepo:     deviceRepo,
+		telemetry:      telemetry,
+		presence:       presence,
+		config:         config,
+		queue:          make(chan RecordSensorDataInput, 2*config.BatchSize), //nolint:mnd
+		stopped:        make(chan struct{}),
+		now:            time.Now,
+		mu:             sync.Mutex{},
+		lastReceivedAt: time.Time{},
+	}
+}
+
+// Ingest validates a reading reported by a device and queues it for writing.
+// Only ACTIVE devices can report, and the payload must be a JSON object.
+//
+// A gateway may report on behalf of its children. The errors about the gateway are the same as
+// for a device reporting itself, while a device that is not an ACTIVE child of the gateway is rejected
+// with ErrNotGatewayChild or ErrChildDeviceNotActive.
+//
+// An accepted reading is the activity of the reporter and of the device it is about, for their presence.
+// A reading without a timestamp is recorded at its time of receipt.
+func (uc *ingestionUsecase) Ingest(ctx context.Context, input IngestSensorDataInput) error {
+	reporterID := input.DeviceID
+	if input.GatewayID != uuid.Nil {
+		reporterID = input.GatewayID
+	}
+
+	reporter, err := findDevice(ctx, uc.deviceRepo, reporterID)
+	if err != nil {
+		return err
+	}
+
+	if reporter.Status != devicestatus.Active {
+		return fmt.Errorf("%w: %s", ErrDeviceNotActive, reporter.Status)
+	}
+
+	if input.GatewayID != uuid.Nil {
+		err = uc.checkChild(ctx, reporter, input.DeviceID)
+		if err != nil {
+			return err
+		}
+	}
+
+	reading, err := decodeSensorData(input)
+	if err != nil {
+		return err
+	}
+
+	if reading.RecordedAt.IsZero() {
+		reading.RecordedAt = uc.receivedAt()
+	}
+
+	uc.recordActivity(ctx, reporterID)
+
+	if input.GatewayID != uuid.Nil {
+		uc.recordActivity(ctx, input.DeviceID)
+	}
+
+	// The queue may still have room after Run has returned, so the stop is checked first.
+	select {
+	case <-uc.stopped:
+		return ErrIngestionStopped
+	default:
+	}
+
+	select {
+	case uc.queue <- reading:
+		return nil
+	case <-uc.stopped:
+		return ErrIngestionStopped
+	case <-ctx.Done():
+		return fmt.Errorf("failed to queue sensor data: %w", ctx.Err())
+	}
+}
+
+// checkChild checks that a gateway may report on behalf of a device: it must be an ACTIVE child of the gateway.
+func (uc *ingestionUsecase) checkChild(ctx context.Context, gateway *entity.Device, deviceID uuid.UUID) error {
+	child, err := findDevice(ctx, uc.deviceRepo, deviceID)
+	if err != nil {
+		if errors.Is(err, entity.ErrDeviceNotFound) {
+			return fmt.Errorf("%w: device %s does not exist", ErrNotGatewayChild, deviceID)
+		}
+
+		return err
+	}
+
+	if child.ParentID == nil || *child.ParentID != gateway.ID {
+		return fmt.Errorf("%w: device %s, gateway %s", ErrNotGatewayChild, deviceID, gateway.ID)
+	}
+
+	if child.Status != devicestatus.Active {
+		return fmt.Errorf("%w: device %s is %s", ErrChildDeviceNotActive, deviceID, child.Status)
+	}
+
+	return nil
+}
+
+// recordActivity records the activity of a device. A reading is not rejected if it cannot be recorded.
+func (uc *ingestionUsecase) recordActivity(ctx context.Context, deviceID uuid.UUID) {
+	err := uc.presence.RecordActivity(ctx, deviceID)
+	if err != nil {
+		log.Printf("failed to record the activity of device %s: %v", deviceID, err)
+	}
+}
+
+// Run writes the queued readings in batches until ctx is done, and then writes the remaining readings.
+// The readings are written when the batch is full or FlushInterval has passed, whichever comes first.
+func (uc *ingestionUsecase) Run(ctx context.Context) {
+	defer close(uc.stopped)
+
+	ticker := time.NewTicker(uc.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]RecordSensorDataInput, 0, uc.config.BatchSize)
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Write what has been accepted so far, even though ctx is already done.
+			uc.drain(context.WithoutCancel(ctx), batch)
+
+			return
+		case reading := <-uc.queue:
+			batch = append(batch, reading)
+			if len(batch) < uc.config.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+
+		uc.flush(ctx, batch)
+		batch = batch[:0]
+	}
+}
+
+// drain writes the given readings together with the readings left in the queue.
+func (uc *ingestionUsecase) drain(ctx context.Context, batch []RecordSensorDataInput) {
+	for {
+		select {
+		case reading := <-uc.queue:
+			batch = append(batch, reading)
+			if len(batch) < uc.config.BatchSize {
+				continue
+			}
+
+			uc.flush(ctx, batch)
+			batch = batch[:0]
+		default:
+			uc.flush(ctx, batch)
+
+			return
+		}
+	}
+}
+
+// flush writes a batch of readings.
+// If the batch cannot be written, the readings are written one by one, so that a single bad reading
+// does not drop the whole batch. The readings that still cannot be written are logged and dropped.
+func (uc *ingestionUsecase) flush(ctx context.Context, batch []RecordSensorDataInput) {
+	if len(batch) == 0 {
+		return
+	}
+
+	err := uc.telemetry.RecordSensorData(ctx, batch)
+	if err == nil {
+		return
+	}
+
+	log.Printf("failed to write %d sensor data readings, writing them one by one: %v", len(batch), err)
+
+	dropped := 0
+
+	for i := range batch {
+		err := uc.telemetry.RecordSensorData(ctx, batch[i:i+1])
+		if err != nil {
+			dropped++
+
+			log.Printf("dropped the sensor data reading of device %s recorded at %s: %v",
+				batch[i].DeviceID, batch[i].RecordedAt.Format(time.RFC3339Nano), err)
+		}
+	}
+
+	if dropped > 0 {
+		log.Printf("dropped %d of %d sensor data readings", dropped, len(batch))
+	}
+}
+
+// receivedAt returns the time of receipt of a reading without a timestamp. The times are strictly increasing
+// at the precision the database stores, so that readings received together are not dropped as duplicates.
+func (uc *ingestionUsecase) receivedAt() time.Time {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	now := uc.now().UTC().Truncate(time.Microsecond)
+	if !now.After(uc.lastReceivedAt) {
+		now = uc.lastReceivedAt.Add(time.Microsecond)
+	}
+
+	uc.lastReceivedAt = now
+
+	return now
+}
+
+// decodeSensorData decodes the JSON payload of a reading.
+// If the payload has a "timestamp" field in RFC 3339 format, it is used as the time of the reading.
+func decodeSensorData(input IngestSensorDataInput) (RecordSensorDataInput, error) {
+	var payload map[string]any
+
+	err := json.Unmarshal(input.Payload, &payload)
+	if err != nil || payload == nil {
+		return RecordSensorDataInput{}, fmt.Errorf("%w: must be a JSON object", ErrInvalidSensorDataPayload)
+	}
+
+	var recordedAt time.Time
+
+	timestamp, ok := payload[SensorDataTimestampField].(string)
+	if ok {
+		recordedAt, err = time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			return RecordSensorDataInput{}, fmt.Errorf(
+				"%w: %s must be an RFC 3339 timestamp", ErrInvalidSensorDataPayload, SensorDataTimestampField,
+			)
+		}
+	}
+
+	return RecordSensorDataInput{
+		DeviceID:   input.DeviceID,
+		RecordedAt: recordedAt,
+		Payload:    payload,
+	}, nil
+}
@@ -0,0 +1,346 @@
+package usecase_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/connectionstate"
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newIngestionTestDevice adds a device with the given status to the repository.
+func newIngestionTestDevice(repo *FakeDeviceRepository, status devicestatus.Status) *entity.Device {
+	device := newTestDevice("hw-ingest-"+uuid.NewString(), status)
+	device.Name = "Ingestion Device"
+	repo.devices[device.ID] = device
+
+	return device
+}
+
+// TestIngest tests the validation of the Ingest method.
+func TestIngest(t *testing.T) {
+	t.Parallel()
+
+	deviceRepo := NewFakeDeviceRepository()
+	active := newIngestionTestDevice(deviceRepo, devicestatus.Active)
+	suspended := newIngestionTestDevice(deviceRepo, devicestatus.Suspended)
+	unregistered := newIngestionTestDevice(deviceRepo, devicestatus.Unregistered)
+
+	tests := []struct {
+		name     string
+		desc     string
+		deviceID uuid.UUID
+		payload  string
+		wantErr  error
+	}{
+		{
+			name:     "success: active device",
+			desc:     "Verify that a JSON object reported by an ACTIVE device is accepted.",
+			deviceID: active.ID,
+			payload:  `{"temperature": 21.5}`,
+			wantErr:  nil,
+		},
+		{
+			name:     "success: with timestamp",
+			desc:     "Verify that a payload with an RFC 3339 timestamp is accepted.",
+			deviceID: active.ID,
+			payload:  `{"temperature": 21.5, "timestamp": "2025-01-02T03:04:05.123Z"}`,
+			wantErr:  nil,
+		},
+		{
+			name:     "failure: unknown device",
+			desc:     "Verify that a reading of an unknown device is rejected.",
+			deviceID: uuid.New(),
+			payload:  `{"temperature": 21.5}`,
+			wantErr:  entity.ErrDeviceNotFound,
+		},
+		{
+			name:     "failure: suspended device",
+			desc:     "Verify that a reading of a SUSPENDED device is rejected.",
+			deviceID: suspended.ID,
+			payload:  `{"temperature": 21.5}`,
+			wantErr:  usecase.ErrDeviceNotActive,
+		},
+		{
+			name:     "failure: unregistered device",
+			desc:     "Verify that a reading of a device that has not been provisioned is rejected.",
+			deviceID: unregistered.ID,
+			payload:  `{"temperature": 21.5}`,
+			wantErr:  usecase.ErrDeviceNotActive,
+		},
+		{
+			name:     "failure: not a JSON object",
+			desc:     "Verify that a payload that is not a JSON object is rejected.",
+			deviceID: active.ID,
+			payload:  `[21.5]`,
+			wantErr:  usecase.ErrInvalidSensorDataPayload,
+		},
+		{
+			name:     "failure: invalid timestamp",
+			desc:     "Verify that a payload with a timestamp that is not in RFC 3339 format is rejected.",
+			deviceID: active.ID,
+			payload:  `{"temperature": 21.5, "timestamp": "yesterday"}`,
+			wantErr:  usecase.ErrInvalidSensorDataPayload,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			telemetry := usecase.NewTelemetryUsecase(deviceRepo, NewFakeTelemetryRepository())
+			presence := newTestDevicePresenceUsecase(deviceRepo)
+			uc := usecase.NewIngestionUsecase(deviceRepo, telemetry, presence, usecase.IngestionConfig{
+				BatchSize:     1,
+				FlushInterval: time.Hour,
+			})
+
+			err := uc.Ingest(context.Background(), usecase.IngestSensorDataInput{
+				DeviceID:  tt.deviceID,
+				GatewayID: uuid.Nil,
+				Payload:   []byte(tt.payload),
+			})
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+// TestIngestFromGateway tests the Ingest method with readings a gateway reports on behalf of its children.
+func TestIngestFromGateway(t *testing.T) {
+	t.Parallel()
+
+	deviceRepo := NewFakeDeviceRepository()
+	gateway := newIngestionTestDevice(deviceRepo, devicestatus.Active)
+	suspendedGateway := newIngestionTestDevice(deviceRepo, devicestatus.Suspended)
+	child := newIngestionTestDevice(deviceRepo, devicestatus.Active)
+	suspendedChild := newIngestionTestDevice(deviceRepo, devicestatus.Suspended)
+	otherChild := newIngestionTestDevice(deviceRepo, devicestatus.Active)
+	stranger := newIngestionTestDevice(deviceRepo, devicestatus.Active)
+
+	child.ParentID = &gateway.ID
+	suspendedChild.ParentID = &gateway.ID
+	otherChild.ParentID = &suspendedGateway.ID
+
+	tests := []struct {
+		name      string
+		gatewayID uuid.UUID
+		deviceID  uuid.UUID
+		wantErr   error
+	}{
+		{name: "success: child", gatewayID: gateway.ID, deviceID: child.ID, wantErr: nil},
+		{name: "failure: device of another gateway", gatewayID: gateway.ID, deviceID: otherChild.ID,
+			wantErr: usecase.ErrNotGatewayChild},
+		{name: "failure: device without parent", gatewayID: gateway.ID, deviceID: stranger.ID,
+			wantErr: usecase.ErrNotGatewayChild},
+		{name: "failure: unknown device", gatewayID: gateway.ID, deviceID: uuid.New(),
+			wantErr: usecase.ErrNotGatewayChild},
+		{name: "failure: suspended child", gatewayID: gateway.ID, deviceID: suspendedChild.ID,
+			wantErr: usecase.ErrChildDeviceNotActive},
+		// The errors about the gateway are the same as for a device reporting itself.
+		{name: "failure: suspended gateway", gatewayID: suspendedGateway.ID, deviceID: otherChild.ID,
+			wantErr: usecase.ErrDeviceNotActive},
+		{name: "failure: unknown gateway", gatewayID: uuid.New(), deviceID: child.ID,
+			wantErr: entity.ErrDeviceNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			telemetryRepo := NewFakeTelemetryRepository()
+			uc := usecase.NewIngestionUsecase(
+				deviceRepo, usecase.NewTelemetryUsecase(deviceRepo, telemetryRepo),
+				newTestDevicePresenceUsecase(deviceRepo), usecase.IngestionConfig{
+					BatchSize:     1,
+					FlushInterval: time.Hour,
+				},
+			)
+
+			err := uc.Ingest(context.Background(), usecase.IngestSensorDataInput{
+				DeviceID:  tt.deviceID,
+				GatewayID: tt.gatewayID,
+				Payload:   []byte(`{"temperature": 21.5}`),
+			})
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			state, _, _ := deviceRepo.Presence(tt.deviceID)
+			assert.Equal(t, connectionstate.Connected, state, "the reading is the activity of the child")
+		})
+	}
+}
+
+// TestIngestionRun tests that the Run method writes the queued readings in batches.
+func TestIngestionRun(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	// startIngestion starts Run and returns a function that stops it and waits for it to return.
+	startIngestion := func(config usecase.IngestionConfig) (*FakeTelemetryRepository, usecase.IngestionUsecase,
+		*entity.Device, func(),
+	) {
+		deviceRepo := NewFakeDeviceRepository()
+		device := newIngestionTestDevice(deviceRepo, devicestatus.Active)
+		telemetryRepo := NewFakeTelemetryRepository()
+		uc := usecase.NewIngestionUsecase(
+			deviceRepo, usecase.NewTelemetryUsecase(deviceRepo, telemetryRepo),
+			newTestDevicePresenceUsecase(deviceRepo), config,
+		)
+
+		runCtx, cancel := context.WithCancel(ctx)
+
+		var wg sync.WaitGroup
+
+		wg.Go(func() { uc.Run(runCtx) })
+
+		return telemetryRepo, uc, device, func() {
+			cancel()
+			wg.Wait()
+		}
+	}
+
+	ingest := func(t *testing.T, uc usecase.IngestionUsecase, deviceID uuid.UUID, timestamp time.Time) {
+		t.Helper()
+
+		err := uc.Ingest(ctx, usecase.IngestSensorDataInput{
+			DeviceID:  deviceID,
+			GatewayID: uuid.Nil,
+			Payload:   []byte(`{"temperature": 21.5, "timestamp": "` + timestamp.Format(time.RFC3339Nano) + `"}`),
+		})
+		require.NoError(t, err)
+	}
+
+	now := time.Now()
+
+	t.Run("write when the batch is full", func(t *testing.T) {
+		t.Parallel()
+
+		telemetryRepo, uc, device, stop := startIngestion(usecase.IngestionConfig{
+			BatchSize:     2,
+			FlushInterval: time.Hour,
+		})
+		defer stop()
+
+		ingest(t, uc, device.ID, now)
+		ingest(t, uc, device.ID, now.Add(time.Second))
+
+		require.Eventually(t, func() bool {
+			return len(telemetryRepo.Data()) == 2
+		}, time.Second, 10*time.Millisecond)
+
+		data := telemetryRepo.Data()
+		assert.True(t, now.Truncate(time.Microsecond).Equal(data[0].RecordedAt), "the reported timestamp is used")
+		assert.Equal(t, map[string]float64{"temperature": 21.5}, data[0].Metrics)
+	})
+
+	t.Run("readings without a timestamp in one batch are all written", func(t *testing.T) {
+		t.Parallel()
+
+		telemetryRepo, uc, device, stop := startIngestion(usecase.IngestionConfig{
+			BatchSize:     2,
+			FlushInterval: time.Hour,
+		})
+		defer stop()
+
+		for range 2 {
+			err := uc.Ingest(ctx, usecase.IngestSensorDataInput{
+				DeviceID: device.ID, GatewayID: uuid.Nil, Payload: []byte(`{"temperature": 21.5}`),
+			})
+			require.NoError(t, err)
+		}
+
+		require.Eventually(t, func() bool {
+			return len(telemetryRepo.Data()) == 2
+		}, time.Second, 10*time.Millisecond)
+
+		data := telemetryRepo.Data()
+		assert.NotEqual(t, data[0].RecordedAt, data[1].RecordedAt, "each reading is stamped when it is received")
+	})
+
+	t.Run("write after the flush interval", func(t *testing.T) {
+		t.Parallel()
+
+		telemetryRepo, uc, device, stop := startIngestion(usecase.IngestionConfig{
+			BatchSize:     100,
+			FlushInterval: 20 * time.Millisecond,
+		})
+		defer stop()
+
+		ingest(t, uc, device.ID, now)
+
+		require.Eventually(t, func() bool {
+			return len(telemetryRepo.Data()) == 1
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("a bad reading does not drop the rest of the batch", func(t *testing.T) {
+		t.Parallel()
+
+		telemetryRepo, uc, device, stop := startIngestion(usecase.IngestionConfig{
+			BatchSize:     3,
+			FlushInterval: time.Hour,
+		})
+		defer stop()
+
+		bad := now.Add(time.Second)
+		telemetryRepo.RejectFunc = func(reading *entity.SensorData) bool {
+			return reading.RecordedAt.Equal(bad.Truncate(time.Microsecond))
+		}
+
+		ingest(t, uc, device.ID, now)
+		ingest(t, uc, device.ID, bad)
+		ingest(t, uc, device.ID, now.Add(2*time.Second))
+
+		require.Eventually(t, func() bool {
+			return len(telemetryRepo.Data()) == 2
+		}, time.Second, 10*time.Millisecond)
+
+		for _, reading := range telemetryRepo.Data() {
+			assert.False(t, reading.RecordedAt.Equal(bad.Truncate(time.Microsecond)))
+		}
+	})
+
+	t.Run("write the remaining readings on shutdown", func(t *testing.T) {
+		t.Parallel()
+
+		telemetryRepo, uc, device, stop := startIngestion(usecase.IngestionConfig{
+			BatchSize:     100,
+			FlushInterval: time.Hour,
+		})
+
+		for i := range 3 {
+			ingest(t, uc, device.ID, now.Add(time.Duration(i)*time.Second))
+		}
+
+		stop()
+		assert.Len(t, telemetryRepo.Data(), 3)
+
+		// Readings reported after shutdown are rejected instead of blocking.
+		err := uc.Ingest(ctx, usecase.IngestSensorDataInput{
+			DeviceID: device.ID, GatewayID: uuid.Nil, Payload: []byte(`{}`),
+		})
+		require.ErrorIs(t, err, usecase.ErrIngestionStopped)
+	})
+}
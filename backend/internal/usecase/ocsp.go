@@ -0,0 +1,209 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"backend/internal/domain/service"
+)
+
+const (
+	// DefaultOCSPValidity is the default interval between the thisUpdate and nextUpdate of an OCSP response.
+	DefaultOCSPValidity = time.Hour
+	// ocspCacheMaxEntries bounds the number of cached OCSP responses.
+	ocspCacheMaxEntries = 10000
+)
+
+// OCSPConfig configures the OCSP responses.
+type OCSPConfig struct {
+	// Validity is the interval between the thisUpdate and nextUpdate of a response.
+	// Responses are cached for half of it.
+	Validity time.Duration
+}
+
+// OCSPUsecase defines the interface for answering OCSP requests.
+type OCSPUsecase interface {
+	// Respond answers a DER-encoded OCSP request with a DER-encoded, signed OCSP response.
+	Respond(ctx context.Context, requestDER []byte) ([]byte, error)
+	// InvalidateOCSPCache drops the cached responses about a certificate, e.g. after it has been revoked.
+	InvalidateOCSPCache(serialNumber int64)
+}
+
+// ocspCacheEntry is a signed OCSP response that can be served until it expires.
+type ocspCacheEntry struct {
+	response  []byte
+	expiresAt time.Time
+}
+
+// ocspUsecase is the implementation of the OCSPUsecase interface.
+type ocspUsecase struct {
+	certRepo  repository.CertificateRepository
+	responder service.OCSPResponder
+	config    OCSPConfig
+	now       func() time.Time
+
+	mu sync.Mutex
+	// cache holds the responses to requests without a nonce, by serial number and then by CertID.
+	cache map[int64]map[string]ocspCacheEntry
+	// generation is incremented on every invalidation, so a response built before it is not cached.
+	generation uint64
+}
+
+// NewOCSPUsecase creates a new instance of ocspUsecase.
+// Zero values in config are replaced by the defaults.
+//
+//nolint:ireturn
+func NewOCSPUsecase(
+	certRepo repository.CertificateRepository,
+	responder service.OCSPResponder,
+	config OCSPConfig,
+) OCSPUsecase {
+	if config.Validity <= 0 {
+		config.Validity = DefaultOCSPValidity
+	}
+
+	return &ocspUsecase{
+		certRepo:   certRepo,
+		responder:  responder,
+		config:     config,
+		now:        time.Now,
+		mu:         sync.Mutex{},
+		cache:      make(map[int64]map[string]ocspCacheEntry),
+		generation: 0,
+	}
+}
+
+// Respond answers a DER-encoded OCSP request with a DER-encoded, signed OCSP response.
+// Responses to requests with a nonce are always freshly signed, as they must echo the nonce.
+func (uc *ocspUsecase) Respond(ctx context.Context, requestDER []byte) ([]byte, error) {
+	req, err := uc.responder.ParseOCSPRequest(requestDER)
+	if err != nil {
+		return nil, err
+	}
+
+	now := uc.now()
+	cacheable := req.Nonce == nil && req.SerialNumber.IsInt64()
+
+	if cacheable {
+		cached, ok := uc.cached(req, now)
+		if ok {
+			return cached, nil
+		}
+	}
+
+	generation := uc.currentGeneration()
+
+	response := service.OCSPResponse{
+		Request:    req,
+		Status:     service.OCSPUnknown,
+		RevokedAt:  time.Time{},
+		ReasonCode: 0,
+		ThisUpdate: now,
+		NextUpdate: now.Add(uc.config.Validity),
+	}
+
+	err = uc.lookupStatus(ctx, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := uc.responder.SignOCSPResponse(ctx, response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOCSPSign, err)
+	}
+
+	// An unknown certificate may be issued later, so only definitive answers are cached.
+	if cacheable && response.Status != service.OCSPUnknown {
+		uc.store(req, generation, ocspCacheEntry{
+			response:  der,
+			expiresAt: now.Add(uc.config.Validity / 2), //nolint:mnd
+		})
+	}
+
+	return der, nil
+}
+
+// InvalidateOCSPCache drops the cached responses about a certificate, e.g. after it has been revoked.
+func (uc *ocspUsecase) InvalidateOCSPCache(serialNumber int64) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	delete(uc.cache, serialNumber)
+	uc.generation++
+}
+
+// lookupStatus sets the status of the requested certificate in the response.
+func (uc *ocspUsecase) lookupStatus(ctx context.Context, response *service.OCSPResponse) error {
+	// Serial numbers issued by the platform always fit in an int64.
+	if !response.Request.SerialNumber.IsInt64() {
+		return nil
+	}
+
+	certificate, err := uc.certRepo.FindBySerialNumber(ctx, response.Request.SerialNumber.Int64())
+	if err != nil {
+		if isNotFound(err, entity.ErrCertificateNotFound) {
+			return nil
+		}
+
+		return fmt.Errorf("%w: %w", ErrDBFindCertificate, err)
+	}
+
+	if !certificate.IsRevoked {
+		response.Status = service.OCSPGood
+
+		return nil
+	}
+
+	revokedAt, reason := revocationDetails(certificate)
+	response.Status = service.OCSPRevoked
+	response.RevokedAt = revokedAt
+	response.ReasonCode = reason.Code()
+
+	return nil
+}
+
+// cached returns the cached response to the request, if it has not expired.
+func (uc *ocspUsecase) cached(req *service.OCSPRequest, now time.Time) ([]byte, bool) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	entry, ok := uc.cache[req.SerialNumber.Int64()][string(req.CertID)]
+	if !ok || !now.Before(entry.expiresAt) {
+		return nil, false
+	}
+
+	return entry.response, true
+}
+
+func (uc *ocspUsecase) currentGeneration() uint64 {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	return uc.generation
+}
+
+// store caches a response, unless the cache has been invalidated since the status was looked up.
+func (uc *ocspUsecase) store(req *service.OCSPRequest, generation uint64, entry ocspCacheEntry) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if generation != uc.generation {
+		return
+	}
+
+	if len(uc.cache) >= ocspCacheMaxEntries {
+		// Keep the cache bounded. Dropping everything is rare and only costs re-signing.
+		clear(uc.cache)
+	}
+
+	serialNumber := req.SerialNumber.Int64()
+	if uc.cache[serialNumber] == nil {
+		uc.cache[serialNumber] = make(map[string]ocspCacheEntry)
+	}
+
+	uc.cache[serialNumber][string(req.CertID)] = entry
+}
@@ -0,0 +1,289 @@
+package usecase_test
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/revocationreason"
+	"backend/internal/domain/service"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// FakeOCSPResponder is a fake implementation of service.OCSPResponder for testing.
+// A request is the decimal serial number, optionally followed by "/" and a nonce.
+type FakeOCSPResponder struct {
+	mu        sync.Mutex
+	responses []service.OCSPResponse
+	// for controlling error case
+	ParseErr error
+	SignErr  error
+}
+
+// NewFakeOCSPResponder creates a new FakeOCSPResponder.
+func NewFakeOCSPResponder() *FakeOCSPResponder {
+	return &FakeOCSPResponder{
+		mu:        sync.Mutex{},
+		responses: nil,
+		ParseErr:  nil,
+		SignErr:   nil,
+	}
+}
+
+// ParseOCSPRequest parses a fake request.
+func (r *FakeOCSPResponder) ParseOCSPRequest(der []byte) (*service.OCSPRequest, error) {
+	if r.ParseErr != nil {
+		return nil, r.ParseErr
+	}
+
+	serial, nonce, _ := strings.Cut(string(der), "/")
+
+	serialNumber, ok := new(big.Int).SetString(serial, 10)
+	if !ok {
+		return nil, service.ErrMalformedOCSPRequest
+	}
+
+	req := &service.OCSPRequest{SerialNumber: serialNumber, CertID: []byte("cert-id-" + serial), Nonce: nil}
+	if nonce != "" {
+		req.Nonce = []byte(nonce)
+	}
+
+	return req, nil
+}
+
+// SignOCSPResponse records the response and returns a fake DER encoding that is unique per call.
+func (r *FakeOCSPResponder) SignOCSPResponse(_ context.Context, response service.OCSPResponse) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.SignErr != nil {
+		return nil, r.SignErr
+	}
+
+	r.responses = append(r.responses, response)
+
+	return []byte(fmt.Sprintf("response-%d-status-%d", len(r.responses), response.Status)), nil
+}
+
+// Responses returns the signed responses.
+func (r *FakeOCSPResponder) Responses() []service.OCSPResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.responses)
+}
+
+// TestOCSPRespond tests the Respond method.
+func TestOCSPRespond(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		desc       string
+		request    string
+		parseErr   error
+		findErr    error
+		signErr    error
+		wantStatus service.OCSPCertStatus
+		wantErr    error
+	}{
+		{
+			name:       "success: good",
+			desc:       "Verify that a certificate that is not revoked is reported as good.",
+			request:    "1001",
+			parseErr:   nil,
+			findErr:    nil,
+			signErr:    nil,
+			wantStatus: service.OCSPGood,
+			wantErr:    nil,
+		},
+		{
+			name:       "success: revoked",
+			desc:       "Verify that a revoked certificate is reported with its revocation time and reason.",
+			request:    "1002",
+			parseErr:   nil,
+			findErr:    nil,
+			signErr:    nil,
+			wantStatus: service.OCSPRevoked,
+			wantErr:    nil,
+		},
+		{
+			name:       "success: unknown serial",
+			desc:       "Verify that a serial number that has not been issued is reported as unknown.",
+			request:    "9999",
+			parseErr:   nil,
+			findErr:    nil,
+			signErr:    nil,
+			wantStatus: service.OCSPUnknown,
+			wantErr:    nil,
+		},
+		{
+			name:       "success: serial out of range",
+			desc:       "Verify that a serial number that does not fit in an int64 is reported as unknown.",
+			request:    "123456789012345678901234567890",
+			parseErr:   nil,
+			findErr:    nil,
+			signErr:    nil,
+			wantStatus: service.OCSPUnknown,
+			wantErr:    nil,
+		},
+		{
+			name:       "failure: malformed request",
+			desc:       "Verify that a parse error is propagated.",
+			request:    "garbage",
+			parseErr:   service.ErrMalformedOCSPRequest,
+			findErr:    nil,
+			signErr:    nil,
+			wantStatus: 0,
+			wantErr:    service.ErrMalformedOCSPRequest,
+		},
+		{
+			name:       "failure: repository returns a generic error",
+			desc:       "Verify that an unexpected error from the repository is propagated.",
+			request:    "1001",
+			parseErr:   nil,
+			findErr:    assert.AnError,
+			signErr:    nil,
+			wantStatus: 0,
+			wantErr:    usecase.ErrDBFindCertificate,
+		},
+		{
+			name:       "failure: signing fails",
+			desc:       "Verify that a signing error is propagated.",
+			request:    "1001",
+			parseErr:   nil,
+			findErr:    nil,
+			signErr:    assert.AnError,
+			wantStatus: 0,
+			wantErr:    usecase.ErrOCSPSign,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			certRepo := NewFakeCertificateRepository()
+			require.NoError(t, certRepo.Save(ctx, newTestCertificate(t, uuid.New(), 1001)))
+
+			revoked := newTestCertificate(t, uuid.New(), 1002)
+			revokedAt := time.Now().Add(-time.Hour)
+			require.NoError(t, revoked.Revoke(revocationreason.KeyCompromise, revokedAt))
+			require.NoError(t, certRepo.Save(ctx, revoked))
+
+			certRepo.FindErr = tt.findErr
+			responder := NewFakeOCSPResponder()
+			responder.ParseErr = tt.parseErr
+			responder.SignErr = tt.signErr
+			uc := usecase.NewOCSPUsecase(certRepo, responder, usecase.OCSPConfig{Validity: time.Hour})
+
+			got, err := uc.Respond(ctx, []byte(tt.request))
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			responses := responder.Responses()
+			require.Len(t, responses, 1)
+			assert.Equal(t, tt.wantStatus, responses[0].Status)
+			assert.Equal(t, time.Hour, responses[0].NextUpdate.Sub(responses[0].ThisUpdate))
+
+			if tt.wantStatus == service.OCSPRevoked {
+				assert.True(t, revokedAt.Equal(responses[0].RevokedAt))
+				assert.Equal(t, revocationreason.KeyCompromise.Code(), responses[0].ReasonCode)
+			}
+		})
+	}
+}
+
+// TestOCSPCache tests the caching of OCSP responses.
+func TestOCSPCache(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	newUsecase := func(t *testing.T) (usecase.OCSPUsecase, *FakeOCSPResponder) {
+		t.Helper()
+
+		certRepo := NewFakeCertificateRepository()
+		require.NoError(t, certRepo.Save(ctx, newTestCertificate(t, uuid.New(), 1001)))
+
+		responder := NewFakeOCSPResponder()
+
+		return usecase.NewOCSPUsecase(certRepo, responder, usecase.OCSPConfig{Validity: time.Hour}), responder
+	}
+
+	t.Run("responses without a nonce are cached", func(t *testing.T) {
+		t.Parallel()
+
+		uc, responder := newUsecase(t)
+
+		first, err := uc.Respond(ctx, []byte("1001"))
+		require.NoError(t, err)
+
+		second, err := uc.Respond(ctx, []byte("1001"))
+		require.NoError(t, err)
+		assert.Equal(t, first, second)
+		assert.Len(t, responder.Responses(), 1)
+	})
+
+	t.Run("responses with a nonce are not cached", func(t *testing.T) {
+		t.Parallel()
+
+		uc, responder := newUsecase(t)
+
+		_, err := uc.Respond(ctx, []byte("1001/nonce-1"))
+		require.NoError(t, err)
+
+		_, err = uc.Respond(ctx, []byte("1001/nonce-2"))
+		require.NoError(t, err)
+
+		responses := responder.Responses()
+		require.Len(t, responses, 2)
+		assert.Equal(t, []byte("nonce-2"), responses[1].Request.Nonce)
+	})
+
+	t.Run("unknown responses are not cached", func(t *testing.T) {
+		t.Parallel()
+
+		uc, responder := newUsecase(t)
+
+		_, err := uc.Respond(ctx, []byte("9999"))
+		require.NoError(t, err)
+
+		_, err = uc.Respond(ctx, []byte("9999"))
+		require.NoError(t, err)
+		assert.Len(t, responder.Responses(), 2)
+	})
+
+	t.Run("invalidation drops the cached response", func(t *testing.T) {
+		t.Parallel()
+
+		uc, responder := newUsecase(t)
+
+		_, err := uc.Respond(ctx, []byte("1001"))
+		require.NoError(t, err)
+
+		uc.InvalidateOCSPCache(1001)
+
+		_, err = uc.Respond(ctx, []byte("1001"))
+		require.NoError(t, err)
+		assert.Len(t, responder.Responses(), 2)
+	})
+}
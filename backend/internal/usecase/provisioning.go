@@ -0,0 +1,202 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+	"backend/internal/domain/service"
+)
+
+// ProvisioningUsecase defines the interface for onboarding devices.
+type ProvisioningUsecase interface {
+	// Provision verifies the enrollment token, signs the CSR and activates the device.
+	Provision(ctx context.Context, input ProvisionInput) (*ProvisionOutput, error)
+}
+
+// provisioningUsecase is the implementation of the ProvisioningUsecase interface.
+type provisioningUsecase struct {
+	deviceRepo  repository.DeviceRepository
+	tokenRepo   repository.EnrollmentTokenRepository
+	certRepo    repository.CertificateRepository
+	signer      service.CertificateSigner
+	transactor  repository.Transactor
+	auditLogger repository.AuditLogger
+	now         func() time.Time
+}
+
+// NewProvisioningUsecase creates a new instance of provisioningUsecase.
+//
+//nolint:ireturn
+func NewProvisioningUsecase(
+	deviceRepo repository.DeviceRepository,
+	tokenRepo repository.EnrollmentTokenRepository,
+	certRepo repository.CertificateRepository,
+	signer service.CertificateSigner,
+	transactor repository.Transactor,
+	auditLogger repository.AuditLogger,
+) ProvisioningUsecase {
+	return &provisioningUsecase{
+		deviceRepo:  deviceRepo,
+		tokenRepo:   tokenRepo,
+		certRepo:    certRepo,
+		signer:      signer,
+		transactor:  transactor,
+		auditLogger: auditLogger,
+		now:         time.Now,
+	}
+}
+
+// Provision verifies the enrollment token, signs the CSR and activates the device.
+// A SUSPENDED device is rejected with ErrDeviceSuspended, and a REVOKED one with a *devicestatus.TransitionError.
+//
+// An unknown hardware ID, an unknown token and a token issued for another device are all reported
+// as ErrInvalidEnrollmentToken, so that the caller cannot probe which devices exist.
+func (uc *provisioningUsecase) Provision(ctx context.Context, input ProvisionInput) (*ProvisionOutput, error) {
+	device, err := uc.deviceRepo.FindByHardwareID(ctx, input.HardwareID)
+	if err != nil {
+		if isNotFound(err, entity.ErrDeviceNotFound) {
+			return nil, ErrInvalidEnrollmentToken
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrDBFindByHardwareID, err)
+	}
+
+	token, err := uc.tokenRepo.FindByTokenHash(ctx, entity.HashEnrollmentToken(input.EnrollmentToken))
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrInvalidEnrollmentToken
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrDBFindEnrollmentToken, err)
+	}
+
+	now := uc.now()
+
+	err = token.Verify(input.EnrollmentToken, device.ID, now)
+	if err != nil {
+		if errors.Is(err, entity.ErrEnrollmentTokenInvalid) {
+			return nil, ErrInvalidEnrollmentToken
+		}
+
+		return nil, err
+	}
+
+	tokenBefore := enrollmentTokenSnapshot(token, now)
+	deviceBefore := deviceSnapshot(device)
+
+	// A suspended device stays suspended until an operator resumes it, so it cannot enroll its way out.
+	if device.Status == devicestatus.Suspended {
+		return nil, ErrDeviceSuspended
+	}
+
+	// An already active device is re-enrolled and keeps its status.
+	activated := device.Status != devicestatus.Active
+	if activated {
+		err = device.Activate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	issued, err := uc.signer.SignCSR(ctx, service.CertificateSignRequest{
+		CSR:        []byte(input.CSR),
+		DeviceID:   device.ID,
+		HardwareID: device.HardwareID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign CSR: %w", err)
+	}
+
+	certificate := entity.NewCertificate(
+		device.ID,
+		issued.SerialNumber,
+		issued.Fingerprint,
+		issued.CertificatePEM,
+		issued.NotBefore,
+		issued.NotAfter,
+	)
+
+	usedToken := *token
+	usedToken.UsedAt = &now
+	auditEvents := []auditEvent{{
+		action:   entity.AuditTokenUse,
+		deviceID: device.ID,
+		target:   enrollmentTokenTarget(token.ID),
+		before:   tokenBefore,
+		after:    enrollmentTokenSnapshot(&usedToken, now),
+	}}
+
+	if activated {
+		auditEvents = append(auditEvents, auditEvent{
+			action:   entity.AuditDeviceStatusChange,
+			deviceID: device.ID,
+			target:   deviceTarget(device.ID),
+			before:   deviceBefore,
+			after:    deviceSnapshot(device),
+		})
+	}
+
+	auditEvents = append(auditEvents, auditEvent{
+		action:   entity.AuditCertificateIssue,
+		deviceID: device.ID,
+		target:   certificateTarget(certificate.SerialNumber),
+		before:   nil,
+		after:    certificateSnapshot(certificate),
+	})
+
+	// Consuming the token, activating the device and recording the certificate and the audit log entries
+	// succeed or fail together.
+	err = uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		err := uc.tokenRepo.MarkUsed(ctx, token.ID, now)
+		if err != nil {
+			return err
+		}
+
+		err = uc.deviceRepo.Save(ctx, device)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+
+		err = uc.certRepo.Save(ctx, certificate)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+		}
+
+		return recordAudit(ctx, uc.auditLogger, auditEvents...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProvisionOutput{
+		DeviceID:     device.ID,
+		SerialNumber: issued.SerialNumber,
+		Certificate:  issued.CertificatePEM,
+		CAChain:      uc.signer.CAChainPEM(),
+		NotBefore:    issued.NotBefore,
+		NotAfter:     issued.NotAfter,
+	}, nil
+}
+
+// isNotFound reports whether err means that a record does not exist.
+// GORM repositories return gorm.ErrRecordNotFound, while some methods return a domain-specific error instead.
+func isNotFound(err error, domainErrs ...error) bool {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true
+	}
+
+	for _, domainErr := range domainErrs {
+		if errors.Is(err, domainErr) {
+			return true
+		}
+	}
+
+	return false
+}
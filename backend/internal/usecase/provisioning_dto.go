@@ -0,0 +1,24 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProvisionInput is the input data for provisioning a device.
+type ProvisionInput struct {
+	HardwareID      string // Required
+	EnrollmentToken string // Required: the plaintext one-time token issued by an operator.
+	CSR             string // Required: PEM-encoded PKCS#10 certificate signing request.
+}
+
+// ProvisionOutput is the output data returned to a provisioned device.
+type ProvisionOutput struct {
+	DeviceID     uuid.UUID `json:"deviceId"`
+	SerialNumber int64     `json:"serialNumber,string"` // Encoded as a string, as it may exceed 2^53.
+	Certificate  string    `json:"certificate"`         // PEM-encoded client certificate
+	CAChain      string    `json:"caChain"`             // PEM-encoded CA certificate chain
+	NotBefore    time.Time `json:"notBefore"`
+	NotAfter     time.Time `json:"notAfter"`
+}
@@ -0,0 +1,448 @@
+package usecase_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/connectionstate"
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/domain/service"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// FakeEnrollmentTokenRepository is an in-memory implementation of the EnrollmentTokenRepository for testing.
+type FakeEnrollmentTokenRepository struct {
+	mu     sync.RWMutex
+	tokens map[uuid.UUID]*entity.EnrollmentToken
+	// for controlling error case
+	SaveErr     error
+	FindErr     error
+	MarkUsedErr error
+}
+
+// NewFakeEnrollmentTokenRepository creates a new FakeEnrollmentTokenRepository.
+func NewFakeEnrollmentTokenRepository() *FakeEnrollmentTokenRepository {
+	return &FakeEnrollmentTokenRepository{
+		mu:          sync.RWMutex{},
+		tokens:      make(map[uuid.UUID]*entity.EnrollmentToken),
+		SaveErr:     nil,
+		FindErr:     nil,
+		MarkUsedErr: nil,
+	}
+}
+
+// Save stores a token in the in-memory store.
+func (r *FakeEnrollmentTokenRepository) Save(_ context.Context, token *entity.EnrollmentToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.SaveErr != nil {
+		return r.SaveErr
+	}
+
+	if token.ID == uuid.Nil {
+		token.ID = uuid.New()
+	}
+
+	r.tokens[token.ID] = token
+
+	return nil
+}
+
+// FindByID retrieves a token by its ID from the in-memory store.
+func (r *FakeEnrollmentTokenRepository) FindByID(_ context.Context, id uuid.UUID) (*entity.EnrollmentToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.FindErr != nil {
+		return nil, r.FindErr
+	}
+
+	token, ok := r.tokens[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	copied := *token
+
+	return &copied, nil
+}
+
+// FindByDeviceID retrieves all tokens of a device from the in-memory store.
+func (r *FakeEnrollmentTokenRepository) FindByDeviceID(
+	_ context.Context,
+	deviceID uuid.UUID,
+) ([]*entity.EnrollmentToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.FindErr != nil {
+		return nil, r.FindErr
+	}
+
+	tokens := make([]*entity.EnrollmentToken, 0, len(r.tokens))
+
+	for _, token := range r.tokens {
+		if token.DeviceID == deviceID {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens, nil
+}
+
+// FindByTokenHash retrieves a token by the hash of its secret from the in-memory store.
+func (r *FakeEnrollmentTokenRepository) FindByTokenHash(
+	_ context.Context,
+	tokenHash string,
+) (*entity.EnrollmentToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.FindErr != nil {
+		return nil, r.FindErr
+	}
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+
+			return &copied, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+// MarkUsed marks an unused token as used in the in-memory store.
+func (r *FakeEnrollmentTokenRepository) MarkUsed(_ context.Context, id uuid.UUID, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.MarkUsedErr != nil {
+		return r.MarkUsedErr
+	}
+
+	token, ok := r.tokens[id]
+	if !ok || token.UsedAt != nil || token.RevokedAt != nil {
+		return entity.ErrEnrollmentTokenUsed
+	}
+
+	token.UsedAt = &usedAt
+
+	return nil
+}
+
+// Revoke marks an unused token as revoked in the in-memory store.
+func (r *FakeEnrollmentTokenRepository) Revoke(_ context.Context, id uuid.UUID, revokedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok || token.UsedAt != nil || token.RevokedAt != nil {
+		return entity.ErrEnrollmentTokenUsed
+	}
+
+	token.RevokedAt = &revokedAt
+
+	return nil
+}
+
+// FakeTransactor is a Transactor that simply runs the function without a transaction.
+type FakeTransactor struct{}
+
+// WithinTransaction runs fn with the given context.
+func (FakeTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// WithinSnapshot runs fn with the given context.
+func (FakeTransactor) WithinSnapshot(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// FakeCertificateSigner is a CertificateSigner that returns a fixed certificate for testing.
+type FakeCertificateSigner struct {
+	// for controlling error case
+	SignErr error
+}
+
+// SignCSR returns a fake certificate bound to the requested device.
+func (s *FakeCertificateSigner) SignCSR(
+	_ context.Context,
+	req service.CertificateSignRequest,
+) (*service.IssuedCertificate, error) {
+	if s.SignErr != nil {
+		return nil, s.SignErr
+	}
+
+	now := time.Now()
+
+	return &service.IssuedCertificate{
+		SerialNumber:   4242,
+		Fingerprint:    "fingerprint-" + req.DeviceID.String(),
+		CertificatePEM: "cert-pem-" + req.HardwareID,
+		NotBefore:      now,
+		NotAfter:       now.Add(time.Hour),
+	}, nil
+}
+
+// CAChainPEM returns a fake CA chain.
+func (s *FakeCertificateSigner) CAChainPEM() string {
+	return "ca-chain-pem"
+}
+
+// TestProvision tests the Provision method.
+func TestProvision(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	const (
+		hardwareID = "hw-provision-001"
+		secret     = "enrollment-secret"
+	)
+
+	usedAt := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name          string
+		desc          string
+		input         usecase.ProvisionInput
+		deviceStatus  devicestatus.Status
+		tokenSetup    func(*entity.EnrollmentToken)
+		repoSetup     func(*FakeEnrollmentTokenRepository, *FakeCertificateRepository, *FakeCertificateSigner)
+		wantErr       error
+		wantActivated bool
+	}{
+		{
+			name:          "success: provision an unregistered device",
+			desc:          "Verify that the certificate is issued, the token is consumed and the device is activated.",
+			input:         usecase.ProvisionInput{HardwareID: hardwareID, EnrollmentToken: secret, CSR: "csr"},
+			deviceStatus:  devicestatus.Unregistered,
+			tokenSetup:    nil,
+			repoSetup:     nil,
+			wantErr:       nil,
+			wantActivated: true,
+		},
+		{
+			name:          "success: re-enroll an active device",
+			desc:          "Verify that an ACTIVE device can obtain a new certificate with a new token.",
+			input:         usecase.ProvisionInput{HardwareID: hardwareID, EnrollmentToken: secret, CSR: "csr"},
+			deviceStatus:  devicestatus.Active,
+			tokenSetup:    nil,
+			repoSetup:     nil,
+			wantErr:       nil,
+			wantActivated: true,
+		},
+		{
+			name:          "failure: unknown hardware ID",
+			desc:          "Verify that an unknown hardware ID is reported as an invalid token.",
+			input:         usecase.ProvisionInput{HardwareID: "hw-unknown", EnrollmentToken: secret, CSR: "csr"},
+			deviceStatus:  devicestatus.Unregistered,
+			tokenSetup:    nil,
+			repoSetup:     nil,
+			wantErr:       usecase.ErrInvalidEnrollmentToken,
+			wantActivated: false,
+		},
+		{
+			name:          "failure: wrong token",
+			desc:          "Verify that an unknown token is rejected.",
+			input:         usecase.ProvisionInput{HardwareID: hardwareID, EnrollmentToken: "wrong", CSR: "csr"},
+			deviceStatus:  devicestatus.Unregistered,
+			tokenSetup:    nil,
+			repoSetup:     nil,
+			wantErr:       usecase.ErrInvalidEnrollmentToken,
+			wantActivated: false,
+		},
+		{
+			name:         "failure: token issued for another device",
+			desc:         "Verify that a token cannot be used to provision a different device.",
+			input:        usecase.ProvisionInput{HardwareID: hardwareID, EnrollmentToken: secret, CSR: "csr"},
+			deviceStatus: devicestatus.Unregistered,
+			tokenSetup: func(token *entity.EnrollmentToken) {
+				token.DeviceID = uuid.New()
+			},
+			repoSetup:     nil,
+			wantErr:       usecase.ErrInvalidEnrollmentToken,
+			wantActivated: false,
+		},
+		{
+			name:         "failure: expired token",
+			desc:         "Verify that an expired token is rejected.",
+			input:        usecase.ProvisionInput{HardwareID: hardwareID, EnrollmentToken: secret, CSR: "csr"},
+			deviceStatus: devicestatus.Unregistered,
+			tokenSetup: func(token *entity.EnrollmentToken) {
+				token.ExpiresAt = time.Now().Add(-time.Second)
+			},
+			repoSetup:     nil,
+			wantErr:       entity.ErrEnrollmentTokenExpired,
+			wantActivated: false,
+		},
+		{
+			name:         "failure: used token",
+			desc:         "Verify that a token that has already been used is rejected.",
+			input:        usecase.ProvisionInput{HardwareID: hardwareID, EnrollmentToken: secret, CSR: "csr"},
+			deviceStatus: devicestatus.Unregistered,
+			tokenSetup: func(token *entity.EnrollmentToken) {
+				token.UsedAt = &usedAt
+			},
+			repoSetup:     nil,
+			wantErr:       entity.ErrEnrollmentTokenUsed,
+			wantActivated: false,
+		},
+		{
+			name:          "failure: suspended device",
+			desc:          "Verify that enrolling again does not resume a SUSPENDED device.",
+			input:         usecase.ProvisionInput{HardwareID: hardwareID, EnrollmentToken: secret, CSR: "csr"},
+			deviceStatus:  devicestatus.Suspended,
+			tokenSetup:    nil,
+			repoSetup:     nil,
+			wantErr:       usecase.ErrDeviceSuspended,
+			wantActivated: false,
+		},
+		{
+			name:          "failure: revoked device",
+			desc:          "Verify that a REVOKED device cannot be provisioned again.",
+			input:         usecase.ProvisionInput{HardwareID: hardwareID, EnrollmentToken: secret, CSR: "csr"},
+			deviceStatus:  devicestatus.Revoked,
+			tokenSetup:    nil,
+			repoSetup:     nil,
+			wantErr:       devicestatus.ErrInvalidTransition,
+			wantActivated: false,
+		},
+		{
+			name:         "failure: invalid CSR",
+			desc:         "Verify that a CSR rejected by the signer is reported and the token is not consumed.",
+			input:        usecase.ProvisionInput{HardwareID: hardwareID, EnrollmentToken: secret, CSR: "csr"},
+			deviceStatus: devicestatus.Unregistered,
+			tokenSetup:   nil,
+			repoSetup: func(_ *FakeEnrollmentTokenRepository, _ *FakeCertificateRepository, s *FakeCertificateSigner) {
+				s.SignErr = service.ErrInvalidCSR
+			},
+			wantErr:       service.ErrInvalidCSR,
+			wantActivated: false,
+		},
+		{
+			name:         "failure: token consumed concurrently",
+			desc:         "Verify that losing the race to consume the token is reported as a used token.",
+			input:        usecase.ProvisionInput{HardwareID: hardwareID, EnrollmentToken: secret, CSR: "csr"},
+			deviceStatus: devicestatus.Unregistered,
+			tokenSetup:   nil,
+			repoSetup: func(r *FakeEnrollmentTokenRepository, _ *FakeCertificateRepository, _ *FakeCertificateSigner) {
+				r.MarkUsedErr = entity.ErrEnrollmentTokenUsed
+			},
+			wantErr:       entity.ErrEnrollmentTokenUsed,
+			wantActivated: false,
+		},
+		{
+			name:         "failure: repository returns error on certificate Save",
+			desc:         "Verify that an error from saving the certificate is propagated.",
+			input:        usecase.ProvisionInput{HardwareID: hardwareID, EnrollmentToken: secret, CSR: "csr"},
+			deviceStatus: devicestatus.Unregistered,
+			tokenSetup:   nil,
+			repoSetup: func(_ *FakeEnrollmentTokenRepository, r *FakeCertificateRepository, _ *FakeCertificateSigner) {
+				r.SaveErr = assert.AnError
+			},
+			wantErr:       usecase.ErrRepositorySave,
+			wantActivated: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			device := &entity.Device{
+				ID:              uuid.New(),
+				HardwareID:      hardwareID,
+				Name:            "Provisioning Device",
+				Status:          tt.deviceStatus,
+				Metadata:        nil,
+				Version:         1,
+				ParentID:        nil,
+				ConnectionState: connectionstate.Disconnected,
+				LastSeenAt:      nil,
+				LastIP:          "",
+				CreatedAt:       time.Now(),
+				UpdatedAt:       time.Now(),
+			}
+			token := &entity.EnrollmentToken{
+				ID:        uuid.New(),
+				DeviceID:  device.ID,
+				TokenHash: entity.HashEnrollmentToken(secret),
+				ExpiresAt: time.Now().Add(time.Hour),
+				UsedAt:    nil,
+				RevokedAt: nil,
+				CreatedAt: time.Now(),
+			}
+
+			if tt.tokenSetup != nil {
+				tt.tokenSetup(token)
+			}
+
+			deviceRepo := NewFakeDeviceRepository()
+			deviceRepo.devices[device.ID] = device
+			tokenRepo := NewFakeEnrollmentTokenRepository()
+			tokenRepo.tokens[token.ID] = token
+			certRepo := NewFakeCertificateRepository()
+			signer := &FakeCertificateSigner{SignErr: nil}
+
+			if tt.repoSetup != nil {
+				tt.repoSetup(tokenRepo, certRepo, signer)
+			}
+
+			auditLogger := NewFakeAuditLogger()
+			uc := usecase.NewProvisioningUsecase(deviceRepo, tokenRepo, certRepo, signer, FakeTransactor{}, auditLogger)
+
+			got, err := uc.Provision(ctx, tt.input)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+				require.Empty(t, certRepo.certificates)
+				require.Empty(t, auditLogger.Logs())
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.NotNil(t, got)
+			assert.Equal(t, device.ID, got.DeviceID)
+			assert.Equal(t, int64(4242), got.SerialNumber)
+			assert.Equal(t, "cert-pem-"+hardwareID, got.Certificate)
+			assert.Equal(t, "ca-chain-pem", got.CAChain)
+
+			// The device is activated, the token is consumed and the certificate is recorded.
+			savedDevice, findErr := deviceRepo.FindByID(ctx, device.ID)
+			require.NoError(t, findErr)
+			assert.Equal(t, tt.wantActivated, savedDevice.Status == devicestatus.Active)
+			assert.NotNil(t, tokenRepo.tokens[token.ID].UsedAt)
+			require.Contains(t, certRepo.certificates, got.SerialNumber)
+			assert.Equal(t, device.ID, certRepo.certificates[got.SerialNumber].DeviceID)
+
+			// The token use, the status change of a newly activated device and the issuance are recorded.
+			wantActions := []entity.AuditAction{entity.AuditTokenUse, entity.AuditCertificateIssue}
+			if tt.deviceStatus != devicestatus.Active {
+				wantActions = []entity.AuditAction{
+					entity.AuditTokenUse, entity.AuditDeviceStatusChange, entity.AuditCertificateIssue,
+				}
+			}
+
+			assert.Equal(t, wantActions, auditLogger.Actions())
+
+			for _, log := range auditLogger.Logs() {
+				assert.Equal(t, &device.ID, log.TargetDeviceID)
+				assert.Equal(t, entity.SystemActor, log.Actor)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"backend/internal/domain/entity"
+	"backend/internal/domain/repository"
+)
+
+const (
+	// DefaultSensorDataRange is the time range queried when the query does not specify a start.
+	DefaultSensorDataRange = 24 * time.Hour
+	// DefaultSensorDataLimit is the number of readings returned when the query does not specify a limit.
+	DefaultSensorDataLimit = 100
+	// MaxSensorDataLimit is the maximum number of readings a query can request.
+	MaxSensorDataLimit = 1000
+)
+
+// TelemetryUsecase defines the interface for storing and querying sensor data.
+type TelemetryUsecase interface {
+	// RecordSensorData stores readings of devices in one batch.
+	RecordSensorData(ctx context.Context, inputs []RecordSensorDataInput) error
+	// ListSensorData retrieves the readings of a device in a time range, newest first.
+	ListSensorData(ctx context.Context, input ListSensorDataInput) ([]*SensorDataOutput, error)
+}
+
+// telemetryUsecase is the implementation of the TelemetryUsecase interface.
+type telemetryUsecase struct {
+	deviceRepo    repository.DeviceRepository
+	telemetryRepo repository.TelemetryRepository
+	now           func() time.Time
+}
+
+// NewTelemetryUsecase creates a new instance of telemetryUsecase.
+//
+//nolint:ireturn
+func NewTelemetryUsecase(
+	deviceRepo repository.DeviceRepository,
+	telemetryRepo repository.TelemetryRepository,
+) TelemetryUsecase {
+	return &telemetryUsecase{
+		deviceRepo:    deviceRepo,
+		telemetryRepo: telemetryRepo,
+		now:           time.Now,
+	}
+}
+
+// RecordSensorData stores readings of devices in one batch.
+// The caller is responsible for checking that the devices are allowed to report.
+func (uc *telemetryUsecase) RecordSensorData(ctx context.Context, inputs []RecordSensorDataInput) error {
+	receivedAt := uc.now()
+	data := make([]*entity.SensorData, 0, len(inputs))
+
+	for _, input := range inputs {
+		reading, err := entity.NewSensorData(input.DeviceID, input.RecordedAt, receivedAt, input.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to create new sensor data entity: %w", err)
+		}
+
+		data = append(data, reading)
+	}
+
+	err := uc.telemetryRepo.SaveBatch(ctx, data)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRepositorySave, err)
+	}
+
+	return nil
+}
+
+// ListSensorData retrieves the readings of a device in a time range, newest first.
+func (uc *telemetryUsecase) ListSensorData(
+	ctx context.Context,
+	input ListSensorDataInput,
+) ([]*SensorDataOutput, error) {
+	to := uc.now()
+	if input.To != nil {
+		to = *input.To
+	}
+
+	from := to.Add(-DefaultSensorDataRange)
+	if input.From != nil {
+		from = *input.From
+	}
+
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidSensorDataQuery)
+	}
+
+	limit := input.Limit
+	if limit == 0 {
+		limit = DefaultSensorDataLimit
+	}
+
+	if limit < 0 || limit > MaxSensorDataLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidSensorDataQuery, MaxSensorDataLimit)
+	}
+
+	_, err := uc.deviceRepo.FindByID(ctx, input.DeviceID)
+	if err != nil {
+		if isNotFound(err, entity.ErrDeviceNotFound) {
+			return nil, entity.ErrDeviceNotFound
+		}
+
+		return nil, fmt.Errorf("%w: %w", ErrDBFindByID, err)
+	}
+
+	data, err := uc.telemetryRepo.FindByDeviceID(ctx, input.DeviceID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDBFindSensorData, err)
+	}
+
+	outputs := make([]*SensorDataOutput, 0, len(data))
+
+	for _, reading := range data {
+		outputs = append(outputs, NewSensorDataOutput(reading))
+	}
+
+	return outputs, nil
+}
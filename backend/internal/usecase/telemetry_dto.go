@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"backend/internal/domain/entity"
+)
+
+// RecordSensorDataInput is the input data for storing a reading of a device.
+type RecordSensorDataInput struct {
+	DeviceID   uuid.UUID
+	RecordedAt time.Time      // Optional: if zero, the time of receipt is used.
+	Payload    map[string]any // Required
+}
+
+// ListSensorDataInput is the input data for querying the readings of a device.
+type ListSensorDataInput struct {
+	DeviceID uuid.UUID
+	From     *time.Time // Optional: if nil, DefaultSensorDataRange before To is used.
+	To       *time.Time // Optional: if nil, the current time is used.
+	Limit    int        // Optional: if zero, DefaultSensorDataLimit is used.
+}
+
+// SensorDataOutput is the output data for displaying a SensorData.
+type SensorDataOutput struct {
+	DeviceID   uuid.UUID          `json:"deviceId"`
+	RecordedAt time.Time          `json:"recordedAt"`
+	ReceivedAt time.Time          `json:"receivedAt"`
+	Payload    map[string]any     `json:"payload"`
+	Metrics    map[string]float64 `json:"metrics,omitempty"`
+}
+
+// NewSensorDataOutput creates a new SensorDataOutput from an entity.
+func NewSensorDataOutput(data *entity.SensorData) *SensorDataOutput {
+	return &SensorDataOutput{
+		DeviceID:   data.DeviceID,
+		RecordedAt: data.RecordedAt,
+		ReceivedAt: data.ReceivedAt,
+		Payload:    data.Payload,
+		Metrics:    data.Metrics,
+	}
+}
+
+// IngestSensorDataInput is the input data for a reading reported by a device.
+type IngestSensorDataInput struct {
+	// DeviceID is the device that took the reading.
+	DeviceID uuid.UUID
+	// GatewayID is the parent that reported the reading on behalf of DeviceID.
+	// Optional: uuid.Nil if the device reported the reading itself.
+	GatewayID uuid.UUID
+	Payload   []byte // JSON object
+}
@@ -0,0 +1,268 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/domain/VO/connectionstate"
+	"backend/internal/domain/VO/devicestatus"
+	"backend/internal/domain/entity"
+	"backend/internal/usecase"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// FakeTelemetryRepository is an in-memory implementation of the TelemetryRepository for testing.
+type FakeTelemetryRepository struct {
+	mu   sync.RWMutex
+	data []*entity.SensorData
+	// for controlling error case
+	SaveErr error
+	FindErr error
+	// RejectFunc fails every batch that contains a reading it returns true for, like a constraint violation.
+	RejectFunc func(reading *entity.SensorData) bool
+}
+
+// NewFakeTelemetryRepository creates a new FakeTelemetryRepository.
+func NewFakeTelemetryRepository() *FakeTelemetryRepository {
+	return &FakeTelemetryRepository{
+		mu:         sync.RWMutex{},
+		data:       nil,
+		SaveErr:    nil,
+		FindErr:    nil,
+		RejectFunc: nil,
+	}
+}
+
+// SaveBatch adds readings to the in-memory store, skipping duplicates.
+func (r *FakeTelemetryRepository) SaveBatch(_ context.Context, data []*entity.SensorData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.SaveErr != nil {
+		return r.SaveErr
+	}
+
+	if r.RejectFunc != nil && slices.ContainsFunc(data, r.RejectFunc) {
+		return assert.AnError
+	}
+
+	for _, reading := range data {
+		duplicate := slices.ContainsFunc(r.data, func(stored *entity.SensorData) bool {
+			return stored.DeviceID == reading.DeviceID && stored.RecordedAt.Equal(reading.RecordedAt)
+		})
+		if !duplicate {
+			r.data = append(r.data, reading)
+		}
+	}
+
+	return nil
+}
+
+// FindByDeviceID retrieves up to limit readings of a device recorded in [from, to), newest first.
+func (r *FakeTelemetryRepository) FindByDeviceID(
+	_ context.Context,
+	deviceID uuid.UUID,
+	from, to time.Time,
+	limit int,
+) ([]*entity.SensorData, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.FindErr != nil {
+		return nil, r.FindErr
+	}
+
+	data := make([]*entity.SensorData, 0)
+
+	for _, reading := range r.data {
+		if reading.DeviceID == deviceID && !reading.RecordedAt.Before(from) && reading.RecordedAt.Before(to) {
+			data = append(data, reading)
+		}
+	}
+
+	slices.SortFunc(data, func(a, b *entity.SensorData) int {
+		return b.RecordedAt.Compare(a.RecordedAt)
+	})
+
+	return data[:min(limit, len(data))], nil
+}
+
+// Data returns a copy of the stored readings.
+func (r *FakeTelemetryRepository) Data() []*entity.SensorData {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.data)
+}
+
+// TestRecordSensorData tests the RecordSensorData method.
+func TestRecordSensorData(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	recordedAt := time.Now().Add(-time.Minute)
+
+	t.Run("success: store a batch of readings", func(t *testing.T) {
+		t.Parallel()
+
+		telemetryRepo := NewFakeTelemetryRepository()
+		uc := usecase.NewTelemetryUsecase(NewFakeDeviceRepository(), telemetryRepo)
+		deviceID := uuid.New()
+
+		err := uc.RecordSensorData(ctx, []usecase.RecordSensorDataInput{
+			{DeviceID: deviceID, RecordedAt: recordedAt, Payload: map[string]any{"temperature": 21.5}},
+			{DeviceID: deviceID, RecordedAt: time.Time{}, Payload: map[string]any{"status": "ok"}},
+		})
+		require.NoError(t, err)
+
+		data := telemetryRepo.Data()
+		require.Len(t, data, 2)
+		assert.True(t, recordedAt.Truncate(time.Microsecond).Equal(data[0].RecordedAt))
+		assert.Equal(t, map[string]float64{"temperature": 21.5}, data[0].Metrics)
+		assert.Equal(t, data[1].ReceivedAt, data[1].RecordedAt, "a missing timestamp falls back to the time of receipt")
+	})
+
+	t.Run("failure: empty device ID", func(t *testing.T) {
+		t.Parallel()
+
+		telemetryRepo := NewFakeTelemetryRepository()
+		uc := usecase.NewTelemetryUsecase(NewFakeDeviceRepository(), telemetryRepo)
+
+		err := uc.RecordSensorData(ctx, []usecase.RecordSensorDataInput{
+			{DeviceID: uuid.New(), RecordedAt: recordedAt, Payload: nil},
+			{DeviceID: uuid.Nil, RecordedAt: recordedAt, Payload: nil},
+		})
+		require.ErrorIs(t, err, entity.ErrSensorDataDeviceIDEmpty)
+		assert.Empty(t, telemetryRepo.Data(), "no reading of the batch is stored")
+	})
+
+	t.Run("failure: repository error", func(t *testing.T) {
+		t.Parallel()
+
+		telemetryRepo := NewFakeTelemetryRepository()
+		telemetryRepo.SaveErr = errors.New("db error")
+		uc := usecase.NewTelemetryUsecase(NewFakeDeviceRepository(), telemetryRepo)
+
+		err := uc.RecordSensorData(ctx, []usecase.RecordSensorDataInput{
+			{DeviceID: uuid.New(), RecordedAt: recordedAt, Payload: nil},
+		})
+		require.ErrorIs(t, err, usecase.ErrRepositorySave)
+	})
+}
+
+// TestListSensorData tests the ListSensorData method.
+func TestListSensorData(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	device := &entity.Device{
+		ID:              uuid.New(),
+		HardwareID:      "hw-telemetry-001",
+		Name:            "Telemetry Device",
+		Status:          devicestatus.Active,
+		Metadata:        nil,
+		Version:         1,
+		ParentID:        nil,
+		ConnectionState: connectionstate.Disconnected,
+		LastSeenAt:      nil,
+		LastIP:          "",
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	deviceRepo := NewFakeDeviceRepository()
+	deviceRepo.devices[device.ID] = device
+	telemetryRepo := NewFakeTelemetryRepository()
+	uc := usecase.NewTelemetryUsecase(deviceRepo, telemetryRepo)
+
+	inputs := make([]usecase.RecordSensorDataInput, 0)
+	for _, age := range []time.Duration{time.Minute, time.Hour, 2 * time.Hour, 48 * time.Hour} {
+		inputs = append(inputs, usecase.RecordSensorDataInput{
+			DeviceID:   device.ID,
+			RecordedAt: now.Add(-age),
+			Payload:    map[string]any{"ageSeconds": age.Seconds()},
+		})
+	}
+
+	require.NoError(t, uc.RecordSensorData(ctx, inputs))
+
+	from := now.Add(-3 * time.Hour)
+	to := now.Add(-30 * time.Minute)
+
+	tests := []struct {
+		name    string
+		desc    string
+		input   usecase.ListSensorDataInput
+		want    []float64 // ageSeconds of the returned readings
+		wantErr error
+	}{
+		{
+			name:    "success: default range",
+			desc:    "Verify that the readings of the last 24 hours are returned, newest first.",
+			input:   usecase.ListSensorDataInput{DeviceID: device.ID, From: nil, To: nil, Limit: 0},
+			want:    []float64{60, 3600, 7200},
+			wantErr: nil,
+		},
+		{
+			name:    "success: explicit range and limit",
+			desc:    "Verify that the range and the limit are applied.",
+			input:   usecase.ListSensorDataInput{DeviceID: device.ID, From: &from, To: &to, Limit: 1},
+			want:    []float64{3600},
+			wantErr: nil,
+		},
+		{
+			name:    "failure: empty range",
+			desc:    "Verify that a range whose start is not before its end is rejected.",
+			input:   usecase.ListSensorDataInput{DeviceID: device.ID, From: &to, To: &from, Limit: 0},
+			want:    nil,
+			wantErr: usecase.ErrInvalidSensorDataQuery,
+		},
+		{
+			name:    "failure: limit too large",
+			desc:    "Verify that a limit above the maximum is rejected.",
+			input:   usecase.ListSensorDataInput{DeviceID: device.ID, From: nil, To: nil, Limit: 1001},
+			want:    nil,
+			wantErr: usecase.ErrInvalidSensorDataQuery,
+		},
+		{
+			name:    "failure: device not found",
+			desc:    "Verify that ErrDeviceNotFound is returned for an unknown device.",
+			input:   usecase.ListSensorDataInput{DeviceID: uuid.New(), From: nil, To: nil, Limit: 0},
+			want:    nil,
+			wantErr: entity.ErrDeviceNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := uc.ListSensorData(ctx, tt.input)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			ages := make([]float64, 0, len(got))
+			for _, output := range got {
+				assert.Equal(t, device.ID, output.DeviceID)
+				ages = append(ages, output.Metrics["ageSeconds"])
+			}
+
+			assert.Equal(t, tt.want, ages)
+		})
+	}
+}
@@ -0,0 +1,2 @@
+-- プロビジョニング時にトークンのハッシュで検索するためのインデックス
+CREATE UNIQUE INDEX IF NOT EXISTS idx_enrollment_tokens_token_hash ON enrollment_tokens(token_hash);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_enrollment_tokens_device_id;
+ALTER TABLE enrollment_tokens DROP COLUMN IF EXISTS revoked_at;
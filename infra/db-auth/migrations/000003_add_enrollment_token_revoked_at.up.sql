@@ -0,0 +1,3 @@
+-- オペレーターによるエンロールメントトークンの失効日時
+ALTER TABLE enrollment_tokens ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_enrollment_tokens_device_id ON enrollment_tokens(device_id);
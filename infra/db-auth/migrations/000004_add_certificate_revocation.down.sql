@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_certs_revoked;
+ALTER TABLE certificates DROP COLUMN IF EXISTS revocation_reason;
+ALTER TABLE certificates DROP COLUMN IF EXISTS revoked_at;
@@ -0,0 +1,5 @@
+-- 証明書の失効日時と失効理由（RFC 5280 CRLReasonコード）
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE certificates ADD COLUMN IF NOT EXISTS revocation_reason SMALLINT;
+-- CRL生成時に失効済み証明書を検索するためのインデックス
+CREATE INDEX IF NOT EXISTS idx_certs_revoked ON certificates(valid_to) WHERE is_revoked;
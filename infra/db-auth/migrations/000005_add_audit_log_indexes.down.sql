@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_audit_logs_created_at;
+DROP INDEX IF EXISTS idx_audit_logs_actor;
+DROP INDEX IF EXISTS idx_audit_logs_action;
+DROP INDEX IF EXISTS idx_audit_logs_target_device_id;
+-- 削除済みデバイスを指すログは外部キー制約を戻す前に切り離す
+UPDATE audit_logs SET target_device_id = NULL
+WHERE target_device_id IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM devices WHERE devices.id = audit_logs.target_device_id);
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_target_device_id_fkey
+    FOREIGN KEY (target_device_id) REFERENCES devices(id) ON DELETE SET NULL;
@@ -0,0 +1,7 @@
+-- デバイス削除後も監査ログに対象デバイスIDを残すため、外部キー制約を外す
+ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS audit_logs_target_device_id_fkey;
+-- 監査ログ検索用のインデックス
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target_device_id ON audit_logs(target_device_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs(action, id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor ON audit_logs(actor, id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
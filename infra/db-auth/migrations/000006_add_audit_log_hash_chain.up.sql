@@ -0,0 +1,6 @@
+-- 監査ログの改ざん検知用ハッシュチェーン
+-- prev_hash: 直前のエントリのハッシュ、hash: 本エントリの内容のSHA-256、signature: hashのHMAC-SHA256（鍵が設定されている場合のみ）
+-- 既存のエントリはNULLのまま残り、検証ではチェーン導入前のエントリとして扱われる
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS prev_hash CHAR(64);
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS hash CHAR(64);
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS signature CHAR(64);
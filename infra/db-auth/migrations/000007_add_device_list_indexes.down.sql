@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_devices_hardware_id_pattern;
+DROP INDEX IF EXISTS idx_devices_name;
+DROP INDEX IF EXISTS idx_devices_updated_at;
+DROP INDEX IF EXISTS idx_devices_created_at;
@@ -0,0 +1,6 @@
+-- デバイス一覧のソート・カーソルページング用のインデックス
+CREATE INDEX IF NOT EXISTS idx_devices_created_at ON devices(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_devices_updated_at ON devices(updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_devices_name ON devices(name, id);
+-- ハードウェアIDの前方一致検索用のインデックス（照合順序に依存せずLIKEで使用できる）
+CREATE INDEX IF NOT EXISTS idx_devices_hardware_id_pattern ON devices(hardware_id varchar_pattern_ops);
@@ -0,0 +1,2 @@
+-- メタデータ検索（@>演算子・?演算子）用のGINインデックス
+CREATE INDEX IF NOT EXISTS idx_devices_metadata ON devices USING GIN (metadata);
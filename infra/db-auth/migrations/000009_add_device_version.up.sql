@@ -0,0 +1,2 @@
+-- 楽観的排他制御用のバージョン（更新のたびに1ずつ増加し、ETagとして返す）
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS device_group_members;
+DROP TABLE IF EXISTS device_groups;
@@ -0,0 +1,20 @@
+-- デバイスグループ（サイトや製品ラインなど、デバイスをまとめて操作するための単位）
+CREATE TABLE IF NOT EXISTS device_groups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    rule JSONB NOT NULL DEFAULT '[]', -- 動的メンバーシップの条件（メタデータフィルタの配列、すべてを満たすデバイスがメンバー）
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_device_groups_name ON device_groups(name);
+
+-- 静的メンバーシップ（グループに明示的に追加されたデバイス）
+CREATE TABLE IF NOT EXISTS device_group_members (
+    group_id UUID NOT NULL CONSTRAINT fk_device_group_members_group REFERENCES device_groups(id) ON DELETE CASCADE,
+    device_id UUID NOT NULL CONSTRAINT fk_device_group_members_device REFERENCES devices(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, device_id)
+);
+-- デバイス削除時のカスケードと、デバイスの所属グループ検索のためのインデックス
+CREATE INDEX IF NOT EXISTS idx_device_group_members_device_id ON device_group_members(device_id);
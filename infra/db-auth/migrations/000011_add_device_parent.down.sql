@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_devices_parent_id;
+ALTER TABLE devices DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,6 @@
+-- 親デバイス（子デバイスの代わりにmTLSで接続し、テレメトリを送信するゲートウェイ）
+-- 親の削除時はアプリケーションが子を切り離すが、念のためNULLに戻す
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS parent_id UUID
+    CONSTRAINT fk_devices_parent REFERENCES devices(id) ON DELETE SET NULL;
+-- 子デバイスの一覧と、親の削除時の参照チェックのためのインデックス
+CREATE INDEX IF NOT EXISTS idx_devices_parent_id ON devices(parent_id);
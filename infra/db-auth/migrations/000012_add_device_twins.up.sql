@@ -0,0 +1,11 @@
+-- デバイスツイン（プラットフォームが適用させたい設定 desired と、デバイスが適用済みと報告した設定 reported）
+-- それぞれのバージョンは変更のたびに1ずつ増え、条件付き更新による楽観的排他制御に使う
+CREATE TABLE IF NOT EXISTS device_twins (
+    device_id UUID PRIMARY KEY CONSTRAINT fk_device_twins_device REFERENCES devices(id) ON DELETE CASCADE,
+    desired JSONB NOT NULL DEFAULT '{}',
+    desired_version BIGINT NOT NULL DEFAULT 0,
+    desired_updated_at TIMESTAMP WITH TIME ZONE,
+    reported JSONB NOT NULL DEFAULT '{}',
+    reported_version BIGINT NOT NULL DEFAULT 0,
+    reported_updated_at TIMESTAMP WITH TIME ZONE
+);
@@ -0,0 +1,19 @@
+-- デバイスへのコマンド（再起動や証明書のローテーションなど、MQTTで配信してデバイスの応答を待つ指示）
+CREATE TABLE IF NOT EXISTS device_commands (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    device_id UUID NOT NULL CONSTRAINT fk_device_commands_device REFERENCES devices(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'QUEUED', -- QUEUED, SENT, ACKED, FAILED, EXPIRED
+    result JSONB, -- ACKED時にデバイスが返した結果
+    failure_reason TEXT NOT NULL DEFAULT '', -- FAILED時にデバイスが返した理由
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+-- デバイス削除時のカスケードのためのインデックス
+CREATE INDEX IF NOT EXISTS idx_device_commands_device_id ON device_commands(device_id);
+-- 未配信コマンドの再送と、期限切れコマンドの失効のための部分インデックス
+CREATE INDEX IF NOT EXISTS idx_device_commands_pending ON device_commands(expires_at)
+    WHERE status IN ('QUEUED', 'SENT');
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS firmware_updates;
+DROP TABLE IF EXISTS firmware_campaigns;
+DROP TABLE IF EXISTS firmware_artifacts;
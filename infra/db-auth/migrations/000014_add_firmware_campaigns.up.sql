@@ -0,0 +1,59 @@
+-- ファームウェアレジストリ（イメージ本体はファームウェアストレージに置き、ハッシュとCA鍵による署名を記録する）
+CREATE TABLE IF NOT EXISTS firmware_artifacts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    version VARCHAR(64) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL, -- イメージのSHA-256ハッシュ（16進数）
+    signature BYTEA NOT NULL, -- SHA-256ハッシュへの署名
+    signature_algorithm VARCHAR(32) NOT NULL, -- ECDSA-SHA256, RSA-PKCS1v15-SHA256, Ed25519
+    storage_key TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+-- バージョンはデバイスがメタデータ firmware.version で報告する値で、一意とする
+CREATE UNIQUE INDEX IF NOT EXISTS idx_firmware_artifacts_version ON firmware_artifacts(version);
+
+-- ファームウェアのロールアウトキャンペーン（フィルタに一致するデバイスをウェーブに分けて更新する）
+-- バージョンは変更のたびに1ずつ増え、条件付き更新による楽観的排他制御に使う
+CREATE TABLE IF NOT EXISTS firmware_campaigns (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    -- ロールアウト中のファームウェアは削除できない
+    firmware_id UUID NOT NULL CONSTRAINT fk_firmware_campaigns_firmware
+        REFERENCES firmware_artifacts(id) ON DELETE RESTRICT,
+    filter JSONB NOT NULL DEFAULT '[]', -- メタデータの条件 (例: "location.building=Factory-A")
+    wave_size INTEGER NOT NULL,
+    failure_threshold INTEGER NOT NULL, -- 送信済みの更新に占める失敗の割合(%)がこれを超えると一時停止する
+    update_timeout_seconds BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'RUNNING', -- RUNNING, PAUSED, COMPLETED, CANCELLED
+    status_reason TEXT NOT NULL DEFAULT '',
+    current_wave INTEGER NOT NULL DEFAULT 0,
+    total_waves INTEGER NOT NULL,
+    version BIGINT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+-- ファームウェア削除時の参照チェックのためのインデックス
+CREATE INDEX IF NOT EXISTS idx_firmware_campaigns_firmware_id ON firmware_campaigns(firmware_id);
+-- 実行中のキャンペーンを定期的に進めるための部分インデックス
+CREATE INDEX IF NOT EXISTS idx_firmware_campaigns_running ON firmware_campaigns(created_at)
+    WHERE status = 'RUNNING';
+
+-- キャンペーン内の各デバイスの更新状況
+CREATE TABLE IF NOT EXISTS firmware_updates (
+    campaign_id UUID NOT NULL CONSTRAINT fk_firmware_updates_campaign
+        REFERENCES firmware_campaigns(id) ON DELETE CASCADE,
+    device_id UUID NOT NULL CONSTRAINT fk_firmware_updates_device REFERENCES devices(id) ON DELETE CASCADE,
+    wave INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING', -- PENDING, SENT, DOWNLOADING, INSTALLING, SUCCEEDED, FAILED
+    progress INTEGER NOT NULL DEFAULT 0, -- デバイスが最後に報告した進捗(%)
+    failure_reason TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (campaign_id, device_id)
+);
+-- ステータスごとの集計と、ウェーブ単位の送信のためのインデックス
+CREATE INDEX IF NOT EXISTS idx_firmware_updates_campaign_status ON firmware_updates(campaign_id, status, wave);
+-- デバイス削除時のカスケードのためのインデックス
+CREATE INDEX IF NOT EXISTS idx_firmware_updates_device_id ON firmware_updates(device_id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS firmware_manifests;
+DROP TABLE IF EXISTS firmware_signing_keys;
+ALTER TABLE firmware_artifacts
+    DROP COLUMN IF EXISTS target,
+    DROP COLUMN IF EXISTS min_hardware_revision;
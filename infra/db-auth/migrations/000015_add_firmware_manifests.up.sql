@@ -0,0 +1,30 @@
+-- ファームウェアの対象（マニフェストに記載し、デバイスが自身に適合するかを確認する）
+ALTER TABLE firmware_artifacts
+    ADD COLUMN IF NOT EXISTS min_hardware_revision INTEGER NOT NULL DEFAULT 0, -- 対応する最小のハードウェアリビジョン（0は制限なし）
+    ADD COLUMN IF NOT EXISTS target JSONB NOT NULL DEFAULT '[]'; -- 対象デバイスのメタデータの条件 (例: "model=X100")
+
+-- ファームウェアマニフェストの署名鍵（秘密鍵は鍵ストアに置き、公開鍵のみを記録する）
+-- 有効な鍵は常に1つで、ローテーションで退役した鍵は猶予期間が終わるまで検証に使える
+CREATE TABLE IF NOT EXISTS firmware_signing_keys (
+    id VARCHAR(64) PRIMARY KEY, -- 公開鍵のSHA-256ハッシュから導出したID
+    algorithm VARCHAR(32) NOT NULL, -- Ed25519, ECDSA-P256-SHA256
+    public_key BYTEA NOT NULL, -- DER形式のSubjectPublicKeyInfo
+    retired_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE, -- 退役した鍵の猶予期間の終わり
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+-- 有効な鍵を1つに制限する部分ユニークインデックス（同時のローテーションの一方を失敗させる）
+CREATE UNIQUE INDEX IF NOT EXISTS idx_firmware_signing_keys_active ON firmware_signing_keys((true))
+    WHERE retired_at IS NULL;
+
+-- ファームウェアごとの署名済みマニフェスト（鍵のローテーション時には新しい鍵で署名し直す）
+CREATE TABLE IF NOT EXISTS firmware_manifests (
+    firmware_id UUID PRIMARY KEY CONSTRAINT fk_firmware_manifests_firmware
+        REFERENCES firmware_artifacts(id) ON DELETE CASCADE,
+    key_id VARCHAR(64) NOT NULL CONSTRAINT fk_firmware_manifests_key
+        REFERENCES firmware_signing_keys(id) ON DELETE RESTRICT,
+    algorithm VARCHAR(32) NOT NULL,
+    payload BYTEA NOT NULL, -- マニフェストのJSON（署名の対象そのもの）
+    signature BYTEA NOT NULL,
+    issued_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_devices_last_seen_at;
+ALTER TABLE devices
+    DROP COLUMN IF EXISTS last_ip,
+    DROP COLUMN IF EXISTS last_seen_at,
+    DROP COLUMN IF EXISTS connection_state;
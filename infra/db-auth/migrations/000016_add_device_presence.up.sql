@@ -0,0 +1,7 @@
+-- デバイスのプレゼンス（ブローカーが観測した接続状態。APIによる更新とは別に書き込み、バージョンを上げない）
+ALTER TABLE devices
+    ADD COLUMN IF NOT EXISTS connection_state VARCHAR(20) NOT NULL DEFAULT 'DISCONNECTED', -- CONNECTED, DISCONNECTED, STALE
+    ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP WITH TIME ZONE, -- 最後に接続・パケット・テレメトリを受け取った時刻
+    ADD COLUMN IF NOT EXISTS last_ip VARCHAR(45) NOT NULL DEFAULT ''; -- 最後に接続したIPアドレス
+-- ハートビート間隔を過ぎたデバイスの検出と lastSeenBefore フィルタ用のインデックス
+CREATE INDEX IF NOT EXISTS idx_devices_last_seen_at ON devices(last_seen_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS sensor_metrics;
+DROP TABLE IF EXISTS sensor_data;
@@ -0,0 +1,21 @@
+-- センサーデータ（デバイスから受信した計測値）
+-- デバイスはAuthデータベースで管理されるため、device_idに外部キー制約は設定しない
+CREATE TABLE IF NOT EXISTS sensor_data (
+    device_id UUID NOT NULL,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL, -- デバイス側の計測時刻
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP, -- サーバーの受信時刻
+    payload JSONB NOT NULL DEFAULT '{}', -- 受信したデータ全体
+    PRIMARY KEY (device_id, recorded_at)
+);
+-- 期間を指定した全デバイス横断の検索・削除用
+CREATE INDEX IF NOT EXISTS idx_sensor_data_recorded_at ON sensor_data(recorded_at);
+
+-- 数値メトリクス（payloadの数値項目を集計・グラフ表示用に型付きで保持）
+CREATE TABLE IF NOT EXISTS sensor_metrics (
+    device_id UUID NOT NULL,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    name VARCHAR(100) NOT NULL, -- "temperature", "humidity"
+    value DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (device_id, name, recorded_at),
+    FOREIGN KEY (device_id, recorded_at) REFERENCES sensor_data(device_id, recorded_at) ON DELETE CASCADE
+);